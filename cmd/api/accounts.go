@@ -0,0 +1,331 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// emailConflictResponse is the 409 body when an email already belongs to
+// another account.
+type emailConflictResponse struct {
+	Error       string `json:"error"`
+	AccountType string `json:"account_type"`
+}
+
+// emailAvailable reports whether email is free for the account self (nil
+// for a new account) across execs, teachers and students. Otherwise it
+// writes a 409 naming the kind of account holding it.
+func (app *application) emailAvailable(w http.ResponseWriter, r *http.Request, email string, self *store.AccountRef) bool {
+	owners, err := app.store.Accounts.EmailOwners(r.Context(), email)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return false
+	}
+
+	for _, owner := range owners {
+		if self != nil && owner.Kind == self.Kind && owner.ID == self.ID {
+			continue
+		}
+		app.logger.Warnw("conflict", "method", r.Method, "path", r.URL.Path, "error", "email in use", "account_type", owner.Kind)
+		writeJSON(w, http.StatusConflict, &emailConflictResponse{
+			Error:       "email already registered",
+			AccountType: owner.Kind,
+		})
+		return false
+	}
+	return true
+}
+
+// accountFilter reads the people list filters: status (active, pending or
+// deactivated), created_from/created_to and last_login_from/last_login_to,
+// dates in the school's time zone with both ends included.
+func (app *application) accountFilter(r *http.Request) (store.AccountFilter, error) {
+	qs := r.URL.Query()
+	f := store.AccountFilter{Status: qs.Get("status")}
+	switch f.Status {
+	case "", store.AccountActive, store.AccountPending, store.AccountDeactivated:
+	default:
+		return f, fmt.Errorf("status must be %s, %s or %s", store.AccountActive, store.AccountPending, store.AccountDeactivated)
+	}
+
+	for param, dst := range map[string]*time.Time{
+		"created_from":    &f.CreatedFrom,
+		"created_to":      &f.CreatedTo,
+		"last_login_from": &f.LastLoginFrom,
+		"last_login_to":   &f.LastLoginTo,
+	} {
+		v := qs.Get(param)
+		if v == "" {
+			continue
+		}
+		day, err := time.ParseInLocation(time.DateOnly, v, app.school.location)
+		if err != nil {
+			return f, fmt.Errorf("invalid %s", param)
+		}
+		if strings.HasSuffix(param, "_to") {
+			day = day.AddDate(0, 0, 1)
+		}
+		*dst = day
+	}
+	return f, nil
+}
+
+// defaultInactiveDays is how long an account must go unused to count as
+// inactive when the caller doesn't say.
+const defaultInactiveDays = 90
+
+// inactiveSince is the cutoff for accounts unused for days.
+func (app *application) inactiveSince(days int) time.Time {
+	return app.school.now().AddDate(0, 0, -days)
+}
+
+// ListInactiveAccounts godoc
+//
+//	@Summary		List accounts not signed in to for a while
+//	@Description	Live accounts whose last sign-in or token refresh, or creation if they never signed in, is more than days ago, longest idle first.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			days	query		int		false	"Days without a sign-in or token refresh (default 90)"
+//	@Param			role	query		string	false	"admin, manager, teacher, student or parent"
+//	@Success		200		{array}		dto.InactiveAccount
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/accounts/inactive [get]
+//	@ID				listInactiveAccounts
+func (app *application) listInactiveAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	days := defaultInactiveDays
+	if v := qs.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			app.badRequestResponse(w, r, errors.New("days must be a positive number"))
+			return
+		}
+		days = n
+	}
+	role := qs.Get("role")
+	if err := Validate.Var(role, "omitempty,oneof=admin manager teacher student parent"); err != nil {
+		app.badRequestResponse(w, r, errors.New("role must be admin, manager, teacher, student or parent"))
+		return
+	}
+
+	accounts, err := app.store.Accounts.Inactive(r.Context(), app.inactiveSince(days), role)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromInactiveAccounts(accounts)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+type DeactivateInactivePayload struct {
+	Days int    `json:"days" validate:"omitempty,min=1" example:"90"`
+	Role string `json:"role" validate:"omitempty,oneof=admin manager teacher student parent" enums:"admin,manager,teacher,student,parent" example:"admin"`
+	// AccountIDs limits the action to these of the inactive accounts;
+	// empty deactivates them all.
+	AccountIDs []int64 `json:"account_ids" validate:"max=1000"`
+}
+
+// DeactivatedAccounts is what a bulk deactivation did.
+type DeactivatedAccounts struct {
+	Count      int     `json:"count"`
+	AccountIDs []int64 `json:"account_ids"`
+}
+
+// DeactivateInactiveAccounts godoc
+//
+//	@Summary		Deactivate inactive accounts
+//	@Description	Deactivates the accounts the inactive report lists for the same days and role, or only the given ones of them, and revokes their refresh tokens. Deactivated accounts cannot sign in or refresh until reactivated; an access token already issued works until it expires.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		DeactivateInactivePayload	true	"Which inactive accounts (days defaults to 90)"
+//	@Success		200		{object}	DeactivatedAccounts
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/accounts/inactive/deactivate [post]
+//	@ID				deactivateInactiveAccounts
+func (app *application) deactivateInactiveAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload DeactivateInactivePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if payload.Days == 0 {
+		payload.Days = defaultInactiveDays
+	}
+
+	ids, err := app.store.Accounts.Deactivate(r.Context(), app.inactiveSince(payload.Days), payload.Role, payload.AccountIDs)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	app.logger.Infow("inactive accounts deactivated", "count", len(ids), "days", payload.Days, "role", payload.Role,
+		"by", getUser(r).ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, &DeactivatedAccounts{Count: len(ids), AccountIDs: ids}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ReactivateAccount godoc
+//
+//	@Summary	Let a deactivated account sign in again
+//	@Tags		Admin
+//	@Param		accountID	path	int	true	"Account ID"
+//	@Success	204
+//	@Failure	404	{object}	error	"No such deactivated account"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/accounts/{accountID}/reactivate [post]
+//	@ID			reactivateAccount
+func (app *application) reactivateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "accountID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Accounts.Reactivate(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPermissionGrants godoc
+//
+//	@Summary		List permissions granted to single accounts
+//	@Description	Grants give an account a permission its role doesn't have, such as counseling:read for counselors.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			permission	query	string	false	"Only grants of this permission"
+//	@Success		200			{array}	dto.PermissionGrant
+//	@Security		ApiKeyAuth
+//	@Router			/admin/permissions [get]
+//	@ID				listPermissionGrants
+func (app *application) listPermissionGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.store.Grants.List(r.Context(), r.URL.Query().Get("permission"))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPermissionGrants(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GrantPermission godoc
+//
+//	@Summary		Grant an account a permission
+//	@Description	Only exec and teacher accounts can be granted permissions; the only grantable one is counseling:read.
+//	@Tags			Admin
+//	@Param			accountID	path	int		true	"Account ID"
+//	@Param			permission	path	string	true	"Permission"
+//	@Success		204
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/accounts/{accountID}/permissions/{permission} [put]
+//	@ID				grantPermission
+func (app *application) grantPermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, permission, ok := app.grantParams(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	account, err := app.store.Accounts.GetByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	if account.ProfileType != store.ProfileExec && account.ProfileType != store.ProfileTeacher {
+		app.badRequestResponse(w, r, fmt.Errorf("%s accounts can't be granted permissions", account.ProfileType))
+		return
+	}
+
+	if err := app.store.Grants.Grant(ctx, id, permission, getUser(r).ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.logger.Infow("permission granted", "account", id, "permission", permission, "granted_by", getUser(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokePermission godoc
+//
+//	@Summary	Revoke a permission granted to an account
+//	@Tags		Admin
+//	@Param		accountID	path	int		true	"Account ID"
+//	@Param		permission	path	string	true	"Permission"
+//	@Success	204
+//	@Failure	400	{object}	error
+//	@Failure	404	{object}	error	"Not granted"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/accounts/{accountID}/permissions/{permission} [delete]
+//	@ID			revokePermission
+func (app *application) revokePermissionHandler(w http.ResponseWriter, r *http.Request) {
+	id, permission, ok := app.grantParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Grants.Revoke(r.Context(), id, permission); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.logger.Infow("permission revoked", "account", id, "permission", permission, "revoked_by", getUser(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) grantParams(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "accountID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return 0, "", false
+	}
+	permission := chi.URLParam(r, "permission")
+	if !grantablePermissions[permission] {
+		app.badRequestResponse(w, r, fmt.Errorf("%q is not a grantable permission", permission))
+		return 0, "", false
+	}
+	return id, permission, true
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	defaultActivityLimit = 20
+	maxActivityLimit     = 100
+)
+
+var activityActions = map[string]string{
+	http.MethodGet:    "viewed",
+	http.MethodPatch:  "updated",
+	http.MethodDelete: "deleted",
+}
+
+// GetMyRecent godoc
+//
+//	@Summary	Recently viewed records of the current user
+//	@Tags		Me
+//	@Produce	json
+//	@Param		limit	query		int	false	"Max items (default 20)"
+//	@Success	200		{array}		cache.EntityRef
+//	@Failure	400		{object}	error
+//	@Failure	500		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/me/recent [get]
+//	@ID			getMyRecent
+func (app *application) getMyRecentHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseActivityLimit(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	refs, err := app.cacheStorage.Activity.Recent(r.Context(), activityUserKey(getUser(r)), limit)
+	if err != nil {
+		app.logger.Warnw("loading recent items failed", "error", err.Error())
+		refs = []cache.EntityRef{}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, refs); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetMyActivity godoc
+//
+//	@Summary	Activity feed of the current user
+//	@Tags		Me
+//	@Produce	json
+//	@Param		limit	query		int	false	"Max items (default 20)"
+//	@Success	200		{array}		cache.ActivityEvent
+//	@Failure	400		{object}	error
+//	@Failure	500		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/me/activity [get]
+//	@ID			getMyActivity
+func (app *application) getMyActivityHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseActivityLimit(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	events, err := app.cacheStorage.Activity.Feed(r.Context(), activityUserKey(getUser(r)), limit)
+	if err != nil {
+		app.logger.Warnw("loading activity feed failed", "error", err.Error())
+		events = []cache.ActivityEvent{}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, events); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// trackActivity records successful views and writes of the entity identified
+// by the idParam URL parameter in the caller's recent list and activity feed.
+func (app *application) trackActivity(entityType, idParam string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() >= 300 {
+				return
+			}
+
+			action, ok := activityActions[r.Method]
+			user := getUser(r)
+			if !ok || user == nil {
+				return
+			}
+
+			id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+			if err != nil {
+				return
+			}
+
+			ctx := r.Context()
+			key := activityUserKey(user)
+			if action == "viewed" {
+				if err := app.cacheStorage.Activity.RecordView(ctx, key, entityType, id); err != nil {
+					app.logger.Warnw("record view failed", "error", err.Error())
+				}
+				return
+			}
+
+			ev := cache.ActivityEvent{Action: action, Type: entityType, ID: id, Occurred: time.Now().UTC()}
+			if err := app.cacheStorage.Activity.RecordEvent(ctx, key, ev); err != nil {
+				app.logger.Warnw("record activity failed", "error", err.Error())
+			}
+		})
+	}
+}
+
+func activityUserKey(user *auth.Claims) string {
+	return fmt.Sprintf("%s:%d", accountKind(user.Role), user.ID)
+}
+
+func parseActivityLimit(r *http.Request) (int, error) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return defaultActivityLimit, nil
+	}
+
+	n, err := strconv.Atoi(l)
+	if err != nil || n < 1 || n > maxActivityLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxActivityLimit)
+	}
+	return n, nil
+}
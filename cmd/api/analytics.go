@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/analytics"
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const analyticsExportJob = "analytics_export"
+
+// track records a domain event for the given user; it is a no-op when
+// analytics is disabled.
+func (app *application) track(name string, user *auth.Claims, props map[string]any) {
+	if app.analytics == nil {
+		return
+	}
+
+	ev := analytics.Event{Name: name, Properties: props}
+	if user != nil {
+		ev.ActorKind = accountKind(user.Role)
+		ev.ActorID = user.ID
+	}
+	app.analytics.Emit(ev)
+}
+
+// trackFeatureUsage records which authenticated routes are used, keyed by
+// route pattern rather than raw path so IDs don't fragment the counts. It is
+// applied by AuthTokenMiddleware, where the caller is known.
+func (app *application) trackFeatureUsage(next http.Handler) http.Handler {
+	if app.analytics == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		user := getUser(r)
+		rctx := chi.RouteContext(r.Context())
+		if user == nil || rctx == nil {
+			return
+		}
+
+		app.track(analytics.EventFeatureUsed, user, map[string]any{
+			"route":  r.Method + " " + rctx.RoutePattern(),
+			"status": ww.Status(),
+		})
+	})
+}
+
+func (app *application) enqueueAnalyticsExport(day time.Time) error {
+	_, err := app.jobs.Enqueue(analyticsExportJob, 0, "system", func(ctx context.Context) ([]byte, error) {
+		res, err := app.analyticsExport.ExportDay(ctx, day)
+		if err != nil {
+			app.logger.Errorw("analytics export failed", "day", day.Format(time.DateOnly), "error", err.Error())
+			return nil, err
+		}
+		app.logger.Infow("analytics exported", "object", res.Object, "rows", res.Rows)
+		return json.Marshal(res)
+	})
+	return err
+}
+
+// startAnalyticsMaintenance keeps monthly partitions ahead of time and, when
+// exports are enabled, ships the previous day's events every night.
+func (app *application) startAnalyticsMaintenance() {
+	if app.analytics == nil {
+		return
+	}
+
+	ensure := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.analytics.EnsurePartitions(ctx, time.Now().UTC()); err != nil {
+			app.logger.Errorw("creating analytics partitions failed", "error", err.Error())
+		}
+	}
+	ensure()
+
+	go func() {
+		for {
+			now := time.Now().UTC()
+			next := time.Date(now.Year(), now.Month(), now.Day(), app.config.analytics.exportHour, 0, 0, 0, time.UTC)
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			ensure()
+			if app.analyticsExport != nil {
+				if err := app.enqueueAnalyticsExport(next.AddDate(0, 0, -1)); err != nil {
+					app.logger.Errorw("scheduling analytics export failed", "error", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+const maxAnalyticsRange = 366 * 24 * time.Hour
+
+// GetAttendanceTrends godoc
+//
+//	@Summary		Attendance trends
+//	@Description	Weekly absence rates per classroom, grade or weekday with week-over-week change and a four-week rolling average, as of the last stats refresh
+//	@Tags			Analytics
+//	@Produce		json
+//	@Param			group_by	query		string	false	"classroom (default), grade or weekday"
+//	@Param			from		query		string	false	"From date YYYY-MM-DD (default: 90 days ago)"
+//	@Param			to			query		string	false	"To date YYYY-MM-DD (default: today)"
+//	@Success		200			{array}		dto.AttendanceTrend
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/analytics/attendance [get]
+//	@ID				getAttendanceTrends
+func (app *application) getAttendanceTrendsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	groupBy := qs.Get("group_by")
+	if groupBy == "" {
+		groupBy = "classroom"
+	}
+
+	from, to, err := parseDateRange(r, 90)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	scope := getScope(r)
+	params := map[string]any{
+		"group_by": groupBy,
+		"from":     from.Format(time.DateOnly),
+		"to":       to.Format(time.DateOnly),
+		"scope":    scope.Key(),
+	}
+
+	trends, err := cache.GetListWithCache(
+		r.Context(),
+		app.cacheStorage.AttendanceTrends,
+		"analytics:attendance",
+		params,
+		func(ctx context.Context) ([]*store.AttendanceTrend, error) {
+			return app.store.Analytics.AttendanceTrends(ctx, groupBy, from, to, scope)
+		},
+	)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidGroupBy) {
+			app.badRequestResponse(w, r, fmt.Errorf("group_by must be one of classroom, grade, weekday"))
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAttendanceTrends(trends)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// TeacherPerformance summarises how a teacher keeps up with class duties.
+type TeacherPerformance struct {
+	TeacherID            int64                         `json:"teacher_id"`
+	From                 string                        `json:"from"`
+	To                   string                        `json:"to"`
+	SchoolDays           int64                         `json:"school_days"`
+	DaysTaken            int64                         `json:"days_taken"`
+	AttendanceCompletion float64                       `json:"attendance_completion"`
+	Classrooms           []*dto.AttendanceCompleteness `json:"classrooms"`
+}
+
+// GetTeacherPerformance godoc
+//
+//	@Summary		Teacher performance overview
+//	@Description	Attendance-taking completeness of the teacher's classrooms per month
+//	@Tags			Analytics
+//	@Produce		json
+//	@Param			teacherID	path		int		true	"Teacher ID"
+//	@Param			from		query		string	false	"From date YYYY-MM-DD (default: 90 days ago)"
+//	@Param			to			query		string	false	"To date YYYY-MM-DD (default: today)"
+//	@Success		200			{object}	TeacherPerformance
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/analytics/teachers/{teacherID} [get]
+//	@ID				getTeacherPerformance
+func (app *application) getTeacherPerformanceHandler(w http.ResponseWriter, r *http.Request) {
+	teacher := getTeacherFromCtx(r)
+
+	from, to, err := parseDateRange(r, 90)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// days that haven't happened yet can't have attendance
+	if today := time.Now().UTC().Truncate(24 * time.Hour); to.After(today) {
+		to = today
+	}
+
+	stats, err := app.store.Analytics.TeacherAttendanceCompleteness(r.Context(), teacher.ID, from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	perf := TeacherPerformance{
+		TeacherID:  teacher.ID,
+		From:       from.Format(time.DateOnly),
+		To:         to.Format(time.DateOnly),
+		Classrooms: dto.FromAttendanceCompletenesses(stats),
+	}
+	for _, s := range stats {
+		perf.SchoolDays += s.SchoolDays
+		perf.DaysTaken += s.DaysTaken
+	}
+	if perf.SchoolDays > 0 {
+		perf.AttendanceCompletion = float64(perf.DaysTaken) / float64(perf.SchoolDays)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, perf); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetGradeAverages godoc
+//
+//	@Summary		Grade averages
+//	@Description	Score averages per classroom and assignment, as of the last stats refresh
+//	@Tags			Analytics
+//	@Produce		json
+//	@Param			classroom_id	query		int	false	"Only this classroom"
+//	@Success		200				{array}		dto.GradeAverage
+//	@Failure		400				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/analytics/grades [get]
+//	@ID				getGradeAverages
+func (app *application) getGradeAveragesHandler(w http.ResponseWriter, r *http.Request) {
+	var classroomID int64
+	if v := r.URL.Query().Get("classroom_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			app.badRequestResponse(w, r, errors.New("classroom_id must be a positive integer"))
+			return
+		}
+		classroomID = id
+	}
+
+	averages, err := app.store.Analytics.GradeAverages(r.Context(), classroomID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromGradeAverages(averages)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// refreshStats recomputes the dashboard views and drops the analytics
+// responses cached from the old ones.
+func (app *application) refreshStats(ctx context.Context) ([]*store.StatsRefresh, error) {
+	refreshed, err := app.store.Analytics.RefreshStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := app.cacheStorage.AttendanceTrends.InvalidatePrefix(ctx, ""); err != nil {
+		app.logger.Warnw("clearing cached analytics failed", "error", err.Error())
+	}
+	return refreshed, nil
+}
+
+// startStatsRefresh refreshes the dashboard views on a schedule.
+func (app *application) startStatsRefresh() {
+	if app.config.analytics.statsRefresh <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(app.config.analytics.statsRefresh)
+	go func() {
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			if _, err := app.refreshStats(ctx); err != nil {
+				app.logger.Errorw("refreshing dashboard stats failed", "error", err.Error())
+			}
+			cancel()
+		}
+	}()
+}
+
+// RefreshStats godoc
+//
+//	@Summary		Refresh dashboard statistics now
+//	@Description	Recomputes the pre-aggregated attendance and grade views the analytics endpoints read; otherwise they are refreshed every STATS_REFRESH_MINUTES.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{array}	dto.StatsRefresh
+//	@Security		ApiKeyAuth
+//	@Router			/admin/refresh-stats [post]
+//	@ID				refreshStats
+func (app *application) refreshStatsHandler(w http.ResponseWriter, r *http.Request) {
+	refreshed, err := app.refreshStats(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStatsRefreshes(refreshed)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// parseDateRange reads the from/to query parameters, defaulting to the last
+// defaultDays days, and bounds the range to a year.
+func parseDateRange(r *http.Request, defaultDays int) (time.Time, time.Time, error) {
+	qs := r.URL.Query()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	if v := qs.Get("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to date; expected YYYY-MM-DD")
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -defaultDays)
+	if v := qs.Get("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from date; expected YYYY-MM-DD")
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > maxAnalyticsRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must not exceed one year")
+	}
+	return from, to, nil
+}
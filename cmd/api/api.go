@@ -3,16 +3,33 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/MahdiiTaheri/classnama-backend/docs"
+	"github.com/MahdiiTaheri/classnama-backend/internal/analytics"
 	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/backup"
+	"github.com/MahdiiTaheri/classnama-backend/internal/captcha"
+	"github.com/MahdiiTaheri/classnama-backend/internal/db"
+	"github.com/MahdiiTaheri/classnama-backend/internal/errreport"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/meetings"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/payments"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/telegram"
+	"github.com/MahdiiTaheri/classnama-backend/internal/jobs"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/ministry"
 	"github.com/MahdiiTaheri/classnama-backend/internal/ratelimiter"
+	"github.com/MahdiiTaheri/classnama-backend/internal/scan"
+	"github.com/MahdiiTaheri/classnama-backend/internal/search"
+	"github.com/MahdiiTaheri/classnama-backend/internal/sms"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
 	"github.com/go-chi/chi/v5"
@@ -22,22 +39,230 @@ import (
 )
 
 type application struct {
-	config        config
-	logger        *zap.SugaredLogger
-	store         store.Storage
-	cacheStorage  cache.Storage
-	authenticator auth.Authenticator
-	ratelimiter   ratelimiter.Limiter
+	config          config
+	logger          *zap.SugaredLogger
+	logLevel        zap.AtomicLevel
+	store           store.Storage
+	cacheStorage    cache.Storage
+	authenticator   auth.Authenticator
+	ratelimiter     ratelimiter.Limiter
+	jobs            *jobs.Queue
+	backup          *backup.Service
+	searchIndex     *search.Client
+	mailer          mailer.Client
+	sms             sms.Client
+	telegram        telegram.Client
+	meetings        meetings.Provider
+	payments        payments.Gateway
+	scanner         scan.Scanner
+	reporter        errreport.Reporter
+	school          *schoolSchedule
+	checkIn         *checkInPolicy
+	staffAttendance *staffAttendancePolicy
+	analytics       *analytics.Emitter
+	analyticsExport *analytics.Exporter
+	ministry        *ministry.Set
+	maintenance     atomic.Pointer[cache.MaintenanceState]
+	dynamic         atomic.Pointer[dynamicConfig]
+	blocklist       atomic.Pointer[[]netip.Prefix]
+	adminNetworks   []netip.Prefix
+	trustedProxies  []netip.Prefix
+	// approvals are the actions staged for a second admin.
+	approvals map[string]bool
+	debug     debugCapture
 }
 
 type config struct {
-	addr        string
-	env         string
-	apiURL      string
-	db          dbConfig
-	auth        authConfig
-	redisCfg    redisCfg
-	ratelimiter ratelimiter.Config
+	addr      string
+	env       string
+	apiURL    string
+	publicURL string
+	// defaultLanguage is the last resort when picking translated fields.
+	defaultLanguage string
+	db              dbConfig
+	auth            authConfig
+	redisCfg        redisCfg
+	trash           trashConfig
+	terms           termsConfig
+	worker          workerConfig
+	backup          backupConfig
+	search          searchConfig
+	analytics       analyticsConfig
+	meetings        meetings.Config
+	payments        paymentsConfig
+	uploads         uploadsConfig
+	errReport       errreport.Config
+	school          schoolConfig
+	reminders       reminderConfig
+	attendance      attendanceConfig
+	approvals       approvalsConfig
+	points          pointsConfig
+	dunning         dunningConfig
+	checkIn         checkInConfig
+	staffAttendance staffAttendanceConfig
+	ipFilter        ipFilterConfig
+	ministry        ministryConfig
+	server          serverConfig
+}
+
+type serverConfig struct {
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	idleTimeout     time.Duration
+	maxHeaderBytes  int
+	h2c             bool
+	shutdownTimeout time.Duration
+	// requestTimeout bounds each request, and the queries it runs;
+	// longRequestTimeout replaces it on routes doing bulk work.
+	requestTimeout     time.Duration
+	longRequestTimeout time.Duration
+}
+
+type schoolConfig struct {
+	name         string
+	timezone     string
+	days         string
+	gradingScale string
+	// currency is the ISO 4217 code amounts are in.
+	currency string
+}
+
+type ministryConfig struct {
+	// jurisdiction picks the report layouts used when a request names
+	// none.
+	jurisdiction string
+	// templatesDir holds layouts added to or replacing the built-in ones.
+	templatesDir string
+	// schoolCode is the school's identifier with the ministry.
+	schoolCode string
+}
+
+type reminderConfig struct {
+	enabled bool
+	cutoff  string
+}
+
+type approvalsConfig struct {
+	// actions is a comma-separated list of the actions a second admin
+	// must approve.
+	actions string
+	// waiverPercent is the discount override from which fee_waiver
+	// applies.
+	waiverPercent int
+}
+
+type attendanceConfig struct {
+	// editDays is how many days back teachers may mark attendance; older
+	// days need a correction request. Zero leaves every day open.
+	editDays int
+}
+
+type absenceSMSConfig struct {
+	enabled bool
+	// webhookSecret must appear as ?secret= on inbound SMS callbacks.
+	webhookSecret string
+}
+
+type dunningConfig struct {
+	enabled bool
+	time    string
+	// schedule lists the days overdue at which reminders go out.
+	schedule string
+}
+
+type pointsConfig struct {
+	summaryEnabled bool
+	summaryDay     string
+	summaryTime    string
+}
+
+type ipFilterConfig struct {
+	// adminCIDRs is a comma-separated allowlist for /v1/admin.
+	adminCIDRs string
+	// trustedProxyCIDRs are the proxies whose forwarded client address is
+	// believed; requests from anywhere else are taken at their peer address.
+	trustedProxyCIDRs string
+}
+
+type paymentsConfig struct {
+	payments.Config
+	// returnURL is where payers land after the gateway, with ?invoice=
+	// and ?status= appended; empty answers the callback with JSON.
+	returnURL string
+	// receiptPrefix starts receipt numbers. Schools sharing a database
+	// use different prefixes so each is numbered on its own.
+	receiptPrefix string
+}
+
+type uploadsConfig struct {
+	scan scan.Config
+	// scanRequired refuses uploads while the scanner can't be reached;
+	// otherwise they are accepted unscanned.
+	scanRequired bool
+}
+
+type captchaConfig struct {
+	enabled bool
+	captcha.Config
+}
+
+type checkInConfig struct {
+	enabled     bool
+	latitude    float64
+	longitude   float64
+	radius      float64
+	windowStart string
+	windowEnd   string
+	lateAfter   string
+}
+
+type staffAttendanceConfig struct {
+	enabled bool
+	// networks is a comma-separated list of the school's IP ranges; empty
+	// allows any address.
+	networks      string
+	requireDevice bool
+	lateAfter     string
+}
+
+type analyticsConfig struct {
+	enabled       bool
+	buffer        int
+	flushInterval time.Duration
+	export        bool
+	exportHour    int
+	s3            backup.Config
+	// statsRefresh is how often the dashboard views are recomputed; zero
+	// leaves it to the admin endpoint.
+	statsRefresh time.Duration
+}
+
+type searchConfig struct {
+	meili    search.Config
+	interval time.Duration
+}
+
+type backupConfig struct {
+	enabled  bool
+	s3       backup.Config
+	interval time.Duration
+}
+
+type workerConfig struct {
+	concurrency int
+	queueSize   int
+	jobTimeout  time.Duration
+	retention   time.Duration
+}
+
+type trashConfig struct {
+	retention time.Duration
+}
+
+type termsConfig struct {
+	// archiveAfter is how long after closing a term it is archived; zero
+	// leaves archiving to the endpoint.
+	archiveAfter time.Duration
 }
 
 type redisCfg struct {
@@ -45,13 +270,35 @@ type redisCfg struct {
 	pw      string
 	db      int
 	enabled bool
+	codec   string
 }
 
 type dbConfig struct {
-	addr         string
+	addr              string
+	maxIdleTime       string
+	maxLifetime       string
+	healthCheckPeriod string
+	pgbouncer         bool
+	// each workload gets its own pool; see store.Workload
+	api       poolSize
+	worker    poolSize
+	reporting poolSize
+}
+
+type poolSize struct {
 	maxOpenConns int
 	maxIdleConns int
-	maxIdleTime  string
+}
+
+func (c dbConfig) pool(size poolSize) db.Config {
+	return db.Config{
+		Addr:              c.addr,
+		MaxOpenConns:      size.maxOpenConns,
+		MaxIdleConns:      size.maxIdleConns,
+		MaxIdleTime:       c.maxIdleTime,
+		MaxLifetime:       c.maxLifetime,
+		HealthCheckPeriod: c.healthCheckPeriod,
+	}
 }
 
 type authConfig struct {
@@ -61,8 +308,12 @@ type authConfig struct {
 
 type tokenConfig struct {
 	secret string
-	exp    time.Duration
-	iss    string
+	// exp is the access token lifetime per role ("exec" covers admins and
+	// managers).
+	exp                map[string]time.Duration
+	refreshExp         time.Duration
+	refreshRememberExp time.Duration
+	iss                string
 }
 
 type basicConfig struct {
@@ -75,22 +326,110 @@ func (app *application) mount() http.Handler {
 
 	// middlewares
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(app.RealIPMiddleware)
 	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(app.RecovererMiddleware)
+	r.Use(app.DeadlineMiddleware)
+	r.Use(workload(store.WorkloadAPI))
+	r.Use(app.BlocklistMiddleware)
 	r.Use(app.RateLimiterMiddleware)
+	r.Use(app.MaintenanceMiddleware)
+	r.Use(app.LanguageMiddleware)
+	r.Use(app.DebugCaptureMiddleware)
 
 	r.Route("/v1", func(r chi.Router) {
 		r.Get("/health", app.healthCheckHandler)
+		r.Get("/health/ready", app.readinessHandler)
+		r.Get("/meta", app.getMetaHandler)
+		r.With(app.adminNetworkMiddleware, middleware.BasicAuth("metrics", map[string]string{
+			app.config.auth.basic.user: app.config.auth.basic.pass,
+		})).Get("/metrics", app.metricsHandler)
 
 		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 
+		// PUBLIC
+		r.With(app.CaptchaMiddleware).Post("/auth/login", app.loginHandler)
+		r.Post("/auth/refresh", app.refreshTokenHandler)
+
+		r.Route("/auth/otp", func(r chi.Router) {
+			// PUBLIC: parents log in with a texted code
+			r.With(app.CaptchaMiddleware).Post("/request", app.requestOTPHandler)
+			r.Post("/verify", app.verifyOTPHandler)
+		})
+
+		// PUBLIC: authorised by the webhook secret
+		r.Post("/webhooks/sms", app.inboundSMSHandler)
+		r.Get("/webhooks/sms", app.inboundSMSHandler)
+		r.Post("/webhooks/telegram", app.telegramWebhookHandler)
+
+		r.Route("/email-changes/{kind}/{id}", func(r chi.Router) {
+			// PUBLIC: authorised by the link signature
+			r.Get("/", app.getEmailChangeHandler)
+			r.Post("/", app.confirmEmailChangeHandler)
+		})
+
+		r.Route("/parents", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("parent"))
+			r.Get("/me/children", app.getMyChildrenHandler)
+			r.Get("/me/telegram", app.getTelegramLinkHandler)
+			r.Post("/me/telegram", app.createTelegramCodeHandler)
+			r.Delete("/me/telegram", app.deleteTelegramLinkHandler)
+			r.Get("/me/invoices", app.listMyInvoicesHandler)
+			r.Get("/me/invoices/{invoiceID}", app.getMyInvoiceHandler)
+			r.Post("/me/invoices/{invoiceID}/pay", app.payInvoiceHandler)
+			r.Get("/me/receipts/{number}/pdf", app.getMyReceiptPDFHandler)
+		})
+
+		// PUBLIC: settled by verifying with the gateway
+		r.Get("/payments/{gateway}/callback", app.paymentCallbackHandler)
+		// PUBLIC: needs the code printed on the receipt
+		r.Get("/receipts/{number}/verify", app.verifyReceiptHandler)
+
+		r.Route("/finance", func(r chi.Router) {
+			r.Use(workload(store.WorkloadReporting))
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+			r.With(app.longBudget).Get("/summary", app.getFinanceSummaryHandler)
+		})
+
+		r.Route("/invoices", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/", app.listInvoicesHandler)
+			r.Post("/", app.createInvoiceHandler)
+			r.With(app.longBudget).Post("/generate", app.generateInvoicesHandler)
+			r.Get("/delinquency", app.getDelinquencyHandler)
+			r.Get("/receipts/{number}/pdf", app.getReceiptPDFHandler)
+			r.Get("/{invoiceID}", app.getInvoiceHandler)
+			r.Put("/{invoiceID}/installments", app.setInstallmentsHandler)
+			r.Post("/{invoiceID}/void", app.voidInvoiceHandler)
+		})
+
+		r.Route("/discounts", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/rules", app.listDiscountRulesHandler)
+			r.Post("/rules", app.createDiscountRuleHandler)
+			r.Patch("/rules/{ruleID}", app.updateDiscountRuleHandler)
+			r.Delete("/rules/{ruleID}", app.deleteDiscountRuleHandler)
+			r.Get("/students/{studentID}", app.getStudentDiscountsHandler)
+			r.Put("/students/{studentID}/rules/{ruleID}", app.setStudentDiscountHandler)
+			r.Delete("/students/{studentID}/rules/{ruleID}", app.deleteStudentDiscountHandler)
+		})
+
+		r.Route("/approvals", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager")).Get("/", app.listApprovalsHandler)
+			r.With(app.requireRole("admin")).Post("/{approvalID}/approve", app.approveActionHandler)
+			r.With(app.requireRole("admin")).Post("/{approvalID}/reject", app.rejectActionHandler)
+		})
+
 		r.Route("/execs", func(r chi.Router) {
 			// PUBLIC
-			r.Post("/register", app.registerExecHandler)
-			r.Post("/login", app.loginExecHandler)
+			r.With(app.CaptchaMiddleware).Post("/register", app.registerExecHandler)
+			r.With(deprecated(http.MethodPost, "/execs/login"), app.CaptchaMiddleware).Post("/login", app.loginExecHandler)
 
 			// PROTECTED
 			r.Group(func(r chi.Router) {
@@ -100,16 +439,17 @@ func (app *application) mount() http.Handler {
 
 				r.Route("/{execID}", func(r chi.Router) {
 					r.Use(app.execsContextMiddleware) // ONLY for routes with execID
-					r.Get("/", app.getExecHandler)
-					r.Patch("/", app.updateExecHandler)
-					r.Delete("/", app.deleteExecHandler)
+					r.With(app.trackActivity("exec", "execID")).Get("/", app.getExecHandler)
+					r.With(app.trackActivity("exec", "execID")).Patch("/", app.updateExecHandler)
+					r.With(app.trackActivity("exec", "execID")).Delete("/", app.deleteExecHandler)
+					r.With(app.requireRole("admin")).Put("/scope", app.setExecScopeHandler)
 				})
 			})
 		})
 
 		r.Route("/teachers", func(r chi.Router) {
 			// PUBLIC LOGIN
-			r.Post("/login", app.loginTeacherHandler)
+			r.With(deprecated(http.MethodPost, "/teachers/login"), app.CaptchaMiddleware).Post("/login", app.loginTeacherHandler)
 
 			// PROTECTED: Only execs can manage teachers
 			r.Group(func(r chi.Router) {
@@ -117,20 +457,30 @@ func (app *application) mount() http.Handler {
 				r.Use(app.requireRole("manager", "admin")) // only execs can access
 				r.Post("/", app.registerTeacherHandler)
 				r.Get("/", app.getTeachersHandler)
+				r.Get("/by-code/{code}", app.getTeacherByCodeHandler)
+				r.Get("/lookup", app.lookupTeachersHandler)
 
 				r.Route("/{teacherID}", func(r chi.Router) {
 					r.Use(app.teachersContextMiddleware)
-					r.Get("/", app.getTeacherHandler)
+					r.With(app.trackActivity("teacher", "teacherID")).Get("/", app.getTeacherHandler)
 					r.Get("/students", app.getStudentsByTeacherHandler)
-					r.Patch("/", app.updateTeacherHandler)
-					r.Delete("/", app.deleteTeacherHandler)
+					r.Get("/history", app.getTeacherHistoryHandler)
+					r.Get("/dependencies", app.getDependenciesHandler("teachers"))
+					r.Get("/tags", app.getRecordTagsHandler("teachers"))
+					r.Put("/tags/{tag}", app.addRecordTagHandler("teachers"))
+					r.Delete("/tags/{tag}", app.removeRecordTagHandler("teachers"))
+					r.Get("/notes", app.getRecordNotesHandler("teachers"))
+					r.Post("/notes", app.createRecordNoteHandler("teachers"))
+					r.Delete("/notes/{noteID}", app.deleteRecordNoteHandler("teachers"))
+					r.With(app.trackActivity("teacher", "teacherID")).Patch("/", app.updateTeacherHandler)
+					r.With(app.trackActivity("teacher", "teacherID")).Delete("/", app.deleteTeacherHandler)
 				})
 			})
 		})
 
 		r.Route("/students", func(r chi.Router) {
 			// PUBLIC LOGIN
-			r.Post("/login", app.loginStudentHandler)
+			r.With(deprecated(http.MethodPost, "/students/login"), app.CaptchaMiddleware).Post("/login", app.loginStudentHandler)
 
 			// PROTECTED: Only execs can manage students
 			r.Group(func(r chi.Router) {
@@ -138,12 +488,32 @@ func (app *application) mount() http.Handler {
 				r.Use(app.requireRole("admin", "manager")) // only execs can access
 				r.Post("/", app.registerStudentHandler)
 				r.Get("/", app.getStudentsHandler)
+				r.Get("/by-code/{code}", app.getStudentByCodeHandler)
+				r.Get("/lookup", app.lookupStudentsHandler)
 
 				r.Route("/{studentID}", func(r chi.Router) {
 					r.Use(app.studentsContextMiddleware)
-					r.Get("/", app.getStudentHandler)
-					r.Patch("/", app.updateStudentHandler)
-					r.Delete("/", app.deleteStudentHandler)
+					r.With(app.trackActivity("student", "studentID")).Get("/", app.getStudentHandler)
+					r.With(app.longBudget).Get("/export", app.exportStudentHandler)
+					r.Post("/merge/{otherID}", app.mergeStudentsHandler)
+					r.Get("/history", app.getStudentHistoryHandler)
+					r.Get("/dependencies", app.getDependenciesHandler("students"))
+					r.Get("/tags", app.getRecordTagsHandler("students"))
+					r.Put("/tags/{tag}", app.addRecordTagHandler("students"))
+					r.Delete("/tags/{tag}", app.removeRecordTagHandler("students"))
+					r.Get("/notes", app.getRecordNotesHandler("students"))
+					r.Post("/notes", app.createRecordNoteHandler("students"))
+					r.Delete("/notes/{noteID}", app.deleteRecordNoteHandler("students"))
+					r.Get("/pickups", app.getPickupContactsHandler)
+					r.Post("/pickups", app.createPickupContactHandler)
+					r.Patch("/pickups/{contactID}", app.updatePickupContactHandler)
+					r.Delete("/pickups/{contactID}", app.deletePickupContactHandler)
+					r.Put("/pickups/{contactID}/photo", app.putPickupContactPhotoHandler)
+					r.Get("/report-cards/{termID}", app.getReportCardHandler)
+					r.Get("/transcript", app.getTranscriptHandler)
+					r.Get("/transcript/pdf", app.getTranscriptPDFHandler)
+					r.With(app.trackActivity("student", "studentID")).Patch("/", app.updateStudentHandler)
+					r.With(app.trackActivity("student", "studentID")).Delete("/", app.deleteStudentHandler)
 				})
 			})
 		})
@@ -154,17 +524,47 @@ func (app *application) mount() http.Handler {
 				r.Use(app.requireRole("admin", "manager")) // only execs can access
 				r.Post("/", app.registerClassroomHandler)
 				r.Get("/", app.getClassroomsHandler)
+				r.Post("/balance/preview", app.previewClassBalanceHandler)
+				r.Post("/transfers", app.transferStudentsHandler)
 
 				r.Route("/{classroomID}", func(r chi.Router) {
 					r.Use(app.classroomsContextMiddleware)
-					r.Get("/", app.getClassroomHandler)
-					r.Patch("/", app.updateClassroomHandler)
-					r.Delete("/", app.deleteClassroomHandler)
+					r.With(app.trackActivity("classroom", "classroomID")).Get("/", app.getClassroomHandler)
+					r.Get("/detail", app.getClassroomDetailHandler)
+					r.Get("/history", app.getClassroomHistoryHandler)
+					r.Get("/dependencies", app.getDependenciesHandler("classrooms"))
+					r.With(app.longBudget).Get("/roster", app.exportClassroomRosterHandler)
+					r.Get("/grades", app.getClassroomGradesHandler)
+					r.With(app.longBudget).Post("/grades/import", app.importClassroomGradesHandler)
+					r.Get("/lms-sync", app.getLMSSyncHandler)
+					r.Put("/lms-sync", app.putLMSSyncHandler)
+					r.Delete("/lms-sync", app.deleteLMSSyncHandler)
+					r.Post("/lms-sync/run", app.runLMSSyncHandler)
+					r.With(app.trackActivity("classroom", "classroomID")).Patch("/", app.updateClassroomHandler)
+					r.With(app.trackActivity("classroom", "classroomID")).Delete("/", app.deleteClassroomHandler)
 				})
 			})
 		})
 
+		r.Route("/staff-attendance", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("teacher")).Post("/arrive", app.staffArriveHandler)
+			r.With(app.requireRole("teacher")).Post("/depart", app.staffDepartHandler)
+			r.With(app.requireRole("admin", "manager", "teacher")).Get("/", app.listStaffAttendanceHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin", "manager"))
+				r.Get("/report", app.staffAttendanceReportHandler)
+				r.Get("/devices", app.listStaffDevicesHandler)
+				r.Post("/devices", app.registerStaffDeviceHandler)
+				r.Delete("/devices/{deviceID}", app.deleteStaffDeviceHandler)
+			})
+		})
+
 		r.Route("/attendance", func(r chi.Router) {
+			r.With(app.AuthTokenMiddleware, app.requireRole("student", "teacher")).Post("/checkin", app.mobileCheckInHandler)
+			r.With(app.AuthTokenMiddleware, app.requireRole("admin", "manager")).Get("/checkins", app.listCheckInsHandler)
+
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 				r.Use(app.requireRole("admin", "manager", "teacher"))
@@ -172,9 +572,402 @@ func (app *application) mount() http.Handler {
 				r.Post("/bulk", app.bulkMarkAttendanceHandler)
 				r.Get("/students/{studentID}", app.getAttendanceByStudentHandler)
 				r.Get("/classrooms/{classroomID}", app.getAttendanceByClassroomDateHandler)
+				r.Get("/corrections", app.listAttendanceCorrectionsHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Use(app.requireRole("admin", "manager"))
+				r.Post("/import", app.importAttendanceHandler)
+				r.Get("/import/{jobID}", app.getAttendanceImportHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.With(app.requireRole("teacher")).Post("/corrections", app.createAttendanceCorrectionHandler)
+				r.With(app.requireRole("admin", "manager")).Post("/corrections/{correctionID}/approve", app.approveAttendanceCorrectionHandler)
+				r.With(app.requireRole("admin", "manager")).Post("/corrections/{correctionID}/reject", app.rejectAttendanceCorrectionHandler)
+			})
+		})
+
+		r.Route("/counseling", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager", "teacher")).Post("/referrals", app.createCounselingReferralHandler)
+			r.With(app.requireRole("admin", "manager")).Get("/stats", app.getCounselingStatsHandler)
+
+			// notes are for counselors alone, whatever the caller's role
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin", "manager", "teacher"))
+				r.Use(app.requireGrant(permCounselingRead))
+				r.Get("/referrals", app.listCounselingReferralsHandler)
+				r.With(app.studentsContextMiddleware).Get("/students/{studentID}/sessions", app.listCounselingSessionsHandler)
+				r.With(app.studentsContextMiddleware).Post("/students/{studentID}/sessions", app.createCounselingSessionHandler)
+			})
+		})
+
+		r.Route("/lost-found", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager", "teacher"))
+			r.Get("/", app.listLostItemsHandler)
+			r.Post("/", app.createLostItemHandler)
+			r.Get("/{itemID}", app.getLostItemHandler)
+			r.Post("/{itemID}/match", app.matchLostItemHandler)
+			r.Post("/{itemID}/claim", app.claimLostItemHandler)
+			r.Put("/{itemID}/photo", app.putLostItemPhotoHandler)
+			r.Get("/{itemID}/photo", app.getLostItemPhotoHandler)
+			r.With(app.requireRole("admin", "manager")).Delete("/{itemID}", app.deleteLostItemHandler)
+		})
+
+		r.Route("/seating-charts/{classroomID}", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager", "teacher"))
+			r.Use(app.classroomsContextMiddleware)
+			r.Get("/", app.getSeatingChartHandler)
+			r.Put("/", app.putSeatingChartHandler)
+			r.Delete("/", app.deleteSeatingChartHandler)
+		})
+
+		r.Route("/online", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager", "teacher"), app.classroomsContextMiddleware).
+				Post("/classrooms/{classroomID}/sessions", app.startOnlineSessionHandler)
+
+			r.Route("/sessions/{sessionID}", func(r chi.Router) {
+				r.Use(app.onlineSessionContextMiddleware)
+				r.With(app.requireRole("admin", "manager", "teacher")).Post("/close", app.closeOnlineSessionHandler)
+				r.With(app.requireRole("student")).Post("/join", app.joinOnlineSessionHandler)
+			})
+		})
+
+		r.Route("/points", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager", "teacher")).Get("/categories", app.listPointCategoriesHandler)
+			r.With(app.requireRole("admin")).Post("/categories", app.createPointCategoryHandler)
+			r.With(app.requireRole("admin")).Patch("/categories/{categoryID}", app.updatePointCategoryHandler)
+			r.With(app.requireRole("admin", "manager", "teacher")).Post("/", app.awardPointsHandler)
+			r.With(app.requireRole("admin", "manager", "teacher", "student"), app.studentsContextMiddleware).
+				Get("/students/{studentID}", app.getStudentPointsHandler)
+			r.With(app.requireRole("admin", "manager", "teacher"), app.classroomsContextMiddleware).
+				Get("/classrooms/{classroomID}/leaderboard", app.getClassroomLeaderboardHandler)
+		})
+
+		r.Route("/surveys", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager")).Post("/", app.createSurveyHandler)
+			r.With(app.requireRole("admin", "manager")).Get("/", app.listSurveysHandler)
+
+			r.Route("/{surveyID}", func(r chi.Router) {
+				r.Use(app.surveyContextMiddleware)
+				r.With(app.requireRole("student", "teacher")).Post("/responses", app.submitSurveyResponseHandler)
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.requireRole("admin", "manager"))
+					r.Get("/", app.getSurveyHandler)
+					r.Patch("/", app.updateSurveyHandler)
+					r.Delete("/", app.deleteSurveyHandler)
+					r.Get("/results", app.getSurveyResultsHandler)
+				})
+			})
+		})
+
+		r.Route("/consents", func(r chi.Router) {
+			// PUBLIC: authorised by the link signature
+			r.Get("/respond/{consentID}", app.getParentConsentHandler)
+			r.Post("/respond/{consentID}", app.respondParentConsentHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.With(app.requireRole("admin", "manager", "teacher"), app.classroomsContextMiddleware).
+					Get("/classrooms/{classroomID}/matrix", app.getClassroomConsentMatrixHandler)
+
+				r.Group(func(r chi.Router) {
+					r.Use(app.requireRole("admin", "manager"))
+					r.Post("/", app.createConsentRequestHandler)
+					r.Get("/", app.listConsentRequestsHandler)
+					r.Get("/{requestID}", app.getConsentRequestHandler)
+					r.Delete("/{requestID}", app.deleteConsentRequestHandler)
+				})
+			})
+		})
+
+		r.Route("/pickups", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager", "teacher")).Post("/check", app.gateCheckHandler)
+			r.With(app.requireRole("admin", "manager", "teacher")).Get("/contacts/{contactID}/photo", app.getPickupContactPhotoHandler)
+			r.With(app.requireRole("admin", "manager")).Get("/checkouts", app.getPickupCheckoutsHandler)
+		})
+
+		r.Route("/assets", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/", app.listAssetsHandler)
+			r.Post("/", app.createAssetHandler)
+			r.Get("/overdue", app.getOverdueAssetsHandler)
+
+			r.Route("/{assetID}", func(r chi.Router) {
+				r.Use(app.assetContextMiddleware)
+				r.Get("/", app.getAssetHandler)
+				r.Patch("/", app.updateAssetHandler)
+				r.Post("/checkout", app.checkOutAssetHandler)
+				r.Post("/checkin", app.checkInAssetHandler)
+				r.Get("/history", app.getAssetHistoryHandler)
+			})
+		})
+
+		r.Route("/bookings", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager", "teacher"))
+			r.Post("/", app.createBookingHandler)
+			r.Delete("/{bookingID}", app.cancelBookingHandler)
+			r.Get("/resources", app.listBookingResourcesHandler)
+			r.With(app.requireRole("admin", "manager")).Post("/resources", app.createBookingResourceHandler)
+
+			r.Route("/resources/{resourceID}", func(r chi.Router) {
+				r.Use(app.bookingResourceContextMiddleware)
+				r.Get("/day", app.getResourceDayHandler)
+				r.With(app.requireRole("admin", "manager")).Patch("/", app.updateBookingResourceHandler)
 			})
 		})
 
+		r.Route("/calendar", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.requireRole("admin", "manager", "teacher")).Get("/", app.getCalendarHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin"))
+				r.With(app.longBudget).Post("/import", app.importCalendarHandler)
+				r.Put("/{date}", app.putCalendarDayHandler)
+				r.Delete("/{date}", app.deleteCalendarDayHandler)
+			})
+		})
+
+		r.Route("/lunch-menus", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.listLunchMenusHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin", "manager"))
+				r.Put("/{date}", app.putLunchMenuHandler)
+				r.Delete("/{date}", app.deleteLunchMenuHandler)
+			})
+		})
+
+		// PUBLIC: authorised by a widget token bound to the embedding origin
+		r.Route("/widgets", func(r chi.Router) {
+			r.With(app.widgetTokenMiddleware(widgetScopeCalendar)).Get("/calendar", app.getWidgetCalendarHandler)
+			r.With(app.widgetTokenMiddleware(widgetScopeLunchMenu)).Get("/lunch-menu", app.getWidgetLunchMenuHandler)
+		})
+
+		r.Route("/announcements", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+			r.Post("/broadcast", app.broadcastHandler)
+			r.Get("/broadcasts", app.listBroadcastsHandler)
+		})
+
+		r.Route("/sms", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/threads", app.getSMSThreadHandler)
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/", app.listTagsHandler)
+			r.Delete("/{tag}", app.deleteTagHandler)
+		})
+
+		r.Route("/search", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/", app.searchHandler)
+		})
+
+		r.Route("/me", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.With(app.longBudget).Get("/export", app.exportMeHandler)
+			r.Get("/recent", app.getMyRecentHandler)
+			r.Get("/activity", app.getMyActivityHandler)
+			r.With(app.requireRole("admin", "manager", "teacher", "student")).Put("/password", app.changePasswordHandler)
+			r.With(app.requireRole("teacher", "student")).Get("/classrooms", app.getMyClassroomsHandler)
+			r.With(app.requireRole("teacher", "student")).Get("/surveys", app.getMySurveysHandler)
+			r.With(app.requireRole("teacher", "student")).Get("/assets", app.getMyAssetsHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin", "manager", "teacher"))
+				r.Get("/payslips", app.listMyPayslipsHandler)
+				r.Get("/payslips/{payslipID}/pdf", app.getPayslipPDFHandler)
+				r.Get("/expenses", app.listMyExpensesHandler)
+				r.Post("/expenses", app.createExpenseHandler)
+				r.Put("/expenses/{expenseID}/receipt", app.putExpenseReceiptHandler)
+				r.Get("/expenses/{expenseID}/receipt", app.getExpenseReceiptHandler)
+			})
+		})
+
+		r.Route("/payroll", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin"))
+				r.Get("/contracts", app.listContractsHandler)
+				r.Post("/contracts", app.createContractHandler)
+				r.Post("/contracts/{contractID}/end", app.endContractHandler)
+				r.Get("/payslips", app.listPayslipsHandler)
+				r.With(app.longBudget).Post("/payslips/generate", app.generatePayslipsHandler)
+				r.Get("/payslips/{payslipID}/pdf", app.getPayslipPDFHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRole("admin", "manager"))
+				r.Get("/expenses", app.listExpensesHandler)
+				r.Get("/expenses/{expenseID}/receipt", app.getExpenseReceiptHandler)
+				r.Post("/expenses/{expenseID}/approve", app.reviewExpenseHandler(store.ExpenseApproved))
+				r.Post("/expenses/{expenseID}/reject", app.reviewExpenseHandler(store.ExpenseRejected))
+			})
+		})
+
+		r.Route("/exports", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/{jobID}", app.getExportHandler)
+			r.With(app.longBudget).Get("/{jobID}/download", app.downloadExportHandler)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.adminNetworkMiddleware)
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+			r.Get("/jobs/{jobID}", app.getJobHandler)
+			r.Get("/config", app.getConfigHandler)
+			r.Post("/config/reload", app.reloadConfigHandler)
+			r.Get("/maintenance", app.getMaintenanceHandler)
+			r.Post("/maintenance", app.setMaintenanceHandler)
+			r.With(app.longBudget).Post("/backups", app.createBackupHandler)
+			r.With(app.longBudget).Get("/backups", app.listBackupsHandler)
+			r.Get("/cache/stats", app.getCacheStatsHandler)
+			r.Get("/cache/keys", app.getCacheKeysHandler)
+			r.Delete("/cache", app.flushCacheHandler)
+			r.With(app.longBudget).Post("/cache/warm", app.warmCacheHandler)
+			r.With(app.longBudget).Post("/refresh-stats", app.refreshStatsHandler)
+			r.Get("/blocklist", app.listBlocklistHandler)
+			r.Post("/blocklist", app.addToBlocklistHandler)
+			r.Delete("/blocklist", app.removeFromBlocklistHandler)
+			r.Get("/ratelimit/top", app.getRateLimitTopHandler)
+			r.Post("/api-keys", app.createAPIKeyHandler)
+			r.Get("/api-keys", app.listAPIKeysHandler)
+			r.Patch("/api-keys/{apiKeyID}", app.updateAPIKeyQuotasHandler)
+			r.Delete("/api-keys/{apiKeyID}", app.revokeAPIKeyHandler)
+			r.Get("/api-keys/{apiKeyID}/usage", app.getAPIKeyUsageHandler)
+			r.Post("/widget-tokens", app.createWidgetTokenHandler)
+			r.Get("/widget-tokens", app.listWidgetTokensHandler)
+			r.Delete("/widget-tokens/{widgetTokenID}", app.revokeWidgetTokenHandler)
+			r.Get("/quarantine", app.listQuarantineHandler)
+			r.Delete("/quarantine/{uploadID}", app.deleteQuarantinedHandler)
+			r.Get("/accounts/inactive", app.listInactiveAccountsHandler)
+			r.Post("/accounts/inactive/deactivate", app.deactivateInactiveAccountsHandler)
+			r.Post("/accounts/{accountID}/reactivate", app.reactivateAccountHandler)
+			r.Put("/accounts/{accountID}/password", app.resetPasswordHandler)
+			r.Post("/accounts/force-password-change", app.forcePasswordChangeHandler)
+			r.Get("/permissions", app.listPermissionGrantsHandler)
+			r.Put("/accounts/{accountID}/permissions/{permission}", app.grantPermissionHandler)
+			r.Delete("/accounts/{accountID}/permissions/{permission}", app.revokePermissionHandler)
+			r.Get("/debug/vars", expvar.Handler().ServeHTTP)
+			r.Get("/debug/rules", app.listDebugRulesHandler)
+			r.Post("/debug/rules", app.createDebugRuleHandler)
+			r.Delete("/debug/rules/{ruleID}", app.deleteDebugRuleHandler)
+			r.Get("/debug/requests", app.listDebugExchangesHandler)
+			r.Delete("/debug/requests", app.clearDebugExchangesHandler)
+		})
+
+		r.Route("/analytics", func(r chi.Router) {
+			r.Use(workload(store.WorkloadReporting))
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/attendance", app.getAttendanceTrendsHandler)
+			r.Get("/attendance/compliance", app.getAttendanceComplianceHandler)
+			r.Get("/grades", app.getGradeAveragesHandler)
+			r.With(app.teachersContextMiddleware).Get("/teachers/{teacherID}", app.getTeacherPerformanceHandler)
+		})
+
+		r.Route("/reports", func(r chi.Router) {
+			r.Use(workload(store.WorkloadReporting))
+			// PUBLIC: authorised by the link signature
+			r.With(app.longBudget).Get("/runs/{runID}/download", app.downloadReportRunHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Use(app.requireRole("admin", "manager"))
+				r.Post("/schedules", app.createReportScheduleHandler)
+				r.Get("/schedules", app.listReportSchedulesHandler)
+				r.Patch("/schedules/{scheduleID}", app.updateReportScheduleHandler)
+				r.Delete("/schedules/{scheduleID}", app.deleteReportScheduleHandler)
+				r.Post("/schedules/{scheduleID}/run", app.runReportScheduleHandler)
+				r.Get("/schedules/{scheduleID}/runs", app.listReportRunsHandler)
+			})
+		})
+
+		r.Route("/ministry/reports", func(r chi.Router) {
+			r.Use(workload(store.WorkloadReporting))
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+			r.Get("/", app.listMinistryReportsHandler)
+			r.With(app.longBudget).Get("/{report}", app.downloadMinistryReportHandler)
+		})
+
+		r.Route("/terms", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin", "manager"))
+			r.Get("/", app.listTermsHandler)
+			r.Post("/", app.createTermHandler)
+			r.Post("/{termID}/close", app.closeTermHandler)
+			r.With(app.longBudget).Post("/{termID}/archive", app.archiveTermHandler)
+		})
+
+		r.Route("/trash", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+			r.Get("/", app.getTrashHandler)
+			r.Post("/{entity}/{id}/restore", app.restoreTrashHandler)
+			r.Delete("/{entity}/{id}", app.purgeTrashHandler)
+		})
+
+	})
+
+	// v2 shares v1's handlers and tokens; see v2.go for what differs
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Use(app.requireRole("admin", "manager"))
+
+		r.Get("/execs", v2List(app.listExecs))
+		r.With(app.execsContextMiddleware).Get("/execs/{execID}", app.getExecHandler)
+		r.Get("/teachers", v2List(app.listTeachers))
+		r.With(app.teachersContextMiddleware).Get("/teachers/{teacherID}", app.getTeacherHandler)
+		r.Get("/students", v2List(app.listStudents))
+		r.With(app.studentsContextMiddleware).Get("/students/{studentID}", app.getStudentHandler)
+		r.Get("/classrooms", v2List(app.listClassrooms))
+		r.With(app.classroomsContextMiddleware).Get("/classrooms/{classroomID}", app.getClassroomHandler)
+	})
+
+	// OneRoster 1.1 read-only facade for third-party ed-tech tools
+	r.Route(oneRosterBase, func(r chi.Router) {
+		r.Use(app.AuthTokenMiddleware)
+		r.Use(app.requireRole("admin", "manager"))
+		r.Get("/orgs", app.oneRosterOrgsHandler)
+		r.Get("/orgs/{sourcedId}", app.oneRosterOrgHandler)
+		r.Get("/schools/{sourcedId}", app.oneRosterOrgHandler)
+		r.Get("/classes", app.oneRosterClassesHandler(false))
+		r.Get("/classes/{sourcedId}", app.oneRosterClassHandler)
+		r.Get("/classes/{sourcedId}/students", app.oneRosterUsersHandler("student", true))
+		r.Get("/classes/{sourcedId}/teachers", app.oneRosterUsersHandler("teacher", true))
+		r.Get("/users", app.oneRosterUsersHandler("", false))
+		r.Get("/users/{sourcedId}", app.oneRosterUserHandler(""))
+		r.Get("/students", app.oneRosterUsersHandler("student", false))
+		r.Get("/students/{sourcedId}", app.oneRosterUserHandler("student"))
+		r.Get("/teachers", app.oneRosterUsersHandler("teacher", false))
+		r.Get("/teachers/{sourcedId}", app.oneRosterUserHandler("teacher"))
+		r.Get("/teachers/{sourcedId}/classes", app.oneRosterClassesHandler(true))
+		r.Get("/enrollments", app.oneRosterEnrollmentsHandler)
+		r.Get("/enrollments/{sourcedId}", app.oneRosterEnrollmentHandler)
 	})
 
 	return r
@@ -188,11 +981,21 @@ func (app *application) run(mux http.Handler) error {
 	docs.SwaggerInfo.BasePath = "/v1"
 
 	srv := &http.Server{
-		Addr:         app.config.addr,
-		Handler:      mux,
-		WriteTimeout: 30 * time.Second,
-		ReadTimeout:  10 * time.Second,
-		IdleTimeout:  time.Minute,
+		Addr:           app.config.addr,
+		Handler:        mux,
+		WriteTimeout:   app.config.server.writeTimeout,
+		ReadTimeout:    app.config.server.readTimeout,
+		IdleTimeout:    app.config.server.idleTimeout,
+		MaxHeaderBytes: app.config.server.maxHeaderBytes,
+	}
+
+	// h2c serves HTTP/2 without TLS, for when a proxy terminates TLS and
+	// speaks HTTP/2 to the backend.
+	if app.config.server.h2c {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = &protocols
 	}
 
 	shutdown := make(chan error)
@@ -203,11 +1006,24 @@ func (app *application) run(mux http.Handler) error {
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 		s := <-quit
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// one budget for draining requests, analytics and background jobs
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.server.shutdownTimeout)
 		defer cancel()
 
-		app.logger.Infow("signal caught", "signal", s.String())
-		shutdown <- srv.Shutdown(ctx)
+		app.logger.Infow("signal caught", "signal", s.String(), "timeout", app.config.server.shutdownTimeout.String())
+		if err := srv.Shutdown(ctx); err != nil {
+			shutdown <- err
+			return
+		}
+		if app.analytics != nil {
+			if err := app.analytics.Close(ctx); err != nil {
+				app.logger.Warnw("flushing analytics events failed", "error", err.Error())
+			}
+		}
+		if err := app.jobs.Drain(ctx); err != nil {
+			app.logger.Warnw("background jobs cancelled before finishing", "error", err.Error())
+		}
+		shutdown <- nil
 	}()
 
 	app.logger.Infow("server started", "addr", app.config.addr, "env", app.config.env)
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// apiKeyHeader carries an API key in place of a bearer token.
+	apiKeyHeader = "X-API-Key"
+	// apiKeyPrefixLen is how much of a key is kept in clear to tell keys
+	// apart.
+	apiKeyPrefixLen = 12
+)
+
+type CreateAPIKeyPayload struct {
+	Name string `json:"name" validate:"required,max=100" example:"Moodle sync"`
+	// ExecID owns the key, which acts with their role and scope; the
+	// caller when zero.
+	ExecID       int64 `json:"exec_id" validate:"min=0" example:"1"`
+	DailyQuota   int64 `json:"daily_quota" validate:"min=0" example:"10000"`
+	MonthlyQuota int64 `json:"monthly_quota" validate:"min=0" example:"200000"`
+}
+
+type UpdateAPIKeyQuotasPayload struct {
+	DailyQuota   int64 `json:"daily_quota" validate:"min=0" example:"10000"`
+	MonthlyQuota int64 `json:"monthly_quota" validate:"min=0" example:"200000"`
+}
+
+// CreatedAPIKey is a new key with its secret, shown this once.
+type CreatedAPIKey struct {
+	*dto.APIKey
+	Key string `json:"key"`
+}
+
+// QuotaPeriod is an API key's use of one quota.
+type QuotaPeriod struct {
+	Used int64 `json:"used"`
+	// Limit is 0 for unlimited.
+	Limit    int64     `json:"limit"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// APIKeyUsage is an API key's use of its quotas in the current day and
+// month.
+type APIKeyUsage struct {
+	APIKeyID int64       `json:"api_key_id"`
+	Day      QuotaPeriod `json:"day"`
+	Month    QuotaPeriod `json:"month"`
+}
+
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// quotaPeriods names the current day and month in the school's time zone,
+// which quotas count in, and when each ends.
+func (app *application) quotaPeriods() (day, month string, dayEnd, monthEnd time.Time) {
+	now := app.school.now()
+	y, m, d := now.Date()
+	return now.Format(time.DateOnly), now.Format("2006-01"),
+		time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()),
+		time.Date(y, m+1, 1, 0, 0, 0, 0, now.Location())
+}
+
+// apiKeyClaims authenticates a request made with an API key as the exec
+// owning it and counts it against the key's quotas. Otherwise it writes a
+// 401, or a 429 with X-Quota-Day-* or X-Quota-Month-* and Retry-After
+// headers once a quota is used up. Keys don't reach /v1/admin, so a leaked one cannot mint more.
+func (app *application) apiKeyClaims(w http.ResponseWriter, r *http.Request, key string) (*auth.Claims, bool) {
+	if strings.HasPrefix(r.URL.Path, "/v1/admin/") {
+		app.forbiddenResponse(w, r)
+		return nil, false
+	}
+
+	ctx := r.Context()
+	k, err := app.store.APIKeys.GetByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.unauthorizedResponse(w, r, fmt.Errorf("invalid API key"))
+			return nil, false
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return nil, false
+	}
+	exec, err := app.store.Execs.GetByID(ctx, k.ExecID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.unauthorizedResponse(w, r, fmt.Errorf("invalid API key"))
+			return nil, false
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if k.DailyQuota > 0 || k.MonthlyQuota > 0 {
+		day, month, dayEnd, monthEnd := app.quotaPeriods()
+		usage, err := app.cacheStorage.Quotas.Count(ctx, k.ID, day, month)
+		if err != nil {
+			// an unreachable counter must not take integrations down
+			app.logger.Warnw("counting API key quota failed", "api_key", k.ID, "error", err.Error())
+		}
+		for _, q := range []struct {
+			period      string
+			used, limit int64
+			end         time.Time
+		}{
+			{"Day", usage.Day, k.DailyQuota, dayEnd},
+			{"Month", usage.Month, k.MonthlyQuota, monthEnd},
+		} {
+			if q.limit == 0 {
+				continue
+			}
+			prefix := "X-Quota-" + q.period + "-"
+			w.Header().Set(prefix+"Limit", strconv.FormatInt(q.limit, 10))
+			w.Header().Set(prefix+"Remaining", strconv.FormatInt(max(q.limit-q.used, 0), 10))
+			w.Header().Set(prefix+"Reset", strconv.FormatInt(q.end.Unix(), 10))
+			if q.used > q.limit {
+				app.quotaExceededResponse(w, r, k.ID, q.end)
+				return nil, false
+			}
+		}
+	}
+
+	return &auth.Claims{ID: exec.ID, Email: exec.Email, Role: string(exec.Role)}, true
+}
+
+// CreateAPIKey godoc
+//
+//	@Summary		Create an API key
+//	@Description	The key authenticates requests in the X-API-Key header as the exec owning it. Its secret is returned this once; only a hash is kept. Quotas of 0 are unlimited.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateAPIKeyPayload	true	"Key"
+//	@Success		201		{object}	CreatedAPIKey
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"No such exec"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/api-keys [post]
+//	@ID				createAPIKey
+func (app *application) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAPIKeyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if payload.ExecID == 0 {
+		payload.ExecID = getUser(r).ID
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	secret := "cnk_" + base64.RawURLEncoding.EncodeToString(b)
+
+	k := &store.APIKey{
+		Name:         payload.Name,
+		Prefix:       secret[:apiKeyPrefixLen],
+		ExecID:       payload.ExecID,
+		DailyQuota:   payload.DailyQuota,
+		MonthlyQuota: payload.MonthlyQuota,
+	}
+	if err := app.store.APIKeys.Create(r.Context(), k, hashAPIKey(secret)); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, errors.New("exec not found"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, &CreatedAPIKey{APIKey: dto.FromAPIKey(k), Key: secret}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListAPIKeys godoc
+//
+//	@Summary	List API keys, revoked ones included
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{array}	dto.APIKey
+//	@Security	ApiKeyAuth
+//	@Router		/admin/api-keys [get]
+//	@ID			listAPIKeys
+func (app *application) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := app.store.APIKeys.List(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAPIKeys(keys)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateAPIKeyQuotas godoc
+//
+//	@Summary	Change an API key's quotas
+//	@Tags		Admin
+//	@Accept		json
+//	@Produce	json
+//	@Param		apiKeyID	path		int							true	"API key ID"
+//	@Param		payload		body		UpdateAPIKeyQuotasPayload	true	"Quotas, 0 for unlimited"
+//	@Success	200			{object}	dto.APIKey
+//	@Failure	400			{object}	error
+//	@Failure	404			{object}	error	"No such live key"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/api-keys/{apiKeyID} [patch]
+//	@ID			updateAPIKeyQuotas
+func (app *application) updateAPIKeyQuotasHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "apiKeyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var payload UpdateAPIKeyQuotasPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	k, err := app.store.APIKeys.SetQuotas(r.Context(), id, payload.DailyQuota, payload.MonthlyQuota)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAPIKey(k)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RevokeAPIKey godoc
+//
+//	@Summary	Revoke an API key
+//	@Tags		Admin
+//	@Param		apiKeyID	path	int	true	"API key ID"
+//	@Success	204
+//	@Failure	404	{object}	error	"No such live key"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/api-keys/{apiKeyID} [delete]
+//	@ID			revokeAPIKey
+func (app *application) revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "apiKeyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.APIKeys.Revoke(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAPIKeyUsage godoc
+//
+//	@Summary		Show an API key's use of its quotas
+//	@Description	Requests made with the key today and this month, in the school's time zone, against its quotas. Needs Redis.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			apiKeyID	path		int	true	"API key ID"
+//	@Success		200			{object}	APIKeyUsage
+//	@Failure		404			{object}	error
+//	@Failure		503			{object}	error	"Redis is not configured"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/api-keys/{apiKeyID}/usage [get]
+//	@ID				getAPIKeyUsage
+func (app *application) getAPIKeyUsageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "apiKeyID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	k, err := app.store.APIKeys.GetByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	day, month, dayEnd, monthEnd := app.quotaPeriods()
+	usage, err := app.cacheStorage.Quotas.Usage(ctx, k.ID, day, month)
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrUnavailable):
+			app.serviceUnavailableResponse(w, r, errors.New("quota usage needs Redis"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	resp := &APIKeyUsage{
+		APIKeyID: k.ID,
+		Day:      QuotaPeriod{Used: usage.Day, Limit: k.DailyQuota, ResetsAt: dayEnd},
+		Month:    QuotaPeriod{Used: usage.Month, Limit: k.MonthlyQuota, ResetsAt: monthEnd},
+	}
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
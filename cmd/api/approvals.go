@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// Actions that APPROVAL_REQUIRED_ACTIONS can put behind a second admin.
+const (
+	// approvalStudentDelete is deleting a student who has attendance,
+	// points, consents, pickups, loans or notes.
+	approvalStudentDelete = "student_delete"
+	// approvalFeeWaiver is giving a student a discount override of at
+	// least APPROVAL_WAIVER_PERCENT.
+	approvalFeeWaiver = "fee_waiver"
+)
+
+// approvalActions carry out staged actions once they are approved, from
+// the params they were staged with.
+var approvalActions = map[string]func(ctx context.Context, app *application, a *store.Approval) error{
+	approvalStudentDelete: func(ctx context.Context, app *application, a *store.Approval) error {
+		var p studentDeleteParams
+		if err := json.Unmarshal(a.Params, &p); err != nil {
+			return err
+		}
+		return app.store.Students.Delete(ctx, p.StudentID, a.RequestedBy)
+	},
+	approvalFeeWaiver: func(ctx context.Context, app *application, a *store.Approval) error {
+		var override store.StudentDiscount
+		if err := json.Unmarshal(a.Params, &override); err != nil {
+			return err
+		}
+		override.CreatedBy = &a.RequestedBy
+		return app.store.Discounts.SetOverride(ctx, &override)
+	},
+}
+
+type studentDeleteParams struct {
+	StudentID int64 `json:"student_id"`
+}
+
+// parseApprovalActions reads APPROVAL_REQUIRED_ACTIONS, a comma-separated
+// list of action names.
+func parseApprovalActions(s string) (map[string]bool, error) {
+	actions := map[string]bool{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := approvalActions[name]; !ok {
+			return nil, fmt.Errorf("unknown approval action %q", name)
+		}
+		actions[name] = true
+	}
+	return actions, nil
+}
+
+// stageApproval stages action for a second admin when it is one that needs
+// approval, answering 202 with the staged request, and reports whether it
+// did. Otherwise the caller carries the action out itself.
+func (app *application) stageApproval(w http.ResponseWriter, r *http.Request, action, summary string, params any) bool {
+	if !app.approvals[action] {
+		return false
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return true
+	}
+	a := &store.Approval{
+		Action:      action,
+		Summary:     summary,
+		Params:      data,
+		RequestedBy: getUser(r).ID,
+	}
+	if err := app.store.Approvals.Create(r.Context(), a); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return true
+	}
+	app.logger.Infow("action staged for approval", "id", a.ID, "action", action, "requested_by", a.RequestedBy)
+
+	if err := app.jsonResponse(w, http.StatusAccepted, dto.FromApproval(a)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+	return true
+}
+
+type ReviewApprovalPayload struct {
+	Note string `json:"note" validate:"max=500" example:"Checked with the family"`
+}
+
+// ListApprovals godoc
+//
+//	@Summary	List staged actions awaiting or past approval
+//	@Tags		Approvals
+//	@Produce	json
+//	@Param		status	query		string	false	"pending, approved, rejected or failed"
+//	@Success	200		{array}		dto.Approval
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/approvals [get]
+//	@ID			listApprovals
+func (app *application) listApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.ApprovalPending, store.ApprovalApproved, store.ApprovalRejected, store.ApprovalFailed:
+	default:
+		app.badRequestResponse(w, r, errors.New("status must be pending, approved, rejected or failed"))
+		return
+	}
+
+	list, err := app.store.Approvals.List(r.Context(), status)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromApprovals(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ApproveAction godoc
+//
+//	@Summary		Approve a staged action
+//	@Description	Carries the action out. Only an admin other than the one who staged it may approve; if the action then fails, the approval is marked failed with the reason.
+//	@Tags			Approvals
+//	@Accept			json
+//	@Produce		json
+//	@Param			approvalID	path		int						true	"Approval ID"
+//	@Param			payload		body		ReviewApprovalPayload	false	"Review note"
+//	@Success		200			{object}	dto.Approval
+//	@Failure		403			{object}	error	"Staged by the caller"
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error	"Already reviewed, or the action failed"
+//	@Security		ApiKeyAuth
+//	@Router			/approvals/{approvalID}/approve [post]
+//	@ID				approveAction
+func (app *application) approveActionHandler(w http.ResponseWriter, r *http.Request) {
+	a, payload, ok := app.approvalForReview(w, r)
+	if !ok {
+		return
+	}
+	if a.RequestedBy == getUser(r).ID {
+		writeError(w, r, http.StatusForbidden, "a second admin must approve this")
+		return
+	}
+
+	if !app.reviewApproval(w, r, a, store.ApprovalApproved, payload.Note) {
+		return
+	}
+
+	if err := approvalActions[a.Action](r.Context(), app, a); err != nil {
+		reason := err.Error()
+		if errors.Is(err, store.ErrNotFound) {
+			reason = "the record no longer exists"
+		}
+		if ferr := app.store.Approvals.Fail(r.Context(), a, reason); ferr != nil {
+			app.internalServerErrorResponse(w, r, ferr)
+			return
+		}
+		app.logger.Warnw("approved action failed", "id", a.ID, "action", a.Action, "error", err.Error())
+		app.conflictResponse(w, r, fmt.Errorf("approved, but the action failed: %s", reason))
+		return
+	}
+	app.logger.Infow("approved action carried out", "id", a.ID, "action", a.Action, "approved_by", *a.ReviewedBy)
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromApproval(a)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RejectAction godoc
+//
+//	@Summary		Reject a staged action
+//	@Description	Any admin may reject, including the one who staged it to withdraw it.
+//	@Tags			Approvals
+//	@Accept			json
+//	@Produce		json
+//	@Param			approvalID	path		int						true	"Approval ID"
+//	@Param			payload		body		ReviewApprovalPayload	false	"Review note"
+//	@Success		200			{object}	dto.Approval
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error	"Already reviewed"
+//	@Security		ApiKeyAuth
+//	@Router			/approvals/{approvalID}/reject [post]
+//	@ID				rejectAction
+func (app *application) rejectActionHandler(w http.ResponseWriter, r *http.Request) {
+	a, payload, ok := app.approvalForReview(w, r)
+	if !ok {
+		return
+	}
+	if !app.reviewApproval(w, r, a, store.ApprovalRejected, payload.Note) {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromApproval(a)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// approvalForReview loads the approval in the URL and reads the review
+// note. It writes the error response and returns false on failure.
+func (app *application) approvalForReview(w http.ResponseWriter, r *http.Request) (*store.Approval, ReviewApprovalPayload, bool) {
+	var payload ReviewApprovalPayload
+	id, err := strconv.ParseInt(chi.URLParam(r, "approvalID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, payload, false
+	}
+
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return nil, payload, false
+		}
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, payload, false
+	}
+
+	a, err := app.store.Approvals.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return nil, payload, false
+	}
+	return a, payload, true
+}
+
+func (app *application) reviewApproval(w http.ResponseWriter, r *http.Request, a *store.Approval, status, note string) bool {
+	if err := app.store.Approvals.Review(r.Context(), a, status, getUser(r).ID, note); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("approval has already been reviewed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type assetKey string
+
+const assetCtx assetKey = "asset"
+
+type CreateAssetPayload struct {
+	Tag          string `json:"tag" validate:"required,max=64"`
+	Name         string `json:"name" validate:"required,max=200"`
+	Category     string `json:"category" validate:"required,oneof=laptop tablet lab other" enums:"laptop,tablet,lab,other" example:"laptop"`
+	SerialNumber string `json:"serial_number" validate:"max=128"`
+	Notes        string `json:"notes" validate:"max=2000"`
+}
+
+type UpdateAssetPayload struct {
+	Tag          *string `json:"tag,omitempty" validate:"omitempty,max=64"`
+	Name         *string `json:"name,omitempty" validate:"omitempty,max=200"`
+	Category     *string `json:"category,omitempty" validate:"omitempty,oneof=laptop tablet lab other" enums:"laptop,tablet,lab,other" example:"laptop"`
+	SerialNumber *string `json:"serial_number,omitempty" validate:"omitempty,max=128"`
+	Status       *string `json:"status,omitempty" validate:"omitempty,oneof=available maintenance retired" enums:"available,maintenance,retired" example:"available"`
+	Notes        *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+type AssetCheckOutPayload struct {
+	HolderType string `json:"holder_type" validate:"required,oneof=classroom teacher student" enums:"classroom,teacher,student" example:"classroom"`
+	HolderID   int64  `json:"holder_id" validate:"required,min=1"`
+	DueDate    string `json:"due_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Condition  string `json:"condition,omitempty" validate:"max=500"`
+}
+
+type AssetCheckInPayload struct {
+	Condition string `json:"condition,omitempty" validate:"max=500"`
+}
+
+// ListAssets godoc
+//
+//	@Summary	List school assets
+//	@Tags		Assets
+//	@Produce	json
+//	@Param		status		query	string	false	"available, checked_out, maintenance or retired"
+//	@Param		category	query	string	false	"laptop, tablet, lab or other"
+//	@Success	200			{array}	dto.Asset
+//	@Security	ApiKeyAuth
+//	@Router		/assets [get]
+//	@ID			listAssets
+func (app *application) listAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	assets, err := app.store.Assets.List(r.Context(), r.URL.Query().Get("status"), r.URL.Query().Get("category"))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAssets(assets)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreateAsset godoc
+//
+//	@Summary	Add an asset to the inventory
+//	@Tags		Assets
+//	@Accept		json
+//	@Produce	json
+//	@Param		payload	body		CreateAssetPayload	true	"Asset"
+//	@Success	201		{object}	dto.Asset
+//	@Failure	400		{object}	error
+//	@Failure	409		{object}	error	"Tag already in use"
+//	@Security	ApiKeyAuth
+//	@Router		/assets [post]
+//	@ID			createAsset
+func (app *application) createAssetHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAssetPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	asset := &store.Asset{
+		Tag:          payload.Tag,
+		Name:         payload.Name,
+		Category:     payload.Category,
+		SerialNumber: payload.SerialNumber,
+		Notes:        payload.Notes,
+	}
+	if err := app.store.Assets.Create(r.Context(), asset); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromAsset(asset)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetAsset godoc
+//
+//	@Summary	Get an asset and its current loan
+//	@Tags		Assets
+//	@Produce	json
+//	@Param		assetID	path		int	true	"Asset ID"
+//	@Success	200		{object}	dto.Asset
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/assets/{assetID} [get]
+//	@ID			getAsset
+func (app *application) getAssetHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAsset(getAssetFromCtx(r))); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateAsset godoc
+//
+//	@Summary		Update an asset
+//	@Description	Status can move between available, maintenance and retired; checked-out assets must be checked in first.
+//	@Tags			Assets
+//	@Accept			json
+//	@Produce		json
+//	@Param			assetID	path		int					true	"Asset ID"
+//	@Param			payload	body		UpdateAssetPayload	true	"Changes"
+//	@Success		200		{object}	dto.Asset
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	error	"Tag in use or asset checked out"
+//	@Security		ApiKeyAuth
+//	@Router			/assets/{assetID} [patch]
+//	@ID				updateAsset
+func (app *application) updateAssetHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateAssetPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	asset := getAssetFromCtx(r)
+	if payload.Tag != nil {
+		asset.Tag = *payload.Tag
+	}
+	if payload.Name != nil {
+		asset.Name = *payload.Name
+	}
+	if payload.Category != nil {
+		asset.Category = *payload.Category
+	}
+	if payload.SerialNumber != nil {
+		asset.SerialNumber = *payload.SerialNumber
+	}
+	if payload.Status != nil {
+		asset.Status = *payload.Status
+	}
+	if payload.Notes != nil {
+		asset.Notes = *payload.Notes
+	}
+
+	if err := app.store.Assets.Update(r.Context(), asset); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAsset(asset)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CheckOutAsset godoc
+//
+//	@Summary	Check an asset out to a classroom, teacher or student
+//	@Tags		Assets
+//	@Accept		json
+//	@Produce	json
+//	@Param		assetID	path		int						true	"Asset ID"
+//	@Param		payload	body		AssetCheckOutPayload	true	"Holder"
+//	@Success	201		{object}	dto.AssetLoan
+//	@Failure	400		{object}	error
+//	@Failure	404		{object}	error	"Holder not found"
+//	@Failure	409		{object}	error	"Asset not available"
+//	@Security	ApiKeyAuth
+//	@Router		/assets/{assetID}/checkout [post]
+//	@ID			checkOutAsset
+func (app *application) checkOutAssetHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AssetCheckOutPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	loan := &store.AssetLoan{
+		AssetID:      getAssetFromCtx(r).ID,
+		HolderType:   payload.HolderType,
+		HolderID:     payload.HolderID,
+		CheckedOutBy: getUser(r).ID,
+		ConditionOut: payload.Condition,
+	}
+	if payload.DueDate != "" {
+		due, _ := time.Parse(time.DateOnly, payload.DueDate)
+		if due.Before(app.schoolToday()) {
+			app.badRequestResponse(w, r, fmt.Errorf("due_date is in the past"))
+			return
+		}
+		loan.DueDate = &due
+	}
+
+	name, err := app.assetHolderName(r.Context(), payload.HolderType, payload.HolderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	loan.HolderName = name
+
+	if err := app.store.Assets.CheckOut(r.Context(), loan); err != nil {
+		switch {
+		case errors.Is(err, store.ErrAssetUnavailable):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromAssetLoan(loan)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CheckInAsset godoc
+//
+//	@Summary	Check a returned asset back in
+//	@Tags		Assets
+//	@Accept		json
+//	@Produce	json
+//	@Param		assetID	path		int					true	"Asset ID"
+//	@Param		payload	body		AssetCheckInPayload	false	"Condition on return"
+//	@Success	200		{object}	dto.AssetLoan
+//	@Failure	404		{object}	error	"Asset is not checked out"
+//	@Security	ApiKeyAuth
+//	@Router		/assets/{assetID}/checkin [post]
+//	@ID			checkInAsset
+func (app *application) checkInAssetHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AssetCheckInPayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if err := Validate.Struct(payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	loan, err := app.store.Assets.CheckIn(r.Context(), getAssetFromCtx(r).ID, getUser(r).ID, payload.Condition)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAssetLoan(loan)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetAssetHistory godoc
+//
+//	@Summary	List an asset's check-outs, newest first
+//	@Tags		Assets
+//	@Produce	json
+//	@Param		assetID	path	int	true	"Asset ID"
+//	@Success	200		{array}	dto.AssetLoan
+//	@Security	ApiKeyAuth
+//	@Router		/assets/{assetID}/history [get]
+//	@ID			getAssetHistory
+func (app *application) getAssetHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	loans, err := app.store.Assets.History(r.Context(), getAssetFromCtx(r).ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAssetLoans(loans)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetOverdueAssets godoc
+//
+//	@Summary		List assets past their return date
+//	@Description	Open loans whose due date is before today in the school's time zone, most overdue first.
+//	@Tags			Assets
+//	@Produce		json
+//	@Success		200	{array}	dto.OverdueLoan
+//	@Security		ApiKeyAuth
+//	@Router			/assets/overdue [get]
+//	@ID				getOverdueAssets
+func (app *application) getOverdueAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	overdue, err := app.store.Assets.Overdue(r.Context(), app.schoolToday())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromOverdueLoans(overdue)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetMyAssets godoc
+//
+//	@Summary		List the assets the caller has checked out
+//	@Description	Teachers also see assets checked out to the classrooms they teach.
+//	@Tags			Assets
+//	@Produce		json
+//	@Success		200	{array}	dto.AssetLoan
+//	@Security		ApiKeyAuth
+//	@Router			/me/assets [get]
+//	@ID				getMyAssets
+func (app *application) getMyAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUser(r)
+	ctx := r.Context()
+
+	loans, err := app.store.Assets.ForHolder(ctx, user.Role, user.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if user.Role == "teacher" {
+		classrooms, err := app.store.Classrooms.GetByTeacherID(ctx, user.ID)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		for _, c := range classrooms {
+			held, err := app.store.Assets.ForHolder(ctx, "classroom", c.ID)
+			if err != nil {
+				app.internalServerErrorResponse(w, r, err)
+				return
+			}
+			loans = append(loans, held...)
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAssetLoans(loans)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// assetHolderName looks up the classroom, teacher or student an asset is
+// being checked out to. It returns store.ErrNotFound when there is none.
+func (app *application) assetHolderName(ctx context.Context, holderType string, id int64) (string, error) {
+	switch holderType {
+	case "classroom":
+		c, err := app.store.Classrooms.GetByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return c.Name, nil
+	case "teacher":
+		t, err := app.store.Teachers.GetByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return t.FirstName + " " + t.LastName, nil
+	case "student":
+		s, err := app.store.Students.GetByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return s.FirstName + " " + s.LastName, nil
+	}
+	return "", fmt.Errorf("unknown holder type %q", holderType)
+}
+
+func (app *application) assetContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "assetID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		asset, err := app.store.Assets.GetByID(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), assetCtx, asset)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getAssetFromCtx(r *http.Request) *store.Asset {
+	a, _ := r.Context().Value(assetCtx).(*store.Asset)
+	return a
+}
@@ -1,33 +1,36 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/MahdiiTaheri/classnama-backend/internal/analytics"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 	"github.com/go-chi/chi/v5"
 )
 
 type markAttendancePayload struct {
-	StudentID   int64   `json:"student_id" validate:"required"`
-	TeacherID   *int64  `json:"teacher_id,omitempty"`
-	ClassroomID *int64  `json:"classroom_id,omitempty"`
-	Date        string  `json:"date" validate:"required,datetime=2006-01-02"`
-	Status      string  `json:"status" validate:"required,oneof=present absent late excused"`
-	Note        *string `json:"note,omitempty"`
+	StudentID   int64   `json:"student_id" validate:"required" example:"42"`
+	TeacherID   *int64  `json:"teacher_id,omitempty" example:"7"`
+	ClassroomID *int64  `json:"classroom_id,omitempty" example:"3"`
+	Date        string  `json:"date" validate:"required,datetime=2006-01-02" example:"2026-10-16"`
+	Status      string  `json:"status" validate:"required,oneof=present absent late excused" enums:"present,absent,late,excused" example:"present"`
+	Note        *string `json:"note,omitempty" example:"Doctor's appointment"`
 }
 
 type bulkAttendanceItem struct {
-	StudentID int64  `json:"student_id" validate:"required"`
-	Status    string `json:"status" validate:"required,oneof=present absent late excused"`
+	StudentID int64  `json:"student_id" validate:"required" example:"42"`
+	Status    string `json:"status" validate:"required,oneof=present absent late excused" enums:"present,absent,late,excused" example:"present"`
 }
 
 type bulkAttendancePayload struct {
-	ClassroomID int64                `json:"classroom_id" validate:"required"`
-	Date        string               `json:"date" validate:"required,datetime=2006-01-02"`
+	ClassroomID int64                `json:"classroom_id" validate:"required" example:"3"`
+	Date        string               `json:"date" validate:"required,datetime=2006-01-02" example:"2026-10-16"`
 	Statuses    []bulkAttendanceItem `json:"statuses" validate:"required,dive"`
 }
 
@@ -39,8 +42,9 @@ type bulkAttendancePayload struct {
 //	@Accept		json
 //	@Produce	json
 //	@Param		payload	body		markAttendancePayload	true	"Attendance payload"
-//	@Success	201		{object}	store.AttendanceRecord
+//	@Success	201		{object}	dto.AttendanceRecord
 //	@Failure	400		{object}	error
+//	@Failure	403		{object}	error	"Date is past the teacher's edit window, or outside the caller's scope"
 //	@Failure	500		{object}	error
 //	@Security	ApiKeyAuth
 //	@Router		/attendance [post]
@@ -62,6 +66,22 @@ func (app *application) markAttendanceHandler(w http.ResponseWriter, r *http.Req
 		app.badRequestResponse(w, r, fmt.Errorf("invalid date format; expected YYYY-MM-DD"))
 		return
 	}
+	if !app.requireSchoolDay(w, r, dt) {
+		return
+	}
+	if !app.requireAttendanceOpen(w, r, dt) {
+		return
+	}
+	ok, err := app.studentInScope(r, payload.StudentID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
+	if payload.ClassroomID != nil {
+		ok, err := app.classroomInScope(r, *payload.ClassroomID)
+		if !app.checkScope(w, r, ok, err) {
+			return
+		}
+	}
 
 	rec := &store.AttendanceRecord{
 		StudentID:   payload.StudentID,
@@ -77,7 +97,17 @@ func (app *application) markAttendanceHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, rec); err != nil {
+	app.track(analytics.EventAttendanceMarked, getUser(r), map[string]any{
+		"classroom_id": payload.ClassroomID,
+		"date":         payload.Date,
+		"records":      1,
+		payload.Status: 1,
+	})
+	if rec.Status == "absent" {
+		app.notifyAbsences(r, dt, []int64{rec.StudentID})
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromAttendanceRecord(rec)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -93,6 +123,7 @@ func (app *application) markAttendanceHandler(w http.ResponseWriter, r *http.Req
 //	@Param		payload	body	bulkAttendancePayload	true	"Bulk attendance payload"
 //	@Success	204
 //	@Failure	400	{object}	error
+//	@Failure	403	{object}	error	"Date is past the teacher's edit window, or outside the caller's scope"
 //	@Failure	500	{object}	error
 //	@Security	ApiKeyAuth
 //	@Router		/attendance/bulk [post]
@@ -113,9 +144,24 @@ func (app *application) bulkMarkAttendanceHandler(w http.ResponseWriter, r *http
 		app.badRequestResponse(w, r, fmt.Errorf("invalid date format; expected YYYY-MM-DD"))
 		return
 	}
+	if !app.requireSchoolDay(w, r, dt) {
+		return
+	}
+	if !app.requireAttendanceOpen(w, r, dt) {
+		return
+	}
+
+	ok, err := app.classroomInScope(r, payload.ClassroomID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
 
 	statusMap := make(map[int64]string, len(payload.Statuses))
 	for _, it := range payload.Statuses {
+		ok, err := app.studentInScope(r, it.StudentID)
+		if !app.checkScope(w, r, ok, err) {
+			return
+		}
 		statusMap[it.StudentID] = it.Status
 	}
 
@@ -124,6 +170,25 @@ func (app *application) bulkMarkAttendanceHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	props := map[string]any{
+		"classroom_id": payload.ClassroomID,
+		"date":         payload.Date,
+		"records":      len(statusMap),
+	}
+	for _, status := range statusMap {
+		n, _ := props[status].(int)
+		props[status] = n + 1
+	}
+	app.track(analytics.EventAttendanceMarked, getUser(r), props)
+
+	absent := []int64{}
+	for sid, status := range statusMap {
+		if status == "absent" {
+			absent = append(absent, sid)
+		}
+	}
+	app.notifyAbsences(r, dt, absent)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -136,8 +201,9 @@ func (app *application) bulkMarkAttendanceHandler(w http.ResponseWriter, r *http
 //	@Param		studentID	path		int		true	"Student ID"
 //	@Param		from		query		string	false	"From date YYYY-MM-DD"
 //	@Param		to			query		string	false	"To date YYYY-MM-DD"
-//	@Success	200			{array}		store.AttendanceRecord
+//	@Success	200			{array}		dto.AttendanceRecord
 //	@Failure	400			{object}	error
+//	@Failure	403			{object}	error	"Student outside the caller's scope"
 //	@Failure	404			{object}	error
 //	@Failure	500			{object}	error
 //	@Security	ApiKeyAuth
@@ -150,6 +216,10 @@ func (app *application) getAttendanceByStudentHandler(w http.ResponseWriter, r *
 		app.badRequestResponse(w, r, fmt.Errorf("invalid student ID"))
 		return
 	}
+	ok, err := app.studentInScope(r, studentID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
 
 	q := r.URL.Query()
 	var from *time.Time
@@ -184,13 +254,13 @@ func (app *application) getAttendanceByStudentHandler(w http.ResponseWriter, r *
 
 	if len(records) == 0 {
 		// return empty array with 200 OR 404 based on your conventions; here return 200 empty list
-		if err := app.jsonResponse(w, http.StatusOK, []store.AttendanceRecord{}); err != nil {
+		if err := app.jsonResponse(w, http.StatusOK, []*dto.AttendanceRecord{}); err != nil {
 			app.internalServerErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, records); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAttendanceRecords(records)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -204,8 +274,9 @@ func (app *application) getAttendanceByStudentHandler(w http.ResponseWriter, r *
 //	@Produce	json
 //	@Param		classroomID	path		int		true	"Classroom ID"
 //	@Param		date		query		string	true	"Date YYYY-MM-DD"
-//	@Success	200			{array}		store.AttendanceRecord
+//	@Success	200			{array}		dto.AttendanceRecord
 //	@Failure	400			{object}	error
+//	@Failure	403			{object}	error	"Classroom outside the caller's scope"
 //	@Failure	500			{object}	error
 //	@Security	ApiKeyAuth
 //	@Router		/attendance/classrooms/{classroomID} [get]
@@ -217,6 +288,10 @@ func (app *application) getAttendanceByClassroomDateHandler(w http.ResponseWrite
 		app.badRequestResponse(w, r, fmt.Errorf("invalid classroom ID"))
 		return
 	}
+	ok, err := app.classroomInScope(r, classID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
 
 	q := r.URL.Query()
 	dateStr := q.Get("date")
@@ -240,8 +315,54 @@ func (app *application) getAttendanceByClassroomDateHandler(w http.ResponseWrite
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, records); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAttendanceRecords(records)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 }
+
+// attendanceLocked reports whether date is further back than the caller may
+// mark attendance for. Only teachers are limited; execs can always edit.
+func (app *application) attendanceLocked(r *http.Request, date time.Time) bool {
+	days := app.config.attendance.editDays
+	if days <= 0 || getUser(r).Role != "teacher" {
+		return false
+	}
+	y, m, d := app.schoolToday().AddDate(0, 0, -days).Date()
+	return date.Before(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+}
+
+// requireAttendanceOpen writes a 403 and returns false when the caller may
+// no longer mark attendance for date.
+func (app *application) requireAttendanceOpen(w http.ResponseWriter, r *http.Request, date time.Time) bool {
+	if !app.attendanceLocked(r, date) {
+		return true
+	}
+	writeError(w, r, http.StatusForbidden, fmt.Sprintf(
+		"attendance for %s is locked; file a correction request at /v1/attendance/corrections", date.Format(time.DateOnly)))
+	return false
+}
+
+// attendancePartitionsAhead is how many months of attendance partitions are
+// kept ready after the current one.
+const attendancePartitionsAhead = 3
+
+// startAttendancePartitions creates the upcoming monthly partitions of
+// attendance_records on startup and once a day after.
+func (app *application) startAttendancePartitions() {
+	ensure := func(now time.Time) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := app.store.Attendance.EnsurePartitions(ctx, now, attendancePartitionsAhead); err != nil {
+			app.logger.Errorw("creating attendance partitions failed", "error", err.Error())
+		}
+	}
+	ensure(time.Now().UTC())
+
+	ticker := time.NewTicker(24 * time.Hour)
+	go func() {
+		for now := range ticker.C {
+			ensure(now.UTC())
+		}
+	}()
+}
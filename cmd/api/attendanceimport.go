@@ -0,0 +1,226 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/jobs"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const attendanceImportJob = "attendance_import"
+
+const (
+	maxAttendanceImportRows  = 50_000
+	maxAttendanceImportBytes = 4 << 20
+)
+
+type attendanceImportJobResponse struct {
+	Job       *jobs.Job `json:"job"`
+	StatusURL string    `json:"status_url"`
+}
+
+// ImportAttendance godoc
+//
+//	@Summary		Import historical attendance from a legacy system
+//	@Description	Takes a CSV body of student_code,date,status[,note] rows, an optional header first, and imports it in a background job; poll status_url for its progress and result. Dates already recorded for a student are skipped, or overwritten with on_conflict=overwrite. With dry_run=true the rows are checked and counted but nothing is written. All rows are imported or none: the result lists the lines in error.
+//	@Tags			Attendance
+//	@Accept			text/csv
+//	@Produce		json
+//	@Param			dry_run		query		bool	false	"Only validate and count"
+//	@Param			on_conflict	query		string	false	"skip (default) or overwrite"
+//	@Success		202			{object}	attendanceImportJobResponse
+//	@Failure		400			{object}	error
+//	@Failure		503			{object}	error	"Job queue is busy"
+//	@Security		ApiKeyAuth
+//	@Router			/attendance/import [post]
+//	@ID				importAttendance
+func (app *application) importAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	dryRun := false
+	if v := qs.Get("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("dry_run must be true or false"))
+			return
+		}
+		dryRun = b
+	}
+	var overwrite bool
+	switch qs.Get("on_conflict") {
+	case "", "skip":
+	case "overwrite":
+		overwrite = true
+	default:
+		app.badRequestResponse(w, r, errors.New("on_conflict must be skip or overwrite"))
+		return
+	}
+
+	rows, rowErrors, err := readAttendanceCSV(http.MaxBytesReader(w, r.Body, maxAttendanceImportBytes), app.schoolToday())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := getUser(r)
+	scope := getScope(r)
+	job, err := app.jobs.Enqueue(attendanceImportJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		// rows in error leave nothing to write, but the rest are still
+		// checked so the result lists every problem at once
+		res, err := app.store.Attendance.Import(ctx, rows, overwrite, dryRun || len(rowErrors) > 0, scope, func(done int) {
+			jobs.SetProgress(ctx, int64(done), int64(len(rows)))
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.DryRun = dryRun
+		res.Rows += len(rowErrors)
+		res.Errors = append(res.Errors, rowErrors...)
+		slices.SortFunc(res.Errors, func(a, b store.AttendanceImportError) int { return cmp.Compare(a.Line, b.Line) })
+
+		if !dryRun && len(res.Errors) == 0 {
+			app.logger.Infow("attendance imported", "rows", res.Rows, "created", res.Created,
+				"overwritten", res.Overwritten, "skipped", res.Skipped, "by", user.ID)
+		}
+		return json.Marshal(dto.FromAttendanceImportResult(res))
+	})
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			writeJSONError(w, http.StatusServiceUnavailable, "import queue is busy, try again later")
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := attendanceImportJobResponse{Job: job, StatusURL: fmt.Sprintf("/v1/attendance/import/%s", job.ID)}
+	if err := app.jsonResponse(w, http.StatusAccepted, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetAttendanceImport godoc
+//
+//	@Summary		Get an attendance import's progress and result
+//	@Description	The job's progress counts rows written so far; once it is done, result holds the counts and any lines in error.
+//	@Tags			Attendance
+//	@Produce		json
+//	@Param			jobID	path		string	true	"Job ID"
+//	@Success		200		{object}	jobs.Job
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/attendance/import/{jobID} [get]
+//	@ID				getAttendanceImport
+func (app *application) getAttendanceImportHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := app.jobs.Get(chi.URLParam(r, "jobID"))
+	user := getUser(r)
+	if !ok || job.Kind != attendanceImportJob ||
+		(user.Role != "admin" && (job.OwnerID != user.ID || job.OwnerRole != user.Role)) {
+		app.notfoundResponse(w, r, errors.New("import not found"))
+		return
+	}
+
+	resp := struct {
+		*jobs.Job
+		Result json.RawMessage `json:"result,omitempty"`
+	}{Job: job}
+	if job.Status == jobs.StatusDone && json.Valid(job.Result()) {
+		resp.Result = job.Result()
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// readAttendanceCSV parses student_code,date,status[,note] rows. A first row
+// starting with "student_code" is treated as a header. Rows that can't be
+// imported, such as future dates or a student's date given twice, are
+// returned as errors; only a file that can't be read at all fails.
+func readAttendanceCSV(body io.Reader, today time.Time) ([]*store.AttendanceImportRow, []store.AttendanceImportError, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	rows := []*store.AttendanceImportRow{}
+	rowErrors := []store.AttendanceImportError{}
+	seen := map[string]int{}
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if first && strings.EqualFold(rec[0], "student_code") {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		if len(rows)+len(rowErrors) == maxAttendanceImportRows {
+			return nil, nil, fmt.Errorf("at most %d rows can be imported at once", maxAttendanceImportRows)
+		}
+
+		fail := func(format string, args ...any) {
+			rowErrors = append(rowErrors, store.AttendanceImportError{Line: line, Error: fmt.Sprintf(format, args...)})
+		}
+		if len(rec) < 3 || len(rec) > 4 {
+			fail("expected student_code,date,status[,note]")
+			continue
+		}
+		row := &store.AttendanceImportRow{Line: line, StudentCode: strings.TrimSpace(rec[0]), Status: strings.ToLower(strings.TrimSpace(rec[2]))}
+		if len(rec) == 4 {
+			row.Note = strings.TrimSpace(rec[3])
+		}
+
+		date, err := time.Parse(time.DateOnly, strings.TrimSpace(rec[1]))
+		switch {
+		case row.StudentCode == "":
+			fail("student_code is empty")
+			continue
+		case err != nil:
+			fail("invalid date %q; expected YYYY-MM-DD", rec[1])
+			continue
+		case date.Format(time.DateOnly) > today.Format(time.DateOnly):
+			fail("%s is in the future", rec[1])
+			continue
+		}
+		row.Date = date
+
+		switch row.Status {
+		case "present", "absent", "late", "excused":
+		default:
+			fail("status must be present, absent, late or excused")
+			continue
+		}
+		if len(row.Note) > 500 {
+			fail("note must be at most 500 characters")
+			continue
+		}
+
+		key := row.StudentCode + "|" + date.Format(time.DateOnly)
+		if first, ok := seen[key]; ok {
+			fail("same student and date as line %d", first)
+			continue
+		}
+		seen[key] = line
+		rows = append(rows, row)
+	}
+	if len(rows)+len(rowErrors) == 0 {
+		return nil, nil, errors.New("no rows to import")
+	}
+	return rows, rowErrors, nil
+}
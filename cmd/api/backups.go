@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/jobs"
+	"github.com/go-chi/chi/v5"
+)
+
+const backupJob = "db_backup"
+
+// CreateBackup godoc
+//
+//	@Summary		Trigger a database backup
+//	@Description	Schedules a pg_dump of the database and its upload to the configured S3 bucket
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		202	{object}	jobs.Job
+//	@Failure		503	{object}	error	"Backups not configured or queue busy"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/backups [post]
+//	@ID				createBackup
+func (app *application) createBackupHandler(w http.ResponseWriter, r *http.Request) {
+	if app.backup == nil {
+		app.serviceUnavailableResponse(w, r, fmt.Errorf("backups are not configured"))
+		return
+	}
+
+	user := getUser(r)
+	job, err := app.enqueueBackup(user.ID, user.Role)
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			app.serviceUnavailableResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, job); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListBackups godoc
+//
+//	@Summary	List stored database backups
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{array}		backup.Object
+//	@Failure	503	{object}	error	"Backups not configured"
+//	@Failure	500	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/admin/backups [get]
+//	@ID			listBackups
+func (app *application) listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.backup == nil {
+		app.serviceUnavailableResponse(w, r, fmt.Errorf("backups are not configured"))
+		return
+	}
+
+	objects, err := app.backup.List(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, objects); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetJob godoc
+//
+//	@Summary	Get the status of a background job
+//	@Tags		Admin
+//	@Produce	json
+//	@Param		jobID	path		string	true	"Job ID"
+//	@Success	200		{object}	jobs.Job
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/admin/jobs/{jobID} [get]
+//	@ID			getJob
+func (app *application) getJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := app.jobs.Get(chi.URLParam(r, "jobID"))
+	if !ok {
+		app.notfoundResponse(w, r, fmt.Errorf("job not found"))
+		return
+	}
+
+	resp := struct {
+		*jobs.Job
+		Result json.RawMessage `json:"result,omitempty"`
+	}{Job: job}
+	if job.Status == jobs.StatusDone && json.Valid(job.Result()) {
+		resp.Result = job.Result()
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) enqueueBackup(ownerID int64, ownerRole string) (*jobs.Job, error) {
+	return app.jobs.Enqueue(backupJob, ownerID, ownerRole, func(ctx context.Context) ([]byte, error) {
+		obj, err := app.backup.Create(ctx)
+		if err != nil {
+			app.logger.Errorw("database backup failed", "error", err.Error())
+			return nil, err
+		}
+		app.logger.Infow("database backup stored", "name", obj.Name, "size", obj.Size)
+		return json.Marshal(obj)
+	})
+}
+
+// startBackupSchedule enqueues a backup every configured interval.
+func (app *application) startBackupSchedule() {
+	if app.backup == nil || app.config.backup.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(app.config.backup.interval)
+	go func() {
+		for range ticker.C {
+			if _, err := app.enqueueBackup(0, "system"); err != nil {
+				app.logger.Errorw("scheduling database backup failed", "error", err.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type BalancePreviewPayload struct {
+	Grade int64 `json:"grade" validate:"required,min=1" example:"10"`
+	// KeepSiblingsTogether keeps students sharing a parent phone number in the
+	// same classroom; defaults to true.
+	KeepSiblingsTogether *bool `json:"keep_siblings_together,omitempty"`
+	// KeepTogether lists extra groups of student IDs that must not be split.
+	KeepTogether [][]int64 `json:"keep_together,omitempty" validate:"omitempty,max=100,dive,min=2,max=20"`
+}
+
+type TransferPayload struct {
+	Moves []*store.StudentTransfer `json:"moves" validate:"required,min=1,max=500,dive,required"`
+}
+
+type BalanceClassroom struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Capacity int64  `json:"capacity"`
+	Before   int64  `json:"before"`
+	After    int64  `json:"after"`
+}
+
+type BalancePlan struct {
+	Grade      int64                  `json:"grade"`
+	Classrooms []*BalanceClassroom    `json:"classrooms"`
+	Moves      []*dto.StudentTransfer `json:"moves"`
+}
+
+// balanceUnit is a set of students that must stay in one classroom.
+type balanceUnit struct {
+	students []int64
+	class    int // index into the roster
+}
+
+// planBalance proposes moves that even out classroom sizes in roster without
+// splitting groups or exceeding capacity. Each step moves one group from the
+// largest classroom to the one that ends up closest in size, and only if
+// that narrows the gap, so the plan always terminates.
+func planBalance(roster []*store.RosterClassroom, groups [][]int64, keepSiblings bool) (*BalancePlan, error) {
+	classOf := map[int64]int{}
+	parent := map[int64]int64{}
+	for i, c := range roster {
+		for _, s := range c.Students {
+			classOf[s.ID] = i
+			parent[s.ID] = s.ID
+		}
+	}
+
+	var find func(int64) int64
+	find = func(id int64) int64 {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	union := func(a, b int64) { parent[find(a)] = find(b) }
+
+	if keepSiblings {
+		byPhone := map[string]int64{}
+		for _, c := range roster {
+			for _, s := range c.Students {
+				if s.ParentPhoneNumber == "" {
+					continue
+				}
+				if other, ok := byPhone[s.ParentPhoneNumber]; ok {
+					union(s.ID, other)
+				} else {
+					byPhone[s.ParentPhoneNumber] = s.ID
+				}
+			}
+		}
+	}
+	for _, g := range groups {
+		for _, id := range g {
+			if _, ok := classOf[id]; !ok {
+				return nil, fmt.Errorf("student %d is not in this grade", id)
+			}
+			union(id, g[0])
+		}
+	}
+
+	// Build movable units; a group already spread over several classrooms
+	// stays where it is.
+	members := map[int64][]int64{}
+	for id := range classOf {
+		root := find(id)
+		members[root] = append(members[root], id)
+	}
+	units := [][]*balanceUnit{}
+	for range roster {
+		units = append(units, []*balanceUnit{})
+	}
+	sizes := make([]int64, len(roster))
+	for i, c := range roster {
+		sizes[i] = int64(len(c.Students))
+	}
+	for _, ids := range members {
+		class := classOf[ids[0]]
+		split := false
+		for _, id := range ids[1:] {
+			if classOf[id] != class {
+				split = true
+				break
+			}
+		}
+		if !split {
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			units[class] = append(units[class], &balanceUnit{students: ids, class: class})
+		}
+	}
+	for _, u := range units {
+		sort.Slice(u, func(i, j int) bool { return u[i].students[0] < u[j].students[0] })
+	}
+
+	for {
+		big := 0
+		for i := range sizes {
+			if sizes[i] > sizes[big] {
+				big = i
+			}
+		}
+
+		bestGap := sizes[big] // any accepted move leaves a smaller gap
+		bestUnit, bestTo := -1, -1
+		for to := range roster {
+			if to == big {
+				continue
+			}
+			for ui, u := range units[big] {
+				n := int64(len(u.students))
+				if sizes[to]+n > roster[to].Capacity || sizes[to]+n >= sizes[big] {
+					continue
+				}
+				gap := (sizes[big] - n) - (sizes[to] + n)
+				if gap < 0 {
+					gap = -gap
+				}
+				if gap < bestGap {
+					bestGap, bestUnit, bestTo = gap, ui, to
+				}
+			}
+		}
+		if bestUnit < 0 {
+			break
+		}
+
+		u := units[big][bestUnit]
+		units[big] = append(units[big][:bestUnit], units[big][bestUnit+1:]...)
+		u.class = bestTo
+		units[bestTo] = append(units[bestTo], u)
+		sizes[big] -= int64(len(u.students))
+		sizes[bestTo] += int64(len(u.students))
+	}
+
+	plan := &BalancePlan{Classrooms: []*BalanceClassroom{}, Moves: []*dto.StudentTransfer{}}
+	for i, c := range roster {
+		plan.Classrooms = append(plan.Classrooms, &BalanceClassroom{
+			ID:       c.ID,
+			Name:     c.Name,
+			Capacity: c.Capacity,
+			Before:   int64(len(c.Students)),
+			After:    sizes[i],
+		})
+		for _, u := range units[i] {
+			for _, id := range u.students {
+				if from := classOf[id]; from != i {
+					plan.Moves = append(plan.Moves, &dto.StudentTransfer{
+						StudentID:       id,
+						FromClassroomID: roster[from].ID,
+						ToClassroomID:   c.ID,
+					})
+				}
+			}
+		}
+	}
+	sort.Slice(plan.Moves, func(i, j int) bool { return plan.Moves[i].StudentID < plan.Moves[j].StudentID })
+
+	return plan, nil
+}
+
+// PreviewClassBalance godoc
+//
+//	@Summary		Propose student moves that even out class sizes in a grade
+//	@Description	Nothing is changed; apply the returned moves with POST /classrooms/transfers. Siblings (same parent phone) and keep_together groups are never split, and no classroom goes over capacity.
+//	@Tags			Classrooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		BalancePreviewPayload	true	"Grade and constraints"
+//	@Success		200		{object}	BalancePlan
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/classrooms/balance/preview [post]
+//	@ID				previewClassBalance
+func (app *application) previewClassBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BalancePreviewPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	roster, err := app.store.Students.GradeRoster(r.Context(), payload.Grade)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if len(roster) == 0 {
+		app.notfoundResponse(w, r, fmt.Errorf("no classrooms in grade %d", payload.Grade))
+		return
+	}
+	scope := getScope(r)
+	for _, c := range roster {
+		if !scope.Allows(&store.Classroom{ID: c.ID, Grade: payload.Grade}) {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	keepSiblings := payload.KeepSiblingsTogether == nil || *payload.KeepSiblingsTogether
+	plan, err := planBalance(roster, payload.KeepTogether, keepSiblings)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	plan.Grade = payload.Grade
+
+	if err := app.jsonResponse(w, http.StatusOK, plan); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// TransferStudents godoc
+//
+//	@Summary		Move students between classrooms in bulk
+//	@Description	All moves succeed or none do. A move fails if the student is no longer in from_classroom_id; the whole batch fails with 409 if a classroom would go over capacity.
+//	@Tags			Classrooms
+//	@Accept			json
+//	@Param			payload	body	TransferPayload	true	"Moves"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/classrooms/transfers [post]
+//	@ID				transferStudents
+func (app *application) transferStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TransferPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	for _, m := range payload.Moves {
+		for _, id := range []int64{m.FromClassroomID, m.ToClassroomID} {
+			ok, err := app.classroomInScope(r, id)
+			if !app.checkScope(w, r, ok, err) {
+				return
+			}
+		}
+	}
+
+	if err := app.store.Students.Transfer(r.Context(), payload.Moves); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrClassroomFull):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"go.uber.org/zap"
+)
+
+// cachedStudents answers every list lookup with the same page, as a warm
+// cache does; the other cache methods are never reached.
+type cachedStudents struct {
+	cache.EntityCache[store.Student]
+	list []*store.Student
+}
+
+func (c *cachedStudents) GetList(context.Context, string) ([]*store.Student, error) {
+	return c.list, nil
+}
+
+func (c *cachedStudents) SetList(context.Context, string, []*store.Student) error { return nil }
+
+func (c *cachedStudents) GetByTeacherID(context.Context, int64) ([]*store.StudentSummary, error) {
+	return nil, nil
+}
+
+func (c *cachedStudents) SetByTeacherID(context.Context, int64, []*store.StudentSummary) error {
+	return nil
+}
+
+func benchStudents(n int) []*store.Student {
+	phone := "+989121234567"
+	students := make([]*store.Student, n)
+	for i := range students {
+		students[i] = &store.Student{
+			ID:                int64(i + 1),
+			FirstName:         "Sara",
+			LastName:          "Ahmadi",
+			Email:             fmt.Sprintf("student%d@example.com", i+1),
+			PhoneNumber:       &phone,
+			ClassRoomID:       3,
+			BirthDate:         time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC),
+			Address:           "12 Valiasr St, Tehran",
+			ParentName:        "Ali Ahmadi",
+			ParentPhoneNumber: phone,
+			TeacherID:         7,
+			StudentCode:       fmt.Sprintf("S%06d", i+1),
+			CreatedAt:         time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC),
+			UpdatedAt:         time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC),
+		}
+	}
+	return students
+}
+
+// BenchmarkListStudents serves a full page of students from a warm cache:
+// query parsing, validation, the cache key, DTO mapping and encoding.
+func BenchmarkListStudents(b *testing.B) {
+	app := &application{logger: zap.NewNop().Sugar()}
+	app.cacheStorage.Students = &cachedStudents{list: benchStudents(50)}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		r := httptest.NewRequest(http.MethodGet, "/v1/students?limit=50&sort_by=last_name&order=asc", nil)
+		w := httptest.NewRecorder()
+		app.getStudentsHandler(w, r)
+		if w.Code != http.StatusOK {
+			b.Fatalf("status = %d; body %s", w.Code, w.Body)
+		}
+	}
+}
+
+// BenchmarkBulkMarkAttendancePayload reads and validates a classroom's
+// bulk attendance, the work done before it is written.
+func BenchmarkBulkMarkAttendancePayload(b *testing.B) {
+	statuses := make([]bulkAttendanceItem, 40)
+	for i := range statuses {
+		statuses[i] = bulkAttendanceItem{StudentID: int64(i + 1), Status: "present"}
+	}
+	body, err := json.Marshal(bulkAttendancePayload{ClassroomID: 3, Date: "2026-10-16", Statuses: statuses})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		r := httptest.NewRequest(http.MethodPost, "/v1/attendance/bulk", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		var payload bulkAttendancePayload
+		if err := readJSON(w, r, &payload); err != nil {
+			b.Fatal(err)
+		}
+		if err := Validate.Struct(payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLogin checks a password and signs the access token, which is
+// where a login spends its CPU; bcrypt dominates.
+func BenchmarkLogin(b *testing.B) {
+	app := &application{
+		authenticator: auth.NewJWTAuthenticator("bench-secret", "classnama", "classnama"),
+	}
+	app.config.auth.token.iss = "classnama"
+	app.config.auth.token.exp = map[string]time.Duration{"exec": 15 * time.Minute}
+
+	var exec store.Exec
+	if err := exec.Password.Set("S3cure-Passw0rd"); err != nil {
+		b.Fatal(err)
+	}
+	body := []byte(`{"email":"sara.ahmadi@example.com","password":"S3cure-Passw0rd"}`)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		var payload LoginPayload
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&payload); err != nil {
+			b.Fatal(err)
+		}
+		if err := Validate.Struct(payload); err != nil {
+			b.Fatal(err)
+		}
+		if !exec.Password.Check(payload.Password) {
+			b.Fatal("password rejected")
+		}
+		if _, _, err := app.issueToken(1, "admin", payload.Email, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
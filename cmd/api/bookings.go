@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type bookingResourceKey string
+
+const bookingResourceCtx bookingResourceKey = "bookingResource"
+
+type CreateBookingResourcePayload struct {
+	Name     string `json:"name" validate:"required,max=128"`
+	Kind     string `json:"kind" validate:"required,oneof=room equipment" enums:"room,equipment" example:"room"`
+	Location string `json:"location" validate:"max=128"`
+	Capacity int    `json:"capacity" validate:"min=0,max=10000" example:"30"`
+}
+
+type UpdateBookingResourcePayload struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,max=128"`
+	Kind     *string `json:"kind,omitempty" validate:"omitempty,oneof=room equipment" enums:"room,equipment" example:"room"`
+	Location *string `json:"location,omitempty" validate:"omitempty,max=128"`
+	Capacity *int    `json:"capacity,omitempty" validate:"omitempty,min=0,max=10000" example:"30"`
+	Active   *bool   `json:"active,omitempty"`
+}
+
+type CreateBookingPayload struct {
+	ResourceID  int64     `json:"resource_id" validate:"required,min=1"`
+	StartsAt    time.Time `json:"starts_at" validate:"required"`
+	EndsAt      time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	ClassroomID *int64    `json:"classroom_id,omitempty" validate:"omitempty,min=1" example:"3"`
+	Purpose     string    `json:"purpose" validate:"max=500"`
+}
+
+// BookingConflict is returned with 409 when the requested slot is taken.
+type BookingConflict struct {
+	Error     string         `json:"error"`
+	Conflicts []*dto.Booking `json:"conflicts"`
+}
+
+type ResourceDay struct {
+	Resource *dto.BookingResource `json:"resource"`
+	Date     string               `json:"date"`
+	Bookings []*dto.Booking       `json:"bookings"`
+}
+
+// ListBookingResources godoc
+//
+//	@Summary	List bookable rooms and equipment
+//	@Tags		Bookings
+//	@Produce	json
+//	@Param		all	query	bool	false	"Include inactive resources"
+//	@Success	200	{array}	dto.BookingResource
+//	@Security	ApiKeyAuth
+//	@Router		/bookings/resources [get]
+//	@ID			listBookingResources
+func (app *application) listBookingResourcesHandler(w http.ResponseWriter, r *http.Request) {
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+
+	resources, err := app.store.Bookings.ListResources(r.Context(), !all)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromBookingResources(resources)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreateBookingResource godoc
+//
+//	@Summary	Add a bookable room or piece of equipment
+//	@Tags		Bookings
+//	@Accept		json
+//	@Produce	json
+//	@Param		payload	body		CreateBookingResourcePayload	true	"Resource"
+//	@Success	201		{object}	dto.BookingResource
+//	@Failure	400		{object}	error
+//	@Failure	409		{object}	error	"Name already in use"
+//	@Security	ApiKeyAuth
+//	@Router		/bookings/resources [post]
+//	@ID			createBookingResource
+func (app *application) createBookingResourceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateBookingResourcePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	resource := &store.BookingResource{
+		Name:     payload.Name,
+		Kind:     payload.Kind,
+		Location: payload.Location,
+		Capacity: payload.Capacity,
+	}
+	if err := app.store.Bookings.CreateResource(r.Context(), resource); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromBookingResource(resource)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateBookingResource godoc
+//
+//	@Summary		Update or retire a bookable resource
+//	@Description	Inactive resources keep their bookings but cannot be booked again.
+//	@Tags			Bookings
+//	@Accept			json
+//	@Produce		json
+//	@Param			resourceID	path		int								true	"Resource ID"
+//	@Param			payload		body		UpdateBookingResourcePayload	true	"Changes"
+//	@Success		200			{object}	dto.BookingResource
+//	@Failure		400			{object}	error
+//	@Failure		409			{object}	error	"Name already in use"
+//	@Security		ApiKeyAuth
+//	@Router			/bookings/resources/{resourceID} [patch]
+//	@ID				updateBookingResource
+func (app *application) updateBookingResourceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload UpdateBookingResourcePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	resource := getBookingResourceFromCtx(r)
+	if payload.Name != nil {
+		resource.Name = *payload.Name
+	}
+	if payload.Kind != nil {
+		resource.Kind = *payload.Kind
+	}
+	if payload.Location != nil {
+		resource.Location = *payload.Location
+	}
+	if payload.Capacity != nil {
+		resource.Capacity = *payload.Capacity
+	}
+	if payload.Active != nil {
+		resource.Active = *payload.Active
+	}
+
+	if err := app.store.Bookings.UpdateResource(r.Context(), resource); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromBookingResource(resource)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetResourceDay godoc
+//
+//	@Summary	List a resource's bookings for one day
+//	@Tags		Bookings
+//	@Produce	json
+//	@Param		resourceID	path		int		true	"Resource ID"
+//	@Param		date		query		string	false	"Day (YYYY-MM-DD), defaults to today"
+//	@Success	200			{object}	ResourceDay
+//	@Failure	400			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/bookings/resources/{resourceID}/day [get]
+//	@ID			getResourceDay
+func (app *application) getResourceDayHandler(w http.ResponseWriter, r *http.Request) {
+	day := app.schoolToday()
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, app.school.location)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid date %q", v))
+			return
+		}
+		day = d
+	}
+
+	resource := getBookingResourceFromCtx(r)
+	bookings, err := app.store.Bookings.Overlapping(r.Context(), resource.ID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	result := ResourceDay{Resource: dto.FromBookingResource(resource), Date: day.Format(time.DateOnly), Bookings: dto.FromBookings(bookings)}
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreateBooking godoc
+//
+//	@Summary		Book a room or piece of equipment
+//	@Description	The slot must fall within a single school day and not overlap another booking of the same resource. Teachers may only book for their own classrooms.
+//	@Tags			Bookings
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateBookingPayload	true	"Booking"
+//	@Success		201		{object}	dto.Booking
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		409		{object}	BookingConflict
+//	@Security		ApiKeyAuth
+//	@Router			/bookings [post]
+//	@ID				createBooking
+func (app *application) createBookingHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateBookingPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUser(r)
+
+	start := payload.StartsAt.In(app.school.location)
+	end := payload.EndsAt.In(app.school.location)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if end.After(day.AddDate(0, 0, 1)) {
+		app.badRequestResponse(w, r, fmt.Errorf("a booking must start and end on the same day"))
+		return
+	}
+	if start.Before(app.school.now()) {
+		app.badRequestResponse(w, r, fmt.Errorf("starts_at is in the past"))
+		return
+	}
+	if !app.requireSchoolDay(w, r, day) {
+		return
+	}
+
+	resource, err := app.store.Bookings.GetResource(ctx, payload.ResourceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("resource %d does not exist", payload.ResourceID))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !resource.Active {
+		app.badRequestResponse(w, r, fmt.Errorf("%s is not available for booking", resource.Name))
+		return
+	}
+
+	if payload.ClassroomID != nil {
+		classroom, err := app.store.Classrooms.GetByID(ctx, *payload.ClassroomID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("classroom %d does not exist", *payload.ClassroomID))
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		if !canRunClassroom(r, classroom) {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	booking := &store.Booking{
+		ResourceID:   resource.ID,
+		BookedBy:     user.ID,
+		BookedByRole: user.Role,
+		ClassroomID:  payload.ClassroomID,
+		StartsAt:     start,
+		EndsAt:       end,
+		Purpose:      payload.Purpose,
+	}
+	if err := app.store.Bookings.Book(ctx, booking); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.bookingConflictResponse(w, r, booking)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromBooking(booking)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// bookingConflictResponse reports the bookings that already hold the
+// requested slot.
+func (app *application) bookingConflictResponse(w http.ResponseWriter, r *http.Request, b *store.Booking) {
+	conflicts, err := app.store.Bookings.Overlapping(r.Context(), b.ResourceID, b.StartsAt, b.EndsAt)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Warnw("conflict", "method", r.Method, "path", r.URL.Path, "error", "booking overlaps")
+	body := BookingConflict{Error: "the resource is already booked for that time", Conflicts: dto.FromBookings(conflicts)}
+	if err := writeJSON(w, http.StatusConflict, body); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CancelBooking godoc
+//
+//	@Summary		Cancel a booking
+//	@Description	Teachers can cancel their own bookings; admins and managers can cancel any.
+//	@Tags			Bookings
+//	@Param			bookingID	path	int	true	"Booking ID"
+//	@Success		204
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/bookings/{bookingID} [delete]
+//	@ID				cancelBooking
+func (app *application) cancelBookingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "bookingID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUser(r)
+
+	booking, err := app.store.Bookings.GetByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	if user.Role == "teacher" && (booking.BookedByRole != "teacher" || booking.BookedBy != user.ID) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if err := app.store.Bookings.Cancel(ctx, id, user.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) bookingResourceContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "resourceID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		resource, err := app.store.Bookings.GetResource(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), bookingResourceCtx, resource)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getBookingResourceFromCtx(r *http.Request) *store.BookingResource {
+	res, _ := r.Context().Value(bookingResourceCtx).(*store.BookingResource)
+	return res
+}
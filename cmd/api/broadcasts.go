@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/sms"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+const broadcastJob = "emergency_broadcast"
+
+// At most broadcastLimit broadcasts go out per broadcastWindow, so a
+// mistaken or compromised admin cannot flood every phone in the school.
+const (
+	broadcastLimit  = 3
+	broadcastWindow = 10 * time.Minute
+)
+
+type BroadcastPayload struct {
+	Subject     string   `json:"subject" validate:"required,max=200" example:"Mathematics"`
+	Message     string   `json:"message" validate:"required,max=1000"`
+	Groups      []string `json:"groups" validate:"required,min=1,dive,oneof=staff teachers students parents"`
+	ClassroomID *int64   `json:"classroom_id,omitempty" validate:"omitempty,min=1" example:"3"`
+	Grade       *int64   `json:"grade,omitempty" validate:"omitempty,min=1,max=30" example:"10"`
+	// Channels defaults to every channel.
+	Channels []string `json:"channels,omitempty" validate:"omitempty,dive,oneof=sms email telegram"`
+}
+
+type BroadcastResponse struct {
+	Broadcast *dto.Broadcast `json:"broadcast"`
+	JobID     string         `json:"job_id"`
+}
+
+// Broadcast godoc
+//
+//	@Summary		Send an emergency broadcast
+//	@Description	Sends an urgent message by SMS, email and Telegram to whole groups (staff, teachers, students, parents) at once; classroom_id or grade narrows students and parents. Delivery runs in the background; the broadcast is recorded with its sender and delivery counts. Limited to three per ten minutes.
+//	@Tags			Announcements
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		BroadcastPayload	true	"Broadcast"
+//	@Success		202		{object}	BroadcastResponse
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/announcements/broadcast [post]
+//	@ID				broadcast
+func (app *application) broadcastHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BroadcastPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if len(payload.Channels) == 0 {
+		payload.Channels = []string{store.ChannelSMS, store.ChannelEmail, store.ChannelTelegram}
+	}
+
+	ctx := r.Context()
+	recent, err := app.store.Broadcasts.CountSince(ctx, time.Now().Add(-broadcastWindow))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if recent >= broadcastLimit {
+		app.rateLimitExceededResponse(w, r, broadcastWindow.String())
+		return
+	}
+
+	user := getUser(r)
+	b := &store.Broadcast{
+		Subject:     payload.Subject,
+		Message:     payload.Message,
+		Groups:      payload.Groups,
+		ClassroomID: payload.ClassroomID,
+		Grade:       payload.Grade,
+		Channels:    payload.Channels,
+		SentBy:      user.ID,
+		SentFromIP:  clientIP(r),
+	}
+
+	recipients, err := app.store.Broadcasts.Recipients(ctx, b)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	b.Recipients = len(recipients)
+
+	if err := app.store.Broadcasts.Create(ctx, b); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	app.logger.Warnw("emergency broadcast", "broadcast", b.ID, "sent_by", user.ID, "groups", b.Groups, "recipients", b.Recipients)
+
+	job, err := app.jobs.Enqueue(broadcastJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		app.deliverBroadcast(ctx, b, recipients)
+		if err := app.store.Broadcasts.Finish(ctx, b); err != nil {
+			return nil, err
+		}
+		return json.Marshal(dto.FromBroadcast(b))
+	})
+	if err != nil {
+		app.serviceUnavailableResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, BroadcastResponse{Broadcast: dto.FromBroadcast(b), JobID: job.ID}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// deliverBroadcast sends the message on every chosen channel at the same
+// time and fills in the delivery counts. There are no per-user
+// notification settings to honour; an emergency goes to everyone.
+func (app *application) deliverBroadcast(ctx context.Context, b *store.Broadcast, recipients []*store.BroadcastRecipient) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	count := func(sent *int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			b.Failed++
+			return
+		}
+		*sent++
+	}
+
+	if slices.Contains(b.Channels, store.ChannelSMS) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text := fmt.Sprintf("%s: %s", b.Subject, b.Message)
+			for _, rc := range recipients {
+				if rc.Phone == "" {
+					continue
+				}
+				err := app.sms.Send(ctx, rc.Phone, text)
+				if errors.Is(err, sms.ErrNotConfigured) {
+					app.logger.Warnw("broadcast sms skipped", "broadcast", b.ID, "error", err.Error())
+					return
+				}
+				if err != nil {
+					app.logger.Warnw("broadcast sms failed", "broadcast", b.ID, "error", err.Error())
+				}
+				count(&b.SMSSent, err)
+			}
+		}()
+	}
+
+	if slices.Contains(b.Channels, store.ChannelEmail) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, rc := range recipients {
+				if rc.Email == "" {
+					continue
+				}
+				err := app.mailer.Send(ctx, mailer.Message{To: []string{rc.Email}, Subject: b.Subject, Body: b.Message})
+				if errors.Is(err, mailer.ErrNotConfigured) {
+					app.logger.Warnw("broadcast email skipped", "broadcast", b.ID, "error", err.Error())
+					return
+				}
+				if err != nil {
+					app.logger.Warnw("broadcast email failed", "broadcast", b.ID, "error", err.Error())
+				}
+				count(&b.EmailSent, err)
+			}
+		}()
+	}
+
+	if slices.Contains(b.Channels, store.ChannelTelegram) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			phones := []string{}
+			for _, rc := range recipients {
+				if rc.Phone != "" {
+					phones = append(phones, rc.Phone)
+				}
+			}
+			// only parents link Telegram, so other phones find no chat
+			sent, err := app.notifyTelegram(ctx, phones, fmt.Sprintf("%s\n\n%s", b.Subject, b.Message))
+			if err != nil {
+				app.logger.Warnw("broadcast telegram failed", "broadcast", b.ID, "error", err.Error())
+			}
+			mu.Lock()
+			b.TelegramSent = sent
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// ListBroadcasts godoc
+//
+//	@Summary	List recent emergency broadcasts
+//	@Tags		Announcements
+//	@Produce	json
+//	@Success	200	{array}	dto.Broadcast
+//	@Security	ApiKeyAuth
+//	@Router		/announcements/broadcasts [get]
+//	@ID			listBroadcasts
+func (app *application) listBroadcastsHandler(w http.ResponseWriter, r *http.Request) {
+	broadcasts, err := app.store.Broadcasts.List(r.Context(), 100)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromBroadcasts(broadcasts)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
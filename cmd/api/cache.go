@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+)
+
+// lookupByID loads a record through get, answering from the negative cache
+// when the ID recently resolved to ErrNotFound and remembering fresh misses.
+// Cache errors are logged and otherwise ignored.
+func lookupByID[T any](app *application, ctx context.Context, entity string, id int64, get func(context.Context, int64) (*T, error)) (*T, error) {
+	missing, err := app.cacheStorage.Missing.IsMissing(ctx, entity, id)
+	if err != nil {
+		app.logger.Warnw("negative cache lookup failed", "entity", entity, "error", err.Error())
+	} else if missing {
+		return nil, store.ErrNotFound
+	}
+
+	v, err := get(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		if err := app.cacheStorage.Missing.MarkMissing(ctx, entity, id); err != nil {
+			app.logger.Warnw("negative cache write failed", "entity", entity, "error", err.Error())
+		}
+	}
+	return v, err
+}
+
+const maxCacheKeys = 1000
+
+// defaultListQuery is the list query before client parameters are applied;
+// the cache warm-up relies on it to hit the same keys as the handlers.
+var defaultListQuery = store.PaginatedQuery{Limit: 10, Offset: 0, SortBy: "id", Order: "asc"}
+
+// listCacheParams are the parts of a list query that make up its cache key.
+func listCacheParams(pq store.PaginatedQuery) map[string]any {
+	return map[string]any{
+		"limit":   pq.Limit,
+		"offset":  pq.Offset,
+		"sort":    pq.SortBy,
+		"order":   pq.Order,
+		"search":  pq.Search,
+		"fields":  strings.Join(pq.Fields, ","),
+		"tag":     pq.Tag,
+		"scope":   pq.Scope.Key(),
+		"filters": fmt.Sprint(pq.Filters),
+		"account": pq.Account.Key(),
+		"after":   pq.After,
+	}
+}
+
+type CacheKeysResponse struct {
+	Prefix    string   `json:"prefix"`
+	Keys      []string `json:"keys"`
+	Truncated bool     `json:"truncated"`
+}
+
+type CacheFlushResponse struct {
+	Prefix  string `json:"prefix"`
+	Removed int64  `json:"removed"`
+}
+
+// GetCacheStats godoc
+//
+//	@Summary	Cache hit/miss/eviction counters per namespace
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{object}	map[string]cache.NamespaceStats
+//	@Security	ApiKeyAuth
+//	@Router		/admin/cache/stats [get]
+//	@ID			getCacheStats
+func (app *application) getCacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, cache.Stats()); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetCacheKeys godoc
+//
+//	@Summary	List cache keys by prefix
+//	@Tags		Admin
+//	@Produce	json
+//	@Param		prefix	query		string	false	"Key prefix, e.g. students:"
+//	@Success	200		{object}	CacheKeysResponse
+//	@Failure	500		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/admin/cache/keys [get]
+//	@ID			getCacheKeys
+func (app *application) getCacheKeysHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	keys, err := app.cacheStorage.Admin.Keys(r.Context(), prefix, maxCacheKeys+1)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := CacheKeysResponse{Prefix: prefix, Keys: keys}
+	if len(keys) > maxCacheKeys {
+		resp.Keys = keys[:maxCacheKeys]
+		resp.Truncated = true
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// FlushCache godoc
+//
+//	@Summary		Flush a cache namespace
+//	@Description	Removes every cached key starting with prefix. Persistent state such as the maintenance switch, OTP codes and limits, and API key quota usage is kept.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			prefix	query		string	true	"Key prefix, e.g. students:"
+//	@Success		200		{object}	CacheFlushResponse
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/cache [delete]
+//	@ID				flushCache
+func (app *application) flushCacheHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		app.badRequestResponse(w, r, fmt.Errorf("prefix is required"))
+		return
+	}
+
+	removed, err := app.cacheStorage.Admin.Flush(r.Context(), prefix)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.logger.Infow("cache flushed", "prefix", prefix, "removed", removed, "by", getUser(r).ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, CacheFlushResponse{Prefix: prefix, Removed: removed}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxCalendarImport = 1000
+
+type CalendarDayPayload struct {
+	Kind string `json:"kind" validate:"required,oneof=holiday closure school_day" enums:"holiday,closure,school_day" example:"holiday"`
+	Name string `json:"name" validate:"max=128"`
+}
+
+type CalendarImportPayload struct {
+	Days []*store.CalendarDay `json:"days" validate:"required,min=1,max=1000,dive,required"`
+}
+
+type CalendarImportResponse struct {
+	Imported int64 `json:"imported"`
+}
+
+// requireSchoolDay writes a 400 and returns false when date is not a school
+// day.
+func (app *application) requireSchoolDay(w http.ResponseWriter, r *http.Request, date time.Time) bool {
+	open, day, err := app.store.SchoolDays.IsSchoolDay(r.Context(), date)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return false
+	}
+	if open {
+		return true
+	}
+
+	reason := "not a school weekday"
+	if day != nil {
+		reason = day.Kind
+		if day.Name != "" {
+			reason += ": " + day.Name
+		}
+	}
+	app.badRequestResponse(w, r, fmt.Errorf("%s is not a school day (%s)", date.Format(time.DateOnly), reason))
+	return false
+}
+
+// GetCalendar godoc
+//
+//	@Summary		List school calendar entries
+//	@Description	Holidays, closures and extra school days between from and to. Days not listed follow the weekly SCHOOL_DAYS.
+//	@Tags			Calendar
+//	@Produce		json
+//	@Param			from	query		string	false	"Start date (YYYY-MM-DD), defaults to 365 days before to"
+//	@Param			to		query		string	false	"End date (YYYY-MM-DD), defaults to today"
+//	@Success		200		{array}		dto.CalendarDay
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/calendar [get]
+//	@ID				getCalendar
+func (app *application) getCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 365)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	days, err := app.store.SchoolDays.List(r.Context(), from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromCalendarDays(days)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PutCalendarDay godoc
+//
+//	@Summary	Mark a date as a holiday, closure or extra school day
+//	@Tags		Calendar
+//	@Accept		json
+//	@Produce	json
+//	@Param		date	path		string				true	"Date (YYYY-MM-DD)"
+//	@Param		payload	body		CalendarDayPayload	true	"Entry"
+//	@Success	200		{object}	dto.CalendarDay
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/calendar/{date} [put]
+//	@ID			putCalendarDay
+func (app *application) putCalendarDayHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid date; expected YYYY-MM-DD"))
+		return
+	}
+
+	var payload CalendarDayPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	day := &store.CalendarDay{Date: date.Format(time.DateOnly), Kind: payload.Kind, Name: payload.Name}
+	if _, err := app.store.SchoolDays.Upsert(r.Context(), []*store.CalendarDay{day}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromCalendarDay(day)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteCalendarDay godoc
+//
+//	@Summary	Remove a calendar entry, so the date follows the weekly schedule again
+//	@Tags		Calendar
+//	@Param		date	path	string	true	"Date (YYYY-MM-DD)"
+//	@Success	204		"No Content"
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/calendar/{date} [delete]
+//	@ID			deleteCalendarDay
+func (app *application) deleteCalendarDayHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid date; expected YYYY-MM-DD"))
+		return
+	}
+
+	if err := app.store.SchoolDays.Delete(r.Context(), date); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportCalendar godoc
+//
+//	@Summary		Bulk import calendar entries, e.g. the national holiday list
+//	@Description	Accepts JSON ({"days": [...]}) or CSV (text/csv) with columns date,name[,kind]; kind defaults to holiday. Existing dates are overwritten. All rows are imported or none.
+//	@Tags			Calendar
+//	@Accept			json,text/csv
+//	@Produce		json
+//	@Param			payload	body		CalendarImportPayload	true	"Entries"
+//	@Success		200		{object}	CalendarImportResponse
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/calendar/import [post]
+//	@ID				importCalendar
+func (app *application) importCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CalendarImportPayload
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		days, err := readCalendarCSV(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		payload.Days = days
+	} else if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	n, err := app.store.SchoolDays.Upsert(r.Context(), payload.Days)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, CalendarImportResponse{Imported: n}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// readCalendarCSV parses date,name[,kind] rows. A first row starting with
+// "date" is treated as a header.
+func readCalendarCSV(body io.Reader) ([]*store.CalendarDay, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	days := []*store.CalendarDay{}
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(rec[0], "date") {
+			continue
+		}
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("line %d: expected date,name[,kind]", line)
+		}
+		if len(days) == maxCalendarImport {
+			return nil, fmt.Errorf("at most %d rows can be imported at once", maxCalendarImport)
+		}
+
+		day := &store.CalendarDay{Date: rec[0], Name: rec[1], Kind: store.CalendarHoliday}
+		if len(rec) > 2 && rec[2] != "" {
+			day.Kind = rec[2]
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+const earthRadiusMeters = 6371000
+
+// checkInPolicy is the geofence and daily time window for mobile check-in.
+// Times are offsets from midnight, school time.
+type checkInPolicy struct {
+	latitude    float64
+	longitude   float64
+	radius      float64
+	windowStart time.Duration
+	windowEnd   time.Duration
+	lateAfter   time.Duration
+}
+
+func newCheckInPolicy(cfg checkInConfig) (*checkInPolicy, error) {
+	if cfg.latitude < -90 || cfg.latitude > 90 || cfg.longitude < -180 || cfg.longitude > 180 ||
+		(cfg.latitude == 0 && cfg.longitude == 0) {
+		return nil, fmt.Errorf("check-in needs SCHOOL_LATITUDE and SCHOOL_LONGITUDE")
+	}
+	if cfg.radius <= 0 {
+		return nil, fmt.Errorf("invalid CHECKIN_RADIUS_METERS %v", cfg.radius)
+	}
+
+	p := &checkInPolicy{latitude: cfg.latitude, longitude: cfg.longitude, radius: cfg.radius}
+	var err error
+	if p.windowStart, err = parseClock(cfg.windowStart); err != nil {
+		return nil, fmt.Errorf("CHECKIN_WINDOW_START: %w", err)
+	}
+	if p.windowEnd, err = parseClock(cfg.windowEnd); err != nil {
+		return nil, fmt.Errorf("CHECKIN_WINDOW_END: %w", err)
+	}
+	if p.lateAfter, err = parseClock(cfg.lateAfter); err != nil {
+		return nil, fmt.Errorf("CHECKIN_LATE_AFTER: %w", err)
+	}
+	if p.windowEnd <= p.windowStart {
+		return nil, fmt.Errorf("CHECKIN_WINDOW_END must be after CHECKIN_WINDOW_START")
+	}
+	return p, nil
+}
+
+// distance returns the great-circle distance in meters from the school.
+func (p *checkInPolicy) distance(lat, lng float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := rad(lat - p.latitude)
+	dLng := rad(lng - p.longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(p.latitude))*math.Cos(rad(lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+type CheckInPayload struct {
+	Latitude  float64 `json:"latitude" validate:"latitude"`
+	Longitude float64 `json:"longitude" validate:"longitude"`
+	// Accuracy is the device's reported accuracy radius in meters.
+	Accuracy *float64 `json:"accuracy,omitempty" validate:"omitempty,min=0"`
+}
+
+// MobileCheckIn godoc
+//
+//	@Summary		Check in from a phone inside the school grounds
+//	@Description	Accepted on school days within CHECKIN_WINDOW_START–CHECKIN_WINDOW_END when the coordinates are inside the school's geofence. A student's attendance is marked present, or late after CHECKIN_LATE_AFTER; an existing record keeps its status and is only annotated with the check-in. Teacher check-ins are recorded without an attendance record.
+//	@Tags			Attendance
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CheckInPayload	true	"Location"
+//	@Success		201		{object}	dto.CheckIn
+//	@Failure		400		{object}	error	"Outside the geofence or the time window"
+//	@Failure		409		{object}	error	"Already checked in today"
+//	@Security		ApiKeyAuth
+//	@Router			/attendance/checkin [post]
+//	@ID				mobileCheckIn
+func (app *application) mobileCheckInHandler(w http.ResponseWriter, r *http.Request) {
+	policy := app.checkIn
+	if policy == nil {
+		app.notfoundResponse(w, r, errors.New("mobile check-in is not enabled"))
+		return
+	}
+
+	var payload CheckInPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	now := app.school.now()
+	today := app.schoolToday()
+	if since := now.Sub(today); since < policy.windowStart || since > policy.windowEnd {
+		app.badRequestResponse(w, r, fmt.Errorf("check-in is open from %s to %s",
+			today.Add(policy.windowStart).Format("15:04"), today.Add(policy.windowEnd).Format("15:04")))
+		return
+	}
+	if !app.requireSchoolDay(w, r, today) {
+		return
+	}
+
+	if payload.Accuracy != nil && *payload.Accuracy > policy.radius {
+		app.badRequestResponse(w, r, fmt.Errorf("location accuracy of %.0f m is too low; try again outdoors", *payload.Accuracy))
+		return
+	}
+	distance := policy.distance(payload.Latitude, payload.Longitude)
+	if distance > policy.radius {
+		app.badRequestResponse(w, r, fmt.Errorf("you are %.0f m from school; check-in is allowed within %.0f m", distance, policy.radius))
+		return
+	}
+
+	status := "present"
+	if now.Sub(today) > policy.lateAfter {
+		status = "late"
+	}
+
+	user := getUser(r)
+	checkIn := &store.CheckIn{
+		UserRole:  user.Role,
+		UserID:    user.ID,
+		Date:      today,
+		Latitude:  payload.Latitude,
+		Longitude: payload.Longitude,
+		Accuracy:  payload.Accuracy,
+		Distance:  math.Round(distance),
+	}
+	if err := app.store.CheckIns.Record(r.Context(), checkIn, status); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("already checked in today"))
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromCheckIn(checkIn)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListCheckIns godoc
+//
+//	@Summary	List a day's mobile check-ins
+//	@Tags		Attendance
+//	@Produce	json
+//	@Param		date	query		string	false	"Day (YYYY-MM-DD), defaults to today"
+//	@Param		role	query		string	false	"student or teacher"
+//	@Success	200		{array}		dto.CheckIn
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/attendance/checkins [get]
+//	@ID			listCheckIns
+func (app *application) listCheckInsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	day := app.schoolToday()
+	if v := q.Get("date"); v != "" {
+		d, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid date %q", v))
+			return
+		}
+		day = d
+	}
+	role := q.Get("role")
+	if role != "" && role != "student" && role != "teacher" {
+		app.badRequestResponse(w, r, fmt.Errorf("role must be student or teacher"))
+		return
+	}
+
+	checkIns, err := app.store.CheckIns.ForDate(r.Context(), day, role)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromCheckIns(checkIns)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -2,25 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
 	"github.com/MahdiiTaheri/classnama-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
 type ClassroomRegisterPayload struct {
-	Name     string `json:"name" validate:"required,max=128"`
-	Capacity int64  `json:"capacity" validate:"required,min=1"`
-	Grade    int64  `json:"grade,omitempty" validate:"required,min=1"`
+	Name       string              `json:"name" validate:"required,max=128" example:"10-A"`
+	NameI18n   store.LocalizedText `json:"name_i18n,omitempty" validate:"omitempty,dive,keys,bcp47_language_tag,endkeys,max=128" example:"en:Grade 10 A,fa:دهم الف"`
+	Capacity   int64               `json:"capacity" validate:"required,min=1" example:"30"`
+	Grade      int64               `json:"grade,omitempty" validate:"required,min=1" example:"10"`
+	Mode       string              `json:"mode,omitempty" validate:"omitempty,oneof=in_person online hybrid" enums:"in_person,online,hybrid" example:"in_person"`
+	MeetingURL string              `json:"meeting_url,omitempty" validate:"omitempty,url,max=512" example:"https://meet.example.com/10-a"`
 }
 
 type UpdateClassroomPayload struct {
-	Name     *string `json:"name,omitempty" validate:"omitempty,max=128"`
-	Capacity *int64  `json:"capacity,omitempty" validate:"omitempty,min=5,max=40"`
-	Grade    *int64  `json:"grade,omitempty" validate:"omitempty,min=1,max=30"`
+	Name       *string              `json:"name,omitempty" validate:"omitempty,max=128" example:"10-A"`
+	NameI18n   *store.LocalizedText `json:"name_i18n,omitempty" validate:"omitempty,dive,keys,bcp47_language_tag,endkeys,max=128" example:"en:Grade 10 A,fa:دهم الف"`
+	Capacity   *int64               `json:"capacity,omitempty" validate:"omitempty,min=5,max=40" example:"30"`
+	Grade      *int64               `json:"grade,omitempty" validate:"omitempty,min=1,max=30" example:"10"`
+	Mode       *string              `json:"mode,omitempty" validate:"omitempty,oneof=in_person online hybrid" enums:"in_person,online,hybrid" example:"in_person"`
+	MeetingURL *string              `json:"meeting_url,omitempty" validate:"omitempty,url,max=512" example:"https://meet.example.com/10-a"`
 }
 
 type classroomKey string
@@ -39,9 +48,20 @@ func (app *application) registerClassroomHandler(w http.ResponseWriter, r *http.
 	}
 
 	classroom := &store.Classroom{
-		Name:     payload.Name,
-		Capacity: payload.Capacity,
-		Grade:    payload.Grade,
+		Name:       payload.Name,
+		NameI18n:   payload.NameI18n,
+		Capacity:   payload.Capacity,
+		Grade:      payload.Grade,
+		Mode:       payload.Mode,
+		MeetingURL: payload.MeetingURL,
+	}
+	if err := app.validateClassroomMode(classroom); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if !getScope(r).Allows(classroom) {
+		app.forbiddenResponse(w, r)
+		return
 	}
 
 	if err := app.store.Classrooms.Create(r.Context(), classroom); err != nil {
@@ -49,14 +69,18 @@ func (app *application) registerClassroomHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	app.jsonResponse(w, http.StatusCreated, classroom)
+	app.jsonResponse(w, http.StatusCreated, dto.FromClassroom(localizeClassroom(r, classroom)))
 }
 
-// getClassroomsHandler (paginated, searchable)
+// getClassroomsHandler (paginated, searchable by name, filterable by
+// ?grade= and ?teacher_id=)
 func (app *application) getClassroomsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listClassrooms(w, r, v1Lists)
+}
+
+func (app *application) listClassrooms(w http.ResponseWriter, r *http.Request, lists listAdapter) {
 	ctx := r.Context()
-	pq := store.PaginatedQuery{Limit: 10, Offset: 0, SortBy: "id", Order: "asc"}
-	pq, err := pq.Parse(r)
+	pq, err := lists.parse(r, defaultListQuery)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -66,13 +90,50 @@ func (app *application) getClassroomsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	classrooms, err := app.store.Classrooms.GetAll(ctx, pq)
+	for _, param := range []string{"grade", "teacher_id"} {
+		v := r.URL.Query().Get(param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid %s", param))
+			return
+		}
+		if pq.Filters == nil {
+			pq.Filters = map[string]any{}
+		}
+		pq.Filters[param] = n
+	}
+
+	pq.Scope = getScope(r)
+	params := listCacheParams(pq)
+
+	classrooms, err := cache.GetListWithCache(
+		ctx,
+		app.cacheStorage.Classrooms,
+		"classrooms:list",
+		params,
+		func(ctx context.Context) ([]*store.Classroom, error) {
+			return app.store.Classrooms.GetAll(ctx, pq)
+		},
+	)
 	if err != nil {
+		if errors.Is(err, store.ErrInvalidField) {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
-	app.jsonResponse(w, http.StatusOK, classrooms)
+	data, err := filterFields(dto.FromClassrooms(localizeClassrooms(r, classrooms)), pq.Fields)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	lists.write(w, r, pq, data, len(classrooms), lastID(classrooms, func(c *store.Classroom) int64 { return c.ID }))
 }
 
 // getClassroomHandler
@@ -83,7 +144,41 @@ func (app *application) getClassroomHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	app.jsonResponse(w, http.StatusOK, classroom)
+	app.jsonResponse(w, http.StatusOK, dto.FromClassroom(localizeClassroom(r, classroom)))
+}
+
+// classroomDetailLessons is how many upcoming lessons the classroom detail
+// lists.
+const classroomDetailLessons = 5
+
+// GetClassroomDetail godoc
+//
+//	@Summary		Get a classroom with its teacher, students, today's attendance and next lessons
+//	@Description	Everything the classroom page shows in one call. Lessons are the classroom's upcoming, uncancelled bookings.
+//	@Tags			Classrooms
+//	@Produce		json
+//	@Param			classroomID	path		int	true	"Classroom ID"
+//	@Success		200			{object}	dto.ClassroomDetail
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/classrooms/{classroomID}/detail [get]
+//	@ID				getClassroomDetail
+func (app *application) getClassroomDetailHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if classroom == nil {
+		app.notfoundResponse(w, r, fmt.Errorf("classroom not found"))
+		return
+	}
+
+	detail, err := app.store.Classrooms.Detail(r.Context(), localizeClassroom(r, classroom), app.school.now(), classroomDetailLessons)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromClassroomDetail(detail)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
 }
 
 // updateClassroomHandler
@@ -104,9 +199,20 @@ func (app *application) updateClassroomHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	utils.ApplyPatch(classroom, payload)
+	// validate the patched record, but only write the provided columns
+	patched := *classroom
+	utils.ApplyPatch(&patched, payload)
+	if err := app.validateClassroomMode(&patched); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if !getScope(r).Allows(&patched) {
+		app.forbiddenResponse(w, r)
+		return
+	}
 
-	if err := app.store.Classrooms.Update(r.Context(), classroom); err != nil {
+	ctx := r.Context()
+	if err := app.store.Classrooms.UpdatePartial(ctx, classroom.ID, utils.PatchFields(payload)); err != nil {
 		switch err {
 		case store.ErrNotFound:
 			app.notfoundResponse(w, r, err)
@@ -116,7 +222,15 @@ func (app *application) updateClassroomHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	app.jsonResponse(w, http.StatusOK, classroom)
+	updated, err := app.store.Classrooms.GetByID(ctx, classroom.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordChanges(r, "classroom", classroom.ID, classroom, updated)
+
+	app.jsonResponse(w, http.StatusOK, dto.FromClassroom(localizeClassroom(r, updated)))
 }
 
 // deleteClassroomHandler
@@ -128,7 +242,7 @@ func (app *application) deleteClassroomHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := app.store.Classrooms.Delete(r.Context(), id); err != nil {
+	if err := app.store.Classrooms.Delete(r.Context(), id, getUser(r).ID); err != nil {
 		switch {
 		case err == store.ErrNotFound:
 			app.notfoundResponse(w, r, err)
@@ -152,7 +266,7 @@ func (app *application) classroomsContextMiddleware(next http.Handler) http.Hand
 			return
 		}
 
-		classroom, err := app.store.Classrooms.GetByID(r.Context(), id)
+		classroom, err := lookupByID(app, r.Context(), "classrooms", id, app.store.Classrooms.GetByID)
 		if err != nil {
 			switch {
 			case err == store.ErrNotFound:
@@ -163,6 +277,11 @@ func (app *application) classroomsContextMiddleware(next http.Handler) http.Hand
 			return
 		}
 
+		if !getScope(r).Allows(classroom) {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), classroomCtx, classroom)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
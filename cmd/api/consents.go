@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const consentNotifyJob = "consent_notify"
+
+type CreateConsentRequestPayload struct {
+	Title       string                `json:"title" validate:"required,max=200"`
+	Description string                `json:"description" validate:"max=4000"`
+	Kind        string                `json:"kind" validate:"required,oneof=trip media other" enums:"trip,media,other" example:"trip"`
+	DueDate     string                `json:"due_date" validate:"required,datetime=2006-01-02"`
+	Audience    store.ConsentAudience `json:"audience"`
+}
+
+type ConsentRequestDetail struct {
+	*dto.ConsentRequest
+	Consents []*dto.StudentConsent `json:"consents"`
+}
+
+type CreateConsentRequestResponse struct {
+	*dto.ConsentRequest
+	// NotifyJobID is the background job texting parents their links.
+	NotifyJobID string `json:"notify_job_id,omitempty"`
+}
+
+// ParentConsentView is what a parent's link shows.
+type ParentConsentView struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Kind        string     `json:"kind"`
+	DueDate     string     `json:"due_date"`
+	StudentName string     `json:"student_name"`
+	Status      string     `json:"status"`
+	RespondedAt *time.Time `json:"responded_at"`
+}
+
+type ConsentDecisionPayload struct {
+	Decision string `json:"decision" validate:"required,oneof=approved declined" enums:"approved,declined" example:"approved"`
+	Name     string `json:"name" validate:"required,max=128"`
+}
+
+func (app *application) consentLinkSignature(consentID, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	fmt.Fprintf(mac, "consent:%d:%d", consentID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// consentLink returns the parent's signed link, valid until the end of the
+// due date.
+func (app *application) consentLink(consentID int64, due time.Time) string {
+	expires := due.AddDate(0, 0, 1).Unix()
+	return fmt.Sprintf("%s/v1/consents/respond/%d?expires=%d&sig=%s",
+		strings.TrimSuffix(app.config.publicURL, "/"), consentID, expires, app.consentLinkSignature(consentID, expires))
+}
+
+// clientIP returns the caller's address without the port.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// CreateConsentRequest godoc
+//
+//	@Summary		Request consent from parents
+//	@Description	Creates a pending consent for every student in the audience (a classroom, a grade, or everyone) and texts each parent a signed link to answer.
+//	@Tags			Consents
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateConsentRequestPayload	true	"Request"
+//	@Success		201		{object}	CreateConsentRequestResponse
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/consents [post]
+//	@ID				createConsentRequest
+func (app *application) createConsentRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateConsentRequestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	due, _ := time.Parse(time.DateOnly, payload.DueDate)
+	if payload.DueDate < app.schoolToday().Format(time.DateOnly) {
+		app.badRequestResponse(w, r, fmt.Errorf("due_date is in the past"))
+		return
+	}
+
+	user := getUser(r)
+	cr := &store.ConsentRequest{
+		Title:       payload.Title,
+		Description: payload.Description,
+		Kind:        payload.Kind,
+		DueDate:     due,
+		CreatedBy:   user.ID,
+	}
+	consents, err := app.store.Consents.CreateRequest(r.Context(), cr, payload.Audience)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := CreateConsentRequestResponse{ConsentRequest: dto.FromConsentRequest(cr)}
+	job, err := app.jobs.Enqueue(consentNotifyJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		return json.Marshal(map[string]int{"sent": app.sendConsentLinks(ctx, cr, consents)})
+	})
+	if err != nil {
+		app.logger.Warnw("queueing consent notifications failed", "request", cr.ID, "error", err.Error())
+	} else {
+		resp.NotifyJobID = job.ID
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// sendConsentLinks texts every parent with a phone number their link and
+// returns how many were sent.
+func (app *application) sendConsentLinks(ctx context.Context, cr *store.ConsentRequest, consents []*store.StudentConsent) int {
+	sent := 0
+	for _, c := range consents {
+		if c.ParentPhone == "" {
+			continue
+		}
+		text := fmt.Sprintf("Dear %s, please answer the consent request %q for %s %s by %s: %s",
+			c.ParentName, cr.Title, c.FirstName, c.LastName, cr.DueDate.Format(time.DateOnly), app.consentLink(c.ID, cr.DueDate))
+		if err := app.sms.Send(ctx, c.ParentPhone, text); err != nil {
+			app.logger.Warnw("consent link sms failed", "consent", c.ID, "error", err.Error())
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// ListConsentRequests godoc
+//
+//	@Summary	List consent requests with answer counts
+//	@Tags		Consents
+//	@Produce	json
+//	@Success	200	{array}	dto.ConsentRequest
+//	@Security	ApiKeyAuth
+//	@Router		/consents [get]
+//	@ID			listConsentRequests
+func (app *application) listConsentRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	requests, err := app.store.Consents.ListRequests(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromConsentRequests(requests)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetConsentRequest godoc
+//
+//	@Summary	Get a consent request with every student's answer
+//	@Tags		Consents
+//	@Produce	json
+//	@Param		requestID	path		int	true	"Request ID"
+//	@Success	200			{object}	ConsentRequestDetail
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/consents/{requestID} [get]
+//	@ID			getConsentRequest
+func (app *application) getConsentRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	cr, err := app.store.Consents.GetRequest(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	consents, err := app.store.Consents.ForRequest(r.Context(), id)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, ConsentRequestDetail{ConsentRequest: dto.FromConsentRequest(cr), Consents: dto.FromStudentConsents(consents)}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteConsentRequest godoc
+//
+//	@Summary	Delete a consent request and its answers
+//	@Tags		Consents
+//	@Param		requestID	path	int	true	"Request ID"
+//	@Success	204			"No Content"
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/consents/{requestID} [delete]
+//	@ID			deleteConsentRequest
+func (app *application) deleteConsentRequestHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Consents.DeleteRequest(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetClassroomConsentMatrix godoc
+//
+//	@Summary	Consent status of a classroom's students for every request
+//	@Tags		Consents
+//	@Produce	json
+//	@Param		classroomID	path		int	true	"Classroom ID"
+//	@Success	200			{object}	dto.ConsentMatrix
+//	@Failure	403			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/consents/classrooms/{classroomID}/matrix [get]
+//	@ID			getClassroomConsentMatrix
+func (app *application) getClassroomConsentMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if user := getUser(r); user.Role == "teacher" && classroom.TeacherID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	matrix, err := app.store.Consents.Matrix(r.Context(), classroom.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromConsentMatrix(matrix)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// loadParentConsent checks the link signature and loads the consent and its
+// request.
+func (app *application) loadParentConsent(w http.ResponseWriter, r *http.Request) (*store.StudentConsent, *store.ConsentRequest, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "consentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid consent ID"))
+		return nil, nil, false
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires ||
+		!hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(app.consentLinkSignature(id, expires))) {
+		app.forbiddenResponse(w, r)
+		return nil, nil, false
+	}
+
+	consent, err := app.store.Consents.GetConsent(r.Context(), id)
+	if err == nil {
+		var cr *store.ConsentRequest
+		cr, err = app.store.Consents.GetRequest(r.Context(), consent.RequestID)
+		if err == nil {
+			return consent, cr, true
+		}
+	}
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		app.notfoundResponse(w, r, err)
+	default:
+		app.internalServerErrorResponse(w, r, err)
+	}
+	return nil, nil, false
+}
+
+func parentConsentView(c *store.StudentConsent, cr *store.ConsentRequest) ParentConsentView {
+	return ParentConsentView{
+		Title:       cr.Title,
+		Description: cr.Description,
+		Kind:        cr.Kind,
+		DueDate:     cr.DueDate.Format(time.DateOnly),
+		StudentName: c.FirstName + " " + c.LastName,
+		Status:      c.Status,
+		RespondedAt: c.RespondedAt,
+	}
+}
+
+// GetParentConsent godoc
+//
+//	@Summary		Show a consent request to a parent
+//	@Description	Authorised by the signature of the link texted to the parent rather than a token
+//	@Tags			Consents
+//	@Produce		json
+//	@Param			consentID	path		int		true	"Consent ID"
+//	@Param			expires		query		int		true	"Link expiry (unix seconds)"
+//	@Param			sig			query		string	true	"Link signature"
+//	@Success		200			{object}	ParentConsentView
+//	@Failure		403			{object}	error
+//	@Router			/consents/respond/{consentID} [get]
+//	@ID				getParentConsent
+func (app *application) getParentConsentHandler(w http.ResponseWriter, r *http.Request) {
+	consent, cr, ok := app.loadParentConsent(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, parentConsentView(consent, cr)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RespondParentConsent godoc
+//
+//	@Summary		Approve or decline a consent request
+//	@Description	Authorised by the link signature. Parents can change their answer until the due date; the time, name and IP of the answer are recorded.
+//	@Tags			Consents
+//	@Accept			json
+//	@Produce		json
+//	@Param			consentID	path		int						true	"Consent ID"
+//	@Param			expires		query		int						true	"Link expiry (unix seconds)"
+//	@Param			sig			query		string					true	"Link signature"
+//	@Param			payload		body		ConsentDecisionPayload	true	"Decision"
+//	@Success		200			{object}	ParentConsentView
+//	@Failure		403			{object}	error
+//	@Failure		409			{object}	error
+//	@Router			/consents/respond/{consentID} [post]
+//	@ID				respondParentConsent
+func (app *application) respondParentConsentHandler(w http.ResponseWriter, r *http.Request) {
+	consent, cr, ok := app.loadParentConsent(w, r)
+	if !ok {
+		return
+	}
+	if app.schoolToday().Format(time.DateOnly) > cr.DueDate.Format(time.DateOnly) {
+		app.conflictResponse(w, r, fmt.Errorf("the due date has passed"))
+		return
+	}
+
+	var payload ConsentDecisionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	consent.Status = payload.Decision
+	consent.ResponderName = payload.Name
+	consent.ResponderIP = clientIP(r)
+	if err := app.store.Consents.Respond(r.Context(), consent); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, parentConsentView(consent, cr)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateAttendanceCorrectionPayload struct {
+	StudentID int64   `json:"student_id" validate:"required" example:"42"`
+	Date      string  `json:"date" validate:"required,datetime=2006-01-02" example:"2026-09-28"`
+	Status    string  `json:"status" validate:"required,oneof=present absent late excused" enums:"present,absent,late,excused" example:"present"`
+	Note      *string `json:"note,omitempty" validate:"omitempty,max=500" example:"Arrived with a pass from the office"`
+	Reason    string  `json:"reason" validate:"required,max=1000" example:"Marked absent by mistake; the student was in class"`
+}
+
+type ReviewAttendanceCorrectionPayload struct {
+	Note string `json:"note" validate:"max=500" example:"Confirmed with the office log"`
+}
+
+// CreateAttendanceCorrection godoc
+//
+//	@Summary		Request an attendance correction
+//	@Description	For days past ATTENDANCE_EDIT_WINDOW_DAYS, which teachers can no longer mark themselves. An exec approving the request applies it; days still open are marked directly.
+//	@Tags			Attendance
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateAttendanceCorrectionPayload	true	"Correction"
+//	@Success		201		{object}	dto.AttendanceCorrection
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"A correction for the student and day is already pending"
+//	@Security		ApiKeyAuth
+//	@Router			/attendance/corrections [post]
+//	@ID				createAttendanceCorrection
+func (app *application) createAttendanceCorrectionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAttendanceCorrectionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	date, _ := time.Parse(time.DateOnly, payload.Date)
+	if !app.attendanceLocked(r, date) {
+		app.badRequestResponse(w, r, fmt.Errorf("attendance for %s is still open; mark it directly", payload.Date))
+		return
+	}
+	if !app.requireSchoolDay(w, r, date) {
+		return
+	}
+
+	c := &store.AttendanceCorrection{
+		StudentID:   payload.StudentID,
+		Date:        date,
+		NewStatus:   payload.Status,
+		NewNote:     payload.Note,
+		Reason:      payload.Reason,
+		RequestedBy: getUser(r).ID,
+	}
+	if err := app.store.AttendanceCorrections.Create(r.Context(), c); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("a correction for this student and day is already pending"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromAttendanceCorrection(c)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListAttendanceCorrections godoc
+//
+//	@Summary		List attendance correction requests
+//	@Description	Teachers see their own requests; execs see everyone's and can filter by teacher.
+//	@Tags			Attendance
+//	@Produce		json
+//	@Param			status		query		string	false	"pending, approved or rejected"
+//	@Param			teacher_id	query		int		false	"Requesting teacher (execs only)"
+//	@Success		200			{array}		dto.AttendanceCorrection
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/attendance/corrections [get]
+//	@ID				listAttendanceCorrections
+func (app *application) listAttendanceCorrectionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var f store.AttendanceCorrectionFilter
+
+	if v := q.Get("status"); v != "" {
+		if v != store.CorrectionPending && v != store.CorrectionApproved && v != store.CorrectionRejected {
+			app.badRequestResponse(w, r, errors.New("status must be pending, approved or rejected"))
+			return
+		}
+		f.Status = v
+	}
+	if v := q.Get("teacher_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid teacher_id %q", v))
+			return
+		}
+		f.RequestedBy = id
+	}
+	if user := getUser(r); user.Role == "teacher" {
+		f.RequestedBy = user.ID
+	}
+	f.Scope = getScope(r)
+
+	list, err := app.store.AttendanceCorrections.List(r.Context(), f)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAttendanceCorrections(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ApproveAttendanceCorrection godoc
+//
+//	@Summary		Approve an attendance correction
+//	@Description	Applies the requested status and note to the student's record for the day, creating it if there is none. The change is kept in the attendance record's history and the request keeps what it replaced.
+//	@Tags			Attendance
+//	@Accept			json
+//	@Produce		json
+//	@Param			correctionID	path		int									true	"Correction ID"
+//	@Param			payload			body		ReviewAttendanceCorrectionPayload	false	"Review note"
+//	@Success		200				{object}	dto.AttendanceCorrection
+//	@Failure		404				{object}	error
+//	@Failure		409				{object}	error	"Already reviewed"
+//	@Security		ApiKeyAuth
+//	@Router			/attendance/corrections/{correctionID}/approve [post]
+//	@ID				approveAttendanceCorrection
+func (app *application) approveAttendanceCorrectionHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewAttendanceCorrection(w, r, store.CorrectionApproved)
+}
+
+// RejectAttendanceCorrection godoc
+//
+//	@Summary	Reject an attendance correction
+//	@Tags		Attendance
+//	@Accept		json
+//	@Produce	json
+//	@Param		correctionID	path		int									true	"Correction ID"
+//	@Param		payload			body		ReviewAttendanceCorrectionPayload	false	"Review note"
+//	@Success	200				{object}	dto.AttendanceCorrection
+//	@Failure	404				{object}	error
+//	@Failure	409				{object}	error	"Already reviewed"
+//	@Security	ApiKeyAuth
+//	@Router		/attendance/corrections/{correctionID}/reject [post]
+//	@ID			rejectAttendanceCorrection
+func (app *application) rejectAttendanceCorrectionHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewAttendanceCorrection(w, r, store.CorrectionRejected)
+}
+
+// reviewAttendanceCorrection approves or rejects a pending correction and,
+// when it is approved, records the change in the attendance record's
+// history under the reviewing exec.
+func (app *application) reviewAttendanceCorrection(w http.ResponseWriter, r *http.Request, status string) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "correctionID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload ReviewAttendanceCorrectionPayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	c, err := app.store.AttendanceCorrections.Get(r.Context(), id)
+	if err == nil {
+		if ok, err := app.studentInScope(r, c.StudentID); !app.checkScope(w, r, ok, err) {
+			return
+		}
+		err = app.store.AttendanceCorrections.Review(r.Context(), c, status, getUser(r).ID, payload.Note)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("correction has already been reviewed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if c.RecordID != nil {
+		type fields struct {
+			Status *string `json:"status"`
+			Note   *string `json:"note"`
+		}
+		app.recordChanges(r, "attendance", *c.RecordID,
+			fields{c.PreviousStatus, c.PreviousNote}, fields{&c.NewStatus, c.NewNote})
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromAttendanceCorrection(c)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// counselingMinGroup is the fewest students a group in the counseling
+// statistics may have before its counts are withheld.
+const counselingMinGroup = 5
+
+type CreateReferralPayload struct {
+	StudentID int64  `json:"student_id" validate:"required" example:"42"`
+	Category  string `json:"category" validate:"required,oneof=academic behavioral social_emotional career family other" enums:"academic,behavioral,social_emotional,career,family,other" example:"academic"`
+	Reason    string `json:"reason" validate:"required,max=2000" example:"Grades dropped sharply this month"`
+}
+
+type CreateCounselingSessionPayload struct {
+	// ReferralID is the referral the session answers; it is closed.
+	ReferralID *int64 `json:"referral_id,omitempty" example:"5"`
+	Category   string `json:"category" validate:"required,oneof=academic behavioral social_emotional career family other" enums:"academic,behavioral,social_emotional,career,family,other" example:"academic"`
+	// HeldOn defaults to today.
+	HeldOn     string `json:"held_on" validate:"omitempty,datetime=2006-01-02" example:"2026-10-16"`
+	Notes      string `json:"notes" validate:"required,max=10000"`
+	FollowUpOn string `json:"follow_up_on,omitempty" validate:"omitempty,datetime=2006-01-02" example:"2026-10-30"`
+}
+
+// CreateCounselingReferral godoc
+//
+//	@Summary		Refer a student to counseling
+//	@Description	Teachers can refer the students of their own classrooms. Referrals are only listed to holders of counseling:read.
+//	@Tags			Counseling
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateReferralPayload	true	"Referral"
+//	@Success		201		{object}	dto.CounselingReferral
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/counseling/referrals [post]
+//	@ID				createCounselingReferral
+func (app *application) createCounselingReferralHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateReferralPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUser(r)
+	student, err := app.store.Students.GetByID(ctx, payload.StudentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	if user.Role == "teacher" {
+		classroom, err := app.store.Classrooms.GetByID(ctx, student.ClassRoomID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if classroom == nil || classroom.TeacherID != user.ID {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	} else if ok, err := app.classroomInScope(r, student.ClassRoomID); !app.checkScope(w, r, ok, err) {
+		return
+	}
+
+	ref := &store.CounselingReferral{
+		StudentID:      student.ID,
+		Category:       payload.Category,
+		Reason:         payload.Reason,
+		ReferredBy:     user.ID,
+		ReferredByRole: user.Role,
+	}
+	if err := app.store.Counseling.CreateReferral(ctx, ref); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromCounselingReferral(ref)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListCounselingReferrals godoc
+//
+//	@Summary		List counseling referrals
+//	@Description	Requires the counseling:read permission.
+//	@Tags			Counseling
+//	@Produce		json
+//	@Param			status	query		string	false	"open or closed"
+//	@Success		200		{array}		dto.CounselingReferral
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/counseling/referrals [get]
+//	@ID				listCounselingReferrals
+func (app *application) listCounselingReferralsHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", store.ReferralOpen, store.ReferralClosed:
+	default:
+		app.badRequestResponse(w, r, errors.New("status must be open or closed"))
+		return
+	}
+
+	list, err := app.store.Counseling.Referrals(r.Context(), status)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromCounselingReferrals(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreateCounselingSession godoc
+//
+//	@Summary		Log a counseling session
+//	@Description	Requires the counseling:read permission. A session with a referral_id closes that referral, which must be the student's.
+//	@Tags			Counseling
+//	@Accept			json
+//	@Produce		json
+//	@Param			studentID	path		int								true	"Student ID"
+//	@Param			payload		body		CreateCounselingSessionPayload	true	"Session"
+//	@Success		201			{object}	dto.CounselingSession
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error	"Student or referral not found"
+//	@Security		ApiKeyAuth
+//	@Router			/counseling/students/{studentID}/sessions [post]
+//	@ID				createCounselingSession
+func (app *application) createCounselingSessionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateCounselingSessionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	today := app.schoolToday()
+	heldOn := today
+	if payload.HeldOn != "" {
+		heldOn, _ = time.Parse(time.DateOnly, payload.HeldOn)
+		if heldOn.After(today) {
+			app.badRequestResponse(w, r, errors.New("held_on is in the future"))
+			return
+		}
+	}
+	var followUp *time.Time
+	if payload.FollowUpOn != "" {
+		d, _ := time.Parse(time.DateOnly, payload.FollowUpOn)
+		if d.Before(heldOn) {
+			app.badRequestResponse(w, r, errors.New("follow_up_on is before held_on"))
+			return
+		}
+		followUp = &d
+	}
+
+	user := getUser(r)
+	cs := &store.CounselingSession{
+		StudentID:     getStudentFromCtx(r).ID,
+		ReferralID:    payload.ReferralID,
+		Category:      payload.Category,
+		HeldOn:        heldOn,
+		Notes:         payload.Notes,
+		FollowUpOn:    followUp,
+		CounselorID:   user.ID,
+		CounselorRole: user.Role,
+	}
+	if err := app.store.Counseling.CreateSession(r.Context(), cs); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromCounselingSession(cs)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListCounselingSessions godoc
+//
+//	@Summary		List a student's counseling sessions
+//	@Description	Requires the counseling:read permission. Every read is logged.
+//	@Tags			Counseling
+//	@Produce		json
+//	@Param			studentID	path		int	true	"Student ID"
+//	@Success		200			{array}		dto.CounselingSession
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/counseling/students/{studentID}/sessions [get]
+//	@ID				listCounselingSessions
+func (app *application) listCounselingSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	student := getStudentFromCtx(r)
+	list, err := app.store.Counseling.Sessions(r.Context(), student.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	user := getUser(r)
+	app.logger.Infow("counseling notes read", "student", student.ID, "role", user.Role, "user", user.ID, "sessions", len(list))
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromCounselingSessions(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetCounselingStats godoc
+//
+//	@Summary		Get anonymized counseling statistics
+//	@Description	Session and student counts over the period, overall and by category, grade and month, with referral counts. No notes or names are included, and groups of fewer than five students have their counts withheld. Defaults to the past year.
+//	@Tags			Counseling
+//	@Produce		json
+//	@Param			from	query		string	false	"From date YYYY-MM-DD"
+//	@Param			to		query		string	false	"To date YYYY-MM-DD"
+//	@Success		200		{object}	dto.CounselingStats
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/counseling/stats [get]
+//	@ID				getCounselingStats
+func (app *application) getCounselingStatsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 365)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	stats, err := app.store.Counseling.Stats(r.Context(), from, to, counselingMinGroup)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromCounselingStats(stats)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const deadlineCtxKey ctxKey = "deadline"
+
+// requestDeadline is the budget a request was given, and its context from
+// before the deadline so a longer budget can replace a shorter one.
+type requestDeadline struct {
+	budget time.Duration
+	base   context.Context
+}
+
+// valuesFrom has the deadline and cancellation of its embedded context and
+// the values of another.
+type valuesFrom struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesFrom) Value(key any) any {
+	return c.values.Value(key)
+}
+
+// requestBudget is how long r may take.
+func (app *application) requestBudget(r *http.Request) time.Duration {
+	if d, ok := r.Context().Value(deadlineCtxKey).(*requestDeadline); ok {
+		return d.budget
+	}
+	return app.config.server.requestTimeout
+}
+
+// withBudget gives each request a deadline budget from now, in place of
+// any deadline an earlier withBudget set. Queries derive theirs from it,
+// and handlers answer 504 once it runs out. Long budgets also get the
+// write deadline pushed out to match.
+func (app *application) withBudget(budget time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			base, parent := r.Context(), r.Context()
+			if d, ok := r.Context().Value(deadlineCtxKey).(*requestDeadline); ok {
+				// keep the values added since, but not the earlier deadline
+				base = d.base
+				parent = valuesFrom{Context: d.base, values: r.Context()}
+			}
+			if budget > app.config.server.writeTimeout {
+				// not every writer supports it; the server timeout then applies
+				_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(budget + time.Second))
+			}
+
+			ctx, cancel := context.WithTimeout(parent, budget)
+			defer cancel()
+			ctx = context.WithValue(ctx, deadlineCtxKey, &requestDeadline{budget: budget, base: base})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// DeadlineMiddleware gives every request the default budget.
+func (app *application) DeadlineMiddleware(next http.Handler) http.Handler {
+	return app.withBudget(app.config.server.requestTimeout)(next)
+}
+
+// longBudget gives routes doing bulk work inline the long budget. It is
+// attached where those routes are defined.
+func (app *application) longBudget(next http.Handler) http.Handler {
+	return app.withBudget(app.config.server.longRequestTimeout)(next)
+}
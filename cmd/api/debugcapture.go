@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// debugCaptureSize is how many exchanges the ring buffer keeps.
+	debugCaptureSize = 200
+	// debugBodyLimit caps each captured body.
+	debugBodyLimit = 16 << 10
+)
+
+var (
+	// debugSecretWords mask any JSON key or query parameter containing them.
+	debugSecretWords = []string{"password", "token", "secret", "signature"}
+	// debugSecretKeys mask these exact JSON keys and query parameters.
+	debugSecretKeys = map[string]bool{"code": true, "national_id": true, "authorization": true, "sig": true}
+)
+
+// DebugRule turns on payload capture for one user and/or route prefix until
+// ExpiresAt.
+type DebugRule struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	Route     string    `json:"route,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedBy int64     `json:"created_by"`
+}
+
+// DebugExchange is one captured request and its response.
+type DebugExchange struct {
+	RuleID       int64     `json:"rule_id"`
+	RequestID    string    `json:"request_id,omitempty"`
+	Time         time.Time `json:"time"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Query        string    `json:"query,omitempty"`
+	UserID       int64     `json:"user_id,omitempty"`
+	Role         string    `json:"role,omitempty"`
+	Status       int       `json:"status"`
+	DurationMs   int64     `json:"duration_ms"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+}
+
+// debugCapture holds the active rules and a ring buffer of exchanges. Both
+// live in memory, so each instance only sees its own traffic.
+type debugCapture struct {
+	mu      sync.Mutex
+	nextID  int64
+	rules   []*DebugRule
+	entries []*DebugExchange
+	next    int
+}
+
+// match returns the first live rule covering the request, pruning expired
+// ones.
+func (d *debugCapture) match(path string, userID int64, role string) *DebugRule {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	live := d.rules[:0]
+	var found *DebugRule
+	for _, rule := range d.rules {
+		if !rule.ExpiresAt.After(now) {
+			continue
+		}
+		live = append(live, rule)
+		if found != nil {
+			continue
+		}
+		if rule.Route != "" && !strings.HasPrefix(path, rule.Route) {
+			continue
+		}
+		if rule.UserID != 0 && (rule.UserID != userID || rule.Role != role) {
+			continue
+		}
+		found = rule
+	}
+	d.rules = live
+	return found
+}
+
+func (d *debugCapture) needsUser() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, rule := range d.rules {
+		if rule.UserID != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *debugCapture) active() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.rules) > 0
+}
+
+func (d *debugCapture) addRule(rule *DebugRule) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextID++
+	rule.ID = d.nextID
+	d.rules = append(d.rules, rule)
+}
+
+func (d *debugCapture) removeRule(id int64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, rule := range d.rules {
+		if rule.ID == id {
+			d.rules = append(d.rules[:i], d.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *debugCapture) listRules() []*DebugRule {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	rules := []*DebugRule{}
+	for _, rule := range d.rules {
+		if rule.ExpiresAt.After(now) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func (d *debugCapture) record(e *DebugExchange) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.entries) < debugCaptureSize {
+		d.entries = append(d.entries, e)
+		return
+	}
+	d.entries[d.next] = e
+	d.next = (d.next + 1) % debugCaptureSize
+}
+
+// exchanges returns captured exchanges newest first, optionally for one rule.
+func (d *debugCapture) exchanges(ruleID int64) []*DebugExchange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := []*DebugExchange{}
+	for i := len(d.entries) - 1; i >= 0; i-- {
+		e := d.entries[(d.next+i)%len(d.entries)]
+		if ruleID == 0 || e.RuleID == ruleID {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (d *debugCapture) clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = nil
+	d.next = 0
+}
+
+// limitedBuffer keeps the first limit bytes written to it and counts the
+// rest.
+type limitedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - b.buf.Len()
+	if room < len(p) {
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		b.dropped += len(p) - max(room, 0)
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// sanitize returns body with sensitive JSON fields masked. Bodies that
+// aren't JSON are summarised rather than stored.
+func (b *limitedBuffer) sanitize() string {
+	if b.buf.Len() == 0 {
+		return ""
+	}
+	var doc any
+	if b.dropped > 0 || json.Unmarshal(b.buf.Bytes(), &doc) != nil {
+		return fmt.Sprintf("[%d bytes, not captured]", b.buf.Len()+b.dropped)
+	}
+	redactJSON(doc)
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func redactJSON(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if isSecretKey(k) {
+				v[k] = "[redacted]"
+				continue
+			}
+			redactJSON(child)
+		}
+	case []any:
+		for _, child := range v {
+			redactJSON(child)
+		}
+	}
+}
+
+// redactQuery returns the raw query with the values of secret parameters,
+// such as signed links' sig, masked. A query that can't be parsed is
+// dropped.
+func redactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	qs, err := url.ParseQuery(raw)
+	if err != nil {
+		return "[unparseable query, not captured]"
+	}
+	for k := range qs {
+		if isSecretKey(k) {
+			qs[k] = []string{"REDACTED"}
+		}
+	}
+	return qs.Encode()
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if debugSecretKeys[key] {
+		return true
+	}
+	for _, word := range debugSecretWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// DebugCaptureMiddleware records sanitised request and response bodies for
+// requests matching an active capture rule.
+func (app *application) DebugCaptureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.debug.active() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var userID int64
+		var role string
+		if app.debug.needsUser() {
+			if claims := app.tokenClaims(r); claims != nil {
+				userID, role = claims.ID, claims.Role
+			}
+		}
+
+		rule := app.debug.match(r.URL.Path, userID, role)
+		if rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqBody := &limitedBuffer{limit: debugBodyLimit}
+		if r.Body != nil {
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(r.Body, reqBody), r.Body}
+		}
+
+		respBody := &limitedBuffer{limit: debugBodyLimit}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(respBody)
+
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		app.debug.record(&DebugExchange{
+			RuleID:       rule.ID,
+			RequestID:    middleware.GetReqID(r.Context()),
+			Time:         start.UTC(),
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Query:        redactQuery(r.URL.RawQuery),
+			UserID:       userID,
+			Role:         role,
+			Status:       ww.Status(),
+			DurationMs:   time.Since(start).Milliseconds(),
+			RequestBody:  reqBody.sanitize(),
+			ResponseBody: respBody.sanitize(),
+		})
+	})
+}
+
+type DebugRulePayload struct {
+	// UserID and Role select one account; both or neither.
+	UserID int64  `json:"user_id,omitempty" validate:"required_with=Role,omitempty,min=1"`
+	Role   string `json:"role,omitempty" validate:"required_with=UserID,omitempty,oneof=admin manager teacher student parent" enums:"admin,manager,teacher,student,parent" example:"admin"`
+	// Route is a path prefix such as /v1/attendance.
+	Route string `json:"route,omitempty" validate:"omitempty,startswith=/v1/,max=256"`
+	// TTLMinutes is how long capture stays on; at most a day.
+	TTLMinutes int `json:"ttl_minutes" validate:"required,min=1,max=1440"`
+}
+
+// CreateDebugRule godoc
+//
+//	@Summary		Start capturing request and response bodies
+//	@Description	Captures sanitised bodies of requests from one user and/or under one route prefix until the TTL runs out. Passwords, tokens, codes, link signatures and national IDs are masked, in bodies and query strings alike. Captures are kept in memory on the instance that served the request.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		DebugRulePayload	true	"Capture rule"
+//	@Success		201		{object}	DebugRule
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/debug/rules [post]
+//	@ID				createDebugRule
+func (app *application) createDebugRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload DebugRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if payload.UserID == 0 && payload.Route == "" {
+		app.badRequestResponse(w, r, fmt.Errorf("give a user, a route or both"))
+		return
+	}
+
+	rule := &DebugRule{
+		UserID:    payload.UserID,
+		Role:      payload.Role,
+		Route:     payload.Route,
+		ExpiresAt: time.Now().Add(time.Duration(payload.TTLMinutes) * time.Minute).UTC(),
+		CreatedBy: getUser(r).ID,
+	}
+	app.debug.addRule(rule)
+
+	app.logger.Infow("debug capture started", "rule", rule.ID, "user", rule.UserID, "route", rule.Route, "by", rule.CreatedBy)
+
+	if err := app.jsonResponse(w, http.StatusCreated, rule); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListDebugRules godoc
+//
+//	@Summary	List active capture rules
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{array}	DebugRule
+//	@Security	ApiKeyAuth
+//	@Router		/admin/debug/rules [get]
+//	@ID			listDebugRules
+func (app *application) listDebugRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, app.debug.listRules()); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteDebugRule godoc
+//
+//	@Summary	Stop a capture rule
+//	@Tags		Admin
+//	@Param		ruleID	path	int	true	"Rule ID"
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/admin/debug/rules/{ruleID} [delete]
+//	@ID			deleteDebugRule
+func (app *application) deleteDebugRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if !app.debug.removeRule(id) {
+		app.notfoundResponse(w, r, fmt.Errorf("capture rule %d not found", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDebugExchanges godoc
+//
+//	@Summary	List captured requests, newest first
+//	@Tags		Admin
+//	@Produce	json
+//	@Param		rule	query	int	false	"Only exchanges captured by this rule"
+//	@Success	200		{array}	DebugExchange
+//	@Security	ApiKeyAuth
+//	@Router		/admin/debug/requests [get]
+//	@ID			listDebugExchanges
+func (app *application) listDebugExchangesHandler(w http.ResponseWriter, r *http.Request) {
+	var ruleID int64
+	if s := r.URL.Query().Get("rule"); s != "" {
+		var err error
+		if ruleID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, app.debug.exchanges(ruleID)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ClearDebugExchanges godoc
+//
+//	@Summary	Discard captured requests
+//	@Tags		Admin
+//	@Success	204
+//	@Security	ApiKeyAuth
+//	@Router		/admin/debug/requests [delete]
+//	@ID			clearDebugExchanges
+func (app *application) clearDebugExchangesHandler(w http.ResponseWriter, r *http.Request) {
+	app.debug.clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// GetDependencies godoc
+//
+//	@Summary		Count the records a delete would affect
+//	@Description	Returns, per kind, how many related records point at the student, teacher or classroom, e.g. attendance records, behavior points and open asset loans, for a delete confirmation dialog.
+//	@Tags			Dependencies
+//	@Produce		json
+//	@Param			id	path		int	true	"Student, teacher or classroom ID"
+//	@Success		200	{object}	map[string]int64
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{id}/dependencies [get]
+//	@Router			/teachers/{id}/dependencies [get]
+//	@Router			/classrooms/{id}/dependencies [get]
+//	@ID				getDependencies
+func (app *application) getDependenciesHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+
+		counts, err := app.store.Dependencies.Count(r.Context(), entity, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				app.notfoundResponse(w, r, err)
+				return
+			}
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusOK, counts); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateDiscountRulePayload struct {
+	Name    string `json:"name" validate:"required,max=100"`
+	Kind    string `json:"kind" validate:"required,oneof=sibling staff_child scholarship" enums:"sibling,staff_child,scholarship" example:"sibling"`
+	Percent int    `json:"percent" validate:"min=0,max=100"`
+	// MinSiblingRank is the first child, eldest first, a sibling rule
+	// applies to; defaults to 2.
+	MinSiblingRank int   `json:"min_sibling_rank" validate:"omitempty,min=2"`
+	Active         *bool `json:"active"`
+}
+
+type UpdateDiscountRulePayload struct {
+	Name           *string `json:"name" validate:"omitempty,max=100"`
+	Percent        *int    `json:"percent" validate:"omitempty,min=0,max=100"`
+	MinSiblingRank *int    `json:"min_sibling_rank" validate:"omitempty,min=2"`
+	Active         *bool   `json:"active"`
+}
+
+type SetStudentDiscountPayload struct {
+	// Percent replaces the rule's for the student; null keeps it and 0
+	// exempts them.
+	Percent *int   `json:"percent" validate:"omitempty,min=0,max=100"`
+	Note    string `json:"note" validate:"max=500" example:"Doctor's appointment"`
+}
+
+type StudentDiscountsResponse struct {
+	Facts     *dto.DiscountFacts     `json:"facts"`
+	Overrides []*dto.StudentDiscount `json:"overrides"`
+}
+
+// priceInvoices works out the lines of a fee of amount for each student:
+// the fee, then a discount per active rule that applies, in rule order.
+// Discounts are percentages of the fee and never take the total below
+// zero.
+func (app *application) priceInvoices(ctx context.Context, studentIDs []int64, title string, amount int64) (map[int64][]*store.InvoiceLine, error) {
+	rules, err := app.store.Discounts.Rules(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	facts, err := app.store.Discounts.Facts(ctx, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[int64][]*store.InvoiceLine, len(studentIDs))
+	for _, id := range studentIDs {
+		lines[id] = discountLines(title, amount, rules, facts[id])
+	}
+	return lines, nil
+}
+
+func discountLines(title string, amount int64, rules []*store.DiscountRule, f *store.DiscountFacts) []*store.InvoiceLine {
+	lines := []*store.InvoiceLine{{Description: title, Amount: amount}}
+	if f == nil {
+		return lines
+	}
+
+	left := amount
+	for _, rule := range rules {
+		override := f.Overrides[rule.ID]
+		var reason string
+		switch rule.Kind {
+		case store.DiscountSibling:
+			if f.SiblingRank < rule.MinSiblingRank {
+				continue
+			}
+			reason = fmt.Sprintf("child %d of %d in the family", f.SiblingRank, f.Siblings)
+		case store.DiscountStaffChild:
+			if !f.StaffChild {
+				continue
+			}
+			reason = "parent is on the staff"
+		case store.DiscountScholarship:
+			if override == nil {
+				continue
+			}
+			reason = "scholarship granted"
+		default:
+			continue
+		}
+
+		percent := rule.Percent
+		if override != nil && override.Percent != nil {
+			percent = *override.Percent
+			reason += fmt.Sprintf("; %d%% set for the student", percent)
+		}
+		if override != nil && override.Note != "" {
+			reason += ": " + override.Note
+		}
+
+		discount := min(amount*int64(percent)/100, left)
+		if discount <= 0 {
+			continue
+		}
+		left -= discount
+		lines = append(lines, &store.InvoiceLine{
+			Description: rule.Name,
+			Amount:      -discount,
+			RuleID:      &rule.ID,
+			Percent:     &percent,
+			Reason:      reason,
+		})
+	}
+	return lines
+}
+
+// linesTotal is what is left to pay of lines.
+func linesTotal(lines []*store.InvoiceLine) int64 {
+	var total int64
+	for _, l := range lines {
+		total += l.Amount
+	}
+	return total
+}
+
+// CreateDiscountRule godoc
+//
+//	@Summary		Create a discount rule
+//	@Description	Active rules are applied, in the order they were created, to every invoice created afterwards. Sibling rules apply from the min_sibling_rank-th child of a family (eldest first), staff_child rules to children of teachers and execs, and scholarship rules only to students granted one with PUT /discounts/students/{studentID}/rules/{ruleID}.
+//	@Tags			Invoices
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateDiscountRulePayload	true	"Rule"
+//	@Success		201		{object}	dto.DiscountRule
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/discounts/rules [post]
+//	@ID				createDiscountRule
+func (app *application) createDiscountRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateDiscountRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rule := &store.DiscountRule{
+		Name:           payload.Name,
+		Kind:           payload.Kind,
+		Percent:        payload.Percent,
+		MinSiblingRank: max(payload.MinSiblingRank, 2),
+		Active:         payload.Active == nil || *payload.Active,
+	}
+	if err := app.store.Discounts.CreateRule(r.Context(), rule); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromDiscountRule(rule)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListDiscountRules godoc
+//
+//	@Summary	List discount rules
+//	@Tags		Invoices
+//	@Produce	json
+//	@Success	200	{array}	dto.DiscountRule
+//	@Security	ApiKeyAuth
+//	@Router		/discounts/rules [get]
+//	@ID			listDiscountRules
+func (app *application) listDiscountRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := app.store.Discounts.Rules(r.Context(), false)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromDiscountRules(rules)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateDiscountRule godoc
+//
+//	@Summary		Update a discount rule
+//	@Description	Only affects invoices created afterwards.
+//	@Tags			Invoices
+//	@Accept			json
+//	@Produce		json
+//	@Param			ruleID	path		int							true	"Rule ID"
+//	@Param			payload	body		UpdateDiscountRulePayload	true	"Fields to change"
+//	@Success		200		{object}	dto.DiscountRule
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/discounts/rules/{ruleID} [patch]
+//	@ID				updateDiscountRule
+func (app *application) updateDiscountRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload UpdateDiscountRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rule, err := app.store.Discounts.GetRule(r.Context(), id)
+	if err == nil {
+		if payload.Name != nil {
+			rule.Name = *payload.Name
+		}
+		if payload.Percent != nil {
+			rule.Percent = *payload.Percent
+		}
+		if payload.MinSiblingRank != nil {
+			rule.MinSiblingRank = *payload.MinSiblingRank
+		}
+		if payload.Active != nil {
+			rule.Active = *payload.Active
+		}
+		err = app.store.Discounts.UpdateRule(r.Context(), rule)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromDiscountRule(rule)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteDiscountRule godoc
+//
+//	@Summary		Delete a discount rule
+//	@Description	Removes its student overrides too. Lines it produced on issued invoices stay, without the link to the rule; deactivate the rule to keep it.
+//	@Tags			Invoices
+//	@Param			ruleID	path	int	true	"Rule ID"
+//	@Success		204
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/discounts/rules/{ruleID} [delete]
+//	@ID				deleteDiscountRule
+func (app *application) deleteDiscountRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Discounts.DeleteRule(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStudentDiscounts godoc
+//
+//	@Summary		Show what discounts a student gets
+//	@Description	The facts the rules look at (place among siblings, staff child) and the student's overrides.
+//	@Tags			Invoices
+//	@Produce		json
+//	@Param			studentID	path		int	true	"Student ID"
+//	@Success		200			{object}	StudentDiscountsResponse
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/discounts/students/{studentID} [get]
+//	@ID				getStudentDiscounts
+func (app *application) getStudentDiscountsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "studentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	facts, err := app.store.Discounts.Facts(r.Context(), []int64{id})
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	f, ok := facts[id]
+	if !ok {
+		app.notfoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+	overrides, err := app.store.Discounts.Overrides(r.Context(), id)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, StudentDiscountsResponse{Facts: dto.FromDiscountFacts(f), Overrides: dto.FromStudentDiscounts(overrides)}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// SetStudentDiscount godoc
+//
+//	@Summary		Override a discount rule for a student
+//	@Description	Sets the percent the rule gives the student (0 exempts them), or grants a scholarship rule. Only affects invoices created afterwards. With fee_waiver in APPROVAL_REQUIRED_ACTIONS, an override of APPROVAL_WAIVER_PERCENT or more is staged until a second admin approves it.
+//	@Tags			Invoices
+//	@Accept			json
+//	@Produce		json
+//	@Param			studentID	path		int							true	"Student ID"
+//	@Param			ruleID		path		int							true	"Rule ID"
+//	@Param			payload		body		SetStudentDiscountPayload	true	"Override"
+//	@Success		200			{object}	dto.StudentDiscount
+//	@Success		202			{object}	dto.Approval	"Staged for approval"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/discounts/students/{studentID}/rules/{ruleID} [put]
+//	@ID				setStudentDiscount
+func (app *application) setStudentDiscountHandler(w http.ResponseWriter, r *http.Request) {
+	studentID, err := strconv.ParseInt(chi.URLParam(r, "studentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	ruleID, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SetStudentDiscountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	creator := getUser(r).ID
+	override := &store.StudentDiscount{
+		StudentID: studentID,
+		RuleID:    ruleID,
+		Percent:   payload.Percent,
+		Note:      payload.Note,
+		CreatedBy: &creator,
+	}
+
+	if app.approvals[approvalFeeWaiver] {
+		rule, err := app.store.Discounts.GetRule(r.Context(), ruleID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, errors.New("student or rule not found"))
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		percent := rule.Percent
+		if payload.Percent != nil {
+			percent = *payload.Percent
+		}
+		if percent >= app.config.approvals.waiverPercent {
+			summary := fmt.Sprintf("Give student %d %d%% off under %q", studentID, percent, rule.Name)
+			app.stageApproval(w, r, approvalFeeWaiver, summary, override)
+			return
+		}
+	}
+
+	if err := app.store.Discounts.SetOverride(r.Context(), override); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, errors.New("student or rule not found"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudentDiscount(override)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteStudentDiscount godoc
+//
+//	@Summary	Remove a student's override of a discount rule
+//	@Tags		Invoices
+//	@Param		studentID	path	int	true	"Student ID"
+//	@Param		ruleID		path	int	true	"Rule ID"
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/discounts/students/{studentID}/rules/{ruleID} [delete]
+//	@ID			deleteStudentDiscount
+func (app *application) deleteStudentDiscountHandler(w http.ResponseWriter, r *http.Request) {
+	studentID, err := strconv.ParseInt(chi.URLParam(r, "studentID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	ruleID, err := strconv.ParseInt(chi.URLParam(r, "ruleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Discounts.DeleteOverride(r.Context(), studentID, ruleID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
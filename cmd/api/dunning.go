@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDunningSchedule parses the comma-separated days overdue at which
+// reminders go out, e.g. "1,7,14,30".
+func parseDunningSchedule(s string) ([]int, error) {
+	var days []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := strconv.Atoi(part)
+		if err != nil || d < 1 || (len(days) > 0 && d <= days[len(days)-1]) {
+			return nil, fmt.Errorf("invalid dunning schedule %q; expected increasing days overdue such as 1,7,14,30", s)
+		}
+		days = append(days, d)
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("empty dunning schedule")
+	}
+	return days, nil
+}
+
+// startDunning reminds parents of overdue installments once a day after
+// the configured time. Each installment gets a reminder at every step of
+// the schedule it reaches, worded more firmly each time.
+func (app *application) startDunning() {
+	if !app.config.dunning.enabled {
+		return
+	}
+
+	schedule, err := parseDunningSchedule(app.config.dunning.schedule)
+	if err != nil {
+		app.logger.Errorw("fee reminders disabled", "error", err.Error())
+		return
+	}
+	at, err := parseClock(app.config.dunning.time)
+	if err != nil {
+		app.logger.Errorw("fee reminders disabled", "error", err.Error())
+		return
+	}
+
+	var lastRun string
+	ticker := time.NewTicker(attendanceReminderTick)
+	go func() {
+		for range ticker.C {
+			now := app.school.now()
+			today := now.Format(time.DateOnly)
+			midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			if today == lastRun || now.Sub(midnight) < at {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			sent, err := app.sendDunningReminders(ctx, midnight, schedule)
+			cancel()
+			if err != nil {
+				app.logger.Errorw("sending fee reminders failed", "error", err.Error())
+				continue // retried on the next tick; sent ones are claimed
+			}
+			lastRun = today
+			app.logger.Infow("fee reminders sent", "date", today, "count", sent)
+		}
+	}()
+}
+
+// sendDunningReminders sends each overdue installment the reminder for the
+// last step of schedule it has reached, unless already sent. Steps missed
+// while the server was down are skipped rather than sent together.
+func (app *application) sendDunningReminders(ctx context.Context, today time.Time, schedule []int) (int, error) {
+	overdue, err := app.store.Invoices.Overdue(ctx, today)
+	if err != nil {
+		return 0, err
+	}
+
+	// due dates come back as UTC midnight
+	todayUTC := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	sent := 0
+	for _, o := range overdue {
+		days := int(todayUTC.Sub(o.DueOn).Hours() / 24)
+		level := 0
+		for level < len(schedule) && days >= schedule[level] {
+			level++
+		}
+		if level <= o.RemindersSent || o.ParentPhone == "" {
+			continue
+		}
+		claimed, err := app.store.Invoices.ClaimReminder(ctx, o.ID, level)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		what := fmt.Sprintf("%s for %s", o.Title, o.StudentName)
+		if o.Installments > 1 {
+			what = fmt.Sprintf("installment %d of %d of %s", o.Seq, o.Installments, what)
+		}
+		var text string
+		switch {
+		case level == len(schedule) && level > 1:
+			text = fmt.Sprintf("Final notice: %s (%s) is %d days overdue. Please pay now or contact the school office.",
+				what, app.formatMoney(o.Amount), days)
+		case level > 1:
+			text = fmt.Sprintf("Overdue notice: %s (%s) is %d days overdue. Please pay it in ClassNama.",
+				what, app.formatMoney(o.Amount), days)
+		default:
+			text = fmt.Sprintf("Reminder: %s (%s) was due on %s. You can pay it in ClassNama.",
+				what, app.formatMoney(o.Amount), o.DueOn.Format(time.DateOnly))
+		}
+
+		smsErr := app.sms.Send(ctx, o.ParentPhone, text)
+		if smsErr != nil {
+			app.logger.Warnw("fee reminder sms failed", "installment", o.ID, "error", smsErr.Error())
+		}
+		chats, err := app.notifyTelegram(ctx, []string{o.ParentPhone}, text)
+		if err != nil {
+			app.logger.Warnw("fee reminder telegram failed", "installment", o.ID, "error", err.Error())
+		}
+		if smsErr == nil || chats > 0 {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
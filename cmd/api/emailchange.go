@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// emailChangeLinkTTL is how long a confirmation link works.
+const emailChangeLinkTTL = 48 * time.Hour
+
+func (app *application) emailChangeSignature(kind string, id int64, email string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	fmt.Fprintf(mac, "email-change:%s:%d:%s:%d", kind, id, strings.ToLower(email), expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// emailChangeLink returns the signed confirmation link sent to the new
+// address.
+func (app *application) emailChangeLink(kind string, id int64, email string) string {
+	expires := time.Now().Add(emailChangeLinkTTL).Unix()
+	q := url.Values{
+		"email":   {email},
+		"expires": {strconv.FormatInt(expires, 10)},
+		"sig":     {app.emailChangeSignature(kind, id, email, expires)},
+	}
+	return fmt.Sprintf("%s/v1/email-changes/%s/%d?%s",
+		strings.TrimSuffix(app.config.publicURL, "/"), kind, id, q.Encode())
+}
+
+// startEmailChange takes "email" out of a PATCH's fields and, when it
+// differs from current, records it as pending, mails a confirmation link to
+// it and tells the current address. It writes the error response and
+// returns false on failure.
+func (app *application) startEmailChange(w http.ResponseWriter, r *http.Request, kind string, id int64, name, current string, fields map[string]any) bool {
+	value, ok := fields["email"]
+	if !ok {
+		return true
+	}
+	delete(fields, "email")
+
+	email, _ := value.(string)
+	if strings.EqualFold(email, current) {
+		return true
+	}
+
+	if !app.emailAvailable(w, r, email, &store.AccountRef{Kind: kind, ID: id}) {
+		return false
+	}
+
+	ctx := r.Context()
+	if err := app.store.EmailChanges.Request(ctx, kind, id, email); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notfoundResponse(w, r, err)
+			return false
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return false
+	}
+
+	err := app.mailer.Send(ctx, mailer.Message{
+		To:      []string{email},
+		Subject: "Confirm your new ClassNama email address",
+		Body: fmt.Sprintf("Hello %s,\n\nYour ClassNama email is being changed to this address. Open the link below within %d hours to confirm; until then your current address keeps working.\n\n%s\n",
+			name, int(emailChangeLinkTTL.Hours()), app.emailChangeLink(kind, id, email)),
+	})
+	if err != nil {
+		if errors.Is(err, mailer.ErrNotConfigured) {
+			app.serviceUnavailableResponse(w, r, errors.New("email changes need SMTP to send the confirmation"))
+			return false
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return false
+	}
+
+	if current != "" {
+		err := app.mailer.Send(ctx, mailer.Message{
+			To:      []string{current},
+			Subject: "Your ClassNama email is being changed",
+			Body: fmt.Sprintf("Hello %s,\n\nA change of your ClassNama email to %s was requested. It takes effect only once confirmed from the new address. If you did not expect this, contact the school office.\n",
+				name, email),
+		})
+		if err != nil {
+			app.logger.Warnw("email change notice failed", "kind", kind, "id", id, "error", err.Error())
+		}
+	}
+
+	app.logger.Infow("email change requested", "kind", kind, "id", id, "by", getUser(r).ID)
+	return true
+}
+
+// EmailChangeView is what a confirmation link shows before it is used.
+type EmailChangeView struct {
+	Kind  string `json:"kind"`
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+// loadEmailChange checks a confirmation link's signature and expiry.
+func (app *application) loadEmailChange(w http.ResponseWriter, r *http.Request) (*EmailChangeView, bool) {
+	kind := chi.URLParam(r, "kind")
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	email := r.URL.Query().Get("email")
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires ||
+		!hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(app.emailChangeSignature(kind, id, email, expires))) {
+		app.unauthorizedResponse(w, r, fmt.Errorf("invalid or expired link"))
+		return nil, false
+	}
+
+	return &EmailChangeView{Kind: kind, ID: id, Email: email}, true
+}
+
+// GetEmailChange godoc
+//
+//	@Summary		Show a pending email change
+//	@Description	Opened from the confirmation link; nothing changes until the link is POSTed.
+//	@Tags			Accounts
+//	@Produce		json
+//	@Param			kind	path		string	true	"exec, teacher or student"
+//	@Param			id		path		int		true	"Account ID"
+//	@Param			email	query		string	true	"New email"
+//	@Param			expires	query		int		true	"Link expiry (unix seconds)"
+//	@Param			sig		query		string	true	"Link signature"
+//	@Success		200		{object}	EmailChangeView
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error	"Already confirmed or replaced by a newer request"
+//	@Router			/email-changes/{kind}/{id} [get]
+//	@ID				getEmailChange
+func (app *application) getEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	view, ok := app.loadEmailChange(w, r)
+	if !ok {
+		return
+	}
+
+	pending, err := app.store.EmailChanges.Pending(r.Context(), view.Kind, view.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if !strings.EqualFold(pending, view.Email) {
+		app.notfoundResponse(w, r, fmt.Errorf("this email change is no longer pending"))
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, view); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ConfirmEmailChange godoc
+//
+//	@Summary		Confirm an email change
+//	@Description	Makes the new address the account's email and tells the old address.
+//	@Tags			Accounts
+//	@Produce		json
+//	@Param			kind	path		string	true	"exec, teacher or student"
+//	@Param			id		path		int		true	"Account ID"
+//	@Param			email	query		string	true	"New email"
+//	@Param			expires	query		int		true	"Link expiry (unix seconds)"
+//	@Param			sig		query		string	true	"Link signature"
+//	@Success		200		{object}	EmailChangeView
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error	"Already confirmed or replaced by a newer request"
+//	@Failure		409		{object}	emailConflictResponse	"The address was taken in the meantime"
+//	@Router			/email-changes/{kind}/{id} [post]
+//	@ID				confirmEmailChange
+func (app *application) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	view, ok := app.loadEmailChange(w, r)
+	if !ok {
+		return
+	}
+
+	// the address may have been taken since the change was requested
+	if !app.emailAvailable(w, r, view.Email, &store.AccountRef{Kind: view.Kind, ID: view.ID}) {
+		return
+	}
+
+	ctx := r.Context()
+	previous, err := app.store.EmailChanges.Confirm(ctx, view.Kind, view.ID, view.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, fmt.Errorf("this email change is no longer pending"))
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("email already registered"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if previous != "" {
+		err := app.mailer.Send(ctx, mailer.Message{
+			To:      []string{previous},
+			Subject: "Your ClassNama email was changed",
+			Body: fmt.Sprintf("Hello,\n\nYour ClassNama email was changed to %s. You will sign in with the new address from now on. If you did not expect this, contact the school office.\n",
+				view.Email),
+		})
+		if err != nil {
+			app.logger.Warnw("email change notice failed", "kind", view.Kind, "id", view.ID, "error", err.Error())
+		}
+	}
+
+	app.logger.Infow("email change confirmed", "kind", view.Kind, "id", view.ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, view); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -2,36 +2,84 @@ package main
 
 import (
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 )
 
 func (app *application) internalServerErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if store.IsTimeout(err) {
+		app.timeoutResponse(w, r, err)
+		return
+	}
 	app.logger.Errorw("internal error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusInternalServerError, "the server encountered a problem")
+	app.reportError(r, err, nil)
+	writeError(w, r, http.StatusInternalServerError, "the server encountered a problem")
 }
 
 func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
 	app.logger.Warnw("forbidden", "method", r.Method, "path", r.URL.Path, "error")
-	writeJSONError(w, http.StatusForbidden, "forbidden")
+	writeError(w, r, http.StatusForbidden, "forbidden")
 }
 
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Warnf("bad request", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusBadRequest, err.Error())
+	writeError(w, r, http.StatusBadRequest, err.Error())
 }
 
 func (app *application) notfoundResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Errorf("not found", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusNotFound, "not found")
+	writeError(w, r, http.StatusNotFound, "not found")
+}
+
+func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("conflict", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	writeError(w, r, http.StatusConflict, err.Error())
 }
 
 func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Warnf("unauthorized error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
-	writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+	writeError(w, r, http.StatusUnauthorized, "unauthorized")
+}
+
+func (app *application) serviceUnavailableResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("service unavailable", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+	writeError(w, r, http.StatusServiceUnavailable, err.Error())
+}
+
+// timeoutResponse answers a request that ran out of its time budget.
+func (app *application) timeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	budget := app.requestBudget(r)
+	app.logger.Warnw("request timed out", "method", r.Method, "path", r.URL.Path, "budget", budget.String(), "error", err.Error())
+	if isV2(r) {
+		writeError(w, r, http.StatusGatewayTimeout, "the request took too long")
+		return
+	}
+
+	type envelope struct {
+		Error         string `json:"error"`
+		Code          string `json:"code"`
+		BudgetSeconds int    `json:"budget_seconds"`
+	}
+
+	writeJSON(w, http.StatusGatewayTimeout, &envelope{
+		Error:         "the request took too long",
+		Code:          "timeout",
+		BudgetSeconds: int(budget.Seconds()),
+	})
+}
+
+func (app *application) quotaExceededResponse(w http.ResponseWriter, r *http.Request, keyID int64, resetsAt time.Time) {
+	app.logger.Warnw("API key quota exceeded", "method", r.Method, "path", r.URL.Path, "api_key", keyID)
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(resetsAt).Seconds())+1))
+
+	writeError(w, r, http.StatusTooManyRequests, "API key quota exceeded, resets at "+resetsAt.Format(time.RFC3339))
 }
 
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter string) {
 	app.logger.Warnw("rate limit exceeded", "method", r.Method, "path", r.URL.Path)
 	w.Header().Set("Retry-After", retryAfter)
 
-	writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
+	writeError(w, r, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
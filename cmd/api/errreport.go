@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/errreport"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// reportError sends err to the error reporter with the request's ID, route
+// and caller.
+func (app *application) reportError(r *http.Request, err error, stack []byte) {
+	e := &errreport.Event{
+		Err:       err,
+		Panic:     stack != nil,
+		Stack:     stack,
+		RequestID: middleware.GetReqID(r.Context()),
+		Method:    r.Method,
+		URL:       r.URL.String(),
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		e.Route = rctx.RoutePattern()
+	}
+
+	// outer middleware runs before AuthTokenMiddleware has set the user
+	claims := getUser(r)
+	if claims == nil {
+		claims = app.tokenClaims(r)
+	}
+	if claims != nil {
+		e.UserID, e.Email, e.Role = claims.ID, claims.Email, claims.Role
+	}
+
+	app.reporter.Report(e)
+}
+
+// RecovererMiddleware turns a panic into a 500, logging and reporting it
+// with its stack. It replaces middleware.Recoverer.
+func (app *application) RecovererMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				// the client went away; let net/http handle it
+				panic(rec)
+			}
+
+			stack := debug.Stack()
+			err, ok := rec.(error)
+			if !ok {
+				err = fmt.Errorf("%v", rec)
+			}
+
+			app.logger.Errorw("panic", "method", r.Method, "path", r.URL.Path, "error", err.Error(), "stack", string(stack))
+			app.reportError(r, err, stack)
+
+			if r.Header.Get("Connection") != "Upgrade" {
+				writeError(w, r, http.StatusInternalServerError, "the server encountered a problem")
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"github.com/MahdiiTaheri/classnama-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -17,10 +19,10 @@ type execKey string
 const execCtx execKey = "exec"
 
 type UpdateExecPayload struct {
-	FirstName *string     `json:"first_name,omitempty" validate:"omitempty,max=72"`
-	LastName  *string     `json:"last_name,omitempty" validate:"omitempty,max=72"`
-	Email     *string     `json:"email,omitempty" validate:"omitempty,email"`
-	Role      *store.Role `json:"role,omitempty" validate:"omitempty,oneof=admin manager"`
+	FirstName *string     `json:"first_name,omitempty" validate:"omitempty,max=72" example:"Sara"`
+	LastName  *string     `json:"last_name,omitempty" validate:"omitempty,max=72" example:"Ahmadi"`
+	Email     *string     `json:"email,omitempty" validate:"omitempty,email" example:"sara.ahmadi@example.com"`
+	Role      *store.Role `json:"role,omitempty" validate:"omitempty,oneof=admin manager" enums:"admin,manager" example:"admin"`
 }
 
 // GetExecs godoc
@@ -29,17 +31,28 @@ type UpdateExecPayload struct {
 //	@Description	Returns a list of all execs
 //	@Tags			Execs
 //	@Accept			json
-//	@Produce		json
-//	@Success		200	{array}		store.Exec	"List of execs"
-//	@Failure		500	{object}	error		"Internal server error"
+//	@Produce		json,text/csv,application/xml
+//	@Param			fields			query		string		false	"Comma-separated fields to return, e.g. id,first_name,last_name"
+//	@Param			role			query		string		false	"admin or manager"
+//	@Param			status			query		string		false	"active (has signed in), pending (never has) or deactivated"
+//	@Param			created_from	query		string		false	"Added on or after this date (YYYY-MM-DD)"
+//	@Param			created_to		query		string		false	"Added on or before this date (YYYY-MM-DD)"
+//	@Param			last_login_from	query		string		false	"Last signed in on or after this date (YYYY-MM-DD)"
+//	@Param			last_login_to	query		string		false	"Last signed in on or before this date (YYYY-MM-DD)"
+//	@Success		200				{array}		dto.Exec	"List of execs"
+//	@Failure		400				{object}	error		"Invalid filter"
+//	@Failure		500				{object}	error		"Internal server error"
 //	@Security		ApiKeyAuth
 //	@Router			/execs [get]
 //	@ID				getExecs
 func (app *application) getExecsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listExecs(w, r, v1Lists)
+}
+
+func (app *application) listExecs(w http.ResponseWriter, r *http.Request, lists listAdapter) {
 	ctx := r.Context()
 
-	pq := store.PaginatedQuery{Limit: 10, Offset: 0, SortBy: "id", Order: "asc"}
-	pq, err := pq.Parse(r)
+	pq, err := lists.parse(r, defaultListQuery)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -48,14 +61,20 @@ func (app *application) getExecsHandler(w http.ResponseWriter, r *http.Request)
 		app.badRequestResponse(w, r, err)
 		return
 	}
-
-	params := map[string]any{
-		"limit":  pq.Limit,
-		"offset": pq.Offset,
-		"sort":   pq.SortBy,
-		"order":  pq.Order,
+	if role := r.URL.Query().Get("role"); role != "" {
+		if role != string(store.RoleAdmin) && role != string(store.RoleManager) {
+			app.badRequestResponse(w, r, errors.New("role must be admin or manager"))
+			return
+		}
+		pq.Filters = map[string]any{"role": role}
+	}
+	if pq.Account, err = app.accountFilter(r); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
+	params := listCacheParams(pq)
+
 	execs, err := cache.GetListWithCache(
 		ctx,
 		app.cacheStorage.Execs,
@@ -66,11 +85,22 @@ func (app *application) getExecsHandler(w http.ResponseWriter, r *http.Request)
 		},
 	)
 	if err != nil {
+		if errors.Is(err, store.ErrInvalidField) {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, execs); err != nil {
+	data, err := filterFields(dto.FromExecs(execs), pq.Fields)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	last := lastID(execs, func(e *store.Exec) int64 { return e.ID })
+	if err := lists.write(w, r, pq, data, len(execs), last); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 	}
 }
@@ -83,7 +113,7 @@ func (app *application) getExecsHandler(w http.ResponseWriter, r *http.Request)
 //	@Accept			json
 //	@Produce		json
 //	@Param			execID	path		int			true	"Exec ID"
-//	@Success		200		{object}	store.Exec	"Exec object"
+//	@Success		200		{object}	dto.Exec	"Exec object"
 //	@Failure		404		{object}	error		"Exec not found"
 //	@Failure		500		{object}	error		"Internal server error"
 //	@Security		ApiKeyAuth
@@ -96,7 +126,7 @@ func (app *application) getExecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, exec); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromExec(exec)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -105,14 +135,15 @@ func (app *application) getExecHandler(w http.ResponseWriter, r *http.Request) {
 // UpdateExec godoc
 //
 //	@Summary		Update an executive
-//	@Description	Updates an exec. Only non-nil fields in the payload are updated. Versioning ensures concurrency safety.
+//	@Description	Updates an exec. Managers may only update themselves and can't change a role. Only the fields present in the payload are written, so concurrent updates of different fields don't overwrite each other. A new email only takes effect once confirmed from a link sent to it; the old address is notified.
 //	@Tags			Execs
 //	@Accept			json
 //	@Produce		json
 //	@Param			execID	path		int					true	"Exec ID"
 //	@Param			payload	body		UpdateExecPayload	true	"Exec fields to update"
-//	@Success		200		{object}	store.Exec			"Updated exec object"
+//	@Success		200		{object}	dto.Exec			"Updated exec object"
 //	@Failure		400		{object}	error				"Bad request / validation failed"
+//	@Failure		403		{object}	error				"Not allowed for managers"
 //	@Failure		404		{object}	error				"Exec not found"
 //	@Failure		409		{object}	error				"Conflict / concurrent update"
 //	@Failure		500		{object}	error				"Internal server error"
@@ -125,6 +156,13 @@ func (app *application) updateExecHandler(w http.ResponseWriter, r *http.Request
 		app.notfoundResponse(w, r, fmt.Errorf("exec not found"))
 		return
 	}
+	// managers may only edit themselves, and never their role: it is copied
+	// to their account and would let them make themselves admin
+	user := getUser(r)
+	if user.Role != "admin" && user.ID != exec.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
 
 	var payload UpdateExecPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -137,23 +175,39 @@ func (app *application) updateExecHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Apply non-nil fields using reflection
-	// utils.ApplyPatch(exec, payload)
-
-	// Update in DB
-	if err := app.store.Execs.Update(r.Context(), exec); err != nil {
+	// A new email waits for confirmation; only the other provided columns
+	// are written
+	fields := utils.PatchFields(payload)
+	if _, ok := fields["role"]; ok && user.Role != "admin" {
+		app.forbiddenResponse(w, r)
+		return
+	}
+	if !app.startEmailChange(w, r, "exec", exec.ID, exec.FirstName, exec.Email, fields) {
+		return
+	}
+	ctx := r.Context()
+	if err := app.store.Execs.UpdatePartial(ctx, exec.ID, fields); err != nil {
 		switch err {
 		case store.ErrNotFound:
 			app.notfoundResponse(w, r, err)
 			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, fmt.Errorf("email already registered"))
+			return
 		default:
 			app.internalServerErrorResponse(w, r, err)
 			return
 		}
 	}
 
+	updated, err := app.store.Execs.GetByID(ctx, exec.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
 	// Return updated exec
-	if err := app.jsonResponse(w, http.StatusOK, exec); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromExec(updated)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -205,7 +259,7 @@ func (app *application) execsContextMiddleware(next http.Handler) http.Handler {
 		}
 		ctx := r.Context()
 
-		exec, err := app.store.Execs.GetByID(ctx, id)
+		exec, err := lookupByID(app, ctx, "execs", id, app.store.Execs.GetByID)
 		if err != nil {
 			switch {
 			case errors.Is(err, store.ErrNotFound):
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"go.uber.org/zap"
+)
+
+func TestUpdateExecHandlerForbidsManagers(t *testing.T) {
+	tests := []struct {
+		name   string
+		target int64
+		body   string
+	}{
+		{name: "another exec", target: 2, body: `{"first_name":"Sara"}`},
+		{name: "another exec's role", target: 2, body: `{"role":"admin"}`},
+		{name: "own role", target: 1, body: `{"role":"admin"}`},
+	}
+
+	// no store: a forbidden request must be refused before touching it
+	app := &application{logger: zap.NewNop().Sugar()}
+	manager := &auth.Claims{ID: 1, Role: "manager"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/v1/execs/1", strings.NewReader(tt.body))
+			ctx := context.WithValue(r.Context(), userCtxKey, manager)
+			ctx = context.WithValue(ctx, execCtx, &store.Exec{ID: tt.target, Role: "manager"})
+			w := httptest.NewRecorder()
+
+			app.updateExecHandler(w, r.WithContext(ctx))
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, http.StatusForbidden, w.Body)
+			}
+		})
+	}
+}
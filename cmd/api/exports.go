@@ -0,0 +1,309 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/jobs"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const personExportJob = "person_export"
+
+type exportJobResponse struct {
+	Job         *jobs.Job `json:"job"`
+	StatusURL   string    `json:"status_url"`
+	DownloadURL string    `json:"download_url"`
+}
+
+type exportManifest struct {
+	Subject     string    `json:"subject"`
+	SubjectID   int64     `json:"subject_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Files       []string  `json:"files"`
+}
+
+// ExportStudent godoc
+//
+//	@Summary		Export all data held about a student
+//	@Description	Schedules a background export and returns links to poll its status and download the ZIP bundle: profile, attendance, grades, behavior points, pickup contacts, invoices, SMS messages, change history, notes and the changes the student made (audit.json)
+//	@Tags			Students
+//	@Produce		json
+//	@Param			studentID	path		int	true	"Student ID"
+//	@Success		202			{object}	exportJobResponse
+//	@Failure		404			{object}	error
+//	@Failure		503			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID}/export [get]
+//	@ID				exportStudent
+func (app *application) exportStudentHandler(w http.ResponseWriter, r *http.Request) {
+	student := getStudentFromCtx(r)
+	if student == nil {
+		app.notfoundResponse(w, r, fmt.Errorf("student not found in context"))
+		return
+	}
+
+	app.enqueuePersonExport(w, r, "student", student.ID)
+}
+
+// ExportMe godoc
+//
+//	@Summary		Export all data held about the current user
+//	@Description	Schedules a background export and returns links to poll its status and download the ZIP bundle. Every role gets its profile and the changes it made (audit.json); students also get what the student export holds, teachers their SMS messages, change history and notes, and parents their children, invoices and SMS messages.
+//	@Tags			Me
+//	@Produce		json
+//	@Success		202	{object}	exportJobResponse
+//	@Failure		503	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/me/export [get]
+//	@ID				exportMe
+func (app *application) exportMeHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUser(r)
+	app.enqueuePersonExport(w, r, accountKind(user.Role), user.ID)
+}
+
+// GetExport godoc
+//
+//	@Summary	Get the status of an export job
+//	@Tags		Exports
+//	@Produce	json
+//	@Param		jobID	path		string	true	"Job ID"
+//	@Success	200		{object}	jobs.Job
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/exports/{jobID} [get]
+//	@ID			getExport
+func (app *application) getExportHandler(w http.ResponseWriter, r *http.Request) {
+	job := app.exportJobFromRequest(r)
+	if job == nil {
+		app.notfoundResponse(w, r, fmt.Errorf("export not found"))
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, job); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DownloadExport godoc
+//
+//	@Summary	Download a finished export bundle
+//	@Tags		Exports
+//	@Produce	application/zip
+//	@Param		jobID	path	string	true	"Job ID"
+//	@Success	200		{file}	binary
+//	@Failure	404		{object}	error
+//	@Failure	409		{object}	error	"Export not finished"
+//	@Security	ApiKeyAuth
+//	@Router		/exports/{jobID}/download [get]
+//	@ID			downloadExport
+func (app *application) downloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	job := app.exportJobFromRequest(r)
+	if job == nil {
+		app.notfoundResponse(w, r, fmt.Errorf("export not found"))
+		return
+	}
+
+	if job.Status != jobs.StatusDone {
+		app.conflictResponse(w, r, fmt.Errorf("export is %s", job.Status))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.zip"`, job.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(job.Result())
+}
+
+func (app *application) enqueuePersonExport(w http.ResponseWriter, r *http.Request, subject string, subjectID int64) {
+	user := getUser(r)
+
+	job, err := app.jobs.Enqueue(personExportJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		return app.buildPersonExport(ctx, subject, subjectID)
+	})
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			writeJSONError(w, http.StatusServiceUnavailable, "export queue is busy, try again later")
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := exportJobResponse{
+		Job:         job,
+		StatusURL:   fmt.Sprintf("/v1/exports/%s", job.ID),
+		DownloadURL: fmt.Sprintf("/v1/exports/%s/download", job.ID),
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// exportJobFromRequest returns the export job named in the URL if the caller
+// requested it or is an admin.
+func (app *application) exportJobFromRequest(r *http.Request) *jobs.Job {
+	job, ok := app.jobs.Get(chi.URLParam(r, "jobID"))
+	if !ok || job.Kind != personExportJob {
+		return nil
+	}
+
+	user := getUser(r)
+	if user.Role != "admin" && (job.OwnerID != user.ID || job.OwnerRole != user.Role) {
+		return nil
+	}
+
+	return job
+}
+
+// exportHistoryPage is how many changes are read at a time when a record's
+// whole history goes into an export.
+const exportHistoryPage = 500
+
+// buildPersonExport collects everything stored about a person into a ZIP of
+// JSON documents: their profile, what they did (audit.json) and, by kind of
+// person, the records held about them.
+func (app *application) buildPersonExport(ctx context.Context, subject string, id int64) ([]byte, error) {
+	files := map[string]any{}
+	history := func(entity string) func() (any, error) {
+		return func() (any, error) { return asDTO(dto.FromEntityChanges)(app.allHistory(ctx, entity, id)) }
+	}
+	notes := func(entity string) func() (any, error) {
+		return func() (any, error) { return asDTO(dto.FromNotes)(app.store.Notes.ForEntity(ctx, entity, id)) }
+	}
+	audit := func(role string) func() (any, error) {
+		return func() (any, error) { return asDTO(dto.FromEntityChanges)(app.store.History.ByActor(ctx, role, id)) }
+	}
+
+	var sources map[string]func() (any, error)
+	switch subject {
+	case "student":
+		student, err := app.store.Students.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		files["profile.json"] = dto.FromStudent(dto.Unrestricted, student)
+		sources = map[string]func() (any, error){
+			"attendance.json": func() (any, error) {
+				return asDTO(dto.FromAttendanceRecords)(app.store.Attendance.GetByStudent(ctx, id, nil, nil))
+			},
+			"grades.json": func() (any, error) { return asDTO(dto.FromGrades)(app.store.LMS.StudentGrades(ctx, id)) },
+			"points.json": func() (any, error) {
+				return asDTO(dto.FromPointAwards)(app.store.Points.ForStudent(ctx, id, time.Time{}, app.schoolToday()))
+			},
+			"pickups.json": func() (any, error) { return asDTO(dto.FromPickupContacts)(app.store.Pickups.ForStudent(ctx, id)) },
+			"invoices.json": func() (any, error) {
+				return asDTO(unrestricted(dto.FromInvoices))(app.store.Invoices.List(ctx, store.InvoiceFilter{StudentID: id}))
+			},
+			"messages.json": func() (any, error) { return asDTO(dto.FromSMSMessages)(app.store.SMSMessages.ForStudent(ctx, id)) },
+			"history.json":  history("student"),
+			"notes.json":    notes("students"),
+			"audit.json":    audit("student"),
+		}
+	case "teacher":
+		teacher, err := app.store.Teachers.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		files["profile.json"] = dto.FromTeacher(dto.Unrestricted, teacher)
+		sources = map[string]func() (any, error){
+			"messages.json": func() (any, error) {
+				return asDTO(dto.FromSMSMessages)(app.store.SMSMessages.Thread(ctx, teacher.PhoneNumber))
+			},
+			"history.json": history("teacher"),
+			"notes.json":   notes("teachers"),
+			"audit.json":   audit("teacher"),
+		}
+	case "exec":
+		exec, err := app.store.Execs.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		files["profile.json"] = dto.FromExec(exec)
+		sources = map[string]func() (any, error){
+			"audit.json": audit(string(exec.Role)),
+		}
+	case "parent":
+		parent, err := app.store.Parents.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		files["profile.json"] = dto.FromParent(parent)
+		sources = map[string]func() (any, error){
+			"children.json": func() (any, error) {
+				return asDTO(unrestricted(dto.FromStudents))(app.store.Parents.Children(ctx, parent.PhoneNumber))
+			},
+			"invoices.json": func() (any, error) {
+				return asDTO(unrestricted(dto.FromInvoices))(app.store.Invoices.List(ctx, store.InvoiceFilter{ParentPhone: parent.PhoneNumber}))
+			},
+			"messages.json": func() (any, error) {
+				return asDTO(dto.FromSMSMessages)(app.store.SMSMessages.Thread(ctx, parent.PhoneNumber))
+			},
+			"audit.json": audit("parent"),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported export subject %q", subject)
+	}
+	for name, load := range sources {
+		data, err := load()
+		if err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", name, err)
+		}
+		files[name] = data
+	}
+
+	manifest := exportManifest{
+		Subject:     subject,
+		SubjectID:   id,
+		GeneratedAt: time.Now().UTC(),
+	}
+	for name := range files {
+		manifest.Files = append(manifest.Files, name)
+	}
+	sort.Strings(manifest.Files)
+	files["manifest.json"] = manifest
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// allHistory returns every recorded change of a record, newest first.
+func (app *application) allHistory(ctx context.Context, entity string, id int64) ([]*store.EntityChange, error) {
+	all := []*store.EntityChange{}
+	for offset := 0; ; offset += exportHistoryPage {
+		page, err := app.store.History.List(ctx, entity, id, exportHistoryPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < exportHistoryPage {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+)
+
+// GetFinanceSummary godoc
+//
+//	@Summary		Billed, collected and outstanding fees
+//	@Description	Totals the non-void invoices of a term, or of all terms, overall and per grade. Outstanding amounts are aged by how long their installments are past due.
+//	@Tags			Finance
+//	@Produce		json
+//	@Param			term	query		string	false	"Term; empty for all"
+//	@Success		200		{object}	dto.FinanceSummary
+//	@Security		ApiKeyAuth
+//	@Router			/finance/summary [get]
+//	@ID				getFinanceSummary
+func (app *application) getFinanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	summary, err := app.store.Invoices.Summary(r.Context(), r.URL.Query().Get("term"), app.schoolToday())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromFinanceSummary(summary)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
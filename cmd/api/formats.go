@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types a list can be written in.
+const (
+	formatJSON = "application/json"
+	formatCSV  = "text/csv"
+	formatXML  = "application/xml"
+)
+
+// listFormat picks the media type a list is answered in from the Accept
+// header, preferring JSON on ties, and notes that the response varies with
+// it.
+func listFormat(w http.ResponseWriter, r *http.Request) string {
+	w.Header().Add("Vary", "Accept")
+
+	best, bestQ := formatJSON, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		var format string
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			format = formatJSON
+		case "text/csv":
+			format = formatCSV
+		case "application/xml", "text/xml":
+			format = formatXML
+		default:
+			continue
+		}
+		if q > bestQ || (q == bestQ && format == formatJSON) {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// writeTable writes a list of records as CSV or XML, one row or element
+// per record and one column per JSON field.
+func writeTable(w http.ResponseWriter, format string, list any) error {
+	header, rows, err := tableRows(list)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case formatCSV:
+		cw := csv.NewWriter(&buf)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	case formatXML:
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(&buf)
+		list := xml.StartElement{Name: xml.Name{Local: "data"}}
+		record := xml.StartElement{Name: xml.Name{Local: "record"}}
+		enc.EncodeToken(list)
+		for _, row := range rows {
+			enc.EncodeToken(record)
+			for i, col := range header {
+				enc.EncodeElement(row[i], xml.StartElement{Name: xml.Name{Local: col}})
+			}
+			enc.EncodeToken(record.End())
+		}
+		enc.EncodeToken(list.End())
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+	}
+
+	w.Header().Set("Content-Type", format+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// tableRows flattens a list of records into rows of text. Columns follow
+// the JSON field order, with fields only later records have appended;
+// nested values are kept as JSON and nulls left empty.
+func tableRows(list any) ([]string, [][]string, error) {
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, err
+	}
+
+	header := []string{}
+	columns := map[string]int{}
+	records := make([]map[string]string, len(items))
+	for i, item := range items {
+		dec := json.NewDecoder(bytes.NewReader(item))
+		if _, err := dec.Token(); err != nil { // {
+			return nil, nil, err
+		}
+		records[i] = map[string]string{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, nil, err
+			}
+
+			col := key.(string)
+			if _, ok := columns[col]; !ok {
+				columns[col] = len(header)
+				header = append(header, col)
+			}
+			records[i][col] = cellText(value)
+		}
+	}
+
+	rows := make([][]string, len(records))
+	for i, rec := range records {
+		rows[i] = make([]string, len(header))
+		for col, j := range columns {
+			rows[i][j] = rec[col]
+		}
+	}
+	return header, rows, nil
+}
+
+func cellText(value json.RawMessage) string {
+	switch {
+	case string(value) == "null":
+		return ""
+	case len(value) > 0 && value[0] == '"':
+		var s string
+		json.Unmarshal(value, &s)
+		return s
+	default:
+		return string(value)
+	}
+}
@@ -2,8 +2,28 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/cmd/migrate/migrations"
 )
 
+// schemaVersion is the migration version this build's queries are written
+// against.
+var schemaVersion = migrations.Latest()
+
+// SchemaStatus compares the database's migration version with the one
+// the server expects.
+type SchemaStatus struct {
+	Current  uint `json:"current"`
+	Expected uint `json:"expected"`
+	Dirty    bool `json:"dirty"`
+}
+
+type ReadinessResponse struct {
+	Status string       `json:"status"`
+	Schema SchemaStatus `json:"schema"`
+	Error  string       `json:"error,omitempty"`
+}
+
 func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	data := map[string]string{
 		"status":  "ok",
@@ -15,3 +35,40 @@ func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Reques
 		app.internalServerErrorResponse(w, r, err)
 	}
 }
+
+// Readiness godoc
+//
+//	@Summary		Check the server can serve traffic
+//	@Description	Ready when the database answers and is migrated to exactly the version this build expects, and no migration is left dirty. Otherwise 503, so a deploy with missing or newer migrations is kept out of rotation rather than failing on queries.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	ReadinessResponse
+//	@Failure		503	{object}	ReadinessResponse
+//	@Router			/health/ready [get]
+//	@ID				readiness
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{Status: "ready", Schema: SchemaStatus{Expected: schemaVersion}}
+
+	current, dirty, err := app.store.Schema.Version(r.Context())
+	resp.Schema.Current, resp.Schema.Dirty = current, dirty
+	switch {
+	case err != nil:
+		resp.Error = "database unavailable"
+		app.logger.Warnw("readiness check failed", "error", err.Error())
+	case dirty:
+		resp.Error = "a migration failed part way; fix the schema and force the version"
+	case current < schemaVersion:
+		resp.Error = "database is missing migrations; run migrate up"
+	case current > schemaVersion:
+		resp.Error = "database has migrations this build does not know; deploy the matching build"
+	}
+
+	status := http.StatusOK
+	if resp.Error != "" {
+		resp.Status = "not ready"
+		status = http.StatusServiceUnavailable
+	}
+	if err := writeJSON(w, status, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// historyIgnored are fields that change on every write and carry no meaning
+// of their own in a record's history.
+var historyIgnored = map[string]struct{}{
+	"created_at": {},
+	"updated_at": {},
+}
+
+// diffFields compares the JSON forms of before and after, so only exported
+// fields are considered and passwords (json:"-") never end up in history.
+func diffFields(before, after any) (map[string]store.FieldChange, error) {
+	oldFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]store.FieldChange{}
+	for k, nv := range newFields {
+		if _, ok := historyIgnored[k]; ok {
+			continue
+		}
+		if ov := oldFields[k]; !bytes.Equal(ov, nv) {
+			changes[k] = store.FieldChange{Old: ov, New: nv}
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+// recordChanges stores what an update changed on a record. A failure is
+// logged rather than failing the update that already succeeded.
+func (app *application) recordChanges(r *http.Request, entity string, id int64, before, after any) {
+	changes, err := diffFields(before, after)
+	if err != nil {
+		app.logger.Warnw("computing change history failed", "entity", entity, "id", id, "error", err.Error())
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	user := getUser(r)
+	c := &store.EntityChange{
+		Entity:        entity,
+		EntityID:      id,
+		ChangedBy:     user.ID,
+		ChangedByRole: user.Role,
+		Changes:       changes,
+	}
+	if err := app.store.History.Record(r.Context(), c); err != nil {
+		app.logger.Warnw("recording change history failed", "entity", entity, "id", id, "error", err.Error())
+	}
+}
+
+// historyHandler serves the change history of the record loaded by a context
+// middleware; id extracts its ID from the request.
+func (app *application) historyHandler(entity string, id func(*http.Request) (int64, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityID, ok := id(r)
+		if !ok {
+			app.notfoundResponse(w, r, store.ErrNotFound)
+			return
+		}
+
+		pq, err := defaultListQuery.Parse(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if err := Validate.Struct(pq); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		history, err := app.store.History.List(r.Context(), entity, entityID, pq.Limit, pq.Offset)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusOK, dto.FromEntityChanges(history)); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// GetStudentHistory godoc
+//
+//	@Summary	Field-level change history of a student
+//	@Tags		Students
+//	@Produce	json
+//	@Param		studentID	path		int	true	"Student ID"
+//	@Param		limit		query		int	false	"Page size (max 50)"
+//	@Param		offset		query		int	false	"Offset"
+//	@Success	200			{array}		dto.EntityChange
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{studentID}/history [get]
+//	@ID			getStudentHistory
+func (app *application) getStudentHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	app.historyHandler("student", func(r *http.Request) (int64, bool) {
+		s := getStudentFromCtx(r)
+		if s == nil {
+			return 0, false
+		}
+		return s.ID, true
+	})(w, r)
+}
+
+// GetTeacherHistory godoc
+//
+//	@Summary	Field-level change history of a teacher
+//	@Tags		Teachers
+//	@Produce	json
+//	@Param		teacherID	path		int	true	"Teacher ID"
+//	@Param		limit		query		int	false	"Page size (max 50)"
+//	@Param		offset		query		int	false	"Offset"
+//	@Success	200			{array}		dto.EntityChange
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/teachers/{teacherID}/history [get]
+//	@ID			getTeacherHistory
+func (app *application) getTeacherHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	app.historyHandler("teacher", func(r *http.Request) (int64, bool) {
+		t := getTeacherFromCtx(r)
+		if t == nil {
+			return 0, false
+		}
+		return t.ID, true
+	})(w, r)
+}
+
+// GetClassroomHistory godoc
+//
+//	@Summary	Field-level change history of a classroom
+//	@Tags		Classrooms
+//	@Produce	json
+//	@Param		classroomID	path		int	true	"Classroom ID"
+//	@Param		limit		query		int	false	"Page size (max 50)"
+//	@Param		offset		query		int	false	"Offset"
+//	@Success	200			{array}		dto.EntityChange
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/classrooms/{classroomID}/history [get]
+//	@ID			getClassroomHistory
+func (app *application) getClassroomHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	app.historyHandler("classroom", func(r *http.Request) (int64, bool) {
+		c := getClassroomFromCtx(r)
+		if c == nil {
+			return 0, false
+		}
+		return c.ID, true
+	})(w, r)
+}
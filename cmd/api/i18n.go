@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"golang.org/x/text/language"
+)
+
+type languageKey string
+
+const languageCtx languageKey = "languages"
+
+// LanguageMiddleware resolves the caller's Accept-Language preferences, in
+// order of quality, followed by the configured default language.
+func (app *application) LanguageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+
+		langs := []string{}
+		tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		if err == nil {
+			for _, tag := range tags {
+				langs = append(langs, tag.String())
+			}
+		}
+		langs = append(langs, app.config.defaultLanguage)
+
+		ctx := context.WithValue(r.Context(), languageCtx, langs)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getLanguages(r *http.Request) []string {
+	langs, _ := r.Context().Value(languageCtx).([]string)
+	return langs
+}
+
+// localizeClassroom returns a copy of c with Name translated for the request.
+// Classrooms may come from the cache or a shared fetch, so they're never
+// modified in place.
+func localizeClassroom(r *http.Request, c *store.Classroom) *store.Classroom {
+	out := *c
+	out.Name = c.NameI18n.Pick(getLanguages(r), c.Name)
+	return &out
+}
+
+func localizeClassrooms(r *http.Request, list []*store.Classroom) []*store.Classroom {
+	out := make([]*store.Classroom, len(list))
+	for i, c := range list {
+		out[i] = localizeClassroom(r, c)
+	}
+	return out
+}
+
+// localizeTeacher returns a copy of t with Subject translated for the request.
+func localizeTeacher(r *http.Request, t *store.Teacher) *store.Teacher {
+	out := *t
+	out.Subject = t.SubjectI18n.Pick(getLanguages(r), t.Subject)
+	return &out
+}
+
+func localizeTeachers(r *http.Request, list []*store.Teacher) []*store.Teacher {
+	out := make([]*store.Teacher, len(list))
+	for i, t := range list {
+		out[i] = localizeTeacher(r, t)
+	}
+	return out
+}
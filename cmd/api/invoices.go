@@ -0,0 +1,678 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/payments"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateInvoicePayload struct {
+	StudentID int64  `json:"student_id" validate:"required,min=1" example:"42"`
+	Title     string `json:"title" validate:"required,max=200"`
+	Term      string `json:"term" validate:"max=32"`
+	Amount    int64  `json:"amount" validate:"required,min=1"`
+	DueOn     string `json:"due_on" validate:"required,datetime=2006-01-02"`
+}
+
+// GenerateInvoicesPayload bills a fee to every live student matching all
+// of the given filters; with none, the whole school.
+type GenerateInvoicesPayload struct {
+	Title       string  `json:"title" validate:"required,max=200"`
+	Term        string  `json:"term" validate:"max=32"`
+	Amount      int64   `json:"amount" validate:"required,min=1"`
+	DueOn       string  `json:"due_on" validate:"required,datetime=2006-01-02"`
+	ClassroomID int64   `json:"classroom_id" validate:"min=0" example:"3"`
+	Grade       int64   `json:"grade" validate:"min=0" example:"10"`
+	StudentIDs  []int64 `json:"student_ids" validate:"max=1000,dive,min=1"`
+}
+
+type GenerateInvoicesResponse struct {
+	Created  int            `json:"created"`
+	Invoices []*dto.Invoice `json:"invoices"`
+}
+
+type InstallmentPayload struct {
+	Amount int64  `json:"amount" validate:"required,min=1"`
+	DueOn  string `json:"due_on" validate:"required,datetime=2006-01-02"`
+}
+
+type SetInstallmentsPayload struct {
+	Installments []InstallmentPayload `json:"installments" validate:"required,min=1,max=24,dive"`
+}
+
+type PaymentLinkResponse struct {
+	PaymentID int64  `json:"payment_id"`
+	Gateway   string `json:"gateway"`
+	URL       string `json:"url"`
+}
+
+// PaymentResult is the outcome of a gateway callback.
+type PaymentResult struct {
+	InvoiceID int64  `json:"invoice_id"`
+	PaymentID int64  `json:"payment_id"`
+	Status    string `json:"status"`
+	RefID     string `json:"ref_id,omitempty"`
+}
+
+// paymentCallbackURL is where the gateway sends the payer back to.
+func (app *application) paymentCallbackURL() string {
+	return fmt.Sprintf("%s/v1/payments/%s/callback", strings.TrimSuffix(app.config.publicURL, "/"), app.payments.Name())
+}
+
+// CreateInvoice godoc
+//
+//	@Summary		Bill a student
+//	@Description	Amount is in SCHOOL_CURRENCY. Active discount rules are applied: the invoice's lines show the fee and each discount with the rule and reason, and its amount is what is left. An invoice discounted to nothing is created paid. The student's parents see the invoice and can pay it online.
+//	@Tags			Invoices
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateInvoicePayload	true	"Invoice"
+//	@Success		201		{object}	dto.Invoice
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/invoices [post]
+//	@ID				createInvoice
+func (app *application) createInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateInvoicePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	lines, err := app.priceInvoices(r.Context(), []int64{payload.StudentID}, payload.Title, payload.Amount)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	dueOn, _ := time.Parse(time.DateOnly, payload.DueOn)
+	creator := getUser(r).ID
+	invoice := &store.Invoice{
+		StudentID: payload.StudentID,
+		Title:     payload.Title,
+		Term:      payload.Term,
+		Amount:    linesTotal(lines[payload.StudentID]),
+		DueOn:     dueOn,
+		CreatedBy: &creator,
+		Lines:     lines[payload.StudentID],
+	}
+
+	if err := app.store.Invoices.Create(r.Context(), invoice); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, errors.New("student not found"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	created, err := app.store.Invoices.GetByID(r.Context(), invoice.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	created.Lines = invoice.Lines
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromInvoice(app.viewer(r), created)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GenerateInvoices godoc
+//
+//	@Summary		Bill a fee to many students
+//	@Description	Creates an invoice for every live student in the classroom, grade or list given (all must match; none means the whole school), applying the active discount rules to each as POST /invoices does. Students who already have an invoice, not void, with the same title and term are skipped, so a failed or partial run can be repeated.
+//	@Tags			Invoices
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		GenerateInvoicesPayload	true	"Fee and students"
+//	@Success		201		{object}	GenerateInvoicesResponse
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/invoices/generate [post]
+//	@ID				generateInvoices
+func (app *application) generateInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var payload GenerateInvoicesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	studentIDs, err := app.store.Invoices.Billable(ctx, store.BillingTarget{
+		ClassroomID: payload.ClassroomID,
+		Grade:       payload.Grade,
+		StudentIDs:  payload.StudentIDs,
+		Title:       payload.Title,
+		Term:        payload.Term,
+	})
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	lines, err := app.priceInvoices(ctx, studentIDs, payload.Title, payload.Amount)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	dueOn, _ := time.Parse(time.DateOnly, payload.DueOn)
+	creator := getUser(r).ID
+	invoices := make([]*store.Invoice, len(studentIDs))
+	for i, id := range studentIDs {
+		invoices[i] = &store.Invoice{
+			StudentID: id,
+			Title:     payload.Title,
+			Term:      payload.Term,
+			Amount:    linesTotal(lines[id]),
+			DueOn:     dueOn,
+			CreatedBy: &creator,
+			Lines:     lines[id],
+		}
+	}
+	if err := app.store.Invoices.CreateMany(ctx, invoices); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := GenerateInvoicesResponse{Created: len(invoices), Invoices: dto.FromInvoices(app.viewer(r), invoices)}
+	if err := app.jsonResponse(w, http.StatusCreated, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListInvoices godoc
+//
+//	@Summary	List invoices
+//	@Tags		Invoices
+//	@Produce	json
+//	@Param		student_id	query		int		false	"Student ID"
+//	@Param		status		query		string	false	"unpaid, paid or void"
+//	@Param		term		query		string	false	"Term"
+//	@Success	200			{array}		dto.Invoice
+//	@Failure	400			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/invoices [get]
+//	@ID			listInvoices
+func (app *application) listInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := store.InvoiceFilter{Term: q.Get("term")}
+	if v := q.Get("student_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid student_id"))
+			return
+		}
+		filter.StudentID = id
+	}
+	switch status := q.Get("status"); status {
+	case "", store.InvoiceUnpaid, store.InvoicePaid, store.InvoiceVoid:
+		filter.Status = status
+	default:
+		app.badRequestResponse(w, r, fmt.Errorf("status must be unpaid, paid or void"))
+		return
+	}
+
+	invoices, err := app.store.Invoices.List(r.Context(), filter)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromInvoices(app.viewer(r), invoices)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetInvoice godoc
+//
+//	@Summary		Get an invoice
+//	@Description	Includes the line items with the discount rules behind them, the installment schedule and every payment attempt.
+//	@Tags			Invoices
+//	@Produce		json
+//	@Param			invoiceID	path		int	true	"Invoice ID"
+//	@Success		200			{object}	dto.Invoice
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/invoices/{invoiceID} [get]
+//	@ID				getInvoice
+func (app *application) getInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.loadInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	invoice.Lines, err = app.store.Invoices.Lines(r.Context(), invoice.ID)
+	if err == nil {
+		invoice.Installments, err = app.store.Invoices.Installments(r.Context(), invoice.ID)
+	}
+	if err == nil {
+		invoice.Payments, err = app.store.Invoices.Payments(r.Context(), invoice.ID)
+	}
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromInvoice(app.viewer(r), invoice)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// SetInstallments godoc
+//
+//	@Summary		Set an invoice's installment plan
+//	@Description	Replaces the payment schedule of an unpaid invoice none of whose installments is paid yet. Amounts must add up to the invoice's and due dates must not go backwards; the invoice's due date becomes the last installment's.
+//	@Tags			Invoices
+//	@Accept			json
+//	@Produce		json
+//	@Param			invoiceID	path		int						true	"Invoice ID"
+//	@Param			payload		body		SetInstallmentsPayload	true	"Installments in order"
+//	@Success		200			{object}	dto.Invoice
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error	"Invoice is not unpaid or an installment is paid"
+//	@Security		ApiKeyAuth
+//	@Router			/invoices/{invoiceID}/installments [put]
+//	@ID				setInstallments
+func (app *application) setInstallmentsHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, ok := app.loadInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var payload SetInstallmentsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	installments := make([]*store.Installment, len(payload.Installments))
+	var total int64
+	for i, p := range payload.Installments {
+		dueOn, _ := time.Parse(time.DateOnly, p.DueOn)
+		if i > 0 && dueOn.Before(installments[i-1].DueOn) {
+			app.badRequestResponse(w, r, fmt.Errorf("installment %d is due before installment %d", i+1, i))
+			return
+		}
+		installments[i] = &store.Installment{Amount: p.Amount, DueOn: dueOn}
+		total += p.Amount
+	}
+	if total != invoice.Amount {
+		app.badRequestResponse(w, r, fmt.Errorf("installments add up to %d, not the invoice's %d", total, invoice.Amount))
+		return
+	}
+
+	if err := app.store.Invoices.SetInstallments(r.Context(), invoice.ID, installments); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("only unpaid invoices without paid installments can be rescheduled"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.getInvoiceHandler(w, r)
+}
+
+// GetDelinquency godoc
+//
+//	@Summary		Overdue fees per classroom or grade
+//	@Description	Totals the unpaid installments past their due date: how many students and installments, the amount, and the longest overdue in days.
+//	@Tags			Invoices
+//	@Produce		json
+//	@Param			group_by	query		string	false	"classroom (default) or grade"
+//	@Success		200			{array}		dto.DelinquencyRow
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/invoices/delinquency [get]
+//	@ID				getDelinquency
+func (app *application) getDelinquencyHandler(w http.ResponseWriter, r *http.Request) {
+	var byGrade bool
+	switch r.URL.Query().Get("group_by") {
+	case "", "classroom":
+	case "grade":
+		byGrade = true
+	default:
+		app.badRequestResponse(w, r, fmt.Errorf("group_by must be classroom or grade"))
+		return
+	}
+
+	report, err := app.store.Invoices.Delinquency(r.Context(), app.schoolToday(), byGrade)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromDelinquencyRows(report)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// VoidInvoice godoc
+//
+//	@Summary	Cancel an unpaid invoice
+//	@Tags		Invoices
+//	@Param		invoiceID	path	int	true	"Invoice ID"
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Failure	409	{object}	error	"Invoice is already paid or void"
+//	@Security	ApiKeyAuth
+//	@Router		/invoices/{invoiceID}/void [post]
+//	@ID			voidInvoice
+func (app *application) voidInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "invoiceID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Invoices.Void(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("only unpaid invoices can be voided"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListMyInvoices godoc
+//
+//	@Summary	List the invoices of the caller's children
+//	@Tags		Parents
+//	@Produce	json
+//	@Param		status	query		string	false	"unpaid, paid or void"
+//	@Success	200		{array}		dto.Invoice
+//	@Failure	401		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/parents/me/invoices [get]
+//	@ID			listMyInvoices
+func (app *application) listMyInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	parent, err := app.store.Parents.GetByID(r.Context(), getUser(r).ID)
+	if err != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account no longer exists"))
+		return
+	}
+
+	invoices, err := app.store.Invoices.List(r.Context(), store.InvoiceFilter{
+		ParentPhone: parent.PhoneNumber,
+		Status:      r.URL.Query().Get("status"),
+	})
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromInvoices(app.viewer(r), invoices)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetMyInvoice godoc
+//
+//	@Summary	Get one of the caller's children's invoices
+//	@Tags		Parents
+//	@Produce	json
+//	@Param		invoiceID	path		int	true	"Invoice ID"
+//	@Success	200			{object}	dto.Invoice
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/parents/me/invoices/{invoiceID} [get]
+//	@ID			getMyInvoice
+func (app *application) getMyInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	invoice, _, ok := app.loadParentInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	invoice.Lines, err = app.store.Invoices.Lines(r.Context(), invoice.ID)
+	if err == nil {
+		invoice.Installments, err = app.store.Invoices.Installments(r.Context(), invoice.ID)
+	}
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromInvoice(app.viewer(r), invoice)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PayInvoice godoc
+//
+//	@Summary		Start paying an invoice
+//	@Description	Opens a session with the payment gateway for the invoice's next unpaid installment and returns the page to send the parent to. The gateway returns them to /payments/{gateway}/callback, which settles the installment, and the invoice with its last. Each call starts a new session.
+//	@Tags			Parents
+//	@Produce		json
+//	@Param			invoiceID	path		int	true	"Invoice ID"
+//	@Success		201			{object}	PaymentLinkResponse
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error	"Invoice is not unpaid"
+//	@Failure		503			{object}	error	"No payment gateway configured or gateway unreachable"
+//	@Security		ApiKeyAuth
+//	@Router			/parents/me/invoices/{invoiceID}/pay [post]
+//	@ID				payInvoice
+func (app *application) payInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	invoice, parent, ok := app.loadParentInvoice(w, r)
+	if !ok {
+		return
+	}
+	if invoice.Status != store.InvoiceUnpaid {
+		app.conflictResponse(w, r, fmt.Errorf("invoice is %s", invoice.Status))
+		return
+	}
+
+	installments, err := app.store.Invoices.Installments(ctx, invoice.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	var next *store.Installment
+	for _, n := range installments {
+		if n.PaidAt == nil {
+			next = n
+			break
+		}
+	}
+	if next == nil {
+		app.conflictResponse(w, r, errors.New("invoice has no unpaid installment"))
+		return
+	}
+	description := fmt.Sprintf("%s - %s", invoice.Title, invoice.StudentName)
+	if len(installments) > 1 {
+		description += fmt.Sprintf(" (installment %d/%d)", next.Seq, len(installments))
+	}
+
+	session, err := app.payments.Create(ctx, payments.Request{
+		Amount:      next.Amount,
+		Currency:    app.config.school.currency,
+		Description: description,
+		CallbackURL: app.paymentCallbackURL(),
+		Mobile:      parent.PhoneNumber,
+		OrderID:     strconv.FormatInt(invoice.ID, 10),
+	})
+	if err != nil {
+		app.serviceUnavailableResponse(w, r, err)
+		return
+	}
+
+	payment := &store.Payment{
+		InvoiceID:     invoice.ID,
+		InstallmentID: &next.ID,
+		Gateway:       app.payments.Name(),
+		Amount:        next.Amount,
+		Authority:     session.Authority,
+		ParentID:      &parent.ID,
+	}
+	if err := app.store.Invoices.CreatePayment(ctx, payment); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := PaymentLinkResponse{PaymentID: payment.ID, Gateway: payment.Gateway, URL: session.PayURL}
+	if err := app.jsonResponse(w, http.StatusCreated, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PaymentCallback godoc
+//
+//	@Summary		Receive a payer back from the payment gateway
+//	@Description	Verifies the payment with the gateway and, in one transaction, marks it and its installment paid (and the invoice, with its last installment) and queues the parent's confirmation. Repeated callbacks return the recorded outcome. Redirects to PAYMENT_RETURN_URL with ?invoice= and ?status= when set.
+//	@Tags			Invoices
+//	@Produce		json
+//	@Param			gateway		path		string	true	"Gateway, e.g. zarinpal"
+//	@Param			Authority	query		string	true	"Gateway session"
+//	@Param			Status		query		string	true	"OK or NOK"
+//	@Success		200			{object}	PaymentResult
+//	@Success		303
+//	@Failure		404			{object}	error
+//	@Failure		503			{object}	error	"Gateway unreachable; the payment stays pending"
+//	@Router			/payments/{gateway}/callback [get]
+//	@ID				paymentCallback
+func (app *application) paymentCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	gateway := app.payments.Name()
+	if gateway == "" || chi.URLParam(r, "gateway") != gateway {
+		app.notfoundResponse(w, r, errors.New("unknown payment gateway"))
+		return
+	}
+
+	authority, ok := app.payments.Callback(r.URL.Query())
+	payment, err := app.store.Invoices.PaymentByAuthority(ctx, gateway, authority)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, errors.New("payment not found"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if payment.Status == store.PaymentPending {
+		if !ok {
+			err = app.store.Invoices.FailPayment(ctx, payment, "cancelled by the payer")
+		} else if v, verr := app.payments.Verify(ctx, authority, payment.Amount); errors.Is(verr, payments.ErrDeclined) {
+			err = app.store.Invoices.FailPayment(ctx, payment, "declined by the gateway")
+		} else if verr != nil {
+			app.serviceUnavailableResponse(w, r, verr)
+			return
+		} else {
+			var applied bool
+			applied, err = app.store.Invoices.ConfirmPayment(ctx, payment, v.RefID, v.CardPAN)
+			if err == nil && !applied {
+				app.logger.Warnw("payment received for an installment that is no longer due; refund it",
+					"invoice", payment.InvoiceID, "payment", payment.ID, "ref", v.RefID)
+			}
+		}
+		// a concurrent callback settled it first
+		if errors.Is(err, store.ErrConflict) {
+			payment, err = app.store.Invoices.PaymentByAuthority(ctx, gateway, authority)
+		}
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	result := PaymentResult{
+		InvoiceID: payment.InvoiceID,
+		PaymentID: payment.ID,
+		Status:    payment.Status,
+		RefID:     payment.RefID,
+	}
+	if app.config.payments.returnURL != "" {
+		target := app.config.payments.returnURL
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		q := url.Values{"invoice": {strconv.FormatInt(result.InvoiceID, 10)}, "status": {result.Status}}
+		http.Redirect(w, r, target+sep+q.Encode(), http.StatusSeeOther)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// loadParentInvoice loads the invoice in the URL if it is one of the
+// calling parent's children's.
+func (app *application) loadParentInvoice(w http.ResponseWriter, r *http.Request) (*store.Invoice, *store.Parent, bool) {
+	parent, err := app.store.Parents.GetByID(r.Context(), getUser(r).ID)
+	if err != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account no longer exists"))
+		return nil, nil, false
+	}
+
+	invoice, ok := app.loadInvoice(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	// someone else's child's invoice is not found rather than forbidden
+	if invoice.ParentPhone != parent.PhoneNumber {
+		app.notfoundResponse(w, r, store.ErrNotFound)
+		return nil, nil, false
+	}
+	return invoice, parent, true
+}
+
+func (app *application) loadInvoice(w http.ResponseWriter, r *http.Request) (*store.Invoice, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "invoiceID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	invoice, err := app.store.Invoices.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return invoice, true
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+)
+
+const blocklistRefreshInterval = 10 * time.Second
+
+// parsePrefixes parses a comma-separated list of IP addresses and CIDR
+// ranges; a bare address is a single-host range.
+func parsePrefixes(list string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p, err := parsePrefix(item)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// RealIPMiddleware sets RemoteAddr to the client's address forwarded by a
+// trusted proxy. X-Forwarded-For and X-Real-IP are only believed when the
+// TCP peer is in TRUSTED_PROXY_CIDRS; otherwise anyone could pick the
+// address the allow and block lists see.
+func (app *application) RealIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if peer, ok := clientAddr(r); ok && prefixesContain(app.trustedProxies, peer) {
+			if addr, ok := app.forwardedAddr(r); ok {
+				r.RemoteAddr = addr.String()
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedAddr is the client address a trusted proxy reported: the last
+// X-Forwarded-For hop that isn't itself a trusted proxy, as earlier hops
+// are whatever the client sent, else X-Real-IP.
+func (app *application) forwardedAddr(r *http.Request) (netip.Addr, bool) {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		var addr netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			a, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = a.Unmap()
+			if !prefixesContain(app.trustedProxies, addr) {
+				break
+			}
+		}
+		return addr, addr.IsValid()
+	}
+	if a, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return a.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// clientAddr is the caller's address, as set by RealIPMiddleware.
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// BlocklistMiddleware refuses requests from denied networks.
+func (app *application) BlocklistMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blocked := app.blocklist.Load(); blocked != nil && len(*blocked) > 0 {
+			if addr, ok := clientAddr(r); ok && prefixesContain(*blocked, addr) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminNetworkMiddleware limits admin routes to ADMIN_ALLOWED_CIDRS; an
+// empty list allows any address.
+func (app *application) adminNetworkMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(app.adminNetworks) > 0 {
+			addr, ok := clientAddr(r)
+			if !ok || !prefixesContain(app.adminNetworks, addr) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startBlocklistSync keeps the in-memory blocklist in step with Redis so an
+// entry added on any instance applies everywhere.
+func (app *application) startBlocklistSync() {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+
+	sync := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		entries, err := app.cacheStorage.Blocklist.List(ctx)
+		if err != nil {
+			app.logger.Warnw("loading blocklist failed", "error", err.Error())
+			return
+		}
+		app.storeBlocklist(entries)
+	}
+
+	sync()
+	ticker := time.NewTicker(blocklistRefreshInterval)
+	go func() {
+		for range ticker.C {
+			sync()
+		}
+	}()
+}
+
+func (app *application) storeBlocklist(entries []*cache.BlockedNetwork) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		p, err := parsePrefix(e.CIDR)
+		if err != nil {
+			app.logger.Warnw("skipping blocklist entry", "cidr", e.CIDR, "error", err.Error())
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+	app.blocklist.Store(&prefixes)
+}
+
+type BlocklistPayload struct {
+	// CIDR is an IP address or range, e.g. 203.0.113.7 or 203.0.113.0/24.
+	CIDR   string `json:"cidr" validate:"required,max=64"`
+	Reason string `json:"reason,omitempty" validate:"max=256"`
+	// ExpiresInHours lifts the block automatically; zero keeps it until removed.
+	ExpiresInHours int `json:"expires_in_hours,omitempty" validate:"min=0,max=8760"`
+}
+
+// ListBlocklist godoc
+//
+//	@Summary	List blocked IP addresses and ranges
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{array}		cache.BlockedNetwork
+//	@Failure	503	{object}	error	"Redis is not configured"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/blocklist [get]
+//	@ID			listBlocklist
+func (app *application) listBlocklistHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.cacheStorage.Blocklist.List(r.Context())
+	if err != nil {
+		app.blocklistError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, entries); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// AddToBlocklist godoc
+//
+//	@Summary		Block an IP address or range
+//	@Description	Requests from the network are refused with 403 on every instance within a few seconds. Adding an existing CIDR replaces its entry.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		BlocklistPayload	true	"Network to block"
+//	@Success		201		{object}	cache.BlockedNetwork
+//	@Failure		400		{object}	error
+//	@Failure		503		{object}	error	"Redis is not configured"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/blocklist [post]
+//	@ID				addToBlocklist
+func (app *application) addToBlocklistHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BlocklistPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	prefix, err := parsePrefix(payload.CIDR)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// refuse entries that would lock the caller out
+	if addr, ok := clientAddr(r); ok && prefix.Contains(addr) {
+		app.badRequestResponse(w, r, errors.New("the range includes your own address"))
+		return
+	}
+
+	entry := &cache.BlockedNetwork{
+		CIDR:      prefix.String(),
+		Reason:    payload.Reason,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: getUser(r).ID,
+	}
+	if payload.ExpiresInHours > 0 {
+		expires := entry.CreatedAt.Add(time.Duration(payload.ExpiresInHours) * time.Hour)
+		entry.ExpiresAt = &expires
+	}
+
+	ctx := r.Context()
+	if err := app.cacheStorage.Blocklist.Add(ctx, entry); err != nil {
+		app.blocklistError(w, r, err)
+		return
+	}
+	if entries, err := app.cacheStorage.Blocklist.List(ctx); err == nil {
+		app.storeBlocklist(entries)
+	}
+
+	app.logger.Infow("network blocked", "cidr", entry.CIDR, "by", entry.CreatedBy)
+
+	if err := app.jsonResponse(w, http.StatusCreated, entry); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RemoveFromBlocklist godoc
+//
+//	@Summary	Unblock an IP address or range
+//	@Tags		Admin
+//	@Param		cidr	query	string	true	"Blocked address or range"
+//	@Success	204
+//	@Failure	400	{object}	error
+//	@Failure	404	{object}	error
+//	@Failure	503	{object}	error	"Redis is not configured"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/blocklist [delete]
+//	@ID			removeFromBlocklist
+func (app *application) removeFromBlocklistHandler(w http.ResponseWriter, r *http.Request) {
+	prefix, err := parsePrefix(r.URL.Query().Get("cidr"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	removed, err := app.cacheStorage.Blocklist.Remove(ctx, prefix.String())
+	if err != nil {
+		app.blocklistError(w, r, err)
+		return
+	}
+	if !removed {
+		app.notfoundResponse(w, r, fmt.Errorf("%s is not blocked", prefix))
+		return
+	}
+	if entries, err := app.cacheStorage.Blocklist.List(ctx); err == nil {
+		app.storeBlocklist(entries)
+	}
+
+	app.logger.Infow("network unblocked", "cidr", prefix.String(), "by", getUser(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) blocklistError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, cache.ErrUnavailable) {
+		app.serviceUnavailableResponse(w, r, errors.New("the blocklist needs Redis"))
+		return
+	}
+	app.internalServerErrorResponse(w, r, err)
+}
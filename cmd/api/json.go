@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/utils"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -11,6 +13,9 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+	Validate.RegisterValidation("national_id", func(fl validator.FieldLevel) bool {
+		return utils.ValidNationalID(fl.Field().String())
+	})
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -38,6 +43,58 @@ func writeJSONError(w http.ResponseWriter, status int, message string) error {
 	return writeJSON(w, status, &envelope{Error: message})
 }
 
+// filterFields keeps only the requested JSON keys (plus "id") of each element
+// of a list, so sparse fieldset responses don't carry zero-valued fields.
+func filterFields(list any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return list, nil
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	keep := map[string]struct{}{"id": {}}
+	for _, f := range fields {
+		keep[f] = struct{}{}
+	}
+
+	for _, item := range items {
+		for k := range item {
+			if _, ok := keep[k]; !ok {
+				delete(item, k)
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// asDTO adapts a store read returning a record and an error to one
+// returning the record mapped to its response shape.
+func asDTO[S, D any](toDTO func(S) D) func(S, error) (any, error) {
+	return func(v S, err error) (any, error) {
+		if err != nil {
+			return nil, err
+		}
+		return toDTO(v), nil
+	}
+}
+
+// unrestricted binds a mapper taking the viewer to dto.Unrestricted, for
+// responses holding only the caller's own records.
+func unrestricted[S, D any](toDTO func(dto.Viewer, S) D) func(S) D {
+	return func(v S) D {
+		return toDTO(dto.Unrestricted, v)
+	}
+}
+
 func (app *application) jsonResponse(w http.ResponseWriter, status int, data any) error {
 	type envelope struct {
 		Data any `json:"data"`
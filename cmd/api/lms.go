@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/lms"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+const (
+	lmsSyncJob = "lms_sync"
+	// lmsSchedulerTick is how often due syncs are looked for.
+	lmsSchedulerTick = time.Minute
+	// maxGradeImport caps an uploaded grade file.
+	maxGradeImport = 10 << 20
+)
+
+type LMSSyncPayload struct {
+	Format       string `json:"format" validate:"required,oneof=google_csv oneroster" enums:"google_csv,oneroster" example:"google_csv"`
+	GradesSource string `json:"grades_source" validate:"required,oneof=google_classroom moodle oneroster csv" enums:"google_classroom,moodle,oneroster,csv" example:"google_classroom"`
+	// RosterURL receives the roster with PUT on every run; empty skips it.
+	RosterURL string `json:"roster_url" validate:"omitempty,url,max=2048"`
+	// GradesURL is fetched with GET on every run; empty skips it.
+	GradesURL string `json:"grades_url" validate:"omitempty,url,max=2048"`
+	// AuthToken is sent as a bearer token to both URLs. Leave empty to keep
+	// the stored one.
+	AuthToken       string `json:"auth_token" validate:"max=4096"`
+	IntervalMinutes int    `json:"interval_minutes" validate:"required,min=15,max=10080"`
+	Enabled         bool   `json:"enabled"`
+}
+
+type GradeImportResponse struct {
+	Imported int `json:"imported"`
+	// Unmatched lists emails that belong to no student of the classroom.
+	Unmatched []string `json:"unmatched"`
+}
+
+// classroomRoster gathers a classroom's teacher and live students.
+func (app *application) classroomRoster(ctx context.Context, classroom *store.Classroom) (*lms.Roster, error) {
+	members, err := app.store.LMS.Roster(ctx, classroom.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	roster := &lms.Roster{
+		SchoolName: app.config.school.name,
+		ClassID:    classroom.ID,
+		ClassName:  classroom.Name,
+		Grade:      classroom.Grade,
+		UpdatedAt:  classroom.UpdatedAt,
+	}
+	if classroom.TeacherID != 0 {
+		teacher, err := app.store.Teachers.GetByID(ctx, classroom.TeacherID)
+		switch {
+		case err == nil:
+			roster.Teacher = &lms.Person{ID: teacher.ID, FirstName: teacher.FirstName, LastName: teacher.LastName, Email: teacher.Email}
+		case !errors.Is(err, store.ErrNotFound):
+			return nil, err
+		}
+	}
+	for _, m := range members {
+		roster.Students = append(roster.Students, &lms.Person{
+			ID: m.ID, FirstName: m.FirstName, LastName: m.LastName, Email: m.Email, Code: m.StudentCode,
+		})
+	}
+	return roster, nil
+}
+
+// encodeRoster renders a roster in format and returns the file with its
+// content type and name.
+func encodeRoster(roster *lms.Roster, format string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case lms.FormatGoogle:
+		if err := lms.WriteGoogleCSV(&buf, roster); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "text/csv", fmt.Sprintf("classroom-%d-roster.csv", roster.ClassID), nil
+	case lms.FormatOneRoster:
+		if err := lms.WriteOneRoster(&buf, roster); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "application/zip", fmt.Sprintf("classroom-%d-oneroster.zip", roster.ClassID), nil
+	default:
+		return nil, "", "", fmt.Errorf("unknown roster format %q", format)
+	}
+}
+
+// importGrades stores parsed grades for the classroom.
+func (app *application) importGrades(ctx context.Context, classroomID int64, source string, grades []*lms.Grade) (*GradeImportResponse, error) {
+	rows := make([]*store.GradeImport, len(grades))
+	for i, g := range grades {
+		rows[i] = &store.GradeImport{Email: g.Email, Assignment: g.Assignment, Score: g.Score, MaxScore: g.MaxScore}
+	}
+	imported, unmatched, err := app.store.LMS.ImportGrades(ctx, classroomID, source, rows)
+	if err != nil {
+		return nil, err
+	}
+	return &GradeImportResponse{Imported: imported, Unmatched: unmatched}, nil
+}
+
+// ExportClassroomRoster godoc
+//
+//	@Summary		Export a classroom roster for an LMS
+//	@Description	google_csv is a CSV for Google Classroom's roster import; oneroster is a OneRoster 1.1 bulk CSV ZIP, which Moodle and most other LMSs import.
+//	@Tags			LMS
+//	@Produce		text/csv,application/zip
+//	@Param			classroomID	path		int		true	"Classroom ID"
+//	@Param			format		query		string	false	"google_csv (default) or oneroster"
+//	@Success		200			{file}		file
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/classrooms/{classroomID}/roster [get]
+//	@ID				exportClassroomRoster
+func (app *application) exportClassroomRosterHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = lms.FormatGoogle
+	}
+	if format != lms.FormatGoogle && format != lms.FormatOneRoster {
+		app.badRequestResponse(w, r, fmt.Errorf("format must be %s or %s", lms.FormatGoogle, lms.FormatOneRoster))
+		return
+	}
+
+	roster, err := app.classroomRoster(r.Context(), classroom)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	data, contentType, filename, err := encodeRoster(roster, format)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+// ImportClassroomGrades godoc
+//
+//	@Summary		Import grades from an LMS export
+//	@Description	Takes a CSV body: a Google Classroom or Moodle grade export (one column per assignment, students matched by email), or email,assignment,score[,max_score] rows. Re-importing an assignment from the same source overwrites it. All rows are imported or none; emails of students outside the classroom are listed as unmatched.
+//	@Tags			LMS
+//	@Accept			text/csv
+//	@Produce		json
+//	@Param			classroomID	path		int		true	"Classroom ID"
+//	@Param			source		query		string	true	"google_classroom, moodle, oneroster or csv"
+//	@Success		200			{object}	GradeImportResponse
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/classrooms/{classroomID}/grades/import [post]
+//	@ID				importClassroomGrades
+func (app *application) importClassroomGradesHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+
+	source := r.URL.Query().Get("source")
+	switch source {
+	case lms.SourceGoogle, lms.SourceMoodle, lms.SourceOneRoster, lms.SourceCSV:
+	default:
+		app.badRequestResponse(w, r, errors.New("source must be google_classroom, moodle, oneroster or csv"))
+		return
+	}
+
+	grades, err := lms.ReadGrades(http.MaxBytesReader(w, r.Body, maxGradeImport))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	resp, err := app.importGrades(r.Context(), classroom.ID, source, grades)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetClassroomGrades godoc
+//
+//	@Summary	List a classroom's imported grades
+//	@Tags		LMS
+//	@Produce	json
+//	@Param		classroomID	path	int	true	"Classroom ID"
+//	@Success	200			{array}	dto.Grade
+//	@Security	ApiKeyAuth
+//	@Router		/classrooms/{classroomID}/grades [get]
+//	@ID			getClassroomGrades
+func (app *application) getClassroomGradesHandler(w http.ResponseWriter, r *http.Request) {
+	grades, err := app.store.LMS.Grades(r.Context(), getClassroomFromCtx(r).ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromGrades(grades)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetLMSSync godoc
+//
+//	@Summary	Get a classroom's LMS sync settings
+//	@Tags		LMS
+//	@Produce	json
+//	@Param		classroomID	path		int	true	"Classroom ID"
+//	@Success	200			{object}	dto.LMSSync
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/classrooms/{classroomID}/lms-sync [get]
+//	@ID			getLMSSync
+func (app *application) getLMSSyncHandler(w http.ResponseWriter, r *http.Request) {
+	ls, err := app.store.LMS.GetSync(r.Context(), getClassroomFromCtx(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLMSSync(ls)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PutLMSSync godoc
+//
+//	@Summary		Configure a classroom's LMS sync
+//	@Description	Every interval_minutes the roster is uploaded to roster_url and grades are fetched from grades_url and imported. The first run is due within a minute of saving.
+//	@Tags			LMS
+//	@Accept			json
+//	@Produce		json
+//	@Param			classroomID	path		int				true	"Classroom ID"
+//	@Param			payload		body		LMSSyncPayload	true	"Sync settings"
+//	@Success		200			{object}	dto.LMSSync
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/classrooms/{classroomID}/lms-sync [put]
+//	@ID				putLMSSync
+func (app *application) putLMSSyncHandler(w http.ResponseWriter, r *http.Request) {
+	var payload LMSSyncPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if payload.RosterURL == "" && payload.GradesURL == "" {
+		app.badRequestResponse(w, r, errors.New("set roster_url, grades_url or both"))
+		return
+	}
+
+	ls := &store.LMSSync{
+		ClassroomID:     getClassroomFromCtx(r).ID,
+		Format:          payload.Format,
+		GradesSource:    payload.GradesSource,
+		RosterURL:       payload.RosterURL,
+		GradesURL:       payload.GradesURL,
+		AuthToken:       payload.AuthToken,
+		IntervalMinutes: payload.IntervalMinutes,
+		Enabled:         payload.Enabled,
+	}
+	if err := app.store.LMS.PutSync(r.Context(), ls); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLMSSync(ls)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteLMSSync godoc
+//
+//	@Summary	Stop syncing a classroom with its LMS
+//	@Tags		LMS
+//	@Param		classroomID	path	int	true	"Classroom ID"
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/classrooms/{classroomID}/lms-sync [delete]
+//	@ID			deleteLMSSync
+func (app *application) deleteLMSSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.store.LMS.DeleteSync(r.Context(), getClassroomFromCtx(r).ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunLMSSync godoc
+//
+//	@Summary	Run a classroom's LMS sync now
+//	@Tags		LMS
+//	@Produce	json
+//	@Param		classroomID	path		int	true	"Classroom ID"
+//	@Success	202			{object}	jobs.Job
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/classrooms/{classroomID}/lms-sync/run [post]
+//	@ID			runLMSSync
+func (app *application) runLMSSyncHandler(w http.ResponseWriter, r *http.Request) {
+	ls, err := app.store.LMS.GetSync(r.Context(), getClassroomFromCtx(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := getUser(r)
+	job, err := app.jobs.Enqueue(lmsSyncJob, user.ID, user.Role, app.lmsSyncJob(ls))
+	if err != nil {
+		app.serviceUnavailableResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, job); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+type lmsSyncResult struct {
+	RosterPushed bool                 `json:"roster_pushed"`
+	Grades       *GradeImportResponse `json:"grades,omitempty"`
+}
+
+func (app *application) lmsSyncJob(ls *store.LMSSync) func(ctx context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		result, err := app.runLMSSync(ctx, ls)
+
+		lastError := ""
+		if err != nil {
+			lastError = err.Error()
+		}
+		if ferr := app.store.LMS.FinishSync(context.WithoutCancel(ctx), ls.ClassroomID, lastError); ferr != nil {
+			app.logger.Errorw("recording lms sync failed", "classroom", ls.ClassroomID, "error", ferr.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(result)
+	}
+}
+
+// runLMSSync pushes the roster and pulls grades for one classroom.
+func (app *application) runLMSSync(ctx context.Context, ls *store.LMSSync) (*lmsSyncResult, error) {
+	result := &lmsSyncResult{}
+
+	if ls.RosterURL != "" {
+		classroom, err := app.store.Classrooms.GetByID(ctx, ls.ClassroomID)
+		if err != nil {
+			return nil, err
+		}
+		roster, err := app.classroomRoster(ctx, classroom)
+		if err != nil {
+			return nil, err
+		}
+		data, contentType, _, err := encodeRoster(roster, ls.Format)
+		if err != nil {
+			return nil, err
+		}
+		if err := lms.Push(ctx, ls.RosterURL, ls.AuthToken, contentType, data); err != nil {
+			return nil, err
+		}
+		result.RosterPushed = true
+	}
+
+	if ls.GradesURL != "" {
+		data, err := lms.Fetch(ctx, ls.GradesURL, ls.AuthToken)
+		if err != nil {
+			return result, err
+		}
+		grades, err := lms.ReadGrades(bytes.NewReader(data))
+		if err != nil {
+			return result, err
+		}
+		if result.Grades, err = app.importGrades(ctx, ls.ClassroomID, ls.GradesSource, grades); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// startLMSSync claims due classroom syncs every minute and queues a run
+// for each.
+func (app *application) startLMSSync() {
+	ticker := time.NewTicker(lmsSchedulerTick)
+	go func() {
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			due, err := app.store.LMS.ClaimDueSyncs(ctx, time.Now().UTC())
+			cancel()
+			if err != nil {
+				app.logger.Errorw("claiming due lms syncs failed", "error", err.Error())
+				continue
+			}
+
+			for _, ls := range due {
+				if _, err := app.jobs.Enqueue(lmsSyncJob, 0, "system", app.lmsSyncJob(ls)); err != nil {
+					app.logger.Errorw("scheduling lms sync failed", "classroom", ls.ClassroomID, "error", err.Error())
+				}
+			}
+		}
+	}()
+}
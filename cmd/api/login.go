@@ -2,32 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
-	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/analytics"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type LoginPayload struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8,max=72"`
+	Email    string `json:"email" validate:"required,email" example:"sara.ahmadi@example.com"`
+	Password string `json:"password" validate:"required,min=8,max=72" example:"S3cure-Passw0rd"`
+	// RememberMe extends the refresh token's lifetime.
+	RememberMe bool `json:"remember_me" example:"true"`
 }
 
 type RegisterPayload struct {
-	FirstName string `json:"first_name" validate:"required,max=72"`
-	LastName  string `json:"last_name" validate:"required,max=72"`
-	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8,max=72"`
-	Role      string `json:"role,omitempty" validate:"omitempty,oneof=admin manager"`
+	FirstName string `json:"first_name" validate:"required,max=72" example:"Sara"`
+	LastName  string `json:"last_name" validate:"required,max=72" example:"Ahmadi"`
+	Email     string `json:"email" validate:"required,email" example:"sara.ahmadi@example.com"`
+	Password  string `json:"password" validate:"required,min=8,max=72" example:"S3cure-Passw0rd"`
+	Role      string `json:"role,omitempty" validate:"omitempty,oneof=admin manager" enums:"admin,manager" example:"admin"`
 }
 
-func (app *application) loginHandler(
-	w http.ResponseWriter,
-	r *http.Request,
-	getByEmail func(ctx context.Context, email string) (any, error)) {
+// loginHandler godoc
+//
+//	@Summary		Login
+//	@Description	Login as any user with an email and password and get a JWT token and a refresh token; remember_me makes the refresh token last longer. The entity returned is the exec, teacher or student profile behind the account.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		LoginPayload		true	"Login payload"
+//	@Success		200		{object}	map[string]any		"Returns the logged-in profile, JWT token, refresh token, permissions, school settings and avatar URL"
+//	@Failure		400		{object}	map[string]string	"Bad request"
+//	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Router			/auth/login [post]
+func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
+	app.login(w, r, "")
+}
+
+// login checks the credentials against the accounts table and issues a
+// token for the profile behind the account. A non-empty profileType limits
+// it to that kind of account, for the per-kind login routes.
+func (app *application) login(w http.ResponseWriter, r *http.Request, profileType string) {
 	var payload LoginPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
@@ -40,66 +58,71 @@ func (app *application) loginHandler(
 	}
 
 	ctx := r.Context()
-	entity, err := getByEmail(ctx, payload.Email)
+	account, err := app.store.Accounts.GetByEmail(ctx, payload.Email)
 	if err != nil {
-		app.unauthorizedResponse(w, r, err)
+		if errors.Is(err, store.ErrNotFound) {
+			app.unauthorizedResponse(w, r, fmt.Errorf("invalid credentials"))
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
-	var id int64
-	var role string
+	if profileType != "" && account.ProfileType != profileType {
+		app.unauthorizedResponse(w, r, fmt.Errorf("invalid credentials"))
+		return
+	}
+	if !account.Password.Check(payload.Password) {
+		app.unauthorizedResponse(w, r, fmt.Errorf("invalid credentials"))
+		return
+	}
+	if account.DeactivatedAt != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account deactivated"))
+		return
+	}
 
-	switch v := entity.(type) {
-	case *store.Exec:
-		if !v.Password.Check(payload.Password) {
-			app.unauthorizedResponse(w, r, fmt.Errorf("invalid credentials"))
-			return
-		}
-		id = v.ID
-		role = string(v.Role)
-	case *store.Teacher:
-		if !v.Password.Check(payload.Password) {
-			app.unauthorizedResponse(w, r, fmt.Errorf("invalid credentials"))
-			return
-		}
-		id = v.ID
-		role = "teacher"
-	case *store.Student:
-		if !v.Password.Check(payload.Password) {
+	entity, err := app.loadProfile(ctx, account)
+	if err != nil {
+		// a soft-deleted profile keeps its account but cannot sign in
+		if errors.Is(err, store.ErrNotFound) {
 			app.unauthorizedResponse(w, r, fmt.Errorf("invalid credentials"))
 			return
 		}
-		id = v.ID
-		role = "student"
-	default:
-		app.internalServerErrorResponse(w, r, fmt.Errorf("unsupported entity type"))
+		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
-	claims := &auth.Claims{
-		ID:    id,
-		Email: payload.Email,
-		Role:  role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   fmt.Sprint(id),
-			Issuer:    app.config.auth.token.iss,
-			Audience:  []string{app.config.auth.token.iss},
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(app.config.auth.token.exp)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+	var email string
+	if account.Email != nil {
+		email = *account.Email
 	}
 
-	token, err := app.authenticator.GenerateToken(claims)
+	claims, token, err := app.issueToken(account.ProfileID, account.Role, email, account.MustChangePassword)
 	if err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
+	refreshToken, err := app.issueRefreshToken(ctx, account.ID, payload.RememberMe)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Accounts.RecordLogin(ctx, account.ID, clientIP(r)); err != nil {
+		app.logger.Warnw("recording login failed", "account", account.ID, "error", err.Error())
+	}
+
+	app.track(analytics.EventLogin, claims, map[string]any{"role": account.Role})
 
 	resp := map[string]any{
-		"entity": entity,
-		"token":  token,
+		"entity":        entity,
+		"token":         token,
+		"refresh_token": refreshToken,
+	}
+	if account.MustChangePassword {
+		resp["must_change_password"] = true
 	}
+	app.addSessionInfo(ctx, resp, account.Role, email, account.ID)
 
 	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
 		app.internalServerErrorResponse(w, r, err)
@@ -107,6 +130,24 @@ func (app *application) loginHandler(
 	}
 }
 
+// loadProfile returns the live exec, teacher, student or parent behind an
+// account, mapped to its response shape. Parents have no password, so only
+// refresh reaches them.
+func (app *application) loadProfile(ctx context.Context, account *store.Account) (any, error) {
+	switch account.ProfileType {
+	case store.ProfileExec:
+		return asDTO(dto.FromExec)(app.store.Execs.GetByID(ctx, account.ProfileID))
+	case store.ProfileTeacher:
+		return asDTO(unrestricted(dto.FromTeacher))(app.store.Teachers.GetByID(ctx, account.ProfileID))
+	case store.ProfileStudent:
+		return asDTO(unrestricted(dto.FromStudent))(app.store.Students.GetByID(ctx, account.ProfileID))
+	case store.ProfileParent:
+		return asDTO(dto.FromParent)(app.store.Parents.GetByID(ctx, account.ProfileID))
+	default:
+		return nil, store.ErrNotFound
+	}
+}
+
 // loginExecHandler godoc
 //
 //	@Summary		Exec Login
@@ -118,12 +159,10 @@ func (app *application) loginHandler(
 //	@Success		200		{object}	map[string]any		"Returns the logged-in exec and JWT token"
 //	@Failure		400		{object}	map[string]string	"Bad request"
 //	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Deprecated
 //	@Router			/execs/login [post]
 func (app *application) loginExecHandler(w http.ResponseWriter, r *http.Request) {
-	app.loginHandler(w, r, func(ctx context.Context, email string) (any, error) {
-		exec, err := app.store.Execs.GetByEmail(ctx, email)
-		return exec, err
-	})
+	app.login(w, r, store.ProfileExec)
 }
 
 // loginTeacherHandler godoc
@@ -137,12 +176,10 @@ func (app *application) loginExecHandler(w http.ResponseWriter, r *http.Request)
 //	@Success		200		{object}	map[string]any		"Returns the logged-in teacher and JWT token"
 //	@Failure		400		{object}	map[string]string	"Bad request"
 //	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Deprecated
 //	@Router			/teachers/login [post]
 func (app *application) loginTeacherHandler(w http.ResponseWriter, r *http.Request) {
-	app.loginHandler(w, r, func(ctx context.Context, email string) (any, error) {
-		teacher, err := app.store.Teachers.GetByEmail(ctx, email)
-		return teacher, err
-	})
+	app.login(w, r, store.ProfileTeacher)
 }
 
 // loginStudentHandler godoc
@@ -156,10 +193,8 @@ func (app *application) loginTeacherHandler(w http.ResponseWriter, r *http.Reque
 //	@Success		200		{object}	map[string]any		"Returns the logged-in student and JWT token"
 //	@Failure		400		{object}	map[string]string	"Bad request"
 //	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Deprecated
 //	@Router			/students/login [post]
 func (app *application) loginStudentHandler(w http.ResponseWriter, r *http.Request) {
-	app.loginHandler(w, r, func(ctx context.Context, email string) (any, error) {
-		student, err := app.store.Students.GetByEmail(ctx, email)
-		return student, err
-	})
+	app.login(w, r, store.ProfileStudent)
 }
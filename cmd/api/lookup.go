@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+)
+
+type lookupQuery struct {
+	Email string `validate:"omitempty,email"`
+	Phone string `validate:"omitempty,e164"`
+}
+
+// parseLookupQuery reads the email and phone query parameters; exactly one of
+// them must be given.
+func parseLookupQuery(r *http.Request) (lookupQuery, error) {
+	q := lookupQuery{
+		Email: r.URL.Query().Get("email"),
+		Phone: r.URL.Query().Get("phone"),
+	}
+	if (q.Email == "") == (q.Phone == "") {
+		return q, fmt.Errorf("exactly one of email or phone is required")
+	}
+	if err := Validate.Struct(q); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// LookupStudents godoc
+//
+//	@Summary		Look up students by email or phone
+//	@Description	Phone matches the student's or the parent's number
+//	@Tags			Students
+//	@Produce		json
+//	@Param			email	query		string	false	"Email (case-insensitive)"
+//	@Param			phone	query		string	false	"Phone number in E.164 format, with + encoded as %2B"
+//	@Success		200		{array}		dto.Student
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/lookup [get]
+//	@ID				lookupStudents
+func (app *application) lookupStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseLookupQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	students, err := app.store.Students.Lookup(r.Context(), q.Email, q.Phone)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	inScope := students[:0]
+	for _, s := range students {
+		ok, err := app.classroomInScope(r, s.ClassRoomID)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if ok {
+			inScope = append(inScope, s)
+		}
+	}
+	students = inScope
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudents(app.viewer(r), students)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// LookupTeachers godoc
+//
+//	@Summary	Look up teachers by email or phone
+//	@Tags		Teachers
+//	@Produce	json
+//	@Param		email	query		string	false	"Email (case-insensitive)"
+//	@Param		phone	query		string	false	"Phone number in E.164 format, with + encoded as %2B"
+//	@Success	200		{array}		dto.Teacher
+//	@Failure	400		{object}	error
+//	@Failure	500		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/teachers/lookup [get]
+//	@ID			lookupTeachers
+func (app *application) lookupTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseLookupQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	teachers, err := app.store.Teachers.Lookup(r.Context(), q.Email, q.Phone)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	inScope := teachers[:0]
+	for _, t := range teachers {
+		ok, err := app.teacherInScope(r, t.ID)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if ok {
+			inScope = append(inScope, t)
+		}
+	}
+	teachers = inScope
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTeachers(app.viewer(r), localizeTeachers(r, teachers))); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
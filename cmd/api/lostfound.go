@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/imaging"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const lostItemNotifyJob = "lost_item_notify"
+
+var lostItemPhotoUpload = uploadKind{
+	name:      "photo",
+	maxSize:   5 << 20,
+	types:     map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true},
+	typesText: "a JPEG, PNG or WebP image",
+}
+
+type CreateLostItemPayload struct {
+	Description string `json:"description" validate:"required,max=500" example:"Blue water bottle with a cat sticker"`
+	Location    string `json:"location" validate:"max=200" example:"Gym changing room"`
+	// FoundOn defaults to today.
+	FoundOn string `json:"found_on" validate:"omitempty,datetime=2006-01-02" example:"2026-10-16"`
+}
+
+type MatchLostItemPayload struct {
+	StudentID int64 `json:"student_id" validate:"required" example:"42"`
+}
+
+func lostItemID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "itemID"), 10, 64)
+}
+
+// CreateLostItem godoc
+//
+//	@Summary	Log a found item
+//	@Tags		Lost and found
+//	@Accept		json
+//	@Produce	json
+//	@Param		payload	body		CreateLostItemPayload	true	"Item"
+//	@Success	201		{object}	dto.LostItem
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/lost-found [post]
+//	@ID			createLostItem
+func (app *application) createLostItemHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateLostItemPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	foundOn := app.schoolToday()
+	if payload.FoundOn != "" {
+		foundOn, _ = time.Parse(time.DateOnly, payload.FoundOn)
+		if foundOn.After(app.schoolToday()) {
+			app.badRequestResponse(w, r, errors.New("found_on is in the future"))
+			return
+		}
+	}
+
+	user := getUser(r)
+	item := &store.LostItem{
+		Description: payload.Description,
+		Location:    payload.Location,
+		FoundOn:     foundOn,
+		FoundBy:     user.ID,
+		FoundByRole: user.Role,
+	}
+	if err := app.store.LostItems.Create(r.Context(), item); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromLostItem(item)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListLostItems godoc
+//
+//	@Summary		List and search found items
+//	@Description	q matches descriptions containing it or with words like it, best matches first; otherwise the most recently found come first.
+//	@Tags			Lost and found
+//	@Produce		json
+//	@Param			q		query		string	false	"Search the descriptions"
+//	@Param			status	query		string	false	"unclaimed, matched or claimed"
+//	@Success		200		{array}		dto.LostItem
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lost-found [get]
+//	@ID				listLostItems
+func (app *application) listLostItemsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := store.LostItemFilter{Query: q.Get("q"), Status: q.Get("status")}
+	switch f.Status {
+	case "", store.LostItemUnclaimed, store.LostItemMatched, store.LostItemClaimed:
+	default:
+		app.badRequestResponse(w, r, errors.New("status must be unclaimed, matched or claimed"))
+		return
+	}
+	if len(f.Query) > 200 {
+		app.badRequestResponse(w, r, errors.New("q must be at most 200 characters"))
+		return
+	}
+
+	list, err := app.store.LostItems.List(r.Context(), f)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLostItems(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetLostItem godoc
+//
+//	@Summary	Get a found item
+//	@Tags		Lost and found
+//	@Produce	json
+//	@Param		itemID	path		int	true	"Item ID"
+//	@Success	200		{object}	dto.LostItem
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/lost-found/{itemID} [get]
+//	@ID			getLostItem
+func (app *application) getLostItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := lostItemID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	item, err := app.store.LostItems.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLostItem(item)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// MatchLostItem godoc
+//
+//	@Summary		Match a found item to its owner
+//	@Description	Records whose the item is and tells the classroom: the student and their classroom teacher by email, and parents who linked Telegram. An item can be matched again to correct the owner until it is claimed.
+//	@Tags			Lost and found
+//	@Accept			json
+//	@Produce		json
+//	@Param			itemID	path		int						true	"Item ID"
+//	@Param			payload	body		MatchLostItemPayload	true	"Owner"
+//	@Success		200		{object}	dto.LostItem
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"Item or student not found"
+//	@Failure		409		{object}	error	"Already claimed"
+//	@Security		ApiKeyAuth
+//	@Router			/lost-found/{itemID}/match [post]
+//	@ID				matchLostItem
+func (app *application) matchLostItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := lostItemID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload MatchLostItemPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	student, err := app.store.Students.GetByID(ctx, payload.StudentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	item, err := app.store.LostItems.Match(ctx, id, student.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("item has already been claimed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.notifyLostItemMatch(r, item, student)
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLostItem(item)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// notifyLostItemMatch tells a student, their classroom teacher and their
+// parents that an item of theirs was found, in the background.
+func (app *application) notifyLostItemMatch(r *http.Request, item *store.LostItem, student *store.Student) {
+	user := getUser(r)
+	_, err := app.jobs.Enqueue(lostItemNotifyJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		text := fmt.Sprintf("Something belonging to %s %s was found: %s. It can be collected from the school office.",
+			student.FirstName, student.LastName, item.Description)
+
+		emails := []string{}
+		if student.Email != "" {
+			emails = append(emails, student.Email)
+		}
+		classroom, err := app.store.Classrooms.GetByID(ctx, student.ClassRoomID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		if classroom != nil {
+			teacher, err := app.store.Teachers.GetByID(ctx, classroom.TeacherID)
+			switch {
+			case err == nil && teacher.Email != "":
+				emails = append(emails, teacher.Email)
+			case err != nil && !errors.Is(err, store.ErrNotFound):
+				return nil, err
+			}
+		}
+
+		sent := map[string]int{}
+		if len(emails) > 0 {
+			if err := app.mailer.Send(ctx, mailer.Message{To: emails, Subject: "ClassNama: lost item found", Body: text + "\n"}); err != nil {
+				app.logger.Warnw("lost item email failed", "item", item.ID, "error", err.Error())
+			} else {
+				sent["email"] = len(emails)
+			}
+		}
+		if student.ParentPhoneNumber != "" {
+			chats, err := app.notifyTelegram(ctx, []string{student.ParentPhoneNumber}, text)
+			if err != nil {
+				app.logger.Warnw("lost item telegram failed", "item", item.ID, "error", err.Error())
+			}
+			sent["telegram"] = chats
+		}
+		return json.Marshal(sent)
+	})
+	if err != nil {
+		app.logger.Warnw("queueing lost item notice failed", "item", item.ID, "error", err.Error())
+	}
+}
+
+// ClaimLostItem godoc
+//
+//	@Summary	Mark a found item as handed back
+//	@Tags		Lost and found
+//	@Produce	json
+//	@Param		itemID	path		int	true	"Item ID"
+//	@Success	200		{object}	dto.LostItem
+//	@Failure	404		{object}	error
+//	@Failure	409		{object}	error	"Already claimed"
+//	@Security	ApiKeyAuth
+//	@Router		/lost-found/{itemID}/claim [post]
+//	@ID			claimLostItem
+func (app *application) claimLostItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := lostItemID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	item, err := app.store.LostItems.Claim(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("item has already been claimed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLostItem(item)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteLostItem godoc
+//
+//	@Summary	Delete a found item
+//	@Tags		Lost and found
+//	@Param		itemID	path	int	true	"Item ID"
+//	@Success	204		"No Content"
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/lost-found/{itemID} [delete]
+//	@ID			deleteLostItem
+func (app *application) deleteLostItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := lostItemID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.LostItems.Delete(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutLostItemPhoto godoc
+//
+//	@Summary		Upload a found item's photo
+//	@Description	The raw image is the request body; it replaces any earlier photo. Metadata is stripped and a thumbnail made for listings.
+//	@Tags			Lost and found
+//	@Accept			image/jpeg,image/png,image/webp
+//	@Param			itemID	path	int	true	"Item ID"
+//	@Success		204		"No Content"
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		422		{object}	error	"Refused as unsafe and quarantined"
+//	@Security		ApiKeyAuth
+//	@Router			/lost-found/{itemID}/photo [put]
+//	@ID				putLostItemPhoto
+func (app *application) putLostItemPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := lostItemID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	data, contentType, ok := app.readUpload(w, r, lostItemPhotoUpload)
+	if !ok {
+		return
+	}
+	data, err = imaging.Strip(data, contentType)
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("photo is not a valid image"))
+		return
+	}
+	thumb, thumbType, err := imaging.Resize(data, contentType, imaging.Thumbnail.MaxEdge)
+	if err != nil && !errors.Is(err, imaging.ErrUnsupported) {
+		app.logger.Warnw("scaling lost item photo failed", "item", id, "error", err.Error())
+	}
+
+	if err := app.store.LostItems.SetPhoto(r.Context(), id, data, contentType, thumb, thumbType); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLostItemPhoto godoc
+//
+//	@Summary		Get a found item's photo
+//	@Description	size=thumbnail returns a copy bounded to 128 px, except for WebP photos, which are served as uploaded.
+//	@Tags			Lost and found
+//	@Produce		image/jpeg,image/png,image/webp
+//	@Param			itemID	path	int		true	"Item ID"
+//	@Param			size	query	string	false	"Scaled copy"	Enums(thumbnail, original)
+//	@Success		200
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/lost-found/{itemID}/photo [get]
+//	@ID				getLostItemPhoto
+func (app *application) getLostItemPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := lostItemID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	size := r.URL.Query().Get("size")
+	if size != "" && size != imaging.Thumbnail.Name && size != "original" {
+		app.badRequestResponse(w, r, fmt.Errorf("unknown size %q", size))
+		return
+	}
+
+	data, contentType, err := app.store.LostItems.Photo(r.Context(), id, size == imaging.Thumbnail.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(data)
+}
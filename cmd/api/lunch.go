@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// lunchMenuDays is how many days of menus are listed when no end date is
+// asked for.
+const lunchMenuDays = 7
+
+type LunchMenuPayload struct {
+	Items []string `json:"items" validate:"required,min=1,max=20,dive,required,max=100" example:"Chelo kabab,Salad,Doogh"`
+	Notes string   `json:"notes" validate:"max=500" example:"Vegetarian option on request"`
+}
+
+// ListLunchMenus godoc
+//
+//	@Summary	List lunch menus
+//	@Tags		Lunch
+//	@Produce	json
+//	@Param		from	query		string	false	"Start date (YYYY-MM-DD), defaults to today"
+//	@Param		to		query		string	false	"End date (YYYY-MM-DD), defaults to a week from from"
+//	@Success	200		{array}		dto.LunchMenu
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/lunch-menus [get]
+//	@ID			listLunchMenus
+func (app *application) listLunchMenusHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	from := app.schoolToday()
+	if v := qs.Get("from"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid from date; expected YYYY-MM-DD"))
+			return
+		}
+		from = t
+	}
+	to := from.AddDate(0, 0, lunchMenuDays-1)
+	if v := qs.Get("to"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid to date; expected YYYY-MM-DD"))
+			return
+		}
+		to = t
+	}
+	if from.After(to) {
+		app.badRequestResponse(w, r, errors.New("from must not be after to"))
+		return
+	}
+	if to.Sub(from) > maxAnalyticsRange {
+		app.badRequestResponse(w, r, errors.New("date range must not exceed one year"))
+		return
+	}
+
+	menus, err := app.store.LunchMenus.List(r.Context(), from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLunchMenus(menus)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PutLunchMenu godoc
+//
+//	@Summary	Set the lunch menu of a date
+//	@Tags		Lunch
+//	@Accept		json
+//	@Produce	json
+//	@Param		date	path		string				true	"Date (YYYY-MM-DD)"
+//	@Param		payload	body		LunchMenuPayload	true	"Menu"
+//	@Success	200		{object}	dto.LunchMenu
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/lunch-menus/{date} [put]
+//	@ID			putLunchMenu
+func (app *application) putLunchMenuHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid date; expected YYYY-MM-DD"))
+		return
+	}
+
+	var payload LunchMenuPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	menu := &store.LunchMenu{Date: date.Format(time.DateOnly), Items: payload.Items, Notes: payload.Notes}
+	if err := app.store.LunchMenus.Put(r.Context(), menu); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLunchMenu(menu)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteLunchMenu godoc
+//
+//	@Summary	Remove the lunch menu of a date
+//	@Tags		Lunch
+//	@Param		date	path	string	true	"Date (YYYY-MM-DD)"
+//	@Success	204		"No Content"
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/lunch-menus/{date} [delete]
+//	@ID			deleteLunchMenu
+func (app *application) deleteLunchMenuHandler(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse(time.DateOnly, chi.URLParam(r, "date"))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid date; expected YYYY-MM-DD"))
+		return
+	}
+
+	if err := app.store.LunchMenus.Delete(r.Context(), date); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+)
+
+const (
+	maintenanceRefreshInterval = 5 * time.Second
+	defaultMaintenanceMessage  = "ClassNama is undergoing scheduled maintenance. Please try again shortly."
+)
+
+// maintenanceExemptPaths stay reachable during maintenance so admins can log
+// in, keep their session and switch it off. Other users can sign in too,
+// but their tokens get them no further.
+var maintenanceExemptPaths = []string{
+	"/v1/health",
+	"/v1/auth/login",
+	"/v1/auth/refresh",
+	"/v1/execs/login",
+	"/v1/admin/",
+}
+
+type MaintenancePayload struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty" validate:"max=256"`
+}
+
+// GetMaintenance godoc
+//
+//	@Summary	Get maintenance mode state
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{object}	cache.MaintenanceState
+//	@Security	ApiKeyAuth
+//	@Router		/admin/maintenance [get]
+//	@ID			getMaintenance
+func (app *application) getMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, app.maintenance.Load()); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// SetMaintenance godoc
+//
+//	@Summary		Switch maintenance mode on or off
+//	@Description	While enabled, all non-admin routes answer 503 with the given message
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		MaintenancePayload	true	"Maintenance state"
+//	@Success		200		{object}	cache.MaintenanceState
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Failure		503		{object}	error	"The switch could not be saved; nothing changed"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/maintenance [post]
+//	@ID				setMaintenance
+func (app *application) setMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload MaintenancePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	state := &cache.MaintenanceState{
+		Enabled:   payload.Enabled,
+		Message:   payload.Message,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: getUser(r).ID,
+	}
+	if state.Enabled && state.Message == "" {
+		state.Message = defaultMaintenanceMessage
+	}
+
+	// Without Redis the switch applies to this instance only. With Redis it
+	// must be saved first: other instances follow what is saved, and a
+	// switch only this instance knows about would be undone by the next sync.
+	if err := app.cacheStorage.Maintenance.Set(r.Context(), state); err != nil {
+		app.logger.Errorw("persisting maintenance state failed", "error", err.Error())
+		writeError(w, r, http.StatusServiceUnavailable, "maintenance state could not be saved; nothing was changed")
+		return
+	}
+	app.maintenance.Store(state)
+
+	app.logger.Infow("maintenance mode changed", "enabled", state.Enabled, "by", state.UpdatedBy)
+
+	if err := app.jsonResponse(w, http.StatusOK, state); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// MaintenanceMiddleware answers 503 for every non-exempt request while
+// maintenance mode is on. Requests carrying an admin token pass through.
+func (app *application) MaintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := app.maintenance.Load()
+		if state == nil || !state.Enabled || maintenanceExempt(r.URL.Path) || app.isAdminRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		type envelope struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+
+		w.Header().Set("Retry-After", "300")
+		writeJSON(w, http.StatusServiceUnavailable, &envelope{
+			Error:   "service under maintenance",
+			Message: state.Message,
+		})
+	})
+}
+
+func maintenanceExempt(path string) bool {
+	for _, p := range maintenanceExemptPaths {
+		if path == p || strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *application) isAdminRequest(r *http.Request) bool {
+	claims := app.tokenClaims(r)
+	return claims != nil && claims.Role == "admin"
+}
+
+// tokenClaims returns the claims of a valid bearer token, or nil. It is for
+// middleware that runs before AuthTokenMiddleware.
+func (app *application) tokenClaims(r *http.Request) *auth.Claims {
+	tokenStr, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+
+	token, err := app.authenticator.ValidateToken(tokenStr)
+	if err != nil || token == nil || !token.Valid {
+		return nil
+	}
+
+	claims, _ := token.Claims.(*auth.Claims)
+	return claims
+}
+
+// startMaintenanceSync loads the persisted maintenance state and keeps it in
+// sync with Redis so every instance follows a switch made on any of them.
+func (app *application) startMaintenanceSync() {
+	app.maintenance.Store(&cache.MaintenanceState{})
+
+	sync := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		state, err := app.cacheStorage.Maintenance.Get(ctx)
+		if err != nil {
+			app.logger.Warnw("loading maintenance state failed", "error", err.Error())
+			return
+		}
+		if state != nil {
+			app.maintenance.Store(state)
+		}
+	}
+
+	sync()
+	ticker := time.NewTicker(maintenanceRefreshInterval)
+	go func() {
+		for range ticker.C {
+			sync()
+		}
+	}()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// apiVersion is the version of the routes under /v1.
+const apiVersion = "v1"
+
+// apiVersions are the versions served, oldest first.
+var apiVersions = []string{"v1", "v2"}
+
+// Deprecation is a route slated for removal. Requests to it get
+// Deprecation and Sunset headers, and a Link to its successor.
+type Deprecation struct {
+	Method    string    `json:"method" example:"POST"`
+	Path      string    `json:"path" example:"/execs/login"`
+	Since     time.Time `json:"since" example:"2026-10-16T00:00:00Z"`
+	Sunset    time.Time `json:"sunset" example:"2027-04-01T00:00:00Z"`
+	Successor string    `json:"successor,omitempty" example:"/auth/login"`
+	Reason    string    `json:"reason,omitempty" example:"Use the unified login, which works for every role."`
+}
+
+// deprecations lists the routes clients should move off, by path relative
+// to /v1.
+var deprecations = []Deprecation{
+	{
+		Method: http.MethodPost, Path: "/execs/login",
+		Since: utcDate(2026, 10, 16), Sunset: utcDate(2027, 4, 1),
+		Successor: "/auth/login", Reason: "Use the unified login, which works for every role.",
+	},
+	{
+		Method: http.MethodPost, Path: "/teachers/login",
+		Since: utcDate(2026, 10, 16), Sunset: utcDate(2027, 4, 1),
+		Successor: "/auth/login", Reason: "Use the unified login, which works for every role.",
+	},
+	{
+		Method: http.MethodPost, Path: "/students/login",
+		Since: utcDate(2026, 10, 16), Sunset: utcDate(2027, 4, 1),
+		Successor: "/auth/login", Reason: "Use the unified login, which works for every role.",
+	},
+}
+
+func utcDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// deprecated marks a route as listed in deprecations. It panics when the
+// route is not listed, so the list and the router can't drift apart.
+func deprecated(method, path string) func(http.Handler) http.Handler {
+	var d *Deprecation
+	for i := range deprecations {
+		if deprecations[i].Method == method && deprecations[i].Path == path {
+			d = &deprecations[i]
+			break
+		}
+	}
+	if d == nil {
+		panic(fmt.Sprintf("deprecated: %s %s is not in deprecations", method, path))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			h.Set("Sunset", d.Sunset.Format(http.TimeFormat))
+			h.Add("Link", `</`+apiVersion+`/meta>; rel="deprecation"; type="application/json"`)
+			if d.Successor != "" {
+				h.Add("Link", `</`+apiVersion+d.Successor+`>; rel="successor-version"`)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MetaResponse describes what this server offers, for clients deciding
+// which features to show and what to migrate off.
+type MetaResponse struct {
+	APIVersion    string          `json:"api_version" example:"v1"`
+	Versions      []string        `json:"versions" example:"v1,v2"`
+	Version       string          `json:"version" example:"0.1.0"`
+	SchemaVersion uint            `json:"schema_version" example:"57"`
+	Features      map[string]bool `json:"features"`
+	Deprecations  []Deprecation   `json:"deprecations"`
+}
+
+// GetMeta godoc
+//
+//	@Summary		Describe the API's version, features and deprecations
+//	@Description	Features are the optional parts of the API enabled on this server. Deprecated routes answer with Deprecation and Sunset headers until they are removed on their sunset date.
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	MetaResponse
+//	@Router			/meta [get]
+//	@ID				getMeta
+func (app *application) getMetaHandler(w http.ResponseWriter, r *http.Request) {
+	dc := app.dynamic.Load()
+	cfg := app.config
+	resp := MetaResponse{
+		APIVersion:    apiVersion,
+		Versions:      apiVersions,
+		Version:       version,
+		SchemaVersion: schemaVersion,
+		Features: map[string]bool{
+			"absence_sms":      dc.absenceSMS.enabled,
+			"captcha":          dc.captcha.enabled,
+			"telegram":         dc.telegram.Token != "",
+			"cache":            cfg.redisCfg.enabled,
+			"search":           app.searchIndex != nil,
+			"online_classes":   cfg.meetings.Provider != "",
+			"online_payments":  cfg.payments.Gateway != "",
+			"upload_scan":      app.scanner.Name() != "",
+			"check_in":         cfg.checkIn.enabled,
+			"staff_attendance": cfg.staffAttendance.enabled,
+			"backups":          cfg.backup.enabled,
+			"analytics":        cfg.analytics.enabled,
+			"reminders":        cfg.reminders.enabled,
+			"dunning":          cfg.dunning.enabled,
+		},
+		Deprecations: deprecations,
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/captcha"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 )
 
 type AuthUser struct {
@@ -21,32 +26,67 @@ const userCtxKey ctxKey = "user"
 
 func (app *application) AuthTokenMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			app.unauthorizedResponse(w, r, fmt.Errorf("authorization header is missing"))
+		claims, ok := app.requestClaims(w, r)
+		if !ok {
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-
-		token, err := app.authenticator.ValidateToken(tokenStr)
-		if err != nil || token == nil || !token.Valid {
-			app.unauthorizedResponse(w, r, fmt.Errorf("authorization header is malformed"))
-			return
-		}
-
-		claims, ok := token.Claims.(*auth.Claims)
-		if !ok || claims == nil {
-			app.unauthorizedResponse(w, r, fmt.Errorf("invalid token claims"))
+		// a temporary password must be changed before anything else
+		if claims.MustChangePassword && !passwordChangeAllowed(r) {
+			app.logger.Warnw("forbidden", "method", r.Method, "path", r.URL.Path, "error", "password change required")
+			writeError(w, r, http.StatusForbidden, "password change required")
 			return
 		}
 
 		// put claims in context
 		ctx := context.WithValue(r.Context(), userCtxKey, claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
+
+		// managers may be limited to some grades or classrooms
+		if claims.Role == "manager" {
+			exec, err := app.store.Execs.GetByID(ctx, claims.ID)
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					app.unauthorizedResponse(w, r, fmt.Errorf("account no longer exists"))
+					return
+				}
+				app.internalServerErrorResponse(w, r, err)
+				return
+			}
+			ctx = context.WithValue(ctx, scopeCtx, exec.Scope)
+		}
+		app.trackFeatureUsage(next).ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// requestClaims authenticates r by its API key or bearer token, writing a
+// 401 (or, for a key over quota, a 429) when it fails.
+func (app *application) requestClaims(w http.ResponseWriter, r *http.Request) (*auth.Claims, bool) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return app.apiKeyClaims(w, r, key)
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		app.unauthorizedResponse(w, r, fmt.Errorf("authorization header is missing"))
+		return nil, false
+	}
+
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+
+	token, err := app.authenticator.ValidateToken(tokenStr)
+	if err != nil || token == nil || !token.Valid {
+		app.unauthorizedResponse(w, r, fmt.Errorf("authorization header is malformed"))
+		return nil, false
+	}
+
+	claims, ok := token.Claims.(*auth.Claims)
+	if !ok || claims == nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("invalid token claims"))
+		return nil, false
+	}
+	return claims, true
+}
+
 func (app *application) requireRole(roles ...string) func(http.Handler) http.Handler {
 	allowed := make(map[string]struct{}, len(roles))
 	for _, r := range roles {
@@ -71,15 +111,68 @@ func (app *application) requireRole(roles ...string) func(http.Handler) http.Han
 	}
 }
 
+// requireGrant only lets through callers whose account was granted
+// permission; their role alone is never enough.
+func (app *application) requireGrant(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := getUser(r)
+			if claims == nil {
+				app.unauthorizedResponse(w, r, fmt.Errorf("missing claims"))
+				return
+			}
+
+			ok, err := app.store.Grants.Has(r.Context(), accountKind(claims.Role), claims.ID, permission)
+			if err != nil {
+				app.internalServerErrorResponse(w, r, err)
+				return
+			}
+			if !ok {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func getUser(r *http.Request) *auth.Claims {
 	claims, _ := r.Context().Value(userCtxKey).(*auth.Claims)
 	return claims
 }
 
+// viewer is who the response to r is for, as the dto mappers take it. A
+// parent's phone number tells their children apart; when it can't be read
+// they see no student in full.
+func (app *application) viewer(r *http.Request) dto.Viewer {
+	user := getUser(r)
+	v := dto.Viewer{Role: user.Role, ID: user.ID}
+	if user.Role == "parent" {
+		parent, err := app.store.Parents.GetByID(r.Context(), user.ID)
+		if err != nil {
+			app.logger.Warnw("loading parent for redaction failed", "parent", user.ID, "error", err.Error())
+		} else {
+			v.Phone = parent.PhoneNumber
+		}
+	}
+	return v
+}
+
+// accountKind maps a token role to the table its ID belongs to.
+func accountKind(role string) string {
+	switch role {
+	case "admin", "manager":
+		return "exec"
+	default:
+		return role
+	}
+}
+
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.ratelimiter.Enabled {
-			if allow, retryAfter := app.ratelimiter.Allow(r.RemoteAddr); !allow {
+		if app.dynamic.Load().ratelimiter.Enabled {
+			if allow, retryAfter := app.ratelimiter.Allow(clientIP(r)); !allow {
 				app.rateLimitExceededResponse(w, r, retryAfter.String())
 				return
 			}
@@ -88,3 +181,40 @@ func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// workload runs the request's queries on w's connection pool. Requests use
+// the API pool unless a route says otherwise.
+func workload(w store.Workload) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(rw, r.WithContext(store.WithWorkload(r.Context(), w)))
+		})
+	}
+}
+
+// CaptchaMiddleware requires a valid X-Captcha-Token header when captcha is
+// enabled; it guards the public login, register and OTP endpoints.
+func (app *application) CaptchaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifier := app.dynamic.Load().captchaVerifier
+		if verifier == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+
+		err := verifier.Verify(r.Context(), r.Header.Get("X-Captcha-Token"), ip)
+		switch {
+		case err == nil:
+			next.ServeHTTP(w, r)
+		case errors.Is(err, captcha.ErrFailed):
+			app.badRequestResponse(w, r, errors.New("captcha verification failed"))
+		default:
+			app.serviceUnavailableResponse(w, r, err)
+		}
+	})
+}
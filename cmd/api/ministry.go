@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/ministry"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// ministryReportData is what report layouts are executed with; its fields
+// are the ones templates can use.
+type ministryReportData struct {
+	School struct {
+		Name string
+		Code string
+	}
+	Jurisdiction string
+	// From and To bound the attendance counted, inclusive; AsOf is the
+	// day enrollment is counted on.
+	From        time.Time
+	To          time.Time
+	AsOf        time.Time
+	GeneratedAt time.Time
+	Enrollment  []*store.GradeEnrollment
+	Attendance  []*store.GradeAttendance
+	// EnrollmentTotal and AttendanceTotal are the whole school's, with a
+	// zero Grade.
+	EnrollmentTotal store.GradeEnrollment
+	AttendanceTotal *store.GradeAttendance
+}
+
+// ListMinistryReports godoc
+//
+//	@Summary		List ministry report layouts
+//	@Description	The built-in generic layouts and those added from MINISTRY_TEMPLATES_DIR, by jurisdiction, report and format.
+//	@Tags			Ministry
+//	@Produce		json
+//	@Success		200	{array}	ministry.Layout
+//	@Security		ApiKeyAuth
+//	@Router			/ministry/reports [get]
+//	@ID				listMinistryReports
+func (app *application) listMinistryReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, app.ministry.Layouts()); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DownloadMinistryReport godoc
+//
+//	@Summary		Download a ministry report
+//	@Description	Renders enrollment (as of today) and attendance (over from–to) statistics by grade in the jurisdiction's fixed layout for the report.
+//	@Tags			Ministry
+//	@Produce		text/csv
+//	@Produce		application/xml
+//	@Param			report			path	string	true	"Report name, such as enrollment or attendance"
+//	@Param			format			query	string	false	"csv (default) or xml"
+//	@Param			jurisdiction	query	string	false	"Defaults to MINISTRY_JURISDICTION"
+//	@Param			from			query	string	false	"Start date (YYYY-MM-DD), default 90 days before to"
+//	@Param			to				query	string	false	"End date (YYYY-MM-DD), default today"
+//	@Success		200
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error	"No such layout"
+//	@Security		ApiKeyAuth
+//	@Router			/ministry/reports/{report} [get]
+//	@ID				downloadMinistryReport
+func (app *application) downloadMinistryReportHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	layout := ministry.Layout{
+		Jurisdiction: qs.Get("jurisdiction"),
+		Report:       chi.URLParam(r, "report"),
+		Format:       qs.Get("format"),
+	}
+	if layout.Jurisdiction == "" {
+		layout.Jurisdiction = app.config.ministry.jurisdiction
+	}
+	if layout.Format == "" {
+		layout.Format = ministry.FormatCSV
+	}
+	if layout.Format != ministry.FormatCSV && layout.Format != ministry.FormatXML {
+		app.badRequestResponse(w, r, fmt.Errorf("format must be %s or %s", ministry.FormatCSV, ministry.FormatXML))
+		return
+	}
+	if !app.ministry.Has(layout) {
+		app.notfoundResponse(w, r, fmt.Errorf("no %s %s layout for %s", layout.Report, layout.Format, layout.Jurisdiction))
+		return
+	}
+
+	from, to, err := parseDateRange(r, 90)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	data := ministryReportData{
+		Jurisdiction: layout.Jurisdiction,
+		From:         from,
+		To:           to,
+		AsOf:         app.schoolToday(),
+		GeneratedAt:  time.Now().UTC(),
+	}
+	data.School.Name = app.config.school.name
+	data.School.Code = app.config.ministry.schoolCode
+
+	data.Enrollment, err = app.store.Analytics.EnrollmentByGrade(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	for _, e := range data.Enrollment {
+		data.EnrollmentTotal.Classrooms += e.Classrooms
+		data.EnrollmentTotal.Capacity += e.Capacity
+		data.EnrollmentTotal.Students += e.Students
+	}
+
+	data.Attendance, data.AttendanceTotal, err = app.store.Analytics.AttendanceByGrade(r.Context(), from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	out, err := app.ministry.Render(layout, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, ministry.ErrNoLayout):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", layout.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("%s-%s-%s.%s", layout.Jurisdiction, layout.Report, to.Format(time.DateOnly), layout.Format)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(out)
+}
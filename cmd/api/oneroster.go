@@ -0,0 +1,479 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/lms"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// oneRosterBase is where the OneRoster 1.1 facade is mounted.
+const oneRosterBase = "/ims/oneroster/v1p1"
+
+const (
+	oneRosterDefaultLimit = 100
+	oneRosterMaxLimit     = 1000
+)
+
+// OneRosterRef is a OneRoster GUIDRef: a link to another record.
+type OneRosterRef struct {
+	Href      string `json:"href"`
+	SourcedID string `json:"sourcedId"`
+	Type      string `json:"type"`
+}
+
+type OneRosterOrg struct {
+	SourcedID        string `json:"sourcedId"`
+	Status           string `json:"status"`
+	DateLastModified string `json:"dateLastModified"`
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	Identifier       string `json:"identifier"`
+}
+
+type OneRosterClass struct {
+	SourcedID        string         `json:"sourcedId"`
+	Status           string         `json:"status"`
+	DateLastModified string         `json:"dateLastModified"`
+	Title            string         `json:"title"`
+	ClassCode        string         `json:"classCode"`
+	ClassType        string         `json:"classType"`
+	Location         string         `json:"location"`
+	Grades           []string       `json:"grades"`
+	Subjects         []string       `json:"subjects"`
+	Course           OneRosterRef   `json:"course"`
+	School           OneRosterRef   `json:"school"`
+	Terms            []OneRosterRef `json:"terms"`
+}
+
+type OneRosterUser struct {
+	SourcedID        string         `json:"sourcedId"`
+	Status           string         `json:"status"`
+	DateLastModified string         `json:"dateLastModified"`
+	Username         string         `json:"username"`
+	EnabledUser      string         `json:"enabledUser"`
+	GivenName        string         `json:"givenName"`
+	FamilyName       string         `json:"familyName"`
+	Role             string         `json:"role"`
+	Identifier       string         `json:"identifier"`
+	Email            string         `json:"email"`
+	Phone            string         `json:"phone"`
+	Orgs             []OneRosterRef `json:"orgs"`
+	Agents           []OneRosterRef `json:"agents"`
+	Grades           []string       `json:"grades"`
+}
+
+type OneRosterEnrollment struct {
+	SourcedID        string       `json:"sourcedId"`
+	Status           string       `json:"status"`
+	DateLastModified string       `json:"dateLastModified"`
+	User             OneRosterRef `json:"user"`
+	Class            OneRosterRef `json:"class"`
+	School           OneRosterRef `json:"school"`
+	Role             string       `json:"role"`
+	Primary          string       `json:"primary"`
+}
+
+func (app *application) oneRosterRef(path, sourcedID, kind string) OneRosterRef {
+	return OneRosterRef{
+		Href:      fmt.Sprintf("%s%s/%s/%s", strings.TrimSuffix(app.config.publicURL, "/"), oneRosterBase, path, sourcedID),
+		SourcedID: sourcedID,
+		Type:      kind,
+	}
+}
+
+func oneRosterTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func oneRosterGrade(grade int64) string {
+	return fmt.Sprintf("%02d", grade)
+}
+
+func (app *application) oneRosterOrg() OneRosterOrg {
+	return OneRosterOrg{
+		SourcedID: lms.OrgSourcedID,
+		Status:    "active",
+		Name:      app.config.school.name,
+		Type:      "school",
+	}
+}
+
+func (app *application) oneRosterClass(c *store.RosterClass) OneRosterClass {
+	return OneRosterClass{
+		SourcedID:        lms.ClassSourcedID(c.ID),
+		Status:           "active",
+		DateLastModified: oneRosterTime(c.UpdatedAt),
+		Title:            c.Name,
+		ClassType:        "homeroom",
+		Grades:           []string{oneRosterGrade(c.Grade)},
+		Subjects:         []string{},
+		Course:           app.oneRosterRef("courses", lms.CourseSourcedID(c.Grade), "course"),
+		School:           app.oneRosterRef("schools", lms.OrgSourcedID, "org"),
+		Terms:            []OneRosterRef{},
+	}
+}
+
+func (app *application) oneRosterUser(u *store.RosterUser) OneRosterUser {
+	user := OneRosterUser{
+		SourcedID:        lms.UserSourcedID(u.Role, u.ID),
+		Status:           "active",
+		DateLastModified: oneRosterTime(u.UpdatedAt),
+		Username:         u.Email,
+		EnabledUser:      "true",
+		GivenName:        u.FirstName,
+		FamilyName:       u.LastName,
+		Role:             u.Role,
+		Identifier:       u.Identifier,
+		Email:            u.Email,
+		Phone:            u.Phone,
+		Orgs:             []OneRosterRef{app.oneRosterRef("orgs", lms.OrgSourcedID, "org")},
+		Agents:           []OneRosterRef{},
+		Grades:           []string{},
+	}
+	if u.Grade != nil {
+		user.Grades = append(user.Grades, oneRosterGrade(*u.Grade))
+	}
+	return user
+}
+
+func (app *application) oneRosterEnrollment(e *store.RosterEnrollment) OneRosterEnrollment {
+	primary := "false"
+	if e.Role == "teacher" {
+		primary = "true"
+	}
+	return OneRosterEnrollment{
+		SourcedID:        lms.EnrollmentSourcedID(e.ClassroomID, e.Role, e.UserID),
+		Status:           "active",
+		DateLastModified: oneRosterTime(e.UpdatedAt),
+		User:             app.oneRosterRef("users", lms.UserSourcedID(e.Role, e.UserID), "user"),
+		Class:            app.oneRosterRef("classes", lms.ClassSourcedID(e.ClassroomID), "class"),
+		School:           app.oneRosterRef("schools", lms.OrgSourcedID, "org"),
+		Role:             e.Role,
+		Primary:          primary,
+	}
+}
+
+// oneRosterPaging reads the OneRoster limit and offset parameters.
+func oneRosterPaging(r *http.Request) (int, int, error) {
+	limit, offset := oneRosterDefaultLimit, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > oneRosterMaxLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", oneRosterMaxLimit)
+		}
+		limit = n
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// writeOneRoster writes a OneRoster response, whose body wraps the payload
+// in a single named field; lists report their full size in X-Total-Count.
+func (app *application) writeOneRoster(w http.ResponseWriter, r *http.Request, field string, data any, total int) {
+	if total >= 0 {
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	}
+	if err := writeJSON(w, http.StatusOK, map[string]any{field: data}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// oneRosterID reads a sourcedId URL parameter of the given kind.
+func oneRosterID(r *http.Request, param string, kinds ...string) (string, int64, bool) {
+	kind, id, ok := lms.ParseSourcedID(chi.URLParam(r, param))
+	if !ok {
+		return "", 0, false
+	}
+	for _, k := range kinds {
+		if kind == k {
+			return kind, id, true
+		}
+	}
+	return "", 0, false
+}
+
+// OneRosterOrgs godoc
+//
+//	@Summary		OneRoster: list orgs
+//	@Description	The school is the only org.
+//	@Tags			OneRoster
+//	@Produce		json
+//	@Success		200	{object}	map[string][]OneRosterOrg
+//	@Security		ApiKeyAuth
+//	@Router			/ims/oneroster/v1p1/orgs [get]
+//	@ID				oneRosterOrgs
+func (app *application) oneRosterOrgsHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeOneRoster(w, r, "orgs", []OneRosterOrg{app.oneRosterOrg()}, 1)
+}
+
+// OneRosterOrg godoc
+//
+//	@Summary	OneRoster: get an org
+//	@Tags		OneRoster
+//	@Produce	json
+//	@Param		sourcedId	path		string	true	"Org sourcedId"
+//	@Success	200			{object}	map[string]OneRosterOrg
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/ims/oneroster/v1p1/orgs/{sourcedId} [get]
+//	@ID			oneRosterOrg
+func (app *application) oneRosterOrgHandler(w http.ResponseWriter, r *http.Request) {
+	if chi.URLParam(r, "sourcedId") != lms.OrgSourcedID {
+		app.notfoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+	field := "org"
+	if strings.Contains(r.URL.Path, "/schools/") {
+		field = "school"
+	}
+	app.writeOneRoster(w, r, field, app.oneRosterOrg(), -1)
+}
+
+// OneRosterClasses godoc
+//
+//	@Summary		OneRoster: list classes
+//	@Description	Every live classroom is a homeroom class. Paged with limit (default 100) and offset; X-Total-Count has the full count.
+//	@Tags			OneRoster
+//	@Produce		json
+//	@Param			limit	query		int	false	"Page size"
+//	@Param			offset	query		int	false	"Records to skip"
+//	@Success		200		{object}	map[string][]OneRosterClass
+//	@Security		ApiKeyAuth
+//	@Router			/ims/oneroster/v1p1/classes [get]
+//	@ID				oneRosterClasses
+func (app *application) oneRosterClassesHandler(byTeacher bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var teacherID int64
+		if byTeacher {
+			_, id, ok := oneRosterID(r, "sourcedId", "teacher")
+			if !ok {
+				app.notfoundResponse(w, r, store.ErrNotFound)
+				return
+			}
+			teacherID = id
+		}
+
+		limit, offset, err := oneRosterPaging(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		classes, total, err := app.store.OneRoster.Classes(r.Context(), teacherID, limit, offset)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		out := make([]OneRosterClass, len(classes))
+		for i, c := range classes {
+			out[i] = app.oneRosterClass(c)
+		}
+		app.writeOneRoster(w, r, "classes", out, total)
+	}
+}
+
+// OneRosterClass godoc
+//
+//	@Summary	OneRoster: get a class
+//	@Tags		OneRoster
+//	@Produce	json
+//	@Param		sourcedId	path		string	true	"Class sourcedId"
+//	@Success	200			{object}	map[string]OneRosterClass
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/ims/oneroster/v1p1/classes/{sourcedId} [get]
+//	@ID			oneRosterClass
+func (app *application) oneRosterClassHandler(w http.ResponseWriter, r *http.Request) {
+	_, id, ok := oneRosterID(r, "sourcedId", "class")
+	if !ok {
+		app.notfoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	class, err := app.store.OneRoster.Class(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeOneRoster(w, r, "class", app.oneRosterClass(class), -1)
+}
+
+// oneRosterUsersHandler lists users, limited to role when it is set and,
+// with byClass, to the class in the URL.
+func (app *application) oneRosterUsersHandler(role string, byClass bool) http.HandlerFunc {
+	field := "users"
+	if role != "" {
+		field = role + "s"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := store.RosterUserFilter{Role: role}
+		if byClass {
+			_, id, ok := oneRosterID(r, "sourcedId", "class")
+			if !ok {
+				app.notfoundResponse(w, r, store.ErrNotFound)
+				return
+			}
+			filter.ClassroomID = id
+		}
+
+		limit, offset, err := oneRosterPaging(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		var users []*store.RosterUser
+		var total int
+		if filter.ClassroomID != 0 && role == "teacher" {
+			// teachers are linked to classes through the classroom, not
+			// the user row
+			users, total, err = app.classTeachers(r, filter.ClassroomID)
+		} else {
+			users, total, err = app.store.OneRoster.Users(r.Context(), filter, limit, offset)
+		}
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		out := make([]OneRosterUser, len(users))
+		for i, u := range users {
+			out[i] = app.oneRosterUser(u)
+		}
+		app.writeOneRoster(w, r, field, out, total)
+	}
+}
+
+// classTeachers returns the teacher of a class, if it has a live one.
+func (app *application) classTeachers(r *http.Request, classroomID int64) ([]*store.RosterUser, int, error) {
+	class, err := app.store.OneRoster.Class(r.Context(), classroomID)
+	if errors.Is(err, store.ErrNotFound) {
+		return []*store.RosterUser{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	teacher, err := app.store.OneRoster.User(r.Context(), "teacher", class.TeacherID)
+	if errors.Is(err, store.ErrNotFound) {
+		return []*store.RosterUser{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return []*store.RosterUser{teacher}, 1, nil
+}
+
+// oneRosterUserHandler returns one user; role limits which kinds the
+// route accepts.
+func (app *application) oneRosterUserHandler(role string) http.HandlerFunc {
+	field, kinds := "user", []string{"student", "teacher"}
+	if role != "" {
+		field, kinds = role, []string{role}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		kind, id, ok := oneRosterID(r, "sourcedId", kinds...)
+		if !ok {
+			app.notfoundResponse(w, r, store.ErrNotFound)
+			return
+		}
+
+		user, err := app.store.OneRoster.User(r.Context(), kind, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.writeOneRoster(w, r, field, app.oneRosterUser(user), -1)
+	}
+}
+
+// OneRosterEnrollments godoc
+//
+//	@Summary		OneRoster: list enrollments
+//	@Description	A student is enrolled in their classroom and a teacher, as primary, in each classroom they lead. Paged with limit (default 100) and offset.
+//	@Tags			OneRoster
+//	@Produce		json
+//	@Param			limit	query		int	false	"Page size"
+//	@Param			offset	query		int	false	"Records to skip"
+//	@Success		200		{object}	map[string][]OneRosterEnrollment
+//	@Security		ApiKeyAuth
+//	@Router			/ims/oneroster/v1p1/enrollments [get]
+//	@ID				oneRosterEnrollments
+func (app *application) oneRosterEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := oneRosterPaging(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	enrollments, total, err := app.store.OneRoster.Enrollments(r.Context(), 0, limit, offset)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	out := make([]OneRosterEnrollment, len(enrollments))
+	for i, e := range enrollments {
+		out[i] = app.oneRosterEnrollment(e)
+	}
+	app.writeOneRoster(w, r, "enrollments", out, total)
+}
+
+// OneRosterEnrollment godoc
+//
+//	@Summary	OneRoster: get an enrollment
+//	@Tags		OneRoster
+//	@Produce	json
+//	@Param		sourcedId	path		string	true	"Enrollment sourcedId"
+//	@Success	200			{object}	map[string]OneRosterEnrollment
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/ims/oneroster/v1p1/enrollments/{sourcedId} [get]
+//	@ID			oneRosterEnrollment
+func (app *application) oneRosterEnrollmentHandler(w http.ResponseWriter, r *http.Request) {
+	classID, role, userID, ok := lms.ParseEnrollmentSourcedID(chi.URLParam(r, "sourcedId"))
+	if !ok {
+		app.notfoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+
+	enrollment, err := app.store.OneRoster.Enrollment(r.Context(), classID, role, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeOneRoster(w, r, "enrollment", app.oneRosterEnrollment(enrollment), -1)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/meetings"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type onlineSessionKey string
+
+const meetingRevokeJob = "meeting_revoke"
+
+const onlineSessionCtx onlineSessionKey = "onlineSession"
+
+type StartOnlineSessionPayload struct {
+	// DefaultStatus is recorded for students who have not joined when the
+	// session closes.
+	DefaultStatus string `json:"default_status" validate:"omitempty,oneof=present absent late excused" enums:"present,absent,late,excused" example:"present"`
+	// DurationMinutes is the planned length of the meeting booked with the
+	// meeting provider; 60 when omitted.
+	DurationMinutes int `json:"duration_minutes" validate:"omitempty,min=5,max=480"`
+}
+
+// MyClassroom is a classroom as seen by its teacher or one of its students,
+// with the open online session if there is one.
+type MyClassroom struct {
+	*dto.Classroom
+	Session *dto.OnlineSession `json:"session"`
+}
+
+type JoinOnlineSessionResponse struct {
+	MeetingURL string `json:"meeting_url"`
+}
+
+// validateClassroomMode checks that online and hybrid classrooms have a
+// meeting link, unless a meeting provider gives each session its own.
+func (app *application) validateClassroomMode(c *store.Classroom) error {
+	if c.Mode != "" && c.Mode != store.ModeInPerson && c.MeetingURL == "" && app.meetings.Name() == "" {
+		return fmt.Errorf("meeting_url is required for %s classrooms", c.Mode)
+	}
+	return nil
+}
+
+// canRunClassroom reports whether the user may start or close sessions of a
+// classroom: execs always, teachers only for their own classrooms.
+func canRunClassroom(r *http.Request, c *store.Classroom) bool {
+	user := getUser(r)
+	if user.Role == "teacher" {
+		return c.TeacherID == user.ID
+	}
+	return true
+}
+
+// schoolToday returns today's date in the school's time zone.
+func (app *application) schoolToday() time.Time {
+	now := app.school.now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// GetMyClassrooms godoc
+//
+//	@Summary		List the caller's classrooms
+//	@Description	Teachers get the classrooms they teach; students get their own. Students only see the meeting link while an online session is open.
+//	@Tags			Online
+//	@Produce		json
+//	@Success		200	{array}		MyClassroom
+//	@Failure		403	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/me/classrooms [get]
+//	@ID				getMyClassrooms
+func (app *application) getMyClassroomsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getUser(r)
+
+	var classrooms []*store.Classroom
+	switch user.Role {
+	case "teacher":
+		var err error
+		classrooms, err = app.store.Classrooms.GetByTeacherID(ctx, user.ID)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+	case "student":
+		student, err := app.store.Students.GetByID(ctx, user.ID)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		classroom, err := app.store.Classrooms.GetByID(ctx, student.ClassRoomID)
+		switch {
+		case err == nil:
+			classrooms = []*store.Classroom{classroom}
+		case errors.Is(err, store.ErrNotFound):
+			classrooms = []*store.Classroom{}
+		default:
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+	default:
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	mine := make([]*MyClassroom, 0, len(classrooms))
+	for _, c := range localizeClassrooms(r, classrooms) {
+		session, err := app.store.OnlineSessions.GetOpen(ctx, c.ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if user.Role == "student" && session == nil {
+			c.MeetingURL = ""
+		}
+		m := &MyClassroom{Classroom: dto.FromClassroom(c)}
+		if session != nil {
+			m.Session = dto.FromOnlineSession(session)
+		}
+		mine = append(mine, m)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mine); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// StartOnlineSession godoc
+//
+//	@Summary		Start an online session for a classroom
+//	@Description	Only for online or hybrid classrooms, on school days. Until the session closes, students mark themselves present by joining; the rest get default_status (absent unless given). With a meeting provider configured the session gets its own meeting link, revoked on close.
+//	@Tags			Online
+//	@Accept			json
+//	@Produce		json
+//	@Param			classroomID	path		int							true	"Classroom ID"
+//	@Param			payload		body		StartOnlineSessionPayload	false	"Options"
+//	@Success		201			{object}	dto.OnlineSession
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		503			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/online/classrooms/{classroomID}/sessions [post]
+//	@ID				startOnlineSession
+func (app *application) startOnlineSessionHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if !canRunClassroom(r, classroom) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+	if classroom.Mode == store.ModeInPerson {
+		app.badRequestResponse(w, r, fmt.Errorf("classroom is in-person; set its mode to online or hybrid first"))
+		return
+	}
+
+	var payload StartOnlineSessionPayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if payload.DefaultStatus == "" {
+		payload.DefaultStatus = "absent"
+	}
+
+	today := app.schoolToday()
+	if !app.requireSchoolDay(w, r, today) {
+		return
+	}
+
+	teacherID := classroom.TeacherID
+	session := &store.OnlineSession{
+		ClassroomID:   classroom.ID,
+		TeacherID:     &teacherID,
+		Date:          today,
+		DefaultStatus: payload.DefaultStatus,
+	}
+	if err := app.provisionMeeting(r.Context(), classroom, session, payload.DurationMinutes); err != nil {
+		app.serviceUnavailableResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.OnlineSessions.Start(r.Context(), session); err != nil {
+		app.revokeMeeting(session)
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("classroom already has an open session"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromOnlineSession(session)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CloseOnlineSession godoc
+//
+//	@Summary	Close an online session and record the default status for students who did not join
+//	@Tags		Online
+//	@Produce	json
+//	@Param		sessionID	path		int	true	"Session ID"
+//	@Success	200			{object}	dto.OnlineSession
+//	@Failure	403			{object}	error
+//	@Failure	409			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/online/sessions/{sessionID}/close [post]
+//	@ID			closeOnlineSession
+func (app *application) closeOnlineSessionHandler(w http.ResponseWriter, r *http.Request) {
+	session := getOnlineSessionFromCtx(r)
+
+	classroom, err := app.store.Classrooms.GetByID(r.Context(), session.ClassroomID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if classroom != nil && !canRunClassroom(r, classroom) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if err := app.store.OnlineSessions.Close(r.Context(), session); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.conflictResponse(w, r, fmt.Errorf("session is already closed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.revokeMeeting(session)
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromOnlineSession(session)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// JoinOnlineSession godoc
+//
+//	@Summary	Join an open online session
+//	@Description	Marks the student present for the day (unless already marked) and returns the meeting link.
+//	@Tags		Online
+//	@Produce	json
+//	@Param		sessionID	path		int	true	"Session ID"
+//	@Success	200			{object}	JoinOnlineSessionResponse
+//	@Failure	403			{object}	error
+//	@Failure	409			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/online/sessions/{sessionID}/join [post]
+//	@ID			joinOnlineSession
+func (app *application) joinOnlineSessionHandler(w http.ResponseWriter, r *http.Request) {
+	session := getOnlineSessionFromCtx(r)
+	if session.EndedAt != nil {
+		app.conflictResponse(w, r, fmt.Errorf("session is closed"))
+		return
+	}
+
+	classroom, err := app.store.Classrooms.GetByID(r.Context(), session.ClassroomID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.OnlineSessions.Join(r.Context(), session, getUser(r).ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.forbiddenResponse(w, r)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, JoinOnlineSessionResponse{MeetingURL: sessionMeetingURL(session, classroom)}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// provisionMeeting books a meeting for a new session with the configured
+// provider. Without one the session uses the classroom's link; if the
+// provider fails, it falls back to that link when there is one.
+func (app *application) provisionMeeting(ctx context.Context, classroom *store.Classroom, session *store.OnlineSession, minutes int) error {
+	if app.meetings.Name() == "" {
+		return nil
+	}
+	if minutes == 0 {
+		minutes = 60
+	}
+
+	created, err := app.meetings.Create(ctx, meetings.Meeting{
+		Topic:    classroom.Name,
+		Start:    time.Now(),
+		Duration: time.Duration(minutes) * time.Minute,
+	})
+	if err != nil {
+		if classroom.MeetingURL != "" {
+			app.logger.Warnw("meeting provisioning failed, using the classroom link", "classroom_id", classroom.ID, "error", err)
+			return nil
+		}
+		return fmt.Errorf("could not create a meeting: %w", err)
+	}
+
+	session.MeetingProvider = app.meetings.Name()
+	session.MeetingID = created.ID
+	session.MeetingURL = created.JoinURL
+	return nil
+}
+
+// revokeMeeting deletes a session's provisioned meeting so its link stops
+// working. It runs in the background; failures are only logged.
+func (app *application) revokeMeeting(session *store.OnlineSession) {
+	if session.MeetingID == "" {
+		return
+	}
+	id, provider := session.MeetingID, session.MeetingProvider
+	if provider != app.meetings.Name() {
+		app.logger.Warnw("cannot revoke meeting from another provider", "session_id", session.ID, "provider", provider)
+		return
+	}
+
+	_, err := app.jobs.Enqueue(meetingRevokeJob, 0, "system", func(ctx context.Context) ([]byte, error) {
+		if err := app.meetings.Delete(ctx, id); err != nil {
+			app.logger.Warnw("meeting revocation failed", "session_id", session.ID, "provider", provider, "error", err)
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		app.logger.Warnw("could not queue meeting revocation", "session_id", session.ID, "error", err)
+	}
+}
+
+// sessionMeetingURL returns the link students join a session with.
+func sessionMeetingURL(session *store.OnlineSession, classroom *store.Classroom) string {
+	if session.MeetingURL != "" {
+		return session.MeetingURL
+	}
+	return classroom.MeetingURL
+}
+
+// ------------------- Middleware -------------------
+
+func (app *application) onlineSessionContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "sessionID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		session, err := app.store.OnlineSessions.GetByID(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), onlineSessionCtx, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getOnlineSessionFromCtx(r *http.Request) *store.OnlineSession {
+	s, _ := r.Context().Value(onlineSessionCtx).(*store.OnlineSession)
+	return s
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/analytics"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+)
+
+const otpDigits = 6
+
+// newOTP returns a random numeric code of otpDigits digits.
+func newOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n.Int64()), nil
+}
+
+// otpHash keys the code to the phone so a leaked hash can't be replayed for
+// another number; only the hash is kept in Redis.
+func (app *application) otpHash(phone, code string) []byte {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	mac.Write([]byte(phone + ":" + code))
+	return mac.Sum(nil)
+}
+
+type OTPRequestPayload struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164" example:"+989121234567"`
+}
+
+type OTPVerifyPayload struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164" example:"+989121234567"`
+	Code        string `json:"code" validate:"required,numeric,len=6" example:"482913"`
+	// RememberMe extends the refresh token's lifetime.
+	RememberMe bool `json:"remember_me" example:"true"`
+}
+
+// RequestOTP godoc
+//
+//	@Summary		Text a parent a login code
+//	@Description	Sends a 6-digit code to the phone number when it is a parent number on file. The response is always 202, whether a code was sent, the number is unknown, it asked too often or sending failed, so it can't be used to find numbers on file. One request per minute and five per hour per number count, known or not.
+//	@Tags			Parents
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		OTPRequestPayload	true	"Phone number"
+//	@Success		202		{object}	map[string]string
+//	@Failure		400		{object}	error
+//	@Router			/auth/otp/request [post]
+//	@ID				requestOTP
+func (app *application) requestOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var payload OTPRequestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// whatever happens, the caller learns nothing about the number
+	switch err := app.sendOTP(r.Context(), payload.PhoneNumber); {
+	case err == nil:
+	case errors.Is(err, cache.ErrOTPTooSoon), errors.Is(err, cache.ErrOTPTooMany):
+		app.logger.Infow("otp request throttled", "path", r.URL.Path, "error", err.Error())
+	default:
+		app.logger.Errorw("sending otp failed", "path", r.URL.Path, "error", err.Error())
+	}
+
+	resp := map[string]string{"message": "if this number is on file, a code has been sent"}
+	if err := app.jsonResponse(w, http.StatusAccepted, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// sendOTP texts phone a new code if it is a parent number on file. The
+// request counts against the number's limits first, known or not.
+func (app *application) sendOTP(ctx context.Context, phone string) error {
+	if err := app.cacheStorage.OTP.Allow(ctx, phone); err != nil {
+		return err
+	}
+
+	known, err := app.store.Parents.HasChildren(ctx, phone)
+	if err != nil || !known {
+		return err
+	}
+
+	code, err := newOTP()
+	if err != nil {
+		return err
+	}
+	if err := app.cacheStorage.OTP.Issue(ctx, phone, app.otpHash(phone, code)); err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("Your classnama login code is %s. It expires in 5 minutes.", code)
+	return app.sms.Send(ctx, phone, text)
+}
+
+// VerifyOTP godoc
+//
+//	@Summary		Log in as a parent with a texted code
+//	@Description	Exchanges a code from /auth/otp/request for a JWT with the parent role. A code works once and is dropped after five wrong guesses.
+//	@Tags			Parents
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		OTPVerifyPayload	true	"Phone number and code"
+//	@Success		200		{object}	map[string]any		"Returns the parent, JWT token and refresh token"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Router			/auth/otp/verify [post]
+//	@ID				verifyOTP
+func (app *application) verifyOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var payload OTPVerifyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.cacheStorage.OTP.Check(ctx, payload.PhoneNumber, app.otpHash(payload.PhoneNumber, payload.Code)); err != nil {
+		if errors.Is(err, cache.ErrOTPInvalid) {
+			app.unauthorizedResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	parent, err := app.store.Parents.Login(ctx, payload.PhoneNumber)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	account, err := app.store.Accounts.GetByProfile(ctx, store.ProfileParent, parent.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if account.DeactivatedAt != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account deactivated"))
+		return
+	}
+
+	claims, token, err := app.issueToken(parent.ID, "parent", "", false)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	refreshToken, err := app.issueRefreshToken(ctx, account.ID, payload.RememberMe)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Accounts.RecordLogin(ctx, account.ID, clientIP(r)); err != nil {
+		app.logger.Warnw("recording login failed", "account", account.ID, "error", err.Error())
+	}
+
+	app.track(analytics.EventLogin, claims, map[string]any{"role": "parent"})
+
+	resp := map[string]any{
+		"entity":        dto.FromParent(parent),
+		"token":         token,
+		"refresh_token": refreshToken,
+	}
+	app.addSessionInfo(ctx, resp, "parent", "", 0)
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetMyChildren godoc
+//
+//	@Summary		List the caller's children
+//	@Description	Students whose parent phone number is the logged-in parent's.
+//	@Tags			Parents
+//	@Produce		json
+//	@Success		200	{array}		dto.Student
+//	@Failure		401	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/parents/me/children [get]
+//	@ID				getMyChildren
+func (app *application) getMyChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	parent, err := app.store.Parents.GetByID(ctx, getUser(r).ID)
+	if err != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account no longer exists"))
+		return
+	}
+
+	children, err := app.store.Parents.Children(ctx, parent.PhoneNumber)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudents(app.viewer(r), children)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/sms"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+const (
+	outboxRelayTick   = 5 * time.Second
+	outboxBatchSize   = 50
+	outboxLease       = time.Minute
+	outboxMaxAttempts = 10
+)
+
+// outboxHandlers deliver outbox events by topic. An error leaves the event
+// to be retried.
+func (app *application) outboxHandlers() map[string]func(context.Context, json.RawMessage) error {
+	return map[string]func(context.Context, json.RawMessage) error{
+		store.OutboxInvoicePaid:     app.deliverPaymentReceived,
+		store.OutboxInstallmentPaid: app.deliverPaymentReceived,
+	}
+}
+
+// startOutboxRelay delivers committed outbox events every few seconds,
+// backing off on events whose delivery fails.
+func (app *application) startOutboxRelay() {
+	handlers := app.outboxHandlers()
+	ticker := time.NewTicker(outboxRelayTick)
+	go func() {
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), outboxLease)
+			events, err := app.store.Outbox.Claim(ctx, outboxBatchSize, outboxMaxAttempts, outboxLease)
+			if err != nil {
+				app.logger.Errorw("claiming outbox events failed", "error", err.Error())
+				cancel()
+				continue
+			}
+
+			for _, e := range events {
+				handle, ok := handlers[e.Topic]
+				if !ok {
+					err = fmt.Errorf("no handler for topic %q", e.Topic)
+				} else {
+					err = handle(ctx, e.Payload)
+				}
+				if err == nil {
+					err = app.store.Outbox.Dispatched(ctx, e.ID)
+				} else {
+					app.logger.Warnw("delivering outbox event failed", "event", e.ID, "topic", e.Topic, "attempt", e.Attempts, "error", err.Error())
+					delay := time.Duration(e.Attempts*e.Attempts) * time.Minute
+					err = app.store.Outbox.Retry(ctx, e.ID, delay, err.Error())
+				}
+				if err != nil {
+					app.logger.Errorw("updating outbox event failed", "event", e.ID, "error", err.Error())
+				}
+			}
+			cancel()
+		}
+	}()
+}
+
+// deliverPaymentReceived tells the student's parent their payment went
+// through, and what is left of the invoice, by SMS and on Telegram if
+// linked.
+func (app *application) deliverPaymentReceived(ctx context.Context, payload json.RawMessage) error {
+	var event store.InvoicePaidEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	invoice, err := app.store.Invoices.GetByID(ctx, event.InvoiceID)
+	if err != nil {
+		return err
+	}
+	if invoice.ParentPhone == "" {
+		return nil
+	}
+	payments, err := app.store.Invoices.Payments(ctx, invoice.ID)
+	if err != nil {
+		return err
+	}
+	var payment *store.Payment
+	for _, p := range payments {
+		if p.ID == event.PaymentID {
+			payment = p
+		}
+	}
+	if payment == nil {
+		return fmt.Errorf("payment %d not found", event.PaymentID)
+	}
+
+	text := fmt.Sprintf("Payment of %s for %s (%s) received. Reference: %s",
+		app.formatMoney(payment.Amount), invoice.Title, invoice.StudentName, payment.RefID)
+	if left := invoice.Amount - invoice.AmountPaid; left > 0 {
+		text += fmt.Sprintf(". Remaining: %s", app.formatMoney(left))
+	}
+	if event.ReceiptNumber != "" {
+		text += fmt.Sprintf(". Receipt: %s", event.ReceiptNumber)
+	}
+	smsErr := app.sms.Send(ctx, invoice.ParentPhone, text)
+	chats, err := app.notifyTelegram(ctx, []string{invoice.ParentPhone}, text)
+	if err != nil {
+		app.logger.Warnw("payment telegram failed", "invoice", invoice.ID, "error", err.Error())
+	}
+	// retrying would resend the Telegram copy, so only retry when nothing
+	// was delivered
+	if smsErr != nil && chats == 0 && !errors.Is(smsErr, sms.ErrNotConfigured) {
+		return smsErr
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=72" example:"S3cure-Passw0rd"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=72" example:"N3w-Passw0rd!"`
+}
+
+type ResetPasswordPayload struct {
+	Password string `json:"password" validate:"required,min=8,max=72" example:"S3cure-Passw0rd"`
+}
+
+// passwordAllowed checks text against the password policy, and against the
+// recent passwords of the account id unless it is 0 (a new account).
+// Otherwise it writes a 400 naming what is wrong.
+func (app *application) passwordAllowed(w http.ResponseWriter, r *http.Request, id int64, text string) bool {
+	policy := app.dynamic.Load().password
+	if err := policy.Check(text); err != nil {
+		app.badRequestResponse(w, r, err)
+		return false
+	}
+	if id == 0 {
+		return true
+	}
+
+	used, err := app.store.Accounts.PasswordUsed(r.Context(), id, text, policy.History)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return false
+	}
+	if used {
+		app.badRequestResponse(w, r, errors.New("password was used recently; choose another"))
+		return false
+	}
+	return true
+}
+
+// callerAccount loads the account of the signed-in user.
+func (app *application) callerAccount(r *http.Request) (*store.Account, error) {
+	claims := getUser(r)
+	profileType := claims.Role
+	if claims.Role == string(store.RoleAdmin) || claims.Role == string(store.RoleManager) {
+		profileType = store.ProfileExec
+	}
+	return app.store.Accounts.GetByProfile(r.Context(), profileType, claims.ID)
+}
+
+// ChangePassword godoc
+//
+//	@Summary		Change the caller's password
+//	@Description	The new password must follow the school's password policy and not be one of the account's recent ones. Other sessions are signed out; the response carries new tokens for this one, which are no longer limited to changing the password.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ChangePasswordPayload	true	"Current and new password"
+//	@Success		200		{object}	map[string]string		"token and refresh_token"
+//	@Failure		400		{object}	error					"The new password breaks the policy"
+//	@Failure		401		{object}	error					"Wrong current password"
+//	@Security		ApiKeyAuth
+//	@Router			/me/password [put]
+//	@ID				changePassword
+func (app *application) changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ChangePasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	account, err := app.callerAccount(r)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if !account.Password.Check(payload.CurrentPassword) {
+		app.unauthorizedResponse(w, r, errors.New("current password is wrong"))
+		return
+	}
+	if !app.passwordAllowed(w, r, account.ID, payload.NewPassword) {
+		return
+	}
+
+	if err := app.store.Accounts.SetPassword(ctx, account.ID, payload.NewPassword, false); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	claims := getUser(r)
+	_, token, err := app.issueToken(claims.ID, claims.Role, claims.Email, false)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	refreshToken, err := app.issueRefreshToken(ctx, account.ID, false)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := map[string]string{"token": token, "refresh_token": refreshToken}
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ResetPassword godoc
+//
+//	@Summary		Set a temporary password on an account
+//	@Description	For users who lost theirs. The password must follow the school's password policy and not be one of the account's recent ones. The account's sessions are signed out, and it must change the password at its next sign-in.
+//	@Tags			Admin
+//	@Accept			json
+//	@Param			accountID	path	int						true	"Account ID"
+//	@Param			payload		body	ResetPasswordPayload	true	"New password"
+//	@Success		204
+//	@Failure		400	{object}	error	"The password breaks the policy"
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/accounts/{accountID}/password [put]
+//	@ID				resetPassword
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "accountID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var payload ResetPasswordPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	account, err := app.store.Accounts.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	if account.ProfileType == store.ProfileParent {
+		app.badRequestResponse(w, r, errors.New("parents sign in with a code and have no password"))
+		return
+	}
+	if !app.passwordAllowed(w, r, account.ID, payload.Password) {
+		return
+	}
+
+	if err := app.store.Accounts.SetPassword(r.Context(), account.ID, payload.Password, true); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	app.logger.Infow("password reset", "account", account.ID, "by", getUser(r).ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ForcePasswordChangePayload struct {
+	Role string `json:"role" validate:"omitempty,oneof=admin manager teacher student" enums:"admin,manager,teacher,student" example:"admin"`
+}
+
+// ForcePasswordChange godoc
+//
+//	@Summary		Make accounts change their passwords
+//	@Description	Every account with a password, or those of one role, must change it at its next sign-in or token refresh. The caller's own account is left out.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		ForcePasswordChangePayload	true	"Role, or empty for everyone"
+//	@Success		200		{object}	map[string]int64			"count of accounts flagged"
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/accounts/force-password-change [post]
+//	@ID				forcePasswordChange
+func (app *application) forcePasswordChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ForcePasswordChangePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	self, err := app.callerAccount(r)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	n, err := app.store.Accounts.ForcePasswordChange(r.Context(), payload.Role, self.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	app.logger.Infow("password change forced", "role", payload.Role, "accounts", n, "by", self.ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, map[string]int64{"count": n}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// passwordChangeAllowed reports whether a token that must change its
+// password may make request r.
+func passwordChangeAllowed(r *http.Request) bool {
+	return r.Method == http.MethodPut && r.URL.Path == "/v1/me/password"
+}
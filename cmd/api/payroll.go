@@ -0,0 +1,697 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/pdf"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var expenseReceiptUpload = uploadKind{
+	name:    "receipt",
+	maxSize: 5 << 20,
+	types: map[string]bool{
+		"image/jpeg":      true,
+		"image/png":       true,
+		"image/webp":      true,
+		"application/pdf": true,
+	},
+	typesText: "a JPEG, PNG or WebP image or a PDF",
+}
+
+type CreateContractPayload struct {
+	StaffKind  string `json:"staff_kind" validate:"required,oneof=teacher exec" enums:"teacher,exec" example:"teacher"`
+	StaffID    int64  `json:"staff_id" validate:"required,min=1"`
+	Title      string `json:"title" validate:"max=128"`
+	BaseSalary int64  `json:"base_salary" validate:"min=0"`
+	Allowances int64  `json:"allowances" validate:"min=0"`
+	Deductions int64  `json:"deductions" validate:"min=0"`
+	StartsOn   string `json:"starts_on" validate:"required,datetime=2006-01-02" example:"2026-09-23"`
+	EndsOn     string `json:"ends_on" validate:"omitempty,datetime=2006-01-02" example:"2027-01-20"`
+}
+
+type EndContractPayload struct {
+	EndsOn string `json:"ends_on" validate:"required,datetime=2006-01-02" example:"2027-01-20"`
+}
+
+type GeneratePayslipsPayload struct {
+	// Month is the month to pay, as YYYY-MM.
+	Month string `json:"month" validate:"required,datetime=2006-01"`
+}
+
+type GeneratePayslipsResponse struct {
+	Issued   int            `json:"issued"`
+	Payslips []*dto.Payslip `json:"payslips"`
+}
+
+type CreateExpensePayload struct {
+	Amount      int64  `json:"amount" validate:"required,min=1"`
+	Description string `json:"description" validate:"required,max=500"`
+	SpentOn     string `json:"spent_on" validate:"required,datetime=2006-01-02"`
+}
+
+type ReviewExpensePayload struct {
+	Note string `json:"note" validate:"max=500" example:"Doctor's appointment"`
+}
+
+// formatMoney writes an amount with thousands separators and the school's
+// currency.
+func (app *application) formatMoney(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String() + " " + app.config.school.currency
+}
+
+// renderPayslip lays out a payslip as a one-page PDF.
+func (app *application) renderPayslip(p *store.Payslip, expenses []*store.ExpenseClaim) []byte {
+	doc := pdf.New()
+	doc.Title = fmt.Sprintf("Payslip %s - %s", p.Period.Format("2006-01"), p.StaffName)
+	doc.Author = app.config.school.name
+
+	const left, right = 56.0, pdf.PageWidth - 56
+	doc.Text(left, 80, 18, pdf.Bold, app.config.school.name)
+	doc.Text(left, 104, 13, pdf.Regular, "Payslip for "+p.Period.Format("January 2006"))
+	doc.TextRight(right, 80, 10, pdf.Regular, fmt.Sprintf("No. %06d", p.ID))
+	doc.TextRight(right, 96, 10, pdf.Regular, "Issued "+p.CreatedAt.In(app.school.location).Format(time.DateOnly))
+	doc.Line(left, 120, right, 120, 1)
+
+	doc.Text(left, 146, 11, pdf.Bold, p.StaffName)
+	if p.Title != "" {
+		doc.Text(left, 162, 10, pdf.Regular, p.Title)
+	}
+
+	y := 200.0
+	row := func(label string, amount int64, font pdf.Font) {
+		doc.Text(left, y, 11, font, label)
+		doc.TextRight(right, y, 11, font, app.formatMoney(amount))
+		y += 20
+	}
+	row("Base salary", p.BaseSalary, pdf.Regular)
+	row("Allowances", p.Allowances, pdf.Regular)
+	row("Expense reimbursements", p.Reimbursements, pdf.Regular)
+	row("Deductions", -p.Deductions, pdf.Regular)
+	doc.Line(left, y-12, right, y-12, 0.5)
+	y += 4
+	row("Net pay", p.Net, pdf.Bold)
+
+	if len(expenses) > 0 {
+		y += 16
+		doc.Text(left, y, 11, pdf.Bold, "Reimbursed expenses")
+		y += 18
+		for _, e := range expenses {
+			doc.Text(left, y, 9, pdf.Regular, e.SpentOn.Format(time.DateOnly)+"  "+e.Description)
+			doc.TextRight(right, y, 9, pdf.Regular, app.formatMoney(e.Amount))
+			y += 14
+			if y > pdf.PageHeight-80 {
+				doc.AddPage()
+				y = 80
+			}
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// CreateContract godoc
+//
+//	@Summary		Create a staff contract
+//	@Description	Monthly amounts in SCHOOL_CURRENCY. A staff member can have one open (no ends_on) contract at a time.
+//	@Tags			Payroll
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateContractPayload	true	"Contract"
+//	@Success		201		{object}	dto.StaffContract
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Staff member already has an open contract"
+//	@Security		ApiKeyAuth
+//	@Router			/payroll/contracts [post]
+//	@ID				createContract
+func (app *application) createContractHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateContractPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	startsOn, _ := time.Parse(time.DateOnly, payload.StartsOn)
+	contract := &store.StaffContract{
+		StaffKind:  payload.StaffKind,
+		StaffID:    payload.StaffID,
+		Title:      payload.Title,
+		BaseSalary: payload.BaseSalary,
+		Allowances: payload.Allowances,
+		Deductions: payload.Deductions,
+		StartsOn:   startsOn,
+	}
+	if payload.EndsOn != "" {
+		endsOn, _ := time.Parse(time.DateOnly, payload.EndsOn)
+		if endsOn.Before(startsOn) {
+			app.badRequestResponse(w, r, errors.New("ends_on must not be before starts_on"))
+			return
+		}
+		contract.EndsOn = &endsOn
+	}
+
+	if err := app.store.Payroll.CreateContract(r.Context(), contract); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("staff member already has an open contract; end it first"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromStaffContract(contract)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListContracts godoc
+//
+//	@Summary	List staff contracts
+//	@Tags		Payroll
+//	@Produce	json
+//	@Param		current	query	bool	false	"Only contracts that have not ended"
+//	@Success	200		{array}	dto.StaffContract
+//	@Security	ApiKeyAuth
+//	@Router		/payroll/contracts [get]
+//	@ID			listContracts
+func (app *application) listContractsHandler(w http.ResponseWriter, r *http.Request) {
+	current := r.URL.Query().Get("current") == "true"
+
+	contracts, err := app.store.Payroll.Contracts(r.Context(), current)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStaffContracts(contracts)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// EndContract godoc
+//
+//	@Summary	End an open staff contract
+//	@Tags		Payroll
+//	@Accept		json
+//	@Param		contractID	path	int					true	"Contract ID"
+//	@Param		payload		body	EndContractPayload	true	"Last day"
+//	@Success	204
+//	@Failure	400	{object}	error
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/payroll/contracts/{contractID}/end [post]
+//	@ID			endContract
+func (app *application) endContractHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "contractID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload EndContractPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	endsOn, _ := time.Parse(time.DateOnly, payload.EndsOn)
+
+	if err := app.store.Payroll.EndContract(r.Context(), id, endsOn); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GeneratePayslips godoc
+//
+//	@Summary		Issue a month's payslips
+//	@Description	Creates a payslip for every contract running during the month that has none yet, including the staff member's approved, unpaid expense claims. Safe to repeat.
+//	@Tags			Payroll
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		GeneratePayslipsPayload	true	"Month"
+//	@Success		200		{object}	GeneratePayslipsResponse
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/payroll/payslips/generate [post]
+//	@ID				generatePayslips
+func (app *application) generatePayslipsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload GeneratePayslipsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	period, _ := time.Parse("2006-01", payload.Month)
+
+	issued, err := app.store.Payroll.GeneratePayslips(r.Context(), period)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	payslips, err := app.store.Payroll.Payslips(r.Context(), store.PayrollFilter{Period: period})
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, GeneratePayslipsResponse{Issued: issued, Payslips: dto.FromPayslips(payslips)}); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListPayslips godoc
+//
+//	@Summary		List payslips
+//	@Description	Execs see everyone's payslips, optionally for one month or staff member; on /me/payslips staff see their own.
+//	@Tags			Payroll
+//	@Produce		json
+//	@Param			month		query		string	false	"YYYY-MM"
+//	@Param			staff_kind	query		string	false	"teacher or exec"
+//	@Param			staff_id	query		int		false	"Staff ID"
+//	@Success		200			{array}		dto.Payslip
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/payroll/payslips [get]
+//	@ID				listPayslips
+func (app *application) listPayslipsHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := payrollFilter(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payslips, err := app.store.Payroll.Payslips(r.Context(), filter)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPayslips(payslips)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetPayslipPDF godoc
+//
+//	@Summary		Download a payslip as PDF
+//	@Description	Staff can download their own payslips; admins any.
+//	@Tags			Payroll
+//	@Produce		application/pdf
+//	@Param			payslipID	path	int	true	"Payslip ID"
+//	@Success		200
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/me/payslips/{payslipID}/pdf [get]
+//	@ID				getPayslipPDF
+func (app *application) getPayslipPDFHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "payslipID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payslip, err := app.store.Payroll.Payslip(r.Context(), id)
+	if err == nil && getUser(r).Role != "admin" && !isPayrollSubject(r, payslip.StaffKind, payslip.StaffID) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	expenses, err := app.store.Payroll.PayslipExpenses(r.Context(), payslip.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="payslip-%s-%d.pdf"`, payslip.Period.Format("2006-01"), payslip.ID))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(app.renderPayslip(payslip, expenses))
+}
+
+// ListMyPayslips godoc
+//
+//	@Summary	List the caller's payslips
+//	@Tags		Payroll
+//	@Produce	json
+//	@Success	200	{array}	dto.Payslip
+//	@Security	ApiKeyAuth
+//	@Router		/me/payslips [get]
+//	@ID			listMyPayslips
+func (app *application) listMyPayslipsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUser(r)
+
+	payslips, err := app.store.Payroll.Payslips(r.Context(), store.PayrollFilter{StaffKind: accountKind(user.Role), StaffID: user.ID})
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPayslips(payslips)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreateExpense godoc
+//
+//	@Summary		Submit an expense claim
+//	@Description	The claim stays pending until an exec reviews it; attach the receipt with PUT /me/expenses/{expenseID}/receipt. Approved claims are paid with the next payslip.
+//	@Tags			Payroll
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateExpensePayload	true	"Claim"
+//	@Success		201		{object}	dto.ExpenseClaim
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/me/expenses [post]
+//	@ID				createExpense
+func (app *application) createExpenseHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateExpensePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	spentOn, _ := time.Parse(time.DateOnly, payload.SpentOn)
+	if spentOn.After(app.schoolToday()) {
+		app.badRequestResponse(w, r, errors.New("spent_on must not be in the future"))
+		return
+	}
+
+	user := getUser(r)
+	claim := &store.ExpenseClaim{
+		StaffKind:   accountKind(user.Role),
+		StaffID:     user.ID,
+		Amount:      payload.Amount,
+		Description: payload.Description,
+		SpentOn:     spentOn,
+	}
+	if err := app.store.Payroll.CreateExpense(r.Context(), claim); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromExpenseClaim(claim)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListMyExpenses godoc
+//
+//	@Summary	List the caller's expense claims
+//	@Tags		Payroll
+//	@Produce	json
+//	@Success	200	{array}	dto.ExpenseClaim
+//	@Security	ApiKeyAuth
+//	@Router		/me/expenses [get]
+//	@ID			listMyExpenses
+func (app *application) listMyExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUser(r)
+
+	claims, err := app.store.Payroll.Expenses(r.Context(), store.PayrollFilter{StaffKind: accountKind(user.Role), StaffID: user.ID})
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromExpenseClaims(claims)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PutExpenseReceipt godoc
+//
+//	@Summary		Attach a receipt to an expense claim
+//	@Description	Send the file as the request body (JPEG, PNG, WebP or PDF, at most 5 MB). Only while the claim is pending.
+//	@Tags			Payroll
+//	@Accept			image/jpeg,image/png,image/webp,application/pdf
+//	@Param			expenseID	path	int	true	"Expense claim ID"
+//	@Success		204			"No Content"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		422			{object}	error	"Refused as unsafe and quarantined"
+//	@Security		ApiKeyAuth
+//	@Router			/me/expenses/{expenseID}/receipt [put]
+//	@ID				putExpenseReceipt
+func (app *application) putExpenseReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	claim, ok := app.expenseForCaller(w, r, false)
+	if !ok {
+		return
+	}
+
+	data, contentType, ok := app.readUpload(w, r, expenseReceiptUpload)
+	if !ok {
+		return
+	}
+
+	if err := app.store.Payroll.SetExpenseReceipt(r.Context(), claim.ID, data, contentType); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.conflictResponse(w, r, errors.New("claim has already been reviewed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetExpenseReceipt godoc
+//
+//	@Summary		Get an expense claim's receipt
+//	@Description	Available to the claimant and to execs.
+//	@Tags			Payroll
+//	@Produce		image/jpeg,image/png,image/webp,application/pdf
+//	@Param			expenseID	path	int	true	"Expense claim ID"
+//	@Success		200
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/me/expenses/{expenseID}/receipt [get]
+//	@ID				getExpenseReceipt
+func (app *application) getExpenseReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	claim, ok := app.expenseForCaller(w, r, true)
+	if !ok {
+		return
+	}
+
+	data, contentType, err := app.store.Payroll.ExpenseReceipt(r.Context(), claim.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(data)
+}
+
+// ListExpenses godoc
+//
+//	@Summary	List expense claims
+//	@Tags		Payroll
+//	@Produce	json
+//	@Param		status		query		string	false	"pending, approved or rejected"
+//	@Param		staff_kind	query		string	false	"teacher or exec"
+//	@Param		staff_id	query		int		false	"Staff ID"
+//	@Success	200			{array}		dto.ExpenseClaim
+//	@Failure	400			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/payroll/expenses [get]
+//	@ID			listExpenses
+func (app *application) listExpensesHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := payrollFilter(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	claims, err := app.store.Payroll.Expenses(r.Context(), filter)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromExpenseClaims(claims)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// reviewExpenseHandler approves or rejects a pending claim. Execs cannot
+// review their own claims.
+func (app *application) reviewExpenseHandler(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "expenseID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		var payload ReviewExpensePayload
+		if r.ContentLength != 0 {
+			if err := readJSON(w, r, &payload); err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+		}
+		if err := Validate.Struct(payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		claim, err := app.store.Payroll.Expense(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		if isPayrollSubject(r, claim.StaffKind, claim.StaffID) {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		if err := app.store.Payroll.ReviewExpense(r.Context(), claim, status, getUser(r).ID, payload.Note); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			case errors.Is(err, store.ErrConflict):
+				app.conflictResponse(w, r, errors.New("claim has already been reviewed"))
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusOK, dto.FromExpenseClaim(claim)); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// expenseForCaller loads the claim in the URL if it is the caller's own or,
+// with execs set, the caller is an exec. It writes the error response and
+// returns false otherwise.
+func (app *application) expenseForCaller(w http.ResponseWriter, r *http.Request, execs bool) (*store.ExpenseClaim, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "expenseID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	claim, err := app.store.Payroll.Expense(r.Context(), id)
+	if err == nil && !isPayrollSubject(r, claim.StaffKind, claim.StaffID) &&
+		!(execs && accountKind(getUser(r).Role) == "exec") {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return claim, true
+}
+
+// isPayrollSubject reports whether a payroll record is about the caller.
+func isPayrollSubject(r *http.Request, staffKind string, staffID int64) bool {
+	user := getUser(r)
+	return accountKind(user.Role) == staffKind && user.ID == staffID
+}
+
+// payrollFilter reads the month, staff_kind, staff_id and status query
+// parameters.
+func payrollFilter(r *http.Request) (store.PayrollFilter, error) {
+	q := r.URL.Query()
+	var f store.PayrollFilter
+
+	if v := q.Get("month"); v != "" {
+		period, err := time.Parse("2006-01", v)
+		if err != nil {
+			return f, fmt.Errorf("invalid month %q; expected YYYY-MM", v)
+		}
+		f.Period = period
+	}
+	if v := q.Get("staff_kind"); v != "" {
+		if v != "teacher" && v != "exec" {
+			return f, errors.New("staff_kind must be teacher or exec")
+		}
+		f.StaffKind = v
+	}
+	if v := q.Get("staff_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			return f, fmt.Errorf("invalid staff_id %q", v)
+		}
+		f.StaffID = id
+	}
+	if v := q.Get("status"); v != "" {
+		if v != store.ExpensePending && v != store.ExpenseApproved && v != store.ExpenseRejected {
+			return f, errors.New("status must be pending, approved or rejected")
+		}
+		f.Status = v
+	}
+	return f, nil
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/imaging"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var pickupPhotoUpload = uploadKind{
+	name:      "photo",
+	maxSize:   2 << 20,
+	types:     map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true},
+	typesText: "a JPEG, PNG or WebP image",
+}
+
+// pickupPhotoSizes are the ?size= values a contact photo is served in,
+// besides the original.
+var pickupPhotoSizes = map[string]imaging.Size{
+	imaging.Thumbnail.Name: imaging.Thumbnail,
+	imaging.Medium.Name:    imaging.Medium,
+}
+
+type PickupContactPayload struct {
+	Name        string `json:"name" validate:"required,max=128"`
+	Relation    string `json:"relation" validate:"required,max=64"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,e164" example:"+989121234567"`
+}
+
+type UpdatePickupContactPayload struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,max=128"`
+	Relation    *string `json:"relation,omitempty" validate:"omitempty,max=64"`
+	PhoneNumber *string `json:"phone_number,omitempty" validate:"omitempty,e164" example:"+989121234567"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+// GateCheckPayload identifies the student by ID or code and the person
+// collecting them by contact ID or phone number.
+type GateCheckPayload struct {
+	StudentID   int64  `json:"student_id,omitempty" validate:"required_without=StudentCode" example:"42"`
+	StudentCode string `json:"student_code,omitempty" validate:"required_without=StudentID,max=16" example:"S-1405-0042"`
+	ContactID   int64  `json:"contact_id,omitempty" validate:"required_without=PhoneNumber"`
+	PhoneNumber string `json:"phone_number,omitempty" validate:"required_without=ContactID,omitempty,e164" example:"+989121234567"`
+	Note        string `json:"note,omitempty" validate:"max=500" example:"Doctor's appointment"`
+}
+
+type GateCheckStudent struct {
+	ID          int64  `json:"id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	StudentCode string `json:"student_code"`
+	ClassroomID int64  `json:"classroom_id"`
+}
+
+// GateCheckResult tells staff whether to release the student. When the
+// person is not authorized it lists who is, so staff can compare photos.
+type GateCheckResult struct {
+	Authorized bool                 `json:"authorized"`
+	Student    GateCheckStudent     `json:"student"`
+	Contact    *dto.PickupContact   `json:"contact,omitempty"`
+	Allowed    []*dto.PickupContact `json:"allowed,omitempty"`
+	Checkout   *dto.PickupCheckout  `json:"checkout"`
+}
+
+func pickupContactID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "contactID"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid contact ID")
+	}
+	return id, nil
+}
+
+// GetPickupContacts godoc
+//
+//	@Summary	List the people authorized to pick a student up
+//	@Tags		Pickups
+//	@Produce	json
+//	@Param		studentID	path	int	true	"Student ID"
+//	@Success	200			{array}	dto.PickupContact
+//	@Security	ApiKeyAuth
+//	@Router		/students/{studentID}/pickups [get]
+//	@ID			getPickupContacts
+func (app *application) getPickupContactsHandler(w http.ResponseWriter, r *http.Request) {
+	contacts, err := app.store.Pickups.ForStudent(r.Context(), getStudentFromCtx(r).ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPickupContacts(contacts)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreatePickupContact godoc
+//
+//	@Summary	Authorize a person to pick a student up
+//	@Tags		Pickups
+//	@Accept		json
+//	@Produce	json
+//	@Param		studentID	path		int						true	"Student ID"
+//	@Param		payload		body		PickupContactPayload	true	"Contact"
+//	@Success	201			{object}	dto.PickupContact
+//	@Failure	400			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{studentID}/pickups [post]
+//	@ID			createPickupContact
+func (app *application) createPickupContactHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PickupContactPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	contact := &store.PickupContact{
+		StudentID:   getStudentFromCtx(r).ID,
+		Name:        payload.Name,
+		Relation:    payload.Relation,
+		PhoneNumber: payload.PhoneNumber,
+	}
+	if err := app.store.Pickups.Create(r.Context(), contact); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromPickupContact(contact)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdatePickupContact godoc
+//
+//	@Summary		Update or deactivate a pickup contact
+//	@Description	Set active to false to withdraw authorization while keeping the contact on file.
+//	@Tags			Pickups
+//	@Accept			json
+//	@Produce		json
+//	@Param			studentID	path		int							true	"Student ID"
+//	@Param			contactID	path		int							true	"Contact ID"
+//	@Param			payload		body		UpdatePickupContactPayload	true	"Changes"
+//	@Success		200			{object}	dto.PickupContact
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID}/pickups/{contactID} [patch]
+//	@ID				updatePickupContact
+func (app *application) updatePickupContactHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pickupContactID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload UpdatePickupContactPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	contact, err := app.store.Pickups.Get(r.Context(), getStudentFromCtx(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if payload.Name != nil {
+		contact.Name = *payload.Name
+	}
+	if payload.Relation != nil {
+		contact.Relation = *payload.Relation
+	}
+	if payload.PhoneNumber != nil {
+		contact.PhoneNumber = *payload.PhoneNumber
+	}
+	if payload.Active != nil {
+		contact.Active = *payload.Active
+	}
+
+	if err := app.store.Pickups.Update(r.Context(), contact); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPickupContact(contact)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeletePickupContact godoc
+//
+//	@Summary	Remove a pickup contact
+//	@Tags		Pickups
+//	@Param		studentID	path	int	true	"Student ID"
+//	@Param		contactID	path	int	true	"Contact ID"
+//	@Success	204			"No Content"
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{studentID}/pickups/{contactID} [delete]
+//	@ID			deletePickupContact
+func (app *application) deletePickupContactHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pickupContactID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Pickups.Delete(r.Context(), getStudentFromCtx(r).ID, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutPickupContactPhoto godoc
+//
+//	@Summary		Upload a pickup contact's photo
+//	@Description	Send the image as the request body (JPEG, PNG or WebP, at most 2 MB). Its metadata (EXIF location, camera, time taken) is removed, and JPEG and PNG photos are also scaled to the thumbnail and medium sizes.
+//	@Tags			Pickups
+//	@Accept			image/jpeg,image/png,image/webp
+//	@Param			studentID	path	int	true	"Student ID"
+//	@Param			contactID	path	int	true	"Contact ID"
+//	@Success		204			"No Content"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		422			{object}	error	"Refused as unsafe and quarantined"
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID}/pickups/{contactID}/photo [put]
+//	@ID				putPickupContactPhoto
+func (app *application) putPickupContactPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pickupContactID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	data, contentType, ok := app.readUpload(w, r, pickupPhotoUpload)
+	if !ok {
+		return
+	}
+	data, err = imaging.Strip(data, contentType)
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("photo is not a valid image"))
+		return
+	}
+
+	if err := app.store.Pickups.SetPhoto(r.Context(), getStudentFromCtx(r).ID, id, data, contentType); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// A size that fails here is made again when it is first asked for.
+	for _, size := range imaging.Sizes {
+		if _, _, err := app.pickupPhotoVariant(r.Context(), id, size, data, contentType); err != nil &&
+			!errors.Is(err, imaging.ErrUnsupported) {
+			app.logger.Warnw("scaling pickup photo failed", "contact", id, "size", size.Name, "error", err.Error())
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pickupPhotoVariant scales a contact's photo to size and stores the copy.
+func (app *application) pickupPhotoVariant(ctx context.Context, id int64, size imaging.Size, data []byte, contentType string) ([]byte, string, error) {
+	scaled, scaledType, err := imaging.Resize(data, contentType, size.MaxEdge)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := app.store.Pickups.SetPhotoVariant(ctx, id, size.Name, scaled, scaledType); err != nil {
+		return nil, "", err
+	}
+	return scaled, scaledType, nil
+}
+
+// GetPickupContactPhoto godoc
+//
+//	@Summary		Get a pickup contact's photo
+//	@Description	size=thumbnail (128 px) or size=medium (512 px) return a scaled copy, bounding the longer side; WebP photos are only served as uploaded.
+//	@Tags			Pickups
+//	@Produce		image/jpeg,image/png,image/webp
+//	@Param			contactID	path	int		true	"Contact ID"
+//	@Param			size		query	string	false	"Scaled copy"	Enums(thumbnail, medium, original)
+//	@Success		200
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pickups/contacts/{contactID}/photo [get]
+//	@ID				getPickupContactPhoto
+func (app *application) getPickupContactPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := pickupContactID(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	size := r.URL.Query().Get("size")
+	if _, ok := pickupPhotoSizes[size]; !ok && size != "" && size != "original" {
+		app.badRequestResponse(w, r, fmt.Errorf("unknown size %q", size))
+		return
+	}
+
+	data, contentType, err := app.pickupPhoto(r.Context(), id, size)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(data)
+}
+
+// pickupPhoto returns a contact's photo in the named size, scaling and
+// storing the copy the first time it is asked for. Photos that can't be
+// scaled are returned as uploaded.
+func (app *application) pickupPhoto(ctx context.Context, id int64, sizeName string) ([]byte, string, error) {
+	size, scaled := pickupPhotoSizes[sizeName]
+	if scaled {
+		data, contentType, err := app.store.Pickups.PhotoVariant(ctx, id, size.Name)
+		if !errors.Is(err, store.ErrNotFound) {
+			return data, contentType, err
+		}
+	}
+
+	data, contentType, err := app.store.Pickups.Photo(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if scaled {
+		scaledData, scaledType, err := app.pickupPhotoVariant(ctx, id, size, data, contentType)
+		switch {
+		case err == nil:
+			return scaledData, scaledType, nil
+		case !errors.Is(err, imaging.ErrUnsupported):
+			app.logger.Warnw("scaling pickup photo failed", "contact", id, "size", size.Name, "error", err.Error())
+		}
+	}
+
+	// Photos stored before uploads were stripped lose their metadata here.
+	if stripped, err := imaging.Strip(data, contentType); err == nil {
+		data = stripped
+	}
+	return data, contentType, nil
+}
+
+// GateCheck godoc
+//
+//	@Summary		Verify a pickup at the gate
+//	@Description	Checks whether the person collecting a student is an active pickup contact and logs the checkout either way.
+//	@Tags			Pickups
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		GateCheckPayload	true	"Student and person"
+//	@Success		200		{object}	GateCheckResult
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/pickups/check [post]
+//	@ID				gateCheck
+func (app *application) gateCheckHandler(w http.ResponseWriter, r *http.Request) {
+	var payload GateCheckPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	var (
+		student *store.Student
+		err     error
+	)
+	if payload.StudentID != 0 {
+		student, err = app.store.Students.GetByID(ctx, payload.StudentID)
+	} else {
+		student, err = app.store.Students.GetByCode(ctx, payload.StudentCode)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	contacts, err := app.store.Pickups.ForStudent(ctx, student.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	result := GateCheckResult{
+		Student: GateCheckStudent{
+			ID:          student.ID,
+			FirstName:   student.FirstName,
+			LastName:    student.LastName,
+			StudentCode: student.StudentCode,
+			ClassroomID: student.ClassRoomID,
+		},
+		Allowed: []*dto.PickupContact{},
+	}
+	for _, c := range contacts {
+		if !c.Active {
+			continue
+		}
+		result.Allowed = append(result.Allowed, dto.FromPickupContact(c))
+		if (payload.ContactID != 0 && c.ID == payload.ContactID) ||
+			(payload.ContactID == 0 && c.PhoneNumber != "" && c.PhoneNumber == payload.PhoneNumber) {
+			result.Contact = dto.FromPickupContact(c)
+		}
+	}
+	result.Authorized = result.Contact != nil
+
+	user := getUser(r)
+	checkout := &store.PickupCheckout{
+		StudentID:     student.ID,
+		Authorized:    result.Authorized,
+		Note:          payload.Note,
+		CheckedBy:     user.ID,
+		CheckedByRole: user.Role,
+	}
+	if result.Authorized {
+		checkout.ContactID = &result.Contact.ID
+		checkout.ContactName = result.Contact.Name
+		result.Allowed = nil
+	} else if payload.PhoneNumber != "" {
+		checkout.ContactName = payload.PhoneNumber
+	}
+	if err := app.store.Pickups.LogCheckout(ctx, checkout); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	result.Checkout = dto.FromPickupCheckout(checkout)
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetPickupCheckouts godoc
+//
+//	@Summary	List logged gate checks
+//	@Tags		Pickups
+//	@Produce	json
+//	@Param		student_id	query		int		false	"Only this student"
+//	@Param		from		query		string	false	"Start date (YYYY-MM-DD), defaults to 7 days before to"
+//	@Param		to			query		string	false	"End date (YYYY-MM-DD), defaults to today"
+//	@Success	200			{array}		dto.PickupCheckout
+//	@Failure	400			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/pickups/checkouts [get]
+//	@ID			getPickupCheckouts
+func (app *application) getPickupCheckoutsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 7)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var studentID int64
+	if v := r.URL.Query().Get("student_id"); v != "" {
+		studentID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid student_id"))
+			return
+		}
+	}
+
+	checkouts, err := app.store.Pickups.Checkouts(r.Context(), studentID, from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPickupCheckouts(checkouts)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
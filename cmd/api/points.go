@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type PointCategoryPayload struct {
+	Name   string `json:"name" validate:"required,max=64"`
+	Points int    `json:"points" validate:"required,min=-100,max=100"`
+}
+
+type UpdatePointCategoryPayload struct {
+	Name   *string `json:"name,omitempty" validate:"omitempty,max=64"`
+	Points *int    `json:"points,omitempty" validate:"omitempty,min=-100,max=100,ne=0"`
+	Active *bool   `json:"active,omitempty"`
+}
+
+type AwardPointsPayload struct {
+	StudentID  int64 `json:"student_id" validate:"required" example:"42"`
+	CategoryID int64 `json:"category_id" validate:"required"`
+	// Points overrides the category's default value.
+	Points *int   `json:"points,omitempty" validate:"omitempty,min=-100,max=100,ne=0"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// StudentPoints is a student's point history with its total.
+type StudentPoints struct {
+	StudentID int64             `json:"student_id"`
+	Total     int64             `json:"total"`
+	Awards    []*dto.PointAward `json:"awards"`
+}
+
+// teachesStudent reports whether a teacher is the student's teacher or
+// teaches their classroom.
+func (app *application) teachesStudent(ctx context.Context, teacherID int64, student *store.Student) (bool, error) {
+	if student.TeacherID == teacherID {
+		return true, nil
+	}
+	classroom, err := app.store.Classrooms.GetByID(ctx, student.ClassRoomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return classroom.TeacherID == teacherID, nil
+}
+
+// ListPointCategories godoc
+//
+//	@Summary	List behavior point categories
+//	@Tags		Points
+//	@Produce	json
+//	@Param		all	query	bool	false	"Include inactive categories"
+//	@Success	200	{array}	dto.PointCategory
+//	@Security	ApiKeyAuth
+//	@Router		/points/categories [get]
+//	@ID			listPointCategories
+func (app *application) listPointCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+
+	categories, err := app.store.Points.ListCategories(r.Context(), all)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPointCategories(categories)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CreatePointCategory godoc
+//
+//	@Summary	Create a behavior point category
+//	@Tags		Points
+//	@Accept		json
+//	@Produce	json
+//	@Param		payload	body		PointCategoryPayload	true	"Category"
+//	@Success	201		{object}	dto.PointCategory
+//	@Failure	400		{object}	error
+//	@Failure	409		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/points/categories [post]
+//	@ID			createPointCategory
+func (app *application) createPointCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PointCategoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &store.PointCategory{Name: payload.Name, Points: payload.Points}
+	if err := app.store.Points.CreateCategory(r.Context(), category); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("a category named %q already exists", payload.Name))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromPointCategory(category)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdatePointCategory godoc
+//
+//	@Summary		Update or deactivate a behavior point category
+//	@Description	Categories cannot be deleted; set active to false to retire one.
+//	@Tags			Points
+//	@Accept			json
+//	@Produce		json
+//	@Param			categoryID	path		int							true	"Category ID"
+//	@Param			payload		body		UpdatePointCategoryPayload	true	"Changes"
+//	@Success		200			{object}	dto.PointCategory
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/points/categories/{categoryID} [patch]
+//	@ID				updatePointCategory
+func (app *application) updatePointCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "categoryID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload UpdatePointCategoryPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category, err := app.store.Points.GetCategory(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if payload.Name != nil {
+		category.Name = *payload.Name
+	}
+	if payload.Points != nil {
+		category.Points = *payload.Points
+	}
+	if payload.Active != nil {
+		category.Active = *payload.Active
+	}
+
+	if err := app.store.Points.UpdateCategory(r.Context(), category); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("a category named %q already exists", category.Name))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromPointCategory(category)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// AwardPoints godoc
+//
+//	@Summary		Award or deduct behavior points
+//	@Description	Teachers can only award points to their own students. Points defaults to the category's value.
+//	@Tags			Points
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		AwardPointsPayload	true	"Award"
+//	@Success		201		{object}	dto.PointAward
+//	@Failure		400		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/points [post]
+//	@ID				awardPoints
+func (app *application) awardPointsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload AwardPointsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	user := getUser(r)
+
+	student, err := app.store.Students.GetByID(ctx, payload.StudentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	if user.Role == "teacher" {
+		ok, err := app.teachesStudent(ctx, user.ID, student)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if !ok {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	category, err := app.store.Points.GetCategory(ctx, payload.CategoryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	award := &store.PointAward{
+		StudentID:     student.ID,
+		CategoryID:    category.ID,
+		Points:        category.Points,
+		Reason:        payload.Reason,
+		AwardedBy:     user.ID,
+		AwardedByRole: user.Role,
+	}
+	if payload.Points != nil {
+		award.Points = *payload.Points
+	}
+
+	if err := app.store.Points.Award(ctx, award); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("category %q is inactive", category.Name))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromPointAward(award)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetStudentPoints godoc
+//
+//	@Summary		Get a student's behavior points
+//	@Description	Teachers see their own students; students see themselves.
+//	@Tags			Points
+//	@Produce		json
+//	@Param			studentID	path		int		true	"Student ID"
+//	@Param			from		query		string	false	"Start date (YYYY-MM-DD), defaults to 30 days before to"
+//	@Param			to			query		string	false	"End date (YYYY-MM-DD), defaults to today"
+//	@Success		200			{object}	StudentPoints
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/points/students/{studentID} [get]
+//	@ID				getStudentPoints
+func (app *application) getStudentPointsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	student := getStudentFromCtx(r)
+	user := getUser(r)
+
+	switch user.Role {
+	case "student":
+		if user.ID != student.ID {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	case "teacher":
+		ok, err := app.teachesStudent(ctx, user.ID, student)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if !ok {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	awards, err := app.store.Points.ForStudent(ctx, student.ID, from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := StudentPoints{StudentID: student.ID, Awards: dto.FromPointAwards(awards)}
+	for _, a := range awards {
+		resp.Total += int64(a.Points)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetClassroomLeaderboard godoc
+//
+//	@Summary	Rank a classroom's students by behavior points
+//	@Tags		Points
+//	@Produce	json
+//	@Param		classroomID	path		int		true	"Classroom ID"
+//	@Param		from		query		string	false	"Start date (YYYY-MM-DD), defaults to 30 days before to"
+//	@Param		to			query		string	false	"End date (YYYY-MM-DD), defaults to today"
+//	@Success	200			{array}		dto.LeaderboardEntry
+//	@Failure	400			{object}	error
+//	@Failure	403			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/points/classrooms/{classroomID}/leaderboard [get]
+//	@ID			getClassroomLeaderboard
+func (app *application) getClassroomLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if user := getUser(r); user.Role == "teacher" && classroom.TeacherID != user.ID {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := app.store.Points.Leaderboard(r.Context(), classroom.ID, from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLeaderboardEntries(entries)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// startPointSummaries texts each parent a summary of their child's points
+// for the past week, once a week on the configured day after the configured
+// time.
+func (app *application) startPointSummaries() {
+	if !app.config.points.summaryEnabled {
+		return
+	}
+	if app.dynamic.Load().sms.APIKey == "" {
+		app.logger.Warn("point summaries disabled: SMS is not configured")
+		return
+	}
+
+	day, ok := weekdayNames[app.config.points.summaryDay]
+	if !ok {
+		app.logger.Errorw("point summaries disabled", "error", fmt.Sprintf("invalid day %q", app.config.points.summaryDay))
+		return
+	}
+	at, err := parseClock(app.config.points.summaryTime)
+	if err != nil {
+		app.logger.Errorw("point summaries disabled", "error", err.Error())
+		return
+	}
+
+	var lastRun string
+	ticker := time.NewTicker(attendanceReminderTick)
+	go func() {
+		for range ticker.C {
+			now := app.school.now()
+			today := now.Format(time.DateOnly)
+			midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			if now.Weekday() != day || today == lastRun || now.Sub(midnight) < at {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			sent, err := app.sendPointSummaries(ctx, midnight)
+			cancel()
+			if err != nil {
+				app.logger.Errorw("sending point summaries failed", "error", err.Error())
+				continue // retried on the next tick; sent ones are claimed
+			}
+			lastRun = today
+			app.logger.Infow("point summaries sent", "week", today, "count", sent)
+		}
+	}()
+}
+
+// sendPointSummaries texts the parents of students with awards in the seven
+// days ending on week.
+func (app *application) sendPointSummaries(ctx context.Context, week time.Time) (int, error) {
+	summaries, err := app.store.Points.Summaries(ctx, week.AddDate(0, 0, -6), week)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, s := range summaries {
+		claimed, err := app.store.Points.ClaimSummary(ctx, s.StudentID, week)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		text := fmt.Sprintf("Dear %s, this week %s earned %d behavior points and lost %d (net %+d).",
+			s.ParentName, s.StudentName, s.Earned, s.Deducted, s.Earned-s.Deducted)
+		if err := app.sms.Send(ctx, s.ParentPhone, text); err != nil {
+			app.logger.Warnw("point summary sms failed", "student", s.StudentID, "error", err.Error())
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// rateLimitTopDefault is how many clients GET /admin/ratelimit/top lists
+// when not told.
+const rateLimitTopDefault = 20
+
+// GetRateLimitTop godoc
+//
+//	@Summary		Show the clients the rate limiter turns away most
+//	@Description	Requests allowed and rejected since this instance started, and the client addresses rejected in the last hour with their counts. Many addresses rejected a little each suggest an attack; one address rejected steadily, a misconfigured client. Each instance counts its own traffic.
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			limit	query		int	false	"How many clients to list (default 20, at most 500)"
+//	@Success		200		{object}	ratelimiter.Stats
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/ratelimit/top [get]
+//	@ID				getRateLimitTop
+func (app *application) getRateLimitTopHandler(w http.ResponseWriter, r *http.Request) {
+	limit := rateLimitTopDefault
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			app.badRequestResponse(w, r, fmt.Errorf("limit must be between 1 and 500"))
+			return
+		}
+		limit = n
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, app.ratelimiter.Stats(limit)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// metricsHandler serves counters in the Prometheus text format. It sits
+// behind basic auth (AUTH_BASIC / AUTH_BASIC_PASS) and the admin network
+// allowlist rather than a token, so scrapers can reach it.
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := app.ratelimiter.Stats(0)
+	enabled := 0
+	if app.dynamic.Load().ratelimiter.Enabled {
+		enabled = 1
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, "# HELP classnama_ratelimit_enabled Whether the rate limiter rejects requests.\n")
+	fmt.Fprint(w, "# TYPE classnama_ratelimit_enabled gauge\n")
+	fmt.Fprintf(w, "classnama_ratelimit_enabled %d\n", enabled)
+	fmt.Fprint(w, "# HELP classnama_ratelimit_requests_total Requests checked by the rate limiter, by outcome.\n")
+	fmt.Fprint(w, "# TYPE classnama_ratelimit_requests_total counter\n")
+	fmt.Fprintf(w, "classnama_ratelimit_requests_total{outcome=\"allowed\"} %d\n", stats.Allowed)
+	fmt.Fprintf(w, "classnama_ratelimit_requests_total{outcome=\"rejected\"} %d\n", stats.Rejected)
+	fmt.Fprint(w, "# HELP classnama_ratelimit_rejected_clients Client addresses rejected in the last hour.\n")
+	fmt.Fprint(w, "# TYPE classnama_ratelimit_rejected_clients gauge\n")
+	fmt.Fprintf(w, "classnama_ratelimit_rejected_clients %d\n", stats.Keys)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/pdf"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var receiptPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,12}$`)
+
+// ReceiptVerification is what anyone holding a receipt can confirm about
+// it.
+type ReceiptVerification struct {
+	Valid        bool      `json:"valid"`
+	Number       string    `json:"number"`
+	School       string    `json:"school"`
+	StudentName  string    `json:"student_name"`
+	InvoiceTitle string    `json:"invoice_title"`
+	Term         string    `json:"term"`
+	Amount       int64     `json:"amount"`
+	Currency     string    `json:"currency"`
+	RefID        string    `json:"ref_id"`
+	PaidAt       time.Time `json:"paid_at"`
+}
+
+// receiptVerifyURL is the link printed on a receipt to check it.
+func (app *application) receiptVerifyURL(rc *store.Receipt) string {
+	return fmt.Sprintf("%s/v1/receipts/%s/verify?%s", strings.TrimSuffix(app.config.publicURL, "/"),
+		url.PathEscape(rc.Number), url.Values{"code": {rc.Code}}.Encode())
+}
+
+// renderReceipt lays out a payment receipt as a one-page PDF.
+func (app *application) renderReceipt(rc *store.Receipt) []byte {
+	doc := pdf.New()
+	doc.Title = "Receipt " + rc.Number
+	doc.Author = app.config.school.name
+
+	const left, right = 56.0, pdf.PageWidth - 56
+	doc.Text(left, 80, 18, pdf.Bold, app.config.school.name)
+	doc.Text(left, 104, 13, pdf.Regular, "Payment receipt")
+	doc.TextRight(right, 80, 10, pdf.Regular, "No. "+rc.Number)
+	doc.TextRight(right, 96, 10, pdf.Regular, "Issued "+rc.IssuedAt.In(app.school.location).Format(time.DateOnly))
+	doc.Line(left, 120, right, 120, 1)
+
+	doc.Text(left, 146, 11, pdf.Bold, rc.StudentName)
+	title := rc.InvoiceTitle
+	if rc.Term != "" {
+		title += " (" + rc.Term + ")"
+	}
+	if rc.InstallmentSeq != nil {
+		title += fmt.Sprintf(", installment %d of %d", *rc.InstallmentSeq, rc.Installments)
+	}
+	doc.Text(left, 162, 10, pdf.Regular, title)
+
+	y := 200.0
+	row := func(label, value string, font pdf.Font) {
+		doc.Text(left, y, 11, font, label)
+		doc.TextRight(right, y, 11, font, value)
+		y += 20
+	}
+	row("Paid on", rc.PaidAt.In(app.school.location).Format("2006-01-02 15:04"), pdf.Regular)
+	row("Gateway reference", rc.RefID, pdf.Regular)
+	if rc.CardPAN != "" {
+		row("Card", rc.CardPAN, pdf.Regular)
+	}
+	doc.Line(left, y-12, right, y-12, 0.5)
+	y += 4
+	row("Amount paid", app.formatMoney(rc.Amount), pdf.Bold)
+
+	y += 24
+	doc.Text(left, y, 9, pdf.Regular, "Verification code: "+rc.Code)
+	doc.Text(left, y+14, 9, pdf.Regular, "Check this receipt at "+app.receiptVerifyURL(rc))
+
+	return doc.Bytes()
+}
+
+// VerifyReceipt godoc
+//
+//	@Summary		Check that a receipt is genuine
+//	@Description	Takes the number and verification code printed on the receipt and returns what the school recorded for it, to compare with the paper. An unknown number or wrong code is not found.
+//	@Tags			Invoices
+//	@Produce		json
+//	@Param			number	path		string	true	"Receipt number"
+//	@Param			code	query		string	true	"Verification code"
+//	@Success		200		{object}	ReceiptVerification
+//	@Failure		404		{object}	error
+//	@Router			/receipts/{number}/verify [get]
+//	@ID				verifyReceipt
+func (app *application) verifyReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("code")))
+
+	rc, err := app.store.Receipts.GetByNumber(r.Context(), chi.URLParam(r, "number"))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if rc == nil || subtle.ConstantTimeCompare([]byte(code), []byte(rc.Code)) != 1 {
+		app.notfoundResponse(w, r, errors.New("no receipt with this number and code"))
+		return
+	}
+
+	resp := ReceiptVerification{
+		Valid:        true,
+		Number:       rc.Number,
+		School:       app.config.school.name,
+		StudentName:  rc.StudentName,
+		InvoiceTitle: rc.InvoiceTitle,
+		Term:         rc.Term,
+		Amount:       rc.Amount,
+		Currency:     app.config.school.currency,
+		RefID:        rc.RefID,
+		PaidAt:       rc.PaidAt,
+	}
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetReceiptPDF godoc
+//
+//	@Summary	Download a payment receipt as PDF
+//	@Tags		Invoices
+//	@Produce	application/pdf
+//	@Param		number	path	string	true	"Receipt number, from the invoice's payments"
+//	@Success	200
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/invoices/receipts/{number}/pdf [get]
+//	@ID			getReceiptPDF
+func (app *application) getReceiptPDFHandler(w http.ResponseWriter, r *http.Request) {
+	rc, ok := app.loadReceipt(w, r)
+	if !ok {
+		return
+	}
+	app.writeReceiptPDF(w, rc)
+}
+
+// GetMyReceiptPDF godoc
+//
+//	@Summary	Download a receipt for one of the caller's payments as PDF
+//	@Tags		Parents
+//	@Produce	application/pdf
+//	@Param		number	path	string	true	"Receipt number, from the invoice's payments"
+//	@Success	200
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/parents/me/receipts/{number}/pdf [get]
+//	@ID			getMyReceiptPDF
+func (app *application) getMyReceiptPDFHandler(w http.ResponseWriter, r *http.Request) {
+	parent, err := app.store.Parents.GetByID(r.Context(), getUser(r).ID)
+	if err != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account no longer exists"))
+		return
+	}
+
+	rc, ok := app.loadReceipt(w, r)
+	if !ok {
+		return
+	}
+	if rc.ParentPhone != parent.PhoneNumber {
+		app.notfoundResponse(w, r, store.ErrNotFound)
+		return
+	}
+	app.writeReceiptPDF(w, rc)
+}
+
+func (app *application) writeReceiptPDF(w http.ResponseWriter, rc *store.Receipt) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%s.pdf"`, rc.Number))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(app.renderReceipt(rc))
+}
+
+func (app *application) loadReceipt(w http.ResponseWriter, r *http.Request) (*store.Receipt, bool) {
+	rc, err := app.store.Receipts.GetByNumber(r.Context(), chi.URLParam(r, "number"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return rc, true
+}
@@ -1,45 +1,57 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type ExecRegisterPayload struct {
-	FirstName string `json:"first_name" validate:"required,max=72"`
-	LastName  string `json:"last_name" validate:"required,max=72"`
-	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8,max=72"`
-	Role      string `json:"role" validate:"required,oneof=admin manager"`
+	FirstName string `json:"first_name" validate:"required,max=72" example:"Sara"`
+	LastName  string `json:"last_name" validate:"required,max=72" example:"Ahmadi"`
+	Email     string `json:"email" validate:"required,email" example:"sara.ahmadi@example.com"`
+	Password  string `json:"password" validate:"required,min=8,max=72" example:"S3cure-Passw0rd"`
+	Role      string `json:"role" validate:"required,oneof=admin manager" enums:"admin,manager" example:"admin"`
 }
 
 type TeacherRegisterPayload struct {
-	FirstName   string `json:"first_name" validate:"required,max=72"`
-	LastName    string `json:"last_name" validate:"required,max=72"`
-	Email       string `json:"email" validate:"required,email"`
-	Password    string `json:"password" validate:"required,min=8,max=72"`
-	Subject     string `json:"subject" validate:"required,max=128"`
-	PhoneNumber string `json:"phone_number" validate:"required,e164"`
-	HireDate    string `json:"hire_date" validate:"required,datetime=2006-01-02"`
+	FirstName   string              `json:"first_name" validate:"required,max=72" example:"Sara"`
+	LastName    string              `json:"last_name" validate:"required,max=72" example:"Ahmadi"`
+	Email       string              `json:"email" validate:"required,email" example:"sara.ahmadi@example.com"`
+	Password    string              `json:"password" validate:"required,min=8,max=72" example:"S3cure-Passw0rd"`
+	Subject     string              `json:"subject" validate:"required,max=128" example:"Mathematics"`
+	SubjectI18n store.LocalizedText `json:"subject_i18n,omitempty" validate:"omitempty,dive,keys,bcp47_language_tag,endkeys,max=128" example:"en:Mathematics,fa:ریاضی"`
+	PhoneNumber string              `json:"phone_number" validate:"required,e164" example:"+989121234567"`
+	HireDate    string              `json:"hire_date" validate:"required,datetime=2006-01-02" example:"2024-09-01"`
+	NationalID  *string             `json:"national_id,omitempty" validate:"omitempty,national_id" example:"0012345678"`
+
+	// TemporaryPassword makes the teacher change the password at first
+	// sign-in.
+	TemporaryPassword bool `json:"temporary_password"`
 }
 
 type StudentRegisterPayload struct {
-	FirstName         string    `json:"first_name" validate:"required,max=72"`
-	LastName          string    `json:"last_name" validate:"required,max=72"`
-	Email             string    `json:"email" validate:"required,email"`
-	Password          string    `json:"password" validate:"required,min=8,max=72"`
-	PhoneNumber       *string   `json:"phone_number"`
-	ClassRoomID       int64     `json:"classroom_id" validate:"required"`
-	BirthDate         time.Time `json:"birth_date" validate:"required"`
-	Address           string    `json:"address" validate:"required"`
-	ParentName        string    `json:"parent_name" validate:"required"`
-	ParentPhoneNumber string    `json:"parent_phone_number" validate:"required"`
-	TeacherID         int64     `json:"teacher_id" validate:"required"`
+	FirstName         string    `json:"first_name" validate:"required,max=72" example:"Sara"`
+	LastName          string    `json:"last_name" validate:"required,max=72" example:"Ahmadi"`
+	Email             string    `json:"email" validate:"required,email" example:"sara.ahmadi@example.com"`
+	Password          string    `json:"password" validate:"required,min=8,max=72" example:"S3cure-Passw0rd"`
+	PhoneNumber       *string   `json:"phone_number" example:"+989121234567"`
+	ClassRoomID       int64     `json:"classroom_id" validate:"required" example:"3"`
+	BirthDate         time.Time `json:"birth_date" validate:"required" example:"2011-05-14T00:00:00Z"`
+	Address           string    `json:"address" validate:"required" example:"12 Azadi St, Tehran"`
+	ParentName        string    `json:"parent_name" validate:"required" example:"Reza Ahmadi"`
+	ParentPhoneNumber string    `json:"parent_phone_number" validate:"required" example:"+989351234567"`
+	TeacherID         int64     `json:"teacher_id" validate:"required" example:"7"`
+	NationalID        *string   `json:"national_id,omitempty" validate:"omitempty,national_id" example:"0012345678"`
+
+	// TemporaryPassword makes the student change the password at first
+	// sign-in.
+	TemporaryPassword bool `json:"temporary_password"`
 }
 
 // registerExecHandler godoc
@@ -53,6 +65,7 @@ type StudentRegisterPayload struct {
 //	@Success		201		{object}	map[string]any		"Returns the created Exec and JWT token"
 //	@Failure		400		{object}	map[string]string	"Bad request"
 //	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Failure		409		{object}	emailConflictResponse	"Email used by another account"
 //	@Failure		500		{object}	map[string]string	"Internal server error"
 //	@Router			/execs/register [post]
 func (app *application) registerExecHandler(w http.ResponseWriter, r *http.Request) {
@@ -72,12 +85,23 @@ func (app *application) registerExecHandler(w http.ResponseWriter, r *http.Reque
 		Email:     payload.Email,
 		Role:      store.Role(payload.Role),
 	}
+	if !app.emailAvailable(w, r, exec.Email, nil) {
+		return
+	}
+
+	if !app.passwordAllowed(w, r, 0, payload.Password) {
+		return
+	}
 	if err := exec.Password.Set(payload.Password); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
 	if err := app.store.Execs.Create(r.Context(), exec); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictResponse(w, r, fmt.Errorf("email already registered"))
+			return
+		}
 		app.badRequestResponse(w, r, err)
 		return
 	}
@@ -93,9 +117,10 @@ func (app *application) registerExecHandler(w http.ResponseWriter, r *http.Reque
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		TeacherRegisterPayload	true	"Teacher registration payload"
-//	@Success		201		{object}	store.Teacher			"Returns the created Teacher"
+//	@Success		201		{object}	dto.Teacher				"Returns the created Teacher"
 //	@Failure		400		{object}	map[string]string		"Bad request"
 //	@Failure		401		{object}	map[string]string		"Unauthorized"
+//	@Failure		409		{object}	emailConflictResponse	"Email used by another account"
 //	@Failure		500		{object}	map[string]string		"Internal server error"
 //	@Router			/teachers [post]
 func (app *application) registerTeacherHandler(w http.ResponseWriter, r *http.Request) {
@@ -109,37 +134,67 @@ func (app *application) registerTeacherHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// a new teacher teaches no classroom yet, so is outside any scope
+	if !getScope(r).Unrestricted() {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
 	teacher := &store.Teacher{
 		FirstName:   payload.FirstName,
 		LastName:    payload.LastName,
 		Email:       payload.Email,
 		Subject:     payload.Subject,
+		SubjectI18n: payload.SubjectI18n,
 		PhoneNumber: payload.PhoneNumber,
+		NationalID:  payload.NationalID,
+	}
+	if !app.emailAvailable(w, r, teacher.Email, nil) {
+		return
+	}
+
+	if !app.passwordAllowed(w, r, 0, payload.Password) {
+		return
+	}
+	setPassword := teacher.Password.Set
+	if payload.TemporaryPassword {
+		setPassword = teacher.Password.SetTemporary
 	}
-	if err := teacher.Password.Set(payload.Password); err != nil {
+	if err := setPassword(payload.Password); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
 	if err := app.store.Teachers.Create(r.Context(), teacher); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictResponse(w, r, fmt.Errorf("email or national ID already registered"))
+			return
+		}
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	app.jsonResponse(w, http.StatusCreated, teacher)
+	app.jsonResponse(w, http.StatusCreated, dto.FromTeacher(app.viewer(r), localizeTeacher(r, teacher)))
+}
+
+type duplicateStudentsResponse struct {
+	Error      string                    `json:"error"`
+	Candidates []*dto.DuplicateCandidate `json:"candidates"`
 }
 
 // registerStudentHandler godoc
 //
 //	@Summary		Register a new Student
-//	@Description	Only Execs with manager/admin roles can create new Students
+//	@Description	Only Execs with manager/admin roles can create new Students. Likely duplicates (same name and birth date, parent phone or national ID) are rejected with 409 and the matching records unless force is set.
 //	@Tags			Students
 //	@Accept			json
 //	@Produce		json
 //	@Param			payload	body		StudentRegisterPayload	true	"Student registration payload"
-//	@Success		201		{object}	store.Student			"Returns the created Student"
+//	@Param			force	query		bool					false	"Register even if likely duplicates exist"
+//	@Success		201		{object}	dto.Student				"Returns the created Student"
 //	@Failure		400		{object}	map[string]string		"Bad request"
 //	@Failure		401		{object}	map[string]string		"Unauthorized"
+//	@Failure		409		{object}	duplicateStudentsResponse	"Likely duplicates, or emailConflictResponse when the email is used by another account"
 //	@Failure		500		{object}	map[string]string		"Internal server error"
 //	@Router			/students [post]
 func (app *application) registerStudentHandler(w http.ResponseWriter, r *http.Request) {
@@ -164,18 +219,57 @@ func (app *application) registerStudentHandler(w http.ResponseWriter, r *http.Re
 		ParentName:        payload.ParentName,
 		ParentPhoneNumber: payload.ParentPhoneNumber,
 		TeacherID:         payload.TeacherID,
+		NationalID:        payload.NationalID,
+	}
+
+	ok, err := app.classroomInScope(r, student.ClassRoomID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
+
+	if !app.emailAvailable(w, r, student.Email, nil) {
+		return
 	}
-	if err := student.Password.Set(payload.Password); err != nil {
+
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	if !force {
+		candidates, err := app.store.Students.FindDuplicates(r.Context(), student)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+		if len(candidates) > 0 {
+			app.logger.Warnw("possible duplicate student", "method", r.Method, "path", r.URL.Path, "candidates", len(candidates))
+			writeJSON(w, http.StatusConflict, &duplicateStudentsResponse{
+				Error:      "possible duplicate student; retry with ?force=true to register anyway",
+				Candidates: dto.FromDuplicateCandidates(app.viewer(r), candidates),
+			})
+			return
+		}
+	}
+
+	if !app.passwordAllowed(w, r, 0, payload.Password) {
+		return
+	}
+	setPassword := student.Password.Set
+	if payload.TemporaryPassword {
+		setPassword = student.Password.SetTemporary
+	}
+	if err := setPassword(payload.Password); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
 	if err := app.store.Students.Create(r.Context(), student); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			app.conflictResponse(w, r, fmt.Errorf("email or national ID already registered"))
+			return
+		}
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	app.jsonResponse(w, http.StatusCreated, student)
+	app.jsonResponse(w, http.StatusCreated, dto.FromStudent(app.viewer(r), student))
 }
 
 func (app *application) createAndRespondJWT(
@@ -186,40 +280,27 @@ func (app *application) createAndRespondJWT(
 	email string,
 ) {
 	var id int64
+	var out any
 	switch v := entity.(type) {
 	case *store.Exec:
-		id = v.ID
+		id, out = v.ID, dto.FromExec(v)
 	case *store.Teacher:
-		id = v.ID
+		id, out = v.ID, dto.FromTeacher(dto.Unrestricted, v)
 	case *store.Student:
-		id = v.ID
+		id, out = v.ID, dto.FromStudent(dto.Unrestricted, v)
 	default:
 		app.internalServerErrorResponse(w, r, fmt.Errorf("unsupported entity type"))
 		return
 	}
 
-	claims := &auth.Claims{
-		ID:    id,
-		Role:  role,
-		Email: email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   fmt.Sprint(id),
-			Issuer:    app.config.auth.token.iss,
-			Audience:  []string{app.config.auth.token.iss},
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(app.config.auth.token.exp)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
-	}
-
-	token, err := app.authenticator.GenerateToken(claims)
+	_, token, err := app.issueToken(id, role, email, false)
 	if err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
 	resp := map[string]any{
-		"entity": entity,
+		"entity": out,
 		"token":  token,
 	}
 
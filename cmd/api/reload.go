@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/captcha"
+	"github.com/MahdiiTaheri/classnama-backend/internal/env"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/telegram"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/password"
+	"github.com/MahdiiTaheri/classnama-backend/internal/ratelimiter"
+	"github.com/MahdiiTaheri/classnama-backend/internal/sms"
+	"go.uber.org/zap/zapcore"
+)
+
+// dynamicConfig is the configuration that can change without a restart,
+// with the clients built from it. A reload replaces it as a whole, so a
+// request never sees half of one version and half of another.
+type dynamicConfig struct {
+	logLevel    zapcore.Level
+	ratelimiter ratelimiter.Config
+	absenceSMS  absenceSMSConfig
+	captcha     captchaConfig
+	mail        mailer.Config
+	sms         sms.Config
+	telegram    telegram.Config
+	password    password.Policy
+	loadedAt    time.Time
+
+	captchaVerifier captcha.Verifier
+	mailClient      mailer.Client
+	smsClient       sms.Client
+	telegramClient  telegram.Client
+}
+
+// ConfigStatus describes the dynamic configuration in effect, without
+// secrets.
+type ConfigStatus struct {
+	LoadedAt    time.Time `json:"loaded_at"`
+	LogLevel    string    `json:"log_level"`
+	RateLimiter struct {
+		Enabled       bool `json:"enabled"`
+		Requests      int  `json:"requests"`
+		WindowSeconds int  `json:"window_seconds"`
+	} `json:"rate_limiter"`
+	Features struct {
+		AbsenceSMS bool   `json:"absence_sms"`
+		Captcha    string `json:"captcha,omitempty"`
+	} `json:"features"`
+	Providers struct {
+		Mail     bool `json:"mail"`
+		SMS      bool `json:"sms"`
+		Telegram bool `json:"telegram"`
+	} `json:"providers"`
+	PasswordPolicy password.Policy `json:"password_policy"`
+}
+
+// readDynamicConfig reads the reloadable settings from the environment and
+// builds their clients. An invalid value fails the whole read.
+func readDynamicConfig() (*dynamicConfig, error) {
+	dc := &dynamicConfig{
+		ratelimiter: ratelimiter.Config{
+			RequestsPerTimeFrame: env.GetInt("RATE_LIMITER_REQUESTS_COUNT", 10),
+			TimeFrame:            time.Second * 5,
+			Enabled:              env.GetBool("RATE_LIMITER_ENABLED", true),
+		},
+		absenceSMS: absenceSMSConfig{
+			enabled:       env.GetBool("ABSENCE_SMS_ENABLED", false),
+			webhookSecret: env.GetString("SMS_WEBHOOK_SECRET", ""),
+		},
+		captcha: captchaConfig{
+			enabled: env.GetBool("CAPTCHA_ENABLED", false),
+			Config: captcha.Config{
+				Provider: env.GetString("CAPTCHA_PROVIDER", captcha.HCaptcha),
+				Secret:   env.GetString("CAPTCHA_SECRET", ""),
+			},
+		},
+		mail: mailer.Config{
+			Host:     env.GetString("SMTP_HOST", ""),
+			Port:     env.GetInt("SMTP_PORT", 587),
+			Username: env.GetString("SMTP_USERNAME", ""),
+			Password: env.GetString("SMTP_PASSWORD", ""),
+			From:     env.GetString("MAIL_FROM", "ClassNama <no-reply@classnama.local>"),
+		},
+		sms: sms.Config{
+			APIKey: env.GetString("SMS_API_KEY", ""),
+			Sender: env.GetString("SMS_SENDER", ""),
+		},
+		telegram: telegram.Config{
+			Token:         env.GetString("TELEGRAM_BOT_TOKEN", ""),
+			Username:      env.GetString("TELEGRAM_BOT_USERNAME", ""),
+			WebhookSecret: env.GetString("TELEGRAM_WEBHOOK_SECRET", ""),
+		},
+		password: password.Policy{
+			MinLength:     env.GetInt("PASSWORD_MIN_LENGTH", 8),
+			RequireUpper:  env.GetBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower:  env.GetBool("PASSWORD_REQUIRE_LOWER", false),
+			RequireDigit:  env.GetBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol: env.GetBool("PASSWORD_REQUIRE_SYMBOL", false),
+			BanCommon:     env.GetBool("PASSWORD_BAN_COMMON", true),
+			History:       env.GetInt("PASSWORD_HISTORY", 5),
+		},
+		loadedAt: time.Now().UTC(),
+	}
+	if err := dc.password.Validate(); err != nil {
+		return nil, err
+	}
+
+	level, err := zapcore.ParseLevel(env.GetString("LOG_LEVEL", "info"))
+	if err != nil {
+		return nil, err
+	}
+	dc.logLevel = level
+
+	if dc.captcha.enabled {
+		dc.captchaVerifier, err = captcha.New(dc.captcha.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dc.mailClient = mailer.New(dc.mail)
+	dc.smsClient = sms.New(dc.sms)
+	dc.telegramClient = telegram.New(dc.telegram)
+
+	return dc, nil
+}
+
+// reloadConfig re-reads the environment and, if it is valid, switches to
+// it. On error the running configuration is kept.
+func (app *application) reloadConfig() (*dynamicConfig, error) {
+	if err := env.Reload(); err != nil {
+		return nil, err
+	}
+	dc, err := readDynamicConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	app.logLevel.SetLevel(dc.logLevel)
+	app.ratelimiter.SetLimit(dc.ratelimiter.RequestsPerTimeFrame)
+	app.dynamic.Store(dc)
+
+	app.logger.Infow("configuration reloaded",
+		"log_level", dc.logLevel.String(),
+		"rate_limit", dc.ratelimiter.RequestsPerTimeFrame,
+		"rate_limiter", dc.ratelimiter.Enabled,
+		"captcha", dc.captchaVerifier != nil,
+		"absence_sms", dc.absenceSMS.enabled)
+	return dc, nil
+}
+
+// listenForReload reloads the configuration on SIGHUP.
+func (app *application) listenForReload() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := app.reloadConfig(); err != nil {
+				app.logger.Errorw("reloading configuration failed; keeping the current one", "error", err.Error())
+			}
+		}
+	}()
+}
+
+func (dc *dynamicConfig) status() ConfigStatus {
+	var s ConfigStatus
+	s.LoadedAt = dc.loadedAt
+	s.LogLevel = dc.logLevel.String()
+	s.RateLimiter.Enabled = dc.ratelimiter.Enabled
+	s.RateLimiter.Requests = dc.ratelimiter.RequestsPerTimeFrame
+	s.RateLimiter.WindowSeconds = int(dc.ratelimiter.TimeFrame.Seconds())
+	s.Features.AbsenceSMS = dc.absenceSMS.enabled
+	if dc.captchaVerifier != nil {
+		s.Features.Captcha = dc.captcha.Provider
+	}
+	s.Providers.Mail = dc.mail.Host != ""
+	s.Providers.SMS = dc.sms.APIKey != ""
+	s.Providers.Telegram = dc.telegram.Token != ""
+	s.PasswordPolicy = dc.password
+	return s
+}
+
+// GetConfig godoc
+//
+//	@Summary	Show the reloadable configuration in effect
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{object}	ConfigStatus
+//	@Security	ApiKeyAuth
+//	@Router		/admin/config [get]
+//	@ID			getConfig
+func (app *application) getConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, app.dynamic.Load().status()); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ReloadConfig godoc
+//
+//	@Summary		Reload configuration without a restart
+//	@Description	Re-reads the environment (the file at ENV_FILE over the built-in .env) and applies the log level, rate limits, captcha and absence SMS switches, password policy, webhook secrets and mail, SMS and Telegram providers. Other settings need a restart. Only this instance reloads; send SIGHUP to the others. An invalid configuration is rejected and the current one kept.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	ConfigStatus
+//	@Failure		400	{object}	error	"Invalid configuration"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/config/reload [post]
+//	@ID				reloadConfig
+func (app *application) reloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	dc, err := app.reloadConfig()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dc.status()); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// The notification clients send through the current dynamic configuration,
+// so a reload switches providers for the next message.
+
+type dynamicMailer struct{ app *application }
+
+func (c dynamicMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return c.app.dynamic.Load().mailClient.Send(ctx, msg)
+}
+
+type dynamicSMS struct{ app *application }
+
+func (c dynamicSMS) Send(ctx context.Context, to, message string) error {
+	return c.app.dynamic.Load().smsClient.Send(ctx, to, message)
+}
+
+type dynamicTelegram struct{ app *application }
+
+func (c dynamicTelegram) Send(ctx context.Context, chatID int64, text string) error {
+	return c.app.dynamic.Load().telegramClient.Send(ctx, chatID, text)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/sms"
+)
+
+const attendanceReminderTick = time.Minute
+
+// parseClock parses "HH:MM" into the offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q; expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// startAttendanceReminders checks once per school day (per the school
+// calendar), after the cutoff, for classrooms without attendance and reminds
+// their teachers.
+func (app *application) startAttendanceReminders() {
+	if !app.config.reminders.enabled {
+		return
+	}
+
+	cutoff, err := parseClock(app.config.reminders.cutoff)
+	if err != nil {
+		app.logger.Errorw("attendance reminders disabled", "error", err.Error())
+		return
+	}
+
+	var lastRun string
+	ticker := time.NewTicker(attendanceReminderTick)
+	go func() {
+		for range ticker.C {
+			now := app.school.now()
+			today := now.Format(time.DateOnly)
+			midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			if today == lastRun || now.Sub(midnight) < cutoff {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			open, _, err := app.store.SchoolDays.IsSchoolDay(ctx, midnight)
+			if err != nil {
+				cancel()
+				app.logger.Errorw("checking school calendar failed", "error", err.Error())
+				continue // retried on the next tick
+			}
+			lastRun = today
+			if !open {
+				cancel()
+				continue
+			}
+			sent, err := app.sendAttendanceReminders(ctx, midnight)
+			cancel()
+			if err != nil {
+				app.logger.Errorw("attendance reminder check failed", "error", err.Error())
+				continue
+			}
+			app.logger.Infow("attendance reminders sent", "date", today, "count", sent)
+		}
+	}()
+}
+
+// sendAttendanceReminders reminds the teacher of every classroom still
+// missing attendance on date, by email and SMS where configured.
+func (app *application) sendAttendanceReminders(ctx context.Context, date time.Time) (int, error) {
+	missing, err := app.store.Reminders.MissingAttendance(ctx, date)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, m := range missing {
+		claimed, err := app.store.Reminders.Claim(ctx, m.ClassroomID, m.TeacherID, date)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		text := fmt.Sprintf("Attendance for %s has not been recorded for %s. Please mark it in ClassNama.",
+			m.ClassroomName, date.Format(time.DateOnly))
+
+		via := []string{}
+		if m.TeacherEmail != "" {
+			err := app.mailer.Send(ctx, mailer.Message{
+				To:      []string{m.TeacherEmail},
+				Subject: fmt.Sprintf("Attendance not recorded: %s", m.ClassroomName),
+				Body:    fmt.Sprintf("Hello %s,\n\n%s\n", m.TeacherName, text),
+			})
+			switch {
+			case err == nil:
+				via = append(via, "email")
+			case !errors.Is(err, mailer.ErrNotConfigured):
+				app.logger.Warnw("attendance reminder email failed", "teacher", m.TeacherID, "error", err.Error())
+			}
+		}
+		if m.TeacherPhone != "" {
+			err := app.sms.Send(ctx, m.TeacherPhone, text)
+			switch {
+			case err == nil:
+				via = append(via, "sms")
+			case !errors.Is(err, sms.ErrNotConfigured):
+				app.logger.Warnw("attendance reminder sms failed", "teacher", m.TeacherID, "error", err.Error())
+			}
+		}
+
+		if err := app.store.Reminders.MarkDelivered(ctx, m.ClassroomID, date, strings.Join(via, ",")); err != nil {
+			app.logger.Warnw("recording attendance reminder failed", "classroom", m.ClassroomID, "error", err.Error())
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// GetAttendanceCompliance godoc
+//
+//	@Summary		Teachers reminded to take attendance
+//	@Description	Per teacher, how many attendance reminders they received in the range, and whether attendance was taken afterwards. Teachers with at least min_reminders are flagged as chronic.
+//	@Tags			Analytics
+//	@Produce		json
+//	@Param			from			query		string	false	"Start date (YYYY-MM-DD), defaults to 30 days before to"
+//	@Param			to				query		string	false	"End date (YYYY-MM-DD), defaults to today"
+//	@Param			min_reminders	query		int		false	"Reminders that count as chronic (default 3)"
+//	@Success		200				{array}		dto.ReminderCompliance
+//	@Failure		400				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/analytics/attendance/compliance [get]
+//	@ID				getAttendanceCompliance
+func (app *application) getAttendanceComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	minReminders := 3
+	if v := r.URL.Query().Get("min_reminders"); v != "" {
+		minReminders, err = strconv.Atoi(v)
+		if err != nil || minReminders < 1 {
+			app.badRequestResponse(w, r, fmt.Errorf("min_reminders must be a positive integer"))
+			return
+		}
+	}
+
+	report, err := app.store.Reminders.Compliance(r.Context(), from, to, minReminders, getScope(r))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromReminderCompliances(report)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/jobs"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	reportRunJob           = "report_run"
+	reportSchedulerTick    = time.Minute
+	reportLinkValidity     = 7 * 24 * time.Hour
+	defaultReportRunsLimit = 20
+)
+
+// reportTable is a generated report before rendering.
+type reportTable struct {
+	header []string
+	rows   [][]string
+}
+
+type reportGenerator func(ctx context.Context, app *application, params map[string]string) (*reportTable, error)
+
+// reportGenerators are the reports that can be scheduled.
+var reportGenerators = map[string]reportGenerator{
+	"attendance_trends": attendanceTrendsReport,
+}
+
+type CreateReportSchedulePayload struct {
+	Name       string            `json:"name" validate:"required,max=128"`
+	Report     string            `json:"report" validate:"required,oneof=attendance_trends" enums:"attendance_trends" example:"attendance_trends"`
+	Params     map[string]string `json:"params,omitempty"`
+	Cron       string            `json:"cron" validate:"required,max=128"`
+	Format     string            `json:"format,omitempty" validate:"omitempty,oneof=csv json" enums:"csv,json" example:"csv"`
+	Delivery   string            `json:"delivery,omitempty" validate:"omitempty,oneof=attachment link" enums:"attachment,link" example:"attachment"`
+	Recipients []string          `json:"recipients" validate:"required,min=1,max=20,dive,email"`
+}
+
+type UpdateReportSchedulePayload struct {
+	Enabled bool `json:"enabled"`
+}
+
+// CreateReportSchedule godoc
+//
+//	@Summary		Schedule a report
+//	@Description	Runs the report on a standard 5-field cron expression (UTC) and emails it to the recipients
+//	@Tags			Reports
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateReportSchedulePayload	true	"Schedule"
+//	@Success		201		{object}	dto.ReportSchedule
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/reports/schedules [post]
+//	@ID				createReportSchedule
+func (app *application) createReportScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateReportSchedulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	sched, err := cron.ParseStandard(payload.Cron)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid cron expression: %w", err))
+		return
+	}
+
+	userID := getUser(r).ID
+	rs := &store.ReportSchedule{
+		Name:       payload.Name,
+		Report:     payload.Report,
+		Params:     payload.Params,
+		Cron:       payload.Cron,
+		Format:     payload.Format,
+		Delivery:   payload.Delivery,
+		Recipients: payload.Recipients,
+		Enabled:    true,
+		NextRunAt:  sched.Next(time.Now().UTC()),
+		CreatedBy:  &userID,
+	}
+	if rs.Params == nil {
+		rs.Params = map[string]string{}
+	}
+	if rs.Format == "" {
+		rs.Format = "csv"
+	}
+	if rs.Delivery == "" {
+		rs.Delivery = "attachment"
+	}
+
+	if err := app.store.Reports.CreateSchedule(r.Context(), rs); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromReportSchedule(rs)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListReportSchedules godoc
+//
+//	@Summary	List report schedules
+//	@Tags		Reports
+//	@Produce	json
+//	@Success	200	{array}		dto.ReportSchedule
+//	@Failure	500	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/reports/schedules [get]
+//	@ID			listReportSchedules
+func (app *application) listReportSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	schedules, err := app.store.Reports.ListSchedules(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromReportSchedules(schedules)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateReportSchedule godoc
+//
+//	@Summary	Enable or pause a report schedule
+//	@Tags		Reports
+//	@Accept		json
+//	@Param		scheduleID	path	int							true	"Schedule ID"
+//	@Param		payload		body	UpdateReportSchedulePayload	true	"State"
+//	@Success	204
+//	@Failure	400	{object}	error
+//	@Failure	404	{object}	error
+//	@Failure	500	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/reports/schedules/{scheduleID} [patch]
+//	@ID			updateReportSchedule
+func (app *application) updateReportScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	rs, ok := app.loadReportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	var payload UpdateReportSchedulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// re-enabling must not fire every run missed while paused
+	next := rs.NextRunAt
+	if sched, err := cron.ParseStandard(rs.Cron); err == nil {
+		next = sched.Next(time.Now().UTC())
+	}
+
+	if err := app.store.Reports.SetScheduleEnabled(r.Context(), rs.ID, payload.Enabled, next); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteReportSchedule godoc
+//
+//	@Summary	Delete a report schedule and its run history
+//	@Tags		Reports
+//	@Param		scheduleID	path	int	true	"Schedule ID"
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Failure	500	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/reports/schedules/{scheduleID} [delete]
+//	@ID			deleteReportSchedule
+func (app *application) deleteReportScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "scheduleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid schedule ID"))
+		return
+	}
+
+	if err := app.store.Reports.DeleteSchedule(r.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunReportSchedule godoc
+//
+//	@Summary	Run a scheduled report now
+//	@Tags		Reports
+//	@Produce	json
+//	@Param		scheduleID	path		int	true	"Schedule ID"
+//	@Success	202			{object}	jobs.Job
+//	@Failure	404			{object}	error
+//	@Failure	503			{object}	error	"Queue busy"
+//	@Security	ApiKeyAuth
+//	@Router		/reports/schedules/{scheduleID}/run [post]
+//	@ID			runReportSchedule
+func (app *application) runReportScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	rs, ok := app.loadReportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	user := getUser(r)
+	job, err := app.jobs.Enqueue(reportRunJob, user.ID, user.Role, app.reportJob(rs))
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			app.serviceUnavailableResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, job); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListReportRuns godoc
+//
+//	@Summary	Run history of a report schedule
+//	@Tags		Reports
+//	@Produce	json
+//	@Param		scheduleID	path		int	true	"Schedule ID"
+//	@Success	200			{array}		dto.ReportRun
+//	@Failure	404			{object}	error
+//	@Failure	500			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/reports/schedules/{scheduleID}/runs [get]
+//	@ID			listReportRuns
+func (app *application) listReportRunsHandler(w http.ResponseWriter, r *http.Request) {
+	rs, ok := app.loadReportSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := app.store.Reports.ListRuns(r.Context(), rs.ID, defaultReportRunsLimit)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromReportRuns(runs)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DownloadReportRun godoc
+//
+//	@Summary		Download a generated report
+//	@Description	Authorised by the signature of the link emailed to recipients rather than a token
+//	@Tags			Reports
+//	@Produce		octet-stream
+//	@Param			runID	path	int		true	"Run ID"
+//	@Param			expires	query	int		true	"Link expiry (unix seconds)"
+//	@Param			sig		query	string	true	"Link signature"
+//	@Success		200
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error
+//	@Router			/reports/runs/{runID}/download [get]
+//	@ID				downloadReportRun
+func (app *application) downloadReportRunHandler(w http.ResponseWriter, r *http.Request) {
+	runID, err := strconv.ParseInt(chi.URLParam(r, "runID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid run ID"))
+		return
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires ||
+		!hmac.Equal([]byte(r.URL.Query().Get("sig")), []byte(app.reportLinkSignature(runID, expires))) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	run, content, err := app.store.Reports.GetRunContent(r.Context(), runID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", *run.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", *run.Filename))
+	w.Write(content)
+}
+
+func (app *application) loadReportSchedule(w http.ResponseWriter, r *http.Request) (*store.ReportSchedule, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "scheduleID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid schedule ID"))
+		return nil, false
+	}
+
+	rs, err := app.store.Reports.GetSchedule(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.notfoundResponse(w, r, err)
+		} else {
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return rs, true
+}
+
+func (app *application) reportLinkSignature(runID, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	fmt.Fprintf(mac, "report-run:%d:%d", runID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (app *application) reportJob(rs *store.ReportSchedule) func(ctx context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		run, err := app.runReport(ctx, rs)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(dto.FromReportRun(run))
+	}
+}
+
+// runReport generates the report, records the run and delivers it. Failures
+// are recorded on the run and reported to the recipients.
+func (app *application) runReport(ctx context.Context, rs *store.ReportSchedule) (*store.ReportRun, error) {
+	ctx = store.WithWorkload(ctx, store.WorkloadReporting)
+	run, err := app.store.Reports.StartRun(ctx, rs.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := app.generateReport(ctx, rs, run)
+	if err == nil {
+		err = app.deliverReport(ctx, rs, run, content)
+	}
+
+	run.Status = store.ReportRunDelivered
+	if err != nil {
+		msg := err.Error()
+		run.Status = store.ReportRunFailed
+		run.Error = &msg
+	}
+	if ferr := app.store.Reports.FinishRun(context.WithoutCancel(ctx), run, content); ferr != nil {
+		app.logger.Errorw("recording report run failed", "run", run.ID, "error", ferr.Error())
+	}
+
+	if err != nil {
+		app.logger.Errorw("scheduled report failed", "schedule", rs.ID, "run", run.ID, "error", err.Error())
+		app.alertReportFailure(rs, run, err)
+		return run, err
+	}
+	return run, nil
+}
+
+func (app *application) generateReport(ctx context.Context, rs *store.ReportSchedule, run *store.ReportRun) ([]byte, error) {
+	gen, ok := reportGenerators[rs.Report]
+	if !ok {
+		return nil, fmt.Errorf("unknown report %q", rs.Report)
+	}
+
+	table, err := gen(ctx, app, rs.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv"
+	switch rs.Format {
+	case "json":
+		contentType = "application/json"
+		records := make([]map[string]string, len(table.rows))
+		for i, row := range table.rows {
+			records[i] = make(map[string]string, len(table.header))
+			for j, col := range table.header {
+				records[i][col] = row[j]
+			}
+		}
+		if err := json.NewEncoder(&buf).Encode(records); err != nil {
+			return nil, err
+		}
+	default:
+		cw := csv.NewWriter(&buf)
+		cw.Write(table.header)
+		cw.WriteAll(table.rows)
+		if err := cw.Error(); err != nil {
+			return nil, err
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", rs.Report, run.StartedAt.UTC().Format("20060102"), rs.Format)
+	run.Filename = &filename
+	run.ContentType = &contentType
+	return buf.Bytes(), nil
+}
+
+func (app *application) deliverReport(ctx context.Context, rs *store.ReportSchedule, run *store.ReportRun, content []byte) error {
+	msg := mailer.Message{
+		To:      rs.Recipients,
+		Subject: fmt.Sprintf("ClassNama report: %s", rs.Name),
+	}
+
+	if rs.Delivery == "link" {
+		expires := time.Now().Add(reportLinkValidity).Unix()
+		link := fmt.Sprintf("%s/v1/reports/runs/%d/download?expires=%d&sig=%s",
+			strings.TrimSuffix(app.config.publicURL, "/"), run.ID, expires, app.reportLinkSignature(run.ID, expires))
+		msg.Body = fmt.Sprintf("Your report %q is ready. Download it within 7 days:\n\n%s\n", rs.Name, link)
+	} else {
+		msg.Body = fmt.Sprintf("Your report %q is attached.\n", rs.Name)
+		msg.Attachments = []mailer.Attachment{{Name: *run.Filename, ContentType: *run.ContentType, Data: content}}
+	}
+
+	return app.mailer.Send(ctx, msg)
+}
+
+func (app *application) alertReportFailure(rs *store.ReportSchedule, run *store.ReportRun, cause error) {
+	if errors.Is(cause, mailer.ErrNotConfigured) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := app.mailer.Send(ctx, mailer.Message{
+		To:      rs.Recipients,
+		Subject: fmt.Sprintf("ClassNama report failed: %s", rs.Name),
+		Body:    fmt.Sprintf("Run %d of report %q failed:\n\n%s\n", run.ID, rs.Name, cause),
+	})
+	if err != nil {
+		app.logger.Warnw("sending report failure alert failed", "schedule", rs.ID, "error", err.Error())
+	}
+}
+
+// startReportScheduler claims due schedules every minute and queues a run
+// for each.
+func (app *application) startReportScheduler() {
+	next := func(rs *store.ReportSchedule) time.Time {
+		sched, err := cron.ParseStandard(rs.Cron)
+		if err != nil {
+			// stored expressions are validated; push broken ones far out
+			return time.Now().UTC().AddDate(1, 0, 0)
+		}
+		return sched.Next(time.Now().UTC())
+	}
+
+	ticker := time.NewTicker(reportSchedulerTick)
+	go func() {
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			due, err := app.store.Reports.ClaimDue(ctx, time.Now().UTC(), next)
+			cancel()
+			if err != nil {
+				app.logger.Errorw("claiming due reports failed", "error", err.Error())
+				continue
+			}
+
+			for _, rs := range due {
+				if _, err := app.jobs.Enqueue(reportRunJob, 0, "system", app.reportJob(rs)); err != nil {
+					app.logger.Errorw("scheduling report failed", "schedule", rs.ID, "error", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func attendanceTrendsReport(ctx context.Context, app *application, params map[string]string) (*reportTable, error) {
+	groupBy := params["group_by"]
+	if groupBy == "" {
+		groupBy = "classroom"
+	}
+	days := 7
+	if v, ok := params["days"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 366 {
+			return nil, fmt.Errorf("days must be between 1 and 366")
+		}
+		days = n
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	trends, err := app.store.Analytics.AttendanceTrends(ctx, groupBy, to.AddDate(0, 0, -days), to, store.Scope{})
+	if err != nil {
+		return nil, err
+	}
+
+	table := &reportTable{header: []string{"group", "week", "records", "absent", "late", "absence_rate"}}
+	for _, t := range trends {
+		table.rows = append(table.rows, []string{
+			t.GroupLabel,
+			t.Period.Format(time.DateOnly),
+			strconv.FormatInt(t.Total, 10),
+			strconv.FormatInt(t.Absent, 10),
+			strconv.FormatInt(t.Late, 10),
+			strconv.FormatFloat(t.AbsenceRate, 'f', 4, 64),
+		})
+	}
+	return table, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// schoolSchedule is the school's local time zone and teaching weekdays.
+type schoolSchedule struct {
+	location *time.Location
+	days     map[time.Weekday]bool
+}
+
+// newSchoolSchedule parses a time zone name and a comma-separated list of
+// weekdays ("sat,sun,mon").
+func newSchoolSchedule(timezone, days string) (*schoolSchedule, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid school timezone: %w", err)
+	}
+
+	s := &schoolSchedule{location: loc, days: map[time.Weekday]bool{}}
+	for _, d := range strings.Split(days, ",") {
+		wd, ok := weekdayNames[strings.ToLower(strings.TrimSpace(d))]
+		if !ok {
+			return nil, fmt.Errorf("invalid school day %q", d)
+		}
+		s.days[wd] = true
+	}
+	return s, nil
+}
+
+// now returns the current time in the school's time zone.
+func (s *schoolSchedule) now() time.Time {
+	return time.Now().In(s.location)
+}
+
+// isoWeekdays returns the teaching weekdays as ISO numbers (Monday = 1 …
+// Sunday = 7), the form store.SchoolWeekdays takes.
+func (s *schoolSchedule) isoWeekdays() []int {
+	days := []int{}
+	for wd := time.Monday; wd <= time.Saturday; wd++ {
+		if s.days[wd] {
+			days = append(days, int(wd))
+		}
+	}
+	if s.days[time.Sunday] {
+		days = append(days, 7)
+	}
+	return days
+}
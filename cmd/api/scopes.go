@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type scopeKey string
+
+const scopeCtx scopeKey = "scope"
+
+type ExecScopePayload struct {
+	Grades     []int64 `json:"grades" validate:"max=30,dive,min=1,max=30"`
+	Classrooms []int64 `json:"classrooms" validate:"max=200,dive,min=1"`
+}
+
+// getScope returns the caller's manager scope. Admins, teachers and
+// students are unrestricted; their access is governed by role checks.
+func getScope(r *http.Request) store.Scope {
+	scope, _ := r.Context().Value(scopeCtx).(store.Scope)
+	return scope
+}
+
+// classroomInScope reports whether the caller's scope covers a classroom.
+// Classrooms that don't exist are out of scope.
+func (app *application) classroomInScope(r *http.Request, classroomID int64) (bool, error) {
+	scope := getScope(r)
+	if scope.Unrestricted() {
+		return true, nil
+	}
+
+	classroom, err := app.store.Classrooms.GetByID(r.Context(), classroomID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return scope.Allows(classroom), nil
+}
+
+// studentInScope reports whether the caller's scope covers a student's
+// classroom. Students that don't exist are out of scope.
+func (app *application) studentInScope(r *http.Request, studentID int64) (bool, error) {
+	if getScope(r).Unrestricted() {
+		return true, nil
+	}
+
+	student, err := app.store.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return app.classroomInScope(r, student.ClassRoomID)
+}
+
+// teacherInScope reports whether the caller's scope covers any classroom
+// the teacher teaches.
+func (app *application) teacherInScope(r *http.Request, teacherID int64) (bool, error) {
+	scope := getScope(r)
+	if scope.Unrestricted() {
+		return true, nil
+	}
+
+	classrooms, err := app.store.Classrooms.GetByTeacherID(r.Context(), teacherID)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(classrooms, scope.Allows), nil
+}
+
+// checkScope responds to a failed scope check and reports whether the
+// request may continue.
+func (app *application) checkScope(w http.ResponseWriter, r *http.Request, ok bool, err error) bool {
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return false
+	}
+	if !ok {
+		app.forbiddenResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// SetExecScope godoc
+//
+//	@Summary		Limit a manager to some grades or classrooms
+//	@Description	A scoped manager only sees, and can only change, classrooms whose grade or ID is listed, the students in them and the teachers who teach them. Empty lists give access to the whole school.
+//	@Tags			Execs
+//	@Accept			json
+//	@Produce		json
+//	@Param			execID	path		int					true	"Exec ID"
+//	@Param			payload	body		ExecScopePayload	true	"Scope"
+//	@Success		200		{object}	dto.Exec
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/execs/{execID}/scope [put]
+//	@ID				setExecScope
+func (app *application) setExecScopeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ExecScopePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	exec := getExecFromCtx(r)
+	scope := store.Scope{Grades: payload.Grades, Classrooms: payload.Classrooms}
+	if err := app.store.Execs.SetScope(r.Context(), exec.ID, scope); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	updated, err := app.store.Execs.GetByID(r.Context(), exec.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromExec(updated)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/search"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+const (
+	defaultSearchLimit     = 10
+	maxSearchLimit         = 25
+	searchFullSyncInterval = 24 * time.Hour
+)
+
+var defaultSearchTypes = []string{"students", "teachers", "classrooms"}
+
+// Search godoc
+//
+//	@Summary		Global typeahead search
+//	@Description	Returns a small ranked result set across students, teachers and classrooms.
+//	@Description	Uses Meilisearch when configured and falls back to Postgres trigram search.
+//	@Tags			Search
+//	@Produce		json
+//	@Param			q		query		string	true	"Search term (min 2 characters)"
+//	@Param			types	query		string	false	"Comma-separated types: students,teachers,classrooms"
+//	@Param			limit	query		int		false	"Max results (default 10, max 25)"
+//	@Success		200		{array}		dto.SearchResult
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/search [get]
+//	@ID				search
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	q := strings.TrimSpace(qs.Get("q"))
+	if err := Validate.Var(q, "min=2,max=72"); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("q must be between 2 and 72 characters"))
+		return
+	}
+
+	types := defaultSearchTypes
+	if t := qs.Get("types"); t != "" {
+		types = strings.Split(t, ",")
+		if err := Validate.Var(types, "dive,oneof=students teachers classrooms"); err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("types must be a subset of students,teachers,classrooms"))
+			return
+		}
+	}
+
+	limit := defaultSearchLimit
+	if l := qs.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			app.badRequestResponse(w, r, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	results, err := app.search(r.Context(), q, types, limit, getScope(r))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSearchResults(results)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// search queries the external index when configured and falls back to the
+// database if it is unavailable. The index knows nothing of classrooms, so
+// scoped callers are always searched in the database.
+func (app *application) search(ctx context.Context, q string, types []string, limit int, scope store.Scope) ([]*store.SearchResult, error) {
+	if app.searchIndex != nil && scope.Unrestricted() {
+		singular := make([]string, len(types))
+		for i, t := range types {
+			singular[i] = strings.TrimSuffix(t, "s")
+		}
+
+		hits, err := app.searchIndex.Search(ctx, q, singular, limit)
+		if err == nil {
+			results := make([]*store.SearchResult, len(hits))
+			for i, h := range hits {
+				results[i] = &store.SearchResult{ID: h.EntityID, Type: h.Type, DisplayName: h.DisplayName, Score: h.Score}
+			}
+			return results, nil
+		}
+		app.logger.Warnw("search index query failed, using database", "error", err.Error())
+	}
+
+	return app.store.Search.Search(ctx, q, types, limit, scope)
+}
+
+// startSearchSync keeps the search index in step with the database:
+// incremental syncs of rows changed since the last run, plus a full reindex
+// at boot and once a day to drop records purged from the database.
+func (app *application) startSearchSync() {
+	if app.searchIndex == nil {
+		return
+	}
+
+	var since, lastFull time.Time
+	syncOnce := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		full := time.Since(lastFull) >= searchFullSyncInterval
+		from := since
+		if full {
+			from = time.Time{}
+		}
+
+		last, err := app.syncSearchIndex(ctx, from, full)
+		if err != nil {
+			app.logger.Errorw("search index sync failed", "full", full, "error", err.Error())
+			return
+		}
+		if last.After(since) {
+			since = last
+		}
+		if full {
+			lastFull = time.Now()
+		}
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := app.searchIndex.Configure(ctx); err != nil {
+			app.logger.Errorw("configuring search index failed", "error", err.Error())
+		}
+		cancel()
+
+		syncOnce()
+		ticker := time.NewTicker(app.config.search.interval)
+		for range ticker.C {
+			syncOnce()
+		}
+	}()
+}
+
+// syncSearchIndex pushes records changed after since to the index and
+// returns the newest change seen. A full sync also drops documents whose
+// records no longer exist.
+func (app *application) syncSearchIndex(ctx context.Context, since time.Time, full bool) (time.Time, error) {
+	docs, err := app.store.Search.ChangedSince(ctx, since)
+	if err != nil {
+		return since, err
+	}
+
+	syncedAt := time.Now().Unix()
+	upserts := []search.Document{}
+	deletes := []string{}
+	last := since
+	for _, d := range docs {
+		id := search.DocumentID(d.Type, d.ID)
+		if d.Deleted {
+			deletes = append(deletes, id)
+		} else {
+			upserts = append(upserts, search.Document{
+				ID:          id,
+				EntityID:    d.ID,
+				Type:        d.Type,
+				DisplayName: d.DisplayName,
+				Email:       d.Email,
+				SyncedAt:    syncedAt,
+			})
+		}
+		if d.ChangedAt.After(last) {
+			last = d.ChangedAt
+		}
+	}
+
+	if err := app.searchIndex.Upsert(ctx, upserts); err != nil {
+		return since, err
+	}
+	if err := app.searchIndex.Delete(ctx, deletes); err != nil {
+		return since, err
+	}
+	if full {
+		if err := app.searchIndex.DeleteSyncedBefore(ctx, syncedAt); err != nil {
+			return since, err
+		}
+	}
+
+	return last, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type SeatPayload struct {
+	Row       int   `json:"row" validate:"required,min=1" example:"1"`
+	Column    int   `json:"column" validate:"required,min=1" example:"3"`
+	StudentID int64 `json:"student_id" validate:"required" example:"42"`
+}
+
+type PutSeatingChartPayload struct {
+	Rows    int           `json:"rows" validate:"required,min=1,max=20" example:"5"`
+	Columns int           `json:"columns" validate:"required,min=1,max=20" example:"6"`
+	Seats   []SeatPayload `json:"seats" validate:"dive"`
+}
+
+// GetSeatingChart godoc
+//
+//	@Summary		Get a classroom's seating chart
+//	@Description	Each seat and unseated student carries their attendance status for the date, so the chart can be shown as a seat map where tapping a seat marks that student with POST /attendance. Teachers can only see their own classrooms' charts.
+//	@Tags			Classrooms
+//	@Produce		json
+//	@Param			classroomID	path		int		true	"Classroom ID"
+//	@Param			date		query		string	false	"Attendance date YYYY-MM-DD, today when omitted"
+//	@Success		200			{object}	dto.SeatingChart
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error	"Classroom or chart not found"
+//	@Security		ApiKeyAuth
+//	@Router			/seating-charts/{classroomID} [get]
+//	@ID				getSeatingChart
+func (app *application) getSeatingChartHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if !canRunClassroom(r, classroom) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	date := app.schoolToday()
+	if s := r.URL.Query().Get("date"); s != "" {
+		var err error
+		if date, err = time.Parse(time.DateOnly, s); err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid date param (YYYY-MM-DD)"))
+			return
+		}
+	}
+
+	chart, err := app.store.Seating.Get(r.Context(), classroom.ID, date)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSeatingChart(chart)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// PutSeatingChart godoc
+//
+//	@Summary		Arrange a classroom's seating chart
+//	@Description	Replaces the chart: its size and every seat. Seats must fit the grid and hold students of the classroom, each in one seat; students left out are unseated. Teachers can only arrange their own classrooms.
+//	@Tags			Classrooms
+//	@Accept			json
+//	@Produce		json
+//	@Param			classroomID	path		int						true	"Classroom ID"
+//	@Param			payload		body		PutSeatingChartPayload	true	"Chart"
+//	@Success		200			{object}	dto.SeatingChart
+//	@Failure		400			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/seating-charts/{classroomID} [put]
+//	@ID				putSeatingChart
+func (app *application) putSeatingChartHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if !canRunClassroom(r, classroom) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var payload PutSeatingChartPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	chart := &store.SeatingChart{ClassroomID: classroom.ID, Rows: payload.Rows, Columns: payload.Columns}
+	taken := map[[2]int]bool{}
+	for _, s := range payload.Seats {
+		if s.Row > payload.Rows || s.Column > payload.Columns {
+			app.badRequestResponse(w, r, fmt.Errorf("seat %d,%d is outside the %dx%d grid", s.Row, s.Column, payload.Rows, payload.Columns))
+			return
+		}
+		if taken[[2]int{s.Row, s.Column}] {
+			app.badRequestResponse(w, r, fmt.Errorf("seat %d,%d is assigned twice", s.Row, s.Column))
+			return
+		}
+		taken[[2]int{s.Row, s.Column}] = true
+		chart.Seats = append(chart.Seats, &store.Seat{Row: s.Row, Column: s.Column, SeatStudent: store.SeatStudent{StudentID: s.StudentID}})
+	}
+
+	ctx := r.Context()
+	if err := app.store.Seating.Put(ctx, chart); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrNotInClassroom):
+			app.badRequestResponse(w, r, errors.New("every seated student must be in the classroom"))
+		case errors.Is(err, store.ErrConflict):
+			app.badRequestResponse(w, r, errors.New("a student can only have one seat"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	chart, err := app.store.Seating.Get(ctx, classroom.ID, app.schoolToday())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSeatingChart(chart)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteSeatingChart godoc
+//
+//	@Summary	Delete a classroom's seating chart
+//	@Tags		Classrooms
+//	@Param		classroomID	path	int	true	"Classroom ID"
+//	@Success	204			"No Content"
+//	@Failure	403			{object}	error
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/seating-charts/{classroomID} [delete]
+//	@ID			deleteSeatingChart
+func (app *application) deleteSeatingChartHandler(w http.ResponseWriter, r *http.Request) {
+	classroom := getClassroomFromCtx(r)
+	if !canRunClassroom(r, classroom) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if err := app.store.Seating.Delete(r.Context(), classroom.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
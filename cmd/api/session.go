@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/password"
+)
+
+// rolePermissions lists what each role may do, as "resource:action" names.
+// Clients use it to decide which screens to show; the routes still enforce
+// access with requireRole, so keep the two in step.
+var rolePermissions = map[string][]string{
+	"admin": {
+		"execs:read", "execs:write", "execs:scope",
+		"teachers:read", "teachers:write",
+		"students:read", "students:write",
+		"classrooms:read", "classrooms:write", "classrooms:lms", "oneroster:read",
+		"attendance:read", "attendance:write", "attendance:checkins", "staff:attendance",
+		"online:host",
+		"points:award", "points:categories",
+		"surveys:manage", "consents:manage",
+		"pickups:check", "pickups:checkouts",
+		"assets:manage",
+		"bookings:create", "bookings:resources",
+		"calendar:read", "calendar:write",
+		"tags:manage", "search",
+		"sms:threads",
+		"announcements:broadcast",
+		"payroll:manage", "expenses:review", "payslips:read", "expenses:submit",
+		"invoices:manage", "discounts:manage", "finance:read",
+		"terms:manage",
+		"counseling:refer", "counseling:stats",
+		"admin",
+	},
+	"manager": {
+		"execs:read", "execs:write",
+		"teachers:read", "teachers:write",
+		"students:read", "students:write",
+		"classrooms:read", "classrooms:write", "classrooms:lms", "oneroster:read",
+		"attendance:read", "attendance:write", "attendance:checkins", "staff:attendance",
+		"online:host",
+		"points:award",
+		"surveys:manage", "consents:manage",
+		"pickups:check", "pickups:checkouts",
+		"assets:manage",
+		"bookings:create", "bookings:resources",
+		"calendar:read",
+		"tags:manage", "search",
+		"sms:threads",
+		"expenses:review", "payslips:read", "expenses:submit",
+		"invoices:manage", "discounts:manage",
+		"terms:manage",
+		"counseling:refer", "counseling:stats",
+	},
+	"teacher": {
+		"attendance:read", "attendance:write", "attendance:checkin", "staff:checkin",
+		"online:host",
+		"points:award",
+		"surveys:respond", "consents:read",
+		"pickups:check",
+		"bookings:create",
+		"calendar:read",
+		"payslips:read", "expenses:submit",
+		"counseling:refer",
+	},
+	"student": {
+		"attendance:checkin",
+		"online:join",
+		"points:read",
+		"surveys:respond",
+	},
+	"parent": {
+		"children:read",
+		"telegram:link",
+		"invoices:pay",
+	},
+}
+
+// permCounselingRead lets an account read and write counseling session
+// notes. No role has it; admins grant it to the counselors.
+const permCounselingRead = "counseling:read"
+
+// grantablePermissions are the permissions admins give single accounts on
+// top of their role's, for access too sensitive for every exec.
+var grantablePermissions = map[string]bool{
+	permCounselingRead: true,
+}
+
+// schoolSettings is the part of the configuration clients need right after
+// sign-in.
+type schoolSettings struct {
+	Name         string `json:"name"`
+	Timezone     string `json:"timezone"`
+	GradingScale string `json:"grading_scale"`
+	Currency     string `json:"currency"`
+	// PasswordPolicy is what new passwords must follow.
+	PasswordPolicy password.Policy `json:"password_policy"`
+}
+
+// addSessionInfo adds the caller's permissions, those of their role and
+// any granted to their account, the school settings and an avatar URL to a
+// login response, so clients need no follow-up requests.
+func (app *application) addSessionInfo(ctx context.Context, resp map[string]any, role, email string, accountID int64) {
+	permissions := append([]string{}, rolePermissions[role]...)
+	if accountID != 0 {
+		granted, err := app.store.Grants.ForAccount(ctx, accountID)
+		if err != nil {
+			app.logger.Warnw("reading granted permissions failed", "account", accountID, "error", err.Error())
+		}
+		permissions = append(permissions, granted...)
+	}
+	resp["permissions"] = permissions
+	resp["school"] = schoolSettings{
+		Name:         app.config.school.name,
+		Timezone:     app.config.school.timezone,
+		GradingScale: app.config.school.gradingScale,
+		Currency:     app.config.school.currency,
+
+		PasswordPolicy: app.dynamic.Load().password,
+	}
+	if email != "" {
+		resp["avatar_url"] = avatarURL(email)
+	}
+}
+
+// avatarURL returns the Gravatar image for email, falling back to a
+// generated pattern when the address has none.
+func avatarURL(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "https://www.gravatar.com/avatar/" + hex.EncodeToString(sum[:]) + "?d=identicon"
+}
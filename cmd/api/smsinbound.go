@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+const absenceNotifyJob = "absence_notify"
+
+// A reply is matched to the last text sent to its number within
+// smsReplyWindow; older notices are considered answered or stale.
+const smsReplyWindow = 7 * 24 * time.Hour
+
+// notifyAbsences texts the parents of any of studentIDs marked absent on
+// date who have not been told yet. It runs in the background so marking
+// attendance never waits on the SMS provider.
+func (app *application) notifyAbsences(r *http.Request, date time.Time, studentIDs []int64) {
+	if !app.dynamic.Load().absenceSMS.enabled || len(studentIDs) == 0 {
+		return
+	}
+	user := getUser(r)
+	_, err := app.jobs.Enqueue(absenceNotifyJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		notices, err := app.store.SMSMessages.UnnotifiedAbsences(ctx, date, studentIDs)
+		if err != nil {
+			return nil, err
+		}
+		sent := 0
+		for _, n := range notices {
+			text := fmt.Sprintf("%s %s was marked absent on %s. Reply 1 if the absence is excused.",
+				n.FirstName, n.LastName, n.Date.Format(time.DateOnly))
+			smsErr := app.sms.Send(ctx, n.ParentPhone, text)
+			if smsErr != nil {
+				app.logger.Warnw("absence sms failed", "attendance", n.AttendanceID, "error", smsErr.Error())
+			}
+			// replies are only matched on SMS; Telegram gets a copy for
+			// parents who linked it
+			alert := fmt.Sprintf("%s %s was marked absent on %s.", n.FirstName, n.LastName, n.Date.Format(time.DateOnly))
+			chats, err := app.notifyTelegram(ctx, []string{n.ParentPhone}, alert)
+			if err != nil {
+				app.logger.Warnw("absence telegram failed", "attendance", n.AttendanceID, "error", err.Error())
+			}
+			if smsErr != nil && chats == 0 {
+				continue
+			}
+			msg := &store.SMSMessage{
+				PhoneNumber:  n.ParentPhone,
+				Direction:    "out",
+				Kind:         store.SMSKindAbsence,
+				Body:         text,
+				StudentID:    &n.StudentID,
+				AttendanceID: &n.AttendanceID,
+			}
+			if err := app.store.SMSMessages.Record(ctx, msg); err != nil {
+				app.logger.Warnw("recording absence sms failed", "attendance", n.AttendanceID, "error", err.Error())
+			}
+			sent++
+		}
+		return json.Marshal(map[string]int{"sent": sent})
+	})
+	if err != nil {
+		app.logger.Warnw("queueing absence notices failed", "date", date.Format(time.DateOnly), "error", err.Error())
+	}
+}
+
+// InboundSMS godoc
+//
+//	@Summary		Receive a text from the SMS provider
+//	@Description	Callback for replies to the school's number. The sender is matched to the last text sent to them in the past week; replying "1" to an absence notice excuses the absence and the parent gets a confirmation. Every reply is kept in the sender's thread. Requires the configured secret as ?secret=.
+//	@Tags			SMS
+//	@Accept			x-www-form-urlencoded
+//	@Param			secret		query	string	true	"Webhook secret"
+//	@Param			from		formData	string	true	"Sender number"
+//	@Param			message		formData	string	true	"Message text"
+//	@Param			messageid	formData	string	false	"Provider message ID"
+//	@Success		200
+//	@Failure		400	{object}	error
+//	@Failure		401	{object}	error
+//	@Router			/webhooks/sms [post]
+//	@ID				inboundSMS
+func (app *application) inboundSMSHandler(w http.ResponseWriter, r *http.Request) {
+	secret := app.dynamic.Load().absenceSMS.webhookSecret
+	if secret == "" || !hmac.Equal([]byte(r.URL.Query().Get("secret")), []byte(secret)) {
+		app.unauthorizedResponse(w, r, errors.New("invalid webhook secret"))
+		return
+	}
+
+	// Kavenegar posts a form, or sends the same fields as a query string
+	phone := normalizePhone(r.FormValue("from"))
+	body := strings.TrimSpace(r.FormValue("message"))
+	if phone == "" || body == "" {
+		app.badRequestResponse(w, r, errors.New("from and message are required"))
+		return
+	}
+
+	ctx := r.Context()
+	msg := &store.SMSMessage{
+		PhoneNumber: phone,
+		Direction:   "in",
+		Kind:        store.SMSKindReply,
+		Body:        body,
+		ProviderID:  r.FormValue("messageid"),
+	}
+
+	original, err := app.store.SMSMessages.LatestOutbound(ctx, phone, time.Now().Add(-smsReplyWindow))
+	switch {
+	case err == nil:
+		msg.ReplyTo = &original.ID
+		msg.StudentID = original.StudentID
+		msg.AttendanceID = original.AttendanceID
+	case errors.Is(err, store.ErrNotFound):
+		// not an answer to anything; it still goes in the thread
+	default:
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	var confirmation string
+	if original != nil && original.Kind == store.SMSKindAbsence && original.AttendanceID != nil && isExcuseReply(body) {
+		switch err := app.store.Attendance.Excuse(ctx, *original.AttendanceID); {
+		case err == nil:
+			msg.Action = "excused"
+			confirmation = "Thank you, the absence has been recorded as excused."
+		case errors.Is(err, store.ErrNotFound):
+			// already excused, changed by staff or deleted
+		default:
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if err := app.store.SMSMessages.Record(ctx, msg); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if confirmation != "" {
+		if err := app.sms.Send(ctx, phone, confirmation); err != nil {
+			app.logger.Warnw("absence confirmation sms failed", "message", msg.ID, "error", err.Error())
+		} else {
+			reply := &store.SMSMessage{
+				PhoneNumber:  phone,
+				Direction:    "out",
+				Kind:         store.SMSKindConfirmation,
+				Body:         confirmation,
+				StudentID:    msg.StudentID,
+				AttendanceID: msg.AttendanceID,
+				ReplyTo:      &msg.ID,
+			}
+			if err := app.store.SMSMessages.Record(ctx, reply); err != nil {
+				app.logger.Warnw("recording confirmation sms failed", "message", msg.ID, "error", err.Error())
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// isExcuseReply reports whether body is the "1" asked for by an absence
+// notice, in Latin or Persian digits.
+func isExcuseReply(body string) bool {
+	return body == "1" || body == "۱"
+}
+
+// normalizePhone turns the formats SMS providers report senders in into
+// E.164, which is how parent numbers are stored. Local Iranian numbers
+// (09...) get the +98 country code.
+func normalizePhone(phone string) string {
+	phone = strings.Map(func(r rune) rune {
+		if r >= '۰' && r <= '۹' {
+			return '0' + (r - '۰')
+		}
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+
+	switch {
+	case phone == "", strings.HasPrefix(phone, "+"):
+		return phone
+	case strings.HasPrefix(phone, "00"):
+		return "+" + phone[2:]
+	case strings.HasPrefix(phone, "0"):
+		return "+98" + phone[1:]
+	default:
+		return "+" + phone
+	}
+}
+
+// SMSThread godoc
+//
+//	@Summary	Get the texts exchanged with a phone number
+//	@Tags		SMS
+//	@Produce	json
+//	@Param		phone	query		string	true	"Phone number"
+//	@Success	200		{array}		dto.SMSMessage
+//	@Failure	400		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/sms/threads [get]
+//	@ID			getSMSThread
+func (app *application) getSMSThreadHandler(w http.ResponseWriter, r *http.Request) {
+	phone := normalizePhone(r.URL.Query().Get("phone"))
+	if phone == "" {
+		app.badRequestResponse(w, r, errors.New("phone is required"))
+		return
+	}
+
+	messages, err := app.store.SMSMessages.Thread(r.Context(), phone)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSMSMessages(messages)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
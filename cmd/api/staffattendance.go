@@ -0,0 +1,383 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// staffAttendancePolicy decides where teachers may mark their own
+// attendance from. Empty networks allow any address.
+type staffAttendancePolicy struct {
+	networks      []netip.Prefix
+	requireDevice bool
+	lateAfter     time.Duration
+}
+
+func newStaffAttendancePolicy(cfg staffAttendanceConfig) (*staffAttendancePolicy, error) {
+	networks, err := parsePrefixes(cfg.networks)
+	if err != nil {
+		return nil, fmt.Errorf("STAFF_ATTENDANCE_NETWORKS: %w", err)
+	}
+	lateAfter, err := parseClock(cfg.lateAfter)
+	if err != nil {
+		return nil, fmt.Errorf("STAFF_LATE_AFTER: %w", err)
+	}
+	return &staffAttendancePolicy{networks: networks, requireDevice: cfg.requireDevice, lateAfter: lateAfter}, nil
+}
+
+type StaffAttendancePayload struct {
+	// DeviceID is the identifier the app registered for this device.
+	DeviceID string `json:"device_id" validate:"omitempty,max=128"`
+}
+
+type RegisterStaffDevicePayload struct {
+	TeacherID int64  `json:"teacher_id" validate:"required,min=1" example:"7"`
+	DeviceID  string `json:"device_id" validate:"required,max=128"`
+	Name      string `json:"name" validate:"max=64"`
+}
+
+// checkStaffPresence applies the policy to a self-marking request and
+// returns the caller's address and registered device, if any. It writes
+// the error response and returns false when the request is refused.
+func (app *application) checkStaffPresence(w http.ResponseWriter, r *http.Request) (string, *int64, bool) {
+	policy := app.staffAttendance
+	if policy == nil {
+		app.notfoundResponse(w, r, errors.New("staff attendance is not enabled"))
+		return "", nil, false
+	}
+
+	var payload StaffAttendancePayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return "", nil, false
+		}
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return "", nil, false
+	}
+
+	addr, ok := clientAddr(r)
+	if len(policy.networks) > 0 && (!ok || !prefixesContain(policy.networks, addr)) {
+		app.badRequestResponse(w, r, errors.New("connect to the school network to mark attendance"))
+		return "", nil, false
+	}
+	ip := ""
+	if ok {
+		ip = addr.String()
+	}
+
+	var deviceID *int64
+	if payload.DeviceID != "" {
+		id, err := app.store.StaffAttendance.UseDevice(r.Context(), getUser(r).ID, payload.DeviceID)
+		switch {
+		case err == nil:
+			deviceID = &id
+		case errors.Is(err, store.ErrNotFound):
+			if policy.requireDevice {
+				app.badRequestResponse(w, r, errors.New("this device is not registered to you"))
+				return "", nil, false
+			}
+		default:
+			app.internalServerErrorResponse(w, r, err)
+			return "", nil, false
+		}
+	}
+	if policy.requireDevice && deviceID == nil {
+		app.badRequestResponse(w, r, errors.New("device_id is required"))
+		return "", nil, false
+	}
+
+	return ip, deviceID, true
+}
+
+// StaffArrive godoc
+//
+//	@Summary		Mark the calling teacher's arrival
+//	@Description	Accepted on school days from the school network (STAFF_ATTENDANCE_NETWORKS) and, with STAFF_ATTENDANCE_REQUIRE_DEVICE, from a registered device. Arrivals after STAFF_LATE_AFTER are flagged late.
+//	@Tags			Staff attendance
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		StaffAttendancePayload	false	"Device"
+//	@Success		201		{object}	dto.StaffAttendance
+//	@Failure		400		{object}	error	"Outside the school network or not a registered device"
+//	@Failure		409		{object}	error	"Already arrived today"
+//	@Security		ApiKeyAuth
+//	@Router			/staff-attendance/arrive [post]
+//	@ID				staffArrive
+func (app *application) staffArriveHandler(w http.ResponseWriter, r *http.Request) {
+	ip, deviceID, ok := app.checkStaffPresence(w, r)
+	if !ok {
+		return
+	}
+
+	today := app.schoolToday()
+	if !app.requireSchoolDay(w, r, today) {
+		return
+	}
+
+	user := getUser(r)
+	record := &store.StaffAttendance{
+		TeacherID:       user.ID,
+		Date:            today,
+		Late:            app.school.now().Sub(today) > app.staffAttendance.lateAfter,
+		ArrivalIP:       ip,
+		ArrivalDeviceID: deviceID,
+	}
+	if err := app.store.StaffAttendance.Arrive(r.Context(), record); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("already marked arrival today"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromStaffAttendance(record)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// StaffDepart godoc
+//
+//	@Summary		Mark the calling teacher's departure
+//	@Description	Same network and device rules as arrival.
+//	@Tags			Staff attendance
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		StaffAttendancePayload	false	"Device"
+//	@Success		200		{object}	dto.StaffAttendance
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error	"No arrival today"
+//	@Failure		409		{object}	error	"Already departed today"
+//	@Security		ApiKeyAuth
+//	@Router			/staff-attendance/depart [post]
+//	@ID				staffDepart
+func (app *application) staffDepartHandler(w http.ResponseWriter, r *http.Request) {
+	ip, deviceID, ok := app.checkStaffPresence(w, r)
+	if !ok {
+		return
+	}
+
+	record, err := app.store.StaffAttendance.Depart(r.Context(), getUser(r).ID, app.schoolToday(), ip, deviceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, errors.New("no arrival marked today"))
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("already marked departure today"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStaffAttendance(record)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListStaffAttendance godoc
+//
+//	@Summary		List staff arrivals and departures
+//	@Description	Teachers only see their own records. Defaults to the last 30 days.
+//	@Tags			Staff attendance
+//	@Produce		json
+//	@Param			from		query		string	false	"Start date (YYYY-MM-DD)"
+//	@Param			to			query		string	false	"End date (YYYY-MM-DD)"
+//	@Param			teacher_id	query		int		false	"Only this teacher (execs)"
+//	@Success		200			{array}		dto.StaffAttendance
+//	@Failure		400			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/staff-attendance [get]
+//	@ID				listStaffAttendance
+func (app *application) listStaffAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var teacherID int64
+	if user := getUser(r); user.Role == "teacher" {
+		teacherID = user.ID
+	} else if v := r.URL.Query().Get("teacher_id"); v != "" {
+		teacherID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || teacherID < 1 {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid teacher_id %q", v))
+			return
+		}
+	}
+
+	records, err := app.store.StaffAttendance.List(r.Context(), teacherID, from, to)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStaffAttendances(records)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// StaffAttendanceReport godoc
+//
+//	@Summary		Staff attendance report
+//	@Description	Per teacher: school days in the period (up to today), days present, late and absent, days without a departure and hours on site. Defaults to the last 30 days.
+//	@Tags			Staff attendance
+//	@Produce		json
+//	@Param			from	query		string	false	"Start date (YYYY-MM-DD)"
+//	@Param			to		query		string	false	"End date (YYYY-MM-DD)"
+//	@Success		200		{array}		dto.StaffAttendanceSummary
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/staff-attendance/report [get]
+//	@ID				staffAttendanceReport
+func (app *application) staffAttendanceReportHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	report, err := app.store.StaffAttendance.Report(r.Context(), from, to, app.schoolToday())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStaffAttendanceSummaries(report)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListStaffDevices godoc
+//
+//	@Summary	List devices registered for staff attendance
+//	@Tags		Staff attendance
+//	@Produce	json
+//	@Param		teacher_id	query		int	false	"Only this teacher"
+//	@Success	200			{array}		dto.StaffDevice
+//	@Failure	400			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/staff-attendance/devices [get]
+//	@ID			listStaffDevices
+func (app *application) listStaffDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	var teacherID int64
+	if v := r.URL.Query().Get("teacher_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid teacher_id %q", v))
+			return
+		}
+		teacherID = id
+	}
+
+	devices, err := app.store.StaffAttendance.Devices(r.Context(), teacherID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStaffDevices(devices)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RegisterStaffDevice godoc
+//
+//	@Summary		Register a teacher's device for staff attendance
+//	@Description	device_id is the identifier the teacher's app shows. A device belongs to one teacher.
+//	@Tags			Staff attendance
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RegisterStaffDevicePayload	true	"Device"
+//	@Success		201		{object}	dto.StaffDevice
+//	@Failure		400		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Device already registered"
+//	@Security		ApiKeyAuth
+//	@Router			/staff-attendance/devices [post]
+//	@ID				registerStaffDevice
+func (app *application) registerStaffDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RegisterStaffDevicePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	teacher, err := app.store.Teachers.GetByID(r.Context(), payload.TeacherID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	device := &store.StaffDevice{
+		TeacherID:   teacher.ID,
+		TeacherName: teacher.FirstName + " " + teacher.LastName,
+		DeviceID:    payload.DeviceID,
+		Name:        payload.Name,
+	}
+	if err := app.store.StaffAttendance.AddDevice(r.Context(), device); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("device is already registered"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromStaffDevice(device)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteStaffDevice godoc
+//
+//	@Summary	Unregister a staff attendance device
+//	@Tags		Staff attendance
+//	@Param		deviceID	path	int	true	"Device ID"
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/staff-attendance/devices/{deviceID} [delete]
+//	@ID			deleteStaffDevice
+func (app *application) deleteStaffDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "deviceID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.StaffAttendance.DeleteDevice(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"github.com/MahdiiTaheri/classnama-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -17,39 +19,45 @@ type studentKey string
 const studentCtx studentKey = "student"
 
 type UpdateStudentPayload struct {
-	FirstName         *string `json:"first_name,omitempty" validate:"omitempty,max=72"`
-	LastName          *string `json:"last_name,omitempty" validate:"omitempty,max=72"`
-	Email             *string `json:"email,omitempty" validate:"omitempty,email"`
-	PhoneNumber       *string `json:"phone_number,omitempty" validate:"omitempty,e164"`
-	ClassRoomID       *int64  `json:"classroom_id,omitempty" validate:"omitempty,max=16"`
-	BirthDate         *string `json:"birth_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
-	Address           *string `json:"address,omitempty" validate:"omitempty,max=256"`
-	ParentName        *string `json:"parent_name,omitempty" validate:"omitempty,max=128"`
-	ParentPhoneNumber *string `json:"parent_phone_number,omitempty" validate:"omitempty,e164"`
-	TeacherID         *int64  `json:"teacher_id,omitempty" validate:"omitempty"`
+	FirstName         *string `json:"first_name,omitempty" validate:"omitempty,max=72" example:"Sara"`
+	LastName          *string `json:"last_name,omitempty" validate:"omitempty,max=72" example:"Ahmadi"`
+	Email             *string `json:"email,omitempty" validate:"omitempty,email" example:"sara.ahmadi@example.com"`
+	PhoneNumber       *string `json:"phone_number,omitempty" validate:"omitempty,e164" example:"+989121234567"`
+	ClassRoomID       *int64  `json:"classroom_id,omitempty" validate:"omitempty,max=16" example:"3"`
+	BirthDate         *string `json:"birth_date,omitempty" validate:"omitempty,datetime=2006-01-02" example:"2011-05-14"`
+	Address           *string `json:"address,omitempty" validate:"omitempty,max=256" example:"12 Azadi St, Tehran"`
+	ParentName        *string `json:"parent_name,omitempty" validate:"omitempty,max=128" example:"Reza Ahmadi"`
+	ParentPhoneNumber *string `json:"parent_phone_number,omitempty" validate:"omitempty,e164" example:"+989351234567"`
+	TeacherID         *int64  `json:"teacher_id,omitempty" validate:"omitempty" example:"7"`
+	NationalID        *string `json:"national_id,omitempty" validate:"omitempty,national_id" example:"0012345678"`
 }
 
 // GetStudents godoc
 //
 //	@Summary	Get all students
 //	@Tags		Students
-//	@Produce	json
-//	@Success	200	{array}		store.Student
-//	@Failure	500	{object}	error
+//	@Produce	json,text/csv,application/xml
+//	@Param		fields			query		string	false	"Comma-separated fields to return, e.g. id,first_name,last_name"
+//	@Param		tag				query		string	false	"Only records carrying this tag"
+//	@Param		status			query		string	false	"active (has signed in), pending (never has) or deactivated"
+//	@Param		created_from	query		string	false	"Added on or after this date (YYYY-MM-DD)"
+//	@Param		created_to		query		string	false	"Added on or before this date (YYYY-MM-DD)"
+//	@Param		last_login_from	query		string	false	"Last signed in on or after this date (YYYY-MM-DD)"
+//	@Param		last_login_to	query		string	false	"Last signed in on or before this date (YYYY-MM-DD)"
+//	@Success	200				{array}		dto.Student
+//	@Failure	400				{object}	error
+//	@Failure	500				{object}	error
 //	@Security	ApiKeyAuth
 //	@Router		/students [get]
 //	@ID			getStudents
 func (app *application) getStudentsHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	app.listStudents(w, r, v1Lists)
+}
 
-	pq := store.PaginatedQuery{
-		Limit:  10,
-		Offset: 0,
-		SortBy: "id",
-		Order:  "asc",
-	}
+func (app *application) listStudents(w http.ResponseWriter, r *http.Request, lists listAdapter) {
+	ctx := r.Context()
 
-	pq, err := pq.Parse(r)
+	pq, err := lists.parse(r, defaultListQuery)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -59,30 +67,41 @@ func (app *application) getStudentsHandler(w http.ResponseWriter, r *http.Reques
 		app.badRequestResponse(w, r, err)
 		return
 	}
-
-	params := map[string]any{
-		"limit":  pq.Limit,
-		"offset": pq.Offset,
-		"sort":   pq.SortBy,
-		"order":  pq.Order,
+	if pq.Account, err = app.accountFilter(r); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
+	pq.Scope = getScope(r)
+	params := listCacheParams(pq)
+
 	students, err := cache.GetListWithCache(
 		ctx,
-		app.cacheStorage.Execs,
+		app.cacheStorage.Students,
 		"students:list",
 		params,
-		func(ctx context.Context) ([]*store.Exec, error) {
-			return app.store.Execs.GetAll(ctx, pq)
+		func(ctx context.Context) ([]*store.Student, error) {
+			return app.store.Students.GetAll(ctx, pq)
 		},
 	)
 
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidField) {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	data, err := filterFields(dto.FromStudents(app.viewer(r), students), pq.Fields)
 	if err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, students); err != nil {
+	last := lastID(students, func(s *store.Student) int64 { return s.ID })
+	if err := lists.write(w, r, pq, data, len(students), last); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -94,7 +113,7 @@ func (app *application) getStudentsHandler(w http.ResponseWriter, r *http.Reques
 //	@Tags		Students
 //	@Produce	json
 //	@Param		studentID	path		int	true	"student ID"
-//	@Success	200			{object}	store.Student
+//	@Success	200			{object}	dto.Student
 //	@Failure	404			{object}	error
 //	@Failure	500			{object}	error
 //	@Security	ApiKeyAuth
@@ -107,28 +126,63 @@ func (app *application) getStudentHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, student); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudent(app.viewer(r), student)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 }
 
-// UpdateStudent godoc
+// GetStudentByCode godoc
 //
-//	@Summary	Update a student
+//	@Summary	Get a student by student code
 //	@Tags		Students
-//	@Accept		json
 //	@Produce	json
-//	@Param		studentID	path		int						true	"student ID"
-//	@Param		payload		body		UpdateStudentPayload	true	"student update payload"
-//	@Success	200			{object}	store.Student
-//	@Failure	400			{object}	error
-//	@Failure	404			{object}	error
-//	@Failure	409			{object}	error
-//	@Failure	500			{object}	error
+//	@Param		code	path		string	true	"Student code"
+//	@Success	200		{object}	dto.Student
+//	@Failure	404		{object}	error
+//	@Failure	500		{object}	error
 //	@Security	ApiKeyAuth
-//	@Router		/students/{studentID} [patch]
-//	@ID			updateStudent
+//	@Router		/students/by-code/{code} [get]
+//	@ID			getStudentByCode
+func (app *application) getStudentByCodeHandler(w http.ResponseWriter, r *http.Request) {
+	student, err := app.store.Students.GetByCode(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	ok, err := app.classroomInScope(r, student.ClassRoomID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudent(app.viewer(r), student)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+}
+
+// UpdateStudent godoc
+//
+//	@Summary		Update a student
+//	@Description	A new email only takes effect once confirmed from a link sent to it; the old address is notified.
+//	@Tags			Students
+//	@Accept			json
+//	@Produce		json
+//	@Param			studentID	path		int						true	"student ID"
+//	@Param			payload		body		UpdateStudentPayload	true	"student update payload"
+//	@Success		200			{object}	dto.Student
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID} [patch]
+//	@ID				updateStudent
 func (app *application) updateStudentHandler(w http.ResponseWriter, r *http.Request) {
 	student := getStudentFromCtx(r)
 	if student == nil {
@@ -147,23 +201,113 @@ func (app *application) updateStudentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Apply non-nil fields using reflection
-	// utils.ApplyPatch(student, payload)
+	if payload.ClassRoomID != nil {
+		ok, err := app.classroomInScope(r, *payload.ClassRoomID)
+		if !app.checkScope(w, r, ok, err) {
+			return
+		}
+	}
 
-	// Update in DB
-	if err := app.store.Students.Update(r.Context(), student); err != nil {
+	// A new email waits for confirmation; only the other provided columns
+	// are written
+	fields := utils.PatchFields(payload)
+	if !app.startEmailChange(w, r, "student", student.ID, student.FirstName, student.Email, fields) {
+		return
+	}
+	ctx := r.Context()
+	if err := app.store.Students.UpdatePartial(ctx, student.ID, fields); err != nil {
 		switch err {
 		case store.ErrNotFound:
 			app.notfoundResponse(w, r, err)
 			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, fmt.Errorf("email or national ID already registered"))
+			return
 		default:
 			app.internalServerErrorResponse(w, r, err)
 			return
 		}
 	}
 
+	updated, err := app.store.Students.GetByID(ctx, student.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordChanges(r, "student", student.ID, student, updated)
+
 	// Return updated student
-	if err := app.jsonResponse(w, http.StatusOK, student); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudent(app.viewer(r), updated)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+}
+
+// MergeStudents godoc
+//
+//	@Summary		Merge a duplicate student into this one
+//	@Description	Moves the duplicate's attendance to this student, fills this student's missing national ID and phone from it, and moves the duplicate to the trash. Attendance on dates this student already has stays with the duplicate.
+//	@Tags			Students
+//	@Produce		json
+//	@Param			studentID	path		int	true	"Surviving student ID"
+//	@Param			otherID		path		int	true	"Duplicate student ID"
+//	@Success		200			{object}	dto.StudentMerge
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID}/merge/{otherID} [post]
+//	@ID				mergeStudents
+func (app *application) mergeStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	student := getStudentFromCtx(r)
+	if student == nil {
+		app.notfoundResponse(w, r, fmt.Errorf("student not found"))
+		return
+	}
+
+	otherID, err := strconv.ParseInt(chi.URLParam(r, "otherID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	other, err := app.store.Students.GetByID(r.Context(), otherID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	ok, err := app.classroomInScope(r, other.ClassRoomID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
+
+	merge, err := app.store.Students.Merge(r.Context(), student.ID, otherID, getUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrSelfMerge):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.logger.Infow("students merged",
+		"survivor_id", merge.SurvivorID,
+		"duplicate_id", merge.DuplicateID,
+		"merged_by", merge.MergedBy,
+		"attendance_moved", merge.AttendanceMoved,
+	)
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudentMerge(merge)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -171,15 +315,18 @@ func (app *application) updateStudentHandler(w http.ResponseWriter, r *http.Requ
 
 // DeleteStudent godoc
 //
-//	@Summary	Delete a student
-//	@Tags		Students
-//	@Param		studentID	path	int	true	"student ID"
-//	@Success	204			"No Content"
-//	@Failure	404			{object}	error
-//	@Failure	500			{object}	error
-//	@Security	ApiKeyAuth
-//	@Router		/students/{studentID} [delete]
-//	@ID			deleteStudent
+//	@Summary		Delete a student
+//	@Description	With student_delete in APPROVAL_REQUIRED_ACTIONS, a student with related records (see /dependencies) is not deleted but staged until a second admin approves it at /approvals/{id}/approve.
+//	@Tags			Students
+//	@Produce		json
+//	@Param			studentID	path		int				true	"student ID"
+//	@Success		202			{object}	dto.Approval	"Staged for approval"
+//	@Success		204			"No Content"
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID} [delete]
+//	@ID				deleteStudent
 func (app *application) deleteStudentHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "studentID")
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -189,7 +336,28 @@ func (app *application) deleteStudentHandler(w http.ResponseWriter, r *http.Requ
 	}
 	ctx := r.Context()
 
-	if err := app.store.Students.Delete(ctx, id); err != nil {
+	if app.approvals[approvalStudentDelete] {
+		counts, err := app.store.Dependencies.Count(ctx, "students", id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		for _, n := range counts {
+			if n > 0 {
+				student := getStudentFromCtx(r)
+				summary := fmt.Sprintf("Delete student %d (%s %s) and their history", id, student.FirstName, student.LastName)
+				app.stageApproval(w, r, approvalStudentDelete, summary, studentDeleteParams{StudentID: id})
+				return
+			}
+		}
+	}
+
+	if err := app.store.Students.Delete(ctx, id, getUser(r).ID); err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			app.notfoundResponse(w, r, err)
@@ -211,7 +379,7 @@ func (app *application) studentsContextMiddleware(next http.Handler) http.Handle
 			return
 		}
 
-		student, err := app.store.Students.GetByID(r.Context(), id)
+		student, err := lookupByID(app, r.Context(), "students", id, app.store.Students.GetByID)
 		if err != nil {
 			if errors.Is(err, store.ErrNotFound) {
 				app.notfoundResponse(w, r, err)
@@ -221,6 +389,11 @@ func (app *application) studentsContextMiddleware(next http.Handler) http.Handle
 			return
 		}
 
+		ok, err := app.classroomInScope(r, student.ClassRoomID)
+		if !app.checkScope(w, r, ok, err) {
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), studentCtx, student)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type surveyKey string
+
+const surveyCtx surveyKey = "survey"
+
+type CreateSurveyPayload struct {
+	Title       string                 `json:"title" validate:"required,max=200"`
+	Description string                 `json:"description" validate:"max=2000"`
+	Questions   []store.SurveyQuestion `json:"questions" validate:"required,min=1,max=50,dive"`
+	Audience    store.SurveyAudience   `json:"audience"`
+	Status      string                 `json:"status,omitempty" validate:"omitempty,oneof=draft open" enums:"draft,open" example:"draft"`
+	ClosesAt    *time.Time             `json:"closes_at,omitempty"`
+}
+
+type UpdateSurveyPayload struct {
+	Title       *string                 `json:"title,omitempty" validate:"omitempty,max=200"`
+	Description *string                 `json:"description,omitempty" validate:"omitempty,max=2000"`
+	Questions   *[]store.SurveyQuestion `json:"questions,omitempty" validate:"omitempty,min=1,max=50,dive"`
+	Audience    *store.SurveyAudience   `json:"audience,omitempty"`
+	Status      *string                 `json:"status,omitempty" validate:"omitempty,oneof=draft open closed" enums:"draft,open,closed" example:"draft"`
+	ClosesAt    *time.Time              `json:"closes_at,omitempty"`
+}
+
+type SurveyResponsePayload struct {
+	Answers []store.SurveyAnswer `json:"answers" validate:"required,max=50,dive"`
+}
+
+// MySurvey is an open survey offered to the caller.
+type MySurvey struct {
+	*dto.Survey
+	Answered bool `json:"answered"`
+}
+
+type SurveyResults struct {
+	SurveyID  int64             `json:"survey_id"`
+	Responses int               `json:"responses"`
+	Questions []*QuestionResult `json:"questions"`
+}
+
+// QuestionResult aggregates the answers to one question: option or scale
+// value counts, the scale average, or the free-text answers.
+type QuestionResult struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Prompt   string         `json:"prompt"`
+	Answered int            `json:"answered"`
+	Counts   map[string]int `json:"counts,omitempty"`
+	Average  *float64       `json:"average,omitempty"`
+	Texts    []string       `json:"texts,omitempty"`
+}
+
+// checkQuestions validates what struct tags can't: unique IDs, scale bounds
+// and choice options.
+func checkQuestions(questions []store.SurveyQuestion) error {
+	seen := map[string]bool{}
+	for _, q := range questions {
+		if seen[q.ID] {
+			return fmt.Errorf("duplicate question id %q", q.ID)
+		}
+		seen[q.ID] = true
+
+		switch q.Type {
+		case store.QuestionScale:
+			if min, max := q.ScaleBounds(); min >= max {
+				return fmt.Errorf("question %q: min must be below max", q.ID)
+			}
+		case store.QuestionChoice:
+			options := map[string]bool{}
+			for _, o := range q.Options {
+				if options[o] {
+					return fmt.Errorf("question %q: duplicate option %q", q.ID, o)
+				}
+				options[o] = true
+			}
+		}
+	}
+	return nil
+}
+
+// checkAnswers validates a response against the survey's questions.
+func checkAnswers(questions []store.SurveyQuestion, answers []store.SurveyAnswer) error {
+	byID := make(map[string]*store.SurveyQuestion, len(questions))
+	for i := range questions {
+		byID[questions[i].ID] = &questions[i]
+	}
+
+	answered := map[string]bool{}
+	for _, a := range answers {
+		q, ok := byID[a.QuestionID]
+		if !ok {
+			return fmt.Errorf("unknown question %q", a.QuestionID)
+		}
+		if answered[a.QuestionID] {
+			return fmt.Errorf("question %q answered twice", a.QuestionID)
+		}
+		answered[a.QuestionID] = true
+
+		switch q.Type {
+		case store.QuestionChoice:
+			if len(a.Choices) == 0 {
+				return fmt.Errorf("question %q: choose an option", q.ID)
+			}
+			if !q.Multiple && len(a.Choices) > 1 {
+				return fmt.Errorf("question %q: only one option may be chosen", q.ID)
+			}
+			for _, c := range a.Choices {
+				if !containsString(q.Options, c) {
+					return fmt.Errorf("question %q: %q is not an option", q.ID, c)
+				}
+			}
+		case store.QuestionScale:
+			min, max := q.ScaleBounds()
+			if a.Value == nil || *a.Value < min || *a.Value > max {
+				return fmt.Errorf("question %q: value must be between %d and %d", q.ID, min, max)
+			}
+		case store.QuestionText:
+			if a.Text == "" {
+				return fmt.Errorf("question %q: text is empty", q.ID)
+			}
+		}
+	}
+
+	for _, q := range questions {
+		if q.Required && !answered[q.ID] {
+			return fmt.Errorf("question %q is required", q.ID)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// respondentGroups returns the classrooms and grades a student or teacher
+// belongs to, for matching survey audiences.
+func (app *application) respondentGroups(ctx context.Context, role string, id int64) ([]int64, []int64, error) {
+	var classrooms []*store.Classroom
+	switch role {
+	case "student":
+		student, err := app.store.Students.GetByID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		classroom, err := app.store.Classrooms.GetByID(ctx, student.ClassRoomID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, nil, err
+		}
+		if classroom != nil {
+			classrooms = append(classrooms, classroom)
+		}
+	case "teacher":
+		var err error
+		classrooms, err = app.store.Classrooms.GetByTeacherID(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ids := make([]int64, 0, len(classrooms))
+	grades := make([]int64, 0, len(classrooms))
+	for _, c := range classrooms {
+		ids = append(ids, c.ID)
+		grades = append(grades, c.Grade)
+	}
+	return ids, grades, nil
+}
+
+// CreateSurvey godoc
+//
+//	@Summary		Create a survey
+//	@Description	Questions are choice (options, optionally multiple), text or scale (min..max, default 1..5). An empty audience targets every student and teacher.
+//	@Tags			Surveys
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateSurveyPayload	true	"Survey"
+//	@Success		201		{object}	dto.Survey
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/surveys [post]
+//	@ID				createSurvey
+func (app *application) createSurveyHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateSurveyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := checkQuestions(payload.Questions); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	survey := &store.Survey{
+		Title:       payload.Title,
+		Description: payload.Description,
+		Questions:   payload.Questions,
+		Audience:    payload.Audience,
+		Status:      payload.Status,
+		ClosesAt:    payload.ClosesAt,
+		CreatedBy:   getUser(r).ID,
+	}
+	if survey.Status == "" {
+		survey.Status = store.SurveyDraft
+	}
+
+	if err := app.store.Surveys.Create(r.Context(), survey); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromSurvey(survey)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListSurveys godoc
+//
+//	@Summary	List surveys with response counts
+//	@Tags		Surveys
+//	@Produce	json
+//	@Success	200	{array}	dto.Survey
+//	@Security	ApiKeyAuth
+//	@Router		/surveys [get]
+//	@ID			listSurveys
+func (app *application) listSurveysHandler(w http.ResponseWriter, r *http.Request) {
+	surveys, err := app.store.Surveys.List(r.Context(), false)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSurveys(surveys)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetSurvey godoc
+//
+//	@Summary	Get a survey
+//	@Tags		Surveys
+//	@Produce	json
+//	@Param		surveyID	path		int	true	"Survey ID"
+//	@Success	200			{object}	dto.Survey
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/surveys/{surveyID} [get]
+//	@ID			getSurvey
+func (app *application) getSurveyHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSurvey(getSurveyFromCtx(r))); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// UpdateSurvey godoc
+//
+//	@Summary		Update, open or close a survey
+//	@Description	Questions cannot change once the survey has responses.
+//	@Tags			Surveys
+//	@Accept			json
+//	@Produce		json
+//	@Param			surveyID	path		int					true	"Survey ID"
+//	@Param			payload		body		UpdateSurveyPayload	true	"Changes"
+//	@Success		200			{object}	dto.Survey
+//	@Failure		400			{object}	error
+//	@Failure		409			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/surveys/{surveyID} [patch]
+//	@ID				updateSurvey
+func (app *application) updateSurveyHandler(w http.ResponseWriter, r *http.Request) {
+	survey := getSurveyFromCtx(r)
+
+	var payload UpdateSurveyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Questions != nil {
+		if survey.Responses > 0 {
+			app.conflictResponse(w, r, fmt.Errorf("questions cannot change after responses were submitted"))
+			return
+		}
+		if err := checkQuestions(*payload.Questions); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		survey.Questions = *payload.Questions
+	}
+	if payload.Title != nil {
+		survey.Title = *payload.Title
+	}
+	if payload.Description != nil {
+		survey.Description = *payload.Description
+	}
+	if payload.Audience != nil {
+		survey.Audience = *payload.Audience
+	}
+	if payload.Status != nil {
+		survey.Status = *payload.Status
+	}
+	if payload.ClosesAt != nil {
+		survey.ClosesAt = payload.ClosesAt
+	}
+
+	if err := app.store.Surveys.Update(r.Context(), survey); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromSurvey(survey)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteSurvey godoc
+//
+//	@Summary	Delete a survey and its responses
+//	@Tags		Surveys
+//	@Param		surveyID	path	int	true	"Survey ID"
+//	@Success	204			"No Content"
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/surveys/{surveyID} [delete]
+//	@ID			deleteSurvey
+func (app *application) deleteSurveyHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.store.Surveys.Delete(r.Context(), getSurveyFromCtx(r).ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMySurveys godoc
+//
+//	@Summary	List open surveys addressed to the caller
+//	@Tags		Surveys
+//	@Produce	json
+//	@Success	200	{array}	MySurvey
+//	@Security	ApiKeyAuth
+//	@Router		/me/surveys [get]
+//	@ID			getMySurveys
+func (app *application) getMySurveysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getUser(r)
+
+	classroomIDs, grades, err := app.respondentGroups(ctx, user.Role, user.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	surveys, err := app.store.Surveys.List(ctx, true)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	answered, err := app.store.Surveys.Answered(ctx, user.Role, user.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	mine := []*MySurvey{}
+	for _, s := range surveys {
+		if s.Audience.Targets(user.Role, classroomIDs, grades) {
+			s.Responses = 0 // counts are for execs
+			mine = append(mine, &MySurvey{Survey: dto.FromSurvey(s), Answered: answered[s.ID]})
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, mine); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// SubmitSurveyResponse godoc
+//
+//	@Summary	Answer a survey
+//	@Tags		Surveys
+//	@Accept		json
+//	@Produce	json
+//	@Param		surveyID	path		int						true	"Survey ID"
+//	@Param		payload		body		SurveyResponsePayload	true	"Answers"
+//	@Success	201			{object}	dto.SurveyResponse
+//	@Failure	400			{object}	error
+//	@Failure	403			{object}	error
+//	@Failure	409			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/surveys/{surveyID}/responses [post]
+//	@ID			submitSurveyResponse
+func (app *application) submitSurveyResponseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	survey := getSurveyFromCtx(r)
+	user := getUser(r)
+
+	if !survey.IsOpen(time.Now()) {
+		app.conflictResponse(w, r, fmt.Errorf("survey is not open"))
+		return
+	}
+
+	classroomIDs, grades, err := app.respondentGroups(ctx, user.Role, user.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if !survey.Audience.Targets(user.Role, classroomIDs, grades) {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	var payload SurveyResponsePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := checkAnswers(survey.Questions, payload.Answers); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	response := &store.SurveyResponse{
+		SurveyID:       survey.ID,
+		RespondentID:   user.ID,
+		RespondentRole: user.Role,
+		Answers:        payload.Answers,
+	}
+	if err := app.store.Surveys.Respond(ctx, response); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("survey already answered"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromSurveyResponse(response)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetSurveyResults godoc
+//
+//	@Summary	Aggregated survey results
+//	@Tags		Surveys
+//	@Produce	json
+//	@Param		surveyID	path		int	true	"Survey ID"
+//	@Success	200			{object}	SurveyResults
+//	@Security	ApiKeyAuth
+//	@Router		/surveys/{surveyID}/results [get]
+//	@ID			getSurveyResults
+func (app *application) getSurveyResultsHandler(w http.ResponseWriter, r *http.Request) {
+	survey := getSurveyFromCtx(r)
+
+	responses, err := app.store.Surveys.Responses(r.Context(), survey.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, aggregateSurvey(survey, responses)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// aggregateSurvey tallies responses per question in question order.
+func aggregateSurvey(survey *store.Survey, responses []*store.SurveyResponse) *SurveyResults {
+	results := &SurveyResults{SurveyID: survey.ID, Responses: len(responses), Questions: []*QuestionResult{}}
+
+	byID := map[string]*QuestionResult{}
+	sums := map[string]int{}
+	for _, q := range survey.Questions {
+		qr := &QuestionResult{ID: q.ID, Type: q.Type, Prompt: q.Prompt}
+		switch q.Type {
+		case store.QuestionChoice:
+			qr.Counts = map[string]int{}
+			for _, o := range q.Options {
+				qr.Counts[o] = 0
+			}
+		case store.QuestionScale:
+			qr.Counts = map[string]int{}
+			min, max := q.ScaleBounds()
+			for v := min; v <= max; v++ {
+				qr.Counts[strconv.Itoa(v)] = 0
+			}
+		case store.QuestionText:
+			qr.Texts = []string{}
+		}
+		byID[q.ID] = qr
+		results.Questions = append(results.Questions, qr)
+	}
+
+	for _, resp := range responses {
+		for _, a := range resp.Answers {
+			qr, ok := byID[a.QuestionID]
+			if !ok {
+				continue
+			}
+			qr.Answered++
+			switch qr.Type {
+			case store.QuestionChoice:
+				for _, c := range a.Choices {
+					qr.Counts[c]++
+				}
+			case store.QuestionScale:
+				if a.Value != nil {
+					qr.Counts[strconv.Itoa(*a.Value)]++
+					sums[qr.ID] += *a.Value
+				}
+			case store.QuestionText:
+				qr.Texts = append(qr.Texts, a.Text)
+			}
+		}
+	}
+
+	for _, qr := range results.Questions {
+		if qr.Type == store.QuestionScale && qr.Answered > 0 {
+			avg := float64(sums[qr.ID]) / float64(qr.Answered)
+			qr.Average = &avg
+		}
+	}
+	return results
+}
+
+// ------------------- Middleware -------------------
+
+func (app *application) surveyContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "surveyID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		survey, err := app.store.Surveys.GetByID(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), surveyCtx, survey)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getSurveyFromCtx(r *http.Request) *store.Survey {
+	s, _ := r.Context().Value(surveyCtx).(*store.Survey)
+	return s
+}
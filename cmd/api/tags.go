@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+type CreateNotePayload struct {
+	Body string `json:"body" validate:"required,max=4000"`
+}
+
+// recordIDFromCtx returns the ID of the record a context middleware loaded
+// for entity (a table name).
+func recordIDFromCtx(r *http.Request, entity string) (int64, error) {
+	switch entity {
+	case "students":
+		if s := getStudentFromCtx(r); s != nil {
+			return s.ID, nil
+		}
+	case "teachers":
+		if t := getTeacherFromCtx(r); t != nil {
+			return t.ID, nil
+		}
+	case "classrooms":
+		if c := getClassroomFromCtx(r); c != nil {
+			return c.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("%s record not found", entity)
+}
+
+// tagParam reads and normalizes the {tag} URL parameter.
+func tagParam(r *http.Request) (string, error) {
+	tag := store.NormalizeTag(chi.URLParam(r, "tag"))
+	if err := Validate.Var(tag, "required,max=64"); err != nil {
+		return "", fmt.Errorf("invalid tag: %w", err)
+	}
+	return tag, nil
+}
+
+// ListTags godoc
+//
+//	@Summary	List tags with usage counts
+//	@Tags		Tags
+//	@Produce	json
+//	@Success	200	{array}		dto.Tag
+//	@Failure	500	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/tags [get]
+//	@ID			listTags
+func (app *application) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := app.store.Tags.List(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTags(tags)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteTag godoc
+//
+//	@Summary	Delete a tag and remove it from every record
+//	@Tags		Tags
+//	@Param		tag	path	string	true	"Tag name"
+//	@Success	204	"No Content"
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/tags/{tag} [delete]
+//	@ID			deleteTag
+func (app *application) deleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := tagParam(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Tags.Delete(r.Context(), tag); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRecordTags godoc
+//
+//	@Summary	List the tags on a student or teacher
+//	@Tags		Tags
+//	@Produce	json
+//	@Param		id	path		int	true	"Student or teacher ID"
+//	@Success	200	{array}		string
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{id}/tags [get]
+//	@Router		/teachers/{id}/tags [get]
+//	@ID			getRecordTags
+func (app *application) getRecordTagsHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+
+		tags, err := app.store.Tags.ForEntity(r.Context(), entity, id)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// AddRecordTag godoc
+//
+//	@Summary		Tag a student or teacher
+//	@Description	The tag is created on first use. Tagging a record twice is a no-op.
+//	@Tags			Tags
+//	@Param			id	path	int		true	"Student or teacher ID"
+//	@Param			tag	path	string	true	"Tag name"
+//	@Success		204	"No Content"
+//	@Failure		400	{object}	error
+//	@Failure		404	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{id}/tags/{tag} [put]
+//	@Router			/teachers/{id}/tags/{tag} [put]
+//	@ID				addRecordTag
+func (app *application) addRecordTagHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+		tag, err := tagParam(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if err := app.store.Tags.Assign(r.Context(), entity, id, tag, getUser(r).ID); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RemoveRecordTag godoc
+//
+//	@Summary	Remove a tag from a student or teacher
+//	@Tags		Tags
+//	@Param		id	path	int		true	"Student or teacher ID"
+//	@Param		tag	path	string	true	"Tag name"
+//	@Success	204	"No Content"
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{id}/tags/{tag} [delete]
+//	@Router		/teachers/{id}/tags/{tag} [delete]
+//	@ID			removeRecordTag
+func (app *application) removeRecordTagHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+		tag, err := tagParam(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if err := app.store.Tags.Unassign(r.Context(), entity, id, tag); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetRecordNotes godoc
+//
+//	@Summary	List staff notes on a student or teacher
+//	@Tags		Notes
+//	@Produce	json
+//	@Param		id	path		int	true	"Student or teacher ID"
+//	@Success	200	{array}		dto.Note
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{id}/notes [get]
+//	@Router		/teachers/{id}/notes [get]
+//	@ID			getRecordNotes
+func (app *application) getRecordNotesHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+
+		notes, err := app.store.Notes.ForEntity(r.Context(), entity, id)
+		if err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusOK, dto.FromNotes(notes)); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// CreateRecordNote godoc
+//
+//	@Summary	Add a staff note to a student or teacher
+//	@Tags		Notes
+//	@Accept		json
+//	@Produce	json
+//	@Param		id		path		int					true	"Student or teacher ID"
+//	@Param		payload	body		CreateNotePayload	true	"Note"
+//	@Success	201		{object}	dto.Note
+//	@Failure	400		{object}	error
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{id}/notes [post]
+//	@Router		/teachers/{id}/notes [post]
+//	@ID			createRecordNote
+func (app *application) createRecordNoteHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+
+		var payload CreateNotePayload
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if err := Validate.Struct(payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user := getUser(r)
+		note := &store.Note{
+			Entity:     entity,
+			EntityID:   id,
+			Body:       payload.Body,
+			AuthorID:   user.ID,
+			AuthorRole: user.Role,
+		}
+		if err := app.store.Notes.Create(r.Context(), note); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+			return
+		}
+
+		if err := app.jsonResponse(w, http.StatusCreated, dto.FromNote(note)); err != nil {
+			app.internalServerErrorResponse(w, r, err)
+		}
+	}
+}
+
+// DeleteRecordNote godoc
+//
+//	@Summary	Delete a staff note
+//	@Tags		Notes
+//	@Param		id		path	int	true	"Student or teacher ID"
+//	@Param		noteID	path	int	true	"Note ID"
+//	@Success	204		"No Content"
+//	@Failure	404		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/students/{id}/notes/{noteID} [delete]
+//	@Router		/teachers/{id}/notes/{noteID} [delete]
+//	@ID			deleteRecordNote
+func (app *application) deleteRecordNoteHandler(entity string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := recordIDFromCtx(r, entity)
+		if err != nil {
+			app.notfoundResponse(w, r, err)
+			return
+		}
+		noteID, err := strconv.ParseInt(chi.URLParam(r, "noteID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if err := app.store.Notes.Delete(r.Context(), entity, id, noteID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store"
 	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+	"github.com/MahdiiTaheri/classnama-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -17,35 +19,48 @@ type teacherKey string
 const teacherCtx teacherKey = "teacher"
 
 type UpdateTeacherPayload struct {
-	FirstName   *string `json:"first_name,omitempty" validate:"omitempty,max=72"`
-	LastName    *string `json:"last_name,omitempty" validate:"omitempty,max=72"`
-	Email       *string `json:"email,omitempty" validate:"omitempty,email"`
-	Subject     *string `json:"subject,omitempty" validate:"omitempty,max=128"`
-	PhoneNumber *string `json:"phone_number,omitempty" validate:"omitempty,e164"`
-	HireDate    *string `json:"hire_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	FirstName   *string              `json:"first_name,omitempty" validate:"omitempty,max=72" example:"Sara"`
+	LastName    *string              `json:"last_name,omitempty" validate:"omitempty,max=72" example:"Ahmadi"`
+	Email       *string              `json:"email,omitempty" validate:"omitempty,email" example:"sara.ahmadi@example.com"`
+	Subject     *string              `json:"subject,omitempty" validate:"omitempty,max=128" example:"Mathematics"`
+	SubjectI18n *store.LocalizedText `json:"subject_i18n,omitempty" validate:"omitempty,dive,keys,bcp47_language_tag,endkeys,max=128" example:"en:Mathematics,fa:ریاضی"`
+	PhoneNumber *string              `json:"phone_number,omitempty" validate:"omitempty,e164" example:"+989121234567"`
+	HireDate    *string              `json:"hire_date,omitempty" validate:"omitempty,datetime=2006-01-02" example:"2024-09-01"`
+	NationalID  *string              `json:"national_id,omitempty" validate:"omitempty,national_id" example:"0012345678"`
 }
 
 // GetTeachers godoc
 //
-//	@Summary	Get all teachers
-//	@Tags		Teachers
-//	@Produce	json
-//	@Success	200	{array}		store.Teacher
-//	@Failure	500	{object}	error
-//	@Security	ApiKeyAuth
-//	@Router		/teachers [get]
-//	@ID			getTeachers
+//	@Summary		Get all teachers
+//	@Description	Lists teachers a page at a time. search matches first name, last name, full name, email or subject.
+//	@Tags			Teachers
+//	@Produce		json,text/csv,application/xml
+//	@Param			limit			query		int		false	"Page size (1-50)"
+//	@Param			offset			query		int		false	"Rows to skip"
+//	@Param			sort_by			query		string	false	"id, first_name, last_name, email, subject, hire_date, staff_code, created_at or updated_at"
+//	@Param			order			query		string	false	"asc or desc"
+//	@Param			search			query		string	false	"Text to search for"
+//	@Param			fields			query		string	false	"Comma-separated fields to return, e.g. id,first_name,last_name"
+//	@Param			tag				query		string	false	"Only records carrying this tag"
+//	@Param			status			query		string	false	"active (has signed in), pending (never has) or deactivated"
+//	@Param			created_from	query		string	false	"Added on or after this date (YYYY-MM-DD)"
+//	@Param			created_to		query		string	false	"Added on or before this date (YYYY-MM-DD)"
+//	@Param			last_login_from	query		string	false	"Last signed in on or after this date (YYYY-MM-DD)"
+//	@Param			last_login_to	query		string	false	"Last signed in on or before this date (YYYY-MM-DD)"
+//	@Success		200				{array}		dto.Teacher
+//	@Failure		400				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/teachers [get]
+//	@ID				getTeachers
 func (app *application) getTeachersHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	app.listTeachers(w, r, v1Lists)
+}
 
-	pq := store.PaginatedQuery{
-		Limit:  10,
-		Offset: 0,
-		SortBy: "id",
-		Order:  "asc",
-	}
+func (app *application) listTeachers(w http.ResponseWriter, r *http.Request, lists listAdapter) {
+	ctx := r.Context()
 
-	pq, err := pq.Parse(r)
+	pq, err := lists.parse(r, defaultListQuery)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -55,14 +70,14 @@ func (app *application) getTeachersHandler(w http.ResponseWriter, r *http.Reques
 		app.badRequestResponse(w, r, err)
 		return
 	}
-
-	params := map[string]any{
-		"limit":  pq.Limit,
-		"offset": pq.Offset,
-		"sort":   pq.SortBy,
-		"order":  pq.Order,
+	if pq.Account, err = app.accountFilter(r); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
+	pq.Scope = getScope(r)
+	params := listCacheParams(pq)
+
 	teachers, err := cache.GetListWithCache(
 		ctx,
 		app.cacheStorage.Teachers,
@@ -74,11 +89,22 @@ func (app *application) getTeachersHandler(w http.ResponseWriter, r *http.Reques
 	)
 
 	if err != nil {
+		if errors.Is(err, store.ErrInvalidField) {
+			app.badRequestResponse(w, r, err)
+			return
+		}
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, teachers); err != nil {
+	data, err := filterFields(dto.FromTeachers(app.viewer(r), localizeTeachers(r, teachers)), pq.Fields)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	last := lastID(teachers, func(t *store.Teacher) int64 { return t.ID })
+	if err := lists.write(w, r, pq, data, len(teachers), last); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -90,7 +116,7 @@ func (app *application) getTeachersHandler(w http.ResponseWriter, r *http.Reques
 //	@Tags		Teachers
 //	@Produce	json
 //	@Param		teacherID	path		int	true	"Teacher ID"
-//	@Success	200			{object}	store.Teacher
+//	@Success	200			{object}	dto.Teacher
 //	@Failure	404			{object}	error
 //	@Failure	500			{object}	error
 //	@Security	ApiKeyAuth
@@ -103,7 +129,7 @@ func (app *application) getTeacherHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, teacher); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTeacher(app.viewer(r), localizeTeacher(r, teacher))); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
@@ -116,11 +142,11 @@ func (app *application) getTeacherHandler(w http.ResponseWriter, r *http.Request
 //	@Tags			Students
 //	@Accept			json
 //	@Produce		json
-//	@Param			teacherID	path		int				true	"Teacher ID"
-//	@Success		200			{array}		store.Student	"List of students"
-//	@Failure		400			{object}	error			"Bad request"
-//	@Failure		404			{object}	error			"Teacher not found / no students"
-//	@Failure		500			{object}	error			"Internal server error"
+//	@Param			teacherID	path		int					true	"Teacher ID"
+//	@Success		200			{array}		dto.StudentSummary	"List of students"
+//	@Failure		400			{object}	error				"Bad request"
+//	@Failure		404			{object}	error				"Teacher not found / no students"
+//	@Failure		500			{object}	error				"Internal server error"
 //	@Security		ApiKeyAuth
 //	@Router			/teachers/{teacherID}/students [get]
 //	@ID				getStudentsByTeacher
@@ -134,12 +160,9 @@ func (app *application) getStudentsByTeacherHandler(w http.ResponseWriter, r *ht
 
 	ctx := r.Context()
 
-	var students []*store.Student
-	if app.config.redisCfg.enabled {
-		students, err = app.cacheStorage.Students.GetByTeacherID(ctx, teacherID)
-		if err != nil {
-			app.logger.Warnf("Redis get by teacher failed: %v", err)
-		}
+	students, err := app.cacheStorage.Students.GetByTeacherID(ctx, teacherID)
+	if err != nil {
+		app.logger.Warnf("Redis get by teacher failed: %v", err)
 	}
 
 	if students == nil {
@@ -149,9 +172,7 @@ func (app *application) getStudentsByTeacherHandler(w http.ResponseWriter, r *ht
 			return
 		}
 
-		if app.config.redisCfg.enabled {
-			_ = app.cacheStorage.Students.SetByTeacherID(ctx, teacherID, students)
-		}
+		_ = app.cacheStorage.Students.SetByTeacherID(ctx, teacherID, students)
 	}
 
 	if len(students) == 0 {
@@ -159,27 +180,62 @@ func (app *application) getStudentsByTeacherHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, students); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromStudentSummaries(app.viewer(r), students)); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 	}
 }
 
-// UpdateTeacher godoc
+// GetTeacherByCode godoc
 //
-//	@Summary	Update a teacher
+//	@Summary	Get a teacher by staff code
 //	@Tags		Teachers
-//	@Accept		json
 //	@Produce	json
-//	@Param		teacherID	path		int						true	"Teacher ID"
-//	@Param		payload		body		UpdateTeacherPayload	true	"Teacher update payload"
-//	@Success	200			{object}	store.Teacher
-//	@Failure	400			{object}	error
-//	@Failure	404			{object}	error
-//	@Failure	409			{object}	error
-//	@Failure	500			{object}	error
+//	@Param		code	path		string	true	"Staff code"
+//	@Success	200		{object}	dto.Teacher
+//	@Failure	404		{object}	error
+//	@Failure	500		{object}	error
 //	@Security	ApiKeyAuth
-//	@Router		/teachers/{teacherID} [patch]
-//	@ID			updateTeacher
+//	@Router		/teachers/by-code/{code} [get]
+//	@ID			getTeacherByCode
+func (app *application) getTeacherByCodeHandler(w http.ResponseWriter, r *http.Request) {
+	teacher, err := app.store.Teachers.GetByCode(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+	ok, err := app.teacherInScope(r, teacher.ID)
+	if !app.checkScope(w, r, ok, err) {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTeacher(app.viewer(r), localizeTeacher(r, teacher))); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+}
+
+// UpdateTeacher godoc
+//
+//	@Summary		Update a teacher
+//	@Description	A new email only takes effect once confirmed from a link sent to it; the old address is notified.
+//	@Tags			Teachers
+//	@Accept			json
+//	@Produce		json
+//	@Param			teacherID	path		int						true	"Teacher ID"
+//	@Param			payload		body		UpdateTeacherPayload	true	"Teacher update payload"
+//	@Success		200			{object}	dto.Teacher
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/teachers/{teacherID} [patch]
+//	@ID				updateTeacher
 func (app *application) updateTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	teacher := getTeacherFromCtx(r)
 	if teacher == nil {
@@ -198,39 +254,62 @@ func (app *application) updateTeacherHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Apply non-nil fields using reflection
-	// utils.ApplyPatch(teacher, payload)
-
-	// Update in DB
-	if err := app.store.Teachers.Update(r.Context(), teacher); err != nil {
+	// A new email waits for confirmation; only the other provided columns
+	// are written
+	fields := utils.PatchFields(payload)
+	if !app.startEmailChange(w, r, "teacher", teacher.ID, teacher.FirstName, teacher.Email, fields) {
+		return
+	}
+	ctx := r.Context()
+	if err := app.store.Teachers.UpdatePartial(ctx, teacher.ID, fields); err != nil {
 		switch err {
 		case store.ErrNotFound:
 			app.notfoundResponse(w, r, err)
 			return
+		case store.ErrConflict:
+			app.conflictResponse(w, r, fmt.Errorf("email or national ID already registered"))
+			return
 		default:
 			app.internalServerErrorResponse(w, r, err)
 			return
 		}
 	}
 
+	updated, err := app.store.Teachers.GetByID(ctx, teacher.ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordChanges(r, "teacher", teacher.ID, teacher, updated)
+
 	// Return updated teacher
-	if err := app.jsonResponse(w, http.StatusOK, teacher); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTeacher(app.viewer(r), localizeTeacher(r, updated))); err != nil {
 		app.internalServerErrorResponse(w, r, err)
 		return
 	}
 }
 
+type teacherDependentsResponse struct {
+	Error      string                 `json:"error"`
+	Dependents *dto.TeacherDependents `json:"dependents"`
+}
+
 // DeleteTeacher godoc
 //
-//	@Summary	Delete a teacher
-//	@Tags		Teachers
-//	@Param		teacherID	path	int	true	"Teacher ID"
-//	@Success	204			"No Content"
-//	@Failure	404			{object}	error
-//	@Failure	500			{object}	error
-//	@Security	ApiKeyAuth
-//	@Router		/teachers/{teacherID} [delete]
-//	@ID			deleteTeacher
+//	@Summary		Delete a teacher
+//	@Description	A teacher who still has classrooms or students is not deleted; the 409 lists them. Pass reassign_to to move them to another teacher and delete in one step.
+//	@Tags			Teachers
+//	@Param			teacherID	path	int	true	"Teacher ID"
+//	@Param			reassign_to	query	int	false	"Teacher to take over the classrooms and students"
+//	@Success		204			"No Content"
+//	@Failure		400			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	teacherDependentsResponse
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/teachers/{teacherID} [delete]
+//	@ID				deleteTeacher
 func (app *application) deleteTeacherHandler(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "teacherID")
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -240,7 +319,42 @@ func (app *application) deleteTeacherHandler(w http.ResponseWriter, r *http.Requ
 	}
 	ctx := r.Context()
 
-	if err := app.store.Teachers.Delete(ctx, id); err != nil {
+	if v := r.URL.Query().Get("reassign_to"); v != "" {
+		reassignTo, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || reassignTo == id {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid reassign_to"))
+			return
+		}
+
+		if err := app.store.Teachers.DeleteReassigning(ctx, id, reassignTo, getUser(r).ID); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notfoundResponse(w, r, err)
+			default:
+				app.internalServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	dependents, err := app.store.Teachers.Dependents(ctx, id)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if !dependents.Empty() {
+		app.logger.Warnw("teacher has dependents", "method", r.Method, "path", r.URL.Path, "teacher", id)
+		writeJSON(w, http.StatusConflict, &teacherDependentsResponse{
+			Error:      "teacher still has classrooms or students; retry with ?reassign_to=<teacher id>",
+			Dependents: dto.FromTeacherDependents(dependents),
+		})
+		return
+	}
+
+	if err := app.store.Teachers.Delete(ctx, id, getUser(r).ID); err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			app.notfoundResponse(w, r, err)
@@ -264,7 +378,7 @@ func (app *application) teachersContextMiddleware(next http.Handler) http.Handle
 			return
 		}
 
-		teacher, err := app.store.Teachers.GetByID(r.Context(), id)
+		teacher, err := lookupByID(app, r.Context(), "teachers", id, app.store.Teachers.GetByID)
 		if err != nil {
 			if errors.Is(err, store.ErrNotFound) {
 				app.notfoundResponse(w, r, err)
@@ -274,6 +388,11 @@ func (app *application) teachersContextMiddleware(next http.Handler) http.Handle
 			return
 		}
 
+		ok, err := app.teacherInScope(r, teacher.ID)
+		if !app.checkScope(w, r, ok, err) {
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), teacherCtx, teacher)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/integrations/telegram"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// telegramCodeTTL is how long a link code stays valid.
+const telegramCodeTTL = 10 * time.Minute
+
+// telegramCodeAlphabet avoids look-alike characters; codes are typed by
+// hand when the deep link can't be used.
+const telegramCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const telegramHelp = `Commands:
+/children – your children and their classes
+/schedule – school days and live classes for the coming week
+/stop – stop receiving messages here`
+
+// newTelegramCode returns a random 8-character link code.
+func newTelegramCode() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = telegramCodeAlphabet[int(b[i])%len(telegramCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// telegramCodeHash is what is stored for a link code.
+func (app *application) telegramCodeHash(code string) []byte {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	mac.Write([]byte("telegram:" + strings.ToUpper(code)))
+	return mac.Sum(nil)
+}
+
+type TelegramCodeResponse struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Link opens the bot with the code filled in; empty when
+	// TELEGRAM_BOT_USERNAME is not set.
+	Link string `json:"link,omitempty"`
+}
+
+// CreateTelegramCode godoc
+//
+//	@Summary		Get a code to link Telegram
+//	@Description	Returns a one-time code, valid for ten minutes, to send to the school's bot as "/start CODE" (or open the link). Once linked, announcements and absence alerts also arrive in Telegram.
+//	@Tags			Parents
+//	@Produce		json
+//	@Success		201	{object}	TelegramCodeResponse
+//	@Failure		503	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/parents/me/telegram [post]
+//	@ID				createTelegramCode
+func (app *application) createTelegramCodeHandler(w http.ResponseWriter, r *http.Request) {
+	if app.dynamic.Load().telegram.Token == "" {
+		app.serviceUnavailableResponse(w, r, telegram.ErrNotConfigured)
+		return
+	}
+
+	code, err := newTelegramCode()
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	expiresAt := time.Now().Add(telegramCodeTTL)
+
+	if err := app.store.Telegram.IssueCode(r.Context(), getUser(r).ID, app.telegramCodeHash(code), expiresAt); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	resp := TelegramCodeResponse{
+		Code:      code,
+		ExpiresAt: expiresAt,
+		Link:      telegram.DeepLink(app.dynamic.Load().telegram.Username, code),
+	}
+	if err := app.jsonResponse(w, http.StatusCreated, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetTelegramLink godoc
+//
+//	@Summary	Check whether Telegram is linked
+//	@Tags		Parents
+//	@Produce	json
+//	@Success	200	{object}	dto.TelegramLink
+//	@Security	ApiKeyAuth
+//	@Router		/parents/me/telegram [get]
+//	@ID			getTelegramLink
+func (app *application) getTelegramLinkHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := app.store.Telegram.Get(r.Context(), getUser(r).ID)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTelegramLink(link)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteTelegramLink godoc
+//
+//	@Summary	Unlink Telegram
+//	@Tags		Parents
+//	@Success	204
+//	@Failure	404	{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/parents/me/telegram [delete]
+//	@ID			deleteTelegramLink
+func (app *application) deleteTelegramLinkHandler(w http.ResponseWriter, r *http.Request) {
+	switch err := app.store.Telegram.Unlink(r.Context(), getUser(r).ID); {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, store.ErrNotFound):
+		app.notfoundResponse(w, r, err)
+	default:
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// TelegramWebhook godoc
+//
+//	@Summary		Receive an update from Telegram
+//	@Description	Bot API webhook. Authorised by the secret registered with setWebhook, sent in X-Telegram-Bot-Api-Secret-Token. Handles /start CODE, /children, /schedule and /stop.
+//	@Tags			Webhooks
+//	@Accept			json
+//	@Success		200
+//	@Failure		401	{object}	error
+//	@Router			/webhooks/telegram [post]
+//	@ID				telegramWebhook
+func (app *application) telegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	secret := app.dynamic.Load().telegram.WebhookSecret
+	if secret == "" || !hmac.Equal([]byte(r.Header.Get(telegram.SecretHeader)), []byte(secret)) {
+		app.unauthorizedResponse(w, r, errors.New("invalid webhook secret"))
+		return
+	}
+
+	var update telegram.Update
+	if err := readJSON(w, r, &update); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Telegram retries every update that doesn't get a 2xx, so failures
+	// are logged and the update is acknowledged regardless.
+	if update.Message != nil && update.Message.Chat.Type == "private" {
+		reply, err := app.telegramReply(r.Context(), update.Message)
+		if err != nil {
+			app.logger.Warnw("telegram update failed", "update", update.UpdateID, "error", err.Error())
+			reply = "Something went wrong, please try again later."
+		}
+		if err := app.telegram.Send(r.Context(), update.Message.Chat.ID, reply); err != nil {
+			app.logger.Warnw("telegram reply failed", "update", update.UpdateID, "error", err.Error())
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// telegramReply runs a bot command and returns the answer to send back.
+func (app *application) telegramReply(ctx context.Context, m *telegram.Message) (string, error) {
+	command, arg, _ := m.Command()
+
+	if command == "start" && arg != "" {
+		_, err := app.store.Telegram.Link(ctx, app.telegramCodeHash(arg), m.Chat.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return "That code is invalid or has expired. Get a new one from the app.", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return "Linked. School announcements and absence alerts will now arrive here.\n\n" + telegramHelp, nil
+	}
+
+	parent, err := app.store.Telegram.ParentForChat(ctx, m.Chat.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return "To link this chat, open the school app and choose Link Telegram, then send the code here as /start CODE.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch command {
+	case "children":
+		return app.telegramChildren(ctx, parent)
+	case "schedule":
+		return app.telegramSchedule(ctx, parent)
+	case "stop":
+		if err := app.store.Telegram.UnlinkChat(ctx, m.Chat.ID); err != nil {
+			return "", err
+		}
+		return "This chat is no longer linked. You can link it again from the app.", nil
+	default:
+		return telegramHelp, nil
+	}
+}
+
+func (app *application) telegramChildren(ctx context.Context, parent *store.Parent) (string, error) {
+	children, err := app.store.Parents.Children(ctx, parent.PhoneNumber)
+	if err != nil {
+		return "", err
+	}
+	if len(children) == 0 {
+		return "No students are registered with your phone number.", nil
+	}
+
+	var b strings.Builder
+	for _, c := range children {
+		fmt.Fprintf(&b, "%s %s", c.FirstName, c.LastName)
+		if classroom, err := app.store.Classrooms.GetByID(ctx, c.ClassRoomID); err == nil {
+			fmt.Fprintf(&b, " – %s", classroom.Name)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// telegramSchedule lists the coming week's school days and any class of
+// the parent's children that is live online right now. There is no
+// timetable to report beyond that.
+func (app *application) telegramSchedule(ctx context.Context, parent *store.Parent) (string, error) {
+	children, err := app.store.Parents.Children(ctx, parent.PhoneNumber)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Coming week:\n")
+	today := app.schoolToday()
+	for i := range 7 {
+		day := today.AddDate(0, 0, i)
+		open, entry, err := app.store.SchoolDays.IsSchoolDay(ctx, day)
+		if err != nil {
+			return "", err
+		}
+		status := "school day"
+		if !open {
+			status = "no school"
+			if entry != nil && entry.Name != "" {
+				status += " (" + entry.Name + ")"
+			}
+		}
+		fmt.Fprintf(&b, "%s %s: %s\n", day.Weekday().String()[:3], day.Format(time.DateOnly), status)
+	}
+
+	for _, c := range children {
+		classroom, err := app.store.Classrooms.GetByID(ctx, c.ClassRoomID)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s %s – %s (%s)", c.FirstName, c.LastName, classroom.Name, strings.ReplaceAll(classroom.Mode, "_", " "))
+		if session, err := app.store.OnlineSessions.GetOpen(ctx, classroom.ID); err == nil {
+			if url := sessionMeetingURL(session, classroom); url != "" {
+				fmt.Fprintf(&b, "\nLive now: %s", url)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// notifyTelegram sends text to the linked chats of the parents with the
+// given phone numbers and returns how many were sent.
+func (app *application) notifyTelegram(ctx context.Context, phones []string, text string) (int, error) {
+	if app.dynamic.Load().telegram.Token == "" || len(phones) == 0 {
+		return 0, nil
+	}
+	chats, err := app.store.Telegram.ChatsForPhones(ctx, phones)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, chatID := range chats {
+		if err := app.telegram.Send(ctx, chatID, text); err != nil {
+			app.logger.Warnw("telegram message failed", "chat", chatID, "error", err.Error())
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/pdf"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// termArchiveInterval is how often closed terms are checked for archiving.
+const termArchiveInterval = time.Hour
+
+type CreateTermPayload struct {
+	Name     string `json:"name" validate:"required,max=100" example:"Fall 2026"`
+	StartsOn string `json:"starts_on" validate:"required,datetime=2006-01-02" example:"2026-09-23"`
+	EndsOn   string `json:"ends_on" validate:"required,datetime=2006-01-02" example:"2027-01-20"`
+}
+
+// CreateTerm godoc
+//
+//	@Summary	Create an academic term
+//	@Tags		Terms
+//	@Accept		json
+//	@Produce	json
+//	@Param		payload	body		CreateTermPayload	true	"Term"
+//	@Success	201		{object}	dto.Term
+//	@Failure	400		{object}	error
+//	@Failure	409		{object}	error	"Name taken or dates overlap another term"
+//	@Security	ApiKeyAuth
+//	@Router		/terms [post]
+//	@ID			createTerm
+func (app *application) createTermHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateTermPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	startsOn, _ := time.Parse(time.DateOnly, payload.StartsOn)
+	endsOn, _ := time.Parse(time.DateOnly, payload.EndsOn)
+	if endsOn.Before(startsOn) {
+		app.badRequestResponse(w, r, errors.New("ends_on must not be before starts_on"))
+		return
+	}
+
+	term := &store.Term{Name: payload.Name, StartsOn: startsOn, EndsOn: endsOn}
+	if err := app.store.Terms.Create(r.Context(), term); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("a term with this name or overlapping dates exists"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, dto.FromTerm(term)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListTerms godoc
+//
+//	@Summary	List academic terms, newest first
+//	@Tags		Terms
+//	@Produce	json
+//	@Success	200	{array}	dto.Term
+//	@Security	ApiKeyAuth
+//	@Router		/terms [get]
+//	@ID			listTerms
+func (app *application) listTermsHandler(w http.ResponseWriter, r *http.Request) {
+	terms, err := app.store.Terms.List(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTerms(terms)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// CloseTerm godoc
+//
+//	@Summary		Close an academic term
+//	@Description	Closing snapshots each student's attendance counts and grades for the term; transcripts are built from those snapshots, which can't change afterwards. A closed term is archived TERM_ARCHIVE_AFTER_DAYS later: its attendance, and the grades imported during it, move to the archive schema.
+//	@Tags			Terms
+//	@Produce		json
+//	@Param			termID	path		int	true	"Term ID"
+//	@Success		200		{object}	dto.Term
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Already closed"
+//	@Security		ApiKeyAuth
+//	@Router			/terms/{termID}/close [post]
+//	@ID				closeTerm
+func (app *application) closeTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "termID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	term, err := app.store.Terms.Close(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("term is already closed"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTerm(term)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ArchiveTerm godoc
+//
+//	@Summary		Archive a closed term now
+//	@Description	Moves the term's attendance, and the grades imported during it, to the archive schema without waiting for TERM_ARCHIVE_AFTER_DAYS.
+//	@Tags			Terms
+//	@Produce		json
+//	@Param			termID	path		int	true	"Term ID"
+//	@Success		200		{object}	dto.TermArchive
+//	@Failure		404		{object}	error
+//	@Failure		409		{object}	error	"Term is open or already archived"
+//	@Security		ApiKeyAuth
+//	@Router			/terms/{termID}/archive [post]
+//	@ID				archiveTerm
+func (app *application) archiveTermHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "termID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	archived, err := app.store.Terms.Archive(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, errors.New("only closed terms that are not archived yet can be archived"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTermArchive(archived)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetReportCard godoc
+//
+//	@Summary		Get a student's report card for a term
+//	@Description	Attendance counts by status and the grades imported during the term, read from the archive for archived terms.
+//	@Tags			Terms
+//	@Produce		json
+//	@Param			studentID	path		int	true	"Student ID"
+//	@Param			termID		path		int	true	"Term ID"
+//	@Success		200			{object}	dto.ReportCard
+//	@Failure		404			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID}/report-cards/{termID} [get]
+//	@ID				getReportCard
+func (app *application) getReportCardHandler(w http.ResponseWriter, r *http.Request) {
+	student := getStudentFromCtx(r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "termID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	term, err := app.store.Terms.GetByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	card, err := app.store.Terms.ReportCard(ctx, student.ID, term)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromReportCard(card)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetTranscript godoc
+//
+//	@Summary		Get a student's transcript
+//	@Description	Every closed term the student has records in, oldest first, from the snapshots taken when each closed. Later changes to attendance or grades don't show.
+//	@Tags			Terms
+//	@Produce		json
+//	@Param			studentID	path		int	true	"Student ID"
+//	@Success		200			{object}	dto.Transcript
+//	@Failure		404			{object}	error	"No closed terms"
+//	@Security		ApiKeyAuth
+//	@Router			/students/{studentID}/transcript [get]
+//	@ID				getTranscript
+func (app *application) getTranscriptHandler(w http.ResponseWriter, r *http.Request) {
+	tr, ok := app.transcript(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTranscript(tr)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetTranscriptPDF godoc
+//
+//	@Summary	Download a student's official transcript
+//	@Tags		Terms
+//	@Produce	application/pdf
+//	@Param		studentID	path	int	true	"Student ID"
+//	@Success	200
+//	@Failure	404	{object}	error	"No closed terms"
+//	@Security	ApiKeyAuth
+//	@Router		/students/{studentID}/transcript/pdf [get]
+//	@ID			getTranscriptPDF
+func (app *application) getTranscriptPDFHandler(w http.ResponseWriter, r *http.Request) {
+	tr, ok := app.transcript(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="transcript-%d.pdf"`, tr.StudentID))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(app.renderTranscript(tr))
+}
+
+func (app *application) transcript(w http.ResponseWriter, r *http.Request) (*store.Transcript, bool) {
+	tr, err := app.store.Terms.Transcript(r.Context(), getStudentFromCtx(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return tr, true
+}
+
+// renderTranscript lays out a transcript as a PDF, a section per term.
+func (app *application) renderTranscript(tr *store.Transcript) []byte {
+	doc := pdf.New()
+	name := tr.FirstName + " " + tr.LastName
+	doc.Title = "Transcript - " + name
+	doc.Author = app.config.school.name
+
+	const left, right = 56.0, pdf.PageWidth - 56
+	doc.Text(left, 80, 18, pdf.Bold, app.config.school.name)
+	doc.Text(left, 104, 13, pdf.Regular, "Official transcript")
+	doc.TextRight(right, 80, 10, pdf.Regular, fmt.Sprintf("Student No. %06d", tr.StudentID))
+	doc.TextRight(right, 96, 10, pdf.Regular, "Issued "+app.schoolToday().Format(time.DateOnly))
+	doc.Line(left, 120, right, 120, 1)
+	doc.Text(left, 146, 11, pdf.Bold, name)
+
+	y := 184.0
+	// need starts a new page unless h points still fit on this one.
+	need := func(h float64) {
+		if y+h > pdf.PageHeight-80 {
+			doc.AddPage()
+			y = 80
+		}
+	}
+	for _, tt := range tr.Terms {
+		need(70)
+		t := tt.Term
+		doc.Text(left, y, 12, pdf.Bold, t.Name)
+		doc.TextRight(right, y, 10, pdf.Regular, t.StartsOn.Format(time.DateOnly)+" to "+t.EndsOn.Format(time.DateOnly))
+		y += 18
+		doc.Text(left, y, 10, pdf.Regular, fmt.Sprintf("Attendance: %d present, %d absent, %d late, %d excused of %d days",
+			tt.Attendance["present"], tt.Attendance["absent"], tt.Attendance["late"], tt.Attendance["excused"], tt.Days))
+		y += 20
+
+		if len(tt.Grades) == 0 {
+			doc.Text(left, y, 9, pdf.Regular, "No grades recorded.")
+			y += 14
+		}
+		for _, g := range tt.Grades {
+			need(14)
+			label := g.Assignment
+			if g.Classroom != "" {
+				label = g.Classroom + "  " + label
+			}
+			score := strconv.FormatFloat(g.Score, 'f', -1, 64)
+			if g.MaxScore != nil {
+				score += " / " + strconv.FormatFloat(*g.MaxScore, 'f', -1, 64)
+			}
+			doc.Text(left, y, 9, pdf.Regular, label)
+			doc.TextRight(right, y, 9, pdf.Regular, score)
+			y += 14
+		}
+		doc.Line(left, y-4, right, y-4, 0.5)
+		y += 24
+	}
+
+	return doc.Bytes()
+}
+
+// startTermArchival archives terms once they have been closed for the
+// configured time.
+func (app *application) startTermArchival() {
+	if app.config.terms.archiveAfter <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(termArchiveInterval)
+	go func() {
+		for now := range ticker.C {
+			terms, err := app.store.Terms.Archivable(context.Background(), now.Add(-app.config.terms.archiveAfter))
+			if err != nil {
+				app.logger.Errorw("listing terms to archive failed", "error", err.Error())
+				continue
+			}
+			for _, t := range terms {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+				archived, err := app.store.Terms.Archive(ctx, t.ID)
+				cancel()
+				if err != nil {
+					app.logger.Errorw("archiving term failed", "term", t.ID, "error", err.Error())
+					continue
+				}
+				app.logger.Infow("term archived", "term", t.ID, "attendance", archived.Attendance, "grades", archived.Grades)
+			}
+		}
+	}()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/auth"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required" example:"Zm9vYmFyYmF6cXV4..."`
+}
+
+// accessTokenExp returns the access token lifetime for role. Execs share
+// one setting.
+func (app *application) accessTokenExp(role string) time.Duration {
+	if role == string(store.RoleAdmin) || role == string(store.RoleManager) {
+		role = "exec"
+	}
+	if exp, ok := app.config.auth.token.exp[role]; ok {
+		return exp
+	}
+	return app.config.auth.token.exp["exec"]
+}
+
+// issueToken signs an access token for the given user, valid for their
+// role's lifetime. A user who must change their password gets a token good
+// for nothing else.
+func (app *application) issueToken(id int64, role, email string, mustChangePassword bool) (*auth.Claims, string, error) {
+	now := time.Now()
+	claims := &auth.Claims{
+		ID:                 id,
+		Email:              email,
+		Role:               role,
+		MustChangePassword: mustChangePassword,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprint(id),
+			Issuer:    app.config.auth.token.iss,
+			Audience:  []string{app.config.auth.token.iss},
+			ExpiresAt: jwt.NewNumericDate(now.Add(app.accessTokenExp(role))),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token, err := app.authenticator.GenerateToken(claims)
+	if err != nil {
+		return nil, "", err
+	}
+	return claims, token, nil
+}
+
+// issueRefreshToken stores a new refresh token for an account and returns
+// it. Remember-me tokens live for refreshRememberExp instead of refreshExp.
+func (app *application) issueRefreshToken(ctx context.Context, accountID int64, rememberMe bool) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	exp := app.config.auth.token.refreshExp
+	if rememberMe {
+		exp = app.config.auth.token.refreshRememberExp
+	}
+
+	err := app.store.RefreshTokens.Create(ctx, hashRefreshToken(token), &store.RefreshToken{
+		AccountID:  accountID,
+		RememberMe: rememberMe,
+		ExpiresAt:  time.Now().Add(exp),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func hashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// refreshTokenHandler godoc
+//
+//	@Summary		Refresh an access token
+//	@Description	Exchanges a refresh token for a new access token and a new refresh token. Each refresh token works once; a remember-me token stays remember-me.
+//	@Tags			Auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		RefreshPayload		true	"Refresh token"
+//	@Success		200		{object}	map[string]any		"Returns the profile, JWT token, refresh token, permissions, school settings and avatar URL"
+//	@Failure		400		{object}	map[string]string	"Bad request"
+//	@Failure		401		{object}	map[string]string	"Unauthorized"
+//	@Router			/auth/refresh [post]
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload RefreshPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := app.store.RefreshTokens.Consume(ctx, hashRefreshToken(payload.RefreshToken))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.unauthorizedResponse(w, r, fmt.Errorf("invalid refresh token"))
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	account, err := app.store.Accounts.GetByID(ctx, stored.AccountID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.unauthorizedResponse(w, r, fmt.Errorf("invalid refresh token"))
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if account.DeactivatedAt != nil {
+		app.unauthorizedResponse(w, r, fmt.Errorf("account deactivated"))
+		return
+	}
+
+	entity, err := app.loadProfile(ctx, account)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			app.unauthorizedResponse(w, r, fmt.Errorf("invalid refresh token"))
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	var email string
+	if account.Email != nil {
+		email = *account.Email
+	}
+
+	_, token, err := app.issueToken(account.ProfileID, account.Role, email, account.MustChangePassword)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	refreshToken, err := app.issueRefreshToken(ctx, account.ID, stored.RememberMe)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	// a refresh is use of the account, so it doesn't turn up as inactive
+	if err := app.store.Accounts.RecordSeen(ctx, account.ID); err != nil {
+		app.logger.Warnw("recording account use failed", "account", account.ID, "error", err.Error())
+	}
+
+	resp := map[string]any{
+		"entity":        entity,
+		"token":         token,
+		"refresh_token": refreshToken,
+	}
+	if account.MustChangePassword {
+		resp["must_change_password"] = true
+	}
+	app.addSessionInfo(ctx, resp, account.Role, email, account.ID)
+
+	if err := app.jsonResponse(w, http.StatusOK, resp); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
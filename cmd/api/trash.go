@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const trashPurgeInterval = time.Hour
+
+// GetTrash godoc
+//
+//	@Summary		List soft-deleted records
+//	@Description	Returns soft-deleted records of an entity with deletion timestamp and actor, most recent first
+//	@Tags			Trash
+//	@Produce		json
+//	@Param			entity	query		string	true	"Entity"	Enums(students, teachers, classrooms)
+//	@Param			search	query		string	false	"Search term"
+//	@Param			limit	query		int		false	"Page size"
+//	@Param			offset	query		int		false	"Page offset"
+//	@Success		200		{array}		dto.TrashedRecord
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/trash [get]
+//	@ID				getTrash
+func (app *application) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	entity := r.URL.Query().Get("entity")
+	if err := Validate.Var(entity, "required,oneof=students teachers classrooms"); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("entity must be one of students, teachers, classrooms"))
+		return
+	}
+
+	pq := store.PaginatedQuery{Limit: 10, Offset: 0, Order: "desc"}
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	records, err := app.store.Trash.List(r.Context(), entity, pq)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromTrashedRecords(records)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RestoreTrash godoc
+//
+//	@Summary	Restore a soft-deleted record
+//	@Tags		Trash
+//	@Param		entity	path	string	true	"Entity"	Enums(students, teachers, classrooms)
+//	@Param		id		path	int		true	"Record ID"
+//	@Success	204		"No Content"
+//	@Failure	400		{object}	error
+//	@Failure	404		{object}	error
+//	@Failure	409		{object}	error	"Email already taken by a live record"
+//	@Failure	500		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/trash/{entity}/{id}/restore [post]
+//	@ID			restoreTrash
+func (app *application) restoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	entity, id, err := parseTrashParams(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Trash.Restore(r.Context(), entity, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("a live record already uses this email"))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.cacheStorage.Missing.Clear(r.Context(), entity, id); err != nil {
+		app.logger.Warnw("clearing negative cache failed", "entity", entity, "error", err.Error())
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeTrash godoc
+//
+//	@Summary	Permanently delete a soft-deleted record
+//	@Tags		Trash
+//	@Param		entity	path	string	true	"Entity"	Enums(students, teachers, classrooms)
+//	@Param		id		path	int		true	"Record ID"
+//	@Success	204		"No Content"
+//	@Failure	400		{object}	error
+//	@Failure	404		{object}	error
+//	@Failure	409		{object}	error	"Records still depend on it"
+//	@Failure	500		{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/trash/{entity}/{id} [delete]
+//	@ID			purgeTrash
+func (app *application) purgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	entity, id, err := parseTrashParams(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Trash.Purge(r.Context(), entity, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflictResponse(w, r, fmt.Errorf("records still depend on this %s; purge or move them first", entity))
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseTrashParams(r *http.Request) (string, int64, error) {
+	entity := chi.URLParam(r, "entity")
+	if err := Validate.Var(entity, "oneof=students teachers classrooms"); err != nil {
+		return "", 0, fmt.Errorf("entity must be one of students, teachers, classrooms")
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid ID")
+	}
+
+	return entity, id, nil
+}
+
+// startTrashRetention periodically purges records that have been in the
+// trash longer than the configured retention.
+func (app *application) startTrashRetention() {
+	if app.config.trash.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	go func() {
+		for now := range ticker.C {
+			purged, err := app.store.Trash.PurgeOlderThan(context.Background(), now.Add(-app.config.trash.retention))
+			if err != nil {
+				app.logger.Errorw("trash retention purge failed", "error", err.Error())
+				continue
+			}
+			if purged > 0 {
+				app.logger.Infow("trash retention purge", "purged", purged)
+			}
+		}
+	}()
+}
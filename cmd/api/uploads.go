@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/mailer"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+const quarantineNotifyJob = "quarantine_notify"
+
+// maxUploadPixels bounds decoded image sizes, so a small file can't
+// expand into a huge bitmap wherever it is shown.
+const maxUploadPixels = 40_000_000
+
+// pdfActiveContent are the PDF names that run code or carry other files;
+// no receipt or form a school takes needs them.
+var pdfActiveContent = [][]byte{
+	[]byte("/JavaScript"), []byte("/JS"), []byte("/Launch"), []byte("/EmbeddedFile"), []byte("/RichMedia"), []byte("/XFA"),
+}
+
+// uploadKind is what an upload endpoint accepts.
+type uploadKind struct {
+	name    string
+	maxSize int64
+	types   map[string]bool
+	// typesText names the types in error messages.
+	typesText string
+}
+
+// readUpload reads a file sent as the request body and checks it: its size,
+// that its content is of an accepted type and matches the declared
+// Content-Type, that it parses as that type, and, with a scanner
+// configured, that it carries no malware. Files that look unsafe are
+// quarantined and the admins told. It answers the request itself when the
+// file is refused.
+func (app *application) readUpload(w http.ResponseWriter, r *http.Request, kind uploadKind) ([]byte, string, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, kind.maxSize))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("%s must be at most %d bytes", kind.name, kind.maxSize))
+		return nil, "", false
+	}
+	if len(data) == 0 {
+		app.badRequestResponse(w, r, fmt.Errorf("%s is empty", kind.name))
+		return nil, "", false
+	}
+
+	contentType := http.DetectContentType(data)
+	if !kind.types[contentType] {
+		app.badRequestResponse(w, r, fmt.Errorf("%s must be %s", kind.name, kind.typesText))
+		return nil, "", false
+	}
+	if declared, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil &&
+		declared != "application/octet-stream" && declared != contentType {
+		app.badRequestResponse(w, r, fmt.Errorf("%s is %s, not %s", kind.name, contentType, declared))
+		return nil, "", false
+	}
+
+	switch {
+	case contentType == "image/jpeg" || contentType == "image/png":
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("%s is not a valid image", kind.name))
+			return nil, "", false
+		}
+		if cfg.Width*cfg.Height > maxUploadPixels {
+			app.badRequestResponse(w, r, fmt.Errorf("%s is too large an image", kind.name))
+			return nil, "", false
+		}
+	case contentType == "application/pdf":
+		if !bytes.Contains(data[max(0, len(data)-1024):], []byte("%%EOF")) {
+			app.badRequestResponse(w, r, fmt.Errorf("%s is not a complete PDF", kind.name))
+			return nil, "", false
+		}
+		for _, name := range pdfActiveContent {
+			if containsPDFName(data, name) {
+				app.quarantineUpload(w, r, kind, data, contentType, "PDF with active content "+string(name), "")
+				return nil, "", false
+			}
+		}
+	}
+
+	scanner := app.scanner
+	if scanner.Name() == "" {
+		return data, contentType, true
+	}
+	res, err := scanner.Scan(r.Context(), data)
+	switch {
+	case err != nil && app.config.uploads.scanRequired:
+		app.logger.Errorw("upload scan failed", "kind", kind.name, "error", err.Error())
+		app.serviceUnavailableResponse(w, r, errors.New("files can't be scanned right now, try again later"))
+		return nil, "", false
+	case err != nil:
+		app.logger.Warnw("upload scan failed, accepting unscanned", "kind", kind.name, "error", err.Error())
+	case !res.Clean:
+		app.quarantineUpload(w, r, kind, data, contentType, "malware", res.Signature)
+		return nil, "", false
+	}
+	return data, contentType, true
+}
+
+// containsPDFName reports whether data has name as a whole PDF name, one
+// followed by whitespace or a delimiter, so stream bytes that happen to
+// spell it out don't count.
+func containsPDFName(data, name []byte) bool {
+	for i := bytes.Index(data, name); i >= 0; {
+		end := i + len(name)
+		if end == len(data) || bytes.IndexByte([]byte(" \t\r\n\f\x00()<>[]{}/%"), data[end]) >= 0 {
+			return true
+		}
+		next := bytes.Index(data[end:], name)
+		if next < 0 {
+			return false
+		}
+		i = end + next
+	}
+	return false
+}
+
+// quarantineUpload keeps a refused file for review, emails the admins and
+// answers 422.
+func (app *application) quarantineUpload(w http.ResponseWriter, r *http.Request, kind uploadKind, data []byte, contentType, reason, signature string) {
+	user := getUser(r)
+	q := &store.QuarantinedUpload{
+		Kind:         kind.name,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Reason:       reason,
+		Signature:    signature,
+		UploaderRole: user.Role,
+		UploaderID:   user.ID,
+		IP:           clientIP(r),
+	}
+	if err := app.store.Quarantine.Create(r.Context(), q, data); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	app.logger.Warnw("upload quarantined", "id", q.ID, "kind", q.Kind, "reason", reason, "signature", signature,
+		"role", q.UploaderRole, "user", q.UploaderID, "ip", q.IP)
+
+	_, err := app.jobs.Enqueue(quarantineNotifyJob, user.ID, user.Role, func(ctx context.Context) ([]byte, error) {
+		emails, err := app.store.Execs.AdminEmails(ctx)
+		if err != nil || len(emails) == 0 {
+			return nil, err
+		}
+		found := reason
+		if signature != "" {
+			found += " (" + signature + ")"
+		}
+		return nil, app.mailer.Send(ctx, mailer.Message{
+			To:      emails,
+			Subject: "ClassNama: an upload was quarantined",
+			Body: fmt.Sprintf("A %s uploaded by %s %d from %s was refused: %s.\n\nIt is kept as quarantined upload %d; review and delete it at /v1/admin/quarantine.\n",
+				q.Kind, q.UploaderRole, q.UploaderID, q.IP, found, q.ID),
+		})
+	})
+	if err != nil {
+		app.logger.Warnw("queueing quarantine notice failed", "id", q.ID, "error", err.Error())
+	}
+
+	writeError(w, r, http.StatusUnprocessableEntity, "the file was refused as unsafe and kept for review")
+}
+
+// ListQuarantine godoc
+//
+//	@Summary		List quarantined uploads
+//	@Description	Files refused by the malware scan or as unsafe PDFs, newest first. Their content is kept but never served.
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{array}	dto.QuarantinedUpload
+//	@Security		ApiKeyAuth
+//	@Router			/admin/quarantine [get]
+//	@ID				listQuarantine
+func (app *application) listQuarantineHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.store.Quarantine.List(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromQuarantinedUploads(list)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// DeleteQuarantined godoc
+//
+//	@Summary	Delete a quarantined upload
+//	@Tags		Admin
+//	@Param		uploadID	path	int	true	"Quarantined upload ID"
+//	@Success	204			"No Content"
+//	@Failure	404			{object}	error
+//	@Security	ApiKeyAuth
+//	@Router		/admin/quarantine/{uploadID} [delete]
+//	@ID			deleteQuarantined
+func (app *application) deleteQuarantinedHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "uploadID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Quarantine.Delete(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// /v2 serves the same records as /v1, always as DTOs, but pages lists with
+// cursors and wraps errors with a machine-readable code. The list handlers
+// are shared and take a listAdapter for the version they answer.
+
+// listAdapter reads a list request's paging parameters and writes the page,
+// in one API version's shape.
+type listAdapter interface {
+	// parse applies the request's query parameters to pq.
+	parse(r *http.Request, pq store.PaginatedQuery) (store.PaginatedQuery, error)
+	// write answers with data, the page fetched with pq, in the format
+	// the request accepts; n is how many records it holds and lastID the
+	// ID of the last one.
+	write(w http.ResponseWriter, r *http.Request, pq store.PaginatedQuery, data any, n int, lastID int64) error
+}
+
+var (
+	v1Lists listAdapter = v1ListAdapter{}
+	v2Lists listAdapter = v2ListAdapter{}
+)
+
+// v1ListAdapter pages with limit and offset and answers with the bare list.
+type v1ListAdapter struct{}
+
+func (v1ListAdapter) parse(r *http.Request, pq store.PaginatedQuery) (store.PaginatedQuery, error) {
+	return pq.Parse(r)
+}
+
+func (v1ListAdapter) write(w http.ResponseWriter, r *http.Request, _ store.PaginatedQuery, data any, _ int, _ int64) error {
+	if format := listFormat(w, r); format != formatJSON {
+		return writeTable(w, format, data)
+	}
+	return writeJSON(w, http.StatusOK, &struct {
+		Data any `json:"data"`
+	}{data})
+}
+
+// v2ListAdapter pages by ID with an opaque cursor and answers with the
+// cursor of the next page, in the body for JSON and in a Link header
+// otherwise.
+type v2ListAdapter struct{}
+
+// Page describes a page of a /v2 list. NextCursor, passed back as ?cursor=,
+// fetches the next page; it is left out once a page comes back short.
+type Page struct {
+	Limit      int    `json:"limit" example:"10"`
+	NextCursor string `json:"next_cursor,omitempty" example:"NDI"`
+}
+
+func (v2ListAdapter) parse(r *http.Request, pq store.PaginatedQuery) (store.PaginatedQuery, error) {
+	qs := r.URL.Query()
+	if qs.Has("offset") {
+		return pq, errors.New("offset is not supported, page with cursor")
+	}
+	if sortBy := qs.Get("sort_by"); sortBy != "" && sortBy != "id" {
+		return pq, errors.New("lists are ordered by id; use order to reverse them")
+	}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		return pq, err
+	}
+	if cursor := qs.Get("cursor"); cursor != "" {
+		if pq.After, err = decodeCursor(cursor); err != nil {
+			return pq, err
+		}
+	}
+	return pq, nil
+}
+
+func (v2ListAdapter) write(w http.ResponseWriter, r *http.Request, pq store.PaginatedQuery, data any, n int, lastID int64) error {
+	page := Page{Limit: pq.Limit}
+	if n == pq.Limit && lastID > 0 {
+		page.NextCursor = encodeCursor(lastID)
+	}
+
+	if format := listFormat(w, r); format != formatJSON {
+		if page.NextCursor != "" {
+			next := *r.URL
+			qs := next.Query()
+			qs.Set("cursor", page.NextCursor)
+			next.RawQuery = qs.Encode()
+			w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+		}
+		return writeTable(w, format, data)
+	}
+
+	return writeJSON(w, http.StatusOK, &struct {
+		Data any  `json:"data"`
+		Page Page `json:"page"`
+	}{data, page})
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
+}
+
+// lastID is the ID of a page's last record, or zero for an empty page.
+func lastID[T any](list []*T, id func(*T) int64) int64 {
+	if len(list) == 0 {
+		return 0
+	}
+	return id(list[len(list)-1])
+}
+
+// v2List serves a shared list handler with the /v2 adapter.
+func v2List(h func(http.ResponseWriter, *http.Request, listAdapter)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h(w, r, v2Lists)
+	}
+}
+
+func isV2(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/v2/")
+}
+
+// APIError is how /v2 reports errors: Code is the status text in
+// snake_case, for clients to switch on.
+type APIError struct {
+	Code    string `json:"code" example:"not_found"`
+	Message string `json:"message" example:"not found"`
+}
+
+// writeError writes an error in the shape of the request's API version.
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) error {
+	if !isV2(r) {
+		return writeJSONError(w, status, message)
+	}
+
+	code := strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	return writeJSON(w, status, &struct {
+		Error APIError `json:"error"`
+	}{APIError{Code: code, Message: message}})
+}
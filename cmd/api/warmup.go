@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/jobs"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store/cache"
+)
+
+const cacheWarmupJob = "cache_warmup"
+
+type WarmupReport struct {
+	Lists    int `json:"lists"`
+	Rosters  int `json:"rosters"`
+	Failures int `json:"failures"`
+}
+
+// WarmCache godoc
+//
+//	@Summary		Pre-populate hot cache keys
+//	@Description	Schedules a job that refreshes the default list pages and today's teacher rosters
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		202	{object}	jobs.Job
+//	@Failure		503	{object}	error	"Queue busy"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/cache/warm [post]
+//	@ID				warmCache
+func (app *application) warmCacheHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUser(r)
+	job, err := app.enqueueCacheWarmup(user.ID, user.Role)
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			app.serviceUnavailableResponse(w, r, err)
+			return
+		}
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusAccepted, job); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) enqueueCacheWarmup(ownerID int64, ownerRole string) (*jobs.Job, error) {
+	return app.jobs.Enqueue(cacheWarmupJob, ownerID, ownerRole, func(ctx context.Context) ([]byte, error) {
+		report := app.warmCaches(ctx)
+		app.logger.Infow("cache warm-up finished", "lists", report.Lists, "rosters", report.Rosters, "failures", report.Failures)
+		return json.Marshal(report)
+	})
+}
+
+// warmCaches refreshes the default page of each list endpoint and the rosters
+// of teachers expected to teach today. Failures are logged and counted; a
+// cold key only costs one DB query later.
+func (app *application) warmCaches(ctx context.Context) WarmupReport {
+	var report WarmupReport
+	pq := defaultListQuery
+	params := listCacheParams(pq)
+
+	record := func(what string, err error) bool {
+		if err != nil {
+			report.Failures++
+			app.logger.Warnw("cache warm-up failed", "key", what, "error", err.Error())
+			return false
+		}
+		return true
+	}
+
+	lists := []struct {
+		name string
+		warm func() error
+	}{
+		{"students:list", func() error {
+			return cache.RefreshList(ctx, app.cacheStorage.Students, "students:list", params, func(ctx context.Context) ([]*store.Student, error) {
+				return app.store.Students.GetAll(ctx, pq)
+			})
+		}},
+		{"teachers:list", func() error {
+			return cache.RefreshList(ctx, app.cacheStorage.Teachers, "teachers:list", params, func(ctx context.Context) ([]*store.Teacher, error) {
+				return app.store.Teachers.GetAll(ctx, pq)
+			})
+		}},
+		{"classrooms:list", func() error {
+			return cache.RefreshList(ctx, app.cacheStorage.Classrooms, "classrooms:list", params, func(ctx context.Context) ([]*store.Classroom, error) {
+				return app.store.Classrooms.GetAll(ctx, pq)
+			})
+		}},
+	}
+	for _, l := range lists {
+		if record(l.name, l.warm()) {
+			report.Lists++
+		}
+	}
+
+	teacherIDs, err := app.store.Teachers.GetTeachingToday(ctx)
+	if !record("teachers:today", err) {
+		return report
+	}
+	for _, id := range teacherIDs {
+		students, err := app.store.Students.GetByTeacherID(ctx, id)
+		if err == nil {
+			err = app.cacheStorage.Students.SetByTeacherID(ctx, id, students)
+		}
+		if record("students:teacher", err) {
+			report.Rosters++
+		}
+	}
+
+	return report
+}
+
+// startCacheWarmup schedules a warm-up right after boot.
+func (app *application) startCacheWarmup() {
+	if !app.config.redisCfg.enabled {
+		return
+	}
+	if _, err := app.enqueueCacheWarmup(0, "system"); err != nil {
+		app.logger.Errorw("scheduling cache warm-up failed", "error", err.Error())
+	}
+}
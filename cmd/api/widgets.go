@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/dto"
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+// Data a widget token can be scoped to.
+const (
+	widgetScopeCalendar  = "calendar"
+	widgetScopeLunchMenu = "lunch_menu"
+)
+
+const (
+	widgetTokenPrefix = "cnw_"
+	// widgetCalendarDays is how far ahead the calendar widget lists
+	// holidays and closures.
+	widgetCalendarDays = 30
+	// widgetMaxAge is how long browsers and proxies may cache widget data.
+	widgetMaxAge = 5 * 60
+)
+
+type CreateWidgetTokenPayload struct {
+	Name   string   `json:"name" validate:"required,max=100" example:"School website"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=calendar lunch_menu" example:"calendar,lunch_menu"`
+	// Origins are the sites allowed to embed the data, as scheme://host[:port].
+	Origins []string `json:"origins" validate:"required,min=1,max=10,dive,required" example:"https://school.example"`
+}
+
+// SignedWidgetToken is a widget token with the value websites embed. The
+// value is not secret: it is bound to the token's origins.
+type SignedWidgetToken struct {
+	*dto.WidgetToken
+	Token string `json:"token"`
+}
+
+// WidgetCalendar is what the calendar widget shows: whether classes are
+// held today and the holidays and closures coming up.
+type WidgetCalendar struct {
+	Date      string `json:"date" example:"2026-10-17"`
+	SchoolDay bool   `json:"school_day"`
+	// Today is the calendar entry deciding today, if any.
+	Today    *dto.CalendarDay   `json:"today,omitempty"`
+	Upcoming []*dto.CalendarDay `json:"upcoming"`
+}
+
+func (app *application) widgetTokenSignature(id int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.auth.token.secret))
+	fmt.Fprintf(mac, "widget:%d", id)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (app *application) signWidgetToken(t *store.WidgetToken) *SignedWidgetToken {
+	return &SignedWidgetToken{
+		WidgetToken: dto.FromWidgetToken(t),
+		Token:       widgetTokenPrefix + strconv.FormatInt(t.ID, 10) + "_" + app.widgetTokenSignature(t.ID),
+	}
+}
+
+// parseWidgetOrigin checks s is a bare origin and returns it as browsers
+// send it in the Origin header.
+func parseWidgetOrigin(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("origin %q must be scheme://host[:port]", s)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// widgetTokenMiddleware authorises a widget request by the token in the
+// token query parameter: it must be live, carry scope and be used from one
+// of its origins. The response then allows that origin to read it.
+func (app *application) widgetTokenMiddleware(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			id, sig, ok := strings.Cut(strings.TrimPrefix(r.URL.Query().Get("token"), widgetTokenPrefix), "_")
+			tokenID, err := strconv.ParseInt(id, 10, 64)
+			if !ok || err != nil || !hmac.Equal([]byte(sig), []byte(app.widgetTokenSignature(tokenID))) {
+				app.unauthorizedResponse(w, r, errors.New("invalid widget token"))
+				return
+			}
+
+			t, err := app.store.WidgetTokens.GetLive(r.Context(), tokenID)
+			if err != nil {
+				switch {
+				case errors.Is(err, store.ErrNotFound):
+					app.unauthorizedResponse(w, r, errors.New("invalid widget token"))
+				default:
+					app.internalServerErrorResponse(w, r, err)
+				}
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			if !slices.Contains(t.Scopes, scope) || !slices.Contains(t.Origins, origin) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", widgetMaxAge))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CreateWidgetToken godoc
+//
+//	@Summary		Create a widget token
+//	@Description	The token lets pages on the given origins read the scoped public data (calendar, lunch_menu) from /v1/widgets with ?token=. It is bound to those origins rather than secret.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateWidgetTokenPayload	true	"Token"
+//	@Success		201		{object}	SignedWidgetToken
+//	@Failure		400		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/admin/widget-tokens [post]
+//	@ID				createWidgetToken
+func (app *application) createWidgetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateWidgetTokenPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	origins := make([]string, 0, len(payload.Origins))
+	for _, s := range payload.Origins {
+		origin, err := parseWidgetOrigin(s)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		if !slices.Contains(origins, origin) {
+			origins = append(origins, origin)
+		}
+	}
+	scopes := slices.Compact(slices.Sorted(slices.Values(payload.Scopes)))
+
+	createdBy := getUser(r).ID
+	t := &store.WidgetToken{Name: payload.Name, Scopes: scopes, Origins: origins, CreatedBy: &createdBy}
+	if err := app.store.WidgetTokens.Create(r.Context(), t); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, app.signWidgetToken(t)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// ListWidgetTokens godoc
+//
+//	@Summary	List widget tokens, revoked ones included
+//	@Tags		Admin
+//	@Produce	json
+//	@Success	200	{array}	SignedWidgetToken
+//	@Security	ApiKeyAuth
+//	@Router		/admin/widget-tokens [get]
+//	@ID			listWidgetTokens
+func (app *application) listWidgetTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := app.store.WidgetTokens.List(r.Context())
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	signed := make([]*SignedWidgetToken, len(tokens))
+	for i, t := range tokens {
+		signed[i] = app.signWidgetToken(t)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, signed); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// RevokeWidgetToken godoc
+//
+//	@Summary	Revoke a widget token
+//	@Tags		Admin
+//	@Param		widgetTokenID	path	int	true	"Widget token ID"
+//	@Success	204				"No Content"
+//	@Failure	404				{object}	error	"No such live token"
+//	@Security	ApiKeyAuth
+//	@Router		/admin/widget-tokens/{widgetTokenID} [delete]
+//	@ID			revokeWidgetToken
+func (app *application) revokeWidgetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "widgetTokenID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.WidgetTokens.Revoke(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notfoundResponse(w, r, err)
+		default:
+			app.internalServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWidgetCalendar godoc
+//
+//	@Summary		Today's school status and upcoming holidays, for embedding
+//	@Description	Authorised by a widget token with the calendar scope, from one of its origins.
+//	@Tags			Widgets
+//	@Produce		json
+//	@Param			token	query		string	true	"Widget token"
+//	@Success		200		{object}	WidgetCalendar
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"Scope or origin not allowed"
+//	@Router			/widgets/calendar [get]
+//	@ID				getWidgetCalendar
+func (app *application) getWidgetCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	today := app.schoolToday()
+
+	open, day, err := app.store.SchoolDays.IsSchoolDay(r.Context(), today)
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+	upcoming, err := app.store.SchoolDays.List(r.Context(), today.AddDate(0, 0, 1), today.AddDate(0, 0, widgetCalendarDays))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	cal := &WidgetCalendar{Date: today.Format(time.DateOnly), SchoolDay: open, Upcoming: dto.FromCalendarDays(upcoming)}
+	if day != nil {
+		cal.Today = dto.FromCalendarDay(day)
+	}
+	if err := app.jsonResponse(w, http.StatusOK, cal); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
+
+// GetWidgetLunchMenu godoc
+//
+//	@Summary		The coming week's lunch menus, for embedding
+//	@Description	Menus from today on. Authorised by a widget token with the lunch_menu scope, from one of its origins.
+//	@Tags			Widgets
+//	@Produce		json
+//	@Param			token	query		string	true	"Widget token"
+//	@Success		200		{array}		dto.LunchMenu
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"Scope or origin not allowed"
+//	@Router			/widgets/lunch-menu [get]
+//	@ID				getWidgetLunchMenu
+func (app *application) getWidgetLunchMenuHandler(w http.ResponseWriter, r *http.Request) {
+	today := app.schoolToday()
+
+	menus, err := app.store.LunchMenus.List(r.Context(), today, today.AddDate(0, 0, lunchMenuDays-1))
+	if err != nil {
+		app.internalServerErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, dto.FromLunchMenus(menus)); err != nil {
+		app.internalServerErrorResponse(w, r, err)
+	}
+}
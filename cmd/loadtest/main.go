@@ -0,0 +1,187 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+//go:embed budget.json
+var defaultBudget []byte
+
+const usage = `usage: loadtest [flags]
+
+Runs the release load profile against a running API seeded with
+"go run ./cmd/migrate/seed", prints latency percentiles per endpoint and
+exits 1 when one is over its budget or regressed past the baseline.
+Start the server with RATE_LIMITER_ENABLED=false and CAPTCHA_ENABLED=false.
+
+flags:
+`
+
+// Budget is the most a scenario may take before a release is held back.
+type Budget struct {
+	P95          float64 `json:"p95_ms"`
+	P99          float64 `json:"p99_ms"`
+	MaxErrorRate float64 `json:"max_error_rate"`
+}
+
+// Report is a run's results; -record writes one to serve as the baseline
+// of later runs.
+type Report struct {
+	StartedAt time.Time         `json:"started_at"`
+	Target    string            `json:"target"`
+	Duration  string            `json:"duration"`
+	Scale     float64           `json:"scale"`
+	Results   map[string]Result `json:"results"`
+}
+
+func main() {
+	var (
+		baseURL       = flag.String("url", "http://localhost:8080/v1", "API base URL")
+		email         = flag.String("email", "exec0@example.com", "exec account to log in as")
+		password      = flag.String("password", "password123", "its password")
+		duration      = flag.Duration("duration", 30*time.Second, "how long to run each scenario")
+		scale         = flag.Float64("scale", 1, "multiply every scenario's rate")
+		only          = flag.String("scenario", "", "comma-separated scenarios to run (default all)")
+		maxInFlight   = flag.Int("max-in-flight", 200, "outstanding requests per scenario before arrivals are dropped")
+		budgetFile    = flag.String("budget", "", "budget file (default the built-in budget.json)")
+		baselineFile  = flag.String("baseline", "", "report of an earlier run to compare p95 against")
+		maxRegression = flag.Float64("max-regression", 0.25, "allowed p95 increase over the baseline, as a fraction")
+		recordFile    = flag.String("record", "", "write this run's report here")
+	)
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	budgetJSON := defaultBudget
+	if *budgetFile != "" {
+		var err error
+		if budgetJSON, err = os.ReadFile(*budgetFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var budgets map[string]Budget
+	if err := json.Unmarshal(budgetJSON, &budgets); err != nil {
+		log.Fatalf("reading budget: %v", err)
+	}
+
+	var baseline *Report
+	if *baselineFile != "" {
+		data, err := os.ReadFile(*baselineFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			log.Fatalf("reading baseline: %v", err)
+		}
+	}
+
+	scenarios := profile
+	if *only != "" {
+		names := strings.Split(*only, ",")
+		scenarios = slices.DeleteFunc(slices.Clone(profile), func(s scenario) bool {
+			return !slices.Contains(names, s.name)
+		})
+		if len(scenarios) == 0 {
+			log.Fatalf("no scenario named %q", *only)
+		}
+	}
+
+	c := &client{baseURL: strings.TrimRight(*baseURL, "/"), http: &http.Client{Timeout: 30 * time.Second}}
+	f, err := setup(c, *email, *password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report := Report{
+		StartedAt: time.Now().UTC(),
+		Target:    c.baseURL,
+		Duration:  duration.String(),
+		Scale:     *scale,
+		Results:   map[string]Result{},
+	}
+	log.Printf("running %d scenarios for %s against %s", len(scenarios), *duration, c.baseURL)
+
+	// the scenarios run together, as the traffic they stand for does
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, s := range scenarios {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := attack(c, f, s, s.rate**scale, *duration, *maxInFlight)
+			mu.Lock()
+			report.Results[s.name] = res
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "scenario\trequests\terrors\trps\tp50\tp95\tp99\tmax\t")
+	var failures []string
+	for _, s := range scenarios {
+		res := report.Results[s.name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+			s.name, res.Requests, res.Errors+res.Dropped, res.RPS, res.P50, res.P95, res.P99, res.Max)
+		failures = append(failures, check(s.name, res, budgets, baseline, *maxRegression)...)
+	}
+	tw.Flush()
+
+	if *recordFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*recordFile, append(data, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("report written to %s", *recordFile)
+	}
+
+	if len(failures) > 0 {
+		fmt.Println()
+		for _, msg := range failures {
+			fmt.Println("FAIL", msg)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("\nwithin budget")
+}
+
+// check compares a result with its budget and with the baseline.
+func check(name string, res Result, budgets map[string]Budget, baseline *Report, maxRegression float64) []string {
+	var failures []string
+	if b, ok := budgets[name]; ok {
+		if b.P95 > 0 && res.P95 > b.P95 {
+			failures = append(failures, fmt.Sprintf("%s: p95 %.1fms over budget %.0fms", name, res.P95, b.P95))
+		}
+		if b.P99 > 0 && res.P99 > b.P99 {
+			failures = append(failures, fmt.Sprintf("%s: p99 %.1fms over budget %.0fms", name, res.P99, b.P99))
+		}
+		if rate := res.errorRate(); rate > b.MaxErrorRate {
+			failures = append(failures, fmt.Sprintf("%s: error rate %.2f%% over budget %.2f%%", name, rate*100, b.MaxErrorRate*100))
+		}
+	}
+	if baseline != nil {
+		if base, ok := baseline.Results[name]; ok && base.P95 > 0 {
+			if limit := base.P95 * (1 + maxRegression); res.P95 > limit {
+				failures = append(failures, fmt.Sprintf("%s: p95 %.1fms regressed past baseline %.1fms (+%.0f%% allowed)",
+					name, res.P95, base.P95, maxRegression*100))
+			}
+		}
+	}
+	return failures
+}
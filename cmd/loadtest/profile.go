@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// scenario is one endpoint under load, requested at a constant rate.
+type scenario struct {
+	name   string
+	method string
+	path   func() string
+	// rate is requests per second at scale 1.
+	rate float64
+	auth bool
+	body func(f *fixture) any
+}
+
+// profile is the release load profile: the endpoints on the hot path of a
+// school day, at rates in proportion to production traffic. Login is kept
+// low since bcrypt makes each one cost a core for tens of milliseconds.
+var profile = []scenario{
+	{
+		name:   "students_list",
+		method: http.MethodGet,
+		// a spread of pages, so the list cache does not answer them all
+		path: func() string {
+			return fmt.Sprintf("/students?limit=20&sort_by=last_name&offset=%d", 20*rand.Intn(10))
+		},
+		rate: 40,
+		auth: true,
+	},
+	{
+		name:   "attendance_bulk_mark",
+		method: http.MethodPost,
+		path:   fixed("/attendance/bulk"),
+		rate:   5,
+		auth:   true,
+		body:   bulkAttendanceBody,
+	},
+	{
+		name:   "login",
+		method: http.MethodPost,
+		path:   fixed("/auth/login"),
+		rate:   2,
+		body:   loginBody,
+	},
+}
+
+func fixed(path string) func() string {
+	return func() string { return path }
+}
+
+// fixture is what the scenarios need from the seeded database.
+type fixture struct {
+	email       string
+	password    string
+	token       string
+	classroomID int64
+	studentIDs  []int64
+}
+
+func loginBody(f *fixture) any {
+	return map[string]string{"email": f.email, "password": f.password}
+}
+
+var attendanceStatuses = []string{"present", "present", "present", "absent", "late", "excused"}
+
+func bulkAttendanceBody(f *fixture) any {
+	type item struct {
+		StudentID int64  `json:"student_id"`
+		Status    string `json:"status"`
+	}
+	statuses := make([]item, len(f.studentIDs))
+	for i, id := range f.studentIDs {
+		statuses[i] = item{id, attendanceStatuses[rand.Intn(len(attendanceStatuses))]}
+	}
+	return map[string]any{
+		"classroom_id": f.classroomID,
+		"date":         time.Now().Format(time.DateOnly),
+		"statuses":     statuses,
+	}
+}
+
+// setup logs in and picks the classroom with the most students among the
+// first page for the bulk mark scenario.
+func setup(c *client, email, password string) (*fixture, error) {
+	f := &fixture{email: email, password: password}
+
+	var login struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := c.getJSON(http.MethodPost, "/auth/login", "", loginBody(f), &login); err != nil {
+		return nil, fmt.Errorf("logging in as %s: %w", email, err)
+	}
+	f.token = login.Data.Token
+
+	var students struct {
+		Data []struct {
+			ID          int64 `json:"id"`
+			ClassroomID int64 `json:"classroom_id"`
+		} `json:"data"`
+	}
+	if err := c.getJSON(http.MethodGet, "/students?limit=50", f.token, nil, &students); err != nil {
+		return nil, fmt.Errorf("listing students: %w", err)
+	}
+	byClassroom := map[int64][]int64{}
+	for _, s := range students.Data {
+		if s.ClassroomID != 0 {
+			byClassroom[s.ClassroomID] = append(byClassroom[s.ClassroomID], s.ID)
+		}
+	}
+	for id, ids := range byClassroom {
+		if len(ids) > len(f.studentIDs) {
+			f.classroomID, f.studentIDs = id, ids
+		}
+	}
+	if len(f.studentIDs) == 0 {
+		return nil, fmt.Errorf("no students in a classroom; seed the database first")
+	}
+	return f, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+type client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func (c *client) do(method, path, token string, body any) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.http.Do(req)
+}
+
+func (c *client) getJSON(method, path, token string, body, dst any) error {
+	resp, err := c.do(method, path, token, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, msg)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// Result is one scenario's numbers. Latencies are in milliseconds and are
+// measured from when the request was due, so a server that falls behind
+// is charged for the queueing too.
+type Result struct {
+	Requests int            `json:"requests"`
+	Errors   int            `json:"errors"`
+	Dropped  int            `json:"dropped"`
+	RPS      float64        `json:"rps"`
+	P50      float64        `json:"p50_ms"`
+	P95      float64        `json:"p95_ms"`
+	P99      float64        `json:"p99_ms"`
+	Max      float64        `json:"max_ms"`
+	Statuses map[string]int `json:"statuses"`
+}
+
+func (r Result) errorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors+r.Dropped) / float64(r.Requests+r.Dropped)
+}
+
+// attack requests s at rate per second for d, with at most maxInFlight
+// outstanding; arrivals beyond that are dropped and counted as errors.
+func attack(c *client, f *fixture, s scenario, rate float64, d time.Duration, maxInFlight int) Result {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		res       = Result{Statuses: map[string]int{}}
+		slots     = make(chan struct{}, maxInFlight)
+	)
+
+	interval := time.Duration(float64(time.Second) / rate)
+	start := time.Now()
+	for due := start; due.Sub(start) < d; due = due.Add(interval) {
+		time.Sleep(time.Until(due))
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			mu.Lock()
+			res.Dropped++
+			mu.Unlock()
+			continue
+		}
+
+		var body any
+		if s.body != nil {
+			body = s.body(f)
+		}
+		token := ""
+		if s.auth {
+			token = f.token
+		}
+
+		wg.Add(1)
+		go func(due time.Time) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			status := "error"
+			resp, err := c.do(s.method, s.path(), token, body)
+			if err == nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				status = fmt.Sprint(resp.StatusCode)
+			}
+			elapsed := time.Since(due)
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.Requests++
+			res.Statuses[status]++
+			if err != nil || resp.StatusCode >= 400 {
+				res.Errors++
+			}
+			latencies = append(latencies, elapsed)
+		}(due)
+	}
+	wg.Wait()
+
+	res.RPS = float64(res.Requests) / time.Since(start).Seconds()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	res.P50 = percentile(latencies, 0.50)
+	res.P95 = percentile(latencies, 0.95)
+	res.P99 = percentile(latencies, 0.99)
+	res.Max = percentile(latencies, 1)
+	return res
+}
+
+// percentile of sorted latencies, in milliseconds.
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	i = max(0, min(i, len(sorted)-1))
+	return float64(sorted[i].Microseconds()) / 1000
+}
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_classrooms_deleted_at;
+DROP INDEX IF EXISTS idx_teachers_deleted_at;
+DROP INDEX IF EXISTS idx_students_deleted_at;
+
+ALTER TABLE classrooms
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE teachers
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE students
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,16 @@
+ALTER TABLE students
+ADD COLUMN deleted_at TIMESTAMPTZ,
+ADD COLUMN deleted_by BIGINT;
+
+ALTER TABLE teachers
+ADD COLUMN deleted_at TIMESTAMPTZ,
+ADD COLUMN deleted_by BIGINT;
+
+ALTER TABLE classrooms
+ADD COLUMN deleted_at TIMESTAMPTZ,
+ADD COLUMN deleted_by BIGINT;
+
+-- Trash listing and retention purge scan only deleted rows
+CREATE INDEX IF NOT EXISTS idx_students_deleted_at ON students(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_teachers_deleted_at ON teachers(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_classrooms_deleted_at ON classrooms(deleted_at) WHERE deleted_at IS NOT NULL;
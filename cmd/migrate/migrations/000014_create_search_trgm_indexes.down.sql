@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_classrooms_name_trgm;
+DROP INDEX IF EXISTS idx_teachers_name_trgm;
+DROP INDEX IF EXISTS idx_students_name_trgm;
@@ -0,0 +1,9 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Typeahead search matches against the display name
+CREATE INDEX IF NOT EXISTS idx_students_name_trgm
+    ON students USING GIN ((first_name || ' ' || last_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_teachers_name_trgm
+    ON teachers USING GIN ((first_name || ' ' || last_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_classrooms_name_trgm
+    ON classrooms USING GIN (name gin_trgm_ops);
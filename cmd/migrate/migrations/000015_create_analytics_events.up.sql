@@ -0,0 +1,19 @@
+BEGIN;
+
+-- analytics_events is range-partitioned by month; the API creates upcoming
+-- partitions on startup, the default partition catches anything else.
+CREATE TABLE IF NOT EXISTS analytics_events (
+    id BIGSERIAL,
+    name TEXT NOT NULL,
+    actor_kind TEXT,
+    actor_id BIGINT,
+    properties JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, occurred_at)
+) PARTITION BY RANGE (occurred_at);
+
+CREATE TABLE IF NOT EXISTS analytics_events_default PARTITION OF analytics_events DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_analytics_events_name_occurred ON analytics_events(name, occurred_at);
+
+COMMIT;
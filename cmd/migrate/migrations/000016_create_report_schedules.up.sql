@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS report_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    report VARCHAR(64) NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    cron VARCHAR(128) NOT NULL,
+    format VARCHAR(8) NOT NULL DEFAULT 'csv',
+    delivery VARCHAR(16) NOT NULL DEFAULT 'attachment',
+    recipients TEXT[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    created_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_due ON report_schedules(next_run_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS report_runs (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL REFERENCES report_schedules(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'running',
+    error TEXT,
+    filename VARCHAR(256),
+    content_type VARCHAR(64),
+    content BYTEA,
+    size BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_runs_schedule ON report_runs(schedule_id, started_at DESC);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE teachers DROP COLUMN IF EXISTS subject_i18n;
+ALTER TABLE classrooms DROP COLUMN IF EXISTS name_i18n;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE classrooms ADD COLUMN IF NOT EXISTS name_i18n JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS subject_i18n JSONB NOT NULL DEFAULT '{}';
+
+COMMIT;
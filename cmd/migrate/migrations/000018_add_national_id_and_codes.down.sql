@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS teachers_national_id_key;
+DROP INDEX IF EXISTS students_national_id_key;
+DROP INDEX IF EXISTS teachers_staff_code_key;
+DROP INDEX IF EXISTS students_student_code_key;
+
+ALTER TABLE teachers DROP COLUMN IF EXISTS staff_code, DROP COLUMN IF EXISTS national_id;
+ALTER TABLE students DROP COLUMN IF EXISTS student_code, DROP COLUMN IF EXISTS national_id;
+
+DROP SEQUENCE IF EXISTS staff_code_seq;
+DROP SEQUENCE IF EXISTS student_code_seq;
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+CREATE SEQUENCE IF NOT EXISTS student_code_seq;
+CREATE SEQUENCE IF NOT EXISTS staff_code_seq;
+
+-- Existing rows are numbered by the column default as it is added.
+ALTER TABLE students
+    ADD COLUMN IF NOT EXISTS national_id CHAR(10),
+    ADD COLUMN IF NOT EXISTS student_code TEXT NOT NULL
+        DEFAULT 'S' || LPAD(nextval('student_code_seq')::text, 6, '0');
+
+ALTER TABLE teachers
+    ADD COLUMN IF NOT EXISTS national_id CHAR(10),
+    ADD COLUMN IF NOT EXISTS staff_code TEXT NOT NULL
+        DEFAULT 'T' || LPAD(nextval('staff_code_seq')::text, 6, '0');
+
+CREATE UNIQUE INDEX IF NOT EXISTS students_student_code_key ON students (student_code);
+CREATE UNIQUE INDEX IF NOT EXISTS teachers_staff_code_key ON teachers (staff_code);
+
+-- National IDs only need to be unique among live records, so a soft-deleted
+-- duplicate doesn't block re-registration.
+CREATE UNIQUE INDEX IF NOT EXISTS students_national_id_key
+    ON students (national_id) WHERE deleted_at IS NULL AND national_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS teachers_national_id_key
+    ON teachers (national_id) WHERE deleted_at IS NULL AND national_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_teachers_email_lower;
+DROP INDEX IF EXISTS idx_students_parent_phone;
+DROP INDEX IF EXISTS idx_students_phone;
+DROP INDEX IF EXISTS idx_students_email_lower;
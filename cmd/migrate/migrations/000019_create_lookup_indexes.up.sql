@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS idx_students_email_lower ON students (LOWER(email));
+CREATE INDEX IF NOT EXISTS idx_students_phone ON students (phone_number);
+CREATE INDEX IF NOT EXISTS idx_students_parent_phone ON students (parent_phone_number);
+CREATE INDEX IF NOT EXISTS idx_teachers_email_lower ON teachers (LOWER(email));
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS student_merges (
+    id BIGSERIAL PRIMARY KEY,
+    survivor_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    duplicate_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    merged_by BIGINT NOT NULL,
+    attendance_moved INT NOT NULL DEFAULT 0,
+    attendance_skipped INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_student_merges_survivor ON student_merges(survivor_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS entity_changes (
+    id BIGSERIAL PRIMARY KEY,
+    entity TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    changed_by BIGINT NOT NULL,
+    changed_by_role TEXT NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_changes_entity ON entity_changes(entity, entity_id, created_at DESC);
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS entity_notes;
+DROP TABLE IF EXISTS tag_assignments;
+DROP TABLE IF EXISTS tags;
+
+COMMIT;
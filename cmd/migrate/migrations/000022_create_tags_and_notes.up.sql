@@ -0,0 +1,33 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- entity is the table the tagged record lives in ("students", "teachers").
+CREATE TABLE IF NOT EXISTS tag_assignments (
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    entity TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (entity, entity_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_assignments_tag ON tag_assignments(tag_id, entity);
+
+CREATE TABLE IF NOT EXISTS entity_notes (
+    id BIGSERIAL PRIMARY KEY,
+    entity TEXT NOT NULL,
+    entity_id BIGINT NOT NULL,
+    body TEXT NOT NULL,
+    author_id BIGINT NOT NULL,
+    author_role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_notes_entity ON entity_notes(entity, entity_id, created_at DESC);
+
+COMMIT;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS attendance_reminders (
+    classroom_id BIGINT NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    teacher_id BIGINT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    delivered_via TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (classroom_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_reminders_teacher ON attendance_reminders(teacher_id, date);
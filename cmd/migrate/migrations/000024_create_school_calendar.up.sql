@@ -0,0 +1,9 @@
+-- Dated exceptions to the weekly school days: holidays and closures cancel
+-- a teaching day, school_day adds one (e.g. a make-up day on a weekend).
+CREATE TABLE IF NOT EXISTS school_calendar (
+    date DATE PRIMARY KEY,
+    kind TEXT NOT NULL CHECK (kind IN ('holiday', 'closure', 'school_day')),
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS online_sessions;
+
+ALTER TABLE classrooms
+    DROP COLUMN IF EXISTS meeting_url,
+    DROP COLUMN IF EXISTS mode;
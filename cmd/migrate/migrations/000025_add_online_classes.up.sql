@@ -0,0 +1,21 @@
+ALTER TABLE classrooms
+    ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'in_person'
+        CHECK (mode IN ('in_person', 'online', 'hybrid')),
+    ADD COLUMN IF NOT EXISTS meeting_url TEXT NOT NULL DEFAULT '';
+
+-- A live online class. Students who join are marked present; when the
+-- session closes everyone else in the classroom without a record for the day
+-- gets default_status.
+CREATE TABLE IF NOT EXISTS online_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    classroom_id BIGINT NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    teacher_id BIGINT REFERENCES teachers(id) ON DELETE SET NULL,
+    date DATE NOT NULL,
+    default_status TEXT NOT NULL CHECK (default_status IN ('present', 'absent', 'late', 'excused')),
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMPTZ,
+    defaulted INT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS online_sessions_open_idx
+    ON online_sessions (classroom_id) WHERE ended_at IS NULL;
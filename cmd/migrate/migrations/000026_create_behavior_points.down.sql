@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS point_summaries;
+DROP TABLE IF EXISTS behavior_points;
+DROP TABLE IF EXISTS point_categories;
+
+COMMIT;
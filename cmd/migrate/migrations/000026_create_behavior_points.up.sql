@@ -0,0 +1,37 @@
+BEGIN;
+
+-- Reasons points can be given for, each with its default value (negative
+-- for deductions). Categories are deactivated rather than deleted so past
+-- awards keep their label.
+CREATE TABLE IF NOT EXISTS point_categories (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    points INT NOT NULL CHECK (points <> 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS behavior_points (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    category_id BIGINT NOT NULL REFERENCES point_categories(id),
+    points INT NOT NULL CHECK (points <> 0),
+    reason TEXT NOT NULL DEFAULT '',
+    awarded_by BIGINT NOT NULL,
+    awarded_by_role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_behavior_points_student ON behavior_points(student_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_behavior_points_classroom ON behavior_points(classroom_id, created_at);
+
+-- One weekly summary per student and week, across instances.
+CREATE TABLE IF NOT EXISTS point_summaries (
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    week DATE NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (student_id, week)
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS survey_responses;
+DROP TABLE IF EXISTS surveys;
+
+COMMIT;
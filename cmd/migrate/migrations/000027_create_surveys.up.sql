@@ -0,0 +1,31 @@
+BEGIN;
+
+-- questions is a JSON array of {id, type, prompt, required, options,
+-- multiple, min, max}. The audience columns narrow who may answer; NULL
+-- means no restriction.
+CREATE TABLE IF NOT EXISTS surveys (
+    id BIGSERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    questions JSONB NOT NULL DEFAULT '[]',
+    audience_role TEXT CHECK (audience_role IN ('student', 'teacher')),
+    audience_grade BIGINT,
+    audience_classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'open', 'closed')),
+    closes_at TIMESTAMPTZ,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS survey_responses (
+    id BIGSERIAL PRIMARY KEY,
+    survey_id BIGINT NOT NULL REFERENCES surveys(id) ON DELETE CASCADE,
+    respondent_id BIGINT NOT NULL,
+    respondent_role TEXT NOT NULL,
+    answers JSONB NOT NULL,
+    submitted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (survey_id, respondent_role, respondent_id)
+);
+
+COMMIT;
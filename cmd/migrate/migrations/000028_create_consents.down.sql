@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS consents;
+DROP TABLE IF EXISTS consent_requests;
+
+COMMIT;
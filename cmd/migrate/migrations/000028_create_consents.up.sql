@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS consent_requests (
+    id BIGSERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL CHECK (kind IN ('trip', 'media', 'other')),
+    due_date DATE NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per student asked. Parents answer through a signed link; the
+-- answer's time, name and IP are kept as evidence.
+CREATE TABLE IF NOT EXISTS consents (
+    id BIGSERIAL PRIMARY KEY,
+    request_id BIGINT NOT NULL REFERENCES consent_requests(id) ON DELETE CASCADE,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'declined')),
+    responder_name TEXT NOT NULL DEFAULT '',
+    responder_ip TEXT NOT NULL DEFAULT '',
+    responded_at TIMESTAMPTZ,
+    UNIQUE (request_id, student_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consents_student ON consents(student_id);
+
+COMMIT;
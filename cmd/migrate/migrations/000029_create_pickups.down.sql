@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS pickup_checkouts;
+DROP TABLE IF EXISTS pickup_contacts;
+
+COMMIT;
@@ -0,0 +1,37 @@
+BEGIN;
+
+-- People allowed to pick a student up. Photos are small ID pictures shown
+-- to staff at the gate.
+CREATE TABLE IF NOT EXISTS pickup_contacts (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    relation TEXT NOT NULL,
+    phone_number TEXT NOT NULL DEFAULT '',
+    photo BYTEA,
+    photo_type TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pickup_contacts_student ON pickup_contacts(student_id);
+
+-- Every gate check, allowed or not. contact_name is copied so the log
+-- survives contact edits and deletion.
+CREATE TABLE IF NOT EXISTS pickup_checkouts (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    contact_id BIGINT REFERENCES pickup_contacts(id) ON DELETE SET NULL,
+    contact_name TEXT NOT NULL DEFAULT '',
+    authorized BOOLEAN NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    checked_by BIGINT NOT NULL,
+    checked_by_role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pickup_checkouts_created ON pickup_checkouts(created_at);
+CREATE INDEX IF NOT EXISTS idx_pickup_checkouts_student ON pickup_checkouts(student_id, created_at);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS asset_loans;
+DROP TABLE IF EXISTS assets;
+
+COMMIT;
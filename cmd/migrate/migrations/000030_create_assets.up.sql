@@ -0,0 +1,35 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS assets (
+    id BIGSERIAL PRIMARY KEY,
+    tag TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    category TEXT NOT NULL CHECK (category IN ('laptop', 'tablet', 'lab', 'other')),
+    serial_number TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'available'
+        CHECK (status IN ('available', 'checked_out', 'maintenance', 'retired')),
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Check-out history. holder_type/holder_id point at a classroom, teacher or
+-- student; an asset has at most one open loan.
+CREATE TABLE IF NOT EXISTS asset_loans (
+    id BIGSERIAL PRIMARY KEY,
+    asset_id BIGINT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+    holder_type TEXT NOT NULL CHECK (holder_type IN ('classroom', 'teacher', 'student')),
+    holder_id BIGINT NOT NULL,
+    checked_out_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    due_date DATE,
+    checked_out_by BIGINT NOT NULL,
+    condition_out TEXT NOT NULL DEFAULT '',
+    returned_at TIMESTAMPTZ,
+    returned_to BIGINT,
+    condition_in TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS asset_loans_open_idx ON asset_loans(asset_id) WHERE returned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_asset_loans_holder ON asset_loans(holder_type, holder_id) WHERE returned_at IS NULL;
+
+COMMIT;
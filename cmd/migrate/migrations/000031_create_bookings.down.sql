@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS booking_resources;
+
+COMMIT;
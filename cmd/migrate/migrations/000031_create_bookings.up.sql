@@ -0,0 +1,38 @@
+BEGIN;
+
+-- Lets the exclusion constraint below combine = on resource_id with && on
+-- the booked time range.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS booking_resources (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    kind TEXT NOT NULL CHECK (kind IN ('room', 'equipment')),
+    location TEXT NOT NULL DEFAULT '',
+    capacity INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    id BIGSERIAL PRIMARY KEY,
+    resource_id BIGINT NOT NULL REFERENCES booking_resources(id) ON DELETE CASCADE,
+    booked_by BIGINT NOT NULL,
+    booked_by_role TEXT NOT NULL,
+    classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+    purpose TEXT NOT NULL DEFAULT '',
+    cancelled_at TIMESTAMPTZ,
+    cancelled_by BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT bookings_no_overlap EXCLUDE USING gist (
+        resource_id WITH =,
+        tstzrange(starts_at, ends_at) WITH &&
+    ) WHERE (cancelled_at IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookings_booked_by ON bookings(booked_by_role, booked_by, starts_at);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS mobile_checkins;
+
+ALTER TABLE attendance_records
+    DROP COLUMN IF EXISTS checked_in_at,
+    DROP COLUMN IF EXISTS method;
+
+COMMIT;
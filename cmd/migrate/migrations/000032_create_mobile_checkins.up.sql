@@ -0,0 +1,26 @@
+BEGIN;
+
+-- method records how the status was last set; checked_in_at is when the
+-- student checked in from their phone, if they did.
+ALTER TABLE attendance_records
+    ADD COLUMN IF NOT EXISTS method TEXT NOT NULL DEFAULT 'manual'
+        CHECK (method IN ('manual', 'online', 'geofence')),
+    ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS mobile_checkins (
+    id BIGSERIAL PRIMARY KEY,
+    user_role TEXT NOT NULL CHECK (user_role IN ('student', 'teacher')),
+    user_id BIGINT NOT NULL,
+    date DATE NOT NULL,
+    checked_in_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    accuracy DOUBLE PRECISION,
+    distance DOUBLE PRECISION NOT NULL,
+    attendance_id BIGINT REFERENCES attendance_records(id) ON DELETE SET NULL,
+    UNIQUE (user_role, user_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mobile_checkins_date ON mobile_checkins(date);
+
+COMMIT;
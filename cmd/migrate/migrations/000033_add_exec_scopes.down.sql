@@ -0,0 +1,3 @@
+ALTER TABLE execs
+    DROP COLUMN IF EXISTS scope_classrooms,
+    DROP COLUMN IF EXISTS scope_grades;
@@ -0,0 +1,5 @@
+-- Managers can be limited to some grades and/or classrooms; both empty means
+-- the whole school.
+ALTER TABLE execs
+    ADD COLUMN IF NOT EXISTS scope_grades BIGINT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS scope_classrooms BIGINT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,9 @@
+-- Parents sign in with a one-time code sent to the phone number recorded on
+-- their children (students.parent_phone_number); a row is created on first
+-- login.
+CREATE TABLE IF NOT EXISTS parents (
+    id BIGSERIAL PRIMARY KEY,
+    phone_number TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
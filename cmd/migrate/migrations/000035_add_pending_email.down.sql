@@ -0,0 +1,3 @@
+ALTER TABLE students DROP COLUMN IF EXISTS pending_email;
+ALTER TABLE teachers DROP COLUMN IF EXISTS pending_email;
+ALTER TABLE execs DROP COLUMN IF EXISTS pending_email;
@@ -0,0 +1,4 @@
+-- An email change waits here until it is confirmed from the new address.
+ALTER TABLE execs ADD COLUMN IF NOT EXISTS pending_email TEXT;
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS pending_email TEXT;
+ALTER TABLE students ADD COLUMN IF NOT EXISTS pending_email TEXT;
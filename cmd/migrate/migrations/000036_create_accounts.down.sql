@@ -0,0 +1,26 @@
+DROP TRIGGER IF EXISTS students_sync_account ON students;
+DROP TRIGGER IF EXISTS teachers_sync_account ON teachers;
+DROP TRIGGER IF EXISTS execs_sync_account ON execs;
+DROP FUNCTION IF EXISTS sync_account();
+
+ALTER TABLE execs ADD COLUMN IF NOT EXISTS password TEXT;
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS password TEXT;
+ALTER TABLE students ADD COLUMN IF NOT EXISTS password BYTEA;
+
+UPDATE execs e SET password = convert_from(a.password, 'UTF8')
+FROM accounts a WHERE a.profile_type = 'exec' AND a.profile_id = e.id;
+UPDATE teachers t SET password = convert_from(a.password, 'UTF8')
+FROM accounts a WHERE a.profile_type = 'teacher' AND a.profile_id = t.id;
+UPDATE students s SET password = a.password
+FROM accounts a WHERE a.profile_type = 'student' AND a.profile_id = s.id;
+
+-- Profiles that never got an account cannot sign in either way.
+UPDATE execs SET password = '' WHERE password IS NULL;
+UPDATE teachers SET password = '' WHERE password IS NULL;
+UPDATE students SET password = ''::bytea WHERE password IS NULL;
+
+ALTER TABLE execs ALTER COLUMN password SET NOT NULL;
+ALTER TABLE teachers ALTER COLUMN password SET NOT NULL;
+ALTER TABLE students ALTER COLUMN password SET NOT NULL;
+
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,92 @@
+-- accounts holds the credentials for every kind of user; the exec, teacher,
+-- student and parent tables keep the profile. Email is still stored on the
+-- profile for listing and search, and the triggers below keep the copy here
+-- in step with it.
+CREATE TABLE IF NOT EXISTS accounts (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT,
+    phone_number TEXT,
+    password BYTEA,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'manager', 'teacher', 'student', 'parent')),
+    profile_type TEXT NOT NULL CHECK (profile_type IN ('exec', 'teacher', 'student', 'parent')),
+    profile_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ,
+    UNIQUE (profile_type, profile_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS accounts_email_key ON accounts (LOWER(email)) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS accounts_parent_phone_key ON accounts (phone_number) WHERE profile_type = 'parent';
+
+-- An email used by more than one exec, teacher or student can only get
+-- one account, and the others' passwords would be lost with the columns
+-- dropped below. Stop instead, naming the clashes so they can be resolved
+-- (e.g. by changing one of the addresses) before migrating again.
+DO $$
+DECLARE
+    clashes TEXT;
+BEGIN
+    SELECT string_agg(format('%s (%s)', email, profiles), '; ' ORDER BY email) INTO clashes
+    FROM (
+        SELECT LOWER(email) AS email, string_agg(kind || ' ' || id, ', ' ORDER BY kind, id) AS profiles
+        FROM (
+            SELECT 'exec' AS kind, id, email FROM execs
+            UNION ALL SELECT 'teacher', id, email FROM teachers
+            UNION ALL SELECT 'student', id, email FROM students
+        ) p
+        WHERE email IS NOT NULL
+        GROUP BY LOWER(email)
+        HAVING COUNT(*) > 1
+    ) c;
+
+    IF clashes IS NOT NULL THEN
+        RAISE EXCEPTION 'emails shared by more than one profile: %', clashes
+            USING HINT = 'give each profile its own email, then run the migration again';
+    END IF;
+END;
+$$;
+
+INSERT INTO accounts (email, password, role, profile_type, profile_id, created_at)
+SELECT email, convert_to(password, 'UTF8'), role, 'exec', id, created_at FROM execs;
+
+INSERT INTO accounts (email, password, role, profile_type, profile_id, created_at)
+SELECT email, convert_to(password, 'UTF8'), 'teacher', 'teacher', id, created_at FROM teachers;
+
+INSERT INTO accounts (email, password, role, profile_type, profile_id, created_at)
+SELECT email, password, 'student', 'student', id, created_at FROM students;
+
+INSERT INTO accounts (phone_number, role, profile_type, profile_id, created_at, last_login_at)
+SELECT phone_number, 'parent', 'parent', id, created_at, last_login_at FROM parents
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE execs DROP COLUMN IF EXISTS password;
+ALTER TABLE teachers DROP COLUMN IF EXISTS password;
+ALTER TABLE students DROP COLUMN IF EXISTS password;
+
+-- sync_account follows a profile's email (and an exec's role) and removes
+-- the account when the profile row is deleted. TG_ARGV[0] is the profile
+-- type.
+CREATE OR REPLACE FUNCTION sync_account() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        DELETE FROM accounts WHERE profile_type = TG_ARGV[0] AND profile_id = OLD.id;
+        RETURN OLD;
+    END IF;
+
+    UPDATE accounts SET email = NEW.email
+    WHERE profile_type = TG_ARGV[0] AND profile_id = NEW.id AND email IS DISTINCT FROM NEW.email;
+
+    IF TG_ARGV[0] = 'exec' THEN
+        UPDATE accounts SET role = NEW.role
+        WHERE profile_type = 'exec' AND profile_id = NEW.id AND role IS DISTINCT FROM NEW.role;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER execs_sync_account AFTER UPDATE OF email, role OR DELETE ON execs
+    FOR EACH ROW EXECUTE FUNCTION sync_account('exec');
+CREATE TRIGGER teachers_sync_account AFTER UPDATE OF email OR DELETE ON teachers
+    FOR EACH ROW EXECUTE FUNCTION sync_account('teacher');
+CREATE TRIGGER students_sync_account AFTER UPDATE OF email OR DELETE ON students
+    FOR EACH ROW EXECUTE FUNCTION sync_account('student');
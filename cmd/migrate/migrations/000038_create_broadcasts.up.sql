@@ -0,0 +1,21 @@
+-- Emergency broadcasts, kept as the audit trail of who sent what to whom
+-- and how many messages went out.
+CREATE TABLE IF NOT EXISTS broadcasts (
+    id BIGSERIAL PRIMARY KEY,
+    subject TEXT NOT NULL,
+    message TEXT NOT NULL,
+    groups TEXT[] NOT NULL,
+    classroom_id BIGINT,
+    grade BIGINT,
+    channels TEXT[] NOT NULL,
+    sent_by BIGINT NOT NULL,
+    sent_from_ip TEXT NOT NULL DEFAULT '',
+    recipients INT NOT NULL DEFAULT 0,
+    sms_sent INT NOT NULL DEFAULT 0,
+    email_sent INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcasts_created_at ON broadcasts (created_at);
@@ -0,0 +1,19 @@
+-- Texts exchanged with parents, one thread per phone number. Outbound
+-- messages that expect an answer (absence notices) are recorded so a reply
+-- can be matched to them; action records what a reply did.
+CREATE TABLE IF NOT EXISTS sms_messages (
+    id BIGSERIAL PRIMARY KEY,
+    phone_number TEXT NOT NULL,
+    direction TEXT NOT NULL CHECK (direction IN ('in', 'out')),
+    kind TEXT NOT NULL CHECK (kind IN ('absence', 'reply', 'confirmation')),
+    body TEXT NOT NULL,
+    student_id BIGINT REFERENCES students(id) ON DELETE SET NULL,
+    attendance_id BIGINT REFERENCES attendance_records(id) ON DELETE SET NULL,
+    reply_to BIGINT REFERENCES sms_messages(id) ON DELETE SET NULL,
+    action TEXT NOT NULL DEFAULT '',
+    provider_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sms_messages_phone ON sms_messages (phone_number, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS sms_messages_absence_key ON sms_messages (attendance_id) WHERE kind = 'absence';
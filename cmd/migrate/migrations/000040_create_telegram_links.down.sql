@@ -0,0 +1,3 @@
+ALTER TABLE broadcasts DROP COLUMN IF EXISTS telegram_sent;
+
+DROP TABLE IF EXISTS telegram_links;
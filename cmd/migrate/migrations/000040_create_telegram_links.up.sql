@@ -0,0 +1,12 @@
+-- Telegram chats linked to parents. A parent asks for a one-time code in
+-- the app and sends it to the bot; code_hash is cleared once used.
+CREATE TABLE IF NOT EXISTS telegram_links (
+    parent_id BIGINT PRIMARY KEY REFERENCES parents(id) ON DELETE CASCADE,
+    chat_id BIGINT UNIQUE,
+    code_hash BYTEA UNIQUE,
+    code_expires_at TIMESTAMPTZ,
+    linked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE broadcasts ADD COLUMN IF NOT EXISTS telegram_sent INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lms_syncs;
+
+DROP TABLE IF EXISTS grades;
@@ -0,0 +1,33 @@
+-- Scores imported from learning management systems, one per student,
+-- classroom, source and assignment; a re-import overwrites.
+CREATE TABLE IF NOT EXISTS grades (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    classroom_id BIGINT NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    assignment TEXT NOT NULL,
+    score NUMERIC(10, 2) NOT NULL,
+    max_score NUMERIC(10, 2),
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (student_id, classroom_id, source, assignment)
+);
+
+CREATE INDEX IF NOT EXISTS idx_grades_classroom ON grades (classroom_id);
+
+-- Per-classroom LMS sync: the roster is pushed to roster_url and grades
+-- pulled from grades_url every interval_minutes.
+CREATE TABLE IF NOT EXISTS lms_syncs (
+    classroom_id BIGINT PRIMARY KEY REFERENCES classrooms(id) ON DELETE CASCADE,
+    format TEXT NOT NULL CHECK (format IN ('google_csv', 'oneroster')),
+    grades_source TEXT NOT NULL CHECK (grades_source IN ('google_classroom', 'moodle', 'oneroster', 'csv')),
+    roster_url TEXT NOT NULL DEFAULT '',
+    grades_url TEXT NOT NULL DEFAULT '',
+    auth_token TEXT NOT NULL DEFAULT '',
+    interval_minutes INT NOT NULL DEFAULT 1440,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_run_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
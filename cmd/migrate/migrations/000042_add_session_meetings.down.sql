@@ -0,0 +1,4 @@
+ALTER TABLE online_sessions
+    DROP COLUMN IF EXISTS meeting_url,
+    DROP COLUMN IF EXISTS meeting_id,
+    DROP COLUMN IF EXISTS meeting_provider;
@@ -0,0 +1,7 @@
+-- A meeting provisioned for an online session by the configured provider.
+-- meeting_id is the provider's handle, used to revoke the meeting when the
+-- session closes; sessions without one use the classroom's meeting_url.
+ALTER TABLE online_sessions
+    ADD COLUMN IF NOT EXISTS meeting_provider TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS meeting_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS meeting_url TEXT NOT NULL DEFAULT '';
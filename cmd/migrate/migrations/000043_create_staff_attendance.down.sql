@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS staff_attendance;
+
+DROP TABLE IF EXISTS staff_devices;
@@ -0,0 +1,34 @@
+BEGIN;
+
+-- Phones and tablets a teacher may mark their own attendance from.
+-- device_id is the identifier the app generates on first launch.
+CREATE TABLE IF NOT EXISTS staff_devices (
+    id BIGSERIAL PRIMARY KEY,
+    teacher_id BIGINT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    device_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_staff_devices_teacher ON staff_devices(teacher_id);
+
+-- A teacher's own arrival and departure for a day, kept apart from student
+-- attendance_records.
+CREATE TABLE IF NOT EXISTS staff_attendance (
+    id BIGSERIAL PRIMARY KEY,
+    teacher_id BIGINT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    arrived_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    late BOOLEAN NOT NULL DEFAULT FALSE,
+    arrival_ip TEXT NOT NULL DEFAULT '',
+    arrival_device_id BIGINT REFERENCES staff_devices(id) ON DELETE SET NULL,
+    departed_at TIMESTAMPTZ,
+    departure_ip TEXT NOT NULL DEFAULT '',
+    departure_device_id BIGINT REFERENCES staff_devices(id) ON DELETE SET NULL,
+    UNIQUE (teacher_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_staff_attendance_date ON staff_attendance(date);
+
+COMMIT;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS expense_claims;
+
+DROP TABLE IF EXISTS payslips;
+
+DROP TABLE IF EXISTS staff_contracts;
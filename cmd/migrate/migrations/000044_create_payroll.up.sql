@@ -0,0 +1,62 @@
+BEGIN;
+
+-- Employment terms of a teacher or exec. Amounts are monthly, in the
+-- school's currency; a staff member has at most one open contract.
+CREATE TABLE IF NOT EXISTS staff_contracts (
+    id BIGSERIAL PRIMARY KEY,
+    staff_kind TEXT NOT NULL CHECK (staff_kind IN ('teacher', 'exec')),
+    staff_id BIGINT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    base_salary BIGINT NOT NULL CHECK (base_salary >= 0),
+    allowances BIGINT NOT NULL DEFAULT 0 CHECK (allowances >= 0),
+    deductions BIGINT NOT NULL DEFAULT 0 CHECK (deductions >= 0),
+    starts_on DATE NOT NULL,
+    ends_on DATE CHECK (ends_on >= starts_on),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS staff_contracts_open_idx
+    ON staff_contracts (staff_kind, staff_id) WHERE ends_on IS NULL;
+
+-- A month's pay, copied from the contract when generated so later
+-- contract changes don't alter issued payslips.
+CREATE TABLE IF NOT EXISTS payslips (
+    id BIGSERIAL PRIMARY KEY,
+    contract_id BIGINT NOT NULL REFERENCES staff_contracts(id) ON DELETE RESTRICT,
+    staff_kind TEXT NOT NULL,
+    staff_id BIGINT NOT NULL,
+    period DATE NOT NULL,
+    base_salary BIGINT NOT NULL,
+    allowances BIGINT NOT NULL,
+    reimbursements BIGINT NOT NULL DEFAULT 0,
+    deductions BIGINT NOT NULL,
+    net BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (staff_kind, staff_id, period)
+);
+
+-- Out-of-pocket spending a staff member asks to be paid back. Approved
+-- claims are added to the next payslip.
+CREATE TABLE IF NOT EXISTS expense_claims (
+    id BIGSERIAL PRIMARY KEY,
+    staff_kind TEXT NOT NULL CHECK (staff_kind IN ('teacher', 'exec')),
+    staff_id BIGINT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    description TEXT NOT NULL,
+    spent_on DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    receipt BYTEA,
+    receipt_type TEXT NOT NULL DEFAULT '',
+    reviewed_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    review_note TEXT NOT NULL DEFAULT '',
+    payslip_id BIGINT REFERENCES payslips(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_claims_staff ON expense_claims(staff_kind, staff_id);
+CREATE INDEX IF NOT EXISTS idx_expense_claims_unpaid
+    ON expense_claims(staff_kind, staff_id) WHERE status = 'approved' AND payslip_id IS NULL;
+
+COMMIT;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS outbox_events;
+
+DROP TABLE IF EXISTS payments;
+
+DROP TABLE IF EXISTS invoices;
@@ -0,0 +1,58 @@
+BEGIN;
+
+-- A fee billed to a student's parents. Amounts are in the school's
+-- currency.
+CREATE TABLE IF NOT EXISTS invoices (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE RESTRICT,
+    title TEXT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    due_on DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'unpaid' CHECK (status IN ('unpaid', 'paid', 'void')),
+    paid_at TIMESTAMPTZ,
+    created_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_student ON invoices(student_id);
+CREATE INDEX IF NOT EXISTS idx_invoices_status_due ON invoices(status, due_on);
+
+-- An attempt to pay an invoice through a payment gateway. authority is the
+-- gateway's session identifier, ref_id its reference once verified.
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE RESTRICT,
+    gateway TEXT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    authority TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'failed')),
+    ref_id TEXT NOT NULL DEFAULT '',
+    card_pan TEXT NOT NULL DEFAULT '',
+    failure TEXT NOT NULL DEFAULT '',
+    parent_id BIGINT REFERENCES parents(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    verified_at TIMESTAMPTZ,
+    UNIQUE (gateway, authority)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_invoice ON payments(invoice_id);
+
+-- Events written in the same transaction as the change they describe and
+-- delivered afterwards by the API's relay, so a notification is never sent
+-- for a change that rolled back nor lost for one that committed.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending
+    ON outbox_events(available_at) WHERE dispatched_at IS NULL;
+
+COMMIT;
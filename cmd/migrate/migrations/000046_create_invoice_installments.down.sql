@@ -0,0 +1,3 @@
+ALTER TABLE payments DROP COLUMN IF EXISTS installment_id;
+
+DROP TABLE IF EXISTS invoice_installments;
@@ -0,0 +1,32 @@
+BEGIN;
+
+-- The schedule an invoice is paid on. Every invoice has at least one
+-- installment; a plan splits its amount over several. reminders_sent counts
+-- the overdue notices sent so far, for escalating dunning.
+CREATE TABLE IF NOT EXISTS invoice_installments (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    seq INT NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    due_on DATE NOT NULL,
+    paid_at TIMESTAMPTZ,
+    reminders_sent INT NOT NULL DEFAULT 0,
+    last_reminded_at TIMESTAMPTZ,
+    UNIQUE (invoice_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_installments_unpaid
+    ON invoice_installments(due_on) WHERE paid_at IS NULL;
+
+INSERT INTO invoice_installments (invoice_id, seq, amount, due_on, paid_at)
+SELECT id, 1, amount, due_on, paid_at FROM invoices
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS installment_id BIGINT REFERENCES invoice_installments(id) ON DELETE SET NULL;
+
+UPDATE payments p SET installment_id = n.id
+FROM invoice_installments n
+WHERE n.invoice_id = p.invoice_id AND p.installment_id IS NULL;
+
+COMMIT;
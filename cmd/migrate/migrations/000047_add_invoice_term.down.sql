@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invoices_term;
+
+ALTER TABLE invoices DROP COLUMN IF EXISTS term;
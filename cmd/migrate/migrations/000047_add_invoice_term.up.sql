@@ -0,0 +1,4 @@
+-- The academic term an invoice bills for, e.g. "1404-1", for reporting.
+ALTER TABLE invoices ADD COLUMN IF NOT EXISTS term TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_invoices_term ON invoices(term);
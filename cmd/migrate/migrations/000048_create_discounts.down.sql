@@ -0,0 +1,8 @@
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_amount_check;
+ALTER TABLE invoices ADD CONSTRAINT invoices_amount_check CHECK (amount > 0) NOT VALID;
+
+DROP TABLE IF EXISTS invoice_lines;
+
+DROP TABLE IF EXISTS student_discounts;
+
+DROP TABLE IF EXISTS discount_rules;
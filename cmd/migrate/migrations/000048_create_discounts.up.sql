@@ -0,0 +1,53 @@
+BEGIN;
+
+-- Discounts applied when invoices are created. A sibling rule applies from
+-- the min_sibling_rank-th child of a family (eldest first); a staff_child
+-- rule to children of teachers and execs; a scholarship rule only to the
+-- students it is granted to in student_discounts.
+CREATE TABLE IF NOT EXISTS discount_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('sibling', 'staff_child', 'scholarship')),
+    percent INT NOT NULL CHECK (percent BETWEEN 0 AND 100),
+    min_sibling_rank INT NOT NULL DEFAULT 2 CHECK (min_sibling_rank >= 2),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-student overrides of a rule. A NULL percent keeps the rule's; 0
+-- exempts the student. For scholarship rules the row grants the
+-- scholarship.
+CREATE TABLE IF NOT EXISTS student_discounts (
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    rule_id BIGINT NOT NULL REFERENCES discount_rules(id) ON DELETE CASCADE,
+    percent INT CHECK (percent BETWEEN 0 AND 100),
+    note TEXT NOT NULL DEFAULT '',
+    created_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (student_id, rule_id)
+);
+
+-- What an invoice's amount is made of: the fee and a negative line per
+-- discount, recording the rule and why it applied.
+CREATE TABLE IF NOT EXISTS invoice_lines (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    description TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    rule_id BIGINT REFERENCES discount_rules(id) ON DELETE SET NULL,
+    percent INT,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_lines_invoice ON invoice_lines(invoice_id);
+
+INSERT INTO invoice_lines (invoice_id, description, amount, created_at)
+SELECT id, title, amount, created_at FROM invoices;
+
+-- a full scholarship leaves nothing to pay
+ALTER TABLE invoices DROP CONSTRAINT IF EXISTS invoices_amount_check;
+ALTER TABLE invoices ADD CONSTRAINT invoices_amount_check CHECK (amount >= 0);
+
+COMMIT;
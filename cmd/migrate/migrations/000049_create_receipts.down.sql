@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS receipts;
+
+DROP TABLE IF EXISTS receipt_counters;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- The last receipt number issued under each prefix. Numbers are taken by
+-- updating the row inside the payment's transaction, so they have no gaps.
+CREATE TABLE IF NOT EXISTS receipt_counters (
+    prefix TEXT PRIMARY KEY,
+    last_seq BIGINT NOT NULL DEFAULT 0
+);
+
+-- A receipt for a confirmed payment. code is printed on the receipt and
+-- must be given with the number to verify it, so receipts cannot be looked
+-- up by guessing numbers.
+CREATE TABLE IF NOT EXISTS receipts (
+    id BIGSERIAL PRIMARY KEY,
+    number TEXT NOT NULL UNIQUE,
+    payment_id BIGINT NOT NULL UNIQUE REFERENCES payments(id) ON DELETE RESTRICT,
+    code TEXT NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,41 @@
+BEGIN;
+
+CREATE TABLE attendance_records_unpartitioned (
+    id BIGINT PRIMARY KEY DEFAULT nextval('attendance_records_id_seq'),
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    teacher_id BIGINT REFERENCES teachers(id) ON DELETE SET NULL,
+    classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    date DATE NOT NULL,
+    status attendance_status NOT NULL DEFAULT 'present',
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    method TEXT NOT NULL DEFAULT 'manual' CHECK (method IN ('manual', 'online', 'geofence')),
+    checked_in_at TIMESTAMPTZ,
+    UNIQUE (student_id, date)
+);
+
+INSERT INTO attendance_records_unpartitioned (id, student_id, teacher_id, classroom_id, date, status, note, created_at, method, checked_in_at)
+SELECT id, student_id, teacher_id, classroom_id, date, status, note, created_at, method, checked_in_at
+FROM attendance_records;
+
+ALTER SEQUENCE attendance_records_id_seq OWNED BY attendance_records_unpartitioned.id;
+DROP TABLE attendance_records;
+
+ALTER TABLE attendance_records_unpartitioned RENAME TO attendance_records;
+ALTER TABLE attendance_records RENAME CONSTRAINT attendance_records_unpartitioned_pkey TO attendance_records_pkey;
+ALTER TABLE attendance_records RENAME CONSTRAINT attendance_records_unpartitioned_student_id_date_key TO attendance_records_student_id_date_key;
+
+CREATE INDEX IF NOT EXISTS idx_attendance_student_date ON attendance_records(student_id, date);
+CREATE INDEX IF NOT EXISTS idx_attendance_classroom_date ON attendance_records(classroom_id, date);
+
+UPDATE mobile_checkins m SET attendance_id = NULL
+WHERE attendance_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM attendance_records a WHERE a.id = m.attendance_id);
+UPDATE sms_messages m SET attendance_id = NULL
+WHERE attendance_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM attendance_records a WHERE a.id = m.attendance_id);
+
+ALTER TABLE mobile_checkins ADD CONSTRAINT mobile_checkins_attendance_id_fkey
+    FOREIGN KEY (attendance_id) REFERENCES attendance_records(id) ON DELETE SET NULL;
+ALTER TABLE sms_messages ADD CONSTRAINT sms_messages_attendance_id_fkey
+    FOREIGN KEY (attendance_id) REFERENCES attendance_records(id) ON DELETE SET NULL;
+
+COMMIT;
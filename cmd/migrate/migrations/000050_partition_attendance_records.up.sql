@@ -0,0 +1,63 @@
+BEGIN;
+
+-- attendance_records becomes range-partitioned by month, so queries for a
+-- day, a week or a term read only the months they cover however many years
+-- accumulate. The API keeps partitions a few months ahead; the default
+-- partition catches dates outside them.
+
+-- A partitioned table can only be referenced through a key that includes
+-- the partition column, so these become plain ids.
+ALTER TABLE mobile_checkins DROP CONSTRAINT IF EXISTS mobile_checkins_attendance_id_fkey;
+ALTER TABLE sms_messages DROP CONSTRAINT IF EXISTS sms_messages_attendance_id_fkey;
+
+ALTER TABLE attendance_records RENAME TO attendance_records_unpartitioned;
+ALTER TABLE attendance_records_unpartitioned RENAME CONSTRAINT attendance_records_pkey TO attendance_records_unpartitioned_pkey;
+ALTER TABLE attendance_records_unpartitioned RENAME CONSTRAINT attendance_records_student_id_date_key TO attendance_records_unpartitioned_student_id_date_key;
+DROP INDEX IF EXISTS idx_attendance_student_date;
+DROP INDEX IF EXISTS idx_attendance_classroom_date;
+
+CREATE TABLE attendance_records (
+    id BIGINT NOT NULL DEFAULT nextval('attendance_records_id_seq'),
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    teacher_id BIGINT REFERENCES teachers(id) ON DELETE SET NULL,
+    classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    date DATE NOT NULL,
+    status attendance_status NOT NULL DEFAULT 'present',
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    method TEXT NOT NULL DEFAULT 'manual' CHECK (method IN ('manual', 'online', 'geofence')),
+    checked_in_at TIMESTAMPTZ,
+    PRIMARY KEY (id, date),
+    UNIQUE (student_id, date)
+) PARTITION BY RANGE (date);
+
+ALTER SEQUENCE attendance_records_id_seq OWNED BY attendance_records.id;
+
+CREATE TABLE IF NOT EXISTS attendance_records_default PARTITION OF attendance_records DEFAULT;
+
+-- a partition for every month with records, through two months from now
+DO $$
+DECLARE
+    m DATE;
+BEGIN
+    FOR m IN
+        SELECT generate_series(
+            date_trunc('month', LEAST(MIN(date), CURRENT_DATE)),
+            GREATEST(date_trunc('month', MAX(date)), date_trunc('month', CURRENT_DATE) + INTERVAL '2 months'),
+            INTERVAL '1 month')::date
+        FROM attendance_records_unpartitioned
+    LOOP
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF attendance_records FOR VALUES FROM (%L) TO (%L)',
+            'attendance_records_' || to_char(m, 'YYYY_MM'), m, (m + INTERVAL '1 month')::date);
+    END LOOP;
+END $$;
+
+INSERT INTO attendance_records (id, student_id, teacher_id, classroom_id, date, status, note, created_at, method, checked_in_at)
+SELECT id, student_id, teacher_id, classroom_id, date, status, note, created_at, method, checked_in_at
+FROM attendance_records_unpartitioned;
+
+DROP TABLE attendance_records_unpartitioned;
+
+CREATE INDEX IF NOT EXISTS idx_attendance_classroom_date ON attendance_records(classroom_id, date);
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+-- archived rows go back to the hot tables before the archive is dropped
+INSERT INTO attendance_records (id, student_id, teacher_id, classroom_id, date, status, note, method, checked_in_at, created_at)
+SELECT id, student_id, teacher_id, classroom_id, date, status, note, method, checked_in_at, created_at
+FROM archive.attendance_records
+ON CONFLICT DO NOTHING;
+
+INSERT INTO grades (id, student_id, classroom_id, source, assignment, score, max_score, imported_at)
+SELECT id, student_id, classroom_id, source, assignment, score, max_score, imported_at
+FROM archive.grades
+WHERE classroom_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS archive.grades;
+DROP TABLE IF EXISTS archive.attendance_records;
+DROP SCHEMA IF EXISTS archive;
+DROP TABLE IF EXISTS terms;
+
+COMMIT;
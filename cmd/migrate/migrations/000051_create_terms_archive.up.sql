@@ -0,0 +1,53 @@
+BEGIN;
+
+-- Academic terms. Once a term has been closed for a while its attendance
+-- and grades move to the archive schema, keeping the hot tables to the
+-- terms still in use.
+CREATE TABLE IF NOT EXISTS terms (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL CHECK (ends_on >= starts_on),
+    closed_at TIMESTAMPTZ,
+    archived_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT terms_no_overlap EXCLUDE USING gist (daterange(starts_on, ends_on, '[]') WITH &&)
+);
+
+CREATE SCHEMA IF NOT EXISTS archive;
+
+-- attendance_records of archived terms
+CREATE TABLE IF NOT EXISTS archive.attendance_records (
+    id BIGINT PRIMARY KEY,
+    term_id BIGINT NOT NULL REFERENCES terms(id),
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    teacher_id BIGINT REFERENCES teachers(id) ON DELETE SET NULL,
+    classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    date DATE NOT NULL,
+    status attendance_status NOT NULL,
+    note TEXT,
+    method TEXT NOT NULL,
+    checked_in_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_archive_attendance_student_date ON archive.attendance_records (student_id, date);
+
+-- grades imported during archived terms
+CREATE TABLE IF NOT EXISTS archive.grades (
+    id BIGINT PRIMARY KEY,
+    term_id BIGINT NOT NULL REFERENCES terms(id),
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    classroom_id BIGINT REFERENCES classrooms(id) ON DELETE SET NULL,
+    source TEXT NOT NULL,
+    assignment TEXT NOT NULL,
+    score NUMERIC(10, 2) NOT NULL,
+    max_score NUMERIC(10, 2),
+    imported_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_archive_grades_student_term ON archive.grades (student_id, term_id);
+
+COMMIT;
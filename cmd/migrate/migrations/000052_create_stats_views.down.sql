@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP MATERIALIZED VIEW IF EXISTS grade_averages;
+DROP MATERIALIZED VIEW IF EXISTS attendance_daily_stats;
+
+COMMIT;
@@ -0,0 +1,41 @@
+BEGIN;
+
+-- Pre-aggregated attendance and grades for the dashboards. The API
+-- refreshes them every STATS_REFRESH_MINUTES and on
+-- POST /v1/admin/refresh-stats; the unique indexes let it refresh them
+-- concurrently with reads.
+
+-- attendance per classroom and day; classroom 0 is unassigned
+CREATE MATERIALIZED VIEW IF NOT EXISTS attendance_daily_stats AS
+SELECT
+    date,
+    COALESCE(classroom_id, 0) AS classroom_id,
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE status = 'present') AS present,
+    COUNT(*) FILTER (WHERE status = 'absent') AS absent,
+    COUNT(*) FILTER (WHERE status = 'late') AS late,
+    COUNT(*) FILTER (WHERE status = 'excused') AS excused
+FROM attendance_records
+GROUP BY date, COALESCE(classroom_id, 0);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_daily_stats_date_classroom ON attendance_daily_stats (date, classroom_id);
+CREATE INDEX IF NOT EXISTS idx_attendance_daily_stats_classroom ON attendance_daily_stats (classroom_id, date);
+
+-- grades per classroom and assignment; average_ratio only counts scores
+-- with a max_score
+CREATE MATERIALIZED VIEW IF NOT EXISTS grade_averages AS
+SELECT
+    classroom_id,
+    source,
+    assignment,
+    COUNT(*) AS students,
+    AVG(score)::float8 AS average_score,
+    (AVG(score / NULLIF(max_score, 0)))::float8 AS average_ratio,
+    MIN(score)::float8 AS min_score,
+    MAX(score)::float8 AS max_score
+FROM grades
+GROUP BY classroom_id, source, assignment;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_grade_averages_assignment ON grade_averages (classroom_id, source, assignment);
+
+COMMIT;
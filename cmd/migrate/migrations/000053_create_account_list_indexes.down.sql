@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_students_created_at;
+DROP INDEX IF EXISTS idx_teachers_created_at;
+DROP INDEX IF EXISTS idx_execs_created_at;
+DROP INDEX IF EXISTS idx_accounts_profile_last_login;
+
+COMMIT;
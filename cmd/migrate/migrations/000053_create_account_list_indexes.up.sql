@@ -0,0 +1,10 @@
+BEGIN;
+
+-- People lists filter on when a record was added and on the last sign-in
+-- of its account.
+CREATE INDEX IF NOT EXISTS idx_accounts_profile_last_login ON accounts (profile_type, last_login_at);
+CREATE INDEX IF NOT EXISTS idx_execs_created_at ON execs (created_at);
+CREATE INDEX IF NOT EXISTS idx_teachers_created_at ON teachers (created_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_students_created_at ON students (created_at) WHERE deleted_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_accounts_last_used;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS deactivated_at,
+    DROP COLUMN IF EXISTS last_login_ip;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS last_login_ip TEXT,
+    ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+-- The inactive accounts report scans the live accounts by last use.
+CREATE INDEX IF NOT EXISTS idx_accounts_last_used ON accounts (COALESCE(last_login_at, created_at))
+    WHERE deactivated_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- password_history keeps the hashes of every password an account has had,
+-- so the password policy can refuse reusing the latest ones.
+CREATE TABLE IF NOT EXISTS password_history (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    hash BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_account ON password_history (account_id, created_at DESC);
+
+INSERT INTO password_history (account_id, hash, created_at)
+SELECT id, password, created_at FROM accounts WHERE password IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Accounts given a temporary password, or caught by a forced rotation, may
+-- do nothing but change it until they do.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
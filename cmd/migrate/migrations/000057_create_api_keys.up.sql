@@ -0,0 +1,20 @@
+BEGIN;
+
+-- api_keys let integrations call the API as the exec owning the key. Only
+-- the SHA-256 of a key is kept; prefix is its first characters, shown to
+-- tell keys apart. A quota of 0 is unlimited.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash BYTEA NOT NULL UNIQUE,
+    exec_id BIGINT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+    daily_quota BIGINT NOT NULL DEFAULT 0 CHECK (daily_quota >= 0),
+    monthly_quota BIGINT NOT NULL DEFAULT 0 CHECK (monthly_quota >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_exec ON api_keys (exec_id);
+
+COMMIT;
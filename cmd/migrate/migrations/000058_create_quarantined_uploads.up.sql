@@ -0,0 +1,19 @@
+BEGIN;
+
+-- quarantined_uploads keeps the files refused by the upload checks, away
+-- from the records they were sent for, until an admin deletes them.
+CREATE TABLE IF NOT EXISTS quarantined_uploads (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    reason TEXT NOT NULL,
+    signature TEXT NOT NULL DEFAULT '',
+    uploader_role TEXT NOT NULL,
+    uploader_id BIGINT NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
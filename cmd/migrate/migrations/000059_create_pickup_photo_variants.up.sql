@@ -0,0 +1,15 @@
+BEGIN;
+
+-- Scaled copies of pickup contact photos, one per size, made from the
+-- photo when it is uploaded or first asked for. Replacing the photo
+-- clears them.
+CREATE TABLE IF NOT EXISTS pickup_photo_variants (
+    contact_id BIGINT NOT NULL REFERENCES pickup_contacts(id) ON DELETE CASCADE,
+    size TEXT NOT NULL,
+    data BYTEA NOT NULL,
+    content_type TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (contact_id, size)
+);
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+-- Changes teachers ask for to attendance past the window they may edit it
+-- in. An exec approving one applies it to record_id; previous_status and
+-- previous_note keep what it replaced.
+CREATE TABLE IF NOT EXISTS attendance_corrections (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    new_status TEXT NOT NULL CHECK (new_status IN ('present', 'absent', 'late', 'excused')),
+    new_note TEXT,
+    reason TEXT NOT NULL,
+    requested_by BIGINT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    previous_status TEXT,
+    previous_note TEXT,
+    record_id BIGINT,
+    reviewed_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    review_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One open request per student and day.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_corrections_pending
+    ON attendance_corrections(student_id, date) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_attendance_corrections_teacher ON attendance_corrections(requested_by);
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Sensitive actions staged until a second admin approves them. params
+-- holds what the action needs to run; error why an approved one failed.
+CREATE TABLE IF NOT EXISTS approvals (
+    id BIGSERIAL PRIMARY KEY,
+    action TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    params JSONB NOT NULL,
+    requested_by BIGINT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'failed')),
+    reviewed_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    review_note TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_approvals_pending ON approvals(created_at) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS term_grade_snapshots;
+DROP TABLE IF EXISTS term_student_snapshots;
+DROP FUNCTION IF EXISTS forbid_snapshot_change();
+
+COMMIT;
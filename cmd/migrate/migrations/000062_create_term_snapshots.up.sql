@@ -0,0 +1,88 @@
+BEGIN;
+
+-- What each student's record held when their term was closed: the source
+-- of official transcripts, so later edits, corrections and archiving
+-- don't change them. Names are copied so a transcript survives the
+-- student or classroom being renamed or deleted.
+CREATE TABLE IF NOT EXISTS term_student_snapshots (
+    term_id BIGINT NOT NULL REFERENCES terms(id),
+    student_id BIGINT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    present INT NOT NULL,
+    absent INT NOT NULL,
+    late INT NOT NULL,
+    excused INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (student_id, term_id)
+);
+
+CREATE TABLE IF NOT EXISTS term_grade_snapshots (
+    term_id BIGINT NOT NULL REFERENCES terms(id),
+    student_id BIGINT NOT NULL,
+    classroom_name TEXT NOT NULL,
+    source TEXT NOT NULL,
+    assignment TEXT NOT NULL,
+    score NUMERIC(10, 2) NOT NULL,
+    max_score NUMERIC(10, 2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_term_grade_snapshots_student ON term_grade_snapshots(student_id, term_id);
+
+CREATE OR REPLACE FUNCTION forbid_snapshot_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% rows are immutable', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER term_student_snapshots_immutable BEFORE UPDATE OR DELETE ON term_student_snapshots
+    FOR EACH ROW EXECUTE FUNCTION forbid_snapshot_change();
+CREATE TRIGGER term_grade_snapshots_immutable BEFORE UPDATE OR DELETE ON term_grade_snapshots
+    FOR EACH ROW EXECUTE FUNCTION forbid_snapshot_change();
+
+-- Terms closed before snapshots existed are snapshotted from what they
+-- hold now, live or archived.
+WITH attendance AS (
+    SELECT t.id AS term_id, a.student_id, a.status::text AS status
+    FROM terms t
+    JOIN attendance_records a ON a.date BETWEEN t.starts_on AND t.ends_on
+    WHERE t.closed_at IS NOT NULL
+    UNION ALL
+    SELECT term_id, student_id, status::text FROM archive.attendance_records
+), grades AS (
+    SELECT t.id AS term_id, g.student_id, g.classroom_id, g.source, g.assignment, g.score, g.max_score
+    FROM terms t
+    JOIN grades g ON g.imported_at >= t.starts_on AND g.imported_at < t.ends_on + 1
+    WHERE t.closed_at IS NOT NULL
+    UNION ALL
+    SELECT term_id, student_id, classroom_id, source, assignment, score, max_score FROM archive.grades
+), students_in AS (
+    SELECT term_id, student_id FROM attendance
+    UNION
+    SELECT term_id, student_id FROM grades
+)
+INSERT INTO term_student_snapshots (term_id, student_id, first_name, last_name, present, absent, late, excused)
+SELECT i.term_id, i.student_id, s.first_name, s.last_name,
+    COUNT(a.status) FILTER (WHERE a.status = 'present'),
+    COUNT(a.status) FILTER (WHERE a.status = 'absent'),
+    COUNT(a.status) FILTER (WHERE a.status = 'late'),
+    COUNT(a.status) FILTER (WHERE a.status = 'excused')
+FROM students_in i
+JOIN students s ON s.id = i.student_id
+LEFT JOIN attendance a ON a.term_id = i.term_id AND a.student_id = i.student_id
+GROUP BY i.term_id, i.student_id, s.first_name, s.last_name;
+
+INSERT INTO term_grade_snapshots (term_id, student_id, classroom_name, source, assignment, score, max_score)
+SELECT g.term_id, g.student_id, COALESCE(c.name, ''), g.source, g.assignment, g.score, g.max_score
+FROM (
+    SELECT t.id AS term_id, g.student_id, g.classroom_id, g.source, g.assignment, g.score, g.max_score
+    FROM terms t
+    JOIN grades g ON g.imported_at >= t.starts_on AND g.imported_at < t.ends_on + 1
+    WHERE t.closed_at IS NOT NULL
+    UNION ALL
+    SELECT term_id, student_id, classroom_id, source, assignment, score, max_score FROM archive.grades
+) g
+LEFT JOIN classrooms c ON c.id = g.classroom_id;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS seat_assignments;
+DROP TABLE IF EXISTS seating_charts;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- A classroom's seat grid, rows by columns, and who sits where. Seats
+-- without a row in seat_assignments are empty.
+CREATE TABLE IF NOT EXISTS seating_charts (
+    classroom_id BIGINT PRIMARY KEY REFERENCES classrooms(id) ON DELETE CASCADE,
+    row_count INT NOT NULL CHECK (row_count BETWEEN 1 AND 20),
+    column_count INT NOT NULL CHECK (column_count BETWEEN 1 AND 20),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS seat_assignments (
+    classroom_id BIGINT NOT NULL REFERENCES seating_charts(classroom_id) ON DELETE CASCADE,
+    seat_row INT NOT NULL,
+    seat_column INT NOT NULL,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    PRIMARY KEY (classroom_id, seat_row, seat_column),
+    UNIQUE (classroom_id, student_id)
+);
+
+COMMIT;
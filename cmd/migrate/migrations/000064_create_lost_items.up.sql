@@ -0,0 +1,27 @@
+BEGIN;
+
+-- Items staff found around the school. An item is matched once its owner
+-- is known (the student is told) and claimed once it is handed back.
+CREATE TABLE IF NOT EXISTS lost_items (
+    id BIGSERIAL PRIMARY KEY,
+    description TEXT NOT NULL,
+    location TEXT NOT NULL DEFAULT '',
+    found_on DATE NOT NULL,
+    found_by BIGINT NOT NULL,
+    found_by_role TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'unclaimed' CHECK (status IN ('unclaimed', 'matched', 'claimed')),
+    student_id BIGINT REFERENCES students(id) ON DELETE SET NULL,
+    matched_at TIMESTAMPTZ,
+    claimed_at TIMESTAMPTZ,
+    photo BYTEA,
+    photo_type TEXT,
+    thumbnail BYTEA,
+    thumbnail_type TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_lost_items_status ON lost_items(status, found_on DESC);
+CREATE INDEX IF NOT EXISTS idx_lost_items_description_trgm ON lost_items USING GIN (description gin_trgm_ops);
+
+COMMIT;
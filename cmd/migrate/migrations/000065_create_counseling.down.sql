@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS counseling_sessions;
+DROP TABLE IF EXISTS counseling_referrals;
+DROP TABLE IF EXISTS permission_grants;
+
+COMMIT;
@@ -0,0 +1,45 @@
+BEGIN;
+
+-- Permissions given to single accounts on top of what their role allows,
+-- for access too sensitive to hand every exec, such as counseling notes.
+CREATE TABLE IF NOT EXISTS permission_grants (
+    account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    granted_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_id, permission)
+);
+
+-- Staff asking a counselor to see a student. A referral closes when a
+-- session is logged against it.
+CREATE TABLE IF NOT EXISTS counseling_referrals (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    referred_by BIGINT NOT NULL,
+    referred_by_role TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    closed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_counseling_referrals_open ON counseling_referrals(created_at) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS counseling_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    student_id BIGINT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+    referral_id BIGINT REFERENCES counseling_referrals(id) ON DELETE SET NULL,
+    category TEXT NOT NULL,
+    held_on DATE NOT NULL,
+    notes TEXT NOT NULL,
+    follow_up_on DATE,
+    counselor_id BIGINT NOT NULL,
+    counselor_role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_counseling_sessions_student ON counseling_sessions(student_id, held_on DESC);
+CREATE INDEX IF NOT EXISTS idx_counseling_sessions_held_on ON counseling_sessions(held_on);
+
+COMMIT;
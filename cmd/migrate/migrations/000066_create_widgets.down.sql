@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS widget_tokens;
+DROP TABLE IF EXISTS lunch_menus;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+-- lunch_menus holds what the canteen serves on a date.
+CREATE TABLE IF NOT EXISTS lunch_menus (
+    date DATE PRIMARY KEY,
+    items TEXT[] NOT NULL,
+    notes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- widget_tokens let a school website embed public data. A token is the
+-- row's id signed with the token secret, so nothing secret is stored; it
+-- only reads its scopes, and only from its origins.
+CREATE TABLE IF NOT EXISTS widget_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    scopes TEXT[] NOT NULL CHECK (cardinality(scopes) > 0),
+    origins TEXT[] NOT NULL CHECK (cardinality(origins) > 0),
+    created_by BIGINT REFERENCES execs(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+ALTER TABLE attendance_records DROP CONSTRAINT IF EXISTS attendance_records_method_check;
+
+DO $$
+DECLARE
+    part regclass;
+BEGIN
+    FOR part IN SELECT inhrelid::regclass FROM pg_inherits WHERE inhparent = 'attendance_records'::regclass LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT IF EXISTS attendance_records_method_check', part);
+    END LOOP;
+END $$;
+
+UPDATE attendance_records SET method = 'manual' WHERE method = 'import';
+
+ALTER TABLE attendance_records ADD CONSTRAINT attendance_records_method_check
+    CHECK (method IN ('manual', 'online', 'geofence'));
+
+COMMIT;
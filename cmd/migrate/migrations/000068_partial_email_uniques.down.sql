@@ -0,0 +1,46 @@
+BEGIN;
+
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_teacher_id_fkey;
+ALTER TABLE students ADD CONSTRAINT students_teacher_id_fkey
+    FOREIGN KEY (teacher_id) REFERENCES teachers(id);
+
+UPDATE accounts a SET email = t.email
+FROM teachers t WHERE a.profile_type = 'teacher' AND a.profile_id = t.id AND a.email IS NULL;
+UPDATE accounts a SET email = s.email
+FROM students s WHERE a.profile_type = 'student' AND a.profile_id = s.id AND a.email IS NULL;
+
+DROP TRIGGER IF EXISTS teachers_sync_account ON teachers;
+CREATE TRIGGER teachers_sync_account AFTER UPDATE OF email OR DELETE ON teachers
+    FOR EACH ROW EXECUTE FUNCTION sync_account('teacher');
+DROP TRIGGER IF EXISTS students_sync_account ON students;
+CREATE TRIGGER students_sync_account AFTER UPDATE OF email OR DELETE ON students
+    FOR EACH ROW EXECUTE FUNCTION sync_account('student');
+
+CREATE OR REPLACE FUNCTION sync_account() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        DELETE FROM accounts WHERE profile_type = TG_ARGV[0] AND profile_id = OLD.id;
+        RETURN OLD;
+    END IF;
+
+    UPDATE accounts SET email = NEW.email
+    WHERE profile_type = TG_ARGV[0] AND profile_id = NEW.id AND email IS DISTINCT FROM NEW.email;
+
+    IF TG_ARGV[0] = 'exec' THEN
+        UPDATE accounts SET role = NEW.role
+        WHERE profile_type = 'exec' AND profile_id = NEW.id AND role IS DISTINCT FROM NEW.role;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Fails while a trashed profile shares its email with a live one.
+DROP INDEX IF EXISTS idx_teachers_email;
+CREATE UNIQUE INDEX idx_teachers_email ON teachers(email);
+ALTER TABLE teachers ADD CONSTRAINT teachers_email_key UNIQUE (email);
+
+DROP INDEX IF EXISTS idx_students_email;
+CREATE UNIQUE INDEX idx_students_email ON students(email);
+ALTER TABLE students ADD CONSTRAINT students_email_key UNIQUE (email);
+
+COMMIT;
@@ -0,0 +1,58 @@
+BEGIN;
+
+-- A trashed student or teacher no longer holds on to their email, so a new
+-- profile may reuse it; restoring the old one then conflicts instead.
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_email_key;
+DROP INDEX IF EXISTS idx_students_email;
+CREATE UNIQUE INDEX idx_students_email ON students(email) WHERE deleted_at IS NULL;
+
+ALTER TABLE teachers DROP CONSTRAINT IF EXISTS teachers_email_key;
+DROP INDEX IF EXISTS idx_teachers_email;
+CREATE UNIQUE INDEX idx_teachers_email ON teachers(email) WHERE deleted_at IS NULL;
+
+-- The account of a trashed profile gives up its email the same way, and
+-- gets it back on restore.
+CREATE OR REPLACE FUNCTION sync_account() RETURNS TRIGGER AS $$
+DECLARE
+    account_email TEXT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        DELETE FROM accounts WHERE profile_type = TG_ARGV[0] AND profile_id = OLD.id;
+        RETURN OLD;
+    END IF;
+
+    account_email := NEW.email;
+    IF TG_ARGV[0] <> 'exec' AND NEW.deleted_at IS NOT NULL THEN
+        account_email := NULL;
+    END IF;
+
+    UPDATE accounts SET email = account_email
+    WHERE profile_type = TG_ARGV[0] AND profile_id = NEW.id AND email IS DISTINCT FROM account_email;
+
+    IF TG_ARGV[0] = 'exec' THEN
+        UPDATE accounts SET role = NEW.role
+        WHERE profile_type = 'exec' AND profile_id = NEW.id AND role IS DISTINCT FROM NEW.role;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS teachers_sync_account ON teachers;
+CREATE TRIGGER teachers_sync_account AFTER UPDATE OF email, deleted_at OR DELETE ON teachers
+    FOR EACH ROW EXECUTE FUNCTION sync_account('teacher');
+DROP TRIGGER IF EXISTS students_sync_account ON students;
+CREATE TRIGGER students_sync_account AFTER UPDATE OF email, deleted_at OR DELETE ON students
+    FOR EACH ROW EXECUTE FUNCTION sync_account('student');
+
+UPDATE accounts a SET email = NULL
+FROM teachers t WHERE a.profile_type = 'teacher' AND a.profile_id = t.id AND t.deleted_at IS NOT NULL;
+UPDATE accounts a SET email = NULL
+FROM students s WHERE a.profile_type = 'student' AND a.profile_id = s.id AND s.deleted_at IS NOT NULL;
+
+-- Purging a teacher takes their trashed students with them; the trash
+-- purge guard keeps it from reaching live ones.
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_teacher_id_fkey;
+ALTER TABLE students ADD CONSTRAINT students_teacher_id_fkey
+    FOREIGN KEY (teacher_id) REFERENCES teachers(id) ON DELETE CASCADE;
+
+COMMIT;
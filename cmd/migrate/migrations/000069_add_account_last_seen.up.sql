@@ -0,0 +1,8 @@
+BEGIN;
+
+-- last_seen_at moves on every sign-in and token refresh, so someone who
+-- stays signed in on a refresh token doesn't look inactive.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+UPDATE accounts SET last_seen_at = last_login_at;
+
+COMMIT;
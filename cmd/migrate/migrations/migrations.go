@@ -0,0 +1,27 @@
+// Package migrations embeds the SQL migrations so the API knows which
+// schema version it was built against.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Latest returns the version of the newest migration, the one the
+// database must be at for this build's queries to work.
+func Latest() uint {
+	names, _ := fs.Glob(files, "*.up.sql")
+	var latest uint
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		if v, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+	return latest
+}
@@ -0,0 +1,161 @@
+// Package analytics records domain events (logins, attendance, feature usage)
+// into the partitioned analytics_events table and exports them for
+// warehouse loading.
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	EventLogin            = "login"
+	EventAttendanceMarked = "attendance_marked"
+	EventFeatureUsed      = "feature_used"
+)
+
+const maxBatch = 500
+
+// Event is a single analytics event.
+type Event struct {
+	Name       string
+	ActorKind  string
+	ActorID    int64
+	Properties map[string]any
+	OccurredAt time.Time
+}
+
+// Emitter buffers events in memory and writes them in batches, so emitting
+// never blocks a request. Events are dropped when the buffer is full.
+type Emitter struct {
+	db       *sql.DB
+	events   chan Event
+	interval time.Duration
+	onError  func(error)
+	dropped  atomic.Int64
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewEmitter starts the background writer. onError receives write failures.
+func NewEmitter(db *sql.DB, buffer int, interval time.Duration, onError func(error)) *Emitter {
+	e := &Emitter{
+		db:       db,
+		events:   make(chan Event, buffer),
+		interval: interval,
+		onError:  onError,
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Emit queues ev; it never blocks.
+func (e *Emitter) Emit(ev Event) {
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = time.Now().UTC()
+	}
+	select {
+	case e.events <- ev:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Dropped reports how many events were discarded because the buffer was full.
+func (e *Emitter) Dropped() int64 {
+	return e.dropped.Load()
+}
+
+// Close flushes buffered events and stops the writer.
+func (e *Emitter) Close(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.events) })
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Emitter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, maxBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.write(batch); err != nil && e.onError != nil {
+			e.onError(err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case ev, ok := <-e.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, ev)
+			if len(batch) == maxBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (e *Emitter) write(batch []Event) error {
+	values := make([]string, 0, len(batch))
+	args := make([]any, 0, len(batch)*5)
+	for i, ev := range batch {
+		props, err := json.Marshal(ev.Properties)
+		if err != nil || ev.Properties == nil {
+			props = []byte("{}")
+		}
+		n := i * 5
+		values = append(values, fmt.Sprintf("($%d, NULLIF($%d, ''), NULLIF($%d, 0), $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, ev.Name, ev.ActorKind, ev.ActorID, props, ev.OccurredAt)
+	}
+
+	query := `INSERT INTO analytics_events (name, actor_kind, actor_id, properties, occurred_at) VALUES ` +
+		strings.Join(values, ", ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// EnsurePartitions creates the monthly partitions for the month of now and
+// the following one.
+func (e *Emitter) EnsurePartitions(ctx context.Context, now time.Time) error {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for range 2 {
+		end := start.AddDate(0, 1, 0)
+		query := fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS analytics_events_%s
+			PARTITION OF analytics_events
+			FOR VALUES FROM ('%s') TO ('%s')`,
+			start.Format("2006_01"), start.Format(time.DateOnly), end.Format(time.DateOnly))
+		if _, err := e.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
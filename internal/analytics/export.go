@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/backup"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Exporter writes a day of events as CSV to an S3-compatible bucket, laid
+// out as <prefix>/dt=YYYY-MM-DD/events.csv for warehouse loaders.
+type Exporter struct {
+	db     *sql.DB
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewExporter reuses the backup S3 settings shape for the target bucket.
+func NewExporter(db *sql.DB, cfg backup.Config) (*Exporter, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{db: db, client: client, bucket: cfg.Bucket, prefix: strings.TrimSuffix(cfg.Prefix, "/")}, nil
+}
+
+// ExportResult describes an uploaded export.
+type ExportResult struct {
+	Object string `json:"object"`
+	Rows   int    `json:"rows"`
+	Size   int64  `json:"size"`
+}
+
+// ExportDay uploads every event that occurred on day (UTC).
+func (e *Exporter) ExportDay(ctx context.Context, day time.Time) (*ExportResult, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT id, name, COALESCE(actor_kind, ''), COALESCE(actor_id, 0), properties, occurred_at
+		FROM analytics_events
+		WHERE occurred_at >= $1 AND occurred_at < $2
+		ORDER BY occurred_at, id`, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "name", "actor_kind", "actor_id", "properties", "occurred_at"})
+
+	count := 0
+	for rows.Next() {
+		var (
+			id, actorID int64
+			name, kind  string
+			props       []byte
+			occurredAt  time.Time
+		)
+		if err := rows.Scan(&id, &name, &kind, &actorID, &props, &occurredAt); err != nil {
+			return nil, err
+		}
+		w.Write([]string{
+			strconv.FormatInt(id, 10),
+			name,
+			kind,
+			strconv.FormatInt(actorID, 10),
+			string(props),
+			occurredAt.UTC().Format(time.RFC3339),
+		})
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s/dt=%s/events.csv", e.prefix, from.Format(time.DateOnly))
+	_, err = e.client.PutObject(ctx, e.bucket, name, &buf, int64(buf.Len()), minio.PutObjectOptions{
+		ContentType: "text/csv",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportResult{Object: name, Rows: count, Size: int64(buf.Len())}, nil
+}
@@ -7,4 +7,7 @@ type Claims struct {
 	Email string `json:"email"`
 	Role  string `json:"role"`
 	jwt.RegisteredClaims
+
+	// MustChangePassword limits the token to changing the password.
+	MustChangePassword bool `json:"mcp,omitempty"`
 }
@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Prefix    string
+	UseSSL    bool
+}
+
+// Object is a stored backup.
+type Object struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Service dumps the database with pg_dump and keeps the dumps in an
+// S3-compatible bucket (AWS S3, MinIO, ...).
+type Service struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	dbAddr string
+}
+
+func New(cfg Config, dbAddr string) (*Service, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: strings.TrimSuffix(cfg.Prefix, "/"),
+		dbAddr: dbAddr,
+	}, nil
+}
+
+// Create dumps the database to a temporary file and uploads it.
+func (s *Service) Create(ctx context.Context) (*Object, error) {
+	tmp, err := os.CreateTemp("", "classnama-backup-*.dump")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cmd := exec.CommandContext(ctx, "pg_dump", "--format=custom", "--no-owner", "--dbname", s.dbAddr)
+	cmd.Stdout = tmp
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pg_dump: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	name := s.objectName(fmt.Sprintf("classnama-%s.dump", now.Format("20060102T150405Z")))
+	_, err = s.client.PutObject(ctx, s.bucket, name, tmp, info.Size(), minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{Name: name, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// List returns stored backups, newest first.
+func (s *Service) List(ctx context.Context) ([]Object, error) {
+	objects := []Object{}
+	opts := minio.ListObjectsOptions{Prefix: s.objectName(""), Recursive: true}
+	for info := range s.client.ListObjects(ctx, s.bucket, opts) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+		objects = append(objects, Object{Name: info.Key, Size: info.Size, CreatedAt: info.LastModified})
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].CreatedAt.After(objects[j].CreatedAt)
+	})
+	return objects, nil
+}
+
+// Restore downloads the named backup and loads it with pg_restore into
+// dbAddr, replacing existing objects.
+func (s *Service) Restore(ctx context.Context, name, dbAddr string) error {
+	obj, err := s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	cmd := exec.CommandContext(ctx, "pg_restore", "--clean", "--if-exists", "--no-owner", "--single-transaction", "--dbname", dbAddr)
+	cmd.Stdin = obj
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_restore: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func (s *Service) objectName(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
@@ -3,32 +3,120 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func New(addr string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", addr)
+// Config is one connection pool. Durations are strings such as "15m";
+// empty or "0" turns the setting off.
+type Config struct {
+	Addr         string
+	MaxOpenConns int
+	MaxIdleConns int
+	MaxIdleTime  string
+	// MaxLifetime closes connections this long after they were opened, so
+	// the pool spreads again after a failover or a pgbouncer restart.
+	MaxLifetime string
+	// HealthCheckPeriod pings a connection before it is reused when it has
+	// not been checked for this long, dropping dead ones instead of failing
+	// the query that gets them.
+	HealthCheckPeriod string
+}
+
+func New(cfg Config) (*sql.DB, error) {
+	maxIdleTime, err := parseDuration(cfg.MaxIdleTime)
+	if err != nil {
+		return nil, err
+	}
+	maxLifetime, err := parseDuration(cfg.MaxLifetime)
+	if err != nil {
+		return nil, err
+	}
+	healthCheck, err := parseDuration(cfg.HealthCheckPeriod)
 	if err != nil {
 		return nil, err
 	}
 
-	db.SetMaxOpenConns(maxOpenConns)
-	db.SetMaxIdleConns(maxIdleConns)
-
-	duration, err := time.ParseDuration(maxIdleTime)
+	pc, err := pq.NewConnector(cfg.Addr)
 	if err != nil {
 		return nil, err
 	}
-	db.SetConnMaxIdleTime(time.Duration(duration))
+	db := sql.OpenDB(connector{pc, healthCheck})
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxIdleTime(maxIdleTime)
+	db.SetConnMaxLifetime(maxLifetime)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err = db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// connector opens health-checked connections when a period is set.
+type connector struct {
+	*pq.Connector
+	healthCheck time.Duration
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil || c.healthCheck <= 0 {
+		return conn, err
+	}
+	pc, ok := conn.(pqConn)
+	if !ok {
+		return conn, nil
+	}
+	return &checkedConn{pqConn: pc, period: c.healthCheck, checked: time.Now()}, nil
+}
+
+// pqConn is what database/sql uses of a lib/pq connection.
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// checkedConn pings itself when taken from the pool after its period.
+// database/sql discards a connection whose reset fails with ErrBadConn and
+// retries on another, so the caller never sees the dead one.
+type checkedConn struct {
+	pqConn
+	period  time.Duration
+	checked time.Time
+}
+
+func (c *checkedConn) ResetSession(ctx context.Context) error {
+	if err := c.pqConn.ResetSession(ctx); err != nil {
+		return err
+	}
+	if time.Since(c.checked) < c.period {
+		return nil
+	}
+	if err := c.Ping(ctx); err != nil {
+		return driver.ErrBadConn
+	}
+	c.checked = time.Now()
+	return nil
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// InactiveAccount is an account nobody has used for a while.
+type InactiveAccount struct {
+	ID          int64      `json:"id"`
+	Role        string     `json:"role"`
+	ProfileType string     `json:"profile_type"`
+	ProfileID   int64      `json:"profile_id"`
+	Name        string     `json:"name"`
+	Email       *string    `json:"email"`
+	PhoneNumber *string    `json:"phone_number"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	LastLoginIP *string    `json:"last_login_ip"`
+	LastSeenAt  *time.Time `json:"last_seen_at"`
+}
+
+func FromInactiveAccount(i *store.InactiveAccount) *InactiveAccount {
+	return &InactiveAccount{
+		ID:          i.ID,
+		Role:        i.Role,
+		ProfileType: i.ProfileType,
+		ProfileID:   i.ProfileID,
+		Name:        i.Name,
+		Email:       i.Email,
+		PhoneNumber: i.PhoneNumber,
+		CreatedAt:   i.CreatedAt,
+		LastLoginAt: i.LastLoginAt,
+		LastLoginIP: i.LastLoginIP,
+		LastSeenAt:  i.LastSeenAt,
+	}
+}
+
+func FromInactiveAccounts(list []*store.InactiveAccount) []*InactiveAccount {
+	return mapAll(list, FromInactiveAccount)
+}
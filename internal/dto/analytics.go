@@ -0,0 +1,116 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// AttendanceCompleteness tells how many school days of a month a classroom
+// had attendance taken on.
+type AttendanceCompleteness struct {
+	ClassroomID   int64     `json:"classroom_id"`
+	ClassroomName string    `json:"classroom_name"`
+	Period        time.Time `json:"period"`
+	SchoolDays    int64     `json:"school_days"`
+	DaysTaken     int64     `json:"days_taken"`
+	Completeness  float64   `json:"completeness"`
+}
+
+func FromAttendanceCompleteness(a *store.AttendanceCompleteness) *AttendanceCompleteness {
+	return &AttendanceCompleteness{
+		ClassroomID:   a.ClassroomID,
+		ClassroomName: a.ClassroomName,
+		Period:        a.Period,
+		SchoolDays:    a.SchoolDays,
+		DaysTaken:     a.DaysTaken,
+		Completeness:  a.Completeness,
+	}
+}
+
+func FromAttendanceCompletenesses(list []*store.AttendanceCompleteness) []*AttendanceCompleteness {
+	return mapAll(list, FromAttendanceCompleteness)
+}
+
+// AttendanceTrend is the attendance of one group over one week.
+type AttendanceTrend struct {
+	GroupKey    string    `json:"group_key"`
+	GroupLabel  string    `json:"group_label"`
+	Period      time.Time `json:"period"`
+	Total       int64     `json:"total"`
+	Absent      int64     `json:"absent"`
+	Late        int64     `json:"late"`
+	AbsenceRate float64   `json:"absence_rate"`
+	// Change is the absence rate difference to the group's previous week.
+	Change *float64 `json:"change"`
+	// RollingRate is the absence rate averaged over the last four weeks.
+	RollingRate float64 `json:"rolling_rate"`
+}
+
+func FromAttendanceTrend(a *store.AttendanceTrend) *AttendanceTrend {
+	return &AttendanceTrend{
+		GroupKey:    a.GroupKey,
+		GroupLabel:  a.GroupLabel,
+		Period:      a.Period,
+		Total:       a.Total,
+		Absent:      a.Absent,
+		Late:        a.Late,
+		AbsenceRate: a.AbsenceRate,
+		Change:      a.Change,
+		RollingRate: a.RollingRate,
+	}
+}
+
+func FromAttendanceTrends(list []*store.AttendanceTrend) []*AttendanceTrend {
+	return mapAll(list, FromAttendanceTrend)
+}
+
+// GradeAverage summarises one assignment's scores in a classroom.
+// AverageRatio is the mean of score / max_score over the scores that have a
+// max_score.
+type GradeAverage struct {
+	ClassroomID   int64    `json:"classroom_id"`
+	ClassroomName string   `json:"classroom_name"`
+	Source        string   `json:"source"`
+	Assignment    string   `json:"assignment"`
+	Students      int64    `json:"students"`
+	AverageScore  float64  `json:"average_score"`
+	AverageRatio  *float64 `json:"average_ratio"`
+	MinScore      float64  `json:"min_score"`
+	MaxScore      float64  `json:"max_score"`
+}
+
+func FromGradeAverage(g *store.GradeAverage) *GradeAverage {
+	return &GradeAverage{
+		ClassroomID:   g.ClassroomID,
+		ClassroomName: g.ClassroomName,
+		Source:        g.Source,
+		Assignment:    g.Assignment,
+		Students:      g.Students,
+		AverageScore:  g.AverageScore,
+		AverageRatio:  g.AverageRatio,
+		MinScore:      g.MinScore,
+		MaxScore:      g.MaxScore,
+	}
+}
+
+func FromGradeAverages(list []*store.GradeAverage) []*GradeAverage {
+	return mapAll(list, FromGradeAverage)
+}
+
+// StatsRefresh is how long refreshing each materialized view took.
+type StatsRefresh struct {
+	View    string  `json:"view"`
+	Seconds float64 `json:"seconds"`
+}
+
+func FromStatsRefresh(s *store.StatsRefresh) *StatsRefresh {
+	return &StatsRefresh{
+		View:    s.View,
+		Seconds: s.Seconds,
+	}
+}
+
+func FromStatsRefreshes(list []*store.StatsRefresh) []*StatsRefresh {
+	return mapAll(list, FromStatsRefresh)
+}
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// APIKey lets an integration call the API as the exec owning it, within
+// daily and monthly request quotas (0 for unlimited). Only the hash of the
+// key is stored.
+type APIKey struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	ExecID       int64      `json:"exec_id"`
+	DailyQuota   int64      `json:"daily_quota"`
+	MonthlyQuota int64      `json:"monthly_quota"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+func FromAPIKey(a *store.APIKey) *APIKey {
+	return &APIKey{
+		ID:           a.ID,
+		Name:         a.Name,
+		Prefix:       a.Prefix,
+		ExecID:       a.ExecID,
+		DailyQuota:   a.DailyQuota,
+		MonthlyQuota: a.MonthlyQuota,
+		CreatedAt:    a.CreatedAt,
+		RevokedAt:    a.RevokedAt,
+	}
+}
+
+func FromAPIKeys(list []*store.APIKey) []*APIKey {
+	return mapAll(list, FromAPIKey)
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// Approval is a sensitive action staged until an admin other than the one
+// who asked approves it. Params is what the action runs with.
+type Approval struct {
+	ID          int64           `json:"id"`
+	Action      string          `json:"action" example:"student_delete"`
+	Summary     string          `json:"summary" example:"Delete student 42 (Sara Ahmadi) and their history"`
+	Params      json.RawMessage `json:"params"`
+	RequestedBy int64           `json:"requested_by"`
+	Status      string          `json:"status" enums:"pending,approved,rejected,failed"`
+	ReviewedBy  *int64          `json:"reviewed_by"`
+	ReviewedAt  *time.Time      `json:"reviewed_at"`
+	ReviewNote  string          `json:"review_note"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func FromApproval(a *store.Approval) *Approval {
+	return &Approval{
+		ID:          a.ID,
+		Action:      a.Action,
+		Summary:     a.Summary,
+		Params:      a.Params,
+		RequestedBy: a.RequestedBy,
+		Status:      a.Status,
+		ReviewedBy:  a.ReviewedBy,
+		ReviewedAt:  a.ReviewedAt,
+		ReviewNote:  a.ReviewNote,
+		Error:       a.Error,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func FromApprovals(list []*store.Approval) []*Approval {
+	return mapAll(list, FromApproval)
+}
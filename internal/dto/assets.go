@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Asset struct {
+	ID           int64      `json:"id"`
+	Tag          string     `json:"tag"`
+	Name         string     `json:"name"`
+	Category     string     `json:"category"`
+	SerialNumber string     `json:"serial_number"`
+	Status       string     `json:"status"`
+	Notes        string     `json:"notes"`
+	Loan         *AssetLoan `json:"loan,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func FromAsset(a *store.Asset) *Asset {
+	return &Asset{
+		ID:           a.ID,
+		Tag:          a.Tag,
+		Name:         a.Name,
+		Category:     a.Category,
+		SerialNumber: a.SerialNumber,
+		Status:       a.Status,
+		Notes:        a.Notes,
+		Loan:         mapOne(a.Loan, FromAssetLoan),
+		CreatedAt:    a.CreatedAt,
+		UpdatedAt:    a.UpdatedAt,
+	}
+}
+
+func FromAssets(list []*store.Asset) []*Asset {
+	return mapAll(list, FromAsset)
+}
+
+// AssetLoan is one check-out of an asset to a classroom, teacher or
+// student.
+type AssetLoan struct {
+	ID           int64      `json:"id"`
+	AssetID      int64      `json:"asset_id"`
+	HolderType   string     `json:"holder_type"`
+	HolderID     int64      `json:"holder_id"`
+	HolderName   string     `json:"holder_name"`
+	CheckedOutAt time.Time  `json:"checked_out_at"`
+	DueDate      *time.Time `json:"due_date"`
+	CheckedOutBy int64      `json:"checked_out_by"`
+	ConditionOut string     `json:"condition_out"`
+	ReturnedAt   *time.Time `json:"returned_at"`
+	ReturnedTo   *int64     `json:"returned_to"`
+	ConditionIn  string     `json:"condition_in"`
+}
+
+func FromAssetLoan(a *store.AssetLoan) *AssetLoan {
+	return &AssetLoan{
+		ID:           a.ID,
+		AssetID:      a.AssetID,
+		HolderType:   a.HolderType,
+		HolderID:     a.HolderID,
+		HolderName:   a.HolderName,
+		CheckedOutAt: a.CheckedOutAt,
+		DueDate:      a.DueDate,
+		CheckedOutBy: a.CheckedOutBy,
+		ConditionOut: a.ConditionOut,
+		ReturnedAt:   a.ReturnedAt,
+		ReturnedTo:   a.ReturnedTo,
+		ConditionIn:  a.ConditionIn,
+	}
+}
+
+func FromAssetLoans(list []*store.AssetLoan) []*AssetLoan {
+	return mapAll(list, FromAssetLoan)
+}
+
+// OverdueLoan is an open loan past its due date.
+type OverdueLoan struct {
+	*AssetLoan
+	AssetTag  string `json:"asset_tag"`
+	AssetName string `json:"asset_name"`
+	DaysLate  int    `json:"days_late"`
+}
+
+func FromOverdueLoan(o *store.OverdueLoan) *OverdueLoan {
+	return &OverdueLoan{
+		AssetLoan: mapOne(o.AssetLoan, FromAssetLoan),
+		AssetTag:  o.AssetTag,
+		AssetName: o.AssetName,
+		DaysLate:  o.DaysLate,
+	}
+}
+
+func FromOverdueLoans(list []*store.OverdueLoan) []*OverdueLoan {
+	return mapAll(list, FromOverdueLoan)
+}
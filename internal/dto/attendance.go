@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type AttendanceRecord struct {
+	ID          int64     `json:"id"`
+	StudentID   int64     `json:"student_id"`
+	TeacherID   *int64    `json:"teacher_id,omitempty"`
+	ClassroomID *int64    `json:"classroom_id,omitempty"`
+	Date        time.Time `json:"date"`   // date part only
+	Status      string    `json:"status"` // 'present','absent','late','excused'
+	Note        *string   `json:"note,omitempty"`
+	// Method is how the status was last set: manual, online, geofence or
+	// import.
+	Method      string     `json:"method"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func FromAttendanceRecord(a *store.AttendanceRecord) *AttendanceRecord {
+	return &AttendanceRecord{
+		ID:          a.ID,
+		StudentID:   a.StudentID,
+		TeacherID:   a.TeacherID,
+		ClassroomID: a.ClassroomID,
+		Date:        a.Date,
+		Status:      a.Status,
+		Note:        a.Note,
+		Method:      a.Method,
+		CheckedInAt: a.CheckedInAt,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func FromAttendanceRecords(list []*store.AttendanceRecord) []*AttendanceRecord {
+	return mapAll(list, FromAttendanceRecord)
+}
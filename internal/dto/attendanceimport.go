@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// AttendanceImportResult counts what an import did, or would do in a dry
+// run. When Errors is not empty nothing was imported.
+type AttendanceImportResult struct {
+	DryRun      bool                    `json:"dry_run"`
+	Rows        int                     `json:"rows"`
+	Created     int                     `json:"created"`
+	Overwritten int                     `json:"overwritten"`
+	Skipped     int                     `json:"skipped"`
+	Errors      []AttendanceImportError `json:"errors"`
+}
+
+func FromAttendanceImportResult(a *store.AttendanceImportResult) *AttendanceImportResult {
+	return &AttendanceImportResult{
+		DryRun:      a.DryRun,
+		Rows:        a.Rows,
+		Created:     a.Created,
+		Overwritten: a.Overwritten,
+		Skipped:     a.Skipped,
+		Errors:      mapValues(a.Errors, FromAttendanceImportError),
+	}
+}
+
+type AttendanceImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func FromAttendanceImportError(a *store.AttendanceImportError) *AttendanceImportError {
+	return &AttendanceImportError{
+		Line:  a.Line,
+		Error: a.Error,
+	}
+}
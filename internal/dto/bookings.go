@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// Booking reserves a resource for [StartsAt, EndsAt). Cancelled bookings
+// are kept for the record but no longer block the slot.
+type Booking struct {
+	ID           int64      `json:"id"`
+	ResourceID   int64      `json:"resource_id"`
+	BookedBy     int64      `json:"booked_by"`
+	BookedByRole string     `json:"booked_by_role"`
+	BookedByName string     `json:"booked_by_name"`
+	ClassroomID  *int64     `json:"classroom_id"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	Purpose      string     `json:"purpose"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func FromBooking(b *store.Booking) *Booking {
+	return &Booking{
+		ID:           b.ID,
+		ResourceID:   b.ResourceID,
+		BookedBy:     b.BookedBy,
+		BookedByRole: b.BookedByRole,
+		BookedByName: b.BookedByName,
+		ClassroomID:  b.ClassroomID,
+		StartsAt:     b.StartsAt,
+		EndsAt:       b.EndsAt,
+		Purpose:      b.Purpose,
+		CancelledAt:  b.CancelledAt,
+		CreatedAt:    b.CreatedAt,
+	}
+}
+
+func FromBookings(list []*store.Booking) []*Booking {
+	return mapAll(list, FromBooking)
+}
+
+type BookingResource struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Location  string    `json:"location"`
+	Capacity  int       `json:"capacity"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func FromBookingResource(b *store.BookingResource) *BookingResource {
+	return &BookingResource{
+		ID:        b.ID,
+		Name:      b.Name,
+		Kind:      b.Kind,
+		Location:  b.Location,
+		Capacity:  b.Capacity,
+		Active:    b.Active,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+}
+
+func FromBookingResources(list []*store.BookingResource) []*BookingResource {
+	return mapAll(list, FromBookingResource)
+}
@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// Broadcast is an urgent message sent to whole groups at once. ClassroomID
+// and Grade narrow the students and parents groups.
+type Broadcast struct {
+	ID          int64    `json:"id"`
+	Subject     string   `json:"subject"`
+	Message     string   `json:"message"`
+	Groups      []string `json:"groups"`
+	ClassroomID *int64   `json:"classroom_id,omitempty"`
+	Grade       *int64   `json:"grade,omitempty"`
+	Channels    []string `json:"channels"`
+	SentBy      int64    `json:"sent_by"`
+	SentFromIP  string   `json:"sent_from_ip"`
+	Recipients  int      `json:"recipients"`
+	SMSSent     int      `json:"sms_sent"`
+	EmailSent   int      `json:"email_sent"`
+	// TelegramSent counts parents reached through a linked Telegram chat.
+	TelegramSent int        `json:"telegram_sent"`
+	Failed       int        `json:"failed"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+func FromBroadcast(b *store.Broadcast) *Broadcast {
+	return &Broadcast{
+		ID:           b.ID,
+		Subject:      b.Subject,
+		Message:      b.Message,
+		Groups:       b.Groups,
+		ClassroomID:  b.ClassroomID,
+		Grade:        b.Grade,
+		Channels:     b.Channels,
+		SentBy:       b.SentBy,
+		SentFromIP:   b.SentFromIP,
+		Recipients:   b.Recipients,
+		SMSSent:      b.SMSSent,
+		EmailSent:    b.EmailSent,
+		TelegramSent: b.TelegramSent,
+		Failed:       b.Failed,
+		CreatedAt:    b.CreatedAt,
+		CompletedAt:  b.CompletedAt,
+	}
+}
+
+func FromBroadcasts(list []*store.Broadcast) []*Broadcast {
+	return mapAll(list, FromBroadcast)
+}
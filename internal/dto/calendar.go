@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// CalendarDay is a dated exception to SchoolWeekdays.
+type CalendarDay struct {
+	Date      string    `json:"date"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func FromCalendarDay(c *store.CalendarDay) *CalendarDay {
+	return &CalendarDay{
+		Date:      c.Date,
+		Kind:      c.Kind,
+		Name:      c.Name,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+func FromCalendarDays(list []*store.CalendarDay) []*CalendarDay {
+	return mapAll(list, FromCalendarDay)
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// CheckIn is a check-in from a student's or teacher's phone, accepted
+// inside the school's geofence. Distance is in meters from the school.
+type CheckIn struct {
+	ID           int64     `json:"id"`
+	UserRole     string    `json:"user_role"`
+	UserID       int64     `json:"user_id"`
+	UserName     string    `json:"user_name,omitempty"`
+	Date         time.Time `json:"date"`
+	CheckedInAt  time.Time `json:"checked_in_at"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	Accuracy     *float64  `json:"accuracy,omitempty"`
+	Distance     float64   `json:"distance"`
+	AttendanceID *int64    `json:"attendance_id,omitempty"`
+	// Status is the student's attendance status for the day after the
+	// check-in.
+	Status string `json:"status,omitempty"`
+}
+
+func FromCheckIn(c *store.CheckIn) *CheckIn {
+	return &CheckIn{
+		ID:           c.ID,
+		UserRole:     c.UserRole,
+		UserID:       c.UserID,
+		UserName:     c.UserName,
+		Date:         c.Date,
+		CheckedInAt:  c.CheckedInAt,
+		Latitude:     c.Latitude,
+		Longitude:    c.Longitude,
+		Accuracy:     c.Accuracy,
+		Distance:     c.Distance,
+		AttendanceID: c.AttendanceID,
+		Status:       c.Status,
+	}
+}
+
+func FromCheckIns(list []*store.CheckIn) []*CheckIn {
+	return mapAll(list, FromCheckIn)
+}
@@ -0,0 +1,128 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Classroom struct {
+	ID        int64             `json:"id" example:"42"`
+	Name      string            `json:"name" example:"10-A"`
+	NameI18n  map[string]string `json:"name_i18n"`
+	Capacity  int64             `json:"capacity" example:"30"`
+	Grade     int64             `json:"grade" example:"10"`
+	TeacherID int64             `json:"teacher_id" example:"7"`
+	// Mode is in_person, online or hybrid; online and hybrid classes meet at
+	// MeetingURL.
+	Mode       string `json:"mode"`
+	MeetingURL string `json:"meeting_url,omitempty" example:"https://meet.example.com/10-a"`
+	// StudentCount is the number of live students, on lists only.
+	StudentCount *int64    `json:"student_count,omitempty"`
+	CreatedAt    time.Time `json:"created_at" example:"2026-09-01T08:00:00Z"`
+	UpdatedAt    time.Time `json:"updated_at" example:"2026-10-16T10:30:00Z"`
+}
+
+func FromClassroom(c *store.Classroom) *Classroom {
+	return &Classroom{
+		ID:           c.ID,
+		Name:         c.Name,
+		NameI18n:     c.NameI18n,
+		Capacity:     c.Capacity,
+		Grade:        c.Grade,
+		TeacherID:    c.TeacherID,
+		Mode:         c.Mode,
+		MeetingURL:   c.MeetingURL,
+		StudentCount: c.StudentCount,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+}
+
+func FromClassrooms(list []*store.Classroom) []*Classroom {
+	return mapAll(list, FromClassroom)
+}
+
+// ClassroomDetail is everything the classroom page shows.
+type ClassroomDetail struct {
+	*Classroom
+	Teacher  *ClassroomTeacher  `json:"teacher"`
+	Students []*ClassroomMember `json:"students"`
+	// Today counts today's attendance of the students listed.
+	Today           AttendanceSummary `json:"today"`
+	UpcomingLessons []*Booking        `json:"upcoming_lessons"`
+}
+
+func FromClassroomDetail(c *store.ClassroomDetail) *ClassroomDetail {
+	return &ClassroomDetail{
+		Classroom:       mapOne(c.Classroom, FromClassroom),
+		Teacher:         mapOne(c.Teacher, FromClassroomTeacher),
+		Students:        mapAll(c.Students, FromClassroomMember),
+		Today:           *FromAttendanceSummary(&c.Today),
+		UpcomingLessons: mapAll(c.UpcomingLessons, FromBooking),
+	}
+}
+
+// ClassroomTeacher is the teacher as the classroom page shows them.
+type ClassroomTeacher struct {
+	ID          int64  `json:"id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	Subject     string `json:"subject"`
+}
+
+func FromClassroomTeacher(c *store.ClassroomTeacher) *ClassroomTeacher {
+	return &ClassroomTeacher{
+		ID:          c.ID,
+		FirstName:   c.FirstName,
+		LastName:    c.LastName,
+		Email:       c.Email,
+		PhoneNumber: c.PhoneNumber,
+		Subject:     c.Subject,
+	}
+}
+
+// ClassroomMember is a student on the classroom page with today's
+// attendance status, nil when not marked yet.
+type ClassroomMember struct {
+	ID          int64   `json:"id"`
+	FirstName   string  `json:"first_name"`
+	LastName    string  `json:"last_name"`
+	StudentCode string  `json:"student_code"`
+	StatusToday *string `json:"status_today"`
+}
+
+func FromClassroomMember(c *store.ClassroomMember) *ClassroomMember {
+	return &ClassroomMember{
+		ID:          c.ID,
+		FirstName:   c.FirstName,
+		LastName:    c.LastName,
+		StudentCode: c.StudentCode,
+		StatusToday: c.StatusToday,
+	}
+}
+
+// AttendanceSummary counts a day's attendance by status.
+type AttendanceSummary struct {
+	Date     string `json:"date"`
+	Students int    `json:"students"`
+	Marked   int    `json:"marked"`
+	Present  int    `json:"present"`
+	Absent   int    `json:"absent"`
+	Late     int    `json:"late"`
+	Excused  int    `json:"excused"`
+}
+
+func FromAttendanceSummary(a *store.AttendanceSummary) *AttendanceSummary {
+	return &AttendanceSummary{
+		Date:     a.Date,
+		Students: a.Students,
+		Marked:   a.Marked,
+		Present:  a.Present,
+		Absent:   a.Absent,
+		Late:     a.Late,
+		Excused:  a.Excused,
+	}
+}
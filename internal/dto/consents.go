@@ -0,0 +1,110 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// ConsentMatrix is the consent status of a classroom's students (rows) for
+// every request any of them was asked (columns).
+type ConsentMatrix struct {
+	ClassroomID int64               `json:"classroom_id"`
+	Requests    []*ConsentRequest   `json:"requests"`
+	Students    []*ConsentMatrixRow `json:"students"`
+}
+
+func FromConsentMatrix(c *store.ConsentMatrix) *ConsentMatrix {
+	return &ConsentMatrix{
+		ClassroomID: c.ClassroomID,
+		Requests:    mapAll(c.Requests, FromConsentRequest),
+		Students:    mapAll(c.Students, FromConsentMatrixRow),
+	}
+}
+
+// ConsentRequest asks the parents of a set of students for consent, e.g.
+// for a field trip or photo usage.
+type ConsentRequest struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Kind        string    `json:"kind"`
+	DueDate     time.Time `json:"due_date"`
+	CreatedBy   int64     `json:"created_by"`
+	Pending     int64     `json:"pending"`
+	Approved    int64     `json:"approved"`
+	Declined    int64     `json:"declined"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func FromConsentRequest(c *store.ConsentRequest) *ConsentRequest {
+	return &ConsentRequest{
+		ID:          c.ID,
+		Title:       c.Title,
+		Description: c.Description,
+		Kind:        c.Kind,
+		DueDate:     c.DueDate,
+		CreatedBy:   c.CreatedBy,
+		Pending:     c.Pending,
+		Approved:    c.Approved,
+		Declined:    c.Declined,
+		CreatedAt:   c.CreatedAt,
+	}
+}
+
+func FromConsentRequests(list []*store.ConsentRequest) []*ConsentRequest {
+	return mapAll(list, FromConsentRequest)
+}
+
+type ConsentMatrixRow struct {
+	StudentID int64  `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	// Statuses maps request IDs to the student's status; requests the
+	// student wasn't asked are absent.
+	Statuses map[int64]string `json:"statuses"`
+}
+
+func FromConsentMatrixRow(c *store.ConsentMatrixRow) *ConsentMatrixRow {
+	return &ConsentMatrixRow{
+		StudentID: c.StudentID,
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		Statuses:  c.Statuses,
+	}
+}
+
+// StudentConsent is one student's consent for a request.
+type StudentConsent struct {
+	ID            int64      `json:"id"`
+	RequestID     int64      `json:"request_id"`
+	StudentID     int64      `json:"student_id"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	ClassroomID   *int64     `json:"classroom_id"`
+	ParentName    string     `json:"parent_name"`
+	Status        string     `json:"status"`
+	ResponderName string     `json:"responder_name"`
+	ResponderIP   string     `json:"responder_ip"`
+	RespondedAt   *time.Time `json:"responded_at"`
+}
+
+func FromStudentConsent(s *store.StudentConsent) *StudentConsent {
+	return &StudentConsent{
+		ID:            s.ID,
+		RequestID:     s.RequestID,
+		StudentID:     s.StudentID,
+		FirstName:     s.FirstName,
+		LastName:      s.LastName,
+		ClassroomID:   s.ClassroomID,
+		ParentName:    s.ParentName,
+		Status:        s.Status,
+		ResponderName: s.ResponderName,
+		ResponderIP:   s.ResponderIP,
+		RespondedAt:   s.RespondedAt,
+	}
+}
+
+func FromStudentConsents(list []*store.StudentConsent) []*StudentConsent {
+	return mapAll(list, FromStudentConsent)
+}
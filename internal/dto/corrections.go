@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// AttendanceCorrection is a teacher's request to change attendance past the
+// window they may edit it in. Approving it applies NewStatus and NewNote to
+// the student's record for Date; PreviousStatus and PreviousNote keep what
+// the record held, nil when there was none.
+type AttendanceCorrection struct {
+	ID             int64      `json:"id"`
+	StudentID      int64      `json:"student_id"`
+	Date           time.Time  `json:"date"`
+	NewStatus      string     `json:"new_status" enums:"present,absent,late,excused"`
+	NewNote        *string    `json:"new_note"`
+	Reason         string     `json:"reason"`
+	RequestedBy    int64      `json:"requested_by"`
+	Status         string     `json:"status" enums:"pending,approved,rejected"`
+	PreviousStatus *string    `json:"previous_status"`
+	PreviousNote   *string    `json:"previous_note"`
+	RecordID       *int64     `json:"record_id"`
+	ReviewedBy     *int64     `json:"reviewed_by"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	ReviewNote     string     `json:"review_note"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func FromAttendanceCorrection(a *store.AttendanceCorrection) *AttendanceCorrection {
+	return &AttendanceCorrection{
+		ID:             a.ID,
+		StudentID:      a.StudentID,
+		Date:           a.Date,
+		NewStatus:      a.NewStatus,
+		NewNote:        a.NewNote,
+		Reason:         a.Reason,
+		RequestedBy:    a.RequestedBy,
+		Status:         a.Status,
+		PreviousStatus: a.PreviousStatus,
+		PreviousNote:   a.PreviousNote,
+		RecordID:       a.RecordID,
+		ReviewedBy:     a.ReviewedBy,
+		ReviewedAt:     a.ReviewedAt,
+		ReviewNote:     a.ReviewNote,
+		CreatedAt:      a.CreatedAt,
+	}
+}
+
+func FromAttendanceCorrections(list []*store.AttendanceCorrection) []*AttendanceCorrection {
+	return mapAll(list, FromAttendanceCorrection)
+}
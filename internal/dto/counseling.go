@@ -0,0 +1,118 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// CounselingReferral is staff asking a counselor to see a student. It
+// closes when a session is logged against it.
+type CounselingReferral struct {
+	ID             int64      `json:"id"`
+	StudentID      int64      `json:"student_id"`
+	Category       string     `json:"category"`
+	Reason         string     `json:"reason"`
+	ReferredBy     int64      `json:"referred_by"`
+	ReferredByRole string     `json:"referred_by_role"`
+	Status         string     `json:"status" enums:"open,closed"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func FromCounselingReferral(c *store.CounselingReferral) *CounselingReferral {
+	return &CounselingReferral{
+		ID:             c.ID,
+		StudentID:      c.StudentID,
+		Category:       c.Category,
+		Reason:         c.Reason,
+		ReferredBy:     c.ReferredBy,
+		ReferredByRole: c.ReferredByRole,
+		Status:         c.Status,
+		ClosedAt:       c.ClosedAt,
+		CreatedAt:      c.CreatedAt,
+	}
+}
+
+func FromCounselingReferrals(list []*store.CounselingReferral) []*CounselingReferral {
+	return mapAll(list, FromCounselingReferral)
+}
+
+// CounselingSession is a counselor's record of meeting a student. Notes
+// are only shown to holders of the counseling:read permission.
+type CounselingSession struct {
+	ID            int64      `json:"id"`
+	StudentID     int64      `json:"student_id"`
+	ReferralID    *int64     `json:"referral_id"`
+	Category      string     `json:"category"`
+	HeldOn        time.Time  `json:"held_on"`
+	Notes         string     `json:"notes"`
+	FollowUpOn    *time.Time `json:"follow_up_on"`
+	CounselorID   int64      `json:"counselor_id"`
+	CounselorRole string     `json:"counselor_role"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func FromCounselingSession(c *store.CounselingSession) *CounselingSession {
+	return &CounselingSession{
+		ID:            c.ID,
+		StudentID:     c.StudentID,
+		ReferralID:    c.ReferralID,
+		Category:      c.Category,
+		HeldOn:        c.HeldOn,
+		Notes:         c.Notes,
+		FollowUpOn:    c.FollowUpOn,
+		CounselorID:   c.CounselorID,
+		CounselorRole: c.CounselorRole,
+		CreatedAt:     c.CreatedAt,
+	}
+}
+
+func FromCounselingSessions(list []*store.CounselingSession) []*CounselingSession {
+	return mapAll(list, FromCounselingSession)
+}
+
+// CounselingStats counts sessions over a period without naming anyone.
+// Groups with fewer students than the threshold it was read with have
+// their counts left out, so small groups can't be traced to a student.
+type CounselingStats struct {
+	From            time.Time          `json:"from"`
+	To              time.Time          `json:"to"`
+	Sessions        int                `json:"sessions"`
+	Students        int                `json:"students"`
+	OpenReferrals   int                `json:"open_referrals"`
+	ClosedReferrals int                `json:"closed_referrals"`
+	ByCategory      []*CounselingGroup `json:"by_category"`
+	ByGrade         []*CounselingGroup `json:"by_grade"`
+	ByMonth         []*CounselingGroup `json:"by_month"`
+}
+
+func FromCounselingStats(c *store.CounselingStats) *CounselingStats {
+	return &CounselingStats{
+		From:            c.From,
+		To:              c.To,
+		Sessions:        c.Sessions,
+		Students:        c.Students,
+		OpenReferrals:   c.OpenReferrals,
+		ClosedReferrals: c.ClosedReferrals,
+		ByCategory:      mapAll(c.ByCategory, FromCounselingGroup),
+		ByGrade:         mapAll(c.ByGrade, FromCounselingGroup),
+		ByMonth:         mapAll(c.ByMonth, FromCounselingGroup),
+	}
+}
+
+// CounselingGroup is one row of a breakdown; Sessions and Students are nil
+// when the group is too small to show.
+type CounselingGroup struct {
+	Key      string `json:"key"`
+	Sessions *int   `json:"sessions"`
+	Students *int   `json:"students"`
+}
+
+func FromCounselingGroup(c *store.CounselingGroup) *CounselingGroup {
+	return &CounselingGroup{
+		Key:      c.Key,
+		Sessions: c.Sessions,
+		Students: c.Students,
+	}
+}
@@ -0,0 +1,84 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// DiscountFacts is what the discount rules look at for a student.
+// SiblingRank is their place among the family's live students, eldest
+// first.
+type DiscountFacts struct {
+	StudentID   int64 `json:"student_id"`
+	SiblingRank int   `json:"sibling_rank"`
+	Siblings    int   `json:"siblings"`
+	StaffChild  bool  `json:"staff_child"`
+}
+
+func FromDiscountFacts(d *store.DiscountFacts) *DiscountFacts {
+	return &DiscountFacts{
+		StudentID:   d.StudentID,
+		SiblingRank: d.SiblingRank,
+		Siblings:    d.Siblings,
+		StaffChild:  d.StaffChild,
+	}
+}
+
+// DiscountRule is a discount applied to new invoices. MinSiblingRank only
+// matters for sibling rules.
+type DiscountRule struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind"`
+	Percent        int       `json:"percent"`
+	MinSiblingRank int       `json:"min_sibling_rank"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func FromDiscountRule(d *store.DiscountRule) *DiscountRule {
+	return &DiscountRule{
+		ID:             d.ID,
+		Name:           d.Name,
+		Kind:           d.Kind,
+		Percent:        d.Percent,
+		MinSiblingRank: d.MinSiblingRank,
+		Active:         d.Active,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+func FromDiscountRules(list []*store.DiscountRule) []*DiscountRule {
+	return mapAll(list, FromDiscountRule)
+}
+
+// StudentDiscount overrides a rule for one student. A nil Percent keeps the
+// rule's; 0 exempts the student.
+type StudentDiscount struct {
+	StudentID int64     `json:"student_id"`
+	RuleID    int64     `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Percent   *int      `json:"percent"`
+	Note      string    `json:"note"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func FromStudentDiscount(s *store.StudentDiscount) *StudentDiscount {
+	return &StudentDiscount{
+		StudentID: s.StudentID,
+		RuleID:    s.RuleID,
+		RuleName:  s.RuleName,
+		Percent:   s.Percent,
+		Note:      s.Note,
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func FromStudentDiscounts(list []*store.StudentDiscount) []*StudentDiscount {
+	return mapAll(list, FromStudentDiscount)
+}
@@ -0,0 +1,32 @@
+// Package dto holds the shapes the API responds with. Handlers map store
+// records into them before writing, so columns the store keeps for itself
+// — password hashes, deletion markers, bookkeeping — never reach clients,
+// whatever is added to the store structs later. The swagger models are
+// these types too.
+package dto
+
+// mapAll maps every element of a list.
+func mapAll[S, D any](list []*S, f func(*S) *D) []*D {
+	out := make([]*D, len(list))
+	for i, v := range list {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// mapValues maps every element of a list held by value.
+func mapValues[S, D any](list []S, f func(*S) *D) []D {
+	out := make([]D, len(list))
+	for i := range list {
+		out[i] = *f(&list[i])
+	}
+	return out
+}
+
+// mapOne maps a record that may be absent.
+func mapOne[S, D any](v *S, f func(*S) *D) *D {
+	if v == nil {
+		return nil
+	}
+	return f(v)
+}
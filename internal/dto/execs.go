@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Exec struct {
+	ID        int64  `json:"id" example:"42"`
+	FirstName string `json:"first_name" example:"Sara"`
+	LastName  string `json:"last_name" example:"Ahmadi"`
+	Email     string `json:"email" example:"sara.ahmadi@example.com"`
+	Role      string `json:"role"`
+	// Scope limits a manager to some grades or classrooms; admins ignore it.
+	Scope     Scope     `json:"scope"`
+	CreatedAt time.Time `json:"created_at" example:"2026-09-01T08:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2026-10-16T10:30:00Z"`
+	// LastLoginAt and LastLoginIP are set on lists only.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" example:"2026-10-16T07:45:00Z"`
+	LastLoginIP *string    `json:"last_login_ip,omitempty" example:"203.0.113.7"`
+}
+
+type Scope struct {
+	Grades     []int64 `json:"grades"`
+	Classrooms []int64 `json:"classrooms"`
+}
+
+func FromExec(e *store.Exec) *Exec {
+	return &Exec{
+		ID:          e.ID,
+		FirstName:   e.FirstName,
+		LastName:    e.LastName,
+		Email:       e.Email,
+		Role:        string(e.Role),
+		Scope:       Scope{Grades: e.Scope.Grades, Classrooms: e.Scope.Classrooms},
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+		LastLoginAt: e.LastLoginAt,
+		LastLoginIP: e.LastLoginIP,
+	}
+}
+
+func FromExecs(list []*store.Exec) []*Exec {
+	return mapAll(list, FromExec)
+}
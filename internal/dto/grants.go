@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// PermissionGrant gives one account a permission its role doesn't have.
+type PermissionGrant struct {
+	AccountID   int64     `json:"account_id"`
+	Permission  string    `json:"permission"`
+	Role        string    `json:"role"`
+	ProfileType string    `json:"profile_type"`
+	ProfileID   int64     `json:"profile_id"`
+	Email       *string   `json:"email"`
+	GrantedBy   *int64    `json:"granted_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func FromPermissionGrant(p *store.PermissionGrant) *PermissionGrant {
+	return &PermissionGrant{
+		AccountID:   p.AccountID,
+		Permission:  p.Permission,
+		Role:        p.Role,
+		ProfileType: p.ProfileType,
+		ProfileID:   p.ProfileID,
+		Email:       p.Email,
+		GrantedBy:   p.GrantedBy,
+		CreatedAt:   p.CreatedAt,
+	}
+}
+
+func FromPermissionGrants(list []*store.PermissionGrant) []*PermissionGrant {
+	return mapAll(list, FromPermissionGrant)
+}
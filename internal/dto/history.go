@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// EntityChange is one update of a record, keyed by JSON field name.
+type EntityChange struct {
+	ID            int64                  `json:"id"`
+	Entity        string                 `json:"entity"`
+	EntityID      int64                  `json:"entity_id"`
+	ChangedBy     int64                  `json:"changed_by"`
+	ChangedByRole string                 `json:"changed_by_role"`
+	Changes       map[string]FieldChange `json:"changes"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+func FromEntityChange(e *store.EntityChange) *EntityChange {
+	changes := make(map[string]FieldChange, len(e.Changes))
+	for field, c := range e.Changes {
+		changes[field] = *FromFieldChange(&c)
+	}
+	return &EntityChange{
+		ID:            e.ID,
+		Entity:        e.Entity,
+		EntityID:      e.EntityID,
+		ChangedBy:     e.ChangedBy,
+		ChangedByRole: e.ChangedByRole,
+		Changes:       changes,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+func FromEntityChanges(list []*store.EntityChange) []*EntityChange {
+	return mapAll(list, FromEntityChange)
+}
+
+// FieldChange is the old and new JSON value of one changed field.
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+func FromFieldChange(f *store.FieldChange) *FieldChange {
+	return &FieldChange{
+		Old: f.Old,
+		New: f.New,
+	}
+}
@@ -0,0 +1,253 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// DelinquencyRow totals the overdue installments of one classroom, or of
+// one grade. Students without a classroom have neither.
+type DelinquencyRow struct {
+	ClassroomID    *int64 `json:"classroom_id,omitempty"`
+	ClassroomName  string `json:"classroom_name,omitempty"`
+	Grade          *int64 `json:"grade"`
+	Students       int    `json:"students"`
+	Installments   int    `json:"installments"`
+	OverdueAmount  int64  `json:"overdue_amount"`
+	MaxDaysOverdue int    `json:"max_days_overdue"`
+}
+
+func FromDelinquencyRow(d *store.DelinquencyRow) *DelinquencyRow {
+	return &DelinquencyRow{
+		ClassroomID:    d.ClassroomID,
+		ClassroomName:  d.ClassroomName,
+		Grade:          d.Grade,
+		Students:       d.Students,
+		Installments:   d.Installments,
+		OverdueAmount:  d.OverdueAmount,
+		MaxDaysOverdue: d.MaxDaysOverdue,
+	}
+}
+
+func FromDelinquencyRows(list []*store.DelinquencyRow) []*DelinquencyRow {
+	return mapAll(list, FromDelinquencyRow)
+}
+
+type FinanceSummary struct {
+	Term string `json:"term"`
+	FinanceTotals
+	Grades []*GradeFinance `json:"grades"`
+}
+
+func FromFinanceSummary(f *store.FinanceSummary) *FinanceSummary {
+	return &FinanceSummary{
+		Term:          f.Term,
+		FinanceTotals: *FromFinanceTotals(&f.FinanceTotals),
+		Grades:        mapAll(f.Grades, FromGradeFinance),
+	}
+}
+
+// FinanceTotals are the billed, collected and outstanding amounts of a set
+// of invoices. Void invoices are not billed.
+type FinanceTotals struct {
+	Invoices    int          `json:"invoices"`
+	Billed      int64        `json:"billed"`
+	Collected   int64        `json:"collected"`
+	Outstanding int64        `json:"outstanding"`
+	Aging       AgingBuckets `json:"aging"`
+}
+
+func FromFinanceTotals(f *store.FinanceTotals) *FinanceTotals {
+	return &FinanceTotals{
+		Invoices:    f.Invoices,
+		Billed:      f.Billed,
+		Collected:   f.Collected,
+		Outstanding: f.Outstanding,
+		Aging:       *FromAgingBuckets(&f.Aging),
+	}
+}
+
+// AgingBuckets splits unpaid amounts by how long past due they are.
+type AgingBuckets struct {
+	Current    int64 `json:"current"`
+	Days1To30  int64 `json:"days_1_30"`
+	Days31To60 int64 `json:"days_31_60"`
+	Days61To90 int64 `json:"days_61_90"`
+	Over90     int64 `json:"over_90"`
+}
+
+func FromAgingBuckets(a *store.AgingBuckets) *AgingBuckets {
+	return &AgingBuckets{
+		Current:    a.Current,
+		Days1To30:  a.Days1To30,
+		Days31To60: a.Days31To60,
+		Days61To90: a.Days61To90,
+		Over90:     a.Over90,
+	}
+}
+
+// GradeFinance is FinanceTotals for one grade; Grade is nil for students
+// without a classroom.
+type GradeFinance struct {
+	Grade *int64 `json:"grade"`
+	FinanceTotals
+}
+
+func FromGradeFinance(g *store.GradeFinance) *GradeFinance {
+	return &GradeFinance{
+		Grade:         g.Grade,
+		FinanceTotals: *FromFinanceTotals(&g.FinanceTotals),
+	}
+}
+
+// Invoice is a fee billed to a student's parents. It is paid in one or
+// more installments; DueOn is the last installment's due date.
+type Invoice struct {
+	ID          int64  `json:"id"`
+	StudentID   int64  `json:"student_id"`
+	StudentName string `json:"student_name"`
+	Title       string `json:"title"`
+	Term        string `json:"term"`
+	// Amount, AmountPaid, Lines, Installments and Payments are left out for
+	// teachers.
+	Amount       *int64         `json:"amount,omitempty"`
+	AmountPaid   *int64         `json:"amount_paid,omitempty"`
+	DueOn        time.Time      `json:"due_on"`
+	Status       string         `json:"status"`
+	PaidAt       *time.Time     `json:"paid_at"`
+	CreatedBy    *int64         `json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Lines        []*InvoiceLine `json:"lines,omitempty"`
+	Installments []*Installment `json:"installments,omitempty"`
+	Payments     []*Payment     `json:"payments,omitempty"`
+}
+
+func FromInvoice(v Viewer, i *store.Invoice) *Invoice {
+	if !v.seesFees() {
+		return &Invoice{
+			ID:          i.ID,
+			StudentID:   i.StudentID,
+			StudentName: i.StudentName,
+			Title:       i.Title,
+			Term:        i.Term,
+			DueOn:       i.DueOn,
+			Status:      i.Status,
+			PaidAt:      i.PaidAt,
+			CreatedBy:   i.CreatedBy,
+			CreatedAt:   i.CreatedAt,
+			UpdatedAt:   i.UpdatedAt,
+		}
+	}
+	return &Invoice{
+		ID:           i.ID,
+		StudentID:    i.StudentID,
+		StudentName:  i.StudentName,
+		Title:        i.Title,
+		Term:         i.Term,
+		Amount:       &i.Amount,
+		AmountPaid:   &i.AmountPaid,
+		DueOn:        i.DueOn,
+		Status:       i.Status,
+		PaidAt:       i.PaidAt,
+		CreatedBy:    i.CreatedBy,
+		CreatedAt:    i.CreatedAt,
+		UpdatedAt:    i.UpdatedAt,
+		Lines:        mapAll(i.Lines, FromInvoiceLine),
+		Installments: mapAll(i.Installments, FromInstallment),
+		Payments:     mapAll(i.Payments, func(p *store.Payment) *Payment { return FromPayment(v, p) }),
+	}
+}
+
+func FromInvoices(v Viewer, list []*store.Invoice) []*Invoice {
+	return mapAll(list, func(i *store.Invoice) *Invoice { return FromInvoice(v, i) })
+}
+
+// InvoiceLine is part of an invoice's amount: the fee, or a negative
+// discount with the rule that produced it, its percent and why it applied.
+type InvoiceLine struct {
+	ID          int64  `json:"id"`
+	InvoiceID   int64  `json:"invoice_id"`
+	Description string `json:"description"`
+	Amount      int64  `json:"amount"`
+	RuleID      *int64 `json:"rule_id"`
+	Percent     *int   `json:"percent"`
+	Reason      string `json:"reason"`
+}
+
+func FromInvoiceLine(i *store.InvoiceLine) *InvoiceLine {
+	return &InvoiceLine{
+		ID:          i.ID,
+		InvoiceID:   i.InvoiceID,
+		Description: i.Description,
+		Amount:      i.Amount,
+		RuleID:      i.RuleID,
+		Percent:     i.Percent,
+		Reason:      i.Reason,
+	}
+}
+
+// Installment is one part of an invoice's payment schedule. Seq counts
+// from 1.
+type Installment struct {
+	ID            int64      `json:"id"`
+	InvoiceID     int64      `json:"invoice_id"`
+	Seq           int        `json:"seq"`
+	Amount        int64      `json:"amount"`
+	DueOn         time.Time  `json:"due_on"`
+	PaidAt        *time.Time `json:"paid_at"`
+	RemindersSent int        `json:"reminders_sent"`
+}
+
+func FromInstallment(i *store.Installment) *Installment {
+	return &Installment{
+		ID:            i.ID,
+		InvoiceID:     i.InvoiceID,
+		Seq:           i.Seq,
+		Amount:        i.Amount,
+		DueOn:         i.DueOn,
+		PaidAt:        i.PaidAt,
+		RemindersSent: i.RemindersSent,
+	}
+}
+
+// Payment is an attempt to pay an invoice installment through a gateway.
+type Payment struct {
+	ID            int64  `json:"id"`
+	InvoiceID     int64  `json:"invoice_id"`
+	InstallmentID *int64 `json:"installment_id"`
+	// Gateway, Amount, RefID and CardPAN are left out for teachers.
+	Gateway    *string    `json:"gateway,omitempty"`
+	Amount     *int64     `json:"amount,omitempty"`
+	Authority  string     `json:"authority"`
+	Status     string     `json:"status"`
+	RefID      *string    `json:"ref_id,omitempty"`
+	CardPAN    *string    `json:"card_pan,omitempty"`
+	Failure    string     `json:"failure"`
+	ParentID   *int64     `json:"parent_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	// ReceiptNumber is set once the payment is confirmed.
+	ReceiptNumber string `json:"receipt_number,omitempty"`
+}
+
+func FromPayment(v Viewer, p *store.Payment) *Payment {
+	fees := v.seesFees()
+	return &Payment{
+		ID:            p.ID,
+		InvoiceID:     p.InvoiceID,
+		InstallmentID: p.InstallmentID,
+		Gateway:       only(fees, &p.Gateway),
+		Amount:        only(fees, &p.Amount),
+		Authority:     p.Authority,
+		Status:        p.Status,
+		RefID:         only(fees, &p.RefID),
+		CardPAN:       only(fees, &p.CardPAN),
+		Failure:       p.Failure,
+		ParentID:      p.ParentID,
+		CreatedAt:     p.CreatedAt,
+		VerifiedAt:    p.VerifiedAt,
+		ReceiptNumber: p.ReceiptNumber,
+	}
+}
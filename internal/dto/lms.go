@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// Grade is a score imported from an LMS.
+type Grade struct {
+	ID          int64     `json:"id"`
+	StudentID   int64     `json:"student_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	ClassroomID int64     `json:"classroom_id"`
+	Source      string    `json:"source"`
+	Assignment  string    `json:"assignment"`
+	Score       float64   `json:"score"`
+	MaxScore    *float64  `json:"max_score,omitempty"`
+	ImportedAt  time.Time `json:"imported_at"`
+}
+
+func FromGrade(g *store.Grade) *Grade {
+	return &Grade{
+		ID:          g.ID,
+		StudentID:   g.StudentID,
+		FirstName:   g.FirstName,
+		LastName:    g.LastName,
+		ClassroomID: g.ClassroomID,
+		Source:      g.Source,
+		Assignment:  g.Assignment,
+		Score:       g.Score,
+		MaxScore:    g.MaxScore,
+		ImportedAt:  g.ImportedAt,
+	}
+}
+
+func FromGrades(list []*store.Grade) []*Grade {
+	return mapAll(list, FromGrade)
+}
+
+// LMSSync is a classroom's scheduled exchange with an LMS.
+type LMSSync struct {
+	ClassroomID     int64      `json:"classroom_id"`
+	Format          string     `json:"format"`
+	GradesSource    string     `json:"grades_source"`
+	RosterURL       string     `json:"roster_url"`
+	GradesURL       string     `json:"grades_url"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Enabled         bool       `json:"enabled"`
+	NextRunAt       time.Time  `json:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	LastError       string     `json:"last_error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func FromLMSSync(l *store.LMSSync) *LMSSync {
+	return &LMSSync{
+		ClassroomID:     l.ClassroomID,
+		Format:          l.Format,
+		GradesSource:    l.GradesSource,
+		RosterURL:       l.RosterURL,
+		GradesURL:       l.GradesURL,
+		IntervalMinutes: l.IntervalMinutes,
+		Enabled:         l.Enabled,
+		NextRunAt:       l.NextRunAt,
+		LastRunAt:       l.LastRunAt,
+		LastError:       l.LastError,
+		CreatedAt:       l.CreatedAt,
+		UpdatedAt:       l.UpdatedAt,
+	}
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// LostItem is something staff found around the school. It is matched once
+// its owner is known and claimed once it is handed back.
+type LostItem struct {
+	ID          int64      `json:"id"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	FoundOn     time.Time  `json:"found_on"`
+	FoundBy     int64      `json:"found_by"`
+	FoundByRole string     `json:"found_by_role"`
+	Status      string     `json:"status" enums:"unclaimed,matched,claimed"`
+	StudentID   *int64     `json:"student_id"`
+	MatchedAt   *time.Time `json:"matched_at"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+	HasPhoto    bool       `json:"has_photo"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func FromLostItem(l *store.LostItem) *LostItem {
+	return &LostItem{
+		ID:          l.ID,
+		Description: l.Description,
+		Location:    l.Location,
+		FoundOn:     l.FoundOn,
+		FoundBy:     l.FoundBy,
+		FoundByRole: l.FoundByRole,
+		Status:      l.Status,
+		StudentID:   l.StudentID,
+		MatchedAt:   l.MatchedAt,
+		ClaimedAt:   l.ClaimedAt,
+		HasPhoto:    l.HasPhoto,
+		CreatedAt:   l.CreatedAt,
+		UpdatedAt:   l.UpdatedAt,
+	}
+}
+
+func FromLostItems(list []*store.LostItem) []*LostItem {
+	return mapAll(list, FromLostItem)
+}
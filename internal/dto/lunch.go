@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// LunchMenu is what the canteen serves on a date.
+type LunchMenu struct {
+	Date      string    `json:"date" example:"2026-10-17"`
+	Items     []string  `json:"items" example:"Chelo kabab,Salad,Doogh"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func FromLunchMenu(l *store.LunchMenu) *LunchMenu {
+	return &LunchMenu{
+		Date:      l.Date,
+		Items:     l.Items,
+		Notes:     l.Notes,
+		CreatedAt: l.CreatedAt,
+		UpdatedAt: l.UpdatedAt,
+	}
+}
+
+func FromLunchMenus(list []*store.LunchMenu) []*LunchMenu {
+	return mapAll(list, FromLunchMenu)
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// StudentMerge records one duplicate folded into a surviving student.
+type StudentMerge struct {
+	ID                int64     `json:"id"`
+	SurvivorID        int64     `json:"survivor_id"`
+	DuplicateID       int64     `json:"duplicate_id"`
+	MergedBy          int64     `json:"merged_by"`
+	AttendanceMoved   int64     `json:"attendance_moved"`
+	AttendanceSkipped int64     `json:"attendance_skipped"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func FromStudentMerge(s *store.StudentMerge) *StudentMerge {
+	return &StudentMerge{
+		ID:                s.ID,
+		SurvivorID:        s.SurvivorID,
+		DuplicateID:       s.DuplicateID,
+		MergedBy:          s.MergedBy,
+		AttendanceMoved:   s.AttendanceMoved,
+		AttendanceSkipped: s.AttendanceSkipped,
+		CreatedAt:         s.CreatedAt,
+	}
+}
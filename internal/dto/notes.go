@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// Note is a free-text staff note on a record, keyed like tags by table name
+// and row ID.
+type Note struct {
+	ID         int64     `json:"id"`
+	Entity     string    `json:"entity"`
+	EntityID   int64     `json:"entity_id"`
+	Body       string    `json:"body"`
+	AuthorID   int64     `json:"author_id"`
+	AuthorRole string    `json:"author_role"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func FromNote(n *store.Note) *Note {
+	return &Note{
+		ID:         n.ID,
+		Entity:     n.Entity,
+		EntityID:   n.EntityID,
+		Body:       n.Body,
+		AuthorID:   n.AuthorID,
+		AuthorRole: n.AuthorRole,
+		CreatedAt:  n.CreatedAt,
+	}
+}
+
+func FromNotes(list []*store.Note) []*Note {
+	return mapAll(list, FromNote)
+}
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// OnlineSession is a live online period of a classroom. While it is open
+// students mark themselves present by joining; closing it records
+// DefaultStatus for everyone else.
+type OnlineSession struct {
+	ID            int64      `json:"id"`
+	ClassroomID   int64      `json:"classroom_id"`
+	TeacherID     *int64     `json:"teacher_id"`
+	Date          time.Time  `json:"date"`
+	DefaultStatus string     `json:"default_status"`
+	StartedAt     time.Time  `json:"started_at"`
+	EndedAt       *time.Time `json:"ended_at"`
+	Defaulted     int64      `json:"defaulted"`
+	// MeetingProvider and MeetingURL are set when the session got its own
+	// meeting; otherwise students use the classroom's link.
+	MeetingProvider string `json:"meeting_provider,omitempty"`
+	MeetingURL      string `json:"meeting_url,omitempty"`
+}
+
+func FromOnlineSession(o *store.OnlineSession) *OnlineSession {
+	return &OnlineSession{
+		ID:              o.ID,
+		ClassroomID:     o.ClassroomID,
+		TeacherID:       o.TeacherID,
+		Date:            o.Date,
+		DefaultStatus:   o.DefaultStatus,
+		StartedAt:       o.StartedAt,
+		EndedAt:         o.EndedAt,
+		Defaulted:       o.Defaulted,
+		MeetingProvider: o.MeetingProvider,
+		MeetingURL:      o.MeetingURL,
+	}
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// Parent is a guardian who signs in by phone. Children are matched by
+// students.parent_phone_number rather than a link table.
+type Parent struct {
+	ID          int64     `json:"id"`
+	PhoneNumber string    `json:"phone_number"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+func FromParent(p *store.Parent) *Parent {
+	return &Parent{
+		ID:          p.ID,
+		PhoneNumber: p.PhoneNumber,
+		CreatedAt:   p.CreatedAt,
+		LastLoginAt: p.LastLoginAt,
+	}
+}
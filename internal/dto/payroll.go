@@ -0,0 +1,125 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type ExpenseClaim struct {
+	ID          int64      `json:"id"`
+	StaffKind   string     `json:"staff_kind"`
+	StaffID     int64      `json:"staff_id"`
+	StaffName   string     `json:"staff_name"`
+	Amount      int64      `json:"amount"`
+	Description string     `json:"description"`
+	SpentOn     time.Time  `json:"spent_on"`
+	Status      string     `json:"status"`
+	HasReceipt  bool       `json:"has_receipt"`
+	ReviewedBy  *int64     `json:"reviewed_by"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	ReviewNote  string     `json:"review_note"`
+	PayslipID   *int64     `json:"payslip_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func FromExpenseClaim(e *store.ExpenseClaim) *ExpenseClaim {
+	return &ExpenseClaim{
+		ID:          e.ID,
+		StaffKind:   e.StaffKind,
+		StaffID:     e.StaffID,
+		StaffName:   e.StaffName,
+		Amount:      e.Amount,
+		Description: e.Description,
+		SpentOn:     e.SpentOn,
+		Status:      e.Status,
+		HasReceipt:  e.HasReceipt,
+		ReviewedBy:  e.ReviewedBy,
+		ReviewedAt:  e.ReviewedAt,
+		ReviewNote:  e.ReviewNote,
+		PayslipID:   e.PayslipID,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+func FromExpenseClaims(list []*store.ExpenseClaim) []*ExpenseClaim {
+	return mapAll(list, FromExpenseClaim)
+}
+
+// Payslip is one month's pay. Period is the first day of the month.
+// Reimbursements are the approved expense claims paid with it.
+type Payslip struct {
+	ID             int64     `json:"id"`
+	ContractID     int64     `json:"contract_id"`
+	StaffKind      string    `json:"staff_kind"`
+	StaffID        int64     `json:"staff_id"`
+	StaffName      string    `json:"staff_name"`
+	Title          string    `json:"title"`
+	Period         time.Time `json:"period"`
+	BaseSalary     int64     `json:"base_salary"`
+	Allowances     int64     `json:"allowances"`
+	Reimbursements int64     `json:"reimbursements"`
+	Deductions     int64     `json:"deductions"`
+	Net            int64     `json:"net"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func FromPayslip(p *store.Payslip) *Payslip {
+	return &Payslip{
+		ID:             p.ID,
+		ContractID:     p.ContractID,
+		StaffKind:      p.StaffKind,
+		StaffID:        p.StaffID,
+		StaffName:      p.StaffName,
+		Title:          p.Title,
+		Period:         p.Period,
+		BaseSalary:     p.BaseSalary,
+		Allowances:     p.Allowances,
+		Reimbursements: p.Reimbursements,
+		Deductions:     p.Deductions,
+		Net:            p.Net,
+		CreatedAt:      p.CreatedAt,
+	}
+}
+
+func FromPayslips(list []*store.Payslip) []*Payslip {
+	return mapAll(list, FromPayslip)
+}
+
+// StaffContract holds a teacher's or exec's monthly pay. StaffKind is
+// "teacher" or "exec", the table StaffID belongs to.
+type StaffContract struct {
+	ID         int64      `json:"id"`
+	StaffKind  string     `json:"staff_kind"`
+	StaffID    int64      `json:"staff_id"`
+	StaffName  string     `json:"staff_name"`
+	Title      string     `json:"title"`
+	BaseSalary int64      `json:"base_salary"`
+	Allowances int64      `json:"allowances"`
+	Deductions int64      `json:"deductions"`
+	StartsOn   time.Time  `json:"starts_on"`
+	EndsOn     *time.Time `json:"ends_on"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func FromStaffContract(s *store.StaffContract) *StaffContract {
+	return &StaffContract{
+		ID:         s.ID,
+		StaffKind:  s.StaffKind,
+		StaffID:    s.StaffID,
+		StaffName:  s.StaffName,
+		Title:      s.Title,
+		BaseSalary: s.BaseSalary,
+		Allowances: s.Allowances,
+		Deductions: s.Deductions,
+		StartsOn:   s.StartsOn,
+		EndsOn:     s.EndsOn,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func FromStaffContracts(list []*store.StaffContract) []*StaffContract {
+	return mapAll(list, FromStaffContract)
+}
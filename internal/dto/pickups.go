@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// PickupCheckout is a logged gate check.
+type PickupCheckout struct {
+	ID            int64     `json:"id"`
+	StudentID     int64     `json:"student_id"`
+	ContactID     *int64    `json:"contact_id"`
+	ContactName   string    `json:"contact_name"`
+	Authorized    bool      `json:"authorized"`
+	Note          string    `json:"note"`
+	CheckedBy     int64     `json:"checked_by"`
+	CheckedByRole string    `json:"checked_by_role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func FromPickupCheckout(p *store.PickupCheckout) *PickupCheckout {
+	return &PickupCheckout{
+		ID:            p.ID,
+		StudentID:     p.StudentID,
+		ContactID:     p.ContactID,
+		ContactName:   p.ContactName,
+		Authorized:    p.Authorized,
+		Note:          p.Note,
+		CheckedBy:     p.CheckedBy,
+		CheckedByRole: p.CheckedByRole,
+		CreatedAt:     p.CreatedAt,
+	}
+}
+
+func FromPickupCheckouts(list []*store.PickupCheckout) []*PickupCheckout {
+	return mapAll(list, FromPickupCheckout)
+}
+
+// PickupContact is a person authorized to pick a student up.
+type PickupContact struct {
+	ID          int64     `json:"id"`
+	StudentID   int64     `json:"student_id"`
+	Name        string    `json:"name"`
+	Relation    string    `json:"relation"`
+	PhoneNumber string    `json:"phone_number"`
+	HasPhoto    bool      `json:"has_photo"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func FromPickupContact(p *store.PickupContact) *PickupContact {
+	return &PickupContact{
+		ID:          p.ID,
+		StudentID:   p.StudentID,
+		Name:        p.Name,
+		Relation:    p.Relation,
+		PhoneNumber: p.PhoneNumber,
+		HasPhoto:    p.HasPhoto,
+		Active:      p.Active,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+func FromPickupContacts(list []*store.PickupContact) []*PickupContact {
+	return mapAll(list, FromPickupContact)
+}
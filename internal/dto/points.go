@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// LeaderboardEntry is a student's point total in a classroom.
+type LeaderboardEntry struct {
+	Rank      int    `json:"rank"`
+	StudentID int64  `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Points    int64  `json:"points"`
+	Awards    int64  `json:"awards"`
+}
+
+func FromLeaderboardEntry(l *store.LeaderboardEntry) *LeaderboardEntry {
+	return &LeaderboardEntry{
+		Rank:      l.Rank,
+		StudentID: l.StudentID,
+		FirstName: l.FirstName,
+		LastName:  l.LastName,
+		Points:    l.Points,
+		Awards:    l.Awards,
+	}
+}
+
+func FromLeaderboardEntries(list []*store.LeaderboardEntry) []*LeaderboardEntry {
+	return mapAll(list, FromLeaderboardEntry)
+}
+
+// PointAward is points given to (or taken from) a student.
+type PointAward struct {
+	ID            int64     `json:"id"`
+	StudentID     int64     `json:"student_id"`
+	ClassroomID   *int64    `json:"classroom_id"`
+	CategoryID    int64     `json:"category_id"`
+	Category      string    `json:"category"`
+	Points        int       `json:"points"`
+	Reason        string    `json:"reason"`
+	AwardedBy     int64     `json:"awarded_by"`
+	AwardedByRole string    `json:"awarded_by_role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func FromPointAward(p *store.PointAward) *PointAward {
+	return &PointAward{
+		ID:            p.ID,
+		StudentID:     p.StudentID,
+		ClassroomID:   p.ClassroomID,
+		CategoryID:    p.CategoryID,
+		Category:      p.Category,
+		Points:        p.Points,
+		Reason:        p.Reason,
+		AwardedBy:     p.AwardedBy,
+		AwardedByRole: p.AwardedByRole,
+		CreatedAt:     p.CreatedAt,
+	}
+}
+
+func FromPointAwards(list []*store.PointAward) []*PointAward {
+	return mapAll(list, FromPointAward)
+}
+
+// PointCategory is a reason behavior points are given for. Points is the
+// default value of an award, negative for deductions.
+type PointCategory struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Points    int       `json:"points"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func FromPointCategory(p *store.PointCategory) *PointCategory {
+	return &PointCategory{
+		ID:        p.ID,
+		Name:      p.Name,
+		Points:    p.Points,
+		Active:    p.Active,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+func FromPointCategories(list []*store.PointCategory) []*PointCategory {
+	return mapAll(list, FromPointCategory)
+}
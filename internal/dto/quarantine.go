@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// QuarantinedUpload is a file the upload checks refused: one that failed
+// the malware scan or looked unsafe. Its content is kept, but never
+// served.
+type QuarantinedUpload struct {
+	ID          int64  `json:"id"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Reason      string `json:"reason"`
+	// Signature is what the scanner found, when it found something.
+	Signature    string    `json:"signature,omitempty"`
+	UploaderRole string    `json:"uploader_role"`
+	UploaderID   int64     `json:"uploader_id"`
+	IP           string    `json:"ip"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func FromQuarantinedUpload(q *store.QuarantinedUpload) *QuarantinedUpload {
+	return &QuarantinedUpload{
+		ID:           q.ID,
+		Kind:         q.Kind,
+		ContentType:  q.ContentType,
+		Size:         q.Size,
+		Reason:       q.Reason,
+		Signature:    q.Signature,
+		UploaderRole: q.UploaderRole,
+		UploaderID:   q.UploaderID,
+		IP:           q.IP,
+		CreatedAt:    q.CreatedAt,
+	}
+}
+
+func FromQuarantinedUploads(list []*store.QuarantinedUpload) []*QuarantinedUpload {
+	return mapAll(list, FromQuarantinedUpload)
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// ReminderCompliance summarizes how often a teacher needed an attendance
+// reminder in a period.
+type ReminderCompliance struct {
+	TeacherID   int64  `json:"teacher_id"`
+	TeacherName string `json:"teacher_name"`
+	Reminders   int64  `json:"reminders"`
+	MarkedLate  int64  `json:"marked_late"`
+	NeverMarked int64  `json:"never_marked"`
+	Chronic     bool   `json:"chronic"`
+}
+
+func FromReminderCompliance(r *store.ReminderCompliance) *ReminderCompliance {
+	return &ReminderCompliance{
+		TeacherID:   r.TeacherID,
+		TeacherName: r.TeacherName,
+		Reminders:   r.Reminders,
+		MarkedLate:  r.MarkedLate,
+		NeverMarked: r.NeverMarked,
+		Chronic:     r.Chronic,
+	}
+}
+
+func FromReminderCompliances(list []*store.ReminderCompliance) []*ReminderCompliance {
+	return mapAll(list, FromReminderCompliance)
+}
@@ -0,0 +1,79 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// ReportRun is one execution of a schedule. The generated file is kept so
+// download links stay valid.
+type ReportRun struct {
+	ID          int64      `json:"id"`
+	ScheduleID  int64      `json:"schedule_id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	Filename    *string    `json:"filename,omitempty"`
+	ContentType *string    `json:"content_type,omitempty"`
+	Size        int64      `json:"size"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+func FromReportRun(r *store.ReportRun) *ReportRun {
+	return &ReportRun{
+		ID:          r.ID,
+		ScheduleID:  r.ScheduleID,
+		Status:      r.Status,
+		Error:       r.Error,
+		Filename:    r.Filename,
+		ContentType: r.ContentType,
+		Size:        r.Size,
+		StartedAt:   r.StartedAt,
+		FinishedAt:  r.FinishedAt,
+	}
+}
+
+func FromReportRuns(list []*store.ReportRun) []*ReportRun {
+	return mapAll(list, FromReportRun)
+}
+
+// ReportSchedule is a report generated on a cron schedule and emailed to its
+// recipients, either attached or as a signed download link.
+type ReportSchedule struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Report     string            `json:"report"`
+	Params     map[string]string `json:"params"`
+	Cron       string            `json:"cron"`
+	Format     string            `json:"format"`
+	Delivery   string            `json:"delivery"`
+	Recipients []string          `json:"recipients"`
+	Enabled    bool              `json:"enabled"`
+	NextRunAt  time.Time         `json:"next_run_at"`
+	CreatedBy  *int64            `json:"created_by,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+func FromReportSchedule(r *store.ReportSchedule) *ReportSchedule {
+	return &ReportSchedule{
+		ID:         r.ID,
+		Name:       r.Name,
+		Report:     r.Report,
+		Params:     r.Params,
+		Cron:       r.Cron,
+		Format:     r.Format,
+		Delivery:   r.Delivery,
+		Recipients: r.Recipients,
+		Enabled:    r.Enabled,
+		NextRunAt:  r.NextRunAt,
+		CreatedBy:  r.CreatedBy,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+func FromReportSchedules(list []*store.ReportSchedule) []*ReportSchedule {
+	return mapAll(list, FromReportSchedule)
+}
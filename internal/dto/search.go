@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// SearchResult is a single typeahead hit.
+type SearchResult struct {
+	ID          int64   `json:"id"`
+	Type        string  `json:"type"`
+	DisplayName string  `json:"display_name"`
+	Score       float64 `json:"score"`
+}
+
+func FromSearchResult(s *store.SearchResult) *SearchResult {
+	return &SearchResult{
+		ID:          s.ID,
+		Type:        s.Type,
+		DisplayName: s.DisplayName,
+		Score:       s.Score,
+	}
+}
+
+func FromSearchResults(list []*store.SearchResult) []*SearchResult {
+	return mapAll(list, FromSearchResult)
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// SeatingChart is a classroom's seat grid and who sits where. Rows and
+// columns count from 1, the first row being the one nearest the board.
+// Status on seats and unseated students is their attendance on the date
+// the chart was read for, nil when it hasn't been marked, so clients can
+// render the chart as a seat map to mark attendance on.
+type SeatingChart struct {
+	ClassroomID int64   `json:"classroom_id"`
+	Rows        int     `json:"rows"`
+	Columns     int     `json:"columns"`
+	Seats       []*Seat `json:"seats"`
+	// Unseated are the classroom's students without a seat.
+	Unseated  []*SeatStudent `json:"unseated"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+func FromSeatingChart(s *store.SeatingChart) *SeatingChart {
+	return &SeatingChart{
+		ClassroomID: s.ClassroomID,
+		Rows:        s.Rows,
+		Columns:     s.Columns,
+		Seats:       mapAll(s.Seats, FromSeat),
+		Unseated:    mapAll(s.Unseated, FromSeatStudent),
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+type Seat struct {
+	Row    int `json:"row"`
+	Column int `json:"column"`
+	SeatStudent
+}
+
+func FromSeat(s *store.Seat) *Seat {
+	return &Seat{
+		Row:         s.Row,
+		Column:      s.Column,
+		SeatStudent: *FromSeatStudent(&s.SeatStudent),
+	}
+}
+
+type SeatStudent struct {
+	StudentID int64   `json:"student_id"`
+	FirstName string  `json:"first_name,omitempty"`
+	LastName  string  `json:"last_name,omitempty"`
+	Status    *string `json:"status,omitempty" enums:"present,absent,late,excused"`
+}
+
+func FromSeatStudent(s *store.SeatStudent) *SeatStudent {
+	return &SeatStudent{
+		StudentID: s.StudentID,
+		FirstName: s.FirstName,
+		LastName:  s.LastName,
+		Status:    s.Status,
+	}
+}
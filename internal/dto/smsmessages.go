@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// SMSMessage is one text in a parent's thread.
+type SMSMessage struct {
+	ID           int64     `json:"id"`
+	PhoneNumber  string    `json:"phone_number"`
+	Direction    string    `json:"direction"`
+	Kind         string    `json:"kind"`
+	Body         string    `json:"body"`
+	StudentID    *int64    `json:"student_id,omitempty"`
+	AttendanceID *int64    `json:"attendance_id,omitempty"`
+	ReplyTo      *int64    `json:"reply_to,omitempty"`
+	Action       string    `json:"action,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func FromSMSMessage(s *store.SMSMessage) *SMSMessage {
+	return &SMSMessage{
+		ID:           s.ID,
+		PhoneNumber:  s.PhoneNumber,
+		Direction:    s.Direction,
+		Kind:         s.Kind,
+		Body:         s.Body,
+		StudentID:    s.StudentID,
+		AttendanceID: s.AttendanceID,
+		ReplyTo:      s.ReplyTo,
+		Action:       s.Action,
+		CreatedAt:    s.CreatedAt,
+	}
+}
+
+func FromSMSMessages(list []*store.SMSMessage) []*SMSMessage {
+	return mapAll(list, FromSMSMessage)
+}
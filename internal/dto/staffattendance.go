@@ -0,0 +1,101 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// StaffAttendance is a teacher's own arrival and departure for a day.
+type StaffAttendance struct {
+	ID                int64      `json:"id"`
+	TeacherID         int64      `json:"teacher_id"`
+	TeacherName       string     `json:"teacher_name,omitempty"`
+	Date              time.Time  `json:"date"`
+	ArrivedAt         time.Time  `json:"arrived_at"`
+	Late              bool       `json:"late"`
+	ArrivalIP         string     `json:"arrival_ip"`
+	ArrivalDeviceID   *int64     `json:"arrival_device_id"`
+	DepartedAt        *time.Time `json:"departed_at"`
+	DepartureIP       string     `json:"departure_ip"`
+	DepartureDeviceID *int64     `json:"departure_device_id"`
+}
+
+func FromStaffAttendance(s *store.StaffAttendance) *StaffAttendance {
+	return &StaffAttendance{
+		ID:                s.ID,
+		TeacherID:         s.TeacherID,
+		TeacherName:       s.TeacherName,
+		Date:              s.Date,
+		ArrivedAt:         s.ArrivedAt,
+		Late:              s.Late,
+		ArrivalIP:         s.ArrivalIP,
+		ArrivalDeviceID:   s.ArrivalDeviceID,
+		DepartedAt:        s.DepartedAt,
+		DepartureIP:       s.DepartureIP,
+		DepartureDeviceID: s.DepartureDeviceID,
+	}
+}
+
+func FromStaffAttendances(list []*store.StaffAttendance) []*StaffAttendance {
+	return mapAll(list, FromStaffAttendance)
+}
+
+// StaffAttendanceSummary totals one teacher's attendance over a period.
+// SchoolDays counts the school days of the period up to today; Absent are
+// those without an arrival. Hours only counts days with a departure.
+type StaffAttendanceSummary struct {
+	TeacherID         int64   `json:"teacher_id"`
+	TeacherName       string  `json:"teacher_name"`
+	SchoolDays        int     `json:"school_days"`
+	Present           int     `json:"present"`
+	Late              int     `json:"late"`
+	Absent            int     `json:"absent"`
+	MissingDepartures int     `json:"missing_departures"`
+	Hours             float64 `json:"hours"`
+}
+
+func FromStaffAttendanceSummary(s *store.StaffAttendanceSummary) *StaffAttendanceSummary {
+	return &StaffAttendanceSummary{
+		TeacherID:         s.TeacherID,
+		TeacherName:       s.TeacherName,
+		SchoolDays:        s.SchoolDays,
+		Present:           s.Present,
+		Late:              s.Late,
+		Absent:            s.Absent,
+		MissingDepartures: s.MissingDepartures,
+		Hours:             s.Hours,
+	}
+}
+
+func FromStaffAttendanceSummaries(list []*store.StaffAttendanceSummary) []*StaffAttendanceSummary {
+	return mapAll(list, FromStaffAttendanceSummary)
+}
+
+// StaffDevice is a device a teacher is allowed to mark their own
+// attendance from.
+type StaffDevice struct {
+	ID          int64      `json:"id"`
+	TeacherID   int64      `json:"teacher_id"`
+	TeacherName string     `json:"teacher_name,omitempty"`
+	DeviceID    string     `json:"device_id"`
+	Name        string     `json:"name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+func FromStaffDevice(s *store.StaffDevice) *StaffDevice {
+	return &StaffDevice{
+		ID:          s.ID,
+		TeacherID:   s.TeacherID,
+		TeacherName: s.TeacherName,
+		DeviceID:    s.DeviceID,
+		Name:        s.Name,
+		CreatedAt:   s.CreatedAt,
+		LastUsedAt:  s.LastUsedAt,
+	}
+}
+
+func FromStaffDevices(list []*store.StaffDevice) []*StaffDevice {
+	return mapAll(list, FromStaffDevice)
+}
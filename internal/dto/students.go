@@ -0,0 +1,123 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Student struct {
+	ID          int64   `json:"id" example:"42"`
+	FirstName   string  `json:"first_name" example:"Sara"`
+	LastName    string  `json:"last_name" example:"Ahmadi"`
+	Email       string  `json:"email" example:"sara.ahmadi@example.com"`
+	PhoneNumber *string `json:"phone_number" example:"+989121234567"`
+	ClassRoomID int64   `json:"classroom_id" example:"3"`
+	// BirthDate, Address and the parent's contact are left out for other
+	// students and their parents, and NationalID for teachers too.
+	BirthDate         *time.Time `json:"birth_date,omitempty" example:"2011-05-14T00:00:00Z"`
+	Address           *string    `json:"address,omitempty" example:"12 Azadi St, Tehran"`
+	ParentName        *string    `json:"parent_name,omitempty" example:"Reza Ahmadi"`
+	ParentPhoneNumber *string    `json:"parent_phone_number,omitempty" example:"+989351234567"`
+	TeacherID         int64      `json:"teacher_id" example:"7"`
+	NationalID        *string    `json:"national_id,omitempty" example:"0012345678"`
+	StudentCode       string     `json:"student_code" example:"S-1405-0042"`
+	CreatedAt         time.Time  `json:"created_at" example:"2026-09-01T08:00:00Z"`
+	UpdatedAt         time.Time  `json:"updated_at" example:"2026-10-16T10:30:00Z"`
+	// LastLoginAt and LastLoginIP are set on lists only.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" example:"2026-10-16T07:45:00Z"`
+	LastLoginIP *string    `json:"last_login_ip,omitempty" example:"203.0.113.7"`
+}
+
+func FromStudent(v Viewer, s *store.Student) *Student {
+	private := v.seesStudentPrivate(s.ID, s.ParentPhoneNumber)
+	return &Student{
+		ID:                s.ID,
+		FirstName:         s.FirstName,
+		LastName:          s.LastName,
+		Email:             s.Email,
+		PhoneNumber:       s.PhoneNumber,
+		ClassRoomID:       s.ClassRoomID,
+		BirthDate:         only(private, &s.BirthDate),
+		Address:           only(private, &s.Address),
+		ParentName:        only(private, &s.ParentName),
+		ParentPhoneNumber: only(private, &s.ParentPhoneNumber),
+		TeacherID:         s.TeacherID,
+		NationalID:        only(v.seesStudentNationalID(s.ID, s.ParentPhoneNumber), s.NationalID),
+		StudentCode:       s.StudentCode,
+		CreatedAt:         s.CreatedAt,
+		UpdatedAt:         s.UpdatedAt,
+		LastLoginAt:       s.LastLoginAt,
+		LastLoginIP:       s.LastLoginIP,
+	}
+}
+
+func FromStudents(v Viewer, list []*store.Student) []*Student {
+	return mapAll(list, func(s *store.Student) *Student { return FromStudent(v, s) })
+}
+
+// StudentSummary is a student as a roster lists them.
+type StudentSummary struct {
+	ID          int64   `json:"id" example:"42"`
+	FirstName   string  `json:"first_name" example:"Sara"`
+	LastName    string  `json:"last_name" example:"Ahmadi"`
+	Email       string  `json:"email" example:"sara.ahmadi@example.com"`
+	PhoneNumber *string `json:"phone_number" example:"+989121234567"`
+	ClassRoomID int64   `json:"classroom_id" example:"3"`
+	// BirthDate, Address and the parent's contact are left out as on
+	// Student.
+	BirthDate         *time.Time `json:"birth_date,omitempty" example:"2011-05-14T00:00:00Z"`
+	Address           *string    `json:"address,omitempty" example:"12 Azadi St, Tehran"`
+	ParentName        *string    `json:"parent_name,omitempty" example:"Reza Ahmadi"`
+	ParentPhoneNumber *string    `json:"parent_phone_number,omitempty" example:"+989351234567"`
+	TeacherID         int64      `json:"teacher_id" example:"7"`
+	StudentCode       string     `json:"student_code" example:"S-1405-0042"`
+	CreatedAt         time.Time  `json:"created_at" example:"2026-09-01T08:00:00Z"`
+	UpdatedAt         time.Time  `json:"updated_at" example:"2026-10-16T10:30:00Z"`
+}
+
+func FromStudentSummary(v Viewer, s *store.StudentSummary) *StudentSummary {
+	private := v.seesStudentPrivate(s.ID, s.ParentPhoneNumber)
+	return &StudentSummary{
+		ID:                s.ID,
+		FirstName:         s.FirstName,
+		LastName:          s.LastName,
+		Email:             s.Email,
+		PhoneNumber:       s.PhoneNumber,
+		ClassRoomID:       s.ClassRoomID,
+		BirthDate:         only(private, &s.BirthDate),
+		Address:           only(private, &s.Address),
+		ParentName:        only(private, &s.ParentName),
+		ParentPhoneNumber: only(private, &s.ParentPhoneNumber),
+		TeacherID:         s.TeacherID,
+		StudentCode:       s.StudentCode,
+		CreatedAt:         s.CreatedAt,
+		UpdatedAt:         s.UpdatedAt,
+	}
+}
+
+func FromStudentSummaries(v Viewer, list []*store.StudentSummary) []*StudentSummary {
+	return mapAll(list, func(s *store.StudentSummary) *StudentSummary { return FromStudentSummary(v, s) })
+}
+
+// DuplicateCandidate is an existing student that looks like the same person
+// as one being registered, with the reasons it matched.
+type DuplicateCandidate struct {
+	Student *Student `json:"student"`
+	Reasons []string `json:"reasons"`
+}
+
+func FromDuplicateCandidate(v Viewer, d *store.DuplicateCandidate) *DuplicateCandidate {
+	var student *Student
+	if d.Student != nil {
+		student = FromStudent(v, d.Student)
+	}
+	return &DuplicateCandidate{
+		Student: student,
+		Reasons: d.Reasons,
+	}
+}
+
+func FromDuplicateCandidates(v Viewer, list []*store.DuplicateCandidate) []*DuplicateCandidate {
+	return mapAll(list, func(d *store.DuplicateCandidate) *DuplicateCandidate { return FromDuplicateCandidate(v, d) })
+}
@@ -0,0 +1,119 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Survey struct {
+	ID          int64            `json:"id"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Questions   []SurveyQuestion `json:"questions"`
+	Audience    SurveyAudience   `json:"audience"`
+	Status      string           `json:"status"`
+	ClosesAt    *time.Time       `json:"closes_at"`
+	CreatedBy   int64            `json:"created_by"`
+	Responses   int64            `json:"responses"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+func FromSurvey(s *store.Survey) *Survey {
+	return &Survey{
+		ID:          s.ID,
+		Title:       s.Title,
+		Description: s.Description,
+		Questions:   mapValues(s.Questions, FromSurveyQuestion),
+		Audience:    *FromSurveyAudience(&s.Audience),
+		Status:      s.Status,
+		ClosesAt:    s.ClosesAt,
+		CreatedBy:   s.CreatedBy,
+		Responses:   s.Responses,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+}
+
+func FromSurveys(list []*store.Survey) []*Survey {
+	return mapAll(list, FromSurvey)
+}
+
+type SurveyQuestion struct {
+	ID       string   `json:"id"`
+	Type     string   `json:"type"`
+	Prompt   string   `json:"prompt"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+	Multiple bool     `json:"multiple,omitempty"`
+	// Min and Max bound scale answers (1 to 5 when unset).
+	Min int `json:"min,omitempty"`
+	Max int `json:"max,omitempty"`
+}
+
+func FromSurveyQuestion(s *store.SurveyQuestion) *SurveyQuestion {
+	return &SurveyQuestion{
+		ID:       s.ID,
+		Type:     s.Type,
+		Prompt:   s.Prompt,
+		Required: s.Required,
+		Options:  s.Options,
+		Multiple: s.Multiple,
+		Min:      s.Min,
+		Max:      s.Max,
+	}
+}
+
+// SurveyAudience narrows who may answer a survey. Nil fields don't restrict.
+type SurveyAudience struct {
+	Role        *string `json:"role,omitempty"`
+	Grade       *int64  `json:"grade,omitempty"`
+	ClassroomID *int64  `json:"classroom_id,omitempty"`
+}
+
+func FromSurveyAudience(s *store.SurveyAudience) *SurveyAudience {
+	return &SurveyAudience{
+		Role:        s.Role,
+		Grade:       s.Grade,
+		ClassroomID: s.ClassroomID,
+	}
+}
+
+type SurveyResponse struct {
+	ID             int64          `json:"id"`
+	SurveyID       int64          `json:"survey_id"`
+	RespondentID   int64          `json:"respondent_id"`
+	RespondentRole string         `json:"respondent_role"`
+	Answers        []SurveyAnswer `json:"answers"`
+	SubmittedAt    time.Time      `json:"submitted_at"`
+}
+
+func FromSurveyResponse(s *store.SurveyResponse) *SurveyResponse {
+	return &SurveyResponse{
+		ID:             s.ID,
+		SurveyID:       s.SurveyID,
+		RespondentID:   s.RespondentID,
+		RespondentRole: s.RespondentRole,
+		Answers:        mapValues(s.Answers, FromSurveyAnswer),
+		SubmittedAt:    s.SubmittedAt,
+	}
+}
+
+// SurveyAnswer answers one question: Choices for choice questions, Text for
+// text questions and Value for scale questions.
+type SurveyAnswer struct {
+	QuestionID string   `json:"question_id"`
+	Choices    []string `json:"choices,omitempty"`
+	Text       string   `json:"text,omitempty"`
+	Value      *int     `json:"value,omitempty"`
+}
+
+func FromSurveyAnswer(s *store.SurveyAnswer) *SurveyAnswer {
+	return &SurveyAnswer{
+		QuestionID: s.QuestionID,
+		Choices:    s.Choices,
+		Text:       s.Text,
+		Value:      s.Value,
+	}
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Uses      int64     `json:"uses"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func FromTag(t *store.Tag) *Tag {
+	return &Tag{
+		ID:        t.ID,
+		Name:      t.Name,
+		Uses:      t.Uses,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+func FromTags(list []*store.Tag) []*Tag {
+	return mapAll(list, FromTag)
+}
@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+type Teacher struct {
+	ID          int64             `json:"id" example:"42"`
+	FirstName   string            `json:"first_name" example:"Sara"`
+	LastName    string            `json:"last_name" example:"Ahmadi"`
+	Email       string            `json:"email" example:"sara.ahmadi@example.com"`
+	Subject     string            `json:"subject" example:"Mathematics"`
+	SubjectI18n map[string]string `json:"subject_i18n"`
+	// PhoneNumber is left out for students, and HireDate and NationalID for
+	// students, parents and other teachers.
+	PhoneNumber *string    `json:"phone_number,omitempty" example:"+989121234567"`
+	HireDate    *time.Time `json:"hire_date,omitempty" example:"2024-09-01T00:00:00Z"`
+	NationalID  *string    `json:"national_id,omitempty" example:"0012345678"`
+	StaffCode   string     `json:"staff_code" example:"T-0007"`
+	CreatedAt   time.Time  `json:"created_at" example:"2026-09-01T08:00:00Z"`
+	UpdatedAt   time.Time  `json:"updated_at" example:"2026-10-16T10:30:00Z"`
+	// LastLoginAt and LastLoginIP are set on lists only.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" example:"2026-10-16T07:45:00Z"`
+	LastLoginIP *string    `json:"last_login_ip,omitempty" example:"203.0.113.7"`
+}
+
+func FromTeacher(v Viewer, t *store.Teacher) *Teacher {
+	private := v.seesStaffPrivate(t.ID)
+	return &Teacher{
+		ID:          t.ID,
+		FirstName:   t.FirstName,
+		LastName:    t.LastName,
+		Email:       t.Email,
+		Subject:     t.Subject,
+		SubjectI18n: t.SubjectI18n,
+		PhoneNumber: only(v.seesStaffPhone(), &t.PhoneNumber),
+		HireDate:    only(private, &t.HireDate),
+		NationalID:  only(private, t.NationalID),
+		StaffCode:   t.StaffCode,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+		LastLoginAt: t.LastLoginAt,
+		LastLoginIP: t.LastLoginIP,
+	}
+}
+
+func FromTeachers(v Viewer, list []*store.Teacher) []*Teacher {
+	return mapAll(list, func(t *store.Teacher) *Teacher { return FromTeacher(v, t) })
+}
+
+// TeacherDependents are the live records that point at a teacher.
+type TeacherDependents struct {
+	Classrooms []int64 `json:"classrooms"`
+	Students   []int64 `json:"students"`
+}
+
+func FromTeacherDependents(t *store.TeacherDependents) *TeacherDependents {
+	return &TeacherDependents{
+		Classrooms: t.Classrooms,
+		Students:   t.Students,
+	}
+}
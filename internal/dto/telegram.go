@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// TelegramLink is a parent's connection to a Telegram chat.
+type TelegramLink struct {
+	ParentID int64      `json:"parent_id"`
+	Linked   bool       `json:"linked"`
+	LinkedAt *time.Time `json:"linked_at,omitempty"`
+}
+
+func FromTelegramLink(t *store.TelegramLink) *TelegramLink {
+	return &TelegramLink{
+		ParentID: t.ParentID,
+		Linked:   t.Linked,
+		LinkedAt: t.LinkedAt,
+	}
+}
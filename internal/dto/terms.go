@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// ReportCard is a student's record for a term, read from the archive once
+// the term is archived and from the live tables before.
+type ReportCard struct {
+	Term       *Term          `json:"term"`
+	StudentID  int64          `json:"student_id"`
+	Archived   bool           `json:"archived"`
+	Attendance map[string]int `json:"attendance"`
+	Days       int            `json:"days"`
+	Grades     []*Grade       `json:"grades"`
+}
+
+func FromReportCard(r *store.ReportCard) *ReportCard {
+	return &ReportCard{
+		Term:       mapOne(r.Term, FromTerm),
+		StudentID:  r.StudentID,
+		Archived:   r.Archived,
+		Attendance: r.Attendance,
+		Days:       r.Days,
+		Grades:     mapAll(r.Grades, FromGrade),
+	}
+}
+
+// Term is an academic term. Closing it ends changes to its records and
+// snapshots them for transcripts; archiving moves its attendance and grades
+// out of the hot tables.
+type Term struct {
+	ID         int64      `json:"id" example:"42"`
+	Name       string     `json:"name"`
+	StartsOn   time.Time  `json:"starts_on" example:"2026-09-23T00:00:00Z"`
+	EndsOn     time.Time  `json:"ends_on" example:"2027-01-20T00:00:00Z"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" example:"2026-09-01T08:00:00Z"`
+}
+
+func FromTerm(t *store.Term) *Term {
+	return &Term{
+		ID:         t.ID,
+		Name:       t.Name,
+		StartsOn:   t.StartsOn,
+		EndsOn:     t.EndsOn,
+		ClosedAt:   t.ClosedAt,
+		ArchivedAt: t.ArchivedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func FromTerms(list []*store.Term) []*Term {
+	return mapAll(list, FromTerm)
+}
+
+// TermArchive is what archiving a term moved.
+type TermArchive struct {
+	TermID     int64 `json:"term_id"`
+	Attendance int64 `json:"attendance"`
+	Grades     int64 `json:"grades"`
+}
+
+func FromTermArchive(t *store.TermArchive) *TermArchive {
+	return &TermArchive{
+		TermID:     t.TermID,
+		Attendance: t.Attendance,
+		Grades:     t.Grades,
+	}
+}
+
+// Transcript is a student's record over every closed term, oldest first,
+// read from the snapshots taken when each term closed. The name is the one
+// the student had when the latest of them closed.
+type Transcript struct {
+	StudentID int64             `json:"student_id"`
+	FirstName string            `json:"first_name"`
+	LastName  string            `json:"last_name"`
+	Terms     []*TranscriptTerm `json:"terms"`
+}
+
+func FromTranscript(t *store.Transcript) *Transcript {
+	return &Transcript{
+		StudentID: t.StudentID,
+		FirstName: t.FirstName,
+		LastName:  t.LastName,
+		Terms:     mapAll(t.Terms, FromTranscriptTerm),
+	}
+}
+
+type TranscriptTerm struct {
+	Term       *Term            `json:"term"`
+	Attendance map[string]int   `json:"attendance"`
+	Days       int              `json:"days"`
+	Grades     []*SnapshotGrade `json:"grades"`
+}
+
+func FromTranscriptTerm(t *store.TranscriptTerm) *TranscriptTerm {
+	return &TranscriptTerm{
+		Term:       mapOne(t.Term, FromTerm),
+		Attendance: t.Attendance,
+		Days:       t.Days,
+		Grades:     mapAll(t.Grades, FromSnapshotGrade),
+	}
+}
+
+// SnapshotGrade is a grade as it stood when its term closed.
+type SnapshotGrade struct {
+	Classroom  string   `json:"classroom"`
+	Source     string   `json:"source"`
+	Assignment string   `json:"assignment"`
+	Score      float64  `json:"score"`
+	MaxScore   *float64 `json:"max_score,omitempty"`
+}
+
+func FromSnapshotGrade(s *store.SnapshotGrade) *SnapshotGrade {
+	return &SnapshotGrade{
+		Classroom:  s.Classroom,
+		Source:     s.Source,
+		Assignment: s.Assignment,
+		Score:      s.Score,
+		MaxScore:   s.MaxScore,
+	}
+}
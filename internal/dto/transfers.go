@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// StudentTransfer moves one student to another classroom.
+type StudentTransfer struct {
+	StudentID       int64 `json:"student_id"`
+	FromClassroomID int64 `json:"from_classroom_id"`
+	ToClassroomID   int64 `json:"to_classroom_id"`
+}
+
+func FromStudentTransfer(s *store.StudentTransfer) *StudentTransfer {
+	return &StudentTransfer{
+		StudentID:       s.StudentID,
+		FromClassroomID: s.FromClassroomID,
+		ToClassroomID:   s.ToClassroomID,
+	}
+}
+
+func FromStudentTransfers(list []*store.StudentTransfer) []*StudentTransfer {
+	return mapAll(list, FromStudentTransfer)
+}
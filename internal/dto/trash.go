@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// TrashedRecord is a soft-deleted row as shown in the recycle bin.
+type TrashedRecord struct {
+	ID          int64     `json:"id"`
+	Entity      string    `json:"entity"`
+	DisplayName string    `json:"display_name"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	DeletedBy   *int64    `json:"deleted_by,omitempty"`
+}
+
+func FromTrashedRecord(t *store.TrashedRecord) *TrashedRecord {
+	return &TrashedRecord{
+		ID:          t.ID,
+		Entity:      t.Entity,
+		DisplayName: t.DisplayName,
+		DeletedAt:   t.DeletedAt,
+		DeletedBy:   t.DeletedBy,
+	}
+}
+
+func FromTrashedRecords(list []*store.TrashedRecord) []*TrashedRecord {
+	return mapAll(list, FromTrashedRecord)
+}
@@ -0,0 +1,67 @@
+package dto
+
+// Viewer is who a response is for. Mappers of records that not every role
+// may see in full take the viewer and leave out what it may not see, so a
+// field is hidden however the record is written: whole or with some
+// fields, nested in another record, or as CSV or XML. Admins and managers
+// see everything.
+type Viewer struct {
+	Role string
+	ID   int64
+	// Phone is a parent's phone number; their children are the students
+	// listing it.
+	Phone string
+}
+
+// Unrestricted sees every record in full, for responses that only hold the
+// caller's own records.
+var Unrestricted = Viewer{}
+
+// seesStudentPrivate reports whether v may see a student's address, birth
+// date and parent contact. Other students and their families may not.
+func (v Viewer) seesStudentPrivate(id int64, parentPhone string) bool {
+	switch v.Role {
+	case "student":
+		return id == v.ID
+	case "parent":
+		return parentPhone != "" && parentPhone == v.Phone
+	}
+	return true
+}
+
+// seesStudentNationalID reports whether v may see a student's national ID,
+// which teachers don't need either.
+func (v Viewer) seesStudentNationalID(id int64, parentPhone string) bool {
+	return v.Role != "teacher" && v.seesStudentPrivate(id, parentPhone)
+}
+
+// seesStaffPrivate reports whether v may see a staff member's national ID
+// and hire date: only the office and the teacher themselves may.
+func (v Viewer) seesStaffPrivate(id int64) bool {
+	switch v.Role {
+	case "teacher":
+		return id == v.ID
+	case "student", "parent":
+		return false
+	}
+	return true
+}
+
+// seesStaffPhone reports whether v may see a staff member's phone number.
+func (v Viewer) seesStaffPhone() bool {
+	return v.Role != "student"
+}
+
+// seesFees reports whether v may see what a family is billed and pays:
+// fees are between the office and the family, so teachers may not.
+func (v Viewer) seesFees() bool {
+	return v.Role != "teacher"
+}
+
+// only returns p when ok and nil otherwise, leaving an omitempty field out.
+func only[T any](ok bool, p *T) *T {
+	if !ok {
+		return nil
+	}
+	return p
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// WidgetToken lets a school website embed read-only public data: the
+// token reads only its Scopes, and only from pages on its Origins.
+type WidgetToken struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Origins   []string   `json:"origins"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func FromWidgetToken(w *store.WidgetToken) *WidgetToken {
+	return &WidgetToken{
+		ID:        w.ID,
+		Name:      w.Name,
+		Scopes:    w.Scopes,
+		Origins:   w.Origins,
+		CreatedBy: w.CreatedBy,
+		CreatedAt: w.CreatedAt,
+		RevokedAt: w.RevokedAt,
+	}
+}
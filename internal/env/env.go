@@ -7,17 +7,51 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 //go:embed .env
 var envFile []byte
 
-var envMap map[string]string
+var (
+	mu     sync.RWMutex
+	envMap map[string]string
+)
 
 func init() {
-	envMap = make(map[string]string)
+	if err := Reload(); err != nil {
+		// the embedded values still apply; say so rather than start
+		// silently misconfigured
+		os.Stderr.WriteString("env: " + err.Error() + "\n")
+	}
+}
+
+// Reload re-reads the configuration: the .env embedded at build time,
+// overridden by the file at $ENV_FILE when set. Values read earlier are
+// not affected; callers read them again to pick up changes.
+func Reload() error {
+	m := make(map[string]string)
+	parse(envFile, m)
+
+	var err error
+	if path := os.Getenv("ENV_FILE"); path != "" {
+		var data []byte
+		if data, err = os.ReadFile(path); err == nil {
+			parse(data, m)
+		}
+	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(envFile))
+	mu.Lock()
+	envMap = m
+	mu.Unlock()
+	for key, val := range m {
+		_ = os.Setenv(key, val)
+	}
+	return err
+}
+
+func parse(data []byte, m map[string]string) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -27,22 +61,26 @@ func init() {
 		if len(parts) != 2 {
 			continue
 		}
-		key := strings.TrimSpace(parts[0])
-		val := strings.TrimSpace(parts[1])
-		envMap[key] = val
-		_ = os.Setenv(key, val)
+		m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 	}
 }
 
+func lookup(key string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	val, ok := envMap[key]
+	return val, ok
+}
+
 func GetString(key, fallback string) string {
-	if val, ok := envMap[key]; ok {
+	if val, ok := lookup(key); ok {
 		return val
 	}
 	return fallback
 }
 
 func GetInt(key string, fallback int) int {
-	if val, ok := envMap[key]; ok {
+	if val, ok := lookup(key); ok {
 		if n, err := strconv.Atoi(val); err == nil {
 			return n
 		}
@@ -51,10 +89,19 @@ func GetInt(key string, fallback int) int {
 }
 
 func GetBool(key string, fallback bool) bool {
-	if val, ok := envMap[key]; ok {
+	if val, ok := lookup(key); ok {
 		if b, err := strconv.ParseBool(val); err == nil {
 			return b
 		}
 	}
 	return fallback
 }
+
+func GetFloat(key string, fallback float64) float64 {
+	if val, ok := lookup(key); ok {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,184 @@
+// Package errreport forwards server errors and panics to Sentry.
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// queueSize bounds events waiting to be sent; more are dropped.
+const queueSize = 100
+
+type Config struct {
+	// DSN is the Sentry project DSN, https://<key>@<host>/<project>.
+	DSN string
+	// SampleRate is the share of events sent, from 0 to 1.
+	SampleRate  float64
+	Environment string
+	Release     string
+}
+
+// Event is one error or panic with the request it happened in.
+type Event struct {
+	Err   error
+	Panic bool
+	// Stack is the goroutine stack for panics.
+	Stack     []byte
+	RequestID string
+	Method    string
+	// URL is sent without its query string, which can carry tokens and
+	// other secrets.
+	URL string
+	// Route is the matched route pattern, e.g. /v1/students/{studentID}.
+	Route  string
+	UserID int64
+	Email  string
+	Role   string
+}
+
+type Reporter interface {
+	Report(*Event)
+}
+
+// New returns a Sentry reporter, or one that drops every event when no DSN
+// is set.
+func New(cfg Config) (Reporter, error) {
+	if cfg.DSN == "" {
+		return disabled{}, nil
+	}
+
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil || dsn.User == nil || dsn.Host == "" {
+		return nil, fmt.Errorf("errreport: invalid DSN")
+	}
+	project := strings.Trim(dsn.Path, "/")
+	if project == "" {
+		return nil, fmt.Errorf("errreport: DSN has no project")
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("errreport: sample rate %v is not between 0 and 1", cfg.SampleRate)
+	}
+
+	s := &sentry{
+		cfg:      cfg,
+		endpoint: fmt.Sprintf("%s://%s/api/%s/store/", dsn.Scheme, dsn.Host, project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=classnama/%s, sentry_key=%s", cfg.Release, dsn.User.Username()),
+		http:     &http.Client{Timeout: 5 * time.Second},
+		queue:    make(chan *Event, queueSize),
+	}
+	go s.run()
+	return s, nil
+}
+
+type disabled struct{}
+
+func (disabled) Report(*Event) {}
+
+type sentry struct {
+	cfg      Config
+	endpoint string
+	auth     string
+	http     *http.Client
+	queue    chan *Event
+}
+
+// Report queues e without blocking the request.
+func (s *sentry) Report(e *Event) {
+	if s.cfg.SampleRate < 1 && mathrand.Float64() >= s.cfg.SampleRate {
+		return
+	}
+	select {
+	case s.queue <- e:
+	default:
+	}
+}
+
+func (s *sentry) run() {
+	for e := range s.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = s.send(ctx, e)
+		cancel()
+	}
+}
+
+func (s *sentry) send(ctx context.Context, e *Event) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	level, kind := "error", fmt.Sprintf("%T", e.Err)
+	if e.Panic {
+		level, kind = "fatal", "panic"
+	}
+
+	extra := map[string]any{}
+	if len(e.Stack) > 0 {
+		extra["stack"] = string(e.Stack)
+	}
+	tags := map[string]string{"method": e.Method}
+	if e.Route != "" {
+		tags["route"] = e.Route
+	}
+	if e.RequestID != "" {
+		tags["request_id"] = e.RequestID
+	}
+
+	payload := map[string]any{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+		"level":       level,
+		"platform":    "go",
+		"environment": s.cfg.Environment,
+		"release":     s.cfg.Release,
+		"exception": map[string]any{
+			"values": []map[string]any{{"type": kind, "value": e.Err.Error()}},
+		},
+		"request": map[string]any{"method": e.Method, "url": withoutQuery(e.URL)},
+		"tags":    tags,
+		"extra":   extra,
+	}
+	if e.UserID != 0 {
+		payload["user"] = map[string]any{
+			"id":    fmt.Sprintf("%s:%d", e.Role, e.UserID),
+			"email": e.Email,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("errreport: sentry answered %s", resp.Status)
+	}
+	return nil
+}
+
+// withoutQuery drops the query string and fragment of rawURL.
+func withoutQuery(rawURL string) string {
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+	rawURL, _, _ = strings.Cut(rawURL, "?")
+	return rawURL
+}
@@ -0,0 +1,348 @@
+// Package imaging cleans and scales uploaded photos with the standard
+// library alone: Strip drops the metadata a camera or phone embeds, and
+// Resize makes the smaller copies lists and rosters show.
+//
+// JPEG and PNG can be resized; WebP, which the standard library can't
+// decode, can only be stripped.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// Size is a scaled copy of a photo, named as clients ask for it.
+type Size struct {
+	Name string
+	// MaxEdge bounds the longer side, in pixels.
+	MaxEdge int
+}
+
+var (
+	Thumbnail = Size{Name: "thumbnail", MaxEdge: 128}
+	Medium    = Size{Name: "medium", MaxEdge: 512}
+
+	// Sizes are the copies made of every photo.
+	Sizes = []Size{Thumbnail, Medium}
+)
+
+var ErrUnsupported = errors.New("imaging: unsupported image type")
+
+// jpegQuality is used for scaled copies; at their sizes it is visually
+// lossless.
+const jpegQuality = 85
+
+// Strip returns the image without its metadata: EXIF (location, camera,
+// time taken), XMP, IPTC and comments. It works on the encoded file and
+// doesn't recompress it. A JPEG's orientation is kept, as the only EXIF
+// tag, so it still shows upright.
+func Strip(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// Resize scales the image down so its longer side is at most maxEdge, turns
+// it upright, and returns it with its content type: PNG for PNGs, to keep
+// transparency, JPEG otherwise. Smaller images are only turned upright.
+func Resize(data []byte, contentType string, maxEdge int) ([]byte, string, error) {
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, "", ErrUnsupported
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	scale := float64(maxEdge) / float64(max(b.Dx(), b.Dy()))
+	if scale < 1 {
+		rgba = shrink(rgba, max(1, int(float64(b.Dx())*scale+0.5)), max(1, int(float64(b.Dy())*scale+0.5)))
+	}
+	if contentType == "image/jpeg" {
+		rgba = orient(rgba, jpegOrientation(data))
+	}
+
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		err = png.Encode(&buf, rgba)
+	} else {
+		err = jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// shrink scales src down to w×h, averaging the source pixels each target
+// pixel covers. RGBA is premultiplied, so transparent pixels don't bleed
+// their color into the average.
+func shrink(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := range w {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					sum[0] += int(p[0])
+					sum[1] += int(p[1])
+					sum[2] += int(p[2])
+					sum[3] += int(p[3])
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			p := dst.Pix[y*dst.Stride+x*4:]
+			for i := range sum {
+				p[i] = uint8((sum[i] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// orient turns an image upright by its EXIF orientation, 1 to 8: mirrored
+// (2), upside down (3), flipped (4), or rotated a quarter turn, mirrored or
+// not (5–8).
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range h {
+		for x := range w {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], src.Pix[y*src.Stride+x*4:])
+		}
+	}
+	return dst
+}
+
+var errMalformed = errors.New("imaging: malformed image")
+
+// JPEG markers.
+const (
+	markerSOI  = 0xd8
+	markerSOS  = 0xda
+	markerAPP0 = 0xe0
+	markerAPP1 = 0xe1
+	// APP13 carries Photoshop's IPTC block.
+	markerAPP13 = 0xed
+	markerCOM   = 0xfe
+)
+
+// jpegSegments calls fn with each segment's marker and whole bytes, up to
+// the start of the scan, and returns the offset of the scan.
+func jpegSegments(data []byte, fn func(marker byte, seg []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != markerSOI {
+		return 0, errMalformed
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 0, errMalformed
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xff: // fill byte
+			i++
+			continue
+		case marker == markerSOS:
+			return i, nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7): // no length
+			fn(marker, data[i:i+2])
+			i += 2
+			continue
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			return 0, errMalformed
+		}
+		fn(marker, data[i:end])
+		i = end
+	}
+	return 0, errMalformed
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, markerSOI)
+
+	// The orientation goes where the EXIF segment would, after JFIF's APP0.
+	var exif []byte
+	if o := jpegOrientation(data); o > 1 {
+		exif = orientationAPP1(o)
+	}
+	sos, err := jpegSegments(data, func(marker byte, seg []byte) {
+		if marker != markerAPP0 && exif != nil {
+			out = append(out, exif...)
+			exif = nil
+		}
+		if marker != markerAPP1 && marker != markerAPP13 && marker != markerCOM {
+			out = append(out, seg...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(append(out, exif...), data[sos:]...), nil
+}
+
+// jpegOrientation reads the EXIF orientation tag, returning 1 (upright)
+// when there is none.
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	jpegSegments(data, func(marker byte, seg []byte) {
+		if marker != markerAPP1 || len(seg) < 4+6+8 || string(seg[4:10]) != "Exif\x00\x00" {
+			return
+		}
+		tiff := seg[10:]
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return
+		}
+
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) {
+			return
+		}
+		n := int(order.Uint16(tiff[ifd:]))
+		for i := range n {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 {
+				orientation = int(order.Uint16(tiff[entry+8:]))
+				return
+			}
+		}
+	})
+	return orientation
+}
+
+// orientationAPP1 is an EXIF segment holding just the orientation tag.
+func orientationAPP1(orientation int) []byte {
+	return []byte{
+		0xff, markerAPP1, 0, 34,
+		'E', 'x', 'i', 'f', 0, 0,
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big-endian TIFF header, IFD at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, // orientation, SHORT, 1 value
+		0, 0, 0, 0, // no next IFD
+	}
+}
+
+// pngMetadata are the chunks that carry text, EXIF or a timestamp; the
+// others describe the image.
+var pngMetadata = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if len(data) < len(signature) || string(data[:len(signature)]) != signature {
+		return nil, errMalformed
+	}
+
+	out := append(make([]byte, 0, len(data)), signature...)
+	for i := len(signature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		if !pngMetadata[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// VP8X flags saying EXIF and XMP chunks follow.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+
+	out := append(make([]byte, 0, len(data)), data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+
+		switch fourCC := string(data[i : i+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
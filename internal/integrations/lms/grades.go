@@ -0,0 +1,166 @@
+package lms
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Grade sources.
+const (
+	SourceGoogle    = "google_classroom"
+	SourceMoodle    = "moodle"
+	SourceOneRoster = "oneroster"
+	SourceCSV       = "csv"
+)
+
+// Grade is one score read from an LMS export.
+type Grade struct {
+	Email      string
+	Assignment string
+	Score      float64
+	MaxScore   *float64
+}
+
+// identityColumns are the non-assignment columns of Google Classroom and
+// Moodle grade exports, lower-cased.
+var identityColumns = map[string]bool{
+	"first name": true, "last name": true, "surname": true, "full name": true,
+	"email address": true, "email": true, "id number": true, "institution": true,
+	"department": true, "username": true, "overall grade": true, "course total": true,
+	"last downloaded from this course": true, "student id": true,
+}
+
+// ReadGrades reads a grade export. It accepts the long form
+// (email,assignment,score[,max_score]) and the wide form Google Classroom
+// and Moodle produce, with one column per assignment. In the wide form a
+// row starting with "Points" gives each assignment's maximum; other rows
+// without an email (dates, averages) are skipped, as are empty and "-"
+// cells.
+func ReadGrades(r io.Reader) ([]*Grade, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty grade file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+
+	if strings.EqualFold(header[0], "email") && len(header) >= 3 && strings.EqualFold(header[1], "assignment") {
+		return readLongGrades(cr)
+	}
+	return readWideGrades(cr, header)
+}
+
+func readLongGrades(cr *csv.Reader) ([]*Grade, error) {
+	grades := []*Grade{}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return grades, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) < 3 {
+			return nil, fmt.Errorf("line %d: expected email,assignment,score[,max_score]", line)
+		}
+		score, err := parseScore(rec[2])
+		if err != nil || score == nil {
+			return nil, fmt.Errorf("line %d: invalid score %q", line, rec[2])
+		}
+		g := &Grade{Email: strings.TrimSpace(rec[0]), Assignment: strings.TrimSpace(rec[1]), Score: *score}
+		if len(rec) > 3 {
+			if g.MaxScore, err = parseScore(rec[3]); err != nil {
+				return nil, fmt.Errorf("line %d: invalid max_score %q", line, rec[3])
+			}
+		}
+		grades = append(grades, g)
+	}
+}
+
+func readWideGrades(cr *csv.Reader, header []string) ([]*Grade, error) {
+	emailCol := -1
+	assignments := map[int]string{}
+	for i, h := range header {
+		name := strings.ToLower(h)
+		switch {
+		case name == "email" || name == "email address":
+			emailCol = i
+		case !identityColumns[name] && h != "":
+			assignments[i] = h
+		}
+	}
+	if emailCol < 0 {
+		return nil, errors.New("grade file has no email column")
+	}
+
+	maxScores := map[int]*float64{}
+	grades := []*Grade{}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return grades, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "points") {
+			for i := range assignments {
+				if i < len(rec) {
+					maxScores[i], _ = parseScore(rec[i])
+				}
+			}
+			continue
+		}
+		if emailCol >= len(rec) || !strings.Contains(rec[emailCol], "@") {
+			continue
+		}
+
+		for i, name := range assignments {
+			if i >= len(rec) {
+				continue
+			}
+			score, err := parseScore(rec[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid score %q for %s", line, rec[i], name)
+			}
+			if score == nil {
+				continue
+			}
+			grades = append(grades, &Grade{
+				Email:      strings.TrimSpace(rec[emailCol]),
+				Assignment: name,
+				Score:      *score,
+				MaxScore:   maxScores[i],
+			})
+		}
+	}
+}
+
+// parseScore returns nil for an empty or "-" cell. A trailing "%" or
+// "/max" is dropped.
+func parseScore(s string) (*float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return nil, nil
+	}
+	s = strings.TrimSuffix(s, "%")
+	s, _, _ = strings.Cut(s, "/")
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,180 @@
+// Package lms exchanges data with learning management systems: it writes
+// classroom rosters as Google Classroom CSV or a OneRoster 1.1 CSV bundle
+// and reads grade exports from Google Classroom, Moodle and plain CSV.
+package lms
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatGoogle    = "google_csv"
+	FormatOneRoster = "oneroster"
+)
+
+// Person is a member of a roster.
+type Person struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Email     string
+	// Code is the school's identifier, e.g. the student code.
+	Code string
+}
+
+// Roster is one classroom with its teacher and students.
+type Roster struct {
+	SchoolName string
+	ClassID    int64
+	ClassName  string
+	Grade      int64
+	Teacher    *Person
+	Students   []*Person
+	UpdatedAt  time.Time
+}
+
+// Sourced IDs shared by the CSV bundle and the OneRoster API, so records
+// keep their identity whichever way a tool reads them.
+const OrgSourcedID = "org-1"
+
+func StudentSourcedID(id int64) string { return "student-" + strconv.FormatInt(id, 10) }
+func TeacherSourcedID(id int64) string { return "teacher-" + strconv.FormatInt(id, 10) }
+func ClassSourcedID(id int64) string   { return "class-" + strconv.FormatInt(id, 10) }
+func CourseSourcedID(grade int64) string {
+	return "course-grade-" + strconv.FormatInt(grade, 10)
+}
+
+// UserSourcedID is StudentSourcedID or TeacherSourcedID by role.
+func UserSourcedID(role string, id int64) string {
+	return role + "-" + strconv.FormatInt(id, 10)
+}
+
+func EnrollmentSourcedID(classID int64, role string, userID int64) string {
+	return ClassSourcedID(classID) + "-" + UserSourcedID(role, userID)
+}
+
+// ParseSourcedID splits an ID such as "student-12" into its kind and
+// number.
+func ParseSourcedID(sourcedID string) (kind string, id int64, ok bool) {
+	i := strings.LastIndexByte(sourcedID, '-')
+	if i <= 0 {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(sourcedID[i+1:], 10, 64)
+	if err != nil || id < 1 {
+		return "", 0, false
+	}
+	return sourcedID[:i], id, true
+}
+
+// ParseEnrollmentSourcedID is the inverse of EnrollmentSourcedID.
+func ParseEnrollmentSourcedID(sourcedID string) (classID int64, role string, userID int64, ok bool) {
+	var class string
+	class, user, found := strings.Cut(strings.TrimPrefix(sourcedID, "class-"), "-")
+	if !found {
+		return 0, "", 0, false
+	}
+	classID, err := strconv.ParseInt(class, 10, 64)
+	if err != nil {
+		return 0, "", 0, false
+	}
+	role, userID, ok = ParseSourcedID(user)
+	if !ok || (role != "student" && role != "teacher") {
+		return 0, "", 0, false
+	}
+	return classID, role, userID, true
+}
+
+// WriteGoogleCSV writes the roster as the CSV Google Classroom's roster
+// import takes: one row per person with their role.
+func WriteGoogleCSV(w io.Writer, r *Roster) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Class Name", "Email Address", "First Name", "Last Name", "Role"})
+	if r.Teacher != nil {
+		cw.Write([]string{r.ClassName, r.Teacher.Email, r.Teacher.FirstName, r.Teacher.LastName, "TEACHER"})
+	}
+	for _, s := range r.Students {
+		cw.Write([]string{r.ClassName, s.Email, s.FirstName, s.LastName, "STUDENT"})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type csvFile struct {
+	name string
+	rows [][]string
+}
+
+// WriteOneRoster writes the roster as a OneRoster 1.1 bulk CSV bundle
+// (a ZIP of manifest, orgs, courses, classes, users and enrollments).
+func WriteOneRoster(w io.Writer, r *Roster) error {
+	modified := r.UpdatedAt.UTC().Format(time.RFC3339)
+	grade := fmt.Sprintf("%02d", r.Grade)
+
+	files := []csvFile{
+		{"manifest.csv", [][]string{
+			{"propertyName", "value"},
+			{"manifest.version", "1.0"},
+			{"oneroster.version", "1.1"},
+			{"file.academicSessions", "absent"},
+			{"file.categories", "absent"},
+			{"file.classes", "bulk"},
+			{"file.classResources", "absent"},
+			{"file.courses", "bulk"},
+			{"file.courseResources", "absent"},
+			{"file.demographics", "absent"},
+			{"file.enrollments", "bulk"},
+			{"file.lineItems", "absent"},
+			{"file.orgs", "bulk"},
+			{"file.resources", "absent"},
+			{"file.results", "absent"},
+			{"file.users", "bulk"},
+			{"source.systemName", "ClassNama"},
+		}},
+		{"orgs.csv", [][]string{
+			{"sourcedId", "status", "dateLastModified", "name", "type", "identifier", "parentSourcedId"},
+			{OrgSourcedID, "", "", r.SchoolName, "school", "", ""},
+		}},
+		{"courses.csv", [][]string{
+			{"sourcedId", "status", "dateLastModified", "schoolYearSourcedId", "title", "courseCode", "grades", "orgSourcedId", "subjects", "subjectCodes"},
+			{CourseSourcedID(r.Grade), "", "", "", "Grade " + strconv.FormatInt(r.Grade, 10), "", grade, OrgSourcedID, "", ""},
+		}},
+		{"classes.csv", [][]string{
+			{"sourcedId", "status", "dateLastModified", "title", "grades", "courseSourcedId", "classCode", "classType", "location", "schoolSourcedId", "termSourcedIds", "subjects", "subjectCodes", "periods"},
+			{ClassSourcedID(r.ClassID), "", modified, r.ClassName, grade, CourseSourcedID(r.Grade), "", "homeroom", "", OrgSourcedID, "", "", "", ""},
+		}},
+	}
+
+	users := [][]string{{"sourcedId", "status", "dateLastModified", "enabledUser", "orgSourcedIds", "role", "username", "userIds", "givenName", "familyName", "middleName", "identifier", "email", "sms", "phone", "agentSourcedIds", "grades", "password"}}
+	enrollments := [][]string{{"sourcedId", "status", "dateLastModified", "classSourcedId", "schoolSourcedId", "userSourcedId", "role", "primary", "beginDate", "endDate"}}
+	if t := r.Teacher; t != nil {
+		id := TeacherSourcedID(t.ID)
+		users = append(users, []string{id, "", "", "true", OrgSourcedID, "teacher", t.Email, "", t.FirstName, t.LastName, "", "", t.Email, "", "", "", "", ""})
+		enrollments = append(enrollments, []string{EnrollmentSourcedID(r.ClassID, "teacher", t.ID), "", "", ClassSourcedID(r.ClassID), OrgSourcedID, id, "teacher", "true", "", ""})
+	}
+	for _, s := range r.Students {
+		id := StudentSourcedID(s.ID)
+		users = append(users, []string{id, "", "", "true", OrgSourcedID, "student", s.Email, "", s.FirstName, s.LastName, "", s.Code, s.Email, "", "", "", grade, ""})
+		enrollments = append(enrollments, []string{EnrollmentSourcedID(r.ClassID, "student", s.ID), "", "", ClassSourcedID(r.ClassID), OrgSourcedID, id, "student", "false", "", ""})
+	}
+	files = append(files, csvFile{"users.csv", users}, csvFile{"enrollments.csv", enrollments})
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := csv.NewWriter(fw).WriteAll(f.rows); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,70 @@
+package lms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxGradeFile caps what Fetch reads.
+const maxGradeFile = 10 << 20
+
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// Push uploads a roster file to url with PUT. token, when set, is sent as
+// a bearer token.
+func Push(ctx context.Context, url, token, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("lms: roster upload failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// Fetch downloads a grade export from url. token, when set, is sent as a
+// bearer token.
+func Fetch(ctx context.Context, url, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lms: grade download failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxGradeFile+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxGradeFile {
+		return nil, fmt.Errorf("lms: grade file is larger than %d bytes", maxGradeFile)
+	}
+	return data, nil
+}
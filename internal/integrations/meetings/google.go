@@ -0,0 +1,150 @@
+package meetings
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleCalendar = "https://www.googleapis.com/calendar/v3/calendars/"
+)
+
+// google creates Meet links as the conference of a calendar event; deleting
+// the event revokes the link.
+type google struct {
+	cfg  GoogleConfig
+	http *http.Client
+
+	mu    sync.Mutex
+	token cachedToken
+}
+
+func (g *google) Name() string { return ProviderGoogle }
+
+func (g *google) Create(ctx context.Context, m Meeting) (*Created, error) {
+	requestID := make([]byte, 16)
+	if _, err := rand.Read(requestID); err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"summary": m.Topic,
+		"start":   map[string]string{"dateTime": m.Start.UTC().Format(time.RFC3339)},
+		"end":     map[string]string{"dateTime": m.Start.Add(m.Duration).UTC().Format(time.RFC3339)},
+		"conferenceData": map[string]any{
+			"createRequest": map[string]any{
+				"requestId":             hex.EncodeToString(requestID),
+				"conferenceSolutionKey": map[string]string{"type": "hangoutsMeet"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.do(ctx, http.MethodPost, "/events?conferenceDataVersion=1", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, "google meet creation"); err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		ID          string `json:"id"`
+		HangoutLink string `json:"hangoutLink"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
+		return nil, err
+	}
+	if event.HangoutLink == "" {
+		// the event exists but Meet did not attach; don't leave it behind
+		g.Delete(ctx, event.ID)
+		return nil, errors.New("meetings: google calendar returned no meet link")
+	}
+	return &Created{ID: event.ID, JoinURL: event.HangoutLink}, nil
+}
+
+func (g *google) Delete(ctx context.Context, id string) error {
+	resp, err := g.do(ctx, http.MethodDelete, "/events/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil
+	}
+	return checkStatus(resp, "google meet deletion")
+}
+
+func (g *google) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := g.cfg.CalendarID
+	if calendar == "" {
+		calendar = "primary"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, googleCalendar+url.PathEscape(calendar)+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return g.http.Do(req)
+}
+
+// accessToken returns a cached token or exchanges the refresh token for a
+// new one.
+func (g *google) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token.valid() {
+		return g.token.value, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {g.cfg.ClientID},
+		"client_secret": {g.cfg.ClientSecret},
+		"refresh_token": {g.cfg.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTokenURL, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkStatus(resp, "google token request"); err != nil {
+		return "", err
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	g.token = cachedToken{value: tok.AccessToken, expires: time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)}
+	return tok.AccessToken, nil
+}
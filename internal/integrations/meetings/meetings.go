@@ -0,0 +1,120 @@
+// Package meetings provisions video meetings for online classes on Zoom or
+// Google Meet.
+package meetings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Providers.
+const (
+	ProviderZoom   = "zoom"
+	ProviderGoogle = "google_meet"
+)
+
+var ErrNotConfigured = errors.New("meetings: no provider configured")
+
+type Config struct {
+	// Provider is ProviderZoom, ProviderGoogle or empty for none.
+	Provider string
+	Zoom     ZoomConfig
+	Google   GoogleConfig
+}
+
+// ZoomConfig holds the credentials of a Zoom server-to-server OAuth app
+// with the meeting:write scope.
+type ZoomConfig struct {
+	AccountID    string
+	ClientID     string
+	ClientSecret string
+	// UserID is the host of the meetings; empty means the app's owner.
+	UserID string
+}
+
+// GoogleConfig holds an OAuth client and a refresh token for the calendar
+// whose events carry the Meet links.
+type GoogleConfig struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	// CalendarID defaults to the account's primary calendar.
+	CalendarID string
+}
+
+// Meeting describes a meeting to create.
+type Meeting struct {
+	Topic    string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Created is a provisioned meeting. ID is what Delete takes.
+type Created struct {
+	ID      string
+	JoinURL string
+}
+
+type Provider interface {
+	// Name returns the provider constant, or "" when none is configured.
+	Name() string
+	Create(ctx context.Context, m Meeting) (*Created, error)
+	// Delete revokes a meeting. Deleting one that is already gone is not an
+	// error.
+	Delete(ctx context.Context, id string) error
+}
+
+// New returns the configured provider, or one whose calls fail with
+// ErrNotConfigured when Provider is empty.
+func New(cfg Config) (Provider, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	switch cfg.Provider {
+	case "":
+		return disabled{}, nil
+	case ProviderZoom:
+		if cfg.Zoom.AccountID == "" || cfg.Zoom.ClientID == "" || cfg.Zoom.ClientSecret == "" {
+			return nil, errors.New("meetings: zoom needs an account ID, client ID and client secret")
+		}
+		return &zoom{cfg: cfg.Zoom, http: client}, nil
+	case ProviderGoogle:
+		if cfg.Google.ClientID == "" || cfg.Google.ClientSecret == "" || cfg.Google.RefreshToken == "" {
+			return nil, errors.New("meetings: google meet needs a client ID, client secret and refresh token")
+		}
+		return &google{cfg: cfg.Google, http: client}, nil
+	default:
+		return nil, fmt.Errorf("meetings: unknown provider %q", cfg.Provider)
+	}
+}
+
+type disabled struct{}
+
+func (disabled) Name() string { return "" }
+
+func (disabled) Create(context.Context, Meeting) (*Created, error) { return nil, ErrNotConfigured }
+
+func (disabled) Delete(context.Context, string) error { return ErrNotConfigured }
+
+// checkStatus turns a non-2xx response into an error carrying the start of
+// the body.
+func checkStatus(resp *http.Response, action string) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return fmt.Errorf("meetings: %s failed: %s: %s", action, resp.Status, body)
+}
+
+// cachedToken is an OAuth access token reused until shortly before it
+// expires.
+type cachedToken struct {
+	value   string
+	expires time.Time
+}
+
+func (t *cachedToken) valid() bool {
+	return t.value != "" && time.Until(t.expires) > time.Minute
+}
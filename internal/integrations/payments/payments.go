@@ -0,0 +1,104 @@
+// Package payments takes fee payments through an online payment gateway.
+// A payment starts with Create, which returns the gateway page the payer is
+// sent to; the gateway then redirects back to the callback URL, where
+// Callback reads the outcome and Verify settles it.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Gateways.
+const (
+	GatewayZarinpal = "zarinpal"
+)
+
+var (
+	ErrNotConfigured = errors.New("payments: no gateway configured")
+	// ErrDeclined is returned by Verify when the payment was not made or
+	// was cancelled.
+	ErrDeclined = errors.New("payments: payment declined")
+)
+
+type Config struct {
+	// Gateway is GatewayZarinpal or empty for none.
+	Gateway  string
+	Zarinpal ZarinpalConfig
+}
+
+type ZarinpalConfig struct {
+	MerchantID string
+	// Sandbox sends requests to Zarinpal's test environment.
+	Sandbox bool
+}
+
+// Request describes a payment to start. Amount is in Currency, which
+// gateways may restrict.
+type Request struct {
+	Amount      int64
+	Currency    string
+	Description string
+	CallbackURL string
+	Mobile      string
+	OrderID     string
+}
+
+// Session is a started payment. Authority identifies it in later calls;
+// the payer is sent to PayURL.
+type Session struct {
+	Authority string
+	PayURL    string
+}
+
+// Verification is a settled payment.
+type Verification struct {
+	RefID   string
+	CardPAN string
+}
+
+type Gateway interface {
+	// Name returns the gateway constant, or "" when none is configured.
+	Name() string
+	Create(ctx context.Context, req Request) (*Session, error)
+	// Callback reads the authority from the query of the gateway's
+	// redirect, and whether the payer reports success. Only Verify proves
+	// the payment.
+	Callback(q url.Values) (authority string, ok bool)
+	// Verify settles a payment of amount. Verifying one that was already
+	// settled succeeds again.
+	Verify(ctx context.Context, authority string, amount int64) (*Verification, error)
+}
+
+// New returns the configured gateway, or one whose calls fail with
+// ErrNotConfigured when Gateway is empty.
+func New(cfg Config) (Gateway, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	switch cfg.Gateway {
+	case "":
+		return disabled{}, nil
+	case GatewayZarinpal:
+		if cfg.Zarinpal.MerchantID == "" {
+			return nil, errors.New("payments: zarinpal needs a merchant ID")
+		}
+		return newZarinpal(cfg.Zarinpal, client), nil
+	default:
+		return nil, fmt.Errorf("payments: unknown gateway %q", cfg.Gateway)
+	}
+}
+
+type disabled struct{}
+
+func (disabled) Name() string { return "" }
+
+func (disabled) Create(context.Context, Request) (*Session, error) { return nil, ErrNotConfigured }
+
+func (disabled) Callback(url.Values) (string, bool) { return "", false }
+
+func (disabled) Verify(context.Context, string, int64) (*Verification, error) {
+	return nil, ErrNotConfigured
+}
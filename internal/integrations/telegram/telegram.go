@@ -0,0 +1,127 @@
+// Package telegram talks to parents through a Telegram bot: it sends
+// messages with the Bot API and decodes the updates Telegram posts to the
+// webhook.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("telegram: not configured")
+
+type Config struct {
+	Token string
+	// Username is the bot's @name without the "@", used for deep links.
+	Username string
+	// WebhookSecret is the secret_token registered with setWebhook;
+	// Telegram echoes it in the X-Telegram-Bot-Api-Secret-Token header.
+	WebhookSecret string
+	// BaseURL overrides the Bot API endpoint; empty means api.telegram.org.
+	BaseURL string
+}
+
+// SecretHeader carries the webhook secret on every update.
+const SecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+type Client interface {
+	Send(ctx context.Context, chatID int64, text string) error
+}
+
+// New returns a Bot API client, or a client whose Send fails with
+// ErrNotConfigured when no token is set.
+func New(cfg Config) Client {
+	if cfg.Token == "" {
+		return disabled{}
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.telegram.org"
+	}
+	return &bot{cfg: cfg, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// DeepLink returns the t.me link that opens the bot and sends
+// "/start <payload>".
+func DeepLink(username, payload string) string {
+	if username == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/%s?start=%s", username, payload)
+}
+
+type disabled struct{}
+
+func (disabled) Send(context.Context, int64, string) error { return ErrNotConfigured }
+
+type bot struct {
+	cfg  Config
+	http *http.Client
+}
+
+func (c *bot) Send(ctx context.Context, chatID int64, text string) error {
+	body, err := json.Marshal(map[string]any{"chat_id": chatID, "text": text})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimRight(c.cfg.BaseURL, "/"), c.cfg.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(data, &result); err != nil || !result.OK {
+		return fmt.Errorf("telegram: send failed: %s: %s", resp.Status, result.Description)
+	}
+	return nil
+}
+
+// Update is the part of a Bot API update the webhook uses. Updates other
+// than messages have a nil Message.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// Command splits a "/command@bot argument" message into the lower-cased
+// command without its slash and the trimmed argument. ok is false for
+// plain text.
+func (m *Message) Command() (command, arg string, ok bool) {
+	text := strings.TrimSpace(m.Text)
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+	command, arg, _ = strings.Cut(text[1:], " ")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(arg), true
+}
@@ -0,0 +1,282 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrQueueClosed = errors.New("job queue is shutting down")
+)
+
+// Job.Error only ever says how a job failed in general terms; the error
+// itself can carry SQL or internal details and goes to onError instead.
+const (
+	jobFailedMessage   = "job failed"
+	jobTimedOutMessage = "job timed out"
+)
+
+// Func does the work of a job and returns its result payload.
+type Func func(ctx context.Context) ([]byte, error)
+
+// Job is a unit of background work tracked by the Queue.
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	OwnerID    int64      `json:"-"`
+	OwnerRole  string     `json:"-"`
+	Status     Status     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Progress   *Progress  `json:"progress,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	result []byte
+	fn     Func
+}
+
+// Progress is how much of its work a job has done, when it reports it.
+type Progress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+type progressKey struct{}
+
+// SetProgress records how much of its work the job running with ctx has
+// done. It does nothing outside a job.
+func SetProgress(ctx context.Context, done, total int64) {
+	if set, ok := ctx.Value(progressKey{}).(func(*Progress)); ok {
+		set(&Progress{Done: done, Total: total})
+	}
+}
+
+// Result returns the payload produced by a finished job.
+func (j *Job) Result() []byte {
+	return j.result
+}
+
+// Queue runs jobs on a fixed pool of in-process workers and keeps finished
+// jobs around for retention so their results can be fetched.
+type Queue struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	pending   chan *Job
+	retention time.Duration
+	timeout   time.Duration
+	closed    bool
+	onError   func(*Job, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts workers to run jobs. onError receives the error of each
+// job that fails.
+func NewQueue(workers, buffer int, timeout, retention time.Duration, onError func(*Job, error)) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:      make(map[string]*Job),
+		pending:   make(chan *Job, buffer),
+		retention: retention,
+		timeout:   timeout,
+		onError:   onError,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	go q.cleanup()
+
+	return q
+}
+
+// Enqueue schedules fn to run in the background.
+func (q *Queue) Enqueue(kind string, ownerID int64, ownerRole string, fn Func) (*Job, error) {
+	job := &Job{
+		ID:        newID(),
+		Kind:      kind,
+		OwnerID:   ownerID,
+		OwnerRole: ownerRole,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		fn:        fn,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+
+	select {
+	case q.pending <- job:
+		q.jobs[job.ID] = job
+		return job.snapshot(), nil
+	default:
+		return nil, ErrQueueFull
+	}
+}
+
+// Get returns a copy of the job with the given ID.
+func (q *Queue) Get(id string) (*Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return job.snapshot(), true
+}
+
+// Drain stops accepting jobs and waits for the queued and running ones to
+// finish. When ctx ends first, the remaining jobs are cancelled as by Stop.
+func (q *Queue) Drain(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.pending)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stop cancels running jobs and waits for the workers to exit.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case job, ok := <-q.pending:
+			if !ok {
+				return
+			}
+			q.run(job)
+		}
+	}
+}
+
+func (q *Queue) run(job *Job) {
+	q.setStatus(job, StatusRunning, nil, nil)
+
+	ctx, cancel := context.WithTimeout(q.ctx, q.timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, progressKey{}, func(p *Progress) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		job.Progress = p
+	})
+
+	result, err := job.fn(ctx)
+	if err != nil {
+		q.setStatus(job, StatusFailed, nil, err)
+		if q.onError != nil {
+			q.onError(job, err)
+		}
+		return
+	}
+	q.setStatus(job, StatusDone, result, nil)
+}
+
+func (q *Queue) setStatus(job *Job, status Status, result []byte, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job.Status = status
+	if status == StatusDone || status == StatusFailed {
+		now := time.Now()
+		job.FinishedAt = &now
+		job.result = result
+		job.fn = nil
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		job.Error = jobTimedOutMessage
+	case err != nil:
+		job.Error = jobFailedMessage
+	}
+}
+
+func (q *Queue) cleanup() {
+	ticker := time.NewTicker(q.retention)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.mu.Lock()
+			for id, job := range q.jobs {
+				if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > q.retention {
+					delete(q.jobs, id)
+				}
+			}
+			q.mu.Unlock()
+		}
+	}
+}
+
+func (j *Job) snapshot() *Job {
+	cp := *j
+	cp.fn = nil
+	return &cp
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
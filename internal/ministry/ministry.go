@@ -0,0 +1,187 @@
+// Package ministry renders the fixed-format files education authorities
+// ask schools for, such as enrollment and attendance statistics. Each
+// jurisdiction's layouts are text/template files named
+// <jurisdiction>/<report>.<format>.tmpl, format being csv or xml. The
+// generic jurisdiction is built in; a templates directory can add others
+// or replace built-in layouts.
+//
+// Besides the text/template builtins, layouts can use:
+//
+//	csv v          v as a CSV field, quoted when it needs to be
+//	xml v          v escaped for XML text or attributes
+//	date t         t as YYYY-MM-DD
+//	datefmt l t    t in the Go time layout l
+//	pad n v        v left-aligned in n characters, cut to fit
+//	zpad n v       v right-aligned in n characters, zero-filled
+//	add a b...     the sum of whole numbers
+//	percent a b    a out of b as a percentage with two decimals
+package ministry
+
+import (
+	"bytes"
+	"embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Formats a layout can be written in.
+const (
+	FormatCSV = "csv"
+	FormatXML = "xml"
+)
+
+var ErrNoLayout = errors.New("ministry: no such layout")
+
+//go:embed templates
+var builtin embed.FS
+
+// Layout names one report layout.
+type Layout struct {
+	Jurisdiction string `json:"jurisdiction"`
+	Report       string `json:"report"`
+	Format       string `json:"format"`
+}
+
+// ContentType is the media type of the files the layout produces.
+func (l Layout) ContentType() string {
+	if l.Format == FormatXML {
+		return "application/xml"
+	}
+	return "text/csv"
+}
+
+// Set holds the layouts of every known jurisdiction.
+type Set struct {
+	layouts map[Layout]*template.Template
+}
+
+// Load reads the built-in layouts and, when dir is not empty, those under
+// dir, which win over built-in ones of the same name.
+func Load(dir string) (*Set, error) {
+	s := &Set{layouts: map[Layout]*template.Template{}}
+
+	sub, err := fs.Sub(builtin, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.load(sub); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := s.load(os.DirFS(dir)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Set) load(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		jurisdiction, name := path.Split(file)
+		report, format, ok := strings.Cut(strings.TrimSuffix(name, ".tmpl"), ".")
+		if !ok || (format != FormatCSV && format != FormatXML) {
+			return fmt.Errorf("ministry: %s: name layouts <report>.csv.tmpl or <report>.xml.tmpl", file)
+		}
+
+		text, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(file).Funcs(funcs).Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			return fmt.Errorf("ministry: %w", err)
+		}
+		s.layouts[Layout{Jurisdiction: strings.TrimSuffix(jurisdiction, "/"), Report: report, Format: format}] = tmpl
+	}
+	return nil
+}
+
+// Layouts lists the layouts by jurisdiction, report and format.
+func (s *Set) Layouts() []Layout {
+	list := make([]Layout, 0, len(s.layouts))
+	for l := range s.layouts {
+		list = append(list, l)
+	}
+	slices.SortFunc(list, func(a, b Layout) int {
+		return strings.Compare(a.Jurisdiction+"/"+a.Report+"."+a.Format, b.Jurisdiction+"/"+b.Report+"."+b.Format)
+	})
+	return list
+}
+
+// Has reports whether the layout exists.
+func (s *Set) Has(l Layout) bool {
+	_, ok := s.layouts[l]
+	return ok
+}
+
+// Render writes data in the layout.
+func (s *Set) Render(l Layout, data any) ([]byte, error) {
+	tmpl, ok := s.layouts[l]
+	if !ok {
+		return nil, ErrNoLayout
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("ministry: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var funcs = template.FuncMap{
+	"csv": func(v any) string {
+		s := fmt.Sprint(v)
+		if strings.ContainsAny(s, ",\"\r\n") {
+			return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+		}
+		return s
+	},
+	"xml": func(v any) (string, error) {
+		var b strings.Builder
+		err := xml.EscapeText(&b, []byte(fmt.Sprint(v)))
+		return b.String(), err
+	},
+	"date": func(t time.Time) string { return t.Format(time.DateOnly) },
+	"datefmt": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"pad": func(n int, v any) string {
+		s := fmt.Sprint(v)
+		if l := utf8.RuneCountInString(s); l < n {
+			return s + strings.Repeat(" ", n-l)
+		}
+		return string([]rune(s)[:n])
+	},
+	"zpad": func(n int, v any) string {
+		s := fmt.Sprint(v)
+		if l := utf8.RuneCountInString(s); l < n {
+			return strings.Repeat("0", n-l) + s
+		}
+		return s
+	},
+	"add": func(n ...int64) int64 {
+		var sum int64
+		for _, v := range n {
+			sum += v
+		}
+		return sum
+	},
+	"percent": func(a, b int64) string {
+		if b == 0 {
+			return "0.00"
+		}
+		return fmt.Sprintf("%.2f", float64(a)*100/float64(b))
+	},
+}
@@ -0,0 +1,105 @@
+// Package password holds the rules new passwords must follow.
+package password
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed common.txt
+var commonList string
+
+// common holds the banned passwords, lowercased.
+var common = func() map[string]struct{} {
+	m := map[string]struct{}{}
+	for _, line := range strings.Split(commonList, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			m[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return m
+}()
+
+// Policy is what a school asks of new passwords.
+type Policy struct {
+	MinLength     int  `json:"min_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	// BanCommon rejects the passwords in common.txt, whatever their case.
+	BanCommon bool `json:"ban_common"`
+	// History is how many of an account's latest passwords may not be
+	// used again; 0 allows reuse.
+	History int `json:"history"`
+}
+
+// Error lists the rules a password breaks.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "password " + strings.Join(e.Problems, ", ")
+}
+
+// Check returns an *Error when text breaks the policy. Reuse is left to the
+// caller, which knows the account's history.
+func (p Policy) Check(text string) error {
+	var problems []string
+	if len([]rune(text)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, c := range text {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			symbol = true
+		}
+	}
+	for _, rule := range []struct {
+		required, met bool
+		what          string
+	}{
+		{p.RequireUpper, upper, "an uppercase letter"},
+		{p.RequireLower, lower, "a lowercase letter"},
+		{p.RequireDigit, digit, "a digit"},
+		{p.RequireSymbol, symbol, "a symbol"},
+	} {
+		if rule.required && !rule.met {
+			problems = append(problems, "must contain "+rule.what)
+		}
+	}
+
+	if p.BanCommon {
+		if _, ok := common[strings.ToLower(text)]; ok {
+			problems = append(problems, "is too common")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// Validate reports a policy the API cannot enforce: passwords are bcrypt
+// hashed, which reads at most 72 bytes, and logins ask for at least 8.
+func (p Policy) Validate() error {
+	if p.MinLength < 8 || p.MinLength > 72 {
+		return fmt.Errorf("password: minimum length must be between 8 and 72, got %d", p.MinLength)
+	}
+	if p.History < 0 || p.History > 24 {
+		return fmt.Errorf("password: history must be between 0 and 24, got %d", p.History)
+	}
+	return nil
+}
@@ -4,6 +4,11 @@ import "time"
 
 type Limiter interface {
 	Allow(ip string) (bool, time.Duration)
+	// SetLimit changes the requests allowed per time frame.
+	SetLimit(requestsPerTimeFrame int)
+	// Stats returns the limiter's counters with the n clients it rejected
+	// most.
+	Stats(n int) Stats
 }
 
 type Config struct {
@@ -0,0 +1,98 @@
+package ratelimiter
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// statsTTL is how long a client is remembered after its last rejection.
+const statsTTL = time.Hour
+
+// KeyStats is how often one client was turned away.
+type KeyStats struct {
+	Key             string    `json:"key"`
+	Rejected        int64     `json:"rejected"`
+	FirstRejectedAt time.Time `json:"first_rejected_at"`
+	LastRejectedAt  time.Time `json:"last_rejected_at"`
+}
+
+// Stats summarises a limiter's decisions. Allowed and Rejected count every
+// request since start; Keys and Top cover the clients rejected in the last
+// hour.
+type Stats struct {
+	Allowed  int64       `json:"allowed"`
+	Rejected int64       `json:"rejected"`
+	Keys     int         `json:"keys"`
+	Top      []*KeyStats `json:"top"`
+}
+
+type keyCounter struct {
+	sync.Mutex
+	KeyStats
+}
+
+// counters keeps the totals and the per-key rejections of a limiter.
+type counters struct {
+	allowed  atomic.Int64
+	rejected atomic.Int64
+	keys     sync.Map // map[key]*keyCounter
+}
+
+func (c *counters) count(key string, allowed bool, now time.Time) {
+	if allowed {
+		c.allowed.Add(1)
+		return
+	}
+	c.rejected.Add(1)
+
+	val, ok := c.keys.Load(key)
+	if !ok {
+		val, _ = c.keys.LoadOrStore(key, &keyCounter{KeyStats: KeyStats{Key: key, FirstRejectedAt: now}})
+	}
+	kc := val.(*keyCounter)
+	kc.Lock()
+	kc.Rejected++
+	kc.LastRejectedAt = now
+	kc.Unlock()
+}
+
+// stats returns the totals and the n keys rejected most, most first.
+func (c *counters) stats(n int) Stats {
+	s := Stats{Allowed: c.allowed.Load(), Rejected: c.rejected.Load(), Top: []*KeyStats{}}
+	c.keys.Range(func(_, value any) bool {
+		kc := value.(*keyCounter)
+		kc.Lock()
+		ks := kc.KeyStats
+		kc.Unlock()
+		s.Keys++
+		s.Top = append(s.Top, &ks)
+		return true
+	})
+	slices.SortFunc(s.Top, func(a, b *KeyStats) int {
+		if c := cmp.Compare(b.Rejected, a.Rejected); c != 0 {
+			return c
+		}
+		return b.LastRejectedAt.Compare(a.LastRejectedAt)
+	})
+	if len(s.Top) > n {
+		s.Top = s.Top[:n]
+	}
+	return s
+}
+
+// prune forgets the keys not rejected since statsTTL before now.
+func (c *counters) prune(now time.Time) {
+	c.keys.Range(func(key, value any) bool {
+		kc := value.(*keyCounter)
+		kc.Lock()
+		expired := now.Sub(kc.LastRejectedAt) > statsTTL
+		kc.Unlock()
+		if expired {
+			c.keys.Delete(key)
+		}
+		return true
+	})
+}
@@ -2,6 +2,7 @@ package ratelimiter
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,19 +12,31 @@ type tokenBucket struct {
 	lastRefill time.Time
 }
 
+type bucketLimit struct {
+	rate  float64 // tokens per second
+	burst int     // bucket capacity
+}
+
 type TokenBucketRateLimiter struct {
 	clients sync.Map // map[ip]*tokenBucket
-	rate    float64  // tokens per second
-	burst   int      // bucket capacity
+	limit   atomic.Pointer[bucketLimit]
 	window  time.Duration
+	counts  counters
 }
 
 func NewTokenBucketLimiter(reqsPerWindow int, window time.Duration) *TokenBucketRateLimiter {
-	return &TokenBucketRateLimiter{
-		rate:   float64(reqsPerWindow) / window.Seconds(),
-		burst:  reqsPerWindow,
-		window: window,
-	}
+	rl := &TokenBucketRateLimiter{window: window}
+	rl.SetLimit(reqsPerWindow)
+	return rl
+}
+
+// SetLimit changes the requests allowed per window. Clients keep their
+// tokens, capped to the new burst on their next request.
+func (rl *TokenBucketRateLimiter) SetLimit(reqsPerWindow int) {
+	rl.limit.Store(&bucketLimit{
+		rate:  float64(reqsPerWindow) / rl.window.Seconds(),
+		burst: reqsPerWindow,
+	})
 }
 
 func (rl *TokenBucketRateLimiter) getBucket(ip string) *tokenBucket {
@@ -31,33 +44,40 @@ func (rl *TokenBucketRateLimiter) getBucket(ip string) *tokenBucket {
 	if ok {
 		return val.(*tokenBucket)
 	}
-	tb := &tokenBucket{tokens: float64(rl.burst), lastRefill: time.Now()}
+	tb := &tokenBucket{tokens: float64(rl.limit.Load().burst), lastRefill: time.Now()}
 	actual, _ := rl.clients.LoadOrStore(ip, tb)
 	return actual.(*tokenBucket)
 }
 
 func (rl *TokenBucketRateLimiter) Allow(ip string) (bool, time.Duration) {
+	limit := rl.limit.Load()
 	tb := rl.getBucket(ip)
 	tb.Lock()
 	defer tb.Unlock()
 
 	now := time.Now()
 	elapsed := now.Sub(tb.lastRefill).Seconds()
-	tb.tokens += elapsed * rl.rate
-	if tb.tokens > float64(rl.burst) {
-		tb.tokens = float64(rl.burst)
+	tb.tokens += elapsed * limit.rate
+	if tb.tokens > float64(limit.burst) {
+		tb.tokens = float64(limit.burst)
 	}
 	tb.lastRefill = now
 
 	if tb.tokens >= 1 {
 		tb.tokens -= 1
+		rl.counts.count(ip, true, now)
 		return true, 0
 	}
 
-	wait := time.Duration((1 - tb.tokens) / rl.rate * float64(time.Second))
+	rl.counts.count(ip, false, now)
+	wait := time.Duration((1 - tb.tokens) / limit.rate * float64(time.Second))
 	return false, wait
 }
 
+func (rl *TokenBucketRateLimiter) Stats(n int) Stats {
+	return rl.counts.stats(n)
+}
+
 // Cleanup: scan occasionally, but not blocking Allow
 func (rl *TokenBucketRateLimiter) StartCleanup() {
 	ticker := time.NewTicker(rl.window)
@@ -73,6 +93,7 @@ func (rl *TokenBucketRateLimiter) StartCleanup() {
 				}
 				return true
 			})
+			rl.counts.prune(now)
 		}
 	}()
 }
@@ -0,0 +1,134 @@
+// Package scan checks uploaded files for malware with a ClamAV daemon,
+// streaming them over clamd's INSTREAM command.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Providers.
+const (
+	ProviderClamAV = "clamav"
+)
+
+var ErrNotConfigured = errors.New("scan: no scanner configured")
+
+type Config struct {
+	// Provider is ProviderClamAV or empty for none.
+	Provider string
+	// Addr is clamd's host:port, or the path of its unix socket.
+	Addr    string
+	Timeout time.Duration
+}
+
+// Result is a scan's verdict; Signature names what was found in a file
+// that is not Clean.
+type Result struct {
+	Clean     bool
+	Signature string
+}
+
+type Scanner interface {
+	// Name returns the provider constant, or "" when none is configured.
+	Name() string
+	Scan(ctx context.Context, data []byte) (*Result, error)
+}
+
+// New returns the configured scanner, or one whose scans fail with
+// ErrNotConfigured when Provider is empty.
+func New(cfg Config) (Scanner, error) {
+	switch cfg.Provider {
+	case "":
+		return disabled{}, nil
+	case ProviderClamAV:
+		if cfg.Addr == "" {
+			return nil, errors.New("scan: clamav needs an address")
+		}
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = 30 * time.Second
+		}
+		return &clamav{addr: cfg.Addr, timeout: cfg.Timeout}, nil
+	default:
+		return nil, fmt.Errorf("scan: unknown provider %q", cfg.Provider)
+	}
+}
+
+type disabled struct{}
+
+func (disabled) Name() string { return "" }
+
+func (disabled) Scan(context.Context, []byte) (*Result, error) { return nil, ErrNotConfigured }
+
+type clamav struct {
+	addr    string
+	timeout time.Duration
+}
+
+// chunkSize is how much of a file goes in each INSTREAM chunk.
+const chunkSize = 64 << 10
+
+func (c *clamav) Name() string { return ProviderClamAV }
+
+func (c *clamav) Scan(ctx context.Context, data []byte) (*Result, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.addr, "/") {
+		network = "unix"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	size := make([]byte, 4)
+	for len(data) > 0 {
+		chunk := data[:min(len(data), chunkSize)]
+		data = data[len(chunk):]
+		binary.BigEndian.PutUint32(size, uint32(len(chunk)))
+		if _, err := conn.Write(append(size, chunk...)); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	return parseReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseReply reads clamd's answer: "stream: OK", "stream: <signature>
+// FOUND" or "<reason> ERROR".
+func parseReply(reply string) (*Result, error) {
+	verdict, _ := strings.CutPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return &Result{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("scan: clamd: %s", reply)
+	}
+}
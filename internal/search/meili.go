@@ -0,0 +1,145 @@
+// Package search syncs people and classrooms into Meilisearch and queries it
+// for typo-tolerant full-text search.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	URL    string
+	APIKey string
+	Index  string
+}
+
+// Document is what gets indexed for every searchable record. ID is unique
+// across types ("student-12"); SyncedAt lets a full reindex drop stale
+// documents.
+type Document struct {
+	ID          string `json:"id"`
+	EntityID    int64  `json:"entity_id"`
+	Type        string `json:"type"`
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email,omitempty"`
+	SyncedAt    int64  `json:"synced_at"`
+}
+
+// Hit is a single search result.
+type Hit struct {
+	EntityID    int64   `json:"entity_id"`
+	Type        string  `json:"type"`
+	DisplayName string  `json:"display_name"`
+	Score       float64 `json:"_rankingScore"`
+}
+
+// Client talks to the Meilisearch HTTP API.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func New(cfg Config) *Client {
+	return &Client{cfg: cfg, http: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func DocumentID(typ string, id int64) string {
+	return fmt.Sprintf("%s-%d", typ, id)
+}
+
+// Configure sets the index attributes the queries rely on. Meilisearch
+// creates the index on first use.
+func (c *Client) Configure(ctx context.Context) error {
+	settings := map[string]any{
+		"searchableAttributes": []string{"display_name", "email"},
+		"filterableAttributes": []string{"type", "synced_at"},
+	}
+	return c.do(ctx, http.MethodPatch, c.indexPath("/settings"), settings, nil)
+}
+
+// Upsert adds or replaces documents.
+func (c *Client) Upsert(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	return c.do(ctx, http.MethodPost, c.indexPath("/documents?primaryKey=id"), docs, nil)
+}
+
+// Delete removes documents by ID.
+func (c *Client) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return c.do(ctx, http.MethodPost, c.indexPath("/documents/delete-batch"), ids, nil)
+}
+
+// DeleteSyncedBefore removes documents not touched by the sync that started
+// at ts, i.e. records that no longer exist.
+func (c *Client) DeleteSyncedBefore(ctx context.Context, ts int64) error {
+	body := map[string]string{"filter": fmt.Sprintf("synced_at < %d", ts)}
+	return c.do(ctx, http.MethodPost, c.indexPath("/documents/delete"), body, nil)
+}
+
+// Search returns the best hits for q restricted to types (singular names).
+func (c *Client) Search(ctx context.Context, q string, types []string, limit int) ([]Hit, error) {
+	quoted := make([]string, len(types))
+	for i, t := range types {
+		quoted[i] = fmt.Sprintf("%q", t)
+	}
+
+	req := map[string]any{
+		"q":                q,
+		"limit":            limit,
+		"filter":           fmt.Sprintf("type IN [%s]", strings.Join(quoted, ", ")),
+		"showRankingScore": true,
+	}
+
+	var resp struct {
+		Hits []Hit `json:"hits"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.indexPath("/search"), req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Hits, nil
+}
+
+func (c *Client) indexPath(suffix string) string {
+	return "/indexes/" + c.cfg.Index + suffix
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.cfg.URL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("meilisearch %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,84 @@
+// Package sms sends text messages through the Kavenegar HTTP API.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("sms: not configured")
+
+type Config struct {
+	APIKey string
+	Sender string
+	// BaseURL overrides the provider endpoint; empty means Kavenegar.
+	BaseURL string
+}
+
+type Client interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// New returns a Kavenegar client, or a client whose Send fails with
+// ErrNotConfigured when no API key is set.
+func New(cfg Config) Client {
+	if cfg.APIKey == "" {
+		return disabled{}
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.kavenegar.com"
+	}
+	return &kavenegar{cfg: cfg, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type disabled struct{}
+
+func (disabled) Send(context.Context, string, string) error { return ErrNotConfigured }
+
+type kavenegar struct {
+	cfg  Config
+	http *http.Client
+}
+
+func (c *kavenegar) Send(ctx context.Context, to, message string) error {
+	form := url.Values{
+		// Kavenegar takes international numbers without the leading "+"
+		"receptor": {strings.TrimPrefix(to, "+")},
+		"message":  {message},
+	}
+	if c.cfg.Sender != "" {
+		form.Set("sender", c.cfg.Sender)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/sms/send.json", strings.TrimRight(c.cfg.BaseURL, "/"), c.cfg.APIKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Return struct {
+			Status  int    `json:"status"`
+			Message string `json:"message"`
+		} `json:"return"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(data, &body); err != nil || resp.StatusCode != http.StatusOK || body.Return.Status != 200 {
+		return fmt.Errorf("sms: send failed: %s: %s", resp.Status, strings.TrimSpace(body.Return.Message))
+	}
+	return nil
+}
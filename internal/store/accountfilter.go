@@ -0,0 +1,99 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Account statuses people lists filter on.
+const (
+	// AccountActive accounts have signed in at least once.
+	AccountActive = "active"
+	// AccountPending accounts have never signed in.
+	AccountPending = "pending"
+	// AccountDeactivated accounts may no longer sign in.
+	AccountDeactivated = "deactivated"
+)
+
+// AccountFilter narrows the exec, teacher and student lists by when the
+// person was added and by their sign-in account. Zero fields don't filter;
+// ranges include From and exclude To.
+type AccountFilter struct {
+	Status        string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	LastLoginFrom time.Time
+	LastLoginTo   time.Time
+}
+
+// Key identifies the filter in cache keys; it is empty when nothing is
+// filtered.
+func (f AccountFilter) Key() string {
+	if f == (AccountFilter{}) {
+		return ""
+	}
+	day := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.DateOnly)
+	}
+	return strings.Join([]string{f.Status, day(f.CreatedFrom), day(f.CreatedTo), day(f.LastLoginFrom), day(f.LastLoginTo)}, ":")
+}
+
+// profileTables maps the people tables to the profile type of their
+// accounts.
+var profileTables = map[string]string{
+	"execs":    ProfileExec,
+	"teachers": ProfileTeacher,
+	"students": ProfileStudent,
+}
+
+// accountColumns computes the list columns of table read from its
+// accounts.
+func accountColumns(table string) map[string]string {
+	column := func(name string) string {
+		return fmt.Sprintf(`(SELECT a.%s FROM accounts a WHERE a.profile_type = '%s' AND a.profile_id = %s.id) AS %s`,
+			name, profileTables[table], table, name)
+	}
+	return map[string]string{
+		"last_login_at": column("last_login_at"),
+		"last_login_ip": column("last_login_ip"),
+	}
+}
+
+// conditions restricts list queries on table to rows matching the filter,
+// binding values from argPos on.
+func (f AccountFilter) conditions(table string, argPos int) ([]string, []any) {
+	where, args := []string{}, []any{}
+	bind := func(cond string, v any) {
+		where = append(where, fmt.Sprintf(cond, argPos))
+		args = append(args, v)
+		argPos++
+	}
+
+	if !f.CreatedFrom.IsZero() {
+		bind(table+".created_at >= $%d", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		bind(table+".created_at < $%d", f.CreatedTo)
+	}
+
+	account := fmt.Sprintf(`SELECT 1 FROM accounts a WHERE a.profile_type = '%s' AND a.profile_id = %s.id`, profileTables[table], table)
+	switch f.Status {
+	case AccountActive:
+		where = append(where, "EXISTS ("+account+" AND a.last_login_at IS NOT NULL AND a.deactivated_at IS NULL)")
+	case AccountPending:
+		where = append(where, "NOT EXISTS ("+account+" AND (a.last_login_at IS NOT NULL OR a.deactivated_at IS NOT NULL))")
+	case AccountDeactivated:
+		where = append(where, "EXISTS ("+account+" AND a.deactivated_at IS NOT NULL)")
+	}
+	if !f.LastLoginFrom.IsZero() {
+		bind("EXISTS ("+account+" AND a.last_login_at >= $%d)", f.LastLoginFrom)
+	}
+	if !f.LastLoginTo.IsZero() {
+		bind("EXISTS ("+account+" AND a.last_login_at < $%d)", f.LastLoginTo)
+	}
+	return where, args
+}
@@ -0,0 +1,377 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Profile types an account can point at.
+const (
+	ProfileExec    = "exec"
+	ProfileTeacher = "teacher"
+	ProfileStudent = "student"
+	ProfileParent  = "parent"
+)
+
+// Account holds the credentials of one user. The person's details live in
+// the profile table named by ProfileType.
+type Account struct {
+	ID          int64      `json:"id"`
+	Email       *string    `json:"email"`
+	PhoneNumber *string    `json:"phone_number"`
+	Password    password   `json:"-"`
+	Role        string     `json:"role"`
+	ProfileType string     `json:"profile_type"`
+	ProfileID   int64      `json:"profile_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	LastLoginIP *string    `json:"last_login_ip"`
+	// LastSeenAt is the last sign-in or token refresh.
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	// DeactivatedAt is set on accounts that may no longer sign in.
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	// MustChangePassword holds the account to changing its password
+	// before anything else.
+	MustChangePassword bool `json:"must_change_password"`
+}
+
+// AccountRef names one login account: its kind (exec, teacher or student)
+// and ID.
+type AccountRef struct {
+	Kind string `json:"account_type"`
+	ID   int64  `json:"-"`
+}
+
+// AccountStore reads the accounts table, which spans every kind of user.
+type AccountStore struct {
+	db *routedDB
+}
+
+// GetByEmail returns the account signed in with email, case-insensitively.
+func (s *AccountStore) GetByEmail(ctx context.Context, email string) (*Account, error) {
+	return s.getOne(ctx, "LOWER(email) = LOWER($1)", email)
+}
+
+func (s *AccountStore) GetByID(ctx context.Context, id int64) (*Account, error) {
+	return s.getOne(ctx, "id = $1", id)
+}
+
+// GetByProfile returns the account of a profile.
+func (s *AccountStore) GetByProfile(ctx context.Context, profileType string, profileID int64) (*Account, error) {
+	return s.getOne(ctx, "profile_type = $1 AND profile_id = $2", profileType, profileID)
+}
+
+func (s *AccountStore) getOne(ctx context.Context, where string, args ...any) (*Account, error) {
+	query := `
+		SELECT id, email, phone_number, password, role, profile_type, profile_id, created_at, last_login_at,
+			last_login_ip, last_seen_at, deactivated_at, must_change_password
+		FROM accounts
+		WHERE ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var a Account
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
+		&a.ID,
+		&a.Email,
+		&a.PhoneNumber,
+		&a.Password.hash,
+		&a.Role,
+		&a.ProfileType,
+		&a.ProfileID,
+		&a.CreatedAt,
+		&a.LastLoginAt,
+		&a.LastLoginIP,
+		&a.LastSeenAt,
+		&a.DeactivatedAt,
+		&a.MustChangePassword,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// RecordLogin stamps the account's last login time and address.
+func (s *AccountStore) RecordLogin(ctx context.Context, id int64, ip string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE accounts SET last_login_at = NOW(), last_login_ip = $2, last_seen_at = NOW() WHERE id = $1`, id, ip)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// RecordSeen stamps the account as in use without counting a login, as
+// when it refreshes its token.
+func (s *AccountStore) RecordSeen(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE accounts SET last_seen_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// EmailOwners returns the accounts using email, case-insensitively. An
+// address signs in to exactly one account, so there should be at most one.
+func (s *AccountStore) EmailOwners(ctx context.Context, email string) ([]*AccountRef, error) {
+	query := `
+		SELECT profile_type, profile_id
+		FROM accounts
+		WHERE LOWER(email) = LOWER($1)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := []*AccountRef{}
+	for rows.Next() {
+		var a AccountRef
+		if err := rows.Scan(&a.Kind, &a.ID); err != nil {
+			return nil, err
+		}
+		owners = append(owners, &a)
+	}
+	return owners, rows.Err()
+}
+
+// createAccount adds the account for a profile inserted in the same
+// transaction.
+func createAccount(ctx context.Context, tx *sql.Tx, email string, pw password, role, profileType string, profileID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO accounts (email, password, role, profile_type, profile_id, must_change_password)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, email, pw.hash, role, profileType, profileID, pw.temporary).Scan(&id)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO password_history (account_id, hash) VALUES ($1, $2)`, id, pw.hash)
+	return err
+}
+
+// PasswordUsed reports whether text is one of the account's last n
+// passwords, the current one included.
+func (s *AccountStore) PasswordUsed(ctx context.Context, id int64, text string, n int) (bool, error) {
+	if n <= 0 {
+		return false, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT hash FROM password_history
+		WHERE account_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, id, n)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var used bool
+	for rows.Next() {
+		var p password
+		if err := rows.Scan(&p.hash); err != nil {
+			return false, err
+		}
+		if !used && p.Check(text) {
+			used = true
+		}
+	}
+	return used, rows.Err()
+}
+
+// SetPassword replaces the account's password, adds it to the history and
+// drops the account's refresh tokens, signing out its other sessions. A
+// temporary password must be changed at the next sign-in. ErrNotFound when
+// there is no such account.
+func (s *AccountStore) SetPassword(ctx context.Context, id int64, text string, temporary bool) error {
+	var p password
+	if err := p.Set(text); err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE accounts SET password = $2, must_change_password = $3 WHERE id = $1`,
+		id, p.hash, temporary)
+	if err != nil {
+		return err
+	}
+	if err := expectRowsAffected(res); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO password_history (account_id, hash) VALUES ($1, $2)`, id, p.hash); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE account_id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ForcePasswordChange makes every live account with a password, of role
+// when not empty and other than except, change it at the next sign-in or
+// token refresh. It returns how many accounts it flagged.
+func (s *AccountStore) ForcePasswordChange(ctx context.Context, role string, except int64) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE accounts SET must_change_password = TRUE
+		WHERE password IS NOT NULL AND deactivated_at IS NULL AND NOT must_change_password
+			AND ($1 = '' OR role = $1) AND id <> $2
+	`, role, except)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// InactiveAccount is an account nobody has used for a while.
+type InactiveAccount struct {
+	ID          int64      `json:"id"`
+	Role        string     `json:"role"`
+	ProfileType string     `json:"profile_type"`
+	ProfileID   int64      `json:"profile_id"`
+	Name        string     `json:"name"`
+	Email       *string    `json:"email"`
+	PhoneNumber *string    `json:"phone_number"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	LastLoginIP *string    `json:"last_login_ip"`
+	LastSeenAt  *time.Time `json:"last_seen_at"`
+}
+
+// inactiveCondition matches the live accounts of role (any when empty)
+// last used, or created if never used, before $1. Token refreshes count as
+// use.
+const inactiveCondition = `
+	a.deactivated_at IS NULL
+	AND COALESCE(a.last_seen_at, a.created_at) < $1
+	AND ($2 = '' OR a.role = $2)`
+
+// Inactive lists the accounts of role (any when empty) not used since
+// since, longest idle first.
+func (s *AccountStore) Inactive(ctx context.Context, since time.Time, role string) ([]*InactiveAccount, error) {
+	query := `
+		SELECT a.id, a.role, a.profile_type, a.profile_id,
+			COALESCE(e.first_name || ' ' || e.last_name, t.first_name || ' ' || t.last_name,
+				s.first_name || ' ' || s.last_name, ''),
+			a.email, a.phone_number, a.created_at, a.last_login_at, a.last_login_ip, a.last_seen_at
+		FROM accounts a
+		LEFT JOIN execs e ON a.profile_type = 'exec' AND e.id = a.profile_id
+		LEFT JOIN teachers t ON a.profile_type = 'teacher' AND t.id = a.profile_id
+		LEFT JOIN students s ON a.profile_type = 'student' AND s.id = a.profile_id
+		WHERE ` + inactiveCondition + `
+		ORDER BY COALESCE(a.last_seen_at, a.created_at), a.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, since, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*InactiveAccount{}
+	for rows.Next() {
+		var a InactiveAccount
+		if err := rows.Scan(&a.ID, &a.Role, &a.ProfileType, &a.ProfileID, &a.Name, &a.Email, &a.PhoneNumber,
+			&a.CreatedAt, &a.LastLoginAt, &a.LastLoginIP, &a.LastSeenAt); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &a)
+	}
+	return accounts, rows.Err()
+}
+
+// Deactivate stops the accounts Inactive would list from signing in, only
+// those in ids when given, and drops their refresh tokens. It returns the
+// IDs deactivated.
+func (s *AccountStore) Deactivate(ctx context.Context, since time.Time, role string, ids []int64) ([]int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE accounts a SET deactivated_at = NOW()
+		WHERE `+inactiveCondition+`
+			AND (cardinality($3::bigint[]) = 0 OR a.id = ANY($3))
+		RETURNING a.id
+	`, since, role, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deactivated := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		deactivated = append(deactivated, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE account_id = ANY($1)`, pq.Array(deactivated)); err != nil {
+		return nil, err
+	}
+	return deactivated, tx.Commit()
+}
+
+// Reactivate lets a deactivated account sign in again. ErrNotFound when no
+// such account is deactivated.
+func (s *AccountStore) Reactivate(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE accounts SET deactivated_at = NULL WHERE id = $1 AND deactivated_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
@@ -0,0 +1,352 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidGroupBy = errors.New("invalid group_by")
+
+// AttendanceTrend is the attendance of one group over one week.
+type AttendanceTrend struct {
+	GroupKey    string    `json:"group_key"`
+	GroupLabel  string    `json:"group_label"`
+	Period      time.Time `json:"period"`
+	Total       int64     `json:"total"`
+	Absent      int64     `json:"absent"`
+	Late        int64     `json:"late"`
+	AbsenceRate float64   `json:"absence_rate"`
+	// Change is the absence rate difference to the group's previous week.
+	Change *float64 `json:"change"`
+	// RollingRate is the absence rate averaged over the last four weeks.
+	RollingRate float64 `json:"rolling_rate"`
+}
+
+// attendanceGroupings maps group_by values to their key and label
+// expressions over attendance_daily_stats a LEFT JOIN classrooms c.
+var attendanceGroupings = map[string]struct {
+	key   string
+	label string
+}{
+	"classroom": {key: "COALESCE(a.classroom_id, 0)::text", label: "COALESCE(c.name, 'Unassigned')"},
+	"grade":     {key: "COALESCE(c.grade, 0)::text", label: "COALESCE('Grade ' || c.grade, 'Unassigned')"},
+	"weekday":   {key: "EXTRACT(ISODOW FROM a.date)::int::text", label: "to_char(a.date, 'FMDay')"},
+}
+
+// statsViews are the materialized views behind the dashboards.
+var statsViews = []string{"attendance_daily_stats", "grade_averages"}
+
+// StatsRefresh is how long refreshing each materialized view took.
+type StatsRefresh struct {
+	View    string  `json:"view"`
+	Seconds float64 `json:"seconds"`
+}
+
+// GradeAverage summarises one assignment's scores in a classroom.
+// AverageRatio is the mean of score / max_score over the scores that have a
+// max_score.
+type GradeAverage struct {
+	ClassroomID   int64    `json:"classroom_id"`
+	ClassroomName string   `json:"classroom_name"`
+	Source        string   `json:"source"`
+	Assignment    string   `json:"assignment"`
+	Students      int64    `json:"students"`
+	AverageScore  float64  `json:"average_score"`
+	AverageRatio  *float64 `json:"average_ratio"`
+	MinScore      float64  `json:"min_score"`
+	MaxScore      float64  `json:"max_score"`
+}
+
+type AnalyticsStore struct {
+	db *routedDB
+}
+
+// RefreshStats recomputes the dashboard views. Reads go on against the
+// previous contents while a view refreshes.
+func (s *AnalyticsStore) RefreshStats(ctx context.Context) ([]*StatsRefresh, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	refreshed := []*StatsRefresh{}
+	for _, view := range statsViews {
+		start := time.Now()
+		if _, err := s.db.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY `+view); err != nil {
+			return refreshed, fmt.Errorf("refreshing %s: %w", view, err)
+		}
+		refreshed = append(refreshed, &StatsRefresh{View: view, Seconds: time.Since(start).Seconds()})
+	}
+	return refreshed, nil
+}
+
+// GradeAverages lists the assignment averages of a classroom, or of all
+// classrooms when classroomID is zero, as of the last stats refresh.
+func (s *AnalyticsStore) GradeAverages(ctx context.Context, classroomID int64) ([]*GradeAverage, error) {
+	query := `
+		SELECT g.classroom_id, c.name, g.source, g.assignment, g.students, g.average_score, g.average_ratio,
+			g.min_score, g.max_score
+		FROM grade_averages g
+		JOIN classrooms c ON c.id = g.classroom_id AND c.deleted_at IS NULL
+		WHERE $1 = 0 OR g.classroom_id = $1
+		ORDER BY c.name, g.classroom_id, g.source, g.assignment
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	averages := []*GradeAverage{}
+	for rows.Next() {
+		var g GradeAverage
+		if err := rows.Scan(&g.ClassroomID, &g.ClassroomName, &g.Source, &g.Assignment, &g.Students,
+			&g.AverageScore, &g.AverageRatio, &g.MinScore, &g.MaxScore); err != nil {
+			return nil, err
+		}
+		averages = append(averages, &g)
+	}
+	return averages, rows.Err()
+}
+
+// AttendanceTrends aggregates attendance between from and to (inclusive) per
+// group and week. It reads the daily statistics, so it is as fresh as
+// their last refresh.
+func (s *AnalyticsStore) AttendanceTrends(ctx context.Context, groupBy string, from, to time.Time, scope Scope) ([]*AttendanceTrend, error) {
+	g, ok := attendanceGroupings[groupBy]
+	if !ok {
+		return nil, ErrInvalidGroupBy
+	}
+	where := schoolDaySQL("a.date")
+	if c := scope.classroomCondition("a.classroom_id"); c != "" {
+		where += " AND " + c
+	}
+
+	query := fmt.Sprintf(`
+		WITH weekly AS (
+			SELECT
+				%s AS group_key,
+				%s AS group_label,
+				date_trunc('week', a.date)::date AS period,
+				SUM(a.total)::bigint AS total,
+				SUM(a.absent)::bigint AS absent,
+				SUM(a.late)::bigint AS late
+			FROM attendance_daily_stats a
+			LEFT JOIN classrooms c ON c.id = a.classroom_id
+			WHERE a.date BETWEEN $1 AND $2 AND %s
+			GROUP BY 1, 2, 3
+		), rated AS (
+			SELECT *, absent::float8 / total AS absence_rate FROM weekly
+		)
+		SELECT
+			group_key, group_label, period, total, absent, late, absence_rate,
+			absence_rate - LAG(absence_rate) OVER w AS change,
+			AVG(absence_rate) OVER (w ROWS BETWEEN 3 PRECEDING AND CURRENT ROW) AS rolling_rate
+		FROM rated
+		WINDOW w AS (PARTITION BY group_key ORDER BY period)
+		ORDER BY group_key, period`, g.key, g.label, where)
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := []*AttendanceTrend{}
+	for rows.Next() {
+		var t AttendanceTrend
+		if err := rows.Scan(
+			&t.GroupKey,
+			&t.GroupLabel,
+			&t.Period,
+			&t.Total,
+			&t.Absent,
+			&t.Late,
+			&t.AbsenceRate,
+			&t.Change,
+			&t.RollingRate,
+		); err != nil {
+			return nil, err
+		}
+		trends = append(trends, &t)
+	}
+
+	return trends, rows.Err()
+}
+
+// AttendanceCompleteness tells how many school days of a month a classroom
+// had attendance taken on.
+type AttendanceCompleteness struct {
+	ClassroomID   int64     `json:"classroom_id"`
+	ClassroomName string    `json:"classroom_name"`
+	Period        time.Time `json:"period"`
+	SchoolDays    int64     `json:"school_days"`
+	DaysTaken     int64     `json:"days_taken"`
+	Completeness  float64   `json:"completeness"`
+}
+
+// TeacherAttendanceCompleteness reports, per month between from and to
+// (inclusive), how consistently attendance was taken in the teacher's
+// classrooms.
+func (s *AnalyticsStore) TeacherAttendanceCompleteness(ctx context.Context, teacherID int64, from, to time.Time) ([]*AttendanceCompleteness, error) {
+	query := `
+		WITH days AS (
+			SELECT d::date AS day
+			FROM generate_series($2::date, $3::date, interval '1 day') d
+			WHERE ` + schoolDaySQL("d::date") + `
+		), taken AS (
+			SELECT a.classroom_id, a.date
+			FROM attendance_daily_stats a
+			JOIN classrooms c ON c.id = a.classroom_id
+			WHERE c.teacher_id = $1 AND a.date BETWEEN $2 AND $3
+		)
+		SELECT
+			c.id,
+			c.name,
+			date_trunc('month', d.day)::date AS period,
+			COUNT(*) AS school_days,
+			COUNT(t.date) AS days_taken
+		FROM classrooms c
+		CROSS JOIN days d
+		LEFT JOIN taken t ON t.classroom_id = c.id AND t.date = d.day
+		WHERE c.teacher_id = $1 AND c.deleted_at IS NULL
+		GROUP BY c.id, c.name, period
+		ORDER BY c.id, period`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, teacherID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*AttendanceCompleteness{}
+	for rows.Next() {
+		var c AttendanceCompleteness
+		if err := rows.Scan(&c.ClassroomID, &c.ClassroomName, &c.Period, &c.SchoolDays, &c.DaysTaken); err != nil {
+			return nil, err
+		}
+		if c.SchoolDays > 0 {
+			c.Completeness = float64(c.DaysTaken) / float64(c.SchoolDays)
+		}
+		stats = append(stats, &c)
+	}
+
+	return stats, rows.Err()
+}
+
+// GradeEnrollment is how many live students and classrooms a grade has.
+type GradeEnrollment struct {
+	Grade      int64 `json:"grade"`
+	Classrooms int64 `json:"classrooms"`
+	Capacity   int64 `json:"capacity"`
+	Students   int64 `json:"students"`
+}
+
+// GradeAttendance is the attendance recorded for a grade's students over
+// a period; Days counts the dates with any record.
+type GradeAttendance struct {
+	Grade    int64 `json:"grade"`
+	Students int64 `json:"students"`
+	Days     int64 `json:"days"`
+	Present  int64 `json:"present"`
+	Absent   int64 `json:"absent"`
+	Late     int64 `json:"late"`
+	Excused  int64 `json:"excused"`
+}
+
+// EnrollmentByGrade counts the live classrooms and students of each grade.
+func (s *AnalyticsStore) EnrollmentByGrade(ctx context.Context) ([]*GradeEnrollment, error) {
+	query := `
+		SELECT c.grade, COUNT(*), COALESCE(SUM(c.capacity), 0), COALESCE(SUM(n.students), 0)
+		FROM classrooms c
+		LEFT JOIN (
+			SELECT classroom_id, COUNT(*) AS students
+			FROM students
+			WHERE deleted_at IS NULL
+			GROUP BY classroom_id
+		) n ON n.classroom_id = c.id
+		WHERE c.deleted_at IS NULL
+		GROUP BY c.grade
+		ORDER BY c.grade`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*GradeEnrollment{}
+	for rows.Next() {
+		var e GradeEnrollment
+		if err := rows.Scan(&e.Grade, &e.Classrooms, &e.Capacity, &e.Students); err != nil {
+			return nil, err
+		}
+		list = append(list, &e)
+	}
+
+	return list, rows.Err()
+}
+
+// AttendanceByGrade totals attendance between from and to, inclusive, by
+// the grade of each student's current classroom, and for the whole school,
+// whose Days counts the dates any grade has records for.
+func (s *AnalyticsStore) AttendanceByGrade(ctx context.Context, from, to time.Time) ([]*GradeAttendance, *GradeAttendance, error) {
+	query := `
+		SELECT
+			c.grade,
+			COUNT(DISTINCT a.student_id),
+			COUNT(DISTINCT a.date),
+			COUNT(*) FILTER (WHERE a.status = 'present'),
+			COUNT(*) FILTER (WHERE a.status = 'absent'),
+			COUNT(*) FILTER (WHERE a.status = 'late'),
+			COUNT(*) FILTER (WHERE a.status = 'excused')
+		FROM attendance_records a
+		JOIN students s ON s.id = a.student_id
+		JOIN classrooms c ON c.id = s.classroom_id
+		WHERE a.date BETWEEN $1::date AND $2::date
+		GROUP BY ROLLUP (c.grade)
+		ORDER BY c.grade NULLS LAST`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	list := []*GradeAttendance{}
+	total := &GradeAttendance{}
+	for rows.Next() {
+		var (
+			a     GradeAttendance
+			grade sql.NullInt64
+		)
+		if err := rows.Scan(&grade, &a.Students, &a.Days, &a.Present, &a.Absent, &a.Late, &a.Excused); err != nil {
+			return nil, nil, err
+		}
+		if !grade.Valid {
+			total = &a
+			continue
+		}
+		a.Grade = grade.Int64
+		list = append(list, &a)
+	}
+
+	return list, total, rows.Err()
+}
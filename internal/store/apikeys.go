@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// APIKey lets an integration call the API as the exec owning it, within
+// daily and monthly request quotas (0 for unlimited). Only the hash of the
+// key is stored.
+type APIKey struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	ExecID       int64      `json:"exec_id"`
+	DailyQuota   int64      `json:"daily_quota"`
+	MonthlyQuota int64      `json:"monthly_quota"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+type APIKeyStore struct {
+	db *routedDB
+}
+
+const apiKeyColumns = `id, name, prefix, exec_id, daily_quota, monthly_quota, created_at, revoked_at`
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.ExecID, &k.DailyQuota, &k.MonthlyQuota, &k.CreatedAt, &k.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Create stores a key under the hash of its secret. ErrNotFound when the
+// exec does not exist.
+func (s *APIKeyStore) Create(ctx context.Context, k *APIKey, hash []byte) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, exec_id, daily_quota, monthly_quota)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, k.Name, k.Prefix, hash, k.ExecID, k.DailyQuota, k.MonthlyQuota).
+		Scan(&k.ID, &k.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// List returns every key, revoked ones included, newest first.
+func (s *APIKeyStore) List(ctx context.Context) ([]*APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *APIKeyStore) GetByID(ctx context.Context, id int64) (*APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+}
+
+// GetByHash returns the live key with hash; ErrNotFound for an unknown or
+// revoked one.
+func (s *APIKeyStore) GetByHash(ctx context.Context, hash []byte) (*APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return scanAPIKey(s.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, hash))
+}
+
+// SetQuotas changes a live key's quotas. ErrNotFound when there is no such
+// live key.
+func (s *APIKeyStore) SetQuotas(ctx context.Context, id, daily, monthly int64) (*APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return scanAPIKey(s.db.QueryRowContext(ctx, `
+		UPDATE api_keys SET daily_quota = $2, monthly_quota = $3
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns, id, daily, monthly))
+}
+
+// Revoke stops a key from working. ErrNotFound when there is no such live
+// key.
+func (s *APIKeyStore) Revoke(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
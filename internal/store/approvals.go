@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	// ApprovalFailed is an approved action that could not be carried out.
+	ApprovalFailed = "failed"
+)
+
+// Approval is a sensitive action staged until an admin other than the one
+// who asked approves it. Params is what the action runs with.
+type Approval struct {
+	ID          int64           `json:"id"`
+	Action      string          `json:"action" example:"student_delete"`
+	Summary     string          `json:"summary" example:"Delete student 42 (Sara Ahmadi) and their history"`
+	Params      json.RawMessage `json:"params"`
+	RequestedBy int64           `json:"requested_by"`
+	Status      string          `json:"status" enums:"pending,approved,rejected,failed"`
+	ReviewedBy  *int64          `json:"reviewed_by"`
+	ReviewedAt  *time.Time      `json:"reviewed_at"`
+	ReviewNote  string          `json:"review_note"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type ApprovalStore struct {
+	db *routedDB
+}
+
+func (s *ApprovalStore) Create(ctx context.Context, a *Approval) error {
+	query := `
+		INSERT INTO approvals (action, summary, params, requested_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, a.Action, a.Summary, []byte(a.Params), a.RequestedBy).
+		Scan(&a.ID, &a.Status, &a.CreatedAt)
+}
+
+// List returns the approvals with status, or all with an empty one, newest
+// first.
+func (s *ApprovalStore) List(ctx context.Context, status string) ([]*Approval, error) {
+	return s.list(ctx, "$1 = '' OR status = $1", status)
+}
+
+func (s *ApprovalStore) Get(ctx context.Context, id int64) (*Approval, error) {
+	list, err := s.list(ctx, "id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (s *ApprovalStore) list(ctx context.Context, where string, args ...any) ([]*Approval, error) {
+	query := `
+		SELECT id, action, summary, params, requested_by, status, reviewed_by, reviewed_at, review_note, error, created_at
+		FROM approvals
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*Approval{}
+	for rows.Next() {
+		var a Approval
+		if err := rows.Scan(&a.ID, &a.Action, &a.Summary, &a.Params, &a.RequestedBy, &a.Status,
+			&a.ReviewedBy, &a.ReviewedAt, &a.ReviewNote, &a.Error, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &a)
+	}
+	return list, rows.Err()
+}
+
+// Review approves or rejects a pending approval. It returns ErrNotFound
+// when the approval does not exist and ErrConflict when it was already
+// reviewed, so an action is only ever approved, and run, once.
+func (s *ApprovalStore) Review(ctx context.Context, a *Approval, status string, reviewerID int64, note string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE approvals
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), review_note = $4
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status, reviewed_by, reviewed_at, review_note
+	`, a.ID, status, reviewerID, note).Scan(&a.Status, &a.ReviewedBy, &a.ReviewedAt, &a.ReviewNote)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM approvals WHERE id = $1)`, a.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrConflict
+	}
+	return ErrNotFound
+}
+
+// Fail marks an approved action that could not be carried out.
+func (s *ApprovalStore) Fail(ctx context.Context, a *Approval, reason string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE approvals SET status = 'failed', error = $2 WHERE id = $1 RETURNING status, error`, a.ID, reason,
+	).Scan(&a.Status, &a.Error)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
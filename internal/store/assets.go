@@ -0,0 +1,342 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Asset statuses. checked_out is managed by CheckOut and CheckIn.
+const (
+	AssetAvailable   = "available"
+	AssetCheckedOut  = "checked_out"
+	AssetMaintenance = "maintenance"
+	AssetRetired     = "retired"
+)
+
+// ErrAssetUnavailable is returned when checking out an asset that is not
+// available.
+var ErrAssetUnavailable = errors.New("asset is not available")
+
+type Asset struct {
+	ID           int64      `json:"id"`
+	Tag          string     `json:"tag"`
+	Name         string     `json:"name"`
+	Category     string     `json:"category"`
+	SerialNumber string     `json:"serial_number"`
+	Status       string     `json:"status"`
+	Notes        string     `json:"notes"`
+	Loan         *AssetLoan `json:"loan,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AssetLoan is one check-out of an asset to a classroom, teacher or
+// student.
+type AssetLoan struct {
+	ID           int64      `json:"id"`
+	AssetID      int64      `json:"asset_id"`
+	HolderType   string     `json:"holder_type"`
+	HolderID     int64      `json:"holder_id"`
+	HolderName   string     `json:"holder_name"`
+	CheckedOutAt time.Time  `json:"checked_out_at"`
+	DueDate      *time.Time `json:"due_date"`
+	CheckedOutBy int64      `json:"checked_out_by"`
+	ConditionOut string     `json:"condition_out"`
+	ReturnedAt   *time.Time `json:"returned_at"`
+	ReturnedTo   *int64     `json:"returned_to"`
+	ConditionIn  string     `json:"condition_in"`
+}
+
+// OverdueLoan is an open loan past its due date.
+type OverdueLoan struct {
+	*AssetLoan
+	AssetTag  string `json:"asset_tag"`
+	AssetName string `json:"asset_name"`
+	DaysLate  int    `json:"days_late"`
+}
+
+type AssetStore struct {
+	db *routedDB
+}
+
+// loanColumns selects a loan with its holder's display name.
+const loanColumns = `
+	l.id, l.asset_id, l.holder_type, l.holder_id,
+	COALESCE(CASE l.holder_type
+		WHEN 'classroom' THEN (SELECT name FROM classrooms WHERE id = l.holder_id)
+		WHEN 'teacher' THEN (SELECT first_name || ' ' || last_name FROM teachers WHERE id = l.holder_id)
+		WHEN 'student' THEN (SELECT first_name || ' ' || last_name FROM students WHERE id = l.holder_id)
+	END, ''),
+	l.checked_out_at, l.due_date, l.checked_out_by, l.condition_out, l.returned_at, l.returned_to, l.condition_in`
+
+func scanLoan(row interface{ Scan(...any) error }, extra ...any) (*AssetLoan, error) {
+	var l AssetLoan
+	dest := []any{&l.ID, &l.AssetID, &l.HolderType, &l.HolderID, &l.HolderName, &l.CheckedOutAt, &l.DueDate,
+		&l.CheckedOutBy, &l.ConditionOut, &l.ReturnedAt, &l.ReturnedTo, &l.ConditionIn}
+	err := row.Scan(append(dest, extra...)...)
+	return &l, err
+}
+
+const assetColumns = `id, tag, name, category, serial_number, status, notes, created_at, updated_at`
+
+func scanAsset(row interface{ Scan(...any) error }) (*Asset, error) {
+	var a Asset
+	err := row.Scan(&a.ID, &a.Tag, &a.Name, &a.Category, &a.SerialNumber, &a.Status, &a.Notes, &a.CreatedAt, &a.UpdatedAt)
+	return &a, err
+}
+
+// List returns assets by tag, filtered by status and category when set.
+func (s *AssetStore) List(ctx context.Context, status, category string) ([]*Asset, error) {
+	query := `
+		SELECT ` + assetColumns + `
+		FROM assets
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR category = $2)
+		ORDER BY tag
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, status, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []*Asset{}
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, a)
+	}
+
+	return assets, rows.Err()
+}
+
+// GetByID returns an asset with its open loan, if any.
+func (s *AssetStore) GetByID(ctx context.Context, id int64) (*Asset, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	a, err := scanAsset(s.db.QueryRowContext(ctx, `SELECT `+assetColumns+` FROM assets WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	loan, err := scanLoan(s.db.QueryRowContext(ctx,
+		`SELECT `+loanColumns+` FROM asset_loans l WHERE l.asset_id = $1 AND l.returned_at IS NULL`, id))
+	switch {
+	case err == nil:
+		a.Loan = loan
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+	return a, nil
+}
+
+// Create adds an asset; tags are unique (ErrConflict).
+func (s *AssetStore) Create(ctx context.Context, a *Asset) error {
+	query := `
+		INSERT INTO assets (tag, name, category, serial_number, notes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, a.Tag, a.Name, a.Category, a.SerialNumber, a.Notes).
+		Scan(&a.ID, &a.Status, &a.CreatedAt, &a.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Update saves an asset's details and status. Moving a checked-out asset to
+// another status, or into checked_out, is rejected with ErrConflict; use
+// CheckOut and CheckIn.
+func (s *AssetStore) Update(ctx context.Context, a *Asset) error {
+	query := `
+		UPDATE assets
+		SET tag = $2, name = $3, category = $4, serial_number = $5, status = $6, notes = $7, updated_at = NOW()
+		WHERE id = $1 AND (status = $6 OR (status <> 'checked_out' AND $6 <> 'checked_out'))
+		RETURNING updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, a.ID, a.Tag, a.Name, a.Category, a.SerialNumber, a.Status, a.Notes).
+		Scan(&a.UpdatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		var exists bool
+		if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM assets WHERE id = $1)`, a.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrConflict
+		}
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return ErrConflict
+	}
+	return err
+}
+
+// CheckOut opens a loan for an available asset. It returns
+// ErrAssetUnavailable when the asset is checked out, in maintenance or
+// retired.
+func (s *AssetStore) CheckOut(ctx context.Context, loan *AssetLoan) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE assets SET status = 'checked_out', updated_at = NOW() WHERE id = $1 AND status = 'available'`,
+		loan.AssetID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrAssetUnavailable
+	}
+
+	var due any
+	if loan.DueDate != nil {
+		due = loan.DueDate.Format(time.DateOnly)
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO asset_loans (asset_id, holder_type, holder_id, due_date, checked_out_by, condition_out)
+		VALUES ($1, $2, $3, $4::date, $5, $6)
+		RETURNING id, checked_out_at
+	`, loan.AssetID, loan.HolderType, loan.HolderID, due, loan.CheckedOutBy, loan.ConditionOut).
+		Scan(&loan.ID, &loan.CheckedOutAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CheckIn closes an asset's open loan and makes it available again. It
+// returns ErrNotFound when the asset is not checked out.
+func (s *AssetStore) CheckIn(ctx context.Context, assetID, returnedTo int64, condition string) (*AssetLoan, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var loanID int64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE asset_loans
+		SET returned_at = NOW(), returned_to = $2, condition_in = $3
+		WHERE asset_id = $1 AND returned_at IS NULL
+		RETURNING id
+	`, assetID, returnedTo, condition).Scan(&loanID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE assets SET status = 'available', updated_at = NOW() WHERE id = $1`, assetID,
+	); err != nil {
+		return nil, err
+	}
+
+	loan, err := scanLoan(tx.QueryRowContext(ctx, `SELECT `+loanColumns+` FROM asset_loans l WHERE l.id = $1`, loanID))
+	if err != nil {
+		return nil, err
+	}
+
+	return loan, tx.Commit()
+}
+
+// History returns an asset's loans, newest first.
+func (s *AssetStore) History(ctx context.Context, assetID int64) ([]*AssetLoan, error) {
+	return s.loans(ctx, `l.asset_id = $1 ORDER BY l.checked_out_at DESC, l.id DESC`, assetID)
+}
+
+// ForHolder returns the open loans of a classroom, teacher or student.
+func (s *AssetStore) ForHolder(ctx context.Context, holderType string, holderID int64) ([]*AssetLoan, error) {
+	return s.loans(ctx, `l.holder_type = $1 AND l.holder_id = $2 AND l.returned_at IS NULL ORDER BY l.checked_out_at`,
+		holderType, holderID)
+}
+
+func (s *AssetStore) loans(ctx context.Context, where string, args ...any) ([]*AssetLoan, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+loanColumns+` FROM asset_loans l WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loans := []*AssetLoan{}
+	for rows.Next() {
+		l, err := scanLoan(rows)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, l)
+	}
+
+	return loans, rows.Err()
+}
+
+// Overdue returns open loans whose due date is before asOf, most overdue
+// first.
+func (s *AssetStore) Overdue(ctx context.Context, asOf time.Time) ([]*OverdueLoan, error) {
+	query := `
+		SELECT ` + loanColumns + `, a.tag, a.name, $1::date - l.due_date
+		FROM asset_loans l
+		JOIN assets a ON a.id = l.asset_id
+		WHERE l.returned_at IS NULL AND l.due_date < $1::date
+		ORDER BY l.due_date, l.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, asOf.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overdue := []*OverdueLoan{}
+	for rows.Next() {
+		o := &OverdueLoan{}
+		o.AssetLoan, err = scanLoan(rows, &o.AssetTag, &o.AssetName, &o.DaysLate)
+		if err != nil {
+			return nil, err
+		}
+		overdue = append(overdue, o)
+	}
+
+	return overdue, rows.Err()
+}
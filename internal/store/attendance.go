@@ -16,15 +16,19 @@ type AttendanceRecord struct {
 	Date        time.Time `json:"date"`   // date part only
 	Status      string    `json:"status"` // 'present','absent','late','excused'
 	Note        *string   `json:"note,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	// Method is how the status was last set: manual, online, geofence or
+	// import.
+	Method      string     `json:"method"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
 type AttendanceStore struct {
-	db *sql.DB
+	db *routedDB
 }
 
 func NewAttendanceStore(db *sql.DB) *AttendanceStore {
-	return &AttendanceStore{db: db}
+	return &AttendanceStore{db: newRoutedDB(Pools{API: db})}
 }
 
 // Mark inserts or updates a single attendance record (upsert by student_id+date).
@@ -35,7 +39,7 @@ func (s *AttendanceStore) Mark(ctx context.Context, rec *AttendanceRecord) error
 	// make sure date has no time component (set to midnight)
 	rec.Date = rec.Date.UTC().Truncate(24 * time.Hour)
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	query := `
@@ -46,8 +50,9 @@ func (s *AttendanceStore) Mark(ctx context.Context, rec *AttendanceRecord) error
 		  teacher_id = EXCLUDED.teacher_id,
 		  classroom_id = EXCLUDED.classroom_id,
 		  status = EXCLUDED.status,
-		  note = EXCLUDED.note
-		RETURNING id, created_at
+		  note = EXCLUDED.note,
+		  method = EXCLUDED.method
+		RETURNING id, method, created_at
 	`
 
 	var teacherID interface{}
@@ -76,7 +81,7 @@ func (s *AttendanceStore) Mark(ctx context.Context, rec *AttendanceRecord) error
 		rec.Date,
 		rec.Status,
 		note,
-	).Scan(&rec.ID, &rec.CreatedAt)
+	).Scan(&rec.ID, &rec.Method, &rec.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -90,7 +95,7 @@ func (s *AttendanceStore) BulkMark(ctx context.Context, classroomID int64, date
 		return nil
 	}
 	date = date.UTC().Truncate(24 * time.Hour)
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -99,14 +104,15 @@ func (s *AttendanceStore) BulkMark(ctx context.Context, classroomID int64, date
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
+	stmt, err := s.db.prepare(ctx, tx, `
 		INSERT INTO attendance_records (student_id, teacher_id, classroom_id, date, status, note)
 		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (student_id, date)
 		DO UPDATE SET
 		  classroom_id = EXCLUDED.classroom_id,
 		  status = EXCLUDED.status,
-		  note = EXCLUDED.note
+		  note = EXCLUDED.note,
+		  method = EXCLUDED.method
 	`)
 	if err != nil {
 		return err
@@ -143,13 +149,13 @@ func (s *AttendanceStore) GetByStudent(ctx context.Context, studentID int64, fro
 		i++
 	}
 	query := fmt.Sprintf(`
-		SELECT id, student_id, teacher_id, classroom_id, date, status, note, created_at
+		SELECT id, student_id, teacher_id, classroom_id, date, status, note, method, checked_in_at, created_at
 		FROM attendance_records
 		%s
 		ORDER BY date ASC
 	`, cond)
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -164,7 +170,7 @@ func (s *AttendanceStore) GetByStudent(ctx context.Context, studentID int64, fro
 		var teacher sql.NullInt64
 		var classroom sql.NullInt64
 		var note sql.NullString
-		if err := rows.Scan(&ar.ID, &ar.StudentID, &teacher, &classroom, &ar.Date, &ar.Status, &note, &ar.CreatedAt); err != nil {
+		if err := rows.Scan(&ar.ID, &ar.StudentID, &teacher, &classroom, &ar.Date, &ar.Status, &note, &ar.Method, &ar.CheckedInAt, &ar.CreatedAt); err != nil {
 			return nil, err
 		}
 		if teacher.Valid {
@@ -191,12 +197,12 @@ func (s *AttendanceStore) GetByStudent(ctx context.Context, studentID int64, fro
 func (s *AttendanceStore) GetByClassroomDate(ctx context.Context, classroomID int64, date time.Time) ([]*AttendanceRecord, error) {
 	date = date.UTC().Truncate(24 * time.Hour)
 	query := `
-		SELECT id, student_id, teacher_id, classroom_id, date, status, note, created_at
+		SELECT id, student_id, teacher_id, classroom_id, date, status, note, method, checked_in_at, created_at
 		FROM attendance_records
 		WHERE classroom_id = $1 AND date = $2
 		ORDER BY student_id ASC
 	`
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, classroomID, date)
@@ -211,7 +217,7 @@ func (s *AttendanceStore) GetByClassroomDate(ctx context.Context, classroomID in
 		var teacher sql.NullInt64
 		var classroom sql.NullInt64
 		var note sql.NullString
-		if err := rows.Scan(&ar.ID, &ar.StudentID, &teacher, &classroom, &ar.Date, &ar.Status, &note, &ar.CreatedAt); err != nil {
+		if err := rows.Scan(&ar.ID, &ar.StudentID, &teacher, &classroom, &ar.Date, &ar.Status, &note, &ar.Method, &ar.CheckedInAt, &ar.CreatedAt); err != nil {
 			return nil, err
 		}
 		if teacher.Valid {
@@ -235,7 +241,7 @@ func (s *AttendanceStore) GetByClassroomDate(ctx context.Context, classroomID in
 }
 
 func (s *AttendanceStore) Delete(ctx context.Context, id int64) error {
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 	res, err := s.db.ExecContext(ctx, `DELETE FROM attendance_records WHERE id = $1`, id)
 	if err != nil {
@@ -250,3 +256,68 @@ func (s *AttendanceStore) Delete(ctx context.Context, id int64) error {
 	}
 	return nil
 }
+
+// Excuse turns an absence into an excused absence. Records with any other
+// status are left alone and reported as ErrNotFound.
+func (s *AttendanceStore) Excuse(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	res, err := s.db.ExecContext(ctx, `UPDATE attendance_records SET status = 'excused' WHERE id = $1 AND status = 'absent'`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// EnsurePartitions creates the monthly partitions of attendance_records for
+// the month of now and the months following it. Records already in the
+// default partition for a new month are moved into it.
+func (s *AttendanceStore) EnsurePartitions(ctx context.Context, now time.Time, months int) error {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for range months + 1 {
+		end := start.AddDate(0, 1, 0)
+		if err := s.ensurePartition(ctx, start, end); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+func (s *AttendanceStore) ensurePartition(ctx context.Context, start, end time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	name := "attendance_records_" + start.Format("2006_01")
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// attaching a range the default partition has rows for fails, so the
+	// partition is filled from it before it is attached
+	from, to := start.Format(time.DateOnly), end.Format(time.DateOnly)
+	for _, query := range []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE attendance_records INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name),
+		fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM attendance_records_default WHERE date >= '%s' AND date < '%s' RETURNING *
+			)
+			INSERT INTO %s SELECT * FROM moved`, from, to, name),
+		fmt.Sprintf(`ALTER TABLE attendance_records ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`, name, from, to),
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
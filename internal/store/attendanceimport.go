@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AttendanceImportRow is one historical record from a legacy system, with
+// the CSV line it came from.
+type AttendanceImportRow struct {
+	Line        int
+	StudentCode string
+	Date        time.Time
+	Status      string
+	Note        string
+}
+
+type AttendanceImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// AttendanceImportResult counts what an import did, or would do in a dry
+// run. When Errors is not empty nothing was imported.
+type AttendanceImportResult struct {
+	DryRun      bool                    `json:"dry_run"`
+	Rows        int                     `json:"rows"`
+	Created     int                     `json:"created"`
+	Overwritten int                     `json:"overwritten"`
+	Skipped     int                     `json:"skipped"`
+	Errors      []AttendanceImportError `json:"errors"`
+}
+
+// attendanceImportBatch is how many rows are written between progress
+// reports.
+const attendanceImportBatch = 500
+
+// Import writes rows as attendance in one transaction, each under its
+// student's current classroom and marked method import. Dates that already
+// have a record are overwritten, or skipped when overwrite is false. A dry
+// run does the same work and rolls it back. Rows whose student code is
+// unknown are reported in the result's Errors, and then nothing is
+// written. Students outside scope count as unknown. progress is called with
+// the number of rows done.
+func (s *AttendanceStore) Import(ctx context.Context, rows []*AttendanceImportRow, overwrite, dryRun bool, scope Scope, progress func(done int)) (*AttendanceImportResult, error) {
+	res := &AttendanceImportResult{DryRun: dryRun, Rows: len(rows), Errors: []AttendanceImportError{}}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes := make([]string, len(rows))
+	for i, row := range rows {
+		codes[i] = row.StudentCode
+	}
+	type student struct {
+		id          int64
+		classroomID sql.NullInt64
+	}
+	students := map[string]student{}
+	query := `SELECT student_code, id, classroom_id FROM students WHERE student_code = ANY($1) AND ` + notDeleted
+	if c := scope.condition("students"); c != "" {
+		query += " AND " + c
+	}
+	found, err := tx.QueryContext(ctx, query, pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer found.Close()
+	for found.Next() {
+		var code string
+		var st student
+		if err := found.Scan(&code, &st.id, &st.classroomID); err != nil {
+			return nil, err
+		}
+		students[code] = st
+	}
+	if err := found.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if _, ok := students[row.StudentCode]; !ok {
+			res.Errors = append(res.Errors, AttendanceImportError{Line: row.Line, Error: fmt.Sprintf("no student with code %q", row.StudentCode)})
+		}
+	}
+	if len(res.Errors) > 0 {
+		return res, nil
+	}
+
+	onConflict := `DO NOTHING`
+	if overwrite {
+		onConflict = `DO UPDATE SET status = EXCLUDED.status, note = EXCLUDED.note, method = EXCLUDED.method`
+	}
+	stmt, err := s.db.prepare(ctx, tx, `
+		INSERT INTO attendance_records (student_id, classroom_id, date, status, note, method)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), 'import')
+		ON CONFLICT (student_id, date) `+onConflict+`
+		RETURNING xmax = 0
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for i, row := range rows {
+		st := students[row.StudentCode]
+		var created bool
+		err := stmt.QueryRowContext(ctx, st.id, st.classroomID, row.Date.Format(time.DateOnly), row.Status, row.Note).Scan(&created)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			res.Skipped++
+		case err != nil:
+			return nil, err
+		case created:
+			res.Created++
+		default:
+			res.Overwritten++
+		}
+		if (i+1)%attendanceImportBatch == 0 || i+1 == len(rows) {
+			progress(i + 1)
+		}
+	}
+
+	if dryRun {
+		return res, nil
+	}
+	return res, tx.Commit()
+}
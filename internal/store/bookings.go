@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type BookingResource struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Location  string    `json:"location"`
+	Capacity  int       `json:"capacity"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Booking reserves a resource for [StartsAt, EndsAt). Cancelled bookings
+// are kept for the record but no longer block the slot.
+type Booking struct {
+	ID           int64      `json:"id"`
+	ResourceID   int64      `json:"resource_id"`
+	BookedBy     int64      `json:"booked_by"`
+	BookedByRole string     `json:"booked_by_role"`
+	BookedByName string     `json:"booked_by_name"`
+	ClassroomID  *int64     `json:"classroom_id"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	Purpose      string     `json:"purpose"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type BookingStore struct {
+	db *routedDB
+}
+
+const resourceColumns = `id, name, kind, location, capacity, active, created_at, updated_at`
+
+func scanResource(row interface{ Scan(...any) error }) (*BookingResource, error) {
+	var r BookingResource
+	err := row.Scan(&r.ID, &r.Name, &r.Kind, &r.Location, &r.Capacity, &r.Active, &r.CreatedAt, &r.UpdatedAt)
+	return &r, err
+}
+
+// ListResources returns bookable rooms and equipment by name.
+func (s *BookingStore) ListResources(ctx context.Context, activeOnly bool) ([]*BookingResource, error) {
+	query := `
+		SELECT ` + resourceColumns + `
+		FROM booking_resources
+		WHERE active OR NOT $1
+		ORDER BY kind, name
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resources := []*BookingResource{}
+	for rows.Next() {
+		r, err := scanResource(rows)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, r)
+	}
+
+	return resources, rows.Err()
+}
+
+func (s *BookingStore) GetResource(ctx context.Context, id int64) (*BookingResource, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	r, err := scanResource(s.db.QueryRowContext(ctx, `SELECT `+resourceColumns+` FROM booking_resources WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// CreateResource adds a room or piece of equipment; names are unique
+// (ErrConflict).
+func (s *BookingStore) CreateResource(ctx context.Context, r *BookingResource) error {
+	query := `
+		INSERT INTO booking_resources (name, kind, location, capacity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, active, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, r.Name, r.Kind, r.Location, r.Capacity).
+		Scan(&r.ID, &r.Active, &r.CreatedAt, &r.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *BookingStore) UpdateResource(ctx context.Context, r *BookingResource) error {
+	query := `
+		UPDATE booking_resources
+		SET name = $2, kind = $3, location = $4, capacity = $5, active = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, r.ID, r.Name, r.Kind, r.Location, r.Capacity, r.Active).Scan(&r.UpdatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return ErrConflict
+	}
+	return err
+}
+
+// bookingColumns selects a booking with the display name of whoever made
+// it.
+const bookingColumns = `
+	b.id, b.resource_id, b.booked_by, b.booked_by_role,
+	COALESCE(CASE b.booked_by_role
+		WHEN 'teacher' THEN (SELECT first_name || ' ' || last_name FROM teachers WHERE id = b.booked_by)
+		ELSE (SELECT first_name || ' ' || last_name FROM execs WHERE id = b.booked_by)
+	END, ''),
+	b.classroom_id, b.starts_at, b.ends_at, b.purpose, b.cancelled_at, b.created_at`
+
+func scanBooking(row interface{ Scan(...any) error }) (*Booking, error) {
+	var b Booking
+	err := row.Scan(&b.ID, &b.ResourceID, &b.BookedBy, &b.BookedByRole, &b.BookedByName, &b.ClassroomID,
+		&b.StartsAt, &b.EndsAt, &b.Purpose, &b.CancelledAt, &b.CreatedAt)
+	return &b, err
+}
+
+// Book reserves a slot. It returns ErrConflict when the slot overlaps an
+// existing booking of the same resource.
+func (s *BookingStore) Book(ctx context.Context, b *Booking) error {
+	query := `
+		INSERT INTO bookings (resource_id, booked_by, booked_by_role, classroom_id, starts_at, ends_at, purpose)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		b.ResourceID, b.BookedBy, b.BookedByRole, b.ClassroomID, b.StartsAt, b.EndsAt, b.Purpose,
+	).Scan(&b.ID, &b.CreatedAt)
+	if isExclusionViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *BookingStore) GetByID(ctx context.Context, id int64) (*Booking, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	b, err := scanBooking(s.db.QueryRowContext(ctx, `SELECT `+bookingColumns+` FROM bookings b WHERE b.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// Cancel frees a booking's slot. Cancelling twice returns ErrNotFound.
+func (s *BookingStore) Cancel(ctx context.Context, id, cancelledBy int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE bookings SET cancelled_at = NOW(), cancelled_by = $2 WHERE id = $1 AND cancelled_at IS NULL`,
+		id, cancelledBy,
+	)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Overlapping returns the live bookings of a resource that intersect
+// [from, to), in start order.
+func (s *BookingStore) Overlapping(ctx context.Context, resourceID int64, from, to time.Time) ([]*Booking, error) {
+	query := `
+		SELECT ` + bookingColumns + `
+		FROM bookings b
+		WHERE b.resource_id = $1 AND b.cancelled_at IS NULL
+		  AND b.starts_at < $3 AND b.ends_at > $2
+		ORDER BY b.starts_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, resourceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookings := []*Booking{}
+	for rows.Next() {
+		b, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+
+	return bookings, rows.Err()
+}
@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Broadcast groups and channels.
+const (
+	BroadcastStaff    = "staff"
+	BroadcastTeachers = "teachers"
+	BroadcastStudents = "students"
+	BroadcastParents  = "parents"
+
+	ChannelSMS      = "sms"
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+)
+
+// Broadcast is an urgent message sent to whole groups at once. ClassroomID
+// and Grade narrow the students and parents groups.
+type Broadcast struct {
+	ID          int64    `json:"id"`
+	Subject     string   `json:"subject"`
+	Message     string   `json:"message"`
+	Groups      []string `json:"groups"`
+	ClassroomID *int64   `json:"classroom_id,omitempty"`
+	Grade       *int64   `json:"grade,omitempty"`
+	Channels    []string `json:"channels"`
+	SentBy      int64    `json:"sent_by"`
+	SentFromIP  string   `json:"sent_from_ip"`
+	Recipients  int      `json:"recipients"`
+	SMSSent     int      `json:"sms_sent"`
+	EmailSent   int      `json:"email_sent"`
+	// TelegramSent counts parents reached through a linked Telegram chat.
+	TelegramSent int        `json:"telegram_sent"`
+	Failed       int        `json:"failed"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+// BroadcastRecipient is where one person can be reached; either may be
+// empty.
+type BroadcastRecipient struct {
+	Email string
+	Phone string
+}
+
+// broadcastGroupQueries select the email and phone of each group. $1 is
+// the classroom and $2 the grade filter.
+var broadcastGroupQueries = map[string]string{
+	BroadcastStaff:    `SELECT email, '' FROM execs`,
+	BroadcastTeachers: `SELECT email, phone_number FROM teachers WHERE deleted_at IS NULL`,
+	BroadcastStudents: `SELECT s.email, COALESCE(s.phone_number, '') FROM students s
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE s.deleted_at IS NULL AND ($1::bigint IS NULL OR s.classroom_id = $1) AND ($2::bigint IS NULL OR c.grade = $2)`,
+	BroadcastParents: `SELECT '', s.parent_phone_number FROM students s
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE s.deleted_at IS NULL AND ($1::bigint IS NULL OR s.classroom_id = $1) AND ($2::bigint IS NULL OR c.grade = $2)`,
+}
+
+type BroadcastStore struct {
+	db *routedDB
+}
+
+func (s *BroadcastStore) Create(ctx context.Context, b *Broadcast) error {
+	query := `
+		INSERT INTO broadcasts (subject, message, groups, classroom_id, grade, channels, sent_by, sent_from_ip, recipients)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		b.Subject, b.Message, pq.Array(b.Groups), b.ClassroomID, b.Grade, pq.Array(b.Channels), b.SentBy, b.SentFromIP, b.Recipients,
+	).Scan(&b.ID, &b.CreatedAt)
+}
+
+// Recipients returns everyone in the broadcast's groups, once per distinct
+// email and phone pair.
+func (s *BroadcastStore) Recipients(ctx context.Context, b *Broadcast) ([]*BroadcastRecipient, error) {
+	parts := []string{}
+	for _, g := range b.Groups {
+		if q, ok := broadcastGroupQueries[g]; ok {
+			parts = append(parts, q)
+		}
+	}
+	if len(parts) == 0 {
+		return []*BroadcastRecipient{}, nil
+	}
+	query := "SELECT DISTINCT * FROM (" + strings.Join(parts, " UNION ALL ") + ") r"
+
+	// postgres rejects parameters no query part refers to
+	args := []any{}
+	if strings.Contains(query, "$1") {
+		args = append(args, b.ClassroomID, b.Grade)
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []*BroadcastRecipient{}
+	for rows.Next() {
+		var r BroadcastRecipient
+		if err := rows.Scan(&r.Email, &r.Phone); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, &r)
+	}
+	return recipients, rows.Err()
+}
+
+// Finish records how a broadcast's delivery went.
+func (s *BroadcastStore) Finish(ctx context.Context, b *Broadcast) error {
+	query := `
+		UPDATE broadcasts
+		SET sms_sent = $2, email_sent = $3, telegram_sent = $4, failed = $5, completed_at = NOW()
+		WHERE id = $1
+		RETURNING completed_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, b.ID, b.SMSSent, b.EmailSent, b.TelegramSent, b.Failed).Scan(&b.CompletedAt)
+}
+
+// CountSince returns how many broadcasts were sent after since.
+func (s *BroadcastStore) CountSince(ctx context.Context, since time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM broadcasts WHERE created_at > $1`, since).Scan(&n)
+	return n, err
+}
+
+// List returns the most recent broadcasts, newest first.
+func (s *BroadcastStore) List(ctx context.Context, limit int) ([]*Broadcast, error) {
+	query := `
+		SELECT id, subject, message, groups, classroom_id, grade, channels, sent_by, sent_from_ip,
+			recipients, sms_sent, email_sent, telegram_sent, failed, created_at, completed_at
+		FROM broadcasts
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	broadcasts := []*Broadcast{}
+	for rows.Next() {
+		var b Broadcast
+		if err := rows.Scan(&b.ID, &b.Subject, &b.Message, pq.Array(&b.Groups), &b.ClassroomID, &b.Grade,
+			pq.Array(&b.Channels), &b.SentBy, &b.SentFromIP, &b.Recipients, &b.SMSSent, &b.EmailSent,
+			&b.TelegramSent, &b.Failed, &b.CreatedAt, &b.CompletedAt); err != nil {
+			return nil, err
+		}
+		broadcasts = append(broadcasts, &b)
+	}
+	return broadcasts, rows.Err()
+}
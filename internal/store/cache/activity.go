@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	recentMaxItems = 20
+	feedMaxItems   = 100
+	activityTTL    = 30 * 24 * time.Hour
+)
+
+// EntityRef points at a record a user has opened.
+type EntityRef struct {
+	Type     string    `json:"type"`
+	ID       int64     `json:"id"`
+	ViewedAt time.Time `json:"viewed_at"`
+}
+
+// ActivityEvent is an entry of a user's activity feed.
+type ActivityEvent struct {
+	Action   string    `json:"action"`
+	Type     string    `json:"type"`
+	ID       int64     `json:"id"`
+	Occurred time.Time `json:"occurred_at"`
+}
+
+type ActivityStore struct {
+	rdb *redis.Client
+}
+
+func recentKey(user string) string { return "activity:recent:" + user }
+func feedKey(user string) string   { return "activity:feed:" + user }
+
+// RecordView moves the entity to the top of the user's recently viewed set.
+func (s *ActivityStore) RecordView(ctx context.Context, user, entityType string, id int64) error {
+	key := recentKey(user)
+	member := fmt.Sprintf("%s:%d", entityType, id)
+
+	pipe := s.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().UnixMilli()), Member: member})
+	pipe.ZRemRangeByRank(ctx, key, 0, -recentMaxItems-1)
+	pipe.Expire(ctx, key, activityTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Recent returns the user's most recently viewed entities, newest first.
+func (s *ActivityStore) Recent(ctx context.Context, user string, limit int) ([]EntityRef, error) {
+	entries, err := s.rdb.ZRevRangeWithScores(ctx, recentKey(user), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]EntityRef, 0, len(entries))
+	for _, z := range entries {
+		member, _ := z.Member.(string)
+		entityType, idStr, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		refs = append(refs, EntityRef{
+			Type:     entityType,
+			ID:       id,
+			ViewedAt: time.UnixMilli(int64(z.Score)).UTC(),
+		})
+	}
+	return refs, nil
+}
+
+// RecordEvent prepends an event to the user's activity feed.
+func (s *ActivityStore) RecordEvent(ctx context.Context, user string, ev ActivityEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	key := feedKey(user)
+	pipe := s.rdb.TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, feedMaxItems-1)
+	pipe.Expire(ctx, key, activityTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Feed returns the user's latest activity events, newest first.
+func (s *ActivityStore) Feed(ctx context.Context, user string, limit int) ([]ActivityEvent, error) {
+	items, err := s.rdb.LRange(ctx, feedKey(user), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ActivityEvent, 0, len(items))
+	for _, item := range items {
+		var ev ActivityEvent
+		if err := json.Unmarshal([]byte(item), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const scanBatch = 500
+
+// protectedKeys are never removed by Flush: they hold state, not cached data.
+var protectedKeys = map[string]bool{
+	maintenanceKey: true,
+	blocklistKey:   true,
+}
+
+// protectedPrefixes are namespaces of state Flush leaves alone: OTP codes
+// with their resend cooldowns and send counts, and API key quota usage.
+// Flushing them would reset those limits.
+var protectedPrefixes = []string{"otp:", "quota:"}
+
+func protected(key string) bool {
+	if protectedKeys[key] {
+		return true
+	}
+	for _, p := range protectedPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminStore lets operators inspect and flush cache namespaces.
+type AdminStore struct {
+	rdb *redis.Client
+}
+
+// Keys returns up to limit keys starting with prefix.
+func (s *AdminStore) Keys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	keys := []string{}
+	iter := s.rdb.Scan(ctx, 0, prefix+"*", scanBatch).Iterator()
+	for iter.Next(ctx) && len(keys) < limit {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// Flush removes every key starting with prefix and returns how many were
+// removed.
+func (s *AdminStore) Flush(ctx context.Context, prefix string) (int64, error) {
+	return unlinkMatching(ctx, s.rdb, prefix+"*")
+}
+
+// unlinkMatching removes the keys matching pattern in batches, skipping
+// protected keys.
+func unlinkMatching(ctx context.Context, rdb *redis.Client, pattern string) (int64, error) {
+	var removed int64
+	batch := make([]string, 0, scanBatch)
+
+	unlink := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := rdb.Unlink(ctx, batch...).Result()
+		removed += n
+		batch = batch[:0]
+		return err
+	}
+
+	iter := rdb.Scan(ctx, 0, pattern, scanBatch).Iterator()
+	for iter.Next(ctx) {
+		if protected(iter.Val()) {
+			continue
+		}
+		batch = append(batch, iter.Val())
+		if len(batch) == scanBatch {
+			if err := unlink(); err != nil {
+				return removed, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return removed, err
+	}
+	return removed, unlink()
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const blocklistKey = "blocklist"
+
+// BlockedNetwork is a denied IP address or CIDR range.
+type BlockedNetwork struct {
+	CIDR      string     `json:"cidr"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy int64      `json:"created_by"`
+}
+
+// BlocklistStore keeps denied networks in one hash keyed by CIDR, without
+// expiry so the list survives restarts.
+type BlocklistStore struct {
+	rdb *redis.Client
+}
+
+// List returns the live entries and drops expired ones.
+func (s *BlocklistStore) List(ctx context.Context) ([]*BlockedNetwork, error) {
+	fields, err := s.rdb.HGetAll(ctx, blocklistKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entries := []*BlockedNetwork{}
+	for cidr, data := range fields {
+		var b BlockedNetwork
+		if err := json.Unmarshal([]byte(data), &b); err != nil {
+			return nil, err
+		}
+		if b.ExpiresAt != nil && !b.ExpiresAt.After(now) {
+			s.rdb.HDel(ctx, blocklistKey, cidr)
+			continue
+		}
+		entries = append(entries, &b)
+	}
+	return entries, nil
+}
+
+// Add stores b, replacing an existing entry for the same CIDR.
+func (s *BlocklistStore) Add(ctx context.Context, b *BlockedNetwork) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return s.rdb.HSet(ctx, blocklistKey, b.CIDR, data).Err()
+}
+
+// Remove deletes the entry for cidr and reports whether it existed.
+func (s *BlocklistStore) Remove(ctx context.Context, cidr string) (bool, error) {
+	n, err := s.rdb.HDel(ctx, blocklistKey, cidr).Result()
+	return n > 0, err
+}
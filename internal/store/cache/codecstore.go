@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultListTTL = 30 * time.Second
+	// analyticsTTL is longer: aggregates are expensive and change slowly.
+	analyticsTTL = 5 * time.Minute
+)
+
+// EntityCache is the cache surface every entity store offers.
+type EntityCache[T any] interface {
+	GetList(context.Context, string) ([]*T, error)
+	SetList(context.Context, string, []*T) error
+	GetByID(context.Context, int64) (*T, error)
+	SetByID(context.Context, int64, *T) error
+	InvalidatePrefix(context.Context, string) error
+}
+
+// CodecStore caches values of T under a namespace such as "students", encoded
+// with the configured codec (JSON unless REDIS_CODEC says otherwise).
+// Misses are reported as a nil value with a nil error.
+type CodecStore[T any] struct {
+	rdb       *redis.Client
+	codec     Codec
+	namespace string
+	ttl       time.Duration
+}
+
+func NewCodecStore[T any](rdb *redis.Client, codec Codec, namespace string, ttl time.Duration) *CodecStore[T] {
+	return &CodecStore[T]{rdb: rdb, codec: codec, namespace: namespace, ttl: ttl}
+}
+
+// GetList returns the cached list stored under key, or nil.
+func (s *CodecStore[T]) GetList(ctx context.Context, key string) ([]*T, error) {
+	var list []*T
+	if ok, err := s.get(ctx, key, &list); !ok {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SetList caches list under key.
+func (s *CodecStore[T]) SetList(ctx context.Context, key string, list []*T) error {
+	return s.set(ctx, key, list)
+}
+
+// GetByID returns the cached record with the given ID, or nil.
+func (s *CodecStore[T]) GetByID(ctx context.Context, id int64) (*T, error) {
+	var v T
+	if ok, err := s.get(ctx, s.idKey(id), &v); !ok {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// SetByID caches a single record.
+func (s *CodecStore[T]) SetByID(ctx context.Context, id int64, v *T) error {
+	return s.set(ctx, s.idKey(id), v)
+}
+
+// InvalidatePrefix drops every key of this namespace starting with prefix;
+// an empty prefix clears the whole namespace.
+func (s *CodecStore[T]) InvalidatePrefix(ctx context.Context, prefix string) error {
+	_, err := unlinkMatching(ctx, s.rdb, s.namespace+":"+prefix+"*")
+	return err
+}
+
+func (s *CodecStore[T]) idKey(id int64) string {
+	return fmt.Sprintf("%s:id:%d", s.namespace, id)
+}
+
+func (s *CodecStore[T]) get(ctx context.Context, key string, dst any) (bool, error) {
+	data, err := s.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := s.codec.Unmarshal(data, dst); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *CodecStore[T]) set(ctx context.Context, key string, v any) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.rdb.SetEx(ctx, key, data, jitter(s.ttl)).Err()
+}
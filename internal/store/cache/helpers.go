@@ -3,9 +3,23 @@ package cache
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"sort"
+	"time"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+	"golang.org/x/sync/singleflight"
 )
 
+// listFetches collapses concurrent cache misses on the same key into a single
+// DB query.
+var listFetches singleflight.Group
+
+// listFetchTimeout bounds a shared fetch. It is detached from the request
+// that started it, so one caller giving up doesn't fail the others waiting
+// on the same key.
+var listFetchTimeout = store.QueryTimeoutDuration
+
 // ListGetter fetches the list from DB
 type ListGetter[T any] func(ctx context.Context) ([]*T, error)
 
@@ -27,15 +41,51 @@ func GetListWithCache[T any](
 		return cached, nil
 	}
 
-	// Fetch from DB
+	// Fetch from DB once per key, then set cache
+	fetch := listFetches.DoChan(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), listFetchTimeout)
+		defer cancel()
+
+		list, err := fetcher(ctx)
+		if err != nil {
+			return nil, err
+		}
+		_ = rdb.SetList(ctx, key, list)
+		return list, nil
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-fetch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]*T), nil
+	}
+}
+
+// RefreshList fetches the list from DB and overwrites the cached entry,
+// whether or not one exists. Used to warm caches ahead of traffic.
+func RefreshList[T any](
+	ctx context.Context,
+	rdb interface {
+		SetList(context.Context, string, []*T) error
+	},
+	prefix string,
+	params map[string]any,
+	fetcher ListGetter[T],
+) error {
 	list, err := fetcher(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return rdb.SetList(ctx, buildCacheKey(prefix, params), list)
+}
 
-	// Set cache
-	_ = rdb.SetList(ctx, key, list)
-	return list, nil
+// jitter spreads ttl by up to +10% so keys written together don't all
+// expire in the same instant.
+func jitter(ttl time.Duration) time.Duration {
+	return ttl + rand.N(ttl/10+1)
 }
 
 // buildCacheKey returns a deterministic key from params
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const maintenanceKey = "maintenance"
+
+// MaintenanceState is the persisted maintenance mode switch.
+type MaintenanceState struct {
+	Enabled   bool      `json:"enabled"`
+	Message   string    `json:"message,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy int64     `json:"updated_by"`
+}
+
+type MaintenanceStore struct {
+	rdb *redis.Client
+}
+
+// Get returns the stored state, or nil when maintenance was never toggled.
+func (s *MaintenanceStore) Get(ctx context.Context) (*MaintenanceState, error) {
+	data, err := s.rdb.Get(ctx, maintenanceKey).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state MaintenanceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Set persists the state without expiry so it survives restarts.
+func (s *MaintenanceStore) Set(ctx context.Context, state *MaintenanceState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, maintenanceKey, data, 0).Err()
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stats holds hit/miss/eviction/error counters per namespace (the key prefix
+// up to the first ':'), published under "cache" in /debug/vars.
+var stats = expvar.NewMap("cache")
+
+// NamespaceStats is a snapshot of the counters of one namespace.
+type NamespaceStats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Errors    int64 `json:"errors"`
+}
+
+// Stats returns the current counters keyed by namespace.
+func Stats() map[string]NamespaceStats {
+	out := map[string]NamespaceStats{}
+	stats.Do(func(kv expvar.KeyValue) {
+		m := kv.Value.(*expvar.Map)
+		out[kv.Key] = NamespaceStats{
+			Hits:      counter(m, "hits"),
+			Misses:    counter(m, "misses"),
+			Evictions: counter(m, "evictions"),
+			Errors:    counter(m, "errors"),
+		}
+	})
+	return out
+}
+
+func counter(m *expvar.Map, name string) int64 {
+	if v, ok := m.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func countKey(key, name string, delta int64) {
+	ns, _, _ := strings.Cut(key, ":")
+	m, ok := stats.Get(ns).(*expvar.Map)
+	if !ok {
+		m = new(expvar.Map).Init()
+		stats.Set(ns, m)
+	}
+	m.Add(name, delta)
+}
+
+// metricsHook is a redis.Hook feeding stats from the commands the cache
+// stores issue.
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		observe(cmd)
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			observe(cmd)
+		}
+		return err
+	}
+}
+
+func observe(cmd redis.Cmder) {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return
+	}
+	key, ok := args[1].(string)
+	if !ok {
+		return
+	}
+
+	err := cmd.Err()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		countKey(key, "errors", 1)
+		return
+	}
+
+	switch cmd.Name() {
+	case "get":
+		if err == nil {
+			countKey(key, "hits", 1)
+		} else {
+			countKey(key, "misses", 1)
+		}
+	case "exists":
+		if c, ok := cmd.(*redis.IntCmd); ok && c.Val() > 0 {
+			countKey(key, "hits", 1)
+		} else {
+			countKey(key, "misses", 1)
+		}
+	case "del", "unlink":
+		if c, ok := cmd.(*redis.IntCmd); ok {
+			countKey(key, "evictions", c.Val())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// missingTTL is kept short so a record created or restored under a
+// remembered ID becomes visible again quickly.
+const missingTTL = 30 * time.Second
+
+// MissingStore remembers IDs that recently resolved to "not found" so repeated
+// lookups of nonexistent records skip Postgres.
+type MissingStore struct {
+	rdb *redis.Client
+}
+
+func missingKey(entity string, id int64) string {
+	return fmt.Sprintf("missing:%s:%d", entity, id)
+}
+
+func (s *MissingStore) IsMissing(ctx context.Context, entity string, id int64) (bool, error) {
+	n, err := s.rdb.Exists(ctx, missingKey(entity, id)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *MissingStore) MarkMissing(ctx context.Context, entity string, id int64) error {
+	return s.rdb.SetEx(ctx, missingKey(entity, id), 1, missingTTL).Err()
+}
+
+func (s *MissingStore) Clear(ctx context.Context, entity string, id int64) error {
+	return s.rdb.Del(ctx, missingKey(entity, id)).Err()
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/MahdiiTaheri/classnama-backend/internal/store"
+)
+
+// NewNoopStorage returns a Storage that never caches: reads always miss and
+// writes are discarded. It is used when Redis is disabled.
+func NewNoopStorage() Storage {
+	return Storage{
+		Students:         noopStudentStore{},
+		Teachers:         noopEntityStore[store.Teacher]{},
+		Execs:            noopEntityStore[store.Exec]{},
+		Classrooms:       noopEntityStore[store.Classroom]{},
+		AttendanceTrends: noopEntityStore[store.AttendanceTrend]{},
+		Activity:         noopActivityStore{},
+		Maintenance:      noopMaintenanceStore{},
+		Missing:          noopMissingStore{},
+		Blocklist:        noopBlocklistStore{},
+		Admin:            noopAdminStore{},
+		OTP:              noopOTPStore{},
+		Quotas:           noopQuotaStore{},
+	}
+}
+
+type noopEntityStore[T any] struct{}
+
+func (noopEntityStore[T]) GetList(context.Context, string) ([]*T, error)  { return nil, nil }
+func (noopEntityStore[T]) SetList(context.Context, string, []*T) error    { return nil }
+func (noopEntityStore[T]) GetByID(context.Context, int64) (*T, error)     { return nil, nil }
+func (noopEntityStore[T]) SetByID(context.Context, int64, *T) error       { return nil }
+func (noopEntityStore[T]) InvalidatePrefix(context.Context, string) error { return nil }
+
+type noopStudentStore struct {
+	noopEntityStore[store.Student]
+}
+
+func (noopStudentStore) GetByTeacherID(context.Context, int64) ([]*store.StudentSummary, error) {
+	return nil, nil
+}
+
+func (noopStudentStore) SetByTeacherID(context.Context, int64, []*store.StudentSummary) error {
+	return nil
+}
+
+type noopActivityStore struct{}
+
+func (noopActivityStore) RecordView(context.Context, string, string, int64) error { return nil }
+func (noopActivityStore) Recent(context.Context, string, int) ([]EntityRef, error) {
+	return []EntityRef{}, nil
+}
+func (noopActivityStore) RecordEvent(context.Context, string, ActivityEvent) error { return nil }
+func (noopActivityStore) Feed(context.Context, string, int) ([]ActivityEvent, error) {
+	return []ActivityEvent{}, nil
+}
+
+type noopMaintenanceStore struct{}
+
+func (noopMaintenanceStore) Get(context.Context) (*MaintenanceState, error) { return nil, nil }
+func (noopMaintenanceStore) Set(context.Context, *MaintenanceState) error   { return nil }
+
+type noopBlocklistStore struct{}
+
+func (noopBlocklistStore) List(context.Context) ([]*BlockedNetwork, error) {
+	return nil, ErrUnavailable
+}
+func (noopBlocklistStore) Add(context.Context, *BlockedNetwork) error   { return ErrUnavailable }
+func (noopBlocklistStore) Remove(context.Context, string) (bool, error) { return false, ErrUnavailable }
+
+type noopMissingStore struct{}
+
+func (noopMissingStore) IsMissing(context.Context, string, int64) (bool, error) { return false, nil }
+func (noopMissingStore) MarkMissing(context.Context, string, int64) error       { return nil }
+func (noopMissingStore) Clear(context.Context, string, int64) error             { return nil }
+
+type noopAdminStore struct{}
+
+func (noopAdminStore) Keys(context.Context, string, int) ([]string, error) { return []string{}, nil }
+func (noopAdminStore) Flush(context.Context, string) (int64, error)        { return 0, nil }
+
+// noopOTPStore refuses to issue codes: without Redis there is nowhere to
+// keep them.
+type noopOTPStore struct{}
+
+func (noopOTPStore) Allow(context.Context, string) error         { return ErrUnavailable }
+func (noopOTPStore) Issue(context.Context, string, []byte) error { return ErrUnavailable }
+func (noopOTPStore) Check(context.Context, string, []byte) error { return ErrOTPInvalid }
+
+// noopQuotaStore counts nothing, so quotas are not enforced without Redis.
+type noopQuotaStore struct{}
+
+func (noopQuotaStore) Count(context.Context, int64, string, string) (QuotaUsage, error) {
+	return QuotaUsage{}, nil
+}
+func (noopQuotaStore) Usage(context.Context, int64, string, string) (QuotaUsage, error) {
+	return QuotaUsage{}, ErrUnavailable
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// otpTTL is how long a login code stays valid.
+	otpTTL = 5 * time.Minute
+	// otpResendAfter is the minimum gap between two codes for one phone.
+	otpResendAfter = time.Minute
+	// otpMaxSends caps the codes sent to one phone per otpSendWindow.
+	otpMaxSends   = 5
+	otpSendWindow = time.Hour
+	// otpMaxAttempts is how many wrong guesses burn a code.
+	otpMaxAttempts = 5
+)
+
+var (
+	ErrOTPTooSoon  = errors.New("a code was sent recently; wait a minute before asking again")
+	ErrOTPTooMany  = errors.New("too many codes requested; try again later")
+	ErrOTPInvalid  = errors.New("invalid or expired code")
+	ErrUnavailable = errors.New("cache: not available")
+)
+
+// OTPStore keeps hashed one-time login codes per phone number with resend,
+// send-count and attempt limits. Only hashes are stored.
+type OTPStore struct {
+	rdb *redis.Client
+}
+
+func otpKey(kind, phone string) string {
+	return "otp:" + kind + ":" + phone
+}
+
+// otpCheckScript counts a guess at the code in KEYS[1] and deletes the code
+// when ARGV[1] matches its hash or too many guesses were made, all in one
+// step so a code can't be used twice. It returns 1 for a match.
+var otpCheckScript = redis.NewScript(`
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+local stored = redis.call('HGET', KEYS[1], 'hash')
+if not stored or attempts > tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+if stored ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+// Allow counts a code request for phone, whether or not the number is
+// known, so the limits can't tell numbers on file apart. It returns
+// ErrOTPTooSoon or ErrOTPTooMany when the phone has asked too often.
+func (s *OTPStore) Allow(ctx context.Context, phone string) error {
+	ok, err := s.rdb.SetNX(ctx, otpKey("cooldown", phone), 1, otpResendAfter).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOTPTooSoon
+	}
+
+	sendsKey := otpKey("sends", phone)
+	sends, err := s.rdb.Incr(ctx, sendsKey).Result()
+	if err != nil {
+		return err
+	}
+	if sends == 1 {
+		s.rdb.Expire(ctx, sendsKey, otpSendWindow)
+	}
+	if sends > otpMaxSends {
+		return ErrOTPTooMany
+	}
+	return nil
+}
+
+// Issue stores the hash of a new code for phone, replacing any earlier
+// one. Callers check Allow first.
+func (s *OTPStore) Issue(ctx context.Context, phone string, hash []byte) error {
+	codeKey := otpKey("code", phone)
+	_, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, codeKey)
+		p.HSet(ctx, codeKey, "hash", hash, "attempts", 0)
+		p.Expire(ctx, codeKey, otpTTL)
+		return nil
+	})
+	return err
+}
+
+// Check consumes the code for phone when hash matches. Every wrong guess
+// counts; after otpMaxAttempts the code is dropped. It returns
+// ErrOTPInvalid for a wrong, expired or burned code. Two requests with the
+// right code can't both succeed: the check and delete are one script.
+func (s *OTPStore) Check(ctx context.Context, phone string, hash []byte) error {
+	ok, err := otpCheckScript.Run(ctx, s.rdb, []string{otpKey("code", phone)}, hash, otpMaxAttempts).Int()
+	if err != nil {
+		return err
+	}
+	if ok != 1 {
+		return ErrOTPInvalid
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// quotaDayTTL and quotaMonthTTL outlive the period a counter is for,
+	// whatever the time zone.
+	quotaDayTTL   = 48 * time.Hour
+	quotaMonthTTL = 32 * 24 * time.Hour
+)
+
+// QuotaUsage is how many requests an API key made in the current day and
+// month.
+type QuotaUsage struct {
+	Day   int64 `json:"day"`
+	Month int64 `json:"month"`
+}
+
+// QuotaStore counts the requests made with each API key, per day and per
+// month, in counters named after the period so they start over by
+// themselves.
+type QuotaStore struct {
+	rdb *redis.Client
+}
+
+func quotaKey(keyID int64, period string) string {
+	return fmt.Sprintf("quota:%d:%s", keyID, period)
+}
+
+// Count adds a request to the key's day and month counters and returns the
+// new totals.
+func (s *QuotaStore) Count(ctx context.Context, keyID int64, day, month string) (QuotaUsage, error) {
+	dayKey, monthKey := quotaKey(keyID, day), quotaKey(keyID, month)
+
+	var dayN, monthN *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		dayN = p.Incr(ctx, dayKey)
+		p.Expire(ctx, dayKey, quotaDayTTL)
+		monthN = p.Incr(ctx, monthKey)
+		p.Expire(ctx, monthKey, quotaMonthTTL)
+		return nil
+	})
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	return QuotaUsage{Day: dayN.Val(), Month: monthN.Val()}, nil
+}
+
+// Usage returns the key's counters without counting a request.
+func (s *QuotaStore) Usage(ctx context.Context, keyID int64, day, month string) (QuotaUsage, error) {
+	var u QuotaUsage
+	for _, c := range []struct {
+		key string
+		dst *int64
+	}{
+		{quotaKey(keyID, day), &u.Day},
+		{quotaKey(keyID, month), &u.Month},
+	} {
+		n, err := s.rdb.Get(ctx, c.key).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return QuotaUsage{}, err
+		}
+		*c.dst = n
+	}
+	return u, nil
+}
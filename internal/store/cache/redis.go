@@ -1,11 +1,28 @@
 package cache
 
-import "github.com/redis/go-redis/v9"
+import (
+	"time"
 
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+// NewRedisClient returns a client with short timeouts and a circuit breaker,
+// so an unreachable Redis degrades to cache misses instead of slow requests.
 func NewRedisClient(addr, pw string, db int) *redis.Client {
-	return redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: pw,
-		DB:       db,
+	rdb := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     pw,
+		DB:           db,
+		DialTimeout:  time.Second,
+		ReadTimeout:  500 * time.Millisecond,
+		WriteTimeout: 500 * time.Millisecond,
 	})
+	rdb.AddHook(metricsHook{})
+	rdb.AddHook(newCircuitBreaker(breakerThreshold, breakerCooldown))
+	return rdb
 }
@@ -9,25 +9,72 @@ import (
 
 type Storage struct {
 	Students interface {
-		GetList(context.Context, string) ([]*store.Student, error)
-		SetList(context.Context, string, []*store.Student) error
-		GetByTeacherID(context.Context, int64) ([]*store.Student, error)
-		SetByTeacherID(context.Context, int64, []*store.Student) error
+		EntityCache[store.Student]
+		GetByTeacherID(context.Context, int64) ([]*store.StudentSummary, error)
+		SetByTeacherID(context.Context, int64, []*store.StudentSummary) error
 	}
-	Teachers interface {
-		GetList(context.Context, string) ([]*store.Teacher, error)
-		SetList(context.Context, string, []*store.Teacher) error
+	Teachers         EntityCache[store.Teacher]
+	Execs            EntityCache[store.Exec]
+	Classrooms       EntityCache[store.Classroom]
+	AttendanceTrends EntityCache[store.AttendanceTrend]
+	Activity         interface {
+		RecordView(context.Context, string, string, int64) error
+		Recent(context.Context, string, int) ([]EntityRef, error)
+		RecordEvent(context.Context, string, ActivityEvent) error
+		Feed(context.Context, string, int) ([]ActivityEvent, error)
 	}
-	Execs interface {
-		GetList(context.Context, string) ([]*store.Exec, error)
-		SetList(context.Context, string, []*store.Exec) error
+	Maintenance interface {
+		Get(context.Context) (*MaintenanceState, error)
+		Set(context.Context, *MaintenanceState) error
+	}
+	Blocklist interface {
+		List(context.Context) ([]*BlockedNetwork, error)
+		Add(context.Context, *BlockedNetwork) error
+		Remove(context.Context, string) (bool, error)
+	}
+	Missing interface {
+		IsMissing(context.Context, string, int64) (bool, error)
+		MarkMissing(context.Context, string, int64) error
+		Clear(context.Context, string, int64) error
+	}
+	Admin interface {
+		Keys(context.Context, string, int) ([]string, error)
+		Flush(context.Context, string) (int64, error)
+	}
+	OTP interface {
+		Allow(ctx context.Context, phone string) error
+		Issue(ctx context.Context, phone string, hash []byte) error
+		Check(ctx context.Context, phone string, hash []byte) error
+	}
+	Quotas interface {
+		Count(ctx context.Context, keyID int64, day, month string) (QuotaUsage, error)
+		Usage(ctx context.Context, keyID int64, day, month string) (QuotaUsage, error)
 	}
 }
 
-func NewRedisStorage(rdb *redis.Client) Storage {
+// NewRedisStorage builds the cache stores on top of rdb, encoding entities
+// with codec. A nil client (Redis disabled) yields the no-op storage, so
+// callers never need to check.
+func NewRedisStorage(rdb *redis.Client, codec Codec) Storage {
+	if rdb == nil {
+		return NewNoopStorage()
+	}
+
 	return Storage{
-		Students: &StudentStore{rdb: rdb},
-		Teachers: &TeacherStore{rdb: rdb},
-		Execs:    &ExecStore{rdb: rdb},
+		Students: &StudentStore{
+			CodecStore: NewCodecStore[store.Student](rdb, codec, "students", defaultListTTL),
+			rosters:    NewCodecStore[store.StudentSummary](rdb, codec, "students", defaultListTTL),
+		},
+		Teachers:         NewCodecStore[store.Teacher](rdb, codec, "teachers", defaultListTTL),
+		Execs:            NewCodecStore[store.Exec](rdb, codec, "execs", defaultListTTL),
+		Classrooms:       NewCodecStore[store.Classroom](rdb, codec, "classrooms", defaultListTTL),
+		AttendanceTrends: NewCodecStore[store.AttendanceTrend](rdb, codec, "analytics", analyticsTTL),
+		Activity:         &ActivityStore{rdb: rdb},
+		Maintenance:      &MaintenanceStore{rdb: rdb},
+		Missing:          &MissingStore{rdb: rdb},
+		Blocklist:        &BlocklistStore{rdb: rdb},
+		Admin:            &AdminStore{rdb: rdb},
+		OTP:              &OTPStore{rdb: rdb},
+		Quotas:           &QuotaStore{rdb: rdb},
 	}
 }
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SchoolWeekdays are the ISO weekdays (Monday = 1 … Sunday = 7) classes are
+// normally held on. It is set from configuration at startup.
+var SchoolWeekdays = []int{1, 2, 3, 4, 5}
+
+const (
+	CalendarHoliday   = "holiday"
+	CalendarClosure   = "closure"
+	CalendarSchoolDay = "school_day"
+)
+
+// CalendarDay is a dated exception to SchoolWeekdays.
+type CalendarDay struct {
+	Date      string    `json:"date" validate:"required,datetime=2006-01-02"`
+	Kind      string    `json:"kind" validate:"required,oneof=holiday closure school_day"`
+	Name      string    `json:"name" validate:"max=128"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// schoolDaySQL is a predicate that holds when the date expression col is a
+// school day: a school weekday or an added school_day, and not a holiday or
+// closure.
+func schoolDaySQL(col string) string {
+	days := make([]string, len(SchoolWeekdays))
+	for i, d := range SchoolWeekdays {
+		days[i] = fmt.Sprint(d)
+	}
+	return fmt.Sprintf(`(
+		(EXTRACT(ISODOW FROM %[1]s)::int = ANY('{%[2]s}'::int[])
+			OR EXISTS (SELECT 1 FROM school_calendar sc WHERE sc.date = %[1]s AND sc.kind = 'school_day'))
+		AND NOT EXISTS (SELECT 1 FROM school_calendar sc WHERE sc.date = %[1]s AND sc.kind IN ('holiday', 'closure'))
+	)`, col, strings.Join(days, ","))
+}
+
+type SchoolDayStore struct {
+	db *routedDB
+}
+
+// List returns the calendar entries between from and to (inclusive).
+func (s *SchoolDayStore) List(ctx context.Context, from, to time.Time) ([]*CalendarDay, error) {
+	query := `
+		SELECT date, kind, name, created_at, updated_at
+		FROM school_calendar
+		WHERE date BETWEEN $1::date AND $2::date
+		ORDER BY date
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*CalendarDay{}
+	for rows.Next() {
+		var d CalendarDay
+		var date time.Time
+		if err := rows.Scan(&date, &d.Kind, &d.Name, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		d.Date = date.Format(time.DateOnly)
+		days = append(days, &d)
+	}
+
+	return days, rows.Err()
+}
+
+// Upsert creates or replaces calendar entries in one transaction and returns
+// how many were written.
+func (s *SchoolDayStore) Upsert(ctx context.Context, days []*CalendarDay) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := s.db.prepare(ctx, tx, `
+		INSERT INTO school_calendar (date, kind, name)
+		VALUES ($1::date, $2, $3)
+		ON CONFLICT (date) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			name = EXCLUDED.name,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, d := range days {
+		if err := stmt.QueryRowContext(ctx, d.Date, d.Kind, d.Name).Scan(&d.CreatedAt, &d.UpdatedAt); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(days)), tx.Commit()
+}
+
+func (s *SchoolDayStore) Delete(ctx context.Context, date time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM school_calendar WHERE date = $1::date`, date.Format(time.DateOnly))
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// IsSchoolDay reports whether classes are held on date. When a calendar
+// entry decides it, the entry is returned as well.
+func (s *SchoolDayStore) IsSchoolDay(ctx context.Context, date time.Time) (bool, *CalendarDay, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var d CalendarDay
+	var on time.Time
+	err := s.db.QueryRowContext(ctx,
+		`SELECT date, kind, name, created_at, updated_at FROM school_calendar WHERE date = $1::date`,
+		date.Format(time.DateOnly),
+	).Scan(&on, &d.Kind, &d.Name, &d.CreatedAt, &d.UpdatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		iso := int(date.Weekday())
+		if iso == 0 {
+			iso = 7
+		}
+		for _, wd := range SchoolWeekdays {
+			if wd == iso {
+				return true, nil, nil
+			}
+		}
+		return false, nil, nil
+	case err != nil:
+		return false, nil, err
+	}
+
+	d.Date = on.Format(time.DateOnly)
+	return d.Kind == CalendarSchoolDay, &d, nil
+}
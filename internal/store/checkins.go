@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CheckIn is a check-in from a student's or teacher's phone, accepted
+// inside the school's geofence. Distance is in meters from the school.
+type CheckIn struct {
+	ID           int64     `json:"id"`
+	UserRole     string    `json:"user_role"`
+	UserID       int64     `json:"user_id"`
+	UserName     string    `json:"user_name,omitempty"`
+	Date         time.Time `json:"date"`
+	CheckedInAt  time.Time `json:"checked_in_at"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	Accuracy     *float64  `json:"accuracy,omitempty"`
+	Distance     float64   `json:"distance"`
+	AttendanceID *int64    `json:"attendance_id,omitempty"`
+	// Status is the student's attendance status for the day after the
+	// check-in.
+	Status string `json:"status,omitempty"`
+}
+
+type CheckInStore struct {
+	db *routedDB
+}
+
+// Record saves a check-in. For students it also creates the day's
+// attendance record with status, or, when a record already exists, keeps
+// its status and only annotates it with the check-in. It returns
+// ErrConflict when the user already checked in that day and ErrNotFound
+// when the student does not exist.
+func (s *CheckInStore) Record(ctx context.Context, c *CheckIn, status string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	date := c.Date.Format(time.DateOnly)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO mobile_checkins (user_role, user_id, date, latitude, longitude, accuracy, distance)
+		VALUES ($1, $2, $3::date, $4, $5, $6, $7)
+		ON CONFLICT (user_role, user_id, date) DO NOTHING
+		RETURNING id, checked_in_at
+	`, c.UserRole, c.UserID, date, c.Latitude, c.Longitude, c.Accuracy, c.Distance).Scan(&c.ID, &c.CheckedInAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	if c.UserRole == "student" {
+		var attendanceID int64
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO attendance_records (student_id, classroom_id, date, status, method, checked_in_at)
+			SELECT s.id, s.classroom_id, $2::date, $3, 'geofence', $4
+			FROM students s
+			WHERE s.id = $1 AND s.deleted_at IS NULL
+			ON CONFLICT (student_id, date) DO UPDATE
+			SET method = EXCLUDED.method, checked_in_at = EXCLUDED.checked_in_at
+			RETURNING id, status
+		`, c.UserID, date, status, c.CheckedInAt).Scan(&attendanceID, &c.Status)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		c.AttendanceID = &attendanceID
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE mobile_checkins SET attendance_id = $2 WHERE id = $1`, c.ID, attendanceID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ForDate returns the day's check-ins, earliest first, optionally limited
+// to one role.
+func (s *CheckInStore) ForDate(ctx context.Context, date time.Time, role string) ([]*CheckIn, error) {
+	query := `
+		SELECT m.id, m.user_role, m.user_id,
+			COALESCE(CASE m.user_role
+				WHEN 'student' THEN (SELECT first_name || ' ' || last_name FROM students WHERE id = m.user_id)
+				WHEN 'teacher' THEN (SELECT first_name || ' ' || last_name FROM teachers WHERE id = m.user_id)
+			END, ''),
+			m.date, m.checked_in_at, m.latitude, m.longitude, m.accuracy, m.distance, m.attendance_id,
+			COALESCE(a.status, '')
+		FROM mobile_checkins m
+		LEFT JOIN attendance_records a ON a.id = m.attendance_id AND a.date = m.date
+		WHERE m.date = $1::date AND ($2 = '' OR m.user_role = $2)
+		ORDER BY m.checked_in_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, date.Format(time.DateOnly), role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkIns := []*CheckIn{}
+	for rows.Next() {
+		var c CheckIn
+		if err := rows.Scan(&c.ID, &c.UserRole, &c.UserID, &c.UserName, &c.Date, &c.CheckedInAt,
+			&c.Latitude, &c.Longitude, &c.Accuracy, &c.Distance, &c.AttendanceID, &c.Status); err != nil {
+			return nil, err
+		}
+		checkIns = append(checkIns, &c)
+	}
+
+	return checkIns, rows.Err()
+}
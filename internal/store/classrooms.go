@@ -7,52 +7,131 @@ import (
 	"time"
 )
 
+// Classroom modes.
+const (
+	ModeInPerson = "in_person"
+	ModeOnline   = "online"
+	ModeHybrid   = "hybrid"
+)
+
 type Classroom struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Capacity  int64     `json:"capacity"`
-	Grade     int64     `json:"grade"`
-	TeacherID int64     `json:"teacher_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// NameI18n holds translations of Name; Name is used when none matches.
+	NameI18n  LocalizedText `json:"name_i18n"`
+	Capacity  int64         `json:"capacity"`
+	Grade     int64         `json:"grade"`
+	TeacherID int64         `json:"teacher_id"`
+	// Mode is in_person, online or hybrid; online and hybrid classes meet at
+	// MeetingURL.
+	Mode       string `json:"mode"`
+	MeetingURL string `json:"meeting_url,omitempty"`
+	// StudentCount is the number of live students; only GetAll fills it.
+	StudentCount *int64    `json:"student_count,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// columns maps list columns to the fields they scan into.
+func (c *Classroom) columns() map[string]any {
+	return map[string]any{
+		"id":            &c.ID,
+		"name":          &c.Name,
+		"name_i18n":     &c.NameI18n,
+		"capacity":      &c.Capacity,
+		"grade":         &c.Grade,
+		"teacher_id":    &c.TeacherID,
+		"mode":          &c.Mode,
+		"meeting_url":   &c.MeetingURL,
+		"student_count": &c.StudentCount,
+		"created_at":    &c.CreatedAt,
+		"updated_at":    &c.UpdatedAt,
+	}
+}
+
+// studentCountColumn computes the student_count list column.
+const studentCountColumn = `(SELECT COUNT(*) FROM students s WHERE s.classroom_id = classrooms.id AND s.deleted_at IS NULL) AS student_count`
+
+// ClassroomDetail is everything the classroom page shows.
+type ClassroomDetail struct {
+	*Classroom
+	Teacher  *ClassroomTeacher  `json:"teacher"`
+	Students []*ClassroomMember `json:"students"`
+	// Today counts today's attendance of the students listed.
+	Today           AttendanceSummary `json:"today"`
+	UpcomingLessons []*Booking        `json:"upcoming_lessons"`
+}
+
+// ClassroomTeacher is the teacher as the classroom page shows them.
+type ClassroomTeacher struct {
+	ID          int64  `json:"id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	Subject     string `json:"subject"`
+}
+
+// ClassroomMember is a student on the classroom page with today's
+// attendance status, nil when not marked yet.
+type ClassroomMember struct {
+	ID          int64   `json:"id"`
+	FirstName   string  `json:"first_name"`
+	LastName    string  `json:"last_name"`
+	StudentCode string  `json:"student_code"`
+	StatusToday *string `json:"status_today"`
+}
+
+// AttendanceSummary counts a day's attendance by status.
+type AttendanceSummary struct {
+	Date     string `json:"date"`
+	Students int    `json:"students"`
+	Marked   int    `json:"marked"`
+	Present  int    `json:"present"`
+	Absent   int    `json:"absent"`
+	Late     int    `json:"late"`
+	Excused  int    `json:"excused"`
 }
 
 type ClassroomStore interface {
 	Create(ctx context.Context, classroom *Classroom) error
 	GetByID(ctx context.Context, id int64) (*Classroom, error)
 	GetAll(ctx context.Context, pq PaginatedQuery) ([]*Classroom, error)
+	GetByTeacherID(ctx context.Context, teacherID int64) ([]*Classroom, error)
 	Update(ctx context.Context, classroom *Classroom) error
-	Delete(ctx context.Context, id int64) error
+	UpdatePartial(ctx context.Context, id int64, fields map[string]any) error
+	Delete(ctx context.Context, id int64, deletedBy int64) error
+	Detail(ctx context.Context, classroom *Classroom, today time.Time, lessons int) (*ClassroomDetail, error)
 }
 
 type classroomStore struct {
-	db *sql.DB
+	db *routedDB
 }
 
 func NewClassroomStore(db *sql.DB) ClassroomStore {
-	return &classroomStore{db: db}
+	return &classroomStore{db: newRoutedDB(Pools{API: db})}
 }
 
 func (s *classroomStore) Create(ctx context.Context, classroom *Classroom) error {
 	query := `
-		INSERT INTO classrooms (name, capacity, grade, teacher_id)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at
+		INSERT INTO classrooms (name, name_i18n, capacity, grade, teacher_id, mode, meeting_url)
+		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), 'in_person'), $7)
+		RETURNING id, mode, created_at, updated_at
 	`
-	return s.db.QueryRowContext(ctx, query, classroom.Name, classroom.Capacity, classroom.Grade, classroom.TeacherID).
-		Scan(&classroom.ID, &classroom.CreatedAt, &classroom.UpdatedAt)
+	return s.db.QueryRowContext(ctx, query, classroom.Name, classroom.NameI18n, classroom.Capacity, classroom.Grade, classroom.TeacherID, classroom.Mode, classroom.MeetingURL).
+		Scan(&classroom.ID, &classroom.Mode, &classroom.CreatedAt, &classroom.UpdatedAt)
 }
 
 func (s *classroomStore) GetByID(ctx context.Context, id int64) (*Classroom, error) {
 	query := `
-		SELECT id, name, capacity, grade, teacher_id, created_at, updated_at
+		SELECT id, name, name_i18n, capacity, grade, teacher_id, mode, meeting_url, created_at, updated_at
 		FROM classrooms
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	row := s.db.QueryRowContext(ctx, query, id)
 
 	var c Classroom
-	err := row.Scan(&c.ID, &c.Name, &c.Capacity, &c.Grade, &c.TeacherID, &c.CreatedAt, &c.UpdatedAt)
+	err := row.Scan(&c.ID, &c.Name, &c.NameI18n, &c.Capacity, &c.Grade, &c.TeacherID, &c.Mode, &c.MeetingURL, &c.CreatedAt, &c.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
@@ -63,12 +142,29 @@ func (s *classroomStore) GetByID(ctx context.Context, id int64) (*Classroom, err
 }
 
 func (s *classroomStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Classroom, error) {
-	columns := []string{"id", "name", "capacity", "grade", "created_at", "updated_at", "teacher_id"}
+	columns := []string{"id", "name", "name_i18n", "capacity", "grade", "teacher_id", "mode", "meeting_url", "student_count", "created_at", "updated_at"}
 	searchCols := []string{"name"}
+	sortable := []string{"id", "name", "capacity", "grade", "teacher_id", "mode", "created_at", "updated_at"}
+
+	columns, err := selectColumns(columns, pq.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = c
+		if c == "student_count" {
+			exprs[i] = studentCountColumn
+		}
+	}
 
-	query, args := BuildPaginatedQuery("classrooms", columns, pq, searchCols)
+	query, args, err := BuildPaginatedQuery("classrooms", exprs, pq, searchCols, sortable, notDeleted)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -80,14 +176,7 @@ func (s *classroomStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Clas
 	classrooms := []*Classroom{}
 	for rows.Next() {
 		var c Classroom
-		if err := rows.Scan(
-			&c.ID,
-			&c.Name,
-			&c.Capacity,
-			&c.Grade,
-			&c.CreatedAt,
-			&c.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(scanTargets(c.columns(), columns)...); err != nil {
 			return nil, err
 		}
 		classrooms = append(classrooms, &c)
@@ -100,16 +189,46 @@ func (s *classroomStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Clas
 	return classrooms, nil
 }
 
+// GetByTeacherID returns the live classrooms taught by a teacher.
+func (s *classroomStore) GetByTeacherID(ctx context.Context, teacherID int64) ([]*Classroom, error) {
+	query := `
+		SELECT id, name, name_i18n, capacity, grade, teacher_id, mode, meeting_url, created_at, updated_at
+		FROM classrooms
+		WHERE teacher_id = $1 AND deleted_at IS NULL
+		ORDER BY id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classrooms := []*Classroom{}
+	for rows.Next() {
+		var c Classroom
+		if err := rows.Scan(&c.ID, &c.Name, &c.NameI18n, &c.Capacity, &c.Grade, &c.TeacherID, &c.Mode, &c.MeetingURL, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		classrooms = append(classrooms, &c)
+	}
+
+	return classrooms, rows.Err()
+}
+
 func (s *classroomStore) Update(ctx context.Context, classroom *Classroom) error {
 	query := `
 		UPDATE classrooms
-		SET name = $1, capacity = $2, grade = $3,teacher_id = $4 , updated_at = NOW()
-		WHERE id = $5
+		SET name = $1, capacity = $2, grade = $3,teacher_id = $4 , name_i18n = $6, mode = $7, meeting_url = $8, updated_at = NOW()
+		WHERE id = $5 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 
 	err := s.db.QueryRowContext(ctx, query,
-		classroom.Name, classroom.Capacity, classroom.Grade, classroom.TeacherID, classroom.ID,
+		classroom.Name, classroom.Capacity, classroom.Grade, classroom.TeacherID, classroom.ID, classroom.NameI18n, classroom.Mode, classroom.MeetingURL,
 	).Scan(&classroom.UpdatedAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -118,9 +237,17 @@ func (s *classroomStore) Update(ctx context.Context, classroom *Classroom) error
 	return err
 }
 
-func (s *classroomStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM classrooms WHERE id = $1`
-	res, err := s.db.ExecContext(ctx, query, id)
+func (s *classroomStore) Delete(ctx context.Context, id int64, deletedBy int64) error {
+	query := `
+		UPDATE classrooms
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id, deletedBy)
 	if err != nil {
 		return err
 	}
@@ -134,3 +261,90 @@ func (s *classroomStore) Delete(ctx context.Context, id int64) error {
 	}
 	return nil
 }
+
+// Detail gathers a classroom's teacher, its live students with their
+// attendance on today, and its next lessons — the upcoming bookings made
+// for it — in three queries.
+func (s *classroomStore) Detail(ctx context.Context, classroom *Classroom, today time.Time, lessons int) (*ClassroomDetail, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	d := &ClassroomDetail{
+		Classroom:       classroom,
+		Students:        []*ClassroomMember{},
+		Today:           AttendanceSummary{Date: today.Format(time.DateOnly)},
+		UpcomingLessons: []*Booking{},
+	}
+
+	var t ClassroomTeacher
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, first_name, last_name, email, phone_number, subject
+		FROM teachers
+		WHERE id = $1 AND deleted_at IS NULL
+	`, classroom.TeacherID).Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.PhoneNumber, &t.Subject)
+	switch {
+	case err == nil:
+		d.Teacher = &t
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id, s.first_name, s.last_name, s.student_code, a.status
+		FROM students s
+		LEFT JOIN attendance_records a ON a.student_id = s.id AND a.date = $2::date
+		WHERE s.classroom_id = $1 AND s.deleted_at IS NULL
+		ORDER BY s.last_name, s.first_name, s.id
+	`, classroom.ID, d.Today.Date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m ClassroomMember
+		if err := rows.Scan(&m.ID, &m.FirstName, &m.LastName, &m.StudentCode, &m.StatusToday); err != nil {
+			return nil, err
+		}
+		d.Students = append(d.Students, &m)
+		d.Today.Students++
+		if m.StatusToday == nil {
+			continue
+		}
+		d.Today.Marked++
+		switch *m.StatusToday {
+		case "present":
+			d.Today.Present++
+		case "absent":
+			d.Today.Absent++
+		case "late":
+			d.Today.Late++
+		case "excused":
+			d.Today.Excused++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT `+bookingColumns+`
+		FROM bookings b
+		WHERE b.classroom_id = $1 AND b.cancelled_at IS NULL AND b.ends_at > NOW()
+		ORDER BY b.starts_at
+		LIMIT $2
+	`, classroom.ID, lessons)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b, err := scanBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		d.UpcomingLessons = append(d.UpcomingLessons, b)
+	}
+	return d, rows.Err()
+}
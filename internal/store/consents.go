@@ -0,0 +1,360 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Consent statuses.
+const (
+	ConsentPending  = "pending"
+	ConsentApproved = "approved"
+	ConsentDeclined = "declined"
+)
+
+// ConsentRequest asks the parents of a set of students for consent, e.g.
+// for a field trip or photo usage.
+type ConsentRequest struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Kind        string    `json:"kind"`
+	DueDate     time.Time `json:"due_date"`
+	CreatedBy   int64     `json:"created_by"`
+	Pending     int64     `json:"pending"`
+	Approved    int64     `json:"approved"`
+	Declined    int64     `json:"declined"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ConsentAudience selects the students a request goes to. With neither set
+// it goes to every student.
+type ConsentAudience struct {
+	ClassroomID *int64 `json:"classroom_id,omitempty" validate:"omitempty,min=1"`
+	Grade       *int64 `json:"grade,omitempty" validate:"omitempty,min=1,max=30"`
+}
+
+// StudentConsent is one student's consent for a request.
+type StudentConsent struct {
+	ID            int64      `json:"id"`
+	RequestID     int64      `json:"request_id"`
+	StudentID     int64      `json:"student_id"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	ClassroomID   *int64     `json:"classroom_id"`
+	ParentName    string     `json:"parent_name"`
+	ParentPhone   string     `json:"-"`
+	Status        string     `json:"status"`
+	ResponderName string     `json:"responder_name"`
+	ResponderIP   string     `json:"responder_ip"`
+	RespondedAt   *time.Time `json:"responded_at"`
+}
+
+// ConsentMatrix is the consent status of a classroom's students (rows) for
+// every request any of them was asked (columns).
+type ConsentMatrix struct {
+	ClassroomID int64               `json:"classroom_id"`
+	Requests    []*ConsentRequest   `json:"requests"`
+	Students    []*ConsentMatrixRow `json:"students"`
+}
+
+type ConsentMatrixRow struct {
+	StudentID int64  `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	// Statuses maps request IDs to the student's status; requests the
+	// student wasn't asked are absent.
+	Statuses map[int64]string `json:"statuses"`
+}
+
+type ConsentStore struct {
+	db *routedDB
+}
+
+const consentRequestColumns = `
+	cr.id, cr.title, cr.description, cr.kind, cr.due_date, cr.created_by,
+	COUNT(c.id) FILTER (WHERE c.status = 'pending'),
+	COUNT(c.id) FILTER (WHERE c.status = 'approved'),
+	COUNT(c.id) FILTER (WHERE c.status = 'declined'),
+	cr.created_at`
+
+func scanConsentRequest(row interface{ Scan(...any) error }) (*ConsentRequest, error) {
+	var cr ConsentRequest
+	err := row.Scan(&cr.ID, &cr.Title, &cr.Description, &cr.Kind, &cr.DueDate, &cr.CreatedBy,
+		&cr.Pending, &cr.Approved, &cr.Declined, &cr.CreatedAt)
+	return &cr, err
+}
+
+// CreateRequest stores a request and a pending consent for every live
+// student in the audience, returning those consents.
+func (s *ConsentStore) CreateRequest(ctx context.Context, cr *ConsentRequest, audience ConsentAudience) ([]*StudentConsent, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO consent_requests (title, description, kind, due_date, created_by)
+		VALUES ($1, $2, $3, $4::date, $5)
+		RETURNING id, created_at
+	`, cr.Title, cr.Description, cr.Kind, cr.DueDate.Format(time.DateOnly), cr.CreatedBy).Scan(&cr.ID, &cr.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH asked AS (
+			INSERT INTO consents (request_id, student_id)
+			SELECT $1, s.id
+			FROM students s
+			LEFT JOIN classrooms c ON c.id = s.classroom_id
+			WHERE s.deleted_at IS NULL
+			  AND ($2::bigint IS NULL OR s.classroom_id = $2)
+			  AND ($3::bigint IS NULL OR c.grade = $3)
+			RETURNING id, student_id, status
+		)
+		SELECT a.id, a.student_id, s.first_name, s.last_name, s.classroom_id, s.parent_name, s.parent_phone_number, a.status
+		FROM asked a
+		JOIN students s ON s.id = a.student_id
+		ORDER BY s.id
+	`, cr.ID, audience.ClassroomID, audience.Grade)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*StudentConsent{}
+	for rows.Next() {
+		c := &StudentConsent{RequestID: cr.ID}
+		if err := rows.Scan(&c.ID, &c.StudentID, &c.FirstName, &c.LastName, &c.ClassroomID,
+			&c.ParentName, &c.ParentPhone, &c.Status); err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	cr.Pending = int64(len(consents))
+	return consents, nil
+}
+
+// ListRequests returns requests newest first with their answer counts.
+func (s *ConsentStore) ListRequests(ctx context.Context) ([]*ConsentRequest, error) {
+	query := `
+		SELECT ` + consentRequestColumns + `
+		FROM consent_requests cr
+		LEFT JOIN consents c ON c.request_id = cr.id
+		GROUP BY cr.id
+		ORDER BY cr.created_at DESC, cr.id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []*ConsentRequest{}
+	for rows.Next() {
+		cr, err := scanConsentRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, cr)
+	}
+
+	return requests, rows.Err()
+}
+
+func (s *ConsentStore) GetRequest(ctx context.Context, id int64) (*ConsentRequest, error) {
+	query := `
+		SELECT ` + consentRequestColumns + `
+		FROM consent_requests cr
+		LEFT JOIN consents c ON c.request_id = cr.id
+		WHERE cr.id = $1
+		GROUP BY cr.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	cr, err := scanConsentRequest(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return cr, nil
+}
+
+// DeleteRequest removes a request with its consents.
+func (s *ConsentStore) DeleteRequest(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM consent_requests WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+const studentConsentQuery = `
+	SELECT c.id, c.request_id, c.student_id, s.first_name, s.last_name, s.classroom_id,
+	       s.parent_name, s.parent_phone_number, c.status, c.responder_name, c.responder_ip, c.responded_at
+	FROM consents c
+	JOIN students s ON s.id = c.student_id
+`
+
+func scanStudentConsent(row interface{ Scan(...any) error }) (*StudentConsent, error) {
+	var c StudentConsent
+	err := row.Scan(&c.ID, &c.RequestID, &c.StudentID, &c.FirstName, &c.LastName, &c.ClassroomID,
+		&c.ParentName, &c.ParentPhone, &c.Status, &c.ResponderName, &c.ResponderIP, &c.RespondedAt)
+	return &c, err
+}
+
+// ForRequest returns the consents of a request by student.
+func (s *ConsentStore) ForRequest(ctx context.Context, requestID int64) ([]*StudentConsent, error) {
+	query := studentConsentQuery + `
+		WHERE c.request_id = $1 AND s.deleted_at IS NULL
+		ORDER BY s.last_name, s.first_name, s.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*StudentConsent{}
+	for rows.Next() {
+		c, err := scanStudentConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+
+	return consents, rows.Err()
+}
+
+func (s *ConsentStore) GetConsent(ctx context.Context, id int64) (*StudentConsent, error) {
+	query := studentConsentQuery + `WHERE c.id = $1 AND s.deleted_at IS NULL`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	c, err := scanStudentConsent(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Respond records a parent's answer. Parents may change their answer until
+// the handler stops accepting them.
+func (s *ConsentStore) Respond(ctx context.Context, c *StudentConsent) error {
+	query := `
+		UPDATE consents
+		SET status = $2, responder_name = $3, responder_ip = $4, responded_at = NOW()
+		WHERE id = $1
+		RETURNING responded_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, c.ID, c.Status, c.ResponderName, c.ResponderIP).Scan(&c.RespondedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Matrix returns the consent matrix of a classroom's current students.
+func (s *ConsentStore) Matrix(ctx context.Context, classroomID int64) (*ConsentMatrix, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	m := &ConsentMatrix{ClassroomID: classroomID, Requests: []*ConsentRequest{}, Students: []*ConsentMatrixRow{}}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+consentRequestColumns+`
+		FROM consent_requests cr
+		JOIN consents c ON c.request_id = cr.id
+		JOIN students s ON s.id = c.student_id AND s.deleted_at IS NULL
+		WHERE s.classroom_id = $1
+		GROUP BY cr.id
+		ORDER BY cr.due_date DESC, cr.id DESC
+	`, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		cr, err := scanConsentRequest(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.Requests = append(m.Requests, cr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT s.id, s.first_name, s.last_name, c.request_id, c.status
+		FROM students s
+		LEFT JOIN consents c ON c.student_id = s.id
+		WHERE s.classroom_id = $1 AND s.deleted_at IS NULL
+		ORDER BY s.last_name, s.first_name, s.id
+	`, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var current *ConsentMatrixRow
+	for rows.Next() {
+		var (
+			studentID           int64
+			firstName, lastName string
+			requestID           sql.NullInt64
+			status              sql.NullString
+		)
+		if err := rows.Scan(&studentID, &firstName, &lastName, &requestID, &status); err != nil {
+			return nil, err
+		}
+		if current == nil || current.StudentID != studentID {
+			current = &ConsentMatrixRow{StudentID: studentID, FirstName: firstName, LastName: lastName, Statuses: map[int64]string{}}
+			m.Students = append(m.Students, current)
+		}
+		if requestID.Valid {
+			current.Statuses[requestID.Int64] = status.String
+		}
+	}
+
+	return m, rows.Err()
+}
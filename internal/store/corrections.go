@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	CorrectionPending  = "pending"
+	CorrectionApproved = "approved"
+	CorrectionRejected = "rejected"
+)
+
+// AttendanceCorrection is a teacher's request to change attendance past the
+// window they may edit it in. Approving it applies NewStatus and NewNote to
+// the student's record for Date; PreviousStatus and PreviousNote keep what
+// the record held, nil when there was none.
+type AttendanceCorrection struct {
+	ID             int64      `json:"id"`
+	StudentID      int64      `json:"student_id"`
+	Date           time.Time  `json:"date"`
+	NewStatus      string     `json:"new_status" enums:"present,absent,late,excused"`
+	NewNote        *string    `json:"new_note"`
+	Reason         string     `json:"reason"`
+	RequestedBy    int64      `json:"requested_by"`
+	Status         string     `json:"status" enums:"pending,approved,rejected"`
+	PreviousStatus *string    `json:"previous_status"`
+	PreviousNote   *string    `json:"previous_note"`
+	RecordID       *int64     `json:"record_id"`
+	ReviewedBy     *int64     `json:"reviewed_by"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	ReviewNote     string     `json:"review_note"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// AttendanceCorrectionFilter narrows a listing; zero values match
+// everything.
+type AttendanceCorrectionFilter struct {
+	Status      string
+	RequestedBy int64
+	// Scope keeps only corrections for students within a manager's scope.
+	Scope Scope
+}
+
+type AttendanceCorrectionStore struct {
+	db *routedDB
+}
+
+// Create files a pending correction. It returns ErrNotFound when the
+// student does not exist and ErrConflict when they already have one pending
+// for the date.
+func (s *AttendanceCorrectionStore) Create(ctx context.Context, c *AttendanceCorrection) error {
+	query := `
+		INSERT INTO attendance_corrections (student_id, date, new_status, new_note, reason, requested_by)
+		VALUES ($1, $2::date, $3, $4, $5, $6)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		c.StudentID, c.Date.Format(time.DateOnly), c.NewStatus, c.NewNote, c.Reason, c.RequestedBy,
+	).Scan(&c.ID, &c.Status, &c.CreatedAt)
+	switch {
+	case isForeignKeyViolation(err):
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return ErrConflict
+	}
+	return err
+}
+
+// List returns the corrections matching f, newest first.
+func (s *AttendanceCorrectionStore) List(ctx context.Context, f AttendanceCorrectionFilter) ([]*AttendanceCorrection, error) {
+	where := "($1 = '' OR status = $1) AND ($2 = 0 OR requested_by = $2)"
+	if c := f.Scope.condition("students"); c != "" {
+		where += " AND student_id IN (SELECT id FROM students WHERE " + c + ")"
+	}
+	return s.list(ctx, where, f.Status, f.RequestedBy)
+}
+
+func (s *AttendanceCorrectionStore) Get(ctx context.Context, id int64) (*AttendanceCorrection, error) {
+	list, err := s.list(ctx, "id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return list[0], nil
+}
+
+func (s *AttendanceCorrectionStore) list(ctx context.Context, where string, args ...any) ([]*AttendanceCorrection, error) {
+	query := `
+		SELECT id, student_id, date, new_status, new_note, reason, requested_by, status,
+			previous_status, previous_note, record_id, reviewed_by, reviewed_at, review_note, created_at
+		FROM attendance_corrections
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*AttendanceCorrection{}
+	for rows.Next() {
+		var c AttendanceCorrection
+		if err := rows.Scan(&c.ID, &c.StudentID, &c.Date, &c.NewStatus, &c.NewNote, &c.Reason, &c.RequestedBy, &c.Status,
+			&c.PreviousStatus, &c.PreviousNote, &c.RecordID, &c.ReviewedBy, &c.ReviewedAt, &c.ReviewNote, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &c)
+	}
+	return list, rows.Err()
+}
+
+// Review approves or rejects a pending correction, applying an approved one
+// to the attendance record in the same transaction. It returns ErrNotFound
+// when the correction does not exist and ErrConflict when it was already
+// reviewed.
+func (s *AttendanceCorrectionStore) Review(ctx context.Context, c *AttendanceCorrection, status string, reviewerID int64, note string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM attendance_corrections WHERE id = $1 FOR UPDATE`, c.ID).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case err != nil:
+		return err
+	case current != CorrectionPending:
+		return ErrConflict
+	}
+
+	var (
+		previousStatus, previousNote *string
+		recordID                     *int64
+	)
+	if status == CorrectionApproved {
+		date := c.Date.Format(time.DateOnly)
+		err := tx.QueryRowContext(ctx,
+			`SELECT status, note FROM attendance_records WHERE student_id = $1 AND date = $2::date`, c.StudentID, date,
+		).Scan(&previousStatus, &previousNote)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// a new record is credited to the teacher who asked for it; an
+		// existing one keeps its teacher, classroom and method
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO attendance_records (student_id, teacher_id, date, status, note)
+			VALUES ($1, $2, $3::date, $4, $5)
+			ON CONFLICT (student_id, date)
+			DO UPDATE SET status = EXCLUDED.status, note = EXCLUDED.note
+			RETURNING id
+		`, c.StudentID, c.RequestedBy, date, c.NewStatus, c.NewNote).Scan(&recordID); err != nil {
+			return err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE attendance_corrections
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), review_note = $4,
+			previous_status = $5, previous_note = $6, record_id = $7
+		WHERE id = $1
+		RETURNING status, previous_status, previous_note, record_id, reviewed_by, reviewed_at, review_note
+	`, c.ID, status, reviewerID, note, previousStatus, previousNote, recordID).Scan(
+		&c.Status, &c.PreviousStatus, &c.PreviousNote, &c.RecordID, &c.ReviewedBy, &c.ReviewedAt, &c.ReviewNote)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	ReferralOpen   = "open"
+	ReferralClosed = "closed"
+)
+
+// CounselingCategories are what a referral or session is about.
+var CounselingCategories = []string{"academic", "behavioral", "social_emotional", "career", "family", "other"}
+
+// CounselingReferral is staff asking a counselor to see a student. It
+// closes when a session is logged against it.
+type CounselingReferral struct {
+	ID             int64      `json:"id"`
+	StudentID      int64      `json:"student_id"`
+	Category       string     `json:"category"`
+	Reason         string     `json:"reason"`
+	ReferredBy     int64      `json:"referred_by"`
+	ReferredByRole string     `json:"referred_by_role"`
+	Status         string     `json:"status" enums:"open,closed"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CounselingSession is a counselor's record of meeting a student. Notes
+// are only shown to holders of the counseling:read permission.
+type CounselingSession struct {
+	ID            int64      `json:"id"`
+	StudentID     int64      `json:"student_id"`
+	ReferralID    *int64     `json:"referral_id"`
+	Category      string     `json:"category"`
+	HeldOn        time.Time  `json:"held_on"`
+	Notes         string     `json:"notes"`
+	FollowUpOn    *time.Time `json:"follow_up_on"`
+	CounselorID   int64      `json:"counselor_id"`
+	CounselorRole string     `json:"counselor_role"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CounselingStats counts sessions over a period without naming anyone.
+// Groups with fewer students than the threshold it was read with have
+// their counts left out, so small groups can't be traced to a student.
+type CounselingStats struct {
+	From            time.Time          `json:"from"`
+	To              time.Time          `json:"to"`
+	Sessions        int                `json:"sessions"`
+	Students        int                `json:"students"`
+	OpenReferrals   int                `json:"open_referrals"`
+	ClosedReferrals int                `json:"closed_referrals"`
+	ByCategory      []*CounselingGroup `json:"by_category"`
+	ByGrade         []*CounselingGroup `json:"by_grade"`
+	ByMonth         []*CounselingGroup `json:"by_month"`
+}
+
+// CounselingGroup is one row of a breakdown; Sessions and Students are nil
+// when the group is too small to show.
+type CounselingGroup struct {
+	Key      string `json:"key"`
+	Sessions *int   `json:"sessions"`
+	Students *int   `json:"students"`
+}
+
+type CounselingStore struct {
+	db *routedDB
+}
+
+// CreateReferral files an open referral. ErrNotFound when the student
+// doesn't exist.
+func (s *CounselingStore) CreateReferral(ctx context.Context, ref *CounselingReferral) error {
+	query := `
+		INSERT INTO counseling_referrals (student_id, category, reason, referred_by, referred_by_role)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, ref.StudentID, ref.Category, ref.Reason, ref.ReferredBy, ref.ReferredByRole).
+		Scan(&ref.ID, &ref.Status, &ref.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Referrals returns the referrals with status, or all of them when it is
+// empty, oldest first.
+func (s *CounselingStore) Referrals(ctx context.Context, status string) ([]*CounselingReferral, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, student_id, category, reason, referred_by, referred_by_role, status, closed_at, created_at
+		FROM counseling_referrals
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at, id
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*CounselingReferral{}
+	for rows.Next() {
+		var ref CounselingReferral
+		if err := rows.Scan(&ref.ID, &ref.StudentID, &ref.Category, &ref.Reason, &ref.ReferredBy, &ref.ReferredByRole,
+			&ref.Status, &ref.ClosedAt, &ref.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &ref)
+	}
+	return list, rows.Err()
+}
+
+// CreateSession logs a session, closing the referral it answers. It
+// returns ErrNotFound when the student doesn't exist or the referral isn't
+// one of theirs.
+func (s *CounselingStore) CreateSession(ctx context.Context, cs *CounselingSession) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if cs.ReferralID != nil {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE counseling_referrals
+			SET status = 'closed', closed_at = COALESCE(closed_at, NOW())
+			WHERE id = $1 AND student_id = $2
+		`, *cs.ReferralID, cs.StudentID)
+		if err != nil {
+			return err
+		}
+		if err := expectRowsAffected(res); err != nil {
+			return err
+		}
+	}
+
+	var followUp *string
+	if cs.FollowUpOn != nil {
+		d := cs.FollowUpOn.Format(time.DateOnly)
+		followUp = &d
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO counseling_sessions (student_id, referral_id, category, held_on, notes, follow_up_on, counselor_id, counselor_role)
+		VALUES ($1, $2, $3, $4::date, $5, $6::date, $7, $8)
+		RETURNING id, created_at
+	`, cs.StudentID, cs.ReferralID, cs.Category, cs.HeldOn.Format(time.DateOnly), cs.Notes, followUp, cs.CounselorID, cs.CounselorRole).
+		Scan(&cs.ID, &cs.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Sessions returns a student's sessions, most recent first.
+func (s *CounselingStore) Sessions(ctx context.Context, studentID int64) ([]*CounselingSession, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, student_id, referral_id, category, held_on, notes, follow_up_on, counselor_id, counselor_role, created_at
+		FROM counseling_sessions
+		WHERE student_id = $1
+		ORDER BY held_on DESC, id DESC
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*CounselingSession{}
+	for rows.Next() {
+		var cs CounselingSession
+		if err := rows.Scan(&cs.ID, &cs.StudentID, &cs.ReferralID, &cs.Category, &cs.HeldOn, &cs.Notes, &cs.FollowUpOn,
+			&cs.CounselorID, &cs.CounselorRole, &cs.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &cs)
+	}
+	return list, rows.Err()
+}
+
+// Stats counts the sessions held between from and to, inclusive, and the
+// referrals filed then. Breakdown groups with fewer than minStudents
+// students are suppressed.
+func (s *CounselingStore) Stats(ctx context.Context, from, to time.Time, minStudents int) (*CounselingStats, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	st := &CounselingStats{From: from, To: to}
+	fromDate, toDate := from.Format(time.DateOnly), to.Format(time.DateOnly)
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM counseling_sessions WHERE held_on BETWEEN $1::date AND $2::date),
+			(SELECT COUNT(DISTINCT student_id) FROM counseling_sessions WHERE held_on BETWEEN $1::date AND $2::date),
+			(SELECT COUNT(*) FROM counseling_referrals WHERE status = 'open' AND created_at::date BETWEEN $1::date AND $2::date),
+			(SELECT COUNT(*) FROM counseling_referrals WHERE status = 'closed' AND created_at::date BETWEEN $1::date AND $2::date)
+	`, fromDate, toDate).Scan(&st.Sessions, &st.Students, &st.OpenReferrals, &st.ClosedReferrals)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range []struct {
+		expr string
+		dst  *[]*CounselingGroup
+	}{
+		{"cs.category", &st.ByCategory},
+		{"COALESCE(c.grade::text, '')", &st.ByGrade},
+		{"to_char(cs.held_on, 'YYYY-MM')", &st.ByMonth},
+	} {
+		if *b.dst, err = s.statGroups(ctx, b.expr, fromDate, toDate, minStudents); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// statGroups counts sessions and students grouped by the SQL expression
+// expr, over sessions and their students' current classrooms.
+func (s *CounselingStore) statGroups(ctx context.Context, expr, from, to string, minStudents int) ([]*CounselingGroup, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s AS key, COUNT(*), COUNT(DISTINCT cs.student_id)
+		FROM counseling_sessions cs
+		JOIN students s ON s.id = cs.student_id
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE cs.held_on BETWEEN $1::date AND $2::date
+		GROUP BY key
+		ORDER BY key
+	`, expr), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*CounselingGroup{}
+	for rows.Next() {
+		var (
+			g                  CounselingGroup
+			sessions, students int
+		)
+		if err := rows.Scan(&g.Key, &sessions, &students); err != nil {
+			return nil, err
+		}
+		if students >= minStudents {
+			g.Sessions, g.Students = &sessions, &students
+		}
+		groups = append(groups, &g)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dependencyCounts lists, per table, the related records to count before
+// deleting a row of it. Each query counts rows pointing at $1.
+var dependencyCounts = map[string][]struct {
+	name  string
+	query string
+}{
+	"students": {
+		{"attendance_records", `SELECT COUNT(*) FROM attendance_records WHERE student_id = $1`},
+		{"behavior_points", `SELECT COUNT(*) FROM behavior_points WHERE student_id = $1`},
+		{"consents", `SELECT COUNT(*) FROM consents WHERE student_id = $1`},
+		{"pickup_contacts", `SELECT COUNT(*) FROM pickup_contacts WHERE student_id = $1`},
+		{"pickup_checkouts", `SELECT COUNT(*) FROM pickup_checkouts WHERE student_id = $1`},
+		{"open_loans", `SELECT COUNT(*) FROM asset_loans WHERE holder_type = 'student' AND holder_id = $1 AND returned_at IS NULL`},
+		{"notes", `SELECT COUNT(*) FROM entity_notes WHERE entity = 'students' AND entity_id = $1`},
+	},
+	"teachers": {
+		{"classrooms", `SELECT COUNT(*) FROM classrooms WHERE teacher_id = $1 AND deleted_at IS NULL`},
+		{"students", `SELECT COUNT(*) FROM students WHERE teacher_id = $1 AND deleted_at IS NULL`},
+		{"attendance_records", `SELECT COUNT(*) FROM attendance_records WHERE teacher_id = $1`},
+		{"online_sessions", `SELECT COUNT(*) FROM online_sessions WHERE teacher_id = $1`},
+		{"open_loans", `SELECT COUNT(*) FROM asset_loans WHERE holder_type = 'teacher' AND holder_id = $1 AND returned_at IS NULL`},
+		{"notes", `SELECT COUNT(*) FROM entity_notes WHERE entity = 'teachers' AND entity_id = $1`},
+	},
+	"classrooms": {
+		{"students", `SELECT COUNT(*) FROM students WHERE classroom_id = $1 AND deleted_at IS NULL`},
+		{"attendance_records", `SELECT COUNT(*) FROM attendance_records WHERE classroom_id = $1`},
+		{"behavior_points", `SELECT COUNT(*) FROM behavior_points WHERE classroom_id = $1`},
+		{"online_sessions", `SELECT COUNT(*) FROM online_sessions WHERE classroom_id = $1`},
+		{"upcoming_bookings", `SELECT COUNT(*) FROM bookings WHERE classroom_id = $1 AND ends_at > NOW() AND cancelled_at IS NULL`},
+		{"open_loans", `SELECT COUNT(*) FROM asset_loans WHERE holder_type = 'classroom' AND holder_id = $1 AND returned_at IS NULL`},
+	},
+}
+
+// DependencyStore reports what a delete would touch.
+type DependencyStore struct {
+	db *routedDB
+}
+
+// Count returns, for a live row of table, how many related records of
+// each kind point at it. A missing row is ErrNotFound.
+func (s *DependencyStore) Count(ctx context.Context, table string, id int64) (map[string]int64, error) {
+	counts, ok := dependencyCounts[table]
+	if !ok {
+		return nil, fmt.Errorf("no dependency report for %s", table)
+	}
+
+	cols := make([]string, len(counts))
+	for i, c := range counts {
+		cols[i] = "(" + c.query + ")"
+	}
+	// table comes from the map above, never from the client
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND deleted_at IS NULL`, strings.Join(cols, ", "), table)
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	values := make([]int64, len(counts))
+	targets := make([]any, len(counts))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(targets...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(counts))
+	for i, c := range counts {
+		result[c.name] = values[i]
+	}
+	return result, nil
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DiscountSibling     = "sibling"
+	DiscountStaffChild  = "staff_child"
+	DiscountScholarship = "scholarship"
+)
+
+// DiscountRule is a discount applied to new invoices. MinSiblingRank only
+// matters for sibling rules.
+type DiscountRule struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind"`
+	Percent        int       `json:"percent"`
+	MinSiblingRank int       `json:"min_sibling_rank"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// StudentDiscount overrides a rule for one student. A nil Percent keeps the
+// rule's; 0 exempts the student.
+type StudentDiscount struct {
+	StudentID int64     `json:"student_id"`
+	RuleID    int64     `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Percent   *int      `json:"percent"`
+	Note      string    `json:"note"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DiscountFacts is what the discount rules look at for a student.
+// SiblingRank is their place among the family's live students, eldest
+// first.
+type DiscountFacts struct {
+	StudentID   int64                      `json:"student_id"`
+	SiblingRank int                        `json:"sibling_rank"`
+	Siblings    int                        `json:"siblings"`
+	StaffChild  bool                       `json:"staff_child"`
+	Overrides   map[int64]*StudentDiscount `json:"-"`
+}
+
+type DiscountStore struct {
+	db *routedDB
+}
+
+func (s *DiscountStore) CreateRule(ctx context.Context, d *DiscountRule) error {
+	query := `
+		INSERT INTO discount_rules (name, kind, percent, min_sibling_rank, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, d.Name, d.Kind, d.Percent, d.MinSiblingRank, d.Active).
+		Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+}
+
+// Rules lists the rules in the order they are applied, only active ones
+// when active is set.
+func (s *DiscountStore) Rules(ctx context.Context, active bool) ([]*DiscountRule, error) {
+	query := `
+		SELECT id, name, kind, percent, min_sibling_rank, active, created_at, updated_at
+		FROM discount_rules
+		WHERE active OR NOT $1
+		ORDER BY id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, active)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*DiscountRule{}
+	for rows.Next() {
+		var d DiscountRule
+		if err := rows.Scan(&d.ID, &d.Name, &d.Kind, &d.Percent, &d.MinSiblingRank, &d.Active, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &d)
+	}
+	return rules, rows.Err()
+}
+
+func (s *DiscountStore) GetRule(ctx context.Context, id int64) (*DiscountRule, error) {
+	query := `
+		SELECT id, name, kind, percent, min_sibling_rank, active, created_at, updated_at
+		FROM discount_rules
+		WHERE id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var d DiscountRule
+	err := s.db.QueryRowContext(ctx, query, id).
+		Scan(&d.ID, &d.Name, &d.Kind, &d.Percent, &d.MinSiblingRank, &d.Active, &d.CreatedAt, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// UpdateRule saves a rule's name, percent, sibling rank and whether it is
+// active. Invoices already issued keep their lines.
+func (s *DiscountStore) UpdateRule(ctx context.Context, d *DiscountRule) error {
+	query := `
+		UPDATE discount_rules
+		SET name = $2, percent = $3, min_sibling_rank = $4, active = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, d.ID, d.Name, d.Percent, d.MinSiblingRank, d.Active).Scan(&d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *DiscountStore) DeleteRule(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM discount_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// SetOverride adds or replaces a student's override of a rule. It returns
+// ErrNotFound when the student or rule does not exist.
+func (s *DiscountStore) SetOverride(ctx context.Context, d *StudentDiscount) error {
+	query := `
+		INSERT INTO student_discounts (student_id, rule_id, percent, note, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (student_id, rule_id) DO UPDATE
+		SET percent = EXCLUDED.percent, note = EXCLUDED.note, created_by = EXCLUDED.created_by, created_at = NOW()
+		RETURNING created_at, (SELECT name FROM discount_rules WHERE id = $2)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, d.StudentID, d.RuleID, d.Percent, d.Note, d.CreatedBy).
+		Scan(&d.CreatedAt, &d.RuleName)
+	if isForeignKeyViolation(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *DiscountStore) DeleteOverride(ctx context.Context, studentID, ruleID int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM student_discounts WHERE student_id = $1 AND rule_id = $2`, studentID, ruleID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Facts returns the discount facts of each of the given live students,
+// with their overrides.
+func (s *DiscountStore) Facts(ctx context.Context, studentIDs []int64) (map[int64]*DiscountFacts, error) {
+	query := `
+		WITH family AS (
+			SELECT id, parent_phone_number,
+				ROW_NUMBER() OVER (PARTITION BY parent_phone_number ORDER BY birth_date, id) AS rank,
+				COUNT(*) OVER (PARTITION BY parent_phone_number) AS siblings
+			FROM students
+			WHERE deleted_at IS NULL
+		)
+		SELECT f.id, f.rank, f.siblings,
+			EXISTS (SELECT 1 FROM teachers t WHERE t.phone_number = f.parent_phone_number AND t.deleted_at IS NULL)
+				OR EXISTS (SELECT 1 FROM accounts a WHERE a.profile_type IN ('exec', 'teacher') AND a.phone_number = f.parent_phone_number)
+		FROM family f
+		WHERE f.id = ANY($1)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(studentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facts := map[int64]*DiscountFacts{}
+	for rows.Next() {
+		f := &DiscountFacts{Overrides: map[int64]*StudentDiscount{}}
+		if err := rows.Scan(&f.StudentID, &f.SiblingRank, &f.Siblings, &f.StaffChild); err != nil {
+			return nil, err
+		}
+		facts[f.StudentID] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.overrides(ctx, studentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		if f, ok := facts[o.StudentID]; ok {
+			f.Overrides[o.RuleID] = o
+		}
+	}
+	return facts, nil
+}
+
+// Overrides lists a student's overrides.
+func (s *DiscountStore) Overrides(ctx context.Context, studentID int64) ([]*StudentDiscount, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.overrides(ctx, []int64{studentID})
+}
+
+func (s *DiscountStore) overrides(ctx context.Context, studentIDs []int64) ([]*StudentDiscount, error) {
+	query := `
+		SELECT d.student_id, d.rule_id, r.name, d.percent, d.note, d.created_by, d.created_at
+		FROM student_discounts d
+		JOIN discount_rules r ON r.id = d.rule_id
+		WHERE d.student_id = ANY($1)
+		ORDER BY d.student_id, d.rule_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(studentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []*StudentDiscount{}
+	for rows.Next() {
+		var d StudentDiscount
+		if err := rows.Scan(&d.StudentID, &d.RuleID, &d.RuleName, &d.Percent, &d.Note, &d.CreatedBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, &d)
+	}
+	return overrides, rows.Err()
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// emailChangeTables maps account kinds to their table and row filter.
+var emailChangeTables = map[string]struct{ table, where string }{
+	"exec":    {"execs", "TRUE"},
+	"teacher": {"teachers", notDeleted},
+	"student": {"students", notDeleted},
+}
+
+// EmailChangeStore keeps an account's new email in pending_email until it
+// is confirmed, so a mistyped or hostile change can't lock the owner out.
+type EmailChangeStore struct {
+	db *routedDB
+}
+
+func emailChangeTable(kind string) (string, string, error) {
+	t, ok := emailChangeTables[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown account kind %q", kind)
+	}
+	return t.table, t.where, nil
+}
+
+// Request records email as the account's pending email, replacing any
+// earlier request.
+func (s *EmailChangeStore) Request(ctx context.Context, kind string, id int64, email string) error {
+	table, where, err := emailChangeTable(kind)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE ` + table + ` SET pending_email = $2 WHERE id = $1 AND ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id, email)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Pending returns the account's pending email, or "" when there is none.
+func (s *EmailChangeStore) Pending(ctx context.Context, kind string, id int64) (string, error) {
+	table, where, err := emailChangeTable(kind)
+	if err != nil {
+		return "", err
+	}
+	query := `SELECT COALESCE(pending_email, '') FROM ` + table + ` WHERE id = $1 AND ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var pending string
+	err = s.db.QueryRowContext(ctx, query, id).Scan(&pending)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return pending, err
+}
+
+// Confirm makes email the account's address when it is still the pending
+// one, and returns the previous address. A link for a replaced or already
+// confirmed request gives ErrNotFound.
+func (s *EmailChangeStore) Confirm(ctx context.Context, kind string, id int64, email string) (string, error) {
+	table, where, err := emailChangeTable(kind)
+	if err != nil {
+		return "", err
+	}
+	query := `
+		WITH old AS (
+			SELECT id, email FROM ` + table + ` WHERE id = $1 AND ` + where + ` FOR UPDATE
+		)
+		UPDATE ` + table + ` t
+		SET email = t.pending_email, pending_email = NULL, updated_at = NOW()
+		FROM old
+		WHERE t.id = old.id AND t.pending_email = $2
+		RETURNING old.email
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var previous string
+	err = s.db.QueryRowContext(ctx, query, id, email).Scan(&previous)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "", ErrNotFound
+	case isUniqueViolation(err):
+		return "", ErrConflict
+	}
+	return previous, err
+}
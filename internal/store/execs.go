@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Role string
@@ -15,36 +17,65 @@ const (
 )
 
 type Exec struct {
-	ID        int64     `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Role      Role      `json:"role"`
+	ID        int64    `json:"id"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Email     string   `json:"email"`
+	Password  password `json:"-"`
+	Role      Role     `json:"role"`
+	// Scope limits a manager to some grades or classrooms; admins ignore it.
+	Scope     Scope     `json:"scope"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// LastLoginAt and LastLoginIP are when and from where the exec last
+	// signed in; only GetAll fills them.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	LastLoginIP *string    `json:"last_login_ip,omitempty"`
+}
+
+// columns maps list columns to the fields they scan into.
+func (e *Exec) columns() map[string]any {
+	return map[string]any{
+		"id":               &e.ID,
+		"first_name":       &e.FirstName,
+		"last_name":        &e.LastName,
+		"email":            &e.Email,
+		"role":             &e.Role,
+		"scope_grades":     pq.Array(&e.Scope.Grades),
+		"scope_classrooms": pq.Array(&e.Scope.Classrooms),
+		"created_at":       &e.CreatedAt,
+		"updated_at":       &e.UpdatedAt,
+		"last_login_at":    &e.LastLoginAt,
+		"last_login_ip":    &e.LastLoginIP,
+	}
 }
 
 type ExecStore struct {
-	db *sql.DB
+	db *routedDB
 }
 
+// Create inserts the exec and its account, which holds the password.
 func (s *ExecStore) Create(ctx context.Context, exec *Exec) error {
 	query := `
-	INSERT INTO execs (first_name, last_name, email, password, role)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO execs (first_name, last_name, email, role)
+	VALUES ($1, $2, $3, $4)
 	RETURNING id, created_at, updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
 		query,
 		exec.FirstName,
 		exec.LastName,
 		exec.Email,
-		exec.Password.hash,
 		exec.Role,
 	).Scan(
 		&exec.ID,
@@ -55,16 +86,30 @@ func (s *ExecStore) Create(ctx context.Context, exec *Exec) error {
 		return err
 	}
 
-	return nil
+	if err := createAccount(ctx, tx, exec.Email, exec.Password, string(exec.Role), ProfileExec, exec.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *ExecStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Exec, error) {
-	columns := []string{"id", "first_name", "last_name", "email", "role", "created_at", "updated_at"}
+	columns := []string{"id", "first_name", "last_name", "email", "role", "scope_grades", "scope_classrooms", "created_at", "updated_at", "last_login_at", "last_login_ip"}
 	searchCols := []string{"first_name", "last_name", "email"}
+	sortable := []string{"id", "first_name", "last_name", "email", "role", "created_at", "updated_at"}
 
-	query, args := BuildPaginatedQuery("execs", columns, pq, searchCols)
+	columns, err := selectColumns(columns, pq.Fields)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	exprs := columnExprs(columns, accountColumns("execs"))
+	query, args, err := BuildPaginatedQuery("execs", exprs, pq, searchCols, sortable)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -76,15 +121,7 @@ func (s *ExecStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Exec, err
 	execs := []*Exec{}
 	for rows.Next() {
 		var e Exec
-		if err := rows.Scan(
-			&e.ID,
-			&e.FirstName,
-			&e.LastName,
-			&e.Email,
-			&e.Role,
-			&e.CreatedAt,
-			&e.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(scanTargets(e.columns(), columns)...); err != nil {
 			return nil, err
 		}
 		execs = append(execs, &e)
@@ -95,12 +132,12 @@ func (s *ExecStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Exec, err
 
 func (s *ExecStore) GetByID(ctx context.Context, id int64) (*Exec, error) {
 	query := `
-	SELECT id, first_name, last_name, email,password, role, created_at, updated_at
+	SELECT id, first_name, last_name, email, role, scope_grades, scope_classrooms, created_at, updated_at
 	FROM execs
 	WHERE id = $1
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	var e Exec
@@ -109,39 +146,9 @@ func (s *ExecStore) GetByID(ctx context.Context, id int64) (*Exec, error) {
 		&e.FirstName,
 		&e.LastName,
 		&e.Email,
-		&e.Password.hash,
-		&e.Role,
-		&e.CreatedAt,
-		&e.UpdatedAt,
-	)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-
-	return &e, nil
-}
-
-func (s *ExecStore) GetByEmail(ctx context.Context, email string) (*Exec, error) {
-	query := `
-	SELECT id, first_name, last_name, email,password, role, created_at, updated_at
-	FROM execs
-	WHERE email = $1
-	`
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	var e Exec
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
-		&e.ID,
-		&e.FirstName,
-		&e.LastName,
-		&e.Email,
-		&e.Password.hash,
 		&e.Role,
+		pq.Array(&e.Scope.Grades),
+		pq.Array(&e.Scope.Classrooms),
 		&e.CreatedAt,
 		&e.UpdatedAt,
 	)
@@ -166,7 +173,7 @@ func (s *ExecStore) Update(ctx context.Context, exec *Exec) error {
 	RETURNING  updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	err := s.db.QueryRowContext(ctx,
@@ -188,13 +195,39 @@ func (s *ExecStore) Update(ctx context.Context, exec *Exec) error {
 	return nil
 }
 
+// SetScope replaces an exec's manager scope.
+func (s *ExecStore) SetScope(ctx context.Context, execID int64, scope Scope) error {
+	query := `
+	UPDATE execs
+	SET scope_grades = $2, scope_classrooms = $3, updated_at = NOW()
+	WHERE id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// pq encodes nil slices as NULL; the columns expect empty arrays.
+	if scope.Grades == nil {
+		scope.Grades = []int64{}
+	}
+	if scope.Classrooms == nil {
+		scope.Classrooms = []int64{}
+	}
+
+	res, err := s.db.ExecContext(ctx, query, execID, pq.Array(scope.Grades), pq.Array(scope.Classrooms))
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
 func (s *ExecStore) Delete(ctx context.Context, execID int64) error {
 	query := `
 	DELETE FROM execs
 	WHERE id = $1
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	res, err := s.db.ExecContext(ctx, query, execID)
@@ -213,3 +246,26 @@ func (s *ExecStore) Delete(ctx context.Context, execID int64) error {
 
 	return nil
 }
+
+// AdminEmails returns the addresses of every admin, for notices about the
+// school's security.
+func (s *ExecStore) AdminEmails(ctx context.Context) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT email FROM execs WHERE role = $1 ORDER BY id`, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// PermissionGrant gives one account a permission its role doesn't have.
+type PermissionGrant struct {
+	AccountID   int64     `json:"account_id"`
+	Permission  string    `json:"permission"`
+	Role        string    `json:"role"`
+	ProfileType string    `json:"profile_type"`
+	ProfileID   int64     `json:"profile_id"`
+	Email       *string   `json:"email"`
+	GrantedBy   *int64    `json:"granted_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type GrantStore struct {
+	db *routedDB
+}
+
+// Grant gives an account a permission. Granting one it already holds
+// keeps the original grant. ErrNotFound when the account doesn't exist.
+func (s *GrantStore) Grant(ctx context.Context, accountID int64, permission string, grantedBy int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO permission_grants (account_id, permission, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id, permission) DO NOTHING
+	`, accountID, permission, grantedBy)
+	if isForeignKeyViolation(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *GrantStore) Revoke(ctx context.Context, accountID int64, permission string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM permission_grants WHERE account_id = $1 AND permission = $2`, accountID, permission)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// List returns the grants of permission, or of every permission when it is
+// empty, oldest first.
+func (s *GrantStore) List(ctx context.Context, permission string) ([]*PermissionGrant, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT g.account_id, g.permission, a.role, a.profile_type, a.profile_id, a.email, g.granted_by, g.created_at
+		FROM permission_grants g
+		JOIN accounts a ON a.id = g.account_id
+		WHERE $1 = '' OR g.permission = $1
+		ORDER BY g.created_at, g.account_id
+	`, permission)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*PermissionGrant{}
+	for rows.Next() {
+		var g PermissionGrant
+		if err := rows.Scan(&g.AccountID, &g.Permission, &g.Role, &g.ProfileType, &g.ProfileID, &g.Email, &g.GrantedBy, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &g)
+	}
+	return list, rows.Err()
+}
+
+// ForAccount returns the permissions granted to an account.
+func (s *GrantStore) ForAccount(ctx context.Context, accountID int64) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT permission FROM permission_grants WHERE account_id = $1 ORDER BY permission
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// Has reports whether the active account of a profile holds permission.
+func (s *GrantStore) Has(ctx context.Context, profileType string, profileID int64, permission string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var ok bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM permission_grants g
+			JOIN accounts a ON a.id = g.account_id
+			WHERE a.profile_type = $1 AND a.profile_id = $2 AND g.permission = $3 AND a.deactivated_at IS NULL
+		)
+	`, profileType, profileID, permission).Scan(&ok)
+	return ok, err
+}
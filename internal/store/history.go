@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// FieldChange is the old and new JSON value of one changed field.
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// EntityChange is one update of a record, keyed by JSON field name.
+type EntityChange struct {
+	ID            int64                  `json:"id"`
+	Entity        string                 `json:"entity"`
+	EntityID      int64                  `json:"entity_id"`
+	ChangedBy     int64                  `json:"changed_by"`
+	ChangedByRole string                 `json:"changed_by_role"`
+	Changes       map[string]FieldChange `json:"changes"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+type HistoryStore struct {
+	db *routedDB
+}
+
+func (s *HistoryStore) Record(ctx context.Context, c *EntityChange) error {
+	changes, err := json.Marshal(c.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO entity_changes (entity, entity_id, changed_by, changed_by_role, changes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		c.Entity, c.EntityID, c.ChangedBy, c.ChangedByRole, changes,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+// List returns the changes of one record, newest first.
+func (s *HistoryStore) List(ctx context.Context, entity string, entityID int64, limit, offset int) ([]*EntityChange, error) {
+	return s.list(ctx, `entity = $1 AND entity_id = $2 ORDER BY created_at DESC, id DESC LIMIT $3 OFFSET $4`,
+		entity, entityID, limit, offset)
+}
+
+// ByActor returns every change made by the user with role and id, newest
+// first.
+func (s *HistoryStore) ByActor(ctx context.Context, role string, id int64) ([]*EntityChange, error) {
+	return s.list(ctx, `changed_by_role = $1 AND changed_by = $2 ORDER BY created_at DESC, id DESC`, role, id)
+}
+
+func (s *HistoryStore) list(ctx context.Context, where string, args ...any) ([]*EntityChange, error) {
+	query := `
+		SELECT id, entity, entity_id, changed_by, changed_by_role, changes, created_at
+		FROM entity_changes
+		WHERE ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*EntityChange{}
+	for rows.Next() {
+		var c EntityChange
+		var changes []byte
+		if err := rows.Scan(&c.ID, &c.Entity, &c.EntityID, &c.ChangedBy, &c.ChangedByRole, &changes, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &c.Changes); err != nil {
+			return nil, err
+		}
+		history = append(history, &c)
+	}
+
+	return history, rows.Err()
+}
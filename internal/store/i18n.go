@@ -0,0 +1,50 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LocalizedText holds translations of a field keyed by language tag
+// ("fa", "en", "en-GB"), stored as JSONB next to the field's canonical value.
+type LocalizedText map[string]string
+
+func (t *LocalizedText) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = LocalizedText{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("cannot scan %T into LocalizedText", src)
+	}
+}
+
+func (t LocalizedText) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(t)
+}
+
+// Pick returns the translation for the first language in prefs that has one,
+// trying each tag as given and then its base language ("fa-IR" -> "fa"). It
+// returns fallback when none match.
+func (t LocalizedText) Pick(prefs []string, fallback string) string {
+	for _, lang := range prefs {
+		if v := t[lang]; v != "" {
+			return v
+		}
+		if base, _, ok := strings.Cut(lang, "-"); ok {
+			if v := t[base]; v != "" {
+				return v
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,781 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	InvoiceUnpaid = "unpaid"
+	InvoicePaid   = "paid"
+	InvoiceVoid   = "void"
+
+	PaymentPending = "pending"
+	PaymentPaid    = "paid"
+	PaymentFailed  = "failed"
+)
+
+// Invoice is a fee billed to a student's parents. It is paid in one or
+// more installments; DueOn is the last installment's due date.
+type Invoice struct {
+	ID           int64          `json:"id"`
+	StudentID    int64          `json:"student_id"`
+	StudentName  string         `json:"student_name"`
+	Title        string         `json:"title"`
+	Term         string         `json:"term"`
+	Amount       int64          `json:"amount"`
+	AmountPaid   int64          `json:"amount_paid"`
+	DueOn        time.Time      `json:"due_on"`
+	Status       string         `json:"status"`
+	PaidAt       *time.Time     `json:"paid_at"`
+	CreatedBy    *int64         `json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Lines        []*InvoiceLine `json:"lines,omitempty"`
+	Installments []*Installment `json:"installments,omitempty"`
+	Payments     []*Payment     `json:"payments,omitempty"`
+	// ParentPhone is the student's parent's number, who may pay it.
+	ParentPhone string `json:"-"`
+}
+
+// InvoiceLine is part of an invoice's amount: the fee, or a negative
+// discount with the rule that produced it, its percent and why it applied.
+type InvoiceLine struct {
+	ID          int64  `json:"id"`
+	InvoiceID   int64  `json:"invoice_id"`
+	Description string `json:"description"`
+	Amount      int64  `json:"amount"`
+	RuleID      *int64 `json:"rule_id"`
+	Percent     *int   `json:"percent"`
+	Reason      string `json:"reason"`
+}
+
+// BillingTarget selects the students to invoice for a fee; zero values
+// match everything.
+type BillingTarget struct {
+	ClassroomID int64
+	Grade       int64
+	StudentIDs  []int64
+	Title       string
+	Term        string
+}
+
+// Installment is one part of an invoice's payment schedule. Seq counts
+// from 1.
+type Installment struct {
+	ID            int64      `json:"id"`
+	InvoiceID     int64      `json:"invoice_id"`
+	Seq           int        `json:"seq"`
+	Amount        int64      `json:"amount"`
+	DueOn         time.Time  `json:"due_on"`
+	PaidAt        *time.Time `json:"paid_at"`
+	RemindersSent int        `json:"reminders_sent"`
+}
+
+// OverdueInstallment is an unpaid installment past its due date, with
+// what a reminder to the parent needs.
+type OverdueInstallment struct {
+	Installment
+	Installments int    `json:"installments"`
+	Title        string `json:"title"`
+	StudentID    int64  `json:"student_id"`
+	StudentName  string `json:"student_name"`
+	ParentPhone  string `json:"parent_phone"`
+}
+
+// DelinquencyRow totals the overdue installments of one classroom, or of
+// one grade. Students without a classroom have neither.
+type DelinquencyRow struct {
+	ClassroomID    *int64 `json:"classroom_id,omitempty"`
+	ClassroomName  string `json:"classroom_name,omitempty"`
+	Grade          *int64 `json:"grade"`
+	Students       int    `json:"students"`
+	Installments   int    `json:"installments"`
+	OverdueAmount  int64  `json:"overdue_amount"`
+	MaxDaysOverdue int    `json:"max_days_overdue"`
+}
+
+// Payment is an attempt to pay an invoice installment through a gateway.
+type Payment struct {
+	ID            int64      `json:"id"`
+	InvoiceID     int64      `json:"invoice_id"`
+	InstallmentID *int64     `json:"installment_id"`
+	Gateway       string     `json:"gateway"`
+	Amount        int64      `json:"amount"`
+	Authority     string     `json:"authority"`
+	Status        string     `json:"status"`
+	RefID         string     `json:"ref_id"`
+	CardPAN       string     `json:"card_pan"`
+	Failure       string     `json:"failure"`
+	ParentID      *int64     `json:"parent_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	VerifiedAt    *time.Time `json:"verified_at"`
+	// ReceiptNumber is set once the payment is confirmed.
+	ReceiptNumber string `json:"receipt_number,omitempty"`
+}
+
+// InvoiceFilter narrows invoice listings; zero values match everything.
+type InvoiceFilter struct {
+	StudentID   int64
+	Status      string
+	Term        string
+	ParentPhone string
+}
+
+// AgingBuckets splits unpaid amounts by how long past due they are.
+type AgingBuckets struct {
+	Current    int64 `json:"current"`
+	Days1To30  int64 `json:"days_1_30"`
+	Days31To60 int64 `json:"days_31_60"`
+	Days61To90 int64 `json:"days_61_90"`
+	Over90     int64 `json:"over_90"`
+}
+
+// FinanceTotals are the billed, collected and outstanding amounts of a set
+// of invoices. Void invoices are not billed.
+type FinanceTotals struct {
+	Invoices    int          `json:"invoices"`
+	Billed      int64        `json:"billed"`
+	Collected   int64        `json:"collected"`
+	Outstanding int64        `json:"outstanding"`
+	Aging       AgingBuckets `json:"aging"`
+}
+
+// GradeFinance is FinanceTotals for one grade; Grade is nil for students
+// without a classroom.
+type GradeFinance struct {
+	Grade *int64 `json:"grade"`
+	FinanceTotals
+}
+
+type FinanceSummary struct {
+	Term string `json:"term"`
+	FinanceTotals
+	Grades []*GradeFinance `json:"grades"`
+}
+
+// InvoicePaidEvent is the outbox payload of OutboxInvoicePaid and
+// OutboxInstallmentPaid.
+type InvoicePaidEvent struct {
+	InvoiceID     int64  `json:"invoice_id"`
+	PaymentID     int64  `json:"payment_id"`
+	InstallmentID int64  `json:"installment_id,omitempty"`
+	ReceiptNumber string `json:"receipt_number,omitempty"`
+}
+
+type InvoiceStore struct {
+	db *routedDB
+}
+
+const invoiceColumns = `
+	i.id, i.student_id, s.first_name || ' ' || s.last_name, i.title, i.term, i.amount, i.due_on, i.status,
+	i.paid_at, i.created_by, i.created_at, i.updated_at, s.parent_phone_number,
+	(SELECT COALESCE(SUM(amount), 0) FROM invoice_installments WHERE invoice_id = i.id AND paid_at IS NOT NULL)`
+
+func scanInvoice(row interface{ Scan(...any) error }, i *Invoice) error {
+	return row.Scan(&i.ID, &i.StudentID, &i.StudentName, &i.Title, &i.Term, &i.Amount, &i.DueOn, &i.Status,
+		&i.PaidAt, &i.CreatedBy, &i.CreatedAt, &i.UpdatedAt, &i.ParentPhone, &i.AmountPaid)
+}
+
+const paymentColumns = `
+	id, invoice_id, installment_id, gateway, amount, authority, status, ref_id, card_pan, failure, parent_id,
+	created_at, verified_at, COALESCE((SELECT number FROM receipts WHERE payment_id = payments.id), '')`
+
+func scanPayment(row interface{ Scan(...any) error }, p *Payment) error {
+	return row.Scan(&p.ID, &p.InvoiceID, &p.InstallmentID, &p.Gateway, &p.Amount, &p.Authority, &p.Status,
+		&p.RefID, &p.CardPAN, &p.Failure, &p.ParentID, &p.CreatedAt, &p.VerifiedAt, &p.ReceiptNumber)
+}
+
+// Create adds an unpaid invoice, due in full on DueOn, with its lines.
+// Amount must be the lines' total; an invoice with nothing to pay is
+// created paid. It returns ErrNotFound when the student does not exist.
+func (s *InvoiceStore) Create(ctx context.Context, i *Invoice) error {
+	return s.CreateMany(ctx, []*Invoice{i})
+}
+
+// CreateMany creates invoices as Create does, all or none.
+func (s *InvoiceStore) CreateMany(ctx context.Context, invoices []*Invoice) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, i := range invoices {
+		err := tx.QueryRowContext(ctx, `
+			WITH i AS (
+				INSERT INTO invoices (student_id, title, term, amount, due_on, created_by, status, paid_at)
+				VALUES ($1, $2, $3, $4::bigint, $5, $6,
+					CASE WHEN $4::bigint = 0 THEN 'paid' ELSE 'unpaid' END,
+					CASE WHEN $4::bigint = 0 THEN NOW() END)
+				RETURNING id, amount, due_on, status, paid_at, created_at, updated_at
+			), n AS (
+				INSERT INTO invoice_installments (invoice_id, seq, amount, due_on)
+				SELECT id, 1, amount, due_on FROM i WHERE amount > 0
+			)
+			SELECT id, status, paid_at, created_at, updated_at FROM i
+		`, i.StudentID, i.Title, i.Term, i.Amount, i.DueOn.Format(time.DateOnly), i.CreatedBy,
+		).Scan(&i.ID, &i.Status, &i.PaidAt, &i.CreatedAt, &i.UpdatedAt)
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, l := range i.Lines {
+			l.InvoiceID = i.ID
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO invoice_lines (invoice_id, description, amount, rule_id, percent, reason)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id
+			`, l.InvoiceID, l.Description, l.Amount, l.RuleID, l.Percent, l.Reason).Scan(&l.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Lines returns what an invoice's amount is made of, fee first.
+func (s *InvoiceStore) Lines(ctx context.Context, invoiceID int64) ([]*InvoiceLine, error) {
+	query := `
+		SELECT id, invoice_id, description, amount, rule_id, percent, reason
+		FROM invoice_lines
+		WHERE invoice_id = $1
+		ORDER BY id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []*InvoiceLine{}
+	for rows.Next() {
+		var l InvoiceLine
+		if err := rows.Scan(&l.ID, &l.InvoiceID, &l.Description, &l.Amount, &l.RuleID, &l.Percent, &l.Reason); err != nil {
+			return nil, err
+		}
+		lines = append(lines, &l)
+	}
+	return lines, rows.Err()
+}
+
+// Billable returns the live students target selects that have no invoice,
+// other than a void one, with its title and term yet.
+func (s *InvoiceStore) Billable(ctx context.Context, target BillingTarget) ([]int64, error) {
+	query := `
+		SELECT s.id
+		FROM students s
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE s.deleted_at IS NULL
+			AND ($1 = 0 OR s.classroom_id = $1)
+			AND ($2 = 0 OR c.grade = $2)
+			AND (CARDINALITY($3::bigint[]) = 0 OR s.id = ANY($3))
+			AND NOT EXISTS (
+				SELECT 1 FROM invoices i
+				WHERE i.student_id = s.id AND i.title = $4 AND i.term = $5 AND i.status <> 'void'
+			)
+		ORDER BY s.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query,
+		target.ClassroomID, target.Grade, pq.Array(target.StudentIDs), target.Title, target.Term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// List returns the invoices matching filter, most recently due first.
+func (s *InvoiceStore) List(ctx context.Context, filter InvoiceFilter) ([]*Invoice, error) {
+	query := `
+		SELECT` + invoiceColumns + `
+		FROM invoices i
+		JOIN students s ON s.id = i.student_id
+		WHERE ($1 = 0 OR i.student_id = $1)
+			AND ($2 = '' OR i.status = $2)
+			AND ($3 = '' OR (s.parent_phone_number = $3 AND s.deleted_at IS NULL))
+			AND ($4 = '' OR i.term = $4)
+		ORDER BY i.due_on DESC, i.id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, filter.StudentID, filter.Status, filter.ParentPhone, filter.Term)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []*Invoice{}
+	for rows.Next() {
+		var i Invoice
+		if err := scanInvoice(rows, &i); err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, &i)
+	}
+	return invoices, rows.Err()
+}
+
+func (s *InvoiceStore) GetByID(ctx context.Context, id int64) (*Invoice, error) {
+	query := `
+		SELECT` + invoiceColumns + `
+		FROM invoices i
+		JOIN students s ON s.id = i.student_id
+		WHERE i.id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var i Invoice
+	err := scanInvoice(s.db.QueryRowContext(ctx, query, id), &i)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// Void cancels an unpaid invoice. It returns ErrConflict when the invoice
+// is already paid or void.
+func (s *InvoiceStore) Void(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var status string
+	err := s.db.QueryRowContext(ctx, `
+		WITH v AS (
+			UPDATE invoices SET status = 'void', updated_at = NOW()
+			WHERE id = $1 AND status = 'unpaid'
+			RETURNING status
+		)
+		SELECT status FROM v
+		UNION ALL
+		SELECT status FROM invoices WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM v)
+	`, id).Scan(&status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case err != nil:
+		return err
+	case status != InvoiceVoid:
+		return ErrConflict
+	}
+	return nil
+}
+
+// Payments lists an invoice's payment attempts, oldest first.
+func (s *InvoiceStore) Payments(ctx context.Context, invoiceID int64) ([]*Payment, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT`+paymentColumns+` FROM payments WHERE invoice_id = $1 ORDER BY id`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []*Payment{}
+	for rows.Next() {
+		var p Payment
+		if err := scanPayment(rows, &p); err != nil {
+			return nil, err
+		}
+		payments = append(payments, &p)
+	}
+	return payments, rows.Err()
+}
+
+// Installments returns an invoice's payment schedule in order.
+func (s *InvoiceStore) Installments(ctx context.Context, invoiceID int64) ([]*Installment, error) {
+	query := `
+		SELECT id, invoice_id, seq, amount, due_on, paid_at, reminders_sent
+		FROM invoice_installments
+		WHERE invoice_id = $1
+		ORDER BY seq
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	installments := []*Installment{}
+	for rows.Next() {
+		var n Installment
+		if err := rows.Scan(&n.ID, &n.InvoiceID, &n.Seq, &n.Amount, &n.DueOn, &n.PaidAt, &n.RemindersSent); err != nil {
+			return nil, err
+		}
+		installments = append(installments, &n)
+	}
+	return installments, rows.Err()
+}
+
+// SetInstallments replaces the payment schedule of an unpaid invoice, in
+// the given order, and moves its due date to the last installment's. The
+// amounts must add up to the invoice's. It returns ErrConflict when the
+// invoice is not unpaid or an installment was already paid.
+func (s *InvoiceStore) SetInstallments(ctx context.Context, invoiceID int64, installments []*Installment) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var paid bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, EXISTS (SELECT 1 FROM invoice_installments WHERE invoice_id = $1 AND paid_at IS NOT NULL)
+		FROM invoices WHERE id = $1
+		FOR UPDATE
+	`, invoiceID).Scan(&status, &paid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != InvoiceUnpaid || paid {
+		return ErrConflict
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM invoice_installments WHERE invoice_id = $1`, invoiceID); err != nil {
+		return err
+	}
+	for i, n := range installments {
+		n.InvoiceID, n.Seq = invoiceID, i+1
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO invoice_installments (invoice_id, seq, amount, due_on)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, invoiceID, n.Seq, n.Amount, n.DueOn.Format(time.DateOnly)).Scan(&n.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoices SET due_on = (SELECT MAX(due_on) FROM invoice_installments WHERE invoice_id = $1), updated_at = NOW()
+		WHERE id = $1
+	`, invoiceID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Overdue returns the unpaid installments of unpaid invoices that were due
+// before today, oldest first.
+func (s *InvoiceStore) Overdue(ctx context.Context, today time.Time) ([]*OverdueInstallment, error) {
+	query := `
+		SELECT n.id, n.invoice_id, n.seq, n.amount, n.due_on, n.paid_at, n.reminders_sent,
+			(SELECT COUNT(*) FROM invoice_installments WHERE invoice_id = i.id),
+			i.title, s.id, s.first_name || ' ' || s.last_name, s.parent_phone_number
+		FROM invoice_installments n
+		JOIN invoices i ON i.id = n.invoice_id
+		JOIN students s ON s.id = i.student_id
+		WHERE n.paid_at IS NULL AND n.due_on < $1::date AND i.status = 'unpaid' AND s.deleted_at IS NULL
+		ORDER BY n.due_on, n.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overdue := []*OverdueInstallment{}
+	for rows.Next() {
+		var o OverdueInstallment
+		if err := rows.Scan(&o.ID, &o.InvoiceID, &o.Seq, &o.Amount, &o.DueOn, &o.PaidAt, &o.RemindersSent,
+			&o.Installments, &o.Title, &o.StudentID, &o.StudentName, &o.ParentPhone); err != nil {
+			return nil, err
+		}
+		overdue = append(overdue, &o)
+	}
+	return overdue, rows.Err()
+}
+
+// ClaimReminder raises an installment's reminder count to level and
+// reports whether it was below, so each notice is sent once.
+func (s *InvoiceStore) ClaimReminder(ctx context.Context, installmentID int64, level int) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE invoice_installments SET reminders_sent = $2, last_reminded_at = NOW()
+		WHERE id = $1 AND reminders_sent < $2 AND paid_at IS NULL
+	`, installmentID, level)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Delinquency totals the overdue installments of unpaid invoices per
+// classroom, or per grade when byGrade is set, largest amount first.
+func (s *InvoiceStore) Delinquency(ctx context.Context, today time.Time, byGrade bool) ([]*DelinquencyRow, error) {
+	columns, group := "c.id, c.name, c.grade", "c.id"
+	if byGrade {
+		columns, group = "NULL::bigint, NULL, c.grade", "c.grade"
+	}
+	query := `
+		SELECT ` + columns + `,
+			COUNT(DISTINCT s.id), COUNT(*), SUM(n.amount), MAX($1::date - n.due_on)
+		FROM invoice_installments n
+		JOIN invoices i ON i.id = n.invoice_id
+		JOIN students s ON s.id = i.student_id
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE n.paid_at IS NULL AND n.due_on < $1::date AND i.status = 'unpaid'
+		GROUP BY ` + group + `
+		ORDER BY SUM(n.amount) DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []*DelinquencyRow{}
+	for rows.Next() {
+		var d DelinquencyRow
+		var name sql.NullString
+		if err := rows.Scan(&d.ClassroomID, &name, &d.Grade, &d.Students, &d.Installments,
+			&d.OverdueAmount, &d.MaxDaysOverdue); err != nil {
+			return nil, err
+		}
+		d.ClassroomName = name.String
+		report = append(report, &d)
+	}
+	return report, rows.Err()
+}
+
+// Summary totals the invoices of term, or all when term is empty, overall
+// and per grade, with unpaid installments aged against today.
+func (s *InvoiceStore) Summary(ctx context.Context, term string, today time.Time) (*FinanceSummary, error) {
+	query := `
+		SELECT GROUPING(c.grade) = 1, c.grade,
+			COUNT(DISTINCT i.id),
+			COALESCE(SUM(n.amount), 0),
+			COALESCE(SUM(n.amount) FILTER (WHERE n.paid_at IS NOT NULL), 0),
+			COALESCE(SUM(n.amount) FILTER (WHERE n.paid_at IS NULL AND n.due_on >= $2::date), 0),
+			COALESCE(SUM(n.amount) FILTER (WHERE n.paid_at IS NULL AND $2::date - n.due_on BETWEEN 1 AND 30), 0),
+			COALESCE(SUM(n.amount) FILTER (WHERE n.paid_at IS NULL AND $2::date - n.due_on BETWEEN 31 AND 60), 0),
+			COALESCE(SUM(n.amount) FILTER (WHERE n.paid_at IS NULL AND $2::date - n.due_on BETWEEN 61 AND 90), 0),
+			COALESCE(SUM(n.amount) FILTER (WHERE n.paid_at IS NULL AND $2::date - n.due_on > 90), 0)
+		FROM invoice_installments n
+		JOIN invoices i ON i.id = n.invoice_id
+		JOIN students s ON s.id = i.student_id
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE i.status <> 'void' AND ($1 = '' OR i.term = $1)
+		GROUP BY ROLLUP (c.grade)
+		ORDER BY GROUPING(c.grade), c.grade NULLS LAST
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, term, today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summary := &FinanceSummary{Term: term, Grades: []*GradeFinance{}}
+	for rows.Next() {
+		var total bool
+		var g GradeFinance
+		a := &g.Aging
+		if err := rows.Scan(&total, &g.Grade, &g.Invoices, &g.Billed, &g.Collected,
+			&a.Current, &a.Days1To30, &a.Days31To60, &a.Days61To90, &a.Over90); err != nil {
+			return nil, err
+		}
+		g.Outstanding = g.Billed - g.Collected
+		if total {
+			summary.FinanceTotals = g.FinanceTotals
+			continue
+		}
+		summary.Grades = append(summary.Grades, &g)
+	}
+	return summary, rows.Err()
+}
+
+// CreatePayment records a started payment.
+func (s *InvoiceStore) CreatePayment(ctx context.Context, p *Payment) error {
+	query := `
+		INSERT INTO payments (invoice_id, installment_id, gateway, amount, authority, parent_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, p.InvoiceID, p.InstallmentID, p.Gateway, p.Amount, p.Authority, p.ParentID).
+		Scan(&p.ID, &p.Status, &p.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *InvoiceStore) PaymentByAuthority(ctx context.Context, gateway, authority string) (*Payment, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var p Payment
+	err := scanPayment(s.db.QueryRowContext(ctx,
+		`SELECT`+paymentColumns+` FROM payments WHERE gateway = $1 AND authority = $2`, gateway, authority,
+	), &p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ConfirmPayment marks a pending payment as paid and, in the same
+// transaction, its installment as paid, the invoice as paid once no
+// installment is left, a numbered receipt, and an OutboxInstallmentPaid or
+// OutboxInvoicePaid event. applied is false when the installment had already been paid or
+// replaced, or the invoice voided, leaving the payment to be refunded. It
+// returns ErrConflict when the payment is no longer pending.
+func (s *InvoiceStore) ConfirmPayment(ctx context.Context, p *Payment, refID, cardPAN string) (applied bool, err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE payments SET status = 'paid', ref_id = $2, card_pan = $3, verified_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status, ref_id, card_pan, verified_at
+	`, p.ID, refID, cardPAN).Scan(&p.Status, &p.RefID, &p.CardPAN, &p.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrConflict
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if p.InstallmentID == nil {
+		return false, tx.Commit()
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE invoice_installments n SET paid_at = NOW()
+		FROM invoices i
+		WHERE i.id = n.invoice_id AND n.id = $1 AND n.paid_at IS NULL AND i.status = 'unpaid'
+	`, *p.InstallmentID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, tx.Commit()
+	}
+
+	res, err = tx.ExecContext(ctx, `
+		UPDATE invoices SET status = 'paid', paid_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'unpaid'
+			AND NOT EXISTS (SELECT 1 FROM invoice_installments WHERE invoice_id = $1 AND paid_at IS NULL)
+	`, p.InvoiceID)
+	if err != nil {
+		return false, err
+	}
+	settled, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	topic := OutboxInstallmentPaid
+	if settled > 0 {
+		topic = OutboxInvoicePaid
+	}
+	p.ReceiptNumber, err = issueReceipt(ctx, tx, p.ID)
+	if err != nil {
+		return false, err
+	}
+	event := InvoicePaidEvent{
+		InvoiceID:     p.InvoiceID,
+		PaymentID:     p.ID,
+		InstallmentID: *p.InstallmentID,
+		ReceiptNumber: p.ReceiptNumber,
+	}
+	if err := addOutboxEvent(ctx, tx, topic, event); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// FailPayment marks a pending payment as failed. It returns ErrConflict
+// when the payment is no longer pending.
+func (s *InvoiceStore) FailPayment(ctx context.Context, p *Payment, reason string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE payments SET status = 'failed', failure = $2, verified_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status, failure, verified_at
+	`, p.ID, reason).Scan(&p.Status, &p.Failure, &p.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
@@ -0,0 +1,309 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Grade is a score imported from an LMS.
+type Grade struct {
+	ID          int64     `json:"id"`
+	StudentID   int64     `json:"student_id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	ClassroomID int64     `json:"classroom_id"`
+	Source      string    `json:"source"`
+	Assignment  string    `json:"assignment"`
+	Score       float64   `json:"score"`
+	MaxScore    *float64  `json:"max_score,omitempty"`
+	ImportedAt  time.Time `json:"imported_at"`
+}
+
+// GradeImport is one score to import, matched to a student by email.
+type GradeImport struct {
+	Email      string
+	Assignment string
+	Score      float64
+	MaxScore   *float64
+}
+
+// RosterMember is a live student of a classroom.
+type RosterMember struct {
+	ID          int64
+	FirstName   string
+	LastName    string
+	Email       string
+	StudentCode string
+}
+
+// LMSSync is a classroom's scheduled exchange with an LMS.
+type LMSSync struct {
+	ClassroomID     int64      `json:"classroom_id"`
+	Format          string     `json:"format"`
+	GradesSource    string     `json:"grades_source"`
+	RosterURL       string     `json:"roster_url"`
+	GradesURL       string     `json:"grades_url"`
+	AuthToken       string     `json:"-"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Enabled         bool       `json:"enabled"`
+	NextRunAt       time.Time  `json:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	LastError       string     `json:"last_error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+const lmsSyncColumns = `classroom_id, format, grades_source, roster_url, grades_url, auth_token, interval_minutes,
+	enabled, next_run_at, last_run_at, last_error, created_at, updated_at`
+
+func scanLMSSync(row interface{ Scan(...any) error }) (*LMSSync, error) {
+	var ls LMSSync
+	err := row.Scan(&ls.ClassroomID, &ls.Format, &ls.GradesSource, &ls.RosterURL, &ls.GradesURL, &ls.AuthToken,
+		&ls.IntervalMinutes, &ls.Enabled, &ls.NextRunAt, &ls.LastRunAt, &ls.LastError, &ls.CreatedAt, &ls.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ls, nil
+}
+
+type LMSStore struct {
+	db *routedDB
+}
+
+// Roster returns the live students of a classroom by name.
+func (s *LMSStore) Roster(ctx context.Context, classroomID int64) ([]*RosterMember, error) {
+	query := `
+		SELECT id, first_name, last_name, email, student_code
+		FROM students
+		WHERE classroom_id = $1 AND deleted_at IS NULL
+		ORDER BY last_name, first_name, id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*RosterMember{}
+	for rows.Next() {
+		var m RosterMember
+		if err := rows.Scan(&m.ID, &m.FirstName, &m.LastName, &m.Email, &m.StudentCode); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+// ImportGrades stores grades for the classroom's students, matching them
+// by email and overwriting earlier imports of the same assignment from the
+// same source. It returns how many were stored and the emails that matched
+// no student of the classroom. All rows are stored or none.
+func (s *LMSStore) ImportGrades(ctx context.Context, classroomID int64, source string, grades []*GradeImport) (int, []string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, LOWER(email) FROM students WHERE classroom_id = $1 AND deleted_at IS NULL`, classroomID)
+	if err != nil {
+		return 0, nil, err
+	}
+	students := map[string]int64{}
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		students[email] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	stmt, err := s.db.prepare(ctx, tx, `
+		INSERT INTO grades (student_id, classroom_id, source, assignment, score, max_score)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (student_id, classroom_id, source, assignment)
+		DO UPDATE SET score = EXCLUDED.score, max_score = EXCLUDED.max_score, imported_at = NOW()
+	`)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer stmt.Close()
+
+	imported := 0
+	unmatched := []string{}
+	seen := map[string]bool{}
+	for _, g := range grades {
+		email := strings.ToLower(strings.TrimSpace(g.Email))
+		studentID, ok := students[email]
+		if !ok {
+			if !seen[email] {
+				seen[email] = true
+				unmatched = append(unmatched, g.Email)
+			}
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, studentID, classroomID, source, g.Assignment, g.Score, g.MaxScore); err != nil {
+			return 0, nil, err
+		}
+		imported++
+	}
+
+	return imported, unmatched, tx.Commit()
+}
+
+// Grades returns the imported grades of a classroom by student and
+// assignment.
+func (s *LMSStore) Grades(ctx context.Context, classroomID int64) ([]*Grade, error) {
+	return s.grades(ctx, `g.classroom_id = $1 ORDER BY s.last_name, s.first_name, g.student_id, g.assignment`, classroomID)
+}
+
+// StudentGrades returns every imported grade of a student, in any
+// classroom.
+func (s *LMSStore) StudentGrades(ctx context.Context, studentID int64) ([]*Grade, error) {
+	return s.grades(ctx, `g.student_id = $1 ORDER BY g.classroom_id, g.assignment`, studentID)
+}
+
+func (s *LMSStore) grades(ctx context.Context, where string, args ...any) ([]*Grade, error) {
+	query := `
+		SELECT g.id, g.student_id, s.first_name, s.last_name, g.classroom_id, g.source, g.assignment,
+			g.score, g.max_score, g.imported_at
+		FROM grades g
+		JOIN students s ON s.id = g.student_id
+		WHERE ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grades := []*Grade{}
+	for rows.Next() {
+		var g Grade
+		if err := rows.Scan(&g.ID, &g.StudentID, &g.FirstName, &g.LastName, &g.ClassroomID, &g.Source,
+			&g.Assignment, &g.Score, &g.MaxScore, &g.ImportedAt); err != nil {
+			return nil, err
+		}
+		grades = append(grades, &g)
+	}
+	return grades, rows.Err()
+}
+
+func (s *LMSStore) GetSync(ctx context.Context, classroomID int64) (*LMSSync, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ls, err := scanLMSSync(s.db.QueryRowContext(ctx, `SELECT `+lmsSyncColumns+` FROM lms_syncs WHERE classroom_id = $1`, classroomID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return ls, nil
+}
+
+// PutSync creates or replaces a classroom's sync settings. An empty
+// AuthToken keeps the stored one. The first run is due right away.
+func (s *LMSStore) PutSync(ctx context.Context, ls *LMSSync) error {
+	query := `
+		INSERT INTO lms_syncs (classroom_id, format, grades_source, roster_url, grades_url, auth_token, interval_minutes, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (classroom_id) DO UPDATE SET
+			format = EXCLUDED.format,
+			grades_source = EXCLUDED.grades_source,
+			roster_url = EXCLUDED.roster_url,
+			grades_url = EXCLUDED.grades_url,
+			auth_token = CASE WHEN EXCLUDED.auth_token = '' THEN lms_syncs.auth_token ELSE EXCLUDED.auth_token END,
+			interval_minutes = EXCLUDED.interval_minutes,
+			enabled = EXCLUDED.enabled,
+			next_run_at = NOW(),
+			updated_at = NOW()
+		RETURNING ` + lmsSyncColumns
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	saved, err := scanLMSSync(s.db.QueryRowContext(ctx, query,
+		ls.ClassroomID, ls.Format, ls.GradesSource, ls.RosterURL, ls.GradesURL, ls.AuthToken, ls.IntervalMinutes, ls.Enabled,
+	))
+	if err != nil {
+		return err
+	}
+	*ls = *saved
+	return nil
+}
+
+func (s *LMSStore) DeleteSync(ctx context.Context, classroomID int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM lms_syncs WHERE classroom_id = $1`, classroomID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// ClaimDueSyncs returns the enabled syncs due at now and moves each one's
+// next_run_at forward by its interval. Rows are locked with SKIP LOCKED,
+// so several API instances never claim the same run.
+func (s *LMSStore) ClaimDueSyncs(ctx context.Context, now time.Time) ([]*LMSSync, error) {
+	query := `
+		UPDATE lms_syncs
+		SET next_run_at = $1 + make_interval(mins => interval_minutes)
+		WHERE classroom_id IN (
+			SELECT classroom_id FROM lms_syncs
+			WHERE enabled AND next_run_at <= $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + lmsSyncColumns
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []*LMSSync{}
+	for rows.Next() {
+		ls, err := scanLMSSync(rows)
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, ls)
+	}
+	return due, rows.Err()
+}
+
+// FinishSync records the outcome of a run; lastError is empty on success.
+func (s *LMSStore) FinishSync(ctx context.Context, classroomID int64, lastError string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE lms_syncs SET last_run_at = NOW(), last_error = $2 WHERE classroom_id = $1`, classroomID, lastError)
+	return err
+}
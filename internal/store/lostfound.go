@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	LostItemUnclaimed = "unclaimed"
+	LostItemMatched   = "matched"
+	LostItemClaimed   = "claimed"
+)
+
+// LostItem is something staff found around the school. It is matched once
+// its owner is known and claimed once it is handed back.
+type LostItem struct {
+	ID          int64      `json:"id"`
+	Description string     `json:"description"`
+	Location    string     `json:"location"`
+	FoundOn     time.Time  `json:"found_on"`
+	FoundBy     int64      `json:"found_by"`
+	FoundByRole string     `json:"found_by_role"`
+	Status      string     `json:"status" enums:"unclaimed,matched,claimed"`
+	StudentID   *int64     `json:"student_id"`
+	MatchedAt   *time.Time `json:"matched_at"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+	HasPhoto    bool       `json:"has_photo"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// LostItemFilter narrows a listing; zero values match everything.
+type LostItemFilter struct {
+	// Query matches descriptions containing it or words like it.
+	Query  string
+	Status string
+}
+
+type LostItemStore struct {
+	db *routedDB
+}
+
+const lostItemColumns = `id, description, location, found_on, found_by, found_by_role, status, student_id,
+	matched_at, claimed_at, photo IS NOT NULL, created_at, updated_at`
+
+func scanLostItem(row interface{ Scan(...any) error }) (*LostItem, error) {
+	var it LostItem
+	err := row.Scan(&it.ID, &it.Description, &it.Location, &it.FoundOn, &it.FoundBy, &it.FoundByRole, &it.Status, &it.StudentID,
+		&it.MatchedAt, &it.ClaimedAt, &it.HasPhoto, &it.CreatedAt, &it.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return &it, err
+}
+
+func (s *LostItemStore) Create(ctx context.Context, it *LostItem) error {
+	query := `
+		INSERT INTO lost_items (description, location, found_on, found_by, found_by_role)
+		VALUES ($1, $2, $3::date, $4, $5)
+		RETURNING status, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, it.Description, it.Location, it.FoundOn.Format(time.DateOnly), it.FoundBy, it.FoundByRole).
+		Scan(&it.Status, &it.CreatedAt, &it.UpdatedAt)
+}
+
+// List returns the items matching f, best matches first when searching and
+// most recently found first otherwise.
+func (s *LostItemStore) List(ctx context.Context, f LostItemFilter) ([]*LostItem, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+lostItemColumns+`
+		FROM lost_items
+		WHERE ($1 = '' OR $1 <% description OR description ILIKE $2)
+			AND ($3 = '' OR status = $3)
+		ORDER BY CASE WHEN $1 = '' THEN 0 ELSE word_similarity($1, description) END DESC, found_on DESC, id DESC
+	`, f.Query, "%"+f.Query+"%", f.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*LostItem{}
+	for rows.Next() {
+		it, err := scanLostItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, it)
+	}
+	return list, rows.Err()
+}
+
+func (s *LostItemStore) Get(ctx context.Context, id int64) (*LostItem, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return scanLostItem(s.db.QueryRowContext(ctx, `SELECT `+lostItemColumns+` FROM lost_items WHERE id = $1`, id))
+}
+
+// Match records whose item it is. It returns ErrNotFound when the item or
+// student does not exist and ErrConflict when the item was already claimed.
+func (s *LostItemStore) Match(ctx context.Context, id, studentID int64) (*LostItem, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	it, err := scanLostItem(s.db.QueryRowContext(ctx, `
+		UPDATE lost_items
+		SET status = 'matched', student_id = $2, matched_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status <> 'claimed'
+		RETURNING `+lostItemColumns, id, studentID))
+	switch {
+	case isForeignKeyViolation(err):
+		return nil, ErrNotFound
+	case errors.Is(err, ErrNotFound):
+		if _, err := s.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrConflict
+	}
+	return it, err
+}
+
+// Claim records that an item was handed back. ErrConflict when it already
+// was.
+func (s *LostItemStore) Claim(ctx context.Context, id int64) (*LostItem, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	it, err := scanLostItem(s.db.QueryRowContext(ctx, `
+		UPDATE lost_items
+		SET status = 'claimed', claimed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status <> 'claimed'
+		RETURNING `+lostItemColumns, id))
+	if errors.Is(err, ErrNotFound) {
+		if _, err := s.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrConflict
+	}
+	return it, err
+}
+
+func (s *LostItemStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM lost_items WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// SetPhoto replaces an item's photo and its thumbnail; a nil thumbnail
+// means the photo is shown as is.
+func (s *LostItemStore) SetPhoto(ctx context.Context, id int64, data []byte, contentType string, thumb []byte, thumbType string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE lost_items
+		SET photo = $2, photo_type = $3, thumbnail = $4, thumbnail_type = NULLIF($5, ''), updated_at = NOW()
+		WHERE id = $1
+	`, id, data, contentType, thumb, thumbType)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Photo returns an item's photo, or its thumbnail when there is one and
+// thumbnail is set. ErrNotFound when the item has no photo.
+func (s *LostItemStore) Photo(ctx context.Context, id int64, thumbnail bool) ([]byte, string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		data        []byte
+		contentType string
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			CASE WHEN $2 AND thumbnail IS NOT NULL THEN thumbnail ELSE photo END,
+			CASE WHEN $2 AND thumbnail IS NOT NULL THEN thumbnail_type ELSE photo_type END
+		FROM lost_items
+		WHERE id = $1 AND photo IS NOT NULL
+	`, id, thumbnail).Scan(&data, &contentType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrNotFound
+	}
+	return data, contentType, err
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// LunchMenu is what the canteen serves on a date.
+type LunchMenu struct {
+	Date      string    `json:"date" example:"2026-10-17"`
+	Items     []string  `json:"items" example:"Chelo kabab,Salad,Doogh"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type LunchMenuStore struct {
+	db *routedDB
+}
+
+// List returns the menus between from and to (inclusive).
+func (s *LunchMenuStore) List(ctx context.Context, from, to time.Time) ([]*LunchMenu, error) {
+	query := `
+		SELECT date, items, notes, created_at, updated_at
+		FROM lunch_menus
+		WHERE date BETWEEN $1::date AND $2::date
+		ORDER BY date
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	menus := []*LunchMenu{}
+	for rows.Next() {
+		var m LunchMenu
+		var date time.Time
+		if err := rows.Scan(&date, pq.Array(&m.Items), &m.Notes, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		m.Date = date.Format(time.DateOnly)
+		menus = append(menus, &m)
+	}
+
+	return menus, rows.Err()
+}
+
+// Put creates or replaces the menu of m.Date.
+func (s *LunchMenuStore) Put(ctx context.Context, m *LunchMenu) error {
+	query := `
+		INSERT INTO lunch_menus (date, items, notes)
+		VALUES ($1::date, $2, $3)
+		ON CONFLICT (date) DO UPDATE SET
+			items = EXCLUDED.items,
+			notes = EXCLUDED.notes,
+			updated_at = NOW()
+		RETURNING created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, m.Date, pq.Array(m.Items), m.Notes).Scan(&m.CreatedAt, &m.UpdatedAt)
+}
+
+func (s *LunchMenuStore) Delete(ctx context.Context, date time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM lunch_menus WHERE date = $1::date`, date.Format(time.DateOnly))
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSelfMerge is returned when a student is merged into itself.
+var ErrSelfMerge = errors.New("cannot merge a student into itself")
+
+// StudentMerge records one duplicate folded into a surviving student.
+type StudentMerge struct {
+	ID                int64     `json:"id"`
+	SurvivorID        int64     `json:"survivor_id"`
+	DuplicateID       int64     `json:"duplicate_id"`
+	MergedBy          int64     `json:"merged_by"`
+	AttendanceMoved   int64     `json:"attendance_moved"`
+	AttendanceSkipped int64     `json:"attendance_skipped"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Merge folds duplicateID into survivorID in one transaction: attendance is
+// re-pointed to the survivor, the survivor's empty national ID and phone are
+// filled from the duplicate, and the duplicate is soft-deleted. Attendance on
+// dates the survivor already has a record for stays with the duplicate, so
+// nothing is overwritten. The merge is recorded in student_merges.
+func (s *StudentStore) Merge(ctx context.Context, survivorID, duplicateID, mergedBy int64) (*StudentMerge, error) {
+	if survivorID == duplicateID {
+		return nil, ErrSelfMerge
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock both rows in id order so concurrent merges can't deadlock.
+	var locked int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM students
+			WHERE id IN ($1, $2) AND deleted_at IS NULL
+			ORDER BY id
+			FOR UPDATE
+		) s
+	`, survivorID, duplicateID).Scan(&locked)
+	if err != nil {
+		return nil, err
+	}
+	if locked != 2 {
+		return nil, ErrNotFound
+	}
+
+	m := &StudentMerge{SurvivorID: survivorID, DuplicateID: duplicateID, MergedBy: mergedBy}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE attendance_records a
+		SET student_id = $1
+		WHERE a.student_id = $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM attendance_records b
+			WHERE b.student_id = $1 AND b.date = a.date
+		  )
+	`, survivorID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	if m.AttendanceMoved, err = res.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM attendance_records WHERE student_id = $1`, duplicateID,
+	).Scan(&m.AttendanceSkipped)
+	if err != nil {
+		return nil, err
+	}
+
+	var nationalID, phone sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		UPDATE students
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1
+		RETURNING national_id, phone_number
+	`, duplicateID, mergedBy).Scan(&nationalID, &phone)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE students
+		SET national_id = COALESCE(national_id, $2),
+		    phone_number = COALESCE(phone_number, $3),
+		    updated_at = NOW()
+		WHERE id = $1
+	`, survivorID, nationalID, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO student_merges (survivor_id, duplicate_id, merged_by, attendance_moved, attendance_skipped)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, survivorID, duplicateID, mergedBy, m.AttendanceMoved, m.AttendanceSkipped).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, tx.Commit()
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Note is a free-text staff note on a record, keyed like tags by table name
+// and row ID.
+type Note struct {
+	ID         int64     `json:"id"`
+	Entity     string    `json:"entity"`
+	EntityID   int64     `json:"entity_id"`
+	Body       string    `json:"body"`
+	AuthorID   int64     `json:"author_id"`
+	AuthorRole string    `json:"author_role"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type NoteStore struct {
+	db *routedDB
+}
+
+func (s *NoteStore) Create(ctx context.Context, n *Note) error {
+	query := `
+		INSERT INTO entity_notes (entity, entity_id, body, author_id, author_role)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, n.Entity, n.EntityID, n.Body, n.AuthorID, n.AuthorRole).
+		Scan(&n.ID, &n.CreatedAt)
+}
+
+// ForEntity returns the notes on one record, newest first.
+func (s *NoteStore) ForEntity(ctx context.Context, entity string, entityID int64) ([]*Note, error) {
+	query := `
+		SELECT id, entity, entity_id, body, author_id, author_role, created_at
+		FROM entity_notes
+		WHERE entity = $1 AND entity_id = $2
+		ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*Note{}
+	for rows.Next() {
+		var n Note
+		if err := rows.Scan(&n.ID, &n.Entity, &n.EntityID, &n.Body, &n.AuthorID, &n.AuthorRole, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, &n)
+	}
+
+	return notes, rows.Err()
+}
+
+// Delete removes a note from a record.
+func (s *NoteStore) Delete(ctx context.Context, entity string, entityID, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM entity_notes WHERE id = $1 AND entity = $2 AND entity_id = $3`,
+		id, entity, entityID,
+	)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RosterClass, RosterUser and RosterEnrollment are the rows behind the
+// OneRoster facade: live classrooms, live students and teachers, and the
+// links between them.
+type RosterClass struct {
+	ID        int64
+	Name      string
+	Grade     int64
+	TeacherID int64
+	UpdatedAt time.Time
+}
+
+type RosterUser struct {
+	ID          int64
+	Role        string // student or teacher
+	FirstName   string
+	LastName    string
+	Email       string
+	Identifier  string
+	Phone       string
+	ClassroomID *int64
+	Grade       *int64
+	UpdatedAt   time.Time
+}
+
+type RosterEnrollment struct {
+	ClassroomID int64
+	UserID      int64
+	Role        string
+	UpdatedAt   time.Time
+}
+
+// RosterUserFilter narrows Users; zero values match everything.
+type RosterUserFilter struct {
+	Role        string
+	ClassroomID int64
+}
+
+const rosterClassesQuery = `
+	SELECT id, name, grade, teacher_id, updated_at
+	FROM classrooms
+	WHERE deleted_at IS NULL`
+
+// rosterUsersQuery unions students and teachers; a teacher's classroom is
+// left empty since one teacher can lead several.
+const rosterUsersQuery = `
+	SELECT * FROM (
+		SELECT 'student' AS role, s.id, s.first_name, s.last_name, s.email, s.student_code AS identifier,
+			COALESCE(s.phone_number, '') AS phone, s.classroom_id, c.grade, s.updated_at
+		FROM students s
+		LEFT JOIN classrooms c ON c.id = s.classroom_id
+		WHERE s.deleted_at IS NULL
+		UNION ALL
+		SELECT 'teacher', t.id, t.first_name, t.last_name, t.email, '', t.phone_number, NULL, NULL, t.updated_at
+		FROM teachers t
+		WHERE t.deleted_at IS NULL
+	) u`
+
+// rosterEnrollmentsQuery yields a student enrollment per placed student
+// and a teacher enrollment per classroom with a teacher.
+const rosterEnrollmentsQuery = `
+	SELECT * FROM (
+		SELECT s.classroom_id, s.id AS user_id, 'student' AS role, s.updated_at
+		FROM students s
+		JOIN classrooms c ON c.id = s.classroom_id AND c.deleted_at IS NULL
+		WHERE s.deleted_at IS NULL
+		UNION ALL
+		SELECT c.id, c.teacher_id, 'teacher', c.updated_at
+		FROM classrooms c
+		JOIN teachers t ON t.id = c.teacher_id AND t.deleted_at IS NULL
+		WHERE c.deleted_at IS NULL
+	) e`
+
+type OneRosterStore struct {
+	db *routedDB
+}
+
+// page returns the total number of rows query yields and scans the page
+// of them selected by limit and offset, in orderBy order.
+func (s *OneRosterStore) page(ctx context.Context, query, orderBy string, args []any, limit, offset int, scan func(*sql.Rows) error) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") p", args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	paged := fmt.Sprintf("%s ORDER BY %s LIMIT $%d OFFSET $%d", query, orderBy, len(args)+1, len(args)+2)
+	rows, err := s.db.QueryContext(ctx, paged, append(args, limit, offset)...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return 0, err
+		}
+	}
+	return total, rows.Err()
+}
+
+// Classes returns a page of live classrooms and the total, optionally only
+// those led by teacherID.
+func (s *OneRosterStore) Classes(ctx context.Context, teacherID int64, limit, offset int) ([]*RosterClass, int, error) {
+	query, args := rosterClassesQuery, []any{}
+	if teacherID != 0 {
+		query += ` AND teacher_id = $1`
+		args = append(args, teacherID)
+	}
+
+	classes := []*RosterClass{}
+	total, err := s.page(ctx, query, "id", args, limit, offset, func(rows *sql.Rows) error {
+		var c RosterClass
+		if err := rows.Scan(&c.ID, &c.Name, &c.Grade, &c.TeacherID, &c.UpdatedAt); err != nil {
+			return err
+		}
+		classes = append(classes, &c)
+		return nil
+	})
+	return classes, total, err
+}
+
+func (s *OneRosterStore) Class(ctx context.Context, id int64) (*RosterClass, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var c RosterClass
+	err := s.db.QueryRowContext(ctx, rosterClassesQuery+` AND id = $1`, id).
+		Scan(&c.ID, &c.Name, &c.Grade, &c.TeacherID, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Users returns a page of live students and teachers and the total.
+func (s *OneRosterStore) Users(ctx context.Context, f RosterUserFilter, limit, offset int) ([]*RosterUser, int, error) {
+	query, args := rosterUsersQuery+` WHERE TRUE`, []any{}
+	if f.Role != "" {
+		args = append(args, f.Role)
+		query += fmt.Sprintf(` AND role = $%d`, len(args))
+	}
+	if f.ClassroomID != 0 {
+		args = append(args, f.ClassroomID)
+		query += fmt.Sprintf(` AND classroom_id = $%d`, len(args))
+	}
+
+	users := []*RosterUser{}
+	total, err := s.page(ctx, query, "role, id", args, limit, offset, func(rows *sql.Rows) error {
+		var u RosterUser
+		if err := rows.Scan(&u.Role, &u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Identifier, &u.Phone,
+			&u.ClassroomID, &u.Grade, &u.UpdatedAt); err != nil {
+			return err
+		}
+		users = append(users, &u)
+		return nil
+	})
+	return users, total, err
+}
+
+func (s *OneRosterStore) User(ctx context.Context, role string, id int64) (*RosterUser, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var u RosterUser
+	err := s.db.QueryRowContext(ctx, rosterUsersQuery+` WHERE role = $1 AND id = $2`, role, id).Scan(
+		&u.Role, &u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Identifier, &u.Phone, &u.ClassroomID, &u.Grade, &u.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Enrollments returns a page of enrollments and the total, optionally for
+// one classroom only.
+func (s *OneRosterStore) Enrollments(ctx context.Context, classroomID int64, limit, offset int) ([]*RosterEnrollment, int, error) {
+	query, args := rosterEnrollmentsQuery, []any{}
+	if classroomID != 0 {
+		query += ` WHERE classroom_id = $1`
+		args = append(args, classroomID)
+	}
+
+	enrollments := []*RosterEnrollment{}
+	total, err := s.page(ctx, query, "classroom_id, role DESC, user_id", args, limit, offset, func(rows *sql.Rows) error {
+		var e RosterEnrollment
+		if err := rows.Scan(&e.ClassroomID, &e.UserID, &e.Role, &e.UpdatedAt); err != nil {
+			return err
+		}
+		enrollments = append(enrollments, &e)
+		return nil
+	})
+	return enrollments, total, err
+}
+
+func (s *OneRosterStore) Enrollment(ctx context.Context, classroomID int64, role string, userID int64) (*RosterEnrollment, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var e RosterEnrollment
+	err := s.db.QueryRowContext(ctx, rosterEnrollmentsQuery+` WHERE classroom_id = $1 AND role = $2 AND user_id = $3`,
+		classroomID, role, userID,
+	).Scan(&e.ClassroomID, &e.UserID, &e.Role, &e.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// OnlineSession is a live online period of a classroom. While it is open
+// students mark themselves present by joining; closing it records
+// DefaultStatus for everyone else.
+type OnlineSession struct {
+	ID            int64      `json:"id"`
+	ClassroomID   int64      `json:"classroom_id"`
+	TeacherID     *int64     `json:"teacher_id"`
+	Date          time.Time  `json:"date"`
+	DefaultStatus string     `json:"default_status"`
+	StartedAt     time.Time  `json:"started_at"`
+	EndedAt       *time.Time `json:"ended_at"`
+	Defaulted     int64      `json:"defaulted"`
+	// MeetingProvider and MeetingURL are set when the session got its own
+	// meeting; otherwise students use the classroom's link.
+	MeetingProvider string `json:"meeting_provider,omitempty"`
+	MeetingID       string `json:"-"`
+	MeetingURL      string `json:"meeting_url,omitempty"`
+}
+
+type OnlineSessionStore struct {
+	db *routedDB
+}
+
+// Start opens a session. It returns ErrConflict when the classroom already
+// has an open one.
+func (s *OnlineSessionStore) Start(ctx context.Context, session *OnlineSession) error {
+	query := `
+		INSERT INTO online_sessions (classroom_id, teacher_id, date, default_status, meeting_provider, meeting_id, meeting_url)
+		VALUES ($1, $2, $3::date, $4, $5, $6, $7)
+		RETURNING id, started_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		session.ClassroomID, session.TeacherID, session.Date.Format(time.DateOnly), session.DefaultStatus,
+		session.MeetingProvider, session.MeetingID, session.MeetingURL,
+	).Scan(&session.ID, &session.StartedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *OnlineSessionStore) GetByID(ctx context.Context, id int64) (*OnlineSession, error) {
+	return s.getOne(ctx, "id = $1", id)
+}
+
+// GetOpen returns the open session of a classroom.
+func (s *OnlineSessionStore) GetOpen(ctx context.Context, classroomID int64) (*OnlineSession, error) {
+	return s.getOne(ctx, "classroom_id = $1 AND ended_at IS NULL", classroomID)
+}
+
+func (s *OnlineSessionStore) getOne(ctx context.Context, where string, arg any) (*OnlineSession, error) {
+	query := `
+		SELECT id, classroom_id, teacher_id, date, default_status, started_at, ended_at, defaulted,
+			meeting_provider, meeting_id, meeting_url
+		FROM online_sessions
+		WHERE ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var o OnlineSession
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(
+		&o.ID, &o.ClassroomID, &o.TeacherID, &o.Date, &o.DefaultStatus, &o.StartedAt, &o.EndedAt, &o.Defaulted,
+		&o.MeetingProvider, &o.MeetingID, &o.MeetingURL,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+// Join marks a student of the session's classroom present for the session's
+// date. An existing record for the day is left alone so the teacher's marks
+// win. It returns ErrNotFound when the student is not in the classroom.
+func (s *OnlineSessionStore) Join(ctx context.Context, session *OnlineSession, studentID int64) error {
+	query := `
+		INSERT INTO attendance_records (student_id, teacher_id, classroom_id, date, status, method)
+		SELECT s.id, $3, s.classroom_id, $4::date, 'present', 'online'
+		FROM students s
+		WHERE s.id = $1 AND s.classroom_id = $2 AND s.deleted_at IS NULL
+		ON CONFLICT (student_id, date) DO NOTHING
+		RETURNING id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, query,
+		studentID, session.ClassroomID, session.TeacherID, session.Date.Format(time.DateOnly),
+	).Scan(&id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Nothing inserted: either already marked or not in this classroom.
+	var member bool
+	err = s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM students WHERE id = $1 AND classroom_id = $2 AND deleted_at IS NULL)`,
+		studentID, session.ClassroomID,
+	).Scan(&member)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close ends an open session and gives every student of the classroom
+// without a record for the day the session's default status, filling in
+// EndedAt and Defaulted. It returns ErrNotFound when the session is not open.
+func (s *OnlineSessionStore) Close(ctx context.Context, session *OnlineSession) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var endedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`UPDATE online_sessions SET ended_at = NOW() WHERE id = $1 AND ended_at IS NULL RETURNING ended_at`,
+		session.ID,
+	).Scan(&endedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO attendance_records (student_id, teacher_id, classroom_id, date, status, method)
+		SELECT s.id, $2, s.classroom_id, $3::date, $4, 'online'
+		FROM students s
+		WHERE s.classroom_id = $1 AND s.deleted_at IS NULL
+		ON CONFLICT (student_id, date) DO NOTHING
+	`, session.ClassroomID, session.TeacherID, session.Date.Format(time.DateOnly), session.DefaultStatus)
+	if err != nil {
+		return err
+	}
+	defaulted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE online_sessions SET defaulted = $2 WHERE id = $1`, session.ID, defaulted,
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	session.EndedAt = &endedAt
+	session.Defaulted = defaulted
+	return nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Outbox topics.
+const (
+	OutboxInvoicePaid     = "invoice.paid"
+	OutboxInstallmentPaid = "invoice.installment_paid"
+)
+
+// OutboxEvent is a change to announce, written in the transaction that
+// made it.
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	Topic     string          `json:"topic"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type OutboxStore struct {
+	db *routedDB
+}
+
+// addOutboxEvent queues an event inside tx, so it is only delivered if tx
+// commits.
+func addOutboxEvent(ctx context.Context, tx *sql.Tx, topic string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox_events (topic, payload) VALUES ($1, $2)`, topic, data)
+	return err
+}
+
+// Claim returns up to limit undelivered events that are due and have had
+// fewer than maxAttempts deliveries, hiding them from other claims for
+// lease. Each claim counts as an attempt.
+func (s *OutboxStore) Claim(ctx context.Context, limit, maxAttempts int, lease time.Duration) ([]*OutboxEvent, error) {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, available_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM outbox_events
+			WHERE dispatched_at IS NULL AND available_at <= NOW() AND attempts < $2
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, payload, attempts, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, maxAttempts, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*OutboxEvent{}
+	for rows.Next() {
+		var e OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Topic, &e.Payload, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func (s *OutboxStore) Dispatched(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE outbox_events SET dispatched_at = NOW(), last_error = '' WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Retry records a failed delivery and makes the event due again after
+// delay.
+func (s *OutboxStore) Retry(ctx context.Context, id int64, delay time.Duration, reason string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE outbox_events SET last_error = $2, available_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $1
+	`, id, reason, delay.Seconds())
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
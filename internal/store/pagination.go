@@ -1,8 +1,11 @@
 package store
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -14,8 +17,25 @@ type PaginatedQuery struct {
 	SortBy string `json:"sort_by" validate:"omitempty"`
 	Order  string `json:"order" validate:"oneof=asc desc,omitempty"`
 	Search string `json:"search" validate:"max=72,omitempty"`
+	// Fields restricts the selected columns (sparse fieldsets); empty means all.
+	Fields []string `json:"fields" validate:"max=32,omitempty"`
+	// Tag keeps only rows carrying this tag.
+	Tag string `json:"tag" validate:"max=64,omitempty"`
+	// Scope keeps only rows within the caller's manager scope.
+	Scope Scope `json:"-"`
+	// Filters keeps only rows whose column equals the value. Handlers set
+	// them from known query params; they are never taken from the client
+	// as column names.
+	Filters map[string]any `json:"-"`
+	// Account narrows people lists by creation date and sign-in account.
+	Account AccountFilter `json:"-"`
+	// After keeps only rows past this id in the sort order, for cursor
+	// pagination; it only makes sense sorted by id.
+	After int64 `json:"-"`
 }
 
+var ErrInvalidField = errors.New("invalid field")
+
 // Parse extracts pagination + sorting from query params.
 func (pq PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
 	qs := r.URL.Query()
@@ -55,30 +75,163 @@ func (pq PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
 		pq.Search = search
 	}
 
+	if tag := qs.Get("tag"); tag != "" {
+		pq.Tag = NormalizeTag(tag)
+	}
+
+	if fields := qs.Get("fields"); fields != "" {
+		pq.Fields = nil
+		for _, f := range strings.Split(fields, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				pq.Fields = append(pq.Fields, f)
+			}
+		}
+	}
+
 	return pq, nil
 }
 
+// selectColumns narrows columns to the requested fields, keeping the
+// whitelist order. "id" is always selected.
+func selectColumns(columns []string, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return columns, nil
+	}
+
+	allowed := make(map[string]struct{}, len(columns))
+	for _, c := range columns {
+		allowed[c] = struct{}{}
+	}
+
+	requested := map[string]struct{}{"id": {}}
+	for _, f := range fields {
+		if _, ok := allowed[f]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidField, f)
+		}
+		requested[f] = struct{}{}
+	}
+
+	selected := make([]string, 0, len(requested))
+	for _, c := range columns {
+		if _, ok := requested[c]; ok {
+			selected = append(selected, c)
+		}
+	}
+	return selected, nil
+}
+
+// checkSort rejects a sort_by outside the sortable columns. BuildPaginatedQuery
+// splices SortBy into the SQL, so it runs this before building anything.
+func checkSort(pq PaginatedQuery, sortable []string) error {
+	if pq.SortBy == "" {
+		return nil
+	}
+	for _, c := range sortable {
+		if c == pq.SortBy {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: sort_by %s", ErrInvalidField, pq.SortBy)
+}
+
+// scanTargets returns the scan destinations for columns from a
+// column -> field pointer map.
+func scanTargets(fields map[string]any, columns []string) []any {
+	targets := make([]any, len(columns))
+	for i, c := range columns {
+		targets[i] = fields[c]
+	}
+	return targets
+}
+
+// columnExprs swaps the computed columns among columns for the expressions
+// producing them.
+func columnExprs(columns []string, computed map[string]string) []string {
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = c
+		if expr, ok := computed[c]; ok {
+			exprs[i] = expr
+		}
+	}
+	return exprs
+}
+
+// BuildPaginatedQuery builds a SELECT with search, sorting and pagination.
+// pq.SortBy must be one of sortable, or ErrInvalidField is returned.
+// conditions are static SQL predicates ANDed into the WHERE clause
+// (e.g. "deleted_at IS NULL").
 func BuildPaginatedQuery(
 	table string,
 	columns []string,
 	pq PaginatedQuery,
 	searchColumns []string,
-) (string, []any) {
+	sortable []string,
+	conditions ...string,
+) (string, []any, error) {
+	if err := checkSort(pq, sortable); err != nil {
+		return "", nil, err
+	}
+
 	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table)
 	args := []any{}
 	argPos := 1 // keeps track of $1, $2, ...
 
+	where := append([]string{}, conditions...)
+	if c := pq.Scope.condition(table); c != "" {
+		where = append(where, c)
+	}
+
 	// Search
 	if pq.Search != "" && len(searchColumns) > 0 {
-		where := []string{}
+		search := []string{}
 		for _, col := range searchColumns {
-			where = append(where, fmt.Sprintf("%s ILIKE $%d", col, argPos))
+			search = append(search, fmt.Sprintf("%s ILIKE $%d", col, argPos))
 		}
-		query += " WHERE " + strings.Join(where, " OR ")
+		where = append(where, "("+strings.Join(search, " OR ")+")")
 		args = append(args, "%"+pq.Search+"%")
 		argPos++
 	}
 
+	// Column filters, in a fixed order so equal queries build equal SQL
+	for _, col := range slices.Sorted(maps.Keys(pq.Filters)) {
+		where = append(where, fmt.Sprintf("%s.%s = $%d", table, col, argPos))
+		args = append(args, pq.Filters[col])
+		argPos++
+	}
+
+	// Account filters, on the tables that have accounts
+	if _, ok := profileTables[table]; ok {
+		conds, condArgs := pq.Account.conditions(table, argPos)
+		where = append(where, conds...)
+		args = append(args, condArgs...)
+		argPos += len(condArgs)
+	}
+
+	// Cursor
+	if pq.After > 0 {
+		op := ">"
+		if pq.Order == "desc" {
+			op = "<"
+		}
+		where = append(where, fmt.Sprintf("%s.id %s $%d", table, op, argPos))
+		args = append(args, pq.After)
+		argPos++
+	}
+
+	// Tag filter; tag assignments are keyed by table name
+	if pq.Tag != "" {
+		where = append(where, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM tag_assignments ta JOIN tags t ON t.id = ta.tag_id
+			WHERE ta.entity = '%s' AND ta.entity_id = %s.id AND t.name = $%d)`, table, table, argPos))
+		args = append(args, pq.Tag)
+		argPos++
+	}
+
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
 	// Sorting
 	if pq.SortBy != "" {
 		query += " ORDER BY " + pq.SortBy
@@ -95,5 +248,5 @@ func BuildPaginatedQuery(
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argPos, argPos+1)
 	args = append(args, pq.Limit, pq.Offset)
 
-	return query, args
+	return query, args, nil
 }
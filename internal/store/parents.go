@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Parent is a guardian who signs in by phone. Children are matched by
+// students.parent_phone_number rather than a link table.
+type Parent struct {
+	ID          int64     `json:"id"`
+	PhoneNumber string    `json:"phone_number"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+type ParentStore struct {
+	db *routedDB
+}
+
+// Login returns the parent for phone, creating it and its account on first
+// login, and records the login time.
+func (s *ParentStore) Login(ctx context.Context, phone string) (*Parent, error) {
+	query := `
+		WITH p AS (
+			INSERT INTO parents (phone_number)
+			VALUES ($1)
+			ON CONFLICT (phone_number) DO UPDATE SET last_login_at = NOW()
+			RETURNING id, phone_number, created_at, last_login_at
+		), a AS (
+			INSERT INTO accounts (phone_number, role, profile_type, profile_id, last_login_at, last_seen_at)
+			SELECT phone_number, 'parent', 'parent', id, last_login_at, last_login_at FROM p
+			ON CONFLICT (profile_type, profile_id) DO UPDATE SET last_login_at = EXCLUDED.last_login_at, last_seen_at = EXCLUDED.last_seen_at
+		)
+		SELECT id, phone_number, created_at, last_login_at FROM p
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var p Parent
+	err := s.db.QueryRowContext(ctx, query, phone).Scan(&p.ID, &p.PhoneNumber, &p.CreatedAt, &p.LastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *ParentStore) GetByID(ctx context.Context, id int64) (*Parent, error) {
+	query := `
+		SELECT id, phone_number, created_at, last_login_at
+		FROM parents
+		WHERE id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var p Parent
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.PhoneNumber, &p.CreatedAt, &p.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// HasChildren reports whether any live student lists phone as their
+// parent's number.
+func (s *ParentStore) HasChildren(ctx context.Context, phone string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM students
+			WHERE parent_phone_number = $1 AND deleted_at IS NULL
+		)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, phone).Scan(&exists)
+	return exists, err
+}
+
+// Children returns the live students whose parent's number is phone.
+func (s *ParentStore) Children(ctx context.Context, phone string) ([]*Student, error) {
+	query := `
+		SELECT id, first_name, last_name, email, phone_number, classroom_id, birth_date, address,
+			parent_name, parent_phone_number, teacher_id, national_id, student_code, created_at, updated_at
+		FROM students
+		WHERE parent_phone_number = $1 AND deleted_at IS NULL
+		ORDER BY id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []*Student{}
+	for rows.Next() {
+		var st Student
+		if err := rows.Scan(
+			&st.ID,
+			&st.FirstName,
+			&st.LastName,
+			&st.Email,
+			&st.PhoneNumber,
+			&st.ClassRoomID,
+			&st.BirthDate,
+			&st.Address,
+			&st.ParentName,
+			&st.ParentPhoneNumber,
+			&st.TeacherID,
+			&st.NationalID,
+			&st.StudentCode,
+			&st.CreatedAt,
+			&st.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		students = append(students, &st)
+	}
+
+	return students, rows.Err()
+}
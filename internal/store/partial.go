@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownField is returned by UpdatePartial for a column that can't be
+// patched.
+var ErrUnknownField = errors.New("unknown field")
+
+// Columns each store lets UpdatePartial set. Email is absent on purpose: it
+// changes through EmailChanges once the new address is confirmed.
+var (
+	studentPatchable = []string{
+		"first_name", "last_name", "phone_number", "classroom_id", "birth_date",
+		"address", "parent_name", "parent_phone_number", "teacher_id", "national_id",
+	}
+	teacherPatchable = []string{
+		"first_name", "last_name", "subject", "subject_i18n", "phone_number", "hire_date", "national_id",
+	}
+	execPatchable      = []string{"first_name", "last_name", "role"}
+	classroomPatchable = []string{"name", "name_i18n", "capacity", "grade", "teacher_id", "mode", "meeting_url"}
+)
+
+// updatePartial sets only the given columns of one row, so two PATCHes
+// touching different fields can't undo each other the way whole-row
+// UPDATEs do. where, if not empty, further restricts the row (e.g.
+// notDeleted).
+func updatePartial(ctx context.Context, db *routedDB, table string, id int64, fields map[string]any, allowed []string, where string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	cols := make([]string, 0, len(fields))
+	for col := range fields {
+		if !slices.Contains(allowed, col) {
+			return fmt.Errorf("%w: %s", ErrUnknownField, col)
+		}
+		cols = append(cols, col)
+	}
+	slices.Sort(cols)
+
+	sets := make([]string, 0, len(cols)+1)
+	args := make([]any, 0, len(cols)+1)
+	for i, col := range cols {
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, i+1))
+		args = append(args, fields[col])
+	}
+	sets = append(sets, "updated_at = NOW()")
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", table, strings.Join(sets, ", "), len(args))
+	if where != "" {
+		query += " AND " + where
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// UpdatePartial sets the given columns of a student, keyed by column name.
+func (s *StudentStore) UpdatePartial(ctx context.Context, id int64, fields map[string]any) error {
+	return updatePartial(ctx, s.db, "students", id, fields, studentPatchable, notDeleted)
+}
+
+// UpdatePartial sets the given columns of a teacher, keyed by column name.
+func (s *TeacherStore) UpdatePartial(ctx context.Context, id int64, fields map[string]any) error {
+	return updatePartial(ctx, s.db, "teachers", id, fields, teacherPatchable, notDeleted)
+}
+
+// UpdatePartial sets the given columns of an exec, keyed by column name.
+func (s *ExecStore) UpdatePartial(ctx context.Context, id int64, fields map[string]any) error {
+	return updatePartial(ctx, s.db, "execs", id, fields, execPatchable, "")
+}
+
+// UpdatePartial sets the given columns of a classroom, keyed by column name.
+func (s *classroomStore) UpdatePartial(ctx context.Context, id int64, fields map[string]any) error {
+	return updatePartial(ctx, s.db, "classrooms", id, fields, classroomPatchable, notDeleted)
+}
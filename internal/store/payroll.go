@@ -0,0 +1,432 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ExpensePending  = "pending"
+	ExpenseApproved = "approved"
+	ExpenseRejected = "rejected"
+)
+
+// StaffContract holds a teacher's or exec's monthly pay. StaffKind is
+// "teacher" or "exec", the table StaffID belongs to.
+type StaffContract struct {
+	ID         int64      `json:"id"`
+	StaffKind  string     `json:"staff_kind"`
+	StaffID    int64      `json:"staff_id"`
+	StaffName  string     `json:"staff_name"`
+	Title      string     `json:"title"`
+	BaseSalary int64      `json:"base_salary"`
+	Allowances int64      `json:"allowances"`
+	Deductions int64      `json:"deductions"`
+	StartsOn   time.Time  `json:"starts_on"`
+	EndsOn     *time.Time `json:"ends_on"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Payslip is one month's pay. Period is the first day of the month.
+// Reimbursements are the approved expense claims paid with it.
+type Payslip struct {
+	ID             int64     `json:"id"`
+	ContractID     int64     `json:"contract_id"`
+	StaffKind      string    `json:"staff_kind"`
+	StaffID        int64     `json:"staff_id"`
+	StaffName      string    `json:"staff_name"`
+	Title          string    `json:"title"`
+	Period         time.Time `json:"period"`
+	BaseSalary     int64     `json:"base_salary"`
+	Allowances     int64     `json:"allowances"`
+	Reimbursements int64     `json:"reimbursements"`
+	Deductions     int64     `json:"deductions"`
+	Net            int64     `json:"net"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ExpenseClaim struct {
+	ID          int64      `json:"id"`
+	StaffKind   string     `json:"staff_kind"`
+	StaffID     int64      `json:"staff_id"`
+	StaffName   string     `json:"staff_name"`
+	Amount      int64      `json:"amount"`
+	Description string     `json:"description"`
+	SpentOn     time.Time  `json:"spent_on"`
+	Status      string     `json:"status"`
+	HasReceipt  bool       `json:"has_receipt"`
+	ReviewedBy  *int64     `json:"reviewed_by"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	ReviewNote  string     `json:"review_note"`
+	PayslipID   *int64     `json:"payslip_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// PayrollFilter narrows payslip and expense listings; zero values match
+// everything.
+type PayrollFilter struct {
+	StaffKind string
+	StaffID   int64
+	Period    time.Time
+	Status    string
+}
+
+type PayrollStore struct {
+	db *routedDB
+}
+
+// staffNameSQL is the name of the teacher or exec a row with staff_kind and
+// staff_id columns belongs to.
+func staffNameSQL(alias string) string {
+	return fmt.Sprintf(`COALESCE(CASE %[1]s.staff_kind
+		WHEN 'teacher' THEN (SELECT first_name || ' ' || last_name FROM teachers WHERE id = %[1]s.staff_id)
+		WHEN 'exec' THEN (SELECT first_name || ' ' || last_name FROM execs WHERE id = %[1]s.staff_id)
+	END, '')`, alias)
+}
+
+// CreateContract adds a contract. It returns ErrNotFound when the staff
+// member does not exist and ErrConflict when they already have an open
+// contract.
+func (s *PayrollStore) CreateContract(ctx context.Context, c *StaffContract) error {
+	query := `
+		INSERT INTO staff_contracts (staff_kind, staff_id, title, base_salary, allowances, deductions, starts_on, ends_on)
+		SELECT $1, $2, $3, $4, $5, $6, $7::date, $8::date
+		WHERE EXISTS (
+			SELECT 1 FROM teachers WHERE $1 = 'teacher' AND id = $2 AND deleted_at IS NULL
+			UNION ALL
+			SELECT 1 FROM execs WHERE $1 = 'exec' AND id = $2
+		)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var endsOn *string
+	if c.EndsOn != nil {
+		d := c.EndsOn.Format(time.DateOnly)
+		endsOn = &d
+	}
+	err := s.db.QueryRowContext(ctx, query,
+		c.StaffKind, c.StaffID, c.Title, c.BaseSalary, c.Allowances, c.Deductions, c.StartsOn.Format(time.DateOnly), endsOn,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case isUniqueViolation(err):
+		return ErrConflict
+	}
+	return err
+}
+
+// Contracts lists contracts, only those still open when current is set.
+func (s *PayrollStore) Contracts(ctx context.Context, current bool) ([]*StaffContract, error) {
+	query := `
+		SELECT c.id, c.staff_kind, c.staff_id, ` + staffNameSQL("c") + `, c.title, c.base_salary, c.allowances,
+			c.deductions, c.starts_on, c.ends_on, c.created_at, c.updated_at
+		FROM staff_contracts c
+		WHERE NOT $1 OR c.ends_on IS NULL OR c.ends_on >= CURRENT_DATE
+		ORDER BY c.staff_kind, c.staff_id, c.starts_on DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, current)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contracts := []*StaffContract{}
+	for rows.Next() {
+		var c StaffContract
+		if err := rows.Scan(&c.ID, &c.StaffKind, &c.StaffID, &c.StaffName, &c.Title, &c.BaseSalary, &c.Allowances,
+			&c.Deductions, &c.StartsOn, &c.EndsOn, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, &c)
+	}
+	return contracts, rows.Err()
+}
+
+// EndContract sets the last day of an open contract. It returns
+// ErrNotFound when there is no such open contract.
+func (s *PayrollStore) EndContract(ctx context.Context, id int64, endsOn time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE staff_contracts SET ends_on = GREATEST($2::date, starts_on), updated_at = NOW() WHERE id = $1 AND ends_on IS NULL`,
+		id, endsOn.Format(time.DateOnly),
+	)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// GeneratePayslips issues the payslips of the month starting at period for
+// every contract running during that month that has none yet, paying out
+// the staff member's approved, unpaid expense claims with it. It returns
+// how many payslips were issued.
+func (s *PayrollStore) GeneratePayslips(ctx context.Context, period time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	month := period.Format(time.DateOnly)
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO payslips (contract_id, staff_kind, staff_id, period, base_salary, allowances, reimbursements, deductions, net)
+		SELECT c.id, c.staff_kind, c.staff_id, $1::date, c.base_salary, c.allowances, r.total, c.deductions,
+			c.base_salary + c.allowances + r.total - c.deductions
+		FROM staff_contracts c
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(amount), 0) AS total
+			FROM expense_claims e
+			WHERE e.staff_kind = c.staff_kind AND e.staff_id = c.staff_id
+				AND e.status = 'approved' AND e.payslip_id IS NULL
+		) r
+		WHERE c.starts_on < ($1::date + INTERVAL '1 month')
+			AND (c.ends_on IS NULL OR c.ends_on >= $1::date)
+		ON CONFLICT (staff_kind, staff_id, period) DO NOTHING
+		RETURNING id
+	`, month)
+	if err != nil {
+		return 0, err
+	}
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE expense_claims e
+		SET payslip_id = p.id
+		FROM payslips p
+		WHERE p.id = ANY($1::bigint[]) AND e.staff_kind = p.staff_kind AND e.staff_id = p.staff_id
+			AND e.status = 'approved' AND e.payslip_id IS NULL
+	`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+const payslipColumns = `
+	p.id, p.contract_id, p.staff_kind, p.staff_id, %s, c.title, p.period, p.base_salary, p.allowances,
+	p.reimbursements, p.deductions, p.net, p.created_at`
+
+func scanPayslip(row interface{ Scan(...any) error }, p *Payslip) error {
+	return row.Scan(&p.ID, &p.ContractID, &p.StaffKind, &p.StaffID, &p.StaffName, &p.Title, &p.Period, &p.BaseSalary,
+		&p.Allowances, &p.Reimbursements, &p.Deductions, &p.Net, &p.CreatedAt)
+}
+
+// Payslips lists payslips matching f, newest month first.
+func (s *PayrollStore) Payslips(ctx context.Context, f PayrollFilter) ([]*Payslip, error) {
+	query := `
+		SELECT ` + fmt.Sprintf(payslipColumns, staffNameSQL("p")) + `
+		FROM payslips p
+		JOIN staff_contracts c ON c.id = p.contract_id
+		WHERE ($1 = '' OR p.staff_kind = $1) AND ($2 = 0 OR p.staff_id = $2)
+			AND ($3 = '' OR p.period = NULLIF($3, '')::date)
+		ORDER BY p.period DESC, p.staff_kind, p.staff_id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	period := ""
+	if !f.Period.IsZero() {
+		period = f.Period.Format(time.DateOnly)
+	}
+	rows, err := s.db.QueryContext(ctx, query, f.StaffKind, f.StaffID, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payslips := []*Payslip{}
+	for rows.Next() {
+		var p Payslip
+		if err := scanPayslip(rows, &p); err != nil {
+			return nil, err
+		}
+		payslips = append(payslips, &p)
+	}
+	return payslips, rows.Err()
+}
+
+func (s *PayrollStore) Payslip(ctx context.Context, id int64) (*Payslip, error) {
+	query := `
+		SELECT ` + fmt.Sprintf(payslipColumns, staffNameSQL("p")) + `
+		FROM payslips p
+		JOIN staff_contracts c ON c.id = p.contract_id
+		WHERE p.id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var p Payslip
+	if err := scanPayslip(s.db.QueryRowContext(ctx, query, id), &p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PayslipExpenses returns the expense claims paid with a payslip.
+func (s *PayrollStore) PayslipExpenses(ctx context.Context, payslipID int64) ([]*ExpenseClaim, error) {
+	return s.expenses(ctx, "e.payslip_id = $1", payslipID)
+}
+
+// CreateExpense files a pending expense claim.
+func (s *PayrollStore) CreateExpense(ctx context.Context, e *ExpenseClaim) error {
+	query := `
+		INSERT INTO expense_claims (staff_kind, staff_id, amount, description, spent_on)
+		VALUES ($1, $2, $3, $4, $5::date)
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		e.StaffKind, e.StaffID, e.Amount, e.Description, e.SpentOn.Format(time.DateOnly),
+	).Scan(&e.ID, &e.Status, &e.CreatedAt)
+}
+
+// Expenses lists expense claims matching f, newest first.
+func (s *PayrollStore) Expenses(ctx context.Context, f PayrollFilter) ([]*ExpenseClaim, error) {
+	return s.expenses(ctx, "($1 = '' OR e.staff_kind = $1) AND ($2 = 0 OR e.staff_id = $2) AND ($3 = '' OR e.status = $3)",
+		f.StaffKind, f.StaffID, f.Status)
+}
+
+func (s *PayrollStore) Expense(ctx context.Context, id int64) (*ExpenseClaim, error) {
+	expenses, err := s.expenses(ctx, "e.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(expenses) == 0 {
+		return nil, ErrNotFound
+	}
+	return expenses[0], nil
+}
+
+func (s *PayrollStore) expenses(ctx context.Context, where string, args ...any) ([]*ExpenseClaim, error) {
+	query := `
+		SELECT e.id, e.staff_kind, e.staff_id, ` + staffNameSQL("e") + `, e.amount, e.description, e.spent_on, e.status,
+			e.receipt IS NOT NULL, e.reviewed_by, e.reviewed_at, e.review_note, e.payslip_id, e.created_at
+		FROM expense_claims e
+		WHERE ` + where + `
+		ORDER BY e.created_at DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []*ExpenseClaim{}
+	for rows.Next() {
+		var e ExpenseClaim
+		if err := rows.Scan(&e.ID, &e.StaffKind, &e.StaffID, &e.StaffName, &e.Amount, &e.Description, &e.SpentOn,
+			&e.Status, &e.HasReceipt, &e.ReviewedBy, &e.ReviewedAt, &e.ReviewNote, &e.PayslipID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, &e)
+	}
+	return expenses, rows.Err()
+}
+
+// SetExpenseReceipt attaches a receipt to a pending claim. It returns
+// ErrNotFound when the claim is not a pending one.
+func (s *PayrollStore) SetExpenseReceipt(ctx context.Context, id int64, data []byte, contentType string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE expense_claims SET receipt = $2, receipt_type = $3 WHERE id = $1 AND status = 'pending'`,
+		id, data, contentType,
+	)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// ExpenseReceipt returns a claim's receipt and its content type.
+func (s *PayrollStore) ExpenseReceipt(ctx context.Context, id int64) ([]byte, string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var data []byte
+	var contentType string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT receipt, receipt_type FROM expense_claims WHERE id = $1 AND receipt IS NOT NULL`, id,
+	).Scan(&data, &contentType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+	return data, contentType, nil
+}
+
+// ReviewExpense approves or rejects a pending claim. It returns
+// ErrNotFound when the claim does not exist and ErrConflict when it was
+// already reviewed.
+func (s *PayrollStore) ReviewExpense(ctx context.Context, e *ExpenseClaim, status string, reviewerID int64, note string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE expense_claims
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), review_note = $4
+		WHERE id = $1 AND status = 'pending'
+		RETURNING status, reviewed_by, reviewed_at, review_note
+	`, e.ID, status, reviewerID, note).Scan(&e.Status, &e.ReviewedBy, &e.ReviewedAt, &e.ReviewNote)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM expense_claims WHERE id = $1)`, e.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrConflict
+	}
+	return ErrNotFound
+}
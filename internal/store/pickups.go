@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PickupContact is a person authorized to pick a student up.
+type PickupContact struct {
+	ID          int64     `json:"id"`
+	StudentID   int64     `json:"student_id"`
+	Name        string    `json:"name"`
+	Relation    string    `json:"relation"`
+	PhoneNumber string    `json:"phone_number"`
+	HasPhoto    bool      `json:"has_photo"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PickupCheckout is a logged gate check.
+type PickupCheckout struct {
+	ID            int64     `json:"id"`
+	StudentID     int64     `json:"student_id"`
+	ContactID     *int64    `json:"contact_id"`
+	ContactName   string    `json:"contact_name"`
+	Authorized    bool      `json:"authorized"`
+	Note          string    `json:"note"`
+	CheckedBy     int64     `json:"checked_by"`
+	CheckedByRole string    `json:"checked_by_role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type PickupStore struct {
+	db *routedDB
+}
+
+const pickupContactColumns = `id, student_id, name, relation, phone_number, photo IS NOT NULL, active, created_at, updated_at`
+
+func scanPickupContact(row interface{ Scan(...any) error }) (*PickupContact, error) {
+	var c PickupContact
+	err := row.Scan(&c.ID, &c.StudentID, &c.Name, &c.Relation, &c.PhoneNumber, &c.HasPhoto, &c.Active, &c.CreatedAt, &c.UpdatedAt)
+	return &c, err
+}
+
+// ForStudent returns a student's pickup contacts, active ones first.
+func (s *PickupStore) ForStudent(ctx context.Context, studentID int64) ([]*PickupContact, error) {
+	query := `
+		SELECT ` + pickupContactColumns + `
+		FROM pickup_contacts
+		WHERE student_id = $1
+		ORDER BY active DESC, name, id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []*PickupContact{}
+	for rows.Next() {
+		c, err := scanPickupContact(rows)
+		if err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, c)
+	}
+
+	return contacts, rows.Err()
+}
+
+func (s *PickupStore) Get(ctx context.Context, studentID, id int64) (*PickupContact, error) {
+	query := `SELECT ` + pickupContactColumns + ` FROM pickup_contacts WHERE id = $1 AND student_id = $2`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	c, err := scanPickupContact(s.db.QueryRowContext(ctx, query, id, studentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *PickupStore) Create(ctx context.Context, c *PickupContact) error {
+	query := `
+		INSERT INTO pickup_contacts (student_id, name, relation, phone_number)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, active, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, c.StudentID, c.Name, c.Relation, c.PhoneNumber).
+		Scan(&c.ID, &c.Active, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (s *PickupStore) Update(ctx context.Context, c *PickupContact) error {
+	query := `
+		UPDATE pickup_contacts
+		SET name = $3, relation = $4, phone_number = $5, active = $6, updated_at = NOW()
+		WHERE id = $1 AND student_id = $2
+		RETURNING updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, c.ID, c.StudentID, c.Name, c.Relation, c.PhoneNumber, c.Active).
+		Scan(&c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *PickupStore) Delete(ctx context.Context, studentID, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM pickup_contacts WHERE id = $1 AND student_id = $2`, id, studentID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// SetPhoto replaces a contact's photo, clearing its scaled copies; nil data
+// removes it.
+func (s *PickupStore) SetPhoto(ctx context.Context, studentID, id int64, data []byte, contentType string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		WITH cleared AS (
+			DELETE FROM pickup_photo_variants
+			WHERE contact_id = (SELECT id FROM pickup_contacts WHERE id = $1 AND student_id = $2)
+		)
+		UPDATE pickup_contacts
+		SET photo = $3, photo_type = $4, updated_at = NOW()
+		WHERE id = $1 AND student_id = $2
+	`, id, studentID, data, contentType)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Photo returns a contact's photo and its content type.
+func (s *PickupStore) Photo(ctx context.Context, id int64) ([]byte, string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		data        []byte
+		contentType string
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT photo, photo_type FROM pickup_contacts WHERE id = $1 AND photo IS NOT NULL`, id,
+	).Scan(&data, &contentType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+	return data, contentType, nil
+}
+
+// PhotoVariant returns a scaled copy of a contact's photo, by size name.
+func (s *PickupStore) PhotoVariant(ctx context.Context, id int64, size string) ([]byte, string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var (
+		data        []byte
+		contentType string
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT data, content_type FROM pickup_photo_variants WHERE contact_id = $1 AND size = $2`, id, size,
+	).Scan(&data, &contentType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+	return data, contentType, nil
+}
+
+// SetPhotoVariant stores a scaled copy of a contact's photo, replacing the
+// one of that size.
+func (s *PickupStore) SetPhotoVariant(ctx context.Context, id int64, size string, data []byte, contentType string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pickup_photo_variants (contact_id, size, data, content_type)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (contact_id, size) DO UPDATE
+		SET data = EXCLUDED.data, content_type = EXCLUDED.content_type, created_at = NOW()
+	`, id, size, data, contentType)
+	return err
+}
+
+func (s *PickupStore) LogCheckout(ctx context.Context, c *PickupCheckout) error {
+	query := `
+		INSERT INTO pickup_checkouts (student_id, contact_id, contact_name, authorized, note, checked_by, checked_by_role)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		c.StudentID, c.ContactID, c.ContactName, c.Authorized, c.Note, c.CheckedBy, c.CheckedByRole,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+// Checkouts returns gate checks between from and to (inclusive dates),
+// newest first, for one student when studentID is non-zero.
+func (s *PickupStore) Checkouts(ctx context.Context, studentID int64, from, to time.Time) ([]*PickupCheckout, error) {
+	query := `
+		SELECT id, student_id, contact_id, contact_name, authorized, note, checked_by, checked_by_role, created_at
+		FROM pickup_checkouts
+		WHERE ($1 = 0 OR student_id = $1)
+		  AND created_at >= $2::date AND created_at < $3::date + 1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1000
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, studentID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkouts := []*PickupCheckout{}
+	for rows.Next() {
+		var c PickupCheckout
+		if err := rows.Scan(&c.ID, &c.StudentID, &c.ContactID, &c.ContactName, &c.Authorized, &c.Note,
+			&c.CheckedBy, &c.CheckedByRole, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		checkouts = append(checkouts, &c)
+	}
+
+	return checkouts, rows.Err()
+}
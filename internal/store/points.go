@@ -0,0 +1,292 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PointCategory is a reason behavior points are given for. Points is the
+// default value of an award, negative for deductions.
+type PointCategory struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Points    int       `json:"points"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PointAward is points given to (or taken from) a student.
+type PointAward struct {
+	ID            int64     `json:"id"`
+	StudentID     int64     `json:"student_id"`
+	ClassroomID   *int64    `json:"classroom_id"`
+	CategoryID    int64     `json:"category_id"`
+	Category      string    `json:"category"`
+	Points        int       `json:"points"`
+	Reason        string    `json:"reason"`
+	AwardedBy     int64     `json:"awarded_by"`
+	AwardedByRole string    `json:"awarded_by_role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LeaderboardEntry is a student's point total in a classroom.
+type LeaderboardEntry struct {
+	Rank      int    `json:"rank"`
+	StudentID int64  `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Points    int64  `json:"points"`
+	Awards    int64  `json:"awards"`
+}
+
+// PointSummary is a student's points over a period, for their parent.
+type PointSummary struct {
+	StudentID   int64
+	StudentName string
+	ParentName  string
+	ParentPhone string
+	Earned      int64
+	Deducted    int64
+}
+
+type PointStore struct {
+	db *routedDB
+}
+
+// ListCategories returns point categories by name, inactive ones only when
+// asked.
+func (s *PointStore) ListCategories(ctx context.Context, includeInactive bool) ([]*PointCategory, error) {
+	query := `
+		SELECT id, name, points, active, created_at
+		FROM point_categories
+		WHERE active OR $1
+		ORDER BY name
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*PointCategory{}
+	for rows.Next() {
+		var c PointCategory
+		if err := rows.Scan(&c.ID, &c.Name, &c.Points, &c.Active, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+
+	return categories, rows.Err()
+}
+
+func (s *PointStore) GetCategory(ctx context.Context, id int64) (*PointCategory, error) {
+	query := `SELECT id, name, points, active, created_at FROM point_categories WHERE id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var c PointCategory
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name, &c.Points, &c.Active, &c.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCategory adds a category; names are unique (ErrConflict).
+func (s *PointStore) CreateCategory(ctx context.Context, c *PointCategory) error {
+	query := `
+		INSERT INTO point_categories (name, points)
+		VALUES ($1, $2)
+		RETURNING id, active, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, c.Name, c.Points).Scan(&c.ID, &c.Active, &c.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *PointStore) UpdateCategory(ctx context.Context, c *PointCategory) error {
+	query := `
+		UPDATE point_categories
+		SET name = $2, points = $3, active = $4
+		WHERE id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, c.ID, c.Name, c.Points, c.Active)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Award records points for a student in their current classroom. It returns
+// ErrNotFound when the category does not exist or is inactive.
+func (s *PointStore) Award(ctx context.Context, a *PointAward) error {
+	query := `
+		INSERT INTO behavior_points (student_id, classroom_id, category_id, points, reason, awarded_by, awarded_by_role)
+		SELECT $1, (SELECT classroom_id FROM students WHERE id = $1), pc.id, $3, $4, $5, $6
+		FROM point_categories pc
+		WHERE pc.id = $2 AND pc.active
+		RETURNING id, classroom_id, (SELECT name FROM point_categories WHERE id = $2), created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		a.StudentID, a.CategoryID, a.Points, a.Reason, a.AwardedBy, a.AwardedByRole,
+	).Scan(&a.ID, &a.ClassroomID, &a.Category, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ForStudent returns a student's awards between from and to (inclusive
+// dates), newest first.
+func (s *PointStore) ForStudent(ctx context.Context, studentID int64, from, to time.Time) ([]*PointAward, error) {
+	query := `
+		SELECT b.id, b.student_id, b.classroom_id, b.category_id, pc.name, b.points, b.reason,
+		       b.awarded_by, b.awarded_by_role, b.created_at
+		FROM behavior_points b
+		JOIN point_categories pc ON pc.id = b.category_id
+		WHERE b.student_id = $1
+		  AND b.created_at >= $2::date AND b.created_at < $3::date + 1
+		ORDER BY b.created_at DESC, b.id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, studentID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	awards := []*PointAward{}
+	for rows.Next() {
+		var a PointAward
+		if err := rows.Scan(
+			&a.ID, &a.StudentID, &a.ClassroomID, &a.CategoryID, &a.Category, &a.Points, &a.Reason,
+			&a.AwardedBy, &a.AwardedByRole, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		awards = append(awards, &a)
+	}
+
+	return awards, rows.Err()
+}
+
+// Leaderboard ranks the current students of a classroom by the points they
+// earned there between from and to. Students without awards are included
+// with zero points; ties share a rank.
+func (s *PointStore) Leaderboard(ctx context.Context, classroomID int64, from, to time.Time) ([]*LeaderboardEntry, error) {
+	query := `
+		SELECT RANK() OVER (ORDER BY COALESCE(SUM(b.points), 0) DESC),
+		       s.id, s.first_name, s.last_name, COALESCE(SUM(b.points), 0), COUNT(b.id)
+		FROM students s
+		LEFT JOIN behavior_points b
+		       ON b.student_id = s.id AND b.classroom_id = s.classroom_id
+		      AND b.created_at >= $2::date AND b.created_at < $3::date + 1
+		WHERE s.classroom_id = $1 AND s.deleted_at IS NULL
+		GROUP BY s.id, s.first_name, s.last_name
+		ORDER BY 1, s.last_name, s.first_name
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, classroomID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*LeaderboardEntry{}
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.Rank, &e.StudentID, &e.FirstName, &e.LastName, &e.Points, &e.Awards); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Summaries returns per-student totals between from and to (inclusive dates)
+// for live students with a parent phone number and at least one award.
+func (s *PointStore) Summaries(ctx context.Context, from, to time.Time) ([]*PointSummary, error) {
+	query := `
+		SELECT s.id, s.first_name || ' ' || s.last_name, s.parent_name, s.parent_phone_number,
+		       COALESCE(SUM(b.points) FILTER (WHERE b.points > 0), 0),
+		       COALESCE(-SUM(b.points) FILTER (WHERE b.points < 0), 0)
+		FROM behavior_points b
+		JOIN students s ON s.id = b.student_id AND s.deleted_at IS NULL
+		WHERE b.created_at >= $1::date AND b.created_at < $2::date + 1
+		  AND s.parent_phone_number <> ''
+		GROUP BY s.id
+		ORDER BY s.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []*PointSummary{}
+	for rows.Next() {
+		var p PointSummary
+		if err := rows.Scan(&p.StudentID, &p.StudentName, &p.ParentName, &p.ParentPhone, &p.Earned, &p.Deducted); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &p)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ClaimSummary records that a student's summary for week is being sent. It
+// reports false when it was already claimed.
+func (s *PointStore) ClaimSummary(ctx context.Context, studentID int64, week time.Time) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO point_summaries (student_id, week) VALUES ($1, $2::date) ON CONFLICT DO NOTHING`,
+		studentID, week.Format(time.DateOnly),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Workload is what a query is run for. Each workload has its own
+// connection pool, so a burst of background jobs or a slow report cannot
+// take the connections interactive requests need.
+type Workload int
+
+const (
+	// WorkloadWorker is background jobs and scheduled tasks. Queries whose
+	// context names no workload count as this.
+	WorkloadWorker Workload = iota
+	WorkloadAPI
+	WorkloadReporting
+)
+
+type workloadKey struct{}
+
+// WithWorkload returns a context whose queries run on w's pool.
+func WithWorkload(ctx context.Context, w Workload) context.Context {
+	return context.WithValue(ctx, workloadKey{}, w)
+}
+
+// Pools are the connection pools of the workloads. A nil Worker or
+// Reporting pool shares the API pool.
+type Pools struct {
+	API       *sql.DB
+	Worker    *sql.DB
+	Reporting *sql.DB
+	// PgBouncer avoids named prepared statements, which do not survive
+	// pgbouncer's transaction pooling.
+	PgBouncer bool
+}
+
+// routedDB runs each query on the pool of the workload in its context.
+type routedDB struct {
+	pools     [3]*sql.DB
+	pgbouncer bool
+}
+
+func newRoutedDB(p Pools) *routedDB {
+	db := &routedDB{pools: [3]*sql.DB{p.Worker, p.API, p.Reporting}, pgbouncer: p.PgBouncer}
+	for i := range db.pools {
+		if db.pools[i] == nil {
+			db.pools[i] = p.API
+		}
+	}
+	return db
+}
+
+func (db *routedDB) pool(ctx context.Context) *sql.DB {
+	w, _ := ctx.Value(workloadKey{}).(Workload)
+	if w < 0 || int(w) >= len(db.pools) {
+		w = WorkloadWorker
+	}
+	return db.pools[w]
+}
+
+func (db *routedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.pool(ctx).BeginTx(ctx, opts)
+}
+
+func (db *routedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.pool(ctx).ExecContext(ctx, query, args...)
+}
+
+func (db *routedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.pool(ctx).QueryContext(ctx, query, args...)
+}
+
+func (db *routedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.pool(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// txStmt is a statement run many times in one transaction.
+type txStmt interface {
+	ExecContext(ctx context.Context, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, args ...any) *sql.Row
+	Close() error
+}
+
+// prepare prepares query in tx. Behind pgbouncer the query is sent afresh
+// each time instead: a named statement stays on the server connection
+// after the transaction, where another client may collide with its name.
+func (db *routedDB) prepare(ctx context.Context, tx *sql.Tx, query string) (txStmt, error) {
+	if db.pgbouncer {
+		return unpreparedStmt{tx, query}, nil
+	}
+	return tx.PrepareContext(ctx, query)
+}
+
+type unpreparedStmt struct {
+	tx    *sql.Tx
+	query string
+}
+
+func (s unpreparedStmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	return s.tx.ExecContext(ctx, s.query, args...)
+}
+
+func (s unpreparedStmt) QueryRowContext(ctx context.Context, args ...any) *sql.Row {
+	return s.tx.QueryRowContext(ctx, s.query, args...)
+}
+
+func (s unpreparedStmt) Close() error { return nil }
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// QuarantinedUpload is a file the upload checks refused: one that failed
+// the malware scan or looked unsafe. Its content is kept, but never
+// served.
+type QuarantinedUpload struct {
+	ID          int64  `json:"id"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Reason      string `json:"reason"`
+	// Signature is what the scanner found, when it found something.
+	Signature    string    `json:"signature,omitempty"`
+	UploaderRole string    `json:"uploader_role"`
+	UploaderID   int64     `json:"uploader_id"`
+	IP           string    `json:"ip"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type QuarantineStore struct {
+	db *routedDB
+}
+
+func (s *QuarantineStore) Create(ctx context.Context, q *QuarantinedUpload, data []byte) error {
+	query := `
+		INSERT INTO quarantined_uploads (kind, content_type, size, data, reason, signature, uploader_role, uploader_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, q.Kind, q.ContentType, q.Size, data, q.Reason, q.Signature,
+		q.UploaderRole, q.UploaderID, q.IP).Scan(&q.ID, &q.CreatedAt)
+}
+
+// List returns the quarantined files, newest first, without their content.
+func (s *QuarantineStore) List(ctx context.Context) ([]*QuarantinedUpload, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, kind, content_type, size, reason, signature, uploader_role, uploader_id, ip, created_at
+		FROM quarantined_uploads
+		ORDER BY created_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*QuarantinedUpload{}
+	for rows.Next() {
+		var q QuarantinedUpload
+		if err := rows.Scan(&q.ID, &q.Kind, &q.ContentType, &q.Size, &q.Reason, &q.Signature,
+			&q.UploaderRole, &q.UploaderID, &q.IP, &q.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, &q)
+	}
+	return list, rows.Err()
+}
+
+func (s *QuarantineStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM quarantined_uploads WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReceiptPrefix starts the numbers of new receipts, e.g. "R-000123"; each
+// prefix is numbered on its own. It is set from configuration at startup.
+var ReceiptPrefix = "R"
+
+const receiptCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Receipt is proof of a confirmed payment. Code must be given with Number
+// to verify it.
+type Receipt struct {
+	Number       string    `json:"number"`
+	Code         string    `json:"-"`
+	PaymentID    int64     `json:"payment_id"`
+	InvoiceID    int64     `json:"invoice_id"`
+	InvoiceTitle string    `json:"invoice_title"`
+	Term         string    `json:"term"`
+	StudentName  string    `json:"student_name"`
+	Amount       int64     `json:"amount"`
+	Gateway      string    `json:"gateway"`
+	RefID        string    `json:"ref_id"`
+	CardPAN      string    `json:"card_pan"`
+	PaidAt       time.Time `json:"paid_at"`
+	IssuedAt     time.Time `json:"issued_at"`
+	// InstallmentSeq is the installment paid, of Installments, when the
+	// invoice is paid in more than one.
+	InstallmentSeq *int `json:"installment_seq,omitempty"`
+	Installments   int  `json:"installments"`
+	// ParentPhone is the student's parent's number, who may download it.
+	ParentPhone string `json:"-"`
+}
+
+type ReceiptStore struct {
+	db *routedDB
+}
+
+// issueReceipt numbers a receipt for a payment inside tx and returns its
+// number. Numbers are taken under a row lock, so concurrent payments wait
+// for each other and a rolled back payment leaves no gap.
+func issueReceipt(ctx context.Context, tx *sql.Tx, paymentID int64) (string, error) {
+	code, err := newReceiptCode()
+	if err != nil {
+		return "", err
+	}
+
+	var seq int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO receipt_counters (prefix, last_seq) VALUES ($1, 1)
+		ON CONFLICT (prefix) DO UPDATE SET last_seq = receipt_counters.last_seq + 1
+		RETURNING last_seq
+	`, ReceiptPrefix).Scan(&seq)
+	if err != nil {
+		return "", err
+	}
+
+	number := fmt.Sprintf("%s-%06d", ReceiptPrefix, seq)
+	_, err = tx.ExecContext(ctx, `INSERT INTO receipts (number, payment_id, code) VALUES ($1, $2, $3)`, number, paymentID, code)
+	return number, err
+}
+
+func newReceiptCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = receiptCodeAlphabet[int(b[i])%len(receiptCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+func (s *ReceiptStore) GetByNumber(ctx context.Context, number string) (*Receipt, error) {
+	query := `
+		SELECT r.number, r.code, p.id, i.id, i.title, i.term, s.first_name || ' ' || s.last_name,
+			p.amount, p.gateway, p.ref_id, p.card_pan, p.verified_at, r.issued_at, n.seq,
+			(SELECT COUNT(*) FROM invoice_installments WHERE invoice_id = i.id), s.parent_phone_number
+		FROM receipts r
+		JOIN payments p ON p.id = r.payment_id
+		JOIN invoices i ON i.id = p.invoice_id
+		JOIN students s ON s.id = i.student_id
+		LEFT JOIN invoice_installments n ON n.id = p.installment_id
+		WHERE r.number = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var r Receipt
+	err := s.db.QueryRowContext(ctx, query, number).Scan(&r.Number, &r.Code, &r.PaymentID, &r.InvoiceID,
+		&r.InvoiceTitle, &r.Term, &r.StudentName, &r.Amount, &r.Gateway, &r.RefID, &r.CardPAN, &r.PaidAt,
+		&r.IssuedAt, &r.InstallmentSeq, &r.Installments, &r.ParentPhone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Installments < 2 {
+		r.InstallmentSeq = nil
+	}
+	return &r, nil
+}
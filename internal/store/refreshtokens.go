@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RefreshToken is a stored refresh token, identified by the hash of the
+// token the client holds.
+type RefreshToken struct {
+	AccountID  int64
+	RememberMe bool
+	ExpiresAt  time.Time
+}
+
+type RefreshTokenStore struct {
+	db *routedDB
+}
+
+// Create stores a refresh token and clears the account's expired ones.
+func (s *RefreshTokenStore) Create(ctx context.Context, hash []byte, t *RefreshToken) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx,
+		`DELETE FROM refresh_tokens WHERE account_id = $1 AND expires_at < NOW()`,
+		t.AccountID,
+	); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (account_id, token_hash, remember_me, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, t.AccountID, hash, t.RememberMe, t.ExpiresAt)
+	return err
+}
+
+// Consume deletes the refresh token with hash and returns it, so a token
+// works once. An unknown or expired token is ErrNotFound.
+func (s *RefreshTokenStore) Consume(ctx context.Context, hash []byte) (*RefreshToken, error) {
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE token_hash = $1
+		RETURNING account_id, remember_me, expires_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var t RefreshToken
+	err := s.db.QueryRowContext(ctx, query, hash).Scan(&t.AccountID, &t.RememberMe, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if time.Now().After(t.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
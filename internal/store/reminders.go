@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// MissingAttendance is a classroom with students but no attendance for a day,
+// with the teacher to remind.
+type MissingAttendance struct {
+	ClassroomID   int64
+	ClassroomName string
+	TeacherID     int64
+	TeacherName   string
+	TeacherEmail  string
+	TeacherPhone  string
+}
+
+// ReminderCompliance summarizes how often a teacher needed an attendance
+// reminder in a period.
+type ReminderCompliance struct {
+	TeacherID   int64  `json:"teacher_id"`
+	TeacherName string `json:"teacher_name"`
+	Reminders   int64  `json:"reminders"`
+	MarkedLate  int64  `json:"marked_late"`
+	NeverMarked int64  `json:"never_marked"`
+	Chronic     bool   `json:"chronic"`
+}
+
+type ReminderStore struct {
+	db *routedDB
+}
+
+// MissingAttendance returns live classrooms with at least one student and a
+// teacher, that have no attendance record on date.
+func (s *ReminderStore) MissingAttendance(ctx context.Context, date time.Time) ([]*MissingAttendance, error) {
+	query := `
+		SELECT c.id, c.name, t.id, t.first_name || ' ' || t.last_name, t.email, t.phone_number
+		FROM classrooms c
+		JOIN teachers t ON t.id = c.teacher_id AND t.deleted_at IS NULL
+		WHERE c.deleted_at IS NULL
+		  AND EXISTS (SELECT 1 FROM students s WHERE s.classroom_id = c.id AND s.deleted_at IS NULL)
+		  AND NOT EXISTS (SELECT 1 FROM attendance_records a WHERE a.classroom_id = c.id AND a.date = $1::date)
+		ORDER BY c.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, date.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missing := []*MissingAttendance{}
+	for rows.Next() {
+		var m MissingAttendance
+		if err := rows.Scan(&m.ClassroomID, &m.ClassroomName, &m.TeacherID, &m.TeacherName, &m.TeacherEmail, &m.TeacherPhone); err != nil {
+			return nil, err
+		}
+		missing = append(missing, &m)
+	}
+
+	return missing, rows.Err()
+}
+
+// Claim records that a classroom's reminder for date is being sent. It
+// reports false when another run already claimed it, so each classroom is
+// reminded at most once a day across instances.
+func (s *ReminderStore) Claim(ctx context.Context, classroomID, teacherID int64, date time.Time) (bool, error) {
+	query := `
+		INSERT INTO attendance_reminders (classroom_id, date, teacher_id)
+		VALUES ($1, $2::date, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, classroomID, date.Format(time.DateOnly), teacherID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MarkDelivered records the channels ("email,sms") a reminder went out on.
+func (s *ReminderStore) MarkDelivered(ctx context.Context, classroomID int64, date time.Time, via string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE attendance_reminders SET delivered_via = $3 WHERE classroom_id = $1 AND date = $2::date`,
+		classroomID, date.Format(time.DateOnly), via,
+	)
+	return err
+}
+
+// Compliance returns, per teacher reminded between from and to (inclusive),
+// how many reminders they got and whether attendance was taken afterwards.
+// Teachers with at least minReminders are flagged as chronic.
+func (s *ReminderStore) Compliance(ctx context.Context, from, to time.Time, minReminders int, scope Scope) ([]*ReminderCompliance, error) {
+	where := "r.date BETWEEN $1::date AND $2::date"
+	if c := scope.classroomCondition("r.classroom_id"); c != "" {
+		where += " AND " + c
+	}
+	query := `
+		SELECT t.id, t.first_name || ' ' || t.last_name,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE marked),
+		       COUNT(*) FILTER (WHERE NOT marked)
+		FROM (
+			SELECT r.teacher_id,
+			       EXISTS (
+					SELECT 1 FROM attendance_records a
+					WHERE a.classroom_id = r.classroom_id AND a.date = r.date
+			       ) AS marked
+			FROM attendance_reminders r
+			WHERE ` + where + `
+		) r
+		JOIN teachers t ON t.id = r.teacher_id
+		GROUP BY t.id
+		ORDER BY COUNT(*) DESC, t.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []*ReminderCompliance{}
+	for rows.Next() {
+		var c ReminderCompliance
+		if err := rows.Scan(&c.TeacherID, &c.TeacherName, &c.Reminders, &c.MarkedLate, &c.NeverMarked); err != nil {
+			return nil, err
+		}
+		c.Chronic = c.Reminders >= int64(minReminders)
+		report = append(report, &c)
+	}
+
+	return report, rows.Err()
+}
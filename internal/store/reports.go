@@ -0,0 +1,324 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ReportRunRunning   = "running"
+	ReportRunDelivered = "delivered"
+	ReportRunFailed    = "failed"
+)
+
+// ReportSchedule is a report generated on a cron schedule and emailed to its
+// recipients, either attached or as a signed download link.
+type ReportSchedule struct {
+	ID         int64             `json:"id"`
+	Name       string            `json:"name"`
+	Report     string            `json:"report"`
+	Params     map[string]string `json:"params"`
+	Cron       string            `json:"cron"`
+	Format     string            `json:"format"`
+	Delivery   string            `json:"delivery"`
+	Recipients []string          `json:"recipients"`
+	Enabled    bool              `json:"enabled"`
+	NextRunAt  time.Time         `json:"next_run_at"`
+	CreatedBy  *int64            `json:"created_by,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// ReportRun is one execution of a schedule. The generated file is kept so
+// download links stay valid.
+type ReportRun struct {
+	ID          int64      `json:"id"`
+	ScheduleID  int64      `json:"schedule_id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	Filename    *string    `json:"filename,omitempty"`
+	ContentType *string    `json:"content_type,omitempty"`
+	Size        int64      `json:"size"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+type ReportStore struct {
+	db *routedDB
+}
+
+const reportScheduleColumns = `id, name, report, params, cron, format, delivery, recipients, enabled, next_run_at, created_by, created_at, updated_at`
+
+func scanReportSchedule(row interface{ Scan(...any) error }) (*ReportSchedule, error) {
+	var rs ReportSchedule
+	var params []byte
+	if err := row.Scan(
+		&rs.ID,
+		&rs.Name,
+		&rs.Report,
+		&params,
+		&rs.Cron,
+		&rs.Format,
+		&rs.Delivery,
+		pq.Array(&rs.Recipients),
+		&rs.Enabled,
+		&rs.NextRunAt,
+		&rs.CreatedBy,
+		&rs.CreatedAt,
+		&rs.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(params, &rs.Params); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+func (s *ReportStore) CreateSchedule(ctx context.Context, rs *ReportSchedule) error {
+	params, err := json.Marshal(rs.Params)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO report_schedules (name, report, params, cron, format, delivery, recipients, enabled, next_run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		rs.Name,
+		rs.Report,
+		params,
+		rs.Cron,
+		rs.Format,
+		rs.Delivery,
+		pq.Array(rs.Recipients),
+		rs.Enabled,
+		rs.NextRunAt,
+		rs.CreatedBy,
+	).Scan(&rs.ID, &rs.CreatedAt, &rs.UpdatedAt)
+}
+
+func (s *ReportStore) ListSchedules(ctx context.Context) ([]*ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + ` FROM report_schedules ORDER BY id ASC`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []*ReportSchedule{}
+	for rows.Next() {
+		rs, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, rs)
+	}
+
+	return schedules, rows.Err()
+}
+
+func (s *ReportStore) GetSchedule(ctx context.Context, id int64) (*ReportSchedule, error) {
+	query := `SELECT ` + reportScheduleColumns + ` FROM report_schedules WHERE id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rs, err := scanReportSchedule(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (s *ReportStore) SetScheduleEnabled(ctx context.Context, id int64, enabled bool, nextRunAt time.Time) error {
+	query := `
+		UPDATE report_schedules
+		SET enabled = $2, next_run_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id, enabled, nextRunAt)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+func (s *ReportStore) DeleteSchedule(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM report_schedules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// ClaimDue returns the enabled schedules due at now and moves each one's
+// next_run_at forward with next. Rows are locked with SKIP LOCKED, so several
+// API instances never claim the same run.
+func (s *ReportStore) ClaimDue(ctx context.Context, now time.Time, next func(*ReportSchedule) time.Time) ([]*ReportSchedule, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+reportScheduleColumns+`
+		FROM report_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+		FOR UPDATE SKIP LOCKED
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+
+	due := []*ReportSchedule{}
+	for rows.Next() {
+		rs, err := scanReportSchedule(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, rs)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, rs := range due {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE report_schedules SET next_run_at = $2 WHERE id = $1`,
+			rs.ID, next(rs),
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return due, tx.Commit()
+}
+
+func (s *ReportStore) StartRun(ctx context.Context, scheduleID int64) (*ReportRun, error) {
+	query := `
+		INSERT INTO report_runs (schedule_id) VALUES ($1)
+		RETURNING id, status, started_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	run := &ReportRun{ScheduleID: scheduleID}
+	err := s.db.QueryRowContext(ctx, query, scheduleID).Scan(&run.ID, &run.Status, &run.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishRun records the outcome of run along with the generated file, if any.
+func (s *ReportStore) FinishRun(ctx context.Context, run *ReportRun, content []byte) error {
+	query := `
+		UPDATE report_runs
+		SET status = $2, error = $3, filename = $4, content_type = $5, content = $6, size = $7, finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	run.Size = int64(len(content))
+	return s.db.QueryRowContext(ctx, query,
+		run.ID,
+		run.Status,
+		run.Error,
+		run.Filename,
+		run.ContentType,
+		content,
+		run.Size,
+	).Scan(&run.FinishedAt)
+}
+
+func (s *ReportStore) ListRuns(ctx context.Context, scheduleID int64, limit int) ([]*ReportRun, error) {
+	query := `
+		SELECT id, schedule_id, status, error, filename, content_type, size, started_at, finished_at
+		FROM report_runs
+		WHERE schedule_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*ReportRun{}
+	for rows.Next() {
+		var r ReportRun
+		if err := rows.Scan(&r.ID, &r.ScheduleID, &r.Status, &r.Error, &r.Filename, &r.ContentType, &r.Size, &r.StartedAt, &r.FinishedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, &r)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetRunContent returns a finished run together with its file.
+func (s *ReportStore) GetRunContent(ctx context.Context, runID int64) (*ReportRun, []byte, error) {
+	query := `
+		SELECT id, schedule_id, status, error, filename, content_type, size, started_at, finished_at, content
+		FROM report_runs
+		WHERE id = $1 AND content IS NOT NULL
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var r ReportRun
+	var content []byte
+	err := s.db.QueryRowContext(ctx, query, runID).Scan(
+		&r.ID, &r.ScheduleID, &r.Status, &r.Error, &r.Filename, &r.ContentType, &r.Size, &r.StartedAt, &r.FinishedAt, &content,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	return &r, content, nil
+}
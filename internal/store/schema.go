@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type SchemaStore struct {
+	db *routedDB
+}
+
+// Version returns the migration version the database is at, as recorded
+// by golang-migrate. dirty means a migration failed part way and the
+// schema needs fixing by hand. A database never migrated is at 0.
+func (s *SchemaStore) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err = s.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) || isUndefinedTable(err) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Scope limits a manager to some grades and/or a set of classrooms (e.g. a
+// "middle school manager" scoped to grades 7–9). A classroom is in scope
+// when its grade or its ID is listed. The zero Scope is unrestricted.
+type Scope struct {
+	Grades     []int64 `json:"grades"`
+	Classrooms []int64 `json:"classrooms"`
+}
+
+func (s Scope) Unrestricted() bool {
+	return len(s.Grades) == 0 && len(s.Classrooms) == 0
+}
+
+// Allows reports whether a classroom falls within the scope.
+func (s Scope) Allows(c *Classroom) bool {
+	return s.Unrestricted() || slices.Contains(s.Grades, c.Grade) || slices.Contains(s.Classrooms, c.ID)
+}
+
+// Key identifies the scope in cache keys; it is empty when unrestricted.
+func (s Scope) Key() string {
+	if s.Unrestricted() {
+		return ""
+	}
+	return "g" + joinIDs(s.Grades) + ":c" + joinIDs(s.Classrooms)
+}
+
+// classroomPredicate matches in-scope rows of a classrooms alias.
+func (s Scope) classroomPredicate(alias string) string {
+	parts := []string{}
+	if len(s.Grades) > 0 {
+		parts = append(parts, fmt.Sprintf("%s.grade IN (%s)", alias, joinIDs(s.Grades)))
+	}
+	if len(s.Classrooms) > 0 {
+		parts = append(parts, fmt.Sprintf("%s.id IN (%s)", alias, joinIDs(s.Classrooms)))
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// condition restricts list queries on table to rows within the scope:
+// classrooms directly, students by their classroom and teachers by the
+// classrooms they teach. The values are integers, so they are inlined
+// rather than bound.
+func (s Scope) condition(table string) string {
+	if s.Unrestricted() {
+		return ""
+	}
+	switch table {
+	case "classrooms":
+		return s.classroomPredicate("classrooms")
+	case "students":
+		return `EXISTS (SELECT 1 FROM classrooms c WHERE c.id = students.classroom_id AND c.deleted_at IS NULL AND ` +
+			s.classroomPredicate("c") + `)`
+	case "teachers":
+		return `EXISTS (SELECT 1 FROM classrooms c WHERE c.teacher_id = teachers.id AND c.deleted_at IS NULL AND ` +
+			s.classroomPredicate("c") + `)`
+	}
+	return ""
+}
+
+// classroomCondition restricts rows to those whose column, a classroom ID,
+// names a classroom within the scope. It is empty when unrestricted.
+func (s Scope) classroomCondition(column string) string {
+	if s.Unrestricted() {
+		return ""
+	}
+	return `EXISTS (SELECT 1 FROM classrooms sc WHERE sc.id = ` + column + ` AND ` + s.classroomPredicate("sc") + `)`
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchResult is a single typeahead hit.
+type SearchResult struct {
+	ID          int64   `json:"id"`
+	Type        string  `json:"type"`
+	DisplayName string  `json:"display_name"`
+	Score       float64 `json:"score"`
+}
+
+// SearchDocument is a record as fed to an external search index.
+type SearchDocument struct {
+	ID          int64
+	Type        string
+	DisplayName string
+	Email       string
+	Deleted     bool
+	ChangedAt   time.Time
+}
+
+// searchSources maps a search type to the table and display expression it
+// matches against. The expressions must match the trigram indexes.
+var searchSources = map[string]struct {
+	table       string
+	displayName string
+}{
+	"students":   {table: "students", displayName: "first_name || ' ' || last_name"},
+	"teachers":   {table: "teachers", displayName: "first_name || ' ' || last_name"},
+	"classrooms": {table: "classrooms", displayName: "name"},
+}
+
+type SearchStore struct {
+	db *routedDB
+}
+
+// Search returns the best matches for q across the given types, ranked by
+// trigram similarity with substring matches as a fallback for short queries.
+// Only records within scope are matched.
+func (s *SearchStore) Search(ctx context.Context, q string, types []string, limit int, scope Scope) ([]*SearchResult, error) {
+	selects := []string{}
+	for _, t := range types {
+		src, ok := searchSources[t]
+		if !ok {
+			return nil, ErrUnknownEntity
+		}
+		where := fmt.Sprintf("deleted_at IS NULL AND (%[1]s %% $1 OR %[1]s ILIKE $2)", src.displayName)
+		if c := scope.condition(src.table); c != "" {
+			where += " AND " + c
+		}
+		selects = append(selects, fmt.Sprintf(`
+			SELECT id, '%[1]s' AS type, %[2]s AS display_name, similarity(%[2]s, $1) AS score
+			FROM %[3]s
+			WHERE %[4]s`,
+			strings.TrimSuffix(t, "s"), src.displayName, src.table, where))
+	}
+	if len(selects) == 0 {
+		return []*SearchResult{}, nil
+	}
+
+	query := strings.Join(selects, " UNION ALL ") + `
+		ORDER BY score DESC, display_name ASC
+		LIMIT $3`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, q, "%"+q+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.ID, &r.Type, &r.DisplayName, &r.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, &r)
+	}
+
+	return results, rows.Err()
+}
+
+// ChangedSince returns searchable records created, updated, deleted or
+// restored after since, oldest change first. Soft-deleted rows are included
+// with Deleted set so indexes can drop them.
+func (s *SearchStore) ChangedSince(ctx context.Context, since time.Time) ([]*SearchDocument, error) {
+	selects := []string{}
+	for _, t := range []string{"students", "teachers", "classrooms"} {
+		src := searchSources[t]
+		email := "COALESCE(email, '')"
+		if t == "classrooms" {
+			email = "''"
+		}
+		selects = append(selects, fmt.Sprintf(`
+			SELECT id, '%[1]s', %[2]s, %[3]s, deleted_at IS NOT NULL,
+				GREATEST(updated_at, COALESCE(deleted_at, updated_at)) AS changed_at
+			FROM %[4]s
+			WHERE GREATEST(updated_at, COALESCE(deleted_at, updated_at)) > $1`,
+			strings.TrimSuffix(t, "s"), src.displayName, email, src.table))
+	}
+	query := strings.Join(selects, " UNION ALL ") + " ORDER BY changed_at ASC"
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := []*SearchDocument{}
+	for rows.Next() {
+		var d SearchDocument
+		if err := rows.Scan(&d.ID, &d.Type, &d.DisplayName, &d.Email, &d.Deleted, &d.ChangedAt); err != nil {
+			return nil, err
+		}
+		docs = append(docs, &d)
+	}
+
+	return docs, rows.Err()
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrNotInClassroom = errors.New("student is not in the classroom")
+
+// SeatingChart is a classroom's seat grid and who sits where. Rows and
+// columns count from 1, the first row being the one nearest the board.
+// Status on seats and unseated students is their attendance on the date
+// the chart was read for, nil when it hasn't been marked, so clients can
+// render the chart as a seat map to mark attendance on.
+type SeatingChart struct {
+	ClassroomID int64   `json:"classroom_id"`
+	Rows        int     `json:"rows"`
+	Columns     int     `json:"columns"`
+	Seats       []*Seat `json:"seats"`
+	// Unseated are the classroom's students without a seat.
+	Unseated  []*SeatStudent `json:"unseated"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type Seat struct {
+	Row    int `json:"row"`
+	Column int `json:"column"`
+	SeatStudent
+}
+
+type SeatStudent struct {
+	StudentID int64   `json:"student_id"`
+	FirstName string  `json:"first_name,omitempty"`
+	LastName  string  `json:"last_name,omitempty"`
+	Status    *string `json:"status,omitempty" enums:"present,absent,late,excused"`
+}
+
+type SeatingStore struct {
+	db *routedDB
+}
+
+// Get reads a classroom's chart with attendance for date. Students who have
+// left the classroom are dropped from their seats. ErrNotFound when the
+// classroom has no chart.
+func (s *SeatingStore) Get(ctx context.Context, classroomID int64, date time.Time) (*SeatingChart, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	c := &SeatingChart{ClassroomID: classroomID, Seats: []*Seat{}, Unseated: []*SeatStudent{}}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT row_count, column_count, updated_at FROM seating_charts WHERE classroom_id = $1
+	`, classroomID).Scan(&c.Rows, &c.Columns, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sa.seat_row, sa.seat_column, s.id, s.first_name, s.last_name, a.status
+		FROM students s
+		LEFT JOIN seat_assignments sa ON sa.student_id = s.id AND sa.classroom_id = s.classroom_id
+		LEFT JOIN attendance_records a ON a.student_id = s.id AND a.date = $2::date
+		WHERE s.classroom_id = $1 AND s.deleted_at IS NULL
+		ORDER BY sa.seat_row, sa.seat_column, s.last_name, s.first_name
+	`, classroomID, date.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			row, column *int
+			st          SeatStudent
+		)
+		if err := rows.Scan(&row, &column, &st.StudentID, &st.FirstName, &st.LastName, &st.Status); err != nil {
+			return nil, err
+		}
+		if row == nil {
+			c.Unseated = append(c.Unseated, &st)
+			continue
+		}
+		c.Seats = append(c.Seats, &Seat{Row: *row, Column: *column, SeatStudent: st})
+	}
+	return c, rows.Err()
+}
+
+// Put replaces a classroom's chart: its size and every assignment. The
+// caller checks the seats fit the grid and are each used once; Put returns
+// ErrNotInClassroom when a seat holds a student of another classroom and
+// ErrConflict when a student has two seats.
+func (s *SeatingStore) Put(ctx context.Context, c *SeatingChart) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO seating_charts (classroom_id, row_count, column_count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (classroom_id) DO UPDATE
+		SET row_count = EXCLUDED.row_count, column_count = EXCLUDED.column_count, updated_at = NOW()
+		RETURNING updated_at
+	`, c.ClassroomID, c.Rows, c.Columns).Scan(&c.UpdatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	ids := make([]int64, 0, len(c.Seats))
+	seen := map[int64]bool{}
+	for _, seat := range c.Seats {
+		if seen[seat.StudentID] {
+			return ErrConflict
+		}
+		seen[seat.StudentID] = true
+		ids = append(ids, seat.StudentID)
+	}
+	var members int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM students WHERE id = ANY($2) AND classroom_id = $1 AND deleted_at IS NULL
+	`, c.ClassroomID, pq.Array(ids)).Scan(&members); err != nil {
+		return err
+	}
+	if members < len(ids) {
+		return ErrNotInClassroom
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM seat_assignments WHERE classroom_id = $1`, c.ClassroomID); err != nil {
+		return err
+	}
+	for _, seat := range c.Seats {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO seat_assignments (classroom_id, seat_row, seat_column, student_id) VALUES ($1, $2, $3, $4)
+		`, c.ClassroomID, seat.Row, seat.Column, seat.StudentID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SeatingStore) Delete(ctx context.Context, classroomID int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM seating_charts WHERE classroom_id = $1`, classroomID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SMS message kinds.
+const (
+	SMSKindAbsence      = "absence"
+	SMSKindReply        = "reply"
+	SMSKindConfirmation = "confirmation"
+)
+
+// SMSMessage is one text in a parent's thread.
+type SMSMessage struct {
+	ID           int64     `json:"id"`
+	PhoneNumber  string    `json:"phone_number"`
+	Direction    string    `json:"direction"`
+	Kind         string    `json:"kind"`
+	Body         string    `json:"body"`
+	StudentID    *int64    `json:"student_id,omitempty"`
+	AttendanceID *int64    `json:"attendance_id,omitempty"`
+	ReplyTo      *int64    `json:"reply_to,omitempty"`
+	Action       string    `json:"action,omitempty"`
+	ProviderID   string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AbsenceNotice is an absence whose parent has not been texted yet.
+type AbsenceNotice struct {
+	AttendanceID int64
+	StudentID    int64
+	FirstName    string
+	LastName     string
+	ParentPhone  string
+	Date         time.Time
+}
+
+type SMSMessageStore struct {
+	db *routedDB
+}
+
+func (s *SMSMessageStore) Record(ctx context.Context, m *SMSMessage) error {
+	query := `
+		INSERT INTO sms_messages (phone_number, direction, kind, body, student_id, attendance_id, reply_to, action, provider_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		m.PhoneNumber, m.Direction, m.Kind, m.Body, m.StudentID, m.AttendanceID, m.ReplyTo, m.Action, m.ProviderID,
+	).Scan(&m.ID, &m.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// LatestOutbound returns the newest message sent to phone after since,
+// which is what a reply from that number answers.
+func (s *SMSMessageStore) LatestOutbound(ctx context.Context, phone string, since time.Time) (*SMSMessage, error) {
+	query := `
+		SELECT id, phone_number, direction, kind, body, student_id, attendance_id, reply_to, action, provider_id, created_at
+		FROM sms_messages
+		WHERE phone_number = $1 AND direction = 'out' AND created_at > $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var m SMSMessage
+	err := s.db.QueryRowContext(ctx, query, phone, since).Scan(
+		&m.ID, &m.PhoneNumber, &m.Direction, &m.Kind, &m.Body, &m.StudentID, &m.AttendanceID,
+		&m.ReplyTo, &m.Action, &m.ProviderID, &m.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Thread returns the messages exchanged with phone, oldest first.
+func (s *SMSMessageStore) Thread(ctx context.Context, phone string) ([]*SMSMessage, error) {
+	return s.list(ctx, `phone_number = $1 ORDER BY created_at, id LIMIT 500`, phone)
+}
+
+// ForStudent returns every message sent or received about a student,
+// oldest first.
+func (s *SMSMessageStore) ForStudent(ctx context.Context, studentID int64) ([]*SMSMessage, error) {
+	return s.list(ctx, `student_id = $1 ORDER BY created_at, id`, studentID)
+}
+
+func (s *SMSMessageStore) list(ctx context.Context, where string, args ...any) ([]*SMSMessage, error) {
+	query := `
+		SELECT id, phone_number, direction, kind, body, student_id, attendance_id, reply_to, action, provider_id, created_at
+		FROM sms_messages
+		WHERE ` + where
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*SMSMessage{}
+	for rows.Next() {
+		var m SMSMessage
+		if err := rows.Scan(
+			&m.ID, &m.PhoneNumber, &m.Direction, &m.Kind, &m.Body, &m.StudentID, &m.AttendanceID,
+			&m.ReplyTo, &m.Action, &m.ProviderID, &m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+	return messages, rows.Err()
+}
+
+// UnnotifiedAbsences returns the absences of the given students on date
+// whose parent has a phone number and has not been sent a notice.
+func (s *SMSMessageStore) UnnotifiedAbsences(ctx context.Context, date time.Time, studentIDs []int64) ([]*AbsenceNotice, error) {
+	query := `
+		SELECT a.id, s.id, s.first_name, s.last_name, s.parent_phone_number, a.date
+		FROM attendance_records a
+		JOIN students s ON s.id = a.student_id AND s.deleted_at IS NULL
+		WHERE a.date = $1::date AND a.student_id = ANY($2) AND a.status = 'absent'
+		  AND s.parent_phone_number <> ''
+		  AND NOT EXISTS (SELECT 1 FROM sms_messages m WHERE m.attendance_id = a.id AND m.kind = 'absence')
+		ORDER BY s.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, date.Format(time.DateOnly), pq.Array(studentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notices := []*AbsenceNotice{}
+	for rows.Next() {
+		var n AbsenceNotice
+		if err := rows.Scan(&n.AttendanceID, &n.StudentID, &n.FirstName, &n.LastName, &n.ParentPhone, &n.Date); err != nil {
+			return nil, err
+		}
+		notices = append(notices, &n)
+	}
+	return notices, rows.Err()
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+)
+
+// StaffDevice is a device a teacher is allowed to mark their own
+// attendance from.
+type StaffDevice struct {
+	ID          int64      `json:"id"`
+	TeacherID   int64      `json:"teacher_id"`
+	TeacherName string     `json:"teacher_name,omitempty"`
+	DeviceID    string     `json:"device_id"`
+	Name        string     `json:"name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+// StaffAttendance is a teacher's own arrival and departure for a day.
+type StaffAttendance struct {
+	ID                int64      `json:"id"`
+	TeacherID         int64      `json:"teacher_id"`
+	TeacherName       string     `json:"teacher_name,omitempty"`
+	Date              time.Time  `json:"date"`
+	ArrivedAt         time.Time  `json:"arrived_at"`
+	Late              bool       `json:"late"`
+	ArrivalIP         string     `json:"arrival_ip"`
+	ArrivalDeviceID   *int64     `json:"arrival_device_id"`
+	DepartedAt        *time.Time `json:"departed_at"`
+	DepartureIP       string     `json:"departure_ip"`
+	DepartureDeviceID *int64     `json:"departure_device_id"`
+}
+
+// StaffAttendanceSummary totals one teacher's attendance over a period.
+// SchoolDays counts the school days of the period up to today; Absent are
+// those without an arrival. Hours only counts days with a departure.
+type StaffAttendanceSummary struct {
+	TeacherID         int64   `json:"teacher_id"`
+	TeacherName       string  `json:"teacher_name"`
+	SchoolDays        int     `json:"school_days"`
+	Present           int     `json:"present"`
+	Late              int     `json:"late"`
+	Absent            int     `json:"absent"`
+	MissingDepartures int     `json:"missing_departures"`
+	Hours             float64 `json:"hours"`
+}
+
+type StaffAttendanceStore struct {
+	db *routedDB
+}
+
+const staffAttendanceColumns = `
+	a.id, a.teacher_id, t.first_name || ' ' || t.last_name, a.date, a.arrived_at, a.late,
+	a.arrival_ip, a.arrival_device_id, a.departed_at, a.departure_ip, a.departure_device_id`
+
+func scanStaffAttendance(row interface{ Scan(...any) error }, a *StaffAttendance) error {
+	return row.Scan(&a.ID, &a.TeacherID, &a.TeacherName, &a.Date, &a.ArrivedAt, &a.Late,
+		&a.ArrivalIP, &a.ArrivalDeviceID, &a.DepartedAt, &a.DepartureIP, &a.DepartureDeviceID)
+}
+
+// AddDevice registers a device for a teacher. It returns ErrConflict when
+// the device is already registered.
+func (s *StaffAttendanceStore) AddDevice(ctx context.Context, d *StaffDevice) error {
+	query := `
+		INSERT INTO staff_devices (teacher_id, device_id, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, d.TeacherID, d.DeviceID, d.Name).Scan(&d.ID, &d.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Devices lists registered devices, of one teacher when teacherID is set.
+func (s *StaffAttendanceStore) Devices(ctx context.Context, teacherID int64) ([]*StaffDevice, error) {
+	query := `
+		SELECT d.id, d.teacher_id, t.first_name || ' ' || t.last_name, d.device_id, d.name, d.created_at, d.last_used_at
+		FROM staff_devices d
+		JOIN teachers t ON t.id = d.teacher_id
+		WHERE $1 = 0 OR d.teacher_id = $1
+		ORDER BY t.last_name, t.first_name, d.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []*StaffDevice{}
+	for rows.Next() {
+		var d StaffDevice
+		if err := rows.Scan(&d.ID, &d.TeacherID, &d.TeacherName, &d.DeviceID, &d.Name, &d.CreatedAt, &d.LastUsedAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, &d)
+	}
+	return devices, rows.Err()
+}
+
+func (s *StaffAttendanceStore) DeleteDevice(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM staff_devices WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// UseDevice returns the ID of the teacher's device with the given device
+// identifier and records the use. It returns ErrNotFound when the device
+// is not registered to the teacher.
+func (s *StaffAttendanceStore) UseDevice(ctx context.Context, teacherID int64, deviceID string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`UPDATE staff_devices SET last_used_at = NOW() WHERE teacher_id = $1 AND device_id = $2 RETURNING id`,
+		teacherID, deviceID,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// Arrive records a teacher's arrival, filling in ID and ArrivedAt. It
+// returns ErrConflict when the teacher already arrived that day.
+func (s *StaffAttendanceStore) Arrive(ctx context.Context, a *StaffAttendance) error {
+	query := `
+		INSERT INTO staff_attendance (teacher_id, date, late, arrival_ip, arrival_device_id)
+		VALUES ($1, $2::date, $3, $4, $5)
+		ON CONFLICT (teacher_id, date) DO NOTHING
+		RETURNING id, arrived_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		a.TeacherID, a.Date.Format(time.DateOnly), a.Late, a.ArrivalIP, a.ArrivalDeviceID,
+	).Scan(&a.ID, &a.ArrivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Depart records a teacher's departure for the day and returns the day's
+// record. It returns ErrNotFound when the teacher has not arrived and
+// ErrConflict when they already left.
+func (s *StaffAttendanceStore) Depart(ctx context.Context, teacherID int64, date time.Time, ip string, deviceID *int64) (*StaffAttendance, error) {
+	query := `
+		UPDATE staff_attendance a
+		SET departed_at = NOW(), departure_ip = $3, departure_device_id = $4
+		FROM teachers t
+		WHERE t.id = a.teacher_id AND a.teacher_id = $1 AND a.date = $2::date AND a.departed_at IS NULL
+		RETURNING` + staffAttendanceColumns
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var a StaffAttendance
+	err := scanStaffAttendance(s.db.QueryRowContext(ctx, query, teacherID, date.Format(time.DateOnly), ip, deviceID), &a)
+	if err == nil {
+		return &a, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Nothing updated: either no arrival or already departed.
+	var exists bool
+	err = s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM staff_attendance WHERE teacher_id = $1 AND date = $2::date)`,
+		teacherID, date.Format(time.DateOnly),
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrConflict
+	}
+	return nil, ErrNotFound
+}
+
+// List returns the records between from and to (inclusive), of one
+// teacher when teacherID is set, by date and arrival.
+func (s *StaffAttendanceStore) List(ctx context.Context, teacherID int64, from, to time.Time) ([]*StaffAttendance, error) {
+	query := `
+		SELECT` + staffAttendanceColumns + `
+		FROM staff_attendance a
+		JOIN teachers t ON t.id = a.teacher_id
+		WHERE a.date BETWEEN $1::date AND $2::date AND ($3 = 0 OR a.teacher_id = $3)
+		ORDER BY a.date, a.arrived_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, from.Format(time.DateOnly), to.Format(time.DateOnly), teacherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*StaffAttendance{}
+	for rows.Next() {
+		var a StaffAttendance
+		if err := scanStaffAttendance(rows, &a); err != nil {
+			return nil, err
+		}
+		records = append(records, &a)
+	}
+	return records, rows.Err()
+}
+
+// Report totals every live teacher's attendance between from and to
+// (inclusive). Days after today are not counted as school days.
+func (s *StaffAttendanceStore) Report(ctx context.Context, from, to, today time.Time) ([]*StaffAttendanceSummary, error) {
+	query := `
+		WITH days AS (
+			SELECT COUNT(*) AS n
+			FROM generate_series($1::date, LEAST($2::date, $3::date), '1 day') AS g(day)
+			WHERE ` + schoolDaySQL("g.day::date") + `
+		)
+		SELECT t.id, t.first_name || ' ' || t.last_name, days.n,
+			COUNT(a.id),
+			COUNT(a.id) FILTER (WHERE a.late),
+			COUNT(a.id) FILTER (WHERE a.departed_at IS NULL AND a.date < $3::date),
+			COALESCE(SUM(EXTRACT(EPOCH FROM a.departed_at - a.arrived_at)) / 3600, 0)
+		FROM teachers t
+		CROSS JOIN days
+		LEFT JOIN staff_attendance a ON a.teacher_id = t.id AND a.date BETWEEN $1::date AND $2::date
+		WHERE t.deleted_at IS NULL
+		GROUP BY t.id, days.n
+		ORDER BY t.last_name, t.first_name
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query,
+		from.Format(time.DateOnly), to.Format(time.DateOnly), today.Format(time.DateOnly),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []*StaffAttendanceSummary{}
+	for rows.Next() {
+		var sum StaffAttendanceSummary
+		if err := rows.Scan(&sum.TeacherID, &sum.TeacherName, &sum.SchoolDays, &sum.Present, &sum.Late,
+			&sum.MissingDepartures, &sum.Hours); err != nil {
+			return nil, err
+		}
+		sum.Absent = max(sum.SchoolDays-sum.Present, 0)
+		sum.Hours = math.Round(sum.Hours*10) / 10
+		summaries = append(summaries, &sum)
+	}
+	return summaries, rows.Err()
+}
@@ -2,22 +2,49 @@ package store
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrNotFound          = errors.New("resource not found")
-	ErrConflict          = errors.New("resource conflict")
+	ErrNotFound = errors.New("resource not found")
+	ErrConflict = errors.New("resource conflict")
+	// QueryTimeoutDuration bounds queries whose caller set no deadline,
+	// such as background tasks.
 	QueryTimeoutDuration = time.Second * 5
+	// DeadlineMargin is kept back from the caller's deadline, so that once
+	// a query runs out there is still time to answer.
+	DeadlineMargin = 500 * time.Millisecond
 )
 
+// withQueryTimeout bounds a query by the caller's deadline less
+// DeadlineMargin, so a long export gets the time its request or job was
+// given while an interactive request stays within its own budget.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(ctx, deadline.Add(-DeadlineMargin))
+	}
+	return context.WithTimeout(ctx, QueryTimeoutDuration)
+}
+
+// IsTimeout reports whether err is a query cut short by its deadline or
+// by the database's statement timeout.
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &pqErr) && pqErr.Code == "57014")
+}
+
+// notDeleted filters out soft-deleted rows; see Trash for the recycle bin.
+const notDeleted = "deleted_at IS NULL"
+
 type password struct {
 	text *string
 	hash []byte
+	// temporary passwords must be changed at the first sign-in.
+	temporary bool
 }
 
 func (p *password) Set(text string) error {
@@ -32,6 +59,16 @@ func (p *password) Set(text string) error {
 	return nil
 }
 
+// SetTemporary sets a password its holder must change before doing
+// anything else.
+func (p *password) SetTemporary(text string) error {
+	if err := p.Set(text); err != nil {
+		return err
+	}
+	p.temporary = true
+	return nil
+}
+
 func (p *password) Check(text string) bool {
 	if p == nil || p.hash == nil {
 		return false
@@ -45,33 +82,89 @@ type Storage struct {
 		Create(context.Context, *Exec) error
 		GetAll(context.Context, PaginatedQuery) ([]*Exec, error)
 		GetByID(context.Context, int64) (*Exec, error)
-		GetByEmail(context.Context, string) (*Exec, error)
 		Update(context.Context, *Exec) error
+		UpdatePartial(context.Context, int64, map[string]any) error
+		SetScope(context.Context, int64, Scope) error
 		Delete(context.Context, int64) error
+		AdminEmails(context.Context) ([]string, error)
 	}
 	Teachers interface {
 		Create(context.Context, *Teacher) error
 		GetAll(context.Context, PaginatedQuery) ([]*Teacher, error)
 		GetByID(context.Context, int64) (*Teacher, error)
-		GetByEmail(context.Context, string) (*Teacher, error)
+		GetByCode(context.Context, string) (*Teacher, error)
+		Lookup(ctx context.Context, email, phone string) ([]*Teacher, error)
+		GetTeachingToday(context.Context) ([]int64, error)
 		Update(context.Context, *Teacher) error
-		Delete(context.Context, int64) error
+		UpdatePartial(context.Context, int64, map[string]any) error
+		Delete(context.Context, int64, int64) error
+		Dependents(context.Context, int64) (*TeacherDependents, error)
+		DeleteReassigning(ctx context.Context, id, reassignTo, deletedBy int64) error
 	}
 	Students interface {
 		Create(context.Context, *Student) error
 		GetAll(context.Context, PaginatedQuery) ([]*Student, error)
 		GetByID(context.Context, int64) (*Student, error)
-		GetByEmail(context.Context, string) (*Student, error)
+		GetByCode(context.Context, string) (*Student, error)
+		Lookup(ctx context.Context, email, phone string) ([]*Student, error)
+		FindDuplicates(context.Context, *Student) ([]*DuplicateCandidate, error)
+		Merge(ctx context.Context, survivorID, duplicateID, mergedBy int64) (*StudentMerge, error)
+		GradeRoster(context.Context, int64) ([]*RosterClassroom, error)
+		Transfer(context.Context, []*StudentTransfer) error
 		Update(context.Context, *Student) error
-		Delete(context.Context, int64) error
-		GetByTeacherID(ctx context.Context, teacherID int64) ([]*Student, error)
+		UpdatePartial(context.Context, int64, map[string]any) error
+		Delete(context.Context, int64, int64) error
+		GetByTeacherID(ctx context.Context, teacherID int64) ([]*StudentSummary, error)
 	}
 	Classrooms interface {
 		Create(context.Context, *Classroom) error
 		GetAll(context.Context, PaginatedQuery) ([]*Classroom, error)
 		GetByID(context.Context, int64) (*Classroom, error)
+		GetByTeacherID(context.Context, int64) ([]*Classroom, error)
 		Update(context.Context, *Classroom) error
+		UpdatePartial(context.Context, int64, map[string]any) error
+		Delete(context.Context, int64, int64) error
+		Detail(context.Context, *Classroom, time.Time, int) (*ClassroomDetail, error)
+	}
+	Grants interface {
+		Grant(ctx context.Context, accountID int64, permission string, grantedBy int64) error
+		Revoke(ctx context.Context, accountID int64, permission string) error
+		List(ctx context.Context, permission string) ([]*PermissionGrant, error)
+		ForAccount(ctx context.Context, accountID int64) ([]string, error)
+		Has(ctx context.Context, profileType string, profileID int64, permission string) (bool, error)
+	}
+	Counseling interface {
+		CreateReferral(context.Context, *CounselingReferral) error
+		Referrals(ctx context.Context, status string) ([]*CounselingReferral, error)
+		CreateSession(context.Context, *CounselingSession) error
+		Sessions(ctx context.Context, studentID int64) ([]*CounselingSession, error)
+		Stats(ctx context.Context, from, to time.Time, minStudents int) (*CounselingStats, error)
+	}
+	LostItems interface {
+		Create(context.Context, *LostItem) error
+		List(context.Context, LostItemFilter) ([]*LostItem, error)
+		Get(context.Context, int64) (*LostItem, error)
+		Match(ctx context.Context, id, studentID int64) (*LostItem, error)
+		Claim(context.Context, int64) (*LostItem, error)
 		Delete(context.Context, int64) error
+		SetPhoto(ctx context.Context, id int64, data []byte, contentType string, thumb []byte, thumbType string) error
+		Photo(ctx context.Context, id int64, thumbnail bool) ([]byte, string, error)
+	}
+	LunchMenus interface {
+		List(ctx context.Context, from, to time.Time) ([]*LunchMenu, error)
+		Put(context.Context, *LunchMenu) error
+		Delete(context.Context, time.Time) error
+	}
+	WidgetTokens interface {
+		Create(context.Context, *WidgetToken) error
+		List(context.Context) ([]*WidgetToken, error)
+		GetLive(context.Context, int64) (*WidgetToken, error)
+		Revoke(context.Context, int64) error
+	}
+	Seating interface {
+		Get(ctx context.Context, classroomID int64, date time.Time) (*SeatingChart, error)
+		Put(context.Context, *SeatingChart) error
+		Delete(ctx context.Context, classroomID int64) error
 	}
 	Attendance interface {
 		Mark(context.Context, *AttendanceRecord) error
@@ -79,15 +172,376 @@ type Storage struct {
 		GetByStudent(context.Context, int64, *time.Time, *time.Time) ([]*AttendanceRecord, error)
 		GetByClassroomDate(context.Context, int64, time.Time) ([]*AttendanceRecord, error)
 		Delete(context.Context, int64) error
+		Excuse(context.Context, int64) error
+		EnsurePartitions(ctx context.Context, now time.Time, months int) error
+		Import(ctx context.Context, rows []*AttendanceImportRow, overwrite, dryRun bool, scope Scope, progress func(done int)) (*AttendanceImportResult, error)
+	}
+	Approvals interface {
+		Create(context.Context, *Approval) error
+		List(ctx context.Context, status string) ([]*Approval, error)
+		Get(context.Context, int64) (*Approval, error)
+		Review(ctx context.Context, a *Approval, status string, reviewerID int64, note string) error
+		Fail(ctx context.Context, a *Approval, reason string) error
+	}
+	AttendanceCorrections interface {
+		Create(context.Context, *AttendanceCorrection) error
+		List(context.Context, AttendanceCorrectionFilter) ([]*AttendanceCorrection, error)
+		Get(context.Context, int64) (*AttendanceCorrection, error)
+		Review(ctx context.Context, c *AttendanceCorrection, status string, reviewerID int64, note string) error
+	}
+	CheckIns interface {
+		Record(ctx context.Context, checkIn *CheckIn, status string) error
+		ForDate(ctx context.Context, date time.Time, role string) ([]*CheckIn, error)
+	}
+	Trash interface {
+		List(context.Context, string, PaginatedQuery) ([]*TrashedRecord, error)
+		Restore(context.Context, string, int64) error
+		Purge(context.Context, string, int64) error
+		PurgeOlderThan(context.Context, time.Time) (int64, error)
+	}
+	Analytics interface {
+		AttendanceTrends(context.Context, string, time.Time, time.Time, Scope) ([]*AttendanceTrend, error)
+		TeacherAttendanceCompleteness(context.Context, int64, time.Time, time.Time) ([]*AttendanceCompleteness, error)
+		GradeAverages(ctx context.Context, classroomID int64) ([]*GradeAverage, error)
+		RefreshStats(context.Context) ([]*StatsRefresh, error)
+		EnrollmentByGrade(context.Context) ([]*GradeEnrollment, error)
+		AttendanceByGrade(ctx context.Context, from, to time.Time) ([]*GradeAttendance, *GradeAttendance, error)
+	}
+	Reports interface {
+		CreateSchedule(context.Context, *ReportSchedule) error
+		ListSchedules(context.Context) ([]*ReportSchedule, error)
+		GetSchedule(context.Context, int64) (*ReportSchedule, error)
+		SetScheduleEnabled(context.Context, int64, bool, time.Time) error
+		DeleteSchedule(context.Context, int64) error
+		ClaimDue(context.Context, time.Time, func(*ReportSchedule) time.Time) ([]*ReportSchedule, error)
+		StartRun(context.Context, int64) (*ReportRun, error)
+		FinishRun(context.Context, *ReportRun, []byte) error
+		ListRuns(context.Context, int64, int) ([]*ReportRun, error)
+		GetRunContent(context.Context, int64) (*ReportRun, []byte, error)
+	}
+	History interface {
+		Record(context.Context, *EntityChange) error
+		List(context.Context, string, int64, int, int) ([]*EntityChange, error)
+		ByActor(ctx context.Context, role string, id int64) ([]*EntityChange, error)
+	}
+	Tags interface {
+		List(context.Context) ([]*Tag, error)
+		Delete(context.Context, string) error
+		ForEntity(context.Context, string, int64) ([]string, error)
+		Assign(ctx context.Context, entity string, entityID int64, name string, createdBy int64) error
+		Unassign(ctx context.Context, entity string, entityID int64, name string) error
+	}
+	Notes interface {
+		Create(context.Context, *Note) error
+		ForEntity(context.Context, string, int64) ([]*Note, error)
+		Delete(ctx context.Context, entity string, entityID, id int64) error
+	}
+	Reminders interface {
+		MissingAttendance(context.Context, time.Time) ([]*MissingAttendance, error)
+		Claim(ctx context.Context, classroomID, teacherID int64, date time.Time) (bool, error)
+		MarkDelivered(ctx context.Context, classroomID int64, date time.Time, via string) error
+		Compliance(ctx context.Context, from, to time.Time, minReminders int, scope Scope) ([]*ReminderCompliance, error)
+	}
+	SchoolDays interface {
+		List(context.Context, time.Time, time.Time) ([]*CalendarDay, error)
+		Upsert(context.Context, []*CalendarDay) (int64, error)
+		Delete(context.Context, time.Time) error
+		IsSchoolDay(context.Context, time.Time) (bool, *CalendarDay, error)
+	}
+	OnlineSessions interface {
+		Start(context.Context, *OnlineSession) error
+		GetByID(context.Context, int64) (*OnlineSession, error)
+		GetOpen(context.Context, int64) (*OnlineSession, error)
+		Join(ctx context.Context, session *OnlineSession, studentID int64) error
+		Close(context.Context, *OnlineSession) error
+	}
+	Points interface {
+		ListCategories(context.Context, bool) ([]*PointCategory, error)
+		GetCategory(context.Context, int64) (*PointCategory, error)
+		CreateCategory(context.Context, *PointCategory) error
+		UpdateCategory(context.Context, *PointCategory) error
+		Award(context.Context, *PointAward) error
+		ForStudent(ctx context.Context, studentID int64, from, to time.Time) ([]*PointAward, error)
+		Leaderboard(ctx context.Context, classroomID int64, from, to time.Time) ([]*LeaderboardEntry, error)
+		Summaries(ctx context.Context, from, to time.Time) ([]*PointSummary, error)
+		ClaimSummary(ctx context.Context, studentID int64, week time.Time) (bool, error)
+	}
+	Surveys interface {
+		Create(context.Context, *Survey) error
+		GetByID(context.Context, int64) (*Survey, error)
+		List(context.Context, bool) ([]*Survey, error)
+		Update(context.Context, *Survey) error
+		Delete(context.Context, int64) error
+		Respond(context.Context, *SurveyResponse) error
+		Answered(ctx context.Context, role string, respondentID int64) (map[int64]bool, error)
+		Responses(context.Context, int64) ([]*SurveyResponse, error)
+	}
+	Consents interface {
+		CreateRequest(context.Context, *ConsentRequest, ConsentAudience) ([]*StudentConsent, error)
+		ListRequests(context.Context) ([]*ConsentRequest, error)
+		GetRequest(context.Context, int64) (*ConsentRequest, error)
+		DeleteRequest(context.Context, int64) error
+		ForRequest(context.Context, int64) ([]*StudentConsent, error)
+		GetConsent(context.Context, int64) (*StudentConsent, error)
+		Respond(context.Context, *StudentConsent) error
+		Matrix(context.Context, int64) (*ConsentMatrix, error)
+	}
+	Pickups interface {
+		ForStudent(context.Context, int64) ([]*PickupContact, error)
+		Get(ctx context.Context, studentID, id int64) (*PickupContact, error)
+		Create(context.Context, *PickupContact) error
+		Update(context.Context, *PickupContact) error
+		Delete(ctx context.Context, studentID, id int64) error
+		SetPhoto(ctx context.Context, studentID, id int64, data []byte, contentType string) error
+		Photo(context.Context, int64) ([]byte, string, error)
+		PhotoVariant(ctx context.Context, id int64, size string) ([]byte, string, error)
+		SetPhotoVariant(ctx context.Context, id int64, size string, data []byte, contentType string) error
+		LogCheckout(context.Context, *PickupCheckout) error
+		Checkouts(ctx context.Context, studentID int64, from, to time.Time) ([]*PickupCheckout, error)
+	}
+	Assets interface {
+		List(ctx context.Context, status, category string) ([]*Asset, error)
+		GetByID(context.Context, int64) (*Asset, error)
+		Create(context.Context, *Asset) error
+		Update(context.Context, *Asset) error
+		CheckOut(context.Context, *AssetLoan) error
+		CheckIn(ctx context.Context, assetID, returnedTo int64, condition string) (*AssetLoan, error)
+		History(context.Context, int64) ([]*AssetLoan, error)
+		ForHolder(ctx context.Context, holderType string, holderID int64) ([]*AssetLoan, error)
+		Overdue(context.Context, time.Time) ([]*OverdueLoan, error)
+	}
+	Bookings interface {
+		ListResources(context.Context, bool) ([]*BookingResource, error)
+		GetResource(context.Context, int64) (*BookingResource, error)
+		CreateResource(context.Context, *BookingResource) error
+		UpdateResource(context.Context, *BookingResource) error
+		Book(context.Context, *Booking) error
+		GetByID(context.Context, int64) (*Booking, error)
+		Cancel(ctx context.Context, id, cancelledBy int64) error
+		Overlapping(ctx context.Context, resourceID int64, from, to time.Time) ([]*Booking, error)
+	}
+	Accounts interface {
+		GetByEmail(context.Context, string) (*Account, error)
+		GetByID(context.Context, int64) (*Account, error)
+		GetByProfile(ctx context.Context, profileType string, profileID int64) (*Account, error)
+		RecordLogin(ctx context.Context, id int64, ip string) error
+		RecordSeen(ctx context.Context, id int64) error
+		EmailOwners(context.Context, string) ([]*AccountRef, error)
+		Inactive(ctx context.Context, since time.Time, role string) ([]*InactiveAccount, error)
+		Deactivate(ctx context.Context, since time.Time, role string, ids []int64) ([]int64, error)
+		Reactivate(context.Context, int64) error
+		PasswordUsed(ctx context.Context, id int64, text string, n int) (bool, error)
+		SetPassword(ctx context.Context, id int64, text string, temporary bool) error
+		ForcePasswordChange(ctx context.Context, role string, except int64) (int64, error)
+	}
+	Broadcasts interface {
+		Create(context.Context, *Broadcast) error
+		Recipients(context.Context, *Broadcast) ([]*BroadcastRecipient, error)
+		Finish(context.Context, *Broadcast) error
+		CountSince(context.Context, time.Time) (int, error)
+		List(context.Context, int) ([]*Broadcast, error)
+	}
+	SMSMessages interface {
+		Record(context.Context, *SMSMessage) error
+		LatestOutbound(ctx context.Context, phone string, since time.Time) (*SMSMessage, error)
+		Thread(context.Context, string) ([]*SMSMessage, error)
+		ForStudent(context.Context, int64) ([]*SMSMessage, error)
+		UnnotifiedAbsences(context.Context, time.Time, []int64) ([]*AbsenceNotice, error)
+	}
+	Telegram interface {
+		IssueCode(ctx context.Context, parentID int64, hash []byte, expiresAt time.Time) error
+		Link(ctx context.Context, hash []byte, chatID int64) (int64, error)
+		ParentForChat(context.Context, int64) (*Parent, error)
+		Get(context.Context, int64) (*TelegramLink, error)
+		Unlink(context.Context, int64) error
+		UnlinkChat(context.Context, int64) error
+		ChatsForPhones(context.Context, []string) (map[string]int64, error)
+	}
+	LMS interface {
+		Roster(context.Context, int64) ([]*RosterMember, error)
+		ImportGrades(ctx context.Context, classroomID int64, source string, grades []*GradeImport) (int, []string, error)
+		Grades(context.Context, int64) ([]*Grade, error)
+		StudentGrades(context.Context, int64) ([]*Grade, error)
+		GetSync(context.Context, int64) (*LMSSync, error)
+		PutSync(context.Context, *LMSSync) error
+		DeleteSync(context.Context, int64) error
+		ClaimDueSyncs(context.Context, time.Time) ([]*LMSSync, error)
+		FinishSync(ctx context.Context, classroomID int64, lastError string) error
+	}
+	OneRoster interface {
+		Classes(ctx context.Context, teacherID int64, limit, offset int) ([]*RosterClass, int, error)
+		Class(context.Context, int64) (*RosterClass, error)
+		Users(ctx context.Context, f RosterUserFilter, limit, offset int) ([]*RosterUser, int, error)
+		User(ctx context.Context, role string, id int64) (*RosterUser, error)
+		Enrollments(ctx context.Context, classroomID int64, limit, offset int) ([]*RosterEnrollment, int, error)
+		Enrollment(ctx context.Context, classroomID int64, role string, userID int64) (*RosterEnrollment, error)
+	}
+	StaffAttendance interface {
+		AddDevice(context.Context, *StaffDevice) error
+		Devices(ctx context.Context, teacherID int64) ([]*StaffDevice, error)
+		DeleteDevice(context.Context, int64) error
+		UseDevice(ctx context.Context, teacherID int64, deviceID string) (int64, error)
+		Arrive(context.Context, *StaffAttendance) error
+		Depart(ctx context.Context, teacherID int64, date time.Time, ip string, deviceID *int64) (*StaffAttendance, error)
+		List(ctx context.Context, teacherID int64, from, to time.Time) ([]*StaffAttendance, error)
+		Report(ctx context.Context, from, to, today time.Time) ([]*StaffAttendanceSummary, error)
+	}
+	Payroll interface {
+		CreateContract(context.Context, *StaffContract) error
+		Contracts(ctx context.Context, current bool) ([]*StaffContract, error)
+		EndContract(ctx context.Context, id int64, endsOn time.Time) error
+		GeneratePayslips(ctx context.Context, period time.Time) (int, error)
+		Payslips(context.Context, PayrollFilter) ([]*Payslip, error)
+		Payslip(context.Context, int64) (*Payslip, error)
+		PayslipExpenses(ctx context.Context, payslipID int64) ([]*ExpenseClaim, error)
+		CreateExpense(context.Context, *ExpenseClaim) error
+		Expenses(context.Context, PayrollFilter) ([]*ExpenseClaim, error)
+		Expense(context.Context, int64) (*ExpenseClaim, error)
+		SetExpenseReceipt(ctx context.Context, id int64, data []byte, contentType string) error
+		ExpenseReceipt(context.Context, int64) ([]byte, string, error)
+		ReviewExpense(ctx context.Context, e *ExpenseClaim, status string, reviewerID int64, note string) error
+	}
+	Invoices interface {
+		Create(context.Context, *Invoice) error
+		CreateMany(context.Context, []*Invoice) error
+		Lines(ctx context.Context, invoiceID int64) ([]*InvoiceLine, error)
+		Billable(context.Context, BillingTarget) ([]int64, error)
+		List(context.Context, InvoiceFilter) ([]*Invoice, error)
+		GetByID(context.Context, int64) (*Invoice, error)
+		Void(context.Context, int64) error
+		Installments(ctx context.Context, invoiceID int64) ([]*Installment, error)
+		SetInstallments(ctx context.Context, invoiceID int64, installments []*Installment) error
+		Overdue(ctx context.Context, today time.Time) ([]*OverdueInstallment, error)
+		ClaimReminder(ctx context.Context, installmentID int64, level int) (bool, error)
+		Delinquency(ctx context.Context, today time.Time, byGrade bool) ([]*DelinquencyRow, error)
+		Summary(ctx context.Context, term string, today time.Time) (*FinanceSummary, error)
+		Payments(ctx context.Context, invoiceID int64) ([]*Payment, error)
+		CreatePayment(context.Context, *Payment) error
+		PaymentByAuthority(ctx context.Context, gateway, authority string) (*Payment, error)
+		ConfirmPayment(ctx context.Context, p *Payment, refID, cardPAN string) (bool, error)
+		FailPayment(ctx context.Context, p *Payment, reason string) error
+	}
+	Discounts interface {
+		CreateRule(context.Context, *DiscountRule) error
+		Rules(ctx context.Context, active bool) ([]*DiscountRule, error)
+		GetRule(context.Context, int64) (*DiscountRule, error)
+		UpdateRule(context.Context, *DiscountRule) error
+		DeleteRule(context.Context, int64) error
+		SetOverride(context.Context, *StudentDiscount) error
+		DeleteOverride(ctx context.Context, studentID, ruleID int64) error
+		Overrides(ctx context.Context, studentID int64) ([]*StudentDiscount, error)
+		Facts(ctx context.Context, studentIDs []int64) (map[int64]*DiscountFacts, error)
+	}
+	Schema interface {
+		Version(context.Context) (version uint, dirty bool, err error)
+	}
+	Receipts interface {
+		GetByNumber(ctx context.Context, number string) (*Receipt, error)
+	}
+	Terms interface {
+		Create(context.Context, *Term) error
+		List(context.Context) ([]*Term, error)
+		GetByID(context.Context, int64) (*Term, error)
+		Close(context.Context, int64) (*Term, error)
+		Archivable(ctx context.Context, closedBefore time.Time) ([]*Term, error)
+		Archive(context.Context, int64) (*TermArchive, error)
+		ReportCard(ctx context.Context, studentID int64, term *Term) (*ReportCard, error)
+		Transcript(ctx context.Context, studentID int64) (*Transcript, error)
+	}
+	Outbox interface {
+		Claim(ctx context.Context, limit, maxAttempts int, lease time.Duration) ([]*OutboxEvent, error)
+		Dispatched(context.Context, int64) error
+		Retry(ctx context.Context, id int64, delay time.Duration, reason string) error
+	}
+	Dependencies interface {
+		Count(ctx context.Context, table string, id int64) (map[string]int64, error)
+	}
+	RefreshTokens interface {
+		Create(context.Context, []byte, *RefreshToken) error
+		Consume(context.Context, []byte) (*RefreshToken, error)
+	}
+	APIKeys interface {
+		Create(context.Context, *APIKey, []byte) error
+		List(context.Context) ([]*APIKey, error)
+		GetByID(context.Context, int64) (*APIKey, error)
+		GetByHash(context.Context, []byte) (*APIKey, error)
+		SetQuotas(ctx context.Context, id, daily, monthly int64) (*APIKey, error)
+		Revoke(context.Context, int64) error
+	}
+	Quarantine interface {
+		Create(context.Context, *QuarantinedUpload, []byte) error
+		List(context.Context) ([]*QuarantinedUpload, error)
+		Delete(context.Context, int64) error
+	}
+	EmailChanges interface {
+		Request(ctx context.Context, kind string, id int64, email string) error
+		Pending(ctx context.Context, kind string, id int64) (string, error)
+		Confirm(ctx context.Context, kind string, id int64, email string) (string, error)
+	}
+	Parents interface {
+		Login(context.Context, string) (*Parent, error)
+		GetByID(context.Context, int64) (*Parent, error)
+		HasChildren(context.Context, string) (bool, error)
+		Children(context.Context, string) ([]*Student, error)
+	}
+	Search interface {
+		Search(context.Context, string, []string, int, Scope) ([]*SearchResult, error)
+		ChangedSince(context.Context, time.Time) ([]*SearchDocument, error)
 	}
 }
 
-func NewStorage(db *sql.DB) Storage {
+func NewStorage(pools Pools) Storage {
+	db := newRoutedDB(pools)
 	return Storage{
-		Execs:      &ExecStore{db},
-		Teachers:   &TeacherStore{db},
-		Students:   &StudentStore{db},
-		Classrooms: &classroomStore{db},
-		Attendance: &AttendanceStore{db},
+		Execs:                 &ExecStore{db},
+		Teachers:              &TeacherStore{db},
+		Students:              &StudentStore{db},
+		Classrooms:            &classroomStore{db},
+		Seating:               &SeatingStore{db},
+		LostItems:             &LostItemStore{db},
+		Grants:                &GrantStore{db},
+		Counseling:            &CounselingStore{db},
+		LunchMenus:            &LunchMenuStore{db},
+		WidgetTokens:          &WidgetTokenStore{db},
+		Attendance:            &AttendanceStore{db},
+		AttendanceCorrections: &AttendanceCorrectionStore{db},
+		Approvals:             &ApprovalStore{db},
+		CheckIns:              &CheckInStore{db},
+		Trash:                 &TrashStore{db},
+		Search:                &SearchStore{db},
+		Analytics:             &AnalyticsStore{db},
+		Reports:               &ReportStore{db},
+		History:               &HistoryStore{db},
+		Tags:                  &TagStore{db},
+		Notes:                 &NoteStore{db},
+		Reminders:             &ReminderStore{db},
+		SchoolDays:            &SchoolDayStore{db},
+		OnlineSessions:        &OnlineSessionStore{db},
+		Points:                &PointStore{db},
+		Surveys:               &SurveyStore{db},
+		Consents:              &ConsentStore{db},
+		Pickups:               &PickupStore{db},
+		Assets:                &AssetStore{db},
+		Bookings:              &BookingStore{db},
+		Parents:               &ParentStore{db},
+		EmailChanges:          &EmailChangeStore{db},
+		Accounts:              &AccountStore{db},
+		RefreshTokens:         &RefreshTokenStore{db},
+		APIKeys:               &APIKeyStore{db},
+		Quarantine:            &QuarantineStore{db},
+		Dependencies:          &DependencyStore{db},
+		Broadcasts:            &BroadcastStore{db},
+		SMSMessages:           &SMSMessageStore{db},
+		Telegram:              &TelegramStore{db},
+		LMS:                   &LMSStore{db},
+		OneRoster:             &OneRosterStore{db},
+		StaffAttendance:       &StaffAttendanceStore{db},
+		Payroll:               &PayrollStore{db},
+		Invoices:              &InvoiceStore{db},
+		Discounts:             &DiscountStore{db},
+		Schema:                &SchemaStore{db},
+		Receipts:              &ReceiptStore{db},
+		Terms:                 &TermStore{db},
+		Outbox:                &OutboxStore{db},
 	}
 }
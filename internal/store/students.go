@@ -20,31 +20,86 @@ type Student struct {
 	ParentName        string    `json:"parent_name"`
 	ParentPhoneNumber string    `json:"parent_phone_number"`
 	TeacherID         int64     `json:"teacher_id"`
+	NationalID        *string   `json:"national_id"`
+	StudentCode       string    `json:"student_code"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
+	// LastLoginAt and LastLoginIP are when and from where the student last
+	// signed in; only GetAll fills them.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	LastLoginIP *string    `json:"last_login_ip,omitempty"`
+}
+
+// StudentSummary is a student as rosters and cached lists show them. It
+// has no room for password material, so none can leak into responses or
+// the cache.
+type StudentSummary struct {
+	ID                int64     `json:"id"`
+	FirstName         string    `json:"first_name"`
+	LastName          string    `json:"last_name"`
+	Email             string    `json:"email"`
+	PhoneNumber       *string   `json:"phone_number"`
+	ClassRoomID       int64     `json:"classroom_id"`
+	BirthDate         time.Time `json:"birth_date"`
+	Address           string    `json:"address"`
+	ParentName        string    `json:"parent_name"`
+	ParentPhoneNumber string    `json:"parent_phone_number"`
+	TeacherID         int64     `json:"teacher_id"`
+	StudentCode       string    `json:"student_code"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// columns maps list columns to the fields they scan into.
+func (s *Student) columns() map[string]any {
+	return map[string]any{
+		"id":                  &s.ID,
+		"first_name":          &s.FirstName,
+		"last_name":           &s.LastName,
+		"email":               &s.Email,
+		"phone_number":        &s.PhoneNumber,
+		"classroom_id":        &s.ClassRoomID,
+		"birth_date":          &s.BirthDate,
+		"address":             &s.Address,
+		"parent_name":         &s.ParentName,
+		"parent_phone_number": &s.ParentPhoneNumber,
+		"teacher_id":          &s.TeacherID,
+		"national_id":         &s.NationalID,
+		"student_code":        &s.StudentCode,
+		"created_at":          &s.CreatedAt,
+		"updated_at":          &s.UpdatedAt,
+		"last_login_at":       &s.LastLoginAt,
+		"last_login_ip":       &s.LastLoginIP,
+	}
 }
 
 type StudentStore struct {
-	db *sql.DB
+	db *routedDB
 }
 
+// Create inserts the student and its account, which holds the password.
 func (s *StudentStore) Create(ctx context.Context, student *Student) error {
 	query := `
 		INSERT INTO students
-		(first_name, last_name, email, password, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id)
+		(first_name, last_name, email, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, national_id)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, created_at, updated_at
+		RETURNING id, student_code, created_at, updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
 		query,
 		student.FirstName,
 		student.LastName,
 		student.Email,
-		student.Password.hash,
 		student.PhoneNumber,
 		student.ClassRoomID,
 		student.BirthDate,
@@ -52,25 +107,48 @@ func (s *StudentStore) Create(ctx context.Context, student *Student) error {
 		student.ParentName,
 		student.ParentPhoneNumber,
 		student.TeacherID,
+		student.NationalID,
 	).Scan(
 		&student.ID,
+		&student.StudentCode,
 		&student.CreatedAt,
 		&student.UpdatedAt,
 	)
-	return err
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := createAccount(ctx, tx, student.Email, student.Password, "student", ProfileStudent, student.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *StudentStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Student, error) {
 	columns := []string{
 		"id", "first_name", "last_name", "email", "phone_number", "classroom_id",
 		"birth_date", "address", "parent_name", "parent_phone_number",
-		"teacher_id", "created_at", "updated_at",
+		"teacher_id", "national_id", "student_code", "created_at", "updated_at", "last_login_at", "last_login_ip",
 	}
 	searchCols := []string{"first_name", "last_name", "email", "classroom_id", "parent_name"}
+	sortable := []string{"id", "first_name", "last_name", "email", "classroom_id", "birth_date", "teacher_id", "student_code", "created_at", "updated_at"}
 
-	query, args := BuildPaginatedQuery("students", columns, pq, searchCols)
+	columns, err := selectColumns(columns, pq.Fields)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	exprs := columnExprs(columns, accountColumns("students"))
+	query, args, err := BuildPaginatedQuery("students", exprs, pq, searchCols, sortable, notDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -82,21 +160,7 @@ func (s *StudentStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Studen
 	students := []*Student{}
 	for rows.Next() {
 		var s Student
-		if err := rows.Scan(
-			&s.ID,
-			&s.FirstName,
-			&s.LastName,
-			&s.Email,
-			&s.PhoneNumber,
-			&s.ClassRoomID,
-			&s.BirthDate,
-			&s.Address,
-			&s.ParentName,
-			&s.ParentPhoneNumber,
-			&s.TeacherID,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(scanTargets(s.columns(), columns)...); err != nil {
 			return nil, err
 		}
 		students = append(students, &s)
@@ -110,17 +174,26 @@ func (s *StudentStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Studen
 }
 
 func (s *StudentStore) GetByID(ctx context.Context, id int64) (*Student, error) {
+	return s.getOne(ctx, "id = $1", id)
+}
+
+// GetByCode returns the student with the given student code.
+func (s *StudentStore) GetByCode(ctx context.Context, code string) (*Student, error) {
+	return s.getOne(ctx, "student_code = $1", code)
+}
+
+func (s *StudentStore) getOne(ctx context.Context, where string, arg any) (*Student, error) {
 	query := `
-	SELECT id, first_name, last_name, email, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, created_at, updated_at
+	SELECT id, first_name, last_name, email, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, national_id, student_code, created_at, updated_at
 	FROM students
-	WHERE id = $1
+	WHERE ` + where + ` AND deleted_at IS NULL
 `
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	var t Student
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(
 		&t.ID,
 		&t.FirstName,
 		&t.LastName,
@@ -132,6 +205,8 @@ func (s *StudentStore) GetByID(ctx context.Context, id int64) (*Student, error)
 		&t.ParentName,
 		&t.ParentPhoneNumber,
 		&t.TeacherID,
+		&t.NationalID,
+		&t.StudentCode,
 		&t.CreatedAt,
 		&t.UpdatedAt,
 	)
@@ -146,42 +221,141 @@ func (s *StudentStore) GetByID(ctx context.Context, id int64) (*Student, error)
 	return &t, nil
 }
 
-func (s *StudentStore) GetByEmail(ctx context.Context, email string) (*Student, error) {
+// Lookup returns students whose email matches email (case-insensitively) or
+// whose own or parent's phone number is phone. Empty arguments are ignored.
+func (s *StudentStore) Lookup(ctx context.Context, email, phone string) ([]*Student, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, created_at, updated_at
+		SELECT id, first_name, last_name, email, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, national_id, student_code, created_at, updated_at
 		FROM students
-		WHERE email = $1
+		WHERE deleted_at IS NULL
+		  AND (($1 <> '' AND LOWER(email) = LOWER($1))
+		    OR ($2 <> '' AND (phone_number = $2 OR parent_phone_number = $2)))
+		ORDER BY id
+		LIMIT 50
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	var t Student
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
-		&t.ID,
-		&t.FirstName,
-		&t.LastName,
-		&t.Email,
-		&t.Password.hash,
-		&t.PhoneNumber,
-		&t.ClassRoomID,
-		&t.BirthDate,
-		&t.Address,
-		&t.ParentName,
-		&t.ParentPhoneNumber,
-		&t.TeacherID,
-		&t.CreatedAt,
-		&t.UpdatedAt,
-	)
-
+	rows, err := s.db.QueryContext(ctx, query, email, phone)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []*Student{}
+	for rows.Next() {
+		var t Student
+		if err := rows.Scan(
+			&t.ID,
+			&t.FirstName,
+			&t.LastName,
+			&t.Email,
+			&t.PhoneNumber,
+			&t.ClassRoomID,
+			&t.BirthDate,
+			&t.Address,
+			&t.ParentName,
+			&t.ParentPhoneNumber,
+			&t.TeacherID,
+			&t.NationalID,
+			&t.StudentCode,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		); err != nil {
+			return nil, err
 		}
+		students = append(students, &t)
+	}
+
+	return students, rows.Err()
+}
+
+// DuplicateCandidate is an existing student that looks like the same person
+// as one being registered, with the reasons it matched.
+type DuplicateCandidate struct {
+	Student *Student `json:"student"`
+	Reasons []string `json:"reasons"`
+}
+
+// FindDuplicates returns live students sharing student's name and birth date,
+// parent phone number or national ID. Names are compared ignoring case and
+// surrounding whitespace.
+func (s *StudentStore) FindDuplicates(ctx context.Context, student *Student) ([]*DuplicateCandidate, error) {
+	query := `
+		SELECT id, first_name, last_name, email, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, national_id, student_code, created_at, updated_at,
+		       same_name, same_parent_phone, same_national_id
+		FROM (
+			SELECT *,
+			       LOWER(TRIM(first_name)) = LOWER(TRIM($1))
+			         AND LOWER(TRIM(last_name)) = LOWER(TRIM($2))
+			         AND birth_date::date = $3::date AS same_name,
+			       parent_phone_number = $4 AS same_parent_phone,
+			       COALESCE(national_id = $5, false) AS same_national_id
+			FROM students
+			WHERE deleted_at IS NULL
+		) s
+		WHERE same_name OR same_parent_phone OR same_national_id
+		ORDER BY same_national_id DESC, same_name DESC, id
+		LIMIT 20
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query,
+		student.FirstName,
+		student.LastName,
+		student.BirthDate,
+		student.ParentPhoneNumber,
+		student.NationalID,
+	)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &t, nil
+	candidates := []*DuplicateCandidate{}
+	for rows.Next() {
+		var t Student
+		var sameName, sameParentPhone, sameNationalID bool
+		if err := rows.Scan(
+			&t.ID,
+			&t.FirstName,
+			&t.LastName,
+			&t.Email,
+			&t.PhoneNumber,
+			&t.ClassRoomID,
+			&t.BirthDate,
+			&t.Address,
+			&t.ParentName,
+			&t.ParentPhoneNumber,
+			&t.TeacherID,
+			&t.NationalID,
+			&t.StudentCode,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+			&sameName,
+			&sameParentPhone,
+			&sameNationalID,
+		); err != nil {
+			return nil, err
+		}
+
+		c := &DuplicateCandidate{Student: &t, Reasons: []string{}}
+		if sameNationalID {
+			c.Reasons = append(c.Reasons, "national_id")
+		}
+		if sameName {
+			c.Reasons = append(c.Reasons, "name_and_birth_date")
+		}
+		if sameParentPhone {
+			c.Reasons = append(c.Reasons, "parent_phone_number")
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
 }
 
 func (s *StudentStore) Update(ctx context.Context, student *Student) error {
@@ -197,12 +371,13 @@ func (s *StudentStore) Update(ctx context.Context, student *Student) error {
 	    parent_name = $8,
 	    parent_phone_number = $9,
 	    teacher_id = $10,
+	    national_id = $12,
 	    updated_at = NOW()
-	WHERE id = $11
+	WHERE id = $11 AND deleted_at IS NULL
 	RETURNING updated_at
 `
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	err := s.db.QueryRowContext(ctx, query,
@@ -217,24 +392,32 @@ func (s *StudentStore) Update(ctx context.Context, student *Student) error {
 		student.ParentPhoneNumber,
 		student.TeacherID,
 		student.ID,
+		student.NationalID,
 	).Scan(&student.UpdatedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrNotFound
 		}
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
 		return err
 	}
 	return nil
 }
 
-func (s *StudentStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM students WHERE id = $1`
+func (s *StudentStore) Delete(ctx context.Context, id int64, deletedBy int64) error {
+	query := `
+		UPDATE students
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	result, err := s.db.ExecContext(ctx, query, id)
+	result, err := s.db.ExecContext(ctx, query, id, deletedBy)
 	if err != nil {
 		return err
 	}
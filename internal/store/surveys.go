@@ -0,0 +1,345 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Survey statuses. Only open surveys accept responses.
+const (
+	SurveyDraft  = "draft"
+	SurveyOpen   = "open"
+	SurveyClosed = "closed"
+)
+
+// Survey question types.
+const (
+	QuestionChoice = "choice"
+	QuestionText   = "text"
+	QuestionScale  = "scale"
+)
+
+type Survey struct {
+	ID          int64           `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Questions   SurveyQuestions `json:"questions"`
+	Audience    SurveyAudience  `json:"audience"`
+	Status      string          `json:"status"`
+	ClosesAt    *time.Time      `json:"closes_at"`
+	CreatedBy   int64           `json:"created_by"`
+	Responses   int64           `json:"responses"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// SurveyAudience narrows who may answer a survey. Nil fields don't restrict.
+type SurveyAudience struct {
+	Role        *string `json:"role,omitempty" validate:"omitempty,oneof=student teacher"`
+	Grade       *int64  `json:"grade,omitempty" validate:"omitempty,min=1,max=30"`
+	ClassroomID *int64  `json:"classroom_id,omitempty" validate:"omitempty,min=1"`
+}
+
+// Targets reports whether a respondent with role, who belongs to the given
+// classrooms and grades, is in the audience.
+func (a SurveyAudience) Targets(role string, classroomIDs, grades []int64) bool {
+	if a.Role != nil && *a.Role != role {
+		return false
+	}
+	if a.ClassroomID != nil && !containsID(classroomIDs, *a.ClassroomID) {
+		return false
+	}
+	if a.Grade != nil && !containsID(grades, *a.Grade) {
+		return false
+	}
+	return true
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether the survey accepts responses at now.
+func (s *Survey) IsOpen(now time.Time) bool {
+	return s.Status == SurveyOpen && (s.ClosesAt == nil || now.Before(*s.ClosesAt))
+}
+
+type SurveyQuestion struct {
+	ID       string   `json:"id" validate:"required,max=32"`
+	Type     string   `json:"type" validate:"required,oneof=choice text scale"`
+	Prompt   string   `json:"prompt" validate:"required,max=500"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty" validate:"required_if=Type choice,max=20,dive,required,max=200"`
+	Multiple bool     `json:"multiple,omitempty"`
+	// Min and Max bound scale answers (1 to 5 when unset).
+	Min int `json:"min,omitempty" validate:"min=0,max=10"`
+	Max int `json:"max,omitempty" validate:"min=0,max=10"`
+}
+
+// ScaleBounds returns the inclusive range of a scale question.
+func (q *SurveyQuestion) ScaleBounds() (int, int) {
+	if q.Min == 0 && q.Max == 0 {
+		return 1, 5
+	}
+	return q.Min, q.Max
+}
+
+type SurveyQuestions []SurveyQuestion
+
+func (q *SurveyQuestions) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, q)
+	case string:
+		return json.Unmarshal([]byte(v), q)
+	default:
+		return fmt.Errorf("cannot scan %T into SurveyQuestions", src)
+	}
+}
+
+func (q SurveyQuestions) Value() (driver.Value, error) {
+	if q == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(q)
+}
+
+// SurveyAnswer answers one question: Choices for choice questions, Text for
+// text questions and Value for scale questions.
+type SurveyAnswer struct {
+	QuestionID string   `json:"question_id" validate:"required,max=32"`
+	Choices    []string `json:"choices,omitempty" validate:"max=20"`
+	Text       string   `json:"text,omitempty" validate:"max=4000"`
+	Value      *int     `json:"value,omitempty"`
+}
+
+type SurveyAnswers []SurveyAnswer
+
+func (a *SurveyAnswers) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("cannot scan %T into SurveyAnswers", src)
+	}
+}
+
+func (a SurveyAnswers) Value() (driver.Value, error) {
+	if a == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(a)
+}
+
+type SurveyResponse struct {
+	ID             int64         `json:"id"`
+	SurveyID       int64         `json:"survey_id"`
+	RespondentID   int64         `json:"respondent_id"`
+	RespondentRole string        `json:"respondent_role"`
+	Answers        SurveyAnswers `json:"answers"`
+	SubmittedAt    time.Time     `json:"submitted_at"`
+}
+
+type SurveyStore struct {
+	db *routedDB
+}
+
+const surveyColumns = `
+	s.id, s.title, s.description, s.questions, s.audience_role, s.audience_grade,
+	s.audience_classroom_id, s.status, s.closes_at, s.created_by,
+	(SELECT COUNT(*) FROM survey_responses r WHERE r.survey_id = s.id),
+	s.created_at, s.updated_at`
+
+func scanSurvey(row interface{ Scan(...any) error }) (*Survey, error) {
+	var s Survey
+	err := row.Scan(
+		&s.ID, &s.Title, &s.Description, &s.Questions, &s.Audience.Role, &s.Audience.Grade,
+		&s.Audience.ClassroomID, &s.Status, &s.ClosesAt, &s.CreatedBy, &s.Responses,
+		&s.CreatedAt, &s.UpdatedAt,
+	)
+	return &s, err
+}
+
+func (s *SurveyStore) Create(ctx context.Context, survey *Survey) error {
+	query := `
+		INSERT INTO surveys (title, description, questions, audience_role, audience_grade, audience_classroom_id, status, closes_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query,
+		survey.Title, survey.Description, survey.Questions, survey.Audience.Role, survey.Audience.Grade,
+		survey.Audience.ClassroomID, survey.Status, survey.ClosesAt, survey.CreatedBy,
+	).Scan(&survey.ID, &survey.CreatedAt, &survey.UpdatedAt)
+}
+
+func (s *SurveyStore) GetByID(ctx context.Context, id int64) (*Survey, error) {
+	query := `SELECT ` + surveyColumns + ` FROM surveys s WHERE s.id = $1`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	survey, err := scanSurvey(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return survey, nil
+}
+
+// List returns surveys newest first, only open ones when openOnly is set.
+func (s *SurveyStore) List(ctx context.Context, openOnly bool) ([]*Survey, error) {
+	query := `
+		SELECT ` + surveyColumns + `
+		FROM surveys s
+		WHERE NOT $1 OR (s.status = 'open' AND (s.closes_at IS NULL OR s.closes_at > NOW()))
+		ORDER BY s.created_at DESC, s.id DESC
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, openOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	surveys := []*Survey{}
+	for rows.Next() {
+		survey, err := scanSurvey(rows)
+		if err != nil {
+			return nil, err
+		}
+		surveys = append(surveys, survey)
+	}
+
+	return surveys, rows.Err()
+}
+
+func (s *SurveyStore) Update(ctx context.Context, survey *Survey) error {
+	query := `
+		UPDATE surveys
+		SET title = $2, description = $3, questions = $4, audience_role = $5, audience_grade = $6,
+		    audience_classroom_id = $7, status = $8, closes_at = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query,
+		survey.ID, survey.Title, survey.Description, survey.Questions, survey.Audience.Role,
+		survey.Audience.Grade, survey.Audience.ClassroomID, survey.Status, survey.ClosesAt,
+	).Scan(&survey.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Delete removes a survey with its responses.
+func (s *SurveyStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM surveys WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// Respond stores a response. Each respondent answers a survey once
+// (ErrConflict).
+func (s *SurveyStore) Respond(ctx context.Context, r *SurveyResponse) error {
+	query := `
+		INSERT INTO survey_responses (survey_id, respondent_id, respondent_role, answers)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, submitted_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, r.SurveyID, r.RespondentID, r.RespondentRole, r.Answers).
+		Scan(&r.ID, &r.SubmittedAt)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+// Answered returns the IDs of the surveys a respondent has answered.
+func (s *SurveyStore) Answered(ctx context.Context, role string, respondentID int64) (map[int64]bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT survey_id FROM survey_responses WHERE respondent_role = $1 AND respondent_id = $2`,
+		role, respondentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	answered := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		answered[id] = true
+	}
+
+	return answered, rows.Err()
+}
+
+// Responses returns all responses to a survey, oldest first.
+func (s *SurveyStore) Responses(ctx context.Context, surveyID int64) ([]*SurveyResponse, error) {
+	query := `
+		SELECT id, survey_id, respondent_id, respondent_role, answers, submitted_at
+		FROM survey_responses
+		WHERE survey_id = $1
+		ORDER BY submitted_at, id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	responses := []*SurveyResponse{}
+	for rows.Next() {
+		var r SurveyResponse
+		if err := rows.Scan(&r.ID, &r.SurveyID, &r.RespondentID, &r.RespondentRole, &r.Answers, &r.SubmittedAt); err != nil {
+			return nil, err
+		}
+		responses = append(responses, &r)
+	}
+
+	return responses, rows.Err()
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// NormalizeTag folds a tag name to its stored form: trimmed and lower-case,
+// so "Scholarship " and "scholarship" are the same tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Uses      int64     `json:"uses"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TagStore manages free-form labels attached to records of any table, keyed
+// by table name ("students", "teachers") and row ID.
+type TagStore struct {
+	db *routedDB
+}
+
+// List returns all tags with how many records carry each.
+func (s *TagStore) List(ctx context.Context) ([]*Tag, error) {
+	query := `
+		SELECT t.id, t.name, COUNT(a.tag_id), t.created_at
+		FROM tags t
+		LEFT JOIN tag_assignments a ON a.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Uses, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &t)
+	}
+
+	return tags, rows.Err()
+}
+
+// Delete removes a tag and all its assignments.
+func (s *TagStore) Delete(ctx context.Context, name string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM tags WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// ForEntity returns the names of the tags on one record.
+func (s *TagStore) ForEntity(ctx context.Context, entity string, entityID int64) ([]string, error) {
+	query := `
+		SELECT t.name
+		FROM tag_assignments a
+		JOIN tags t ON t.id = a.tag_id
+		WHERE a.entity = $1 AND a.entity_id = $2
+		ORDER BY t.name
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// Assign puts a tag on a record, creating the tag on first use. Assigning a
+// tag twice is a no-op.
+func (s *TagStore) Assign(ctx context.Context, entity string, entityID int64, name string, createdBy int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var tagID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO tags (name) VALUES ($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	`, name).Scan(&tagID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tag_assignments (tag_id, entity, entity_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, tagID, entity, entityID, createdBy)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Unassign removes a tag from a record. The tag itself is kept.
+func (s *TagStore) Unassign(ctx context.Context, entity string, entityID int64, name string) error {
+	query := `
+		DELETE FROM tag_assignments a
+		USING tags t
+		WHERE t.id = a.tag_id AND t.name = $3 AND a.entity = $1 AND a.entity_id = $2
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, entity, entityID, name)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
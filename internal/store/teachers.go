@@ -5,66 +5,122 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Teacher struct {
-	ID          int64     `json:"id"`
-	FirstName   string    `json:"first_name"`
-	LastName    string    `json:"last_name"`
-	Email       string    `json:"email"`
-	Password    password  `json:"-"`
-	Subject     string    `json:"subject"`
-	PhoneNumber string    `json:"phone_number"`
-	HireDate    time.Time `json:"hire_date"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID        int64    `json:"id"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Email     string   `json:"email"`
+	Password  password `json:"-"`
+	Subject   string   `json:"subject"`
+	// SubjectI18n holds translations of Subject.
+	SubjectI18n LocalizedText `json:"subject_i18n"`
+	PhoneNumber string        `json:"phone_number"`
+	HireDate    time.Time     `json:"hire_date"`
+	NationalID  *string       `json:"national_id"`
+	StaffCode   string        `json:"staff_code"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	// LastLoginAt and LastLoginIP are when and from where the teacher last
+	// signed in; only GetAll fills them.
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	LastLoginIP *string    `json:"last_login_ip,omitempty"`
+}
+
+// columns maps list columns to the fields they scan into.
+func (t *Teacher) columns() map[string]any {
+	return map[string]any{
+		"id":            &t.ID,
+		"first_name":    &t.FirstName,
+		"last_name":     &t.LastName,
+		"email":         &t.Email,
+		"subject":       &t.Subject,
+		"subject_i18n":  &t.SubjectI18n,
+		"phone_number":  &t.PhoneNumber,
+		"hire_date":     &t.HireDate,
+		"national_id":   &t.NationalID,
+		"staff_code":    &t.StaffCode,
+		"created_at":    &t.CreatedAt,
+		"updated_at":    &t.UpdatedAt,
+		"last_login_at": &t.LastLoginAt,
+		"last_login_ip": &t.LastLoginIP,
+	}
 }
 
 type TeacherStore struct {
-	db *sql.DB
+	db *routedDB
 }
 
+// Create inserts the teacher and its account, which holds the password.
 func (s *TeacherStore) Create(ctx context.Context, teacher *Teacher) error {
 	query := `
-		INSERT INTO teachers (first_name, last_name, email, password, subject, phone_number, hire_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at, updated_at
+		INSERT INTO teachers (first_name, last_name, email, subject, phone_number, hire_date, subject_i18n, national_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, staff_code, created_at, updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
 		query,
 		teacher.FirstName,
 		teacher.LastName,
 		teacher.Email,
-		teacher.Password.hash,
 		teacher.Subject,
 		teacher.PhoneNumber,
 		teacher.HireDate,
+		teacher.SubjectI18n,
+		teacher.NationalID,
 	).Scan(
 		&teacher.ID,
+		&teacher.StaffCode,
 		&teacher.CreatedAt,
 		&teacher.UpdatedAt,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
 		return err
 	}
 
-	return nil
+	if err := createAccount(ctx, tx, teacher.Email, teacher.Password, "teacher", ProfileTeacher, teacher.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *TeacherStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Teacher, error) {
 	columns := []string{
-		"id", "first_name", "last_name", "email", "subject",
-		"phone_number", "hire_date", "created_at", "updated_at",
+		"id", "first_name", "last_name", "email", "subject", "subject_i18n",
+		"phone_number", "hire_date", "national_id", "staff_code", "created_at", "updated_at", "last_login_at", "last_login_ip",
 	}
-	searchCols := []string{"first_name", "last_name", "email", "subject"}
+	searchCols := []string{"first_name", "last_name", "first_name || ' ' || last_name", "email", "subject"}
+	sortable := []string{"id", "first_name", "last_name", "email", "subject", "hire_date", "staff_code", "created_at", "updated_at"}
 
-	query, args := BuildPaginatedQuery("teachers", columns, pq, searchCols)
+	columns, err := selectColumns(columns, pq.Fields)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	exprs := columnExprs(columns, accountColumns("teachers"))
+	query, args, err := BuildPaginatedQuery("teachers", exprs, pq, searchCols, sortable, notDeleted)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -76,17 +132,7 @@ func (s *TeacherStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Teache
 	teachers := []*Teacher{}
 	for rows.Next() {
 		var t Teacher
-		if err := rows.Scan(
-			&t.ID,
-			&t.FirstName,
-			&t.LastName,
-			&t.Email,
-			&t.Subject,
-			&t.PhoneNumber,
-			&t.HireDate,
-			&t.CreatedAt,
-			&t.UpdatedAt,
-		); err != nil {
+		if err := rows.Scan(scanTargets(t.columns(), columns)...); err != nil {
 			return nil, err
 		}
 		teachers = append(teachers, &t)
@@ -100,24 +146,36 @@ func (s *TeacherStore) GetAll(ctx context.Context, pq PaginatedQuery) ([]*Teache
 }
 
 func (s *TeacherStore) GetByID(ctx context.Context, id int64) (*Teacher, error) {
+	return s.getOne(ctx, "id = $1", id)
+}
+
+// GetByCode returns the teacher with the given staff code.
+func (s *TeacherStore) GetByCode(ctx context.Context, code string) (*Teacher, error) {
+	return s.getOne(ctx, "staff_code = $1", code)
+}
+
+func (s *TeacherStore) getOne(ctx context.Context, where string, arg any) (*Teacher, error) {
 	query := `
-		SELECT id, first_name, last_name, email, subject, phone_number, hire_date, created_at, updated_at
+		SELECT id, first_name, last_name, email, subject, subject_i18n, phone_number, hire_date, national_id, staff_code, created_at, updated_at
 		FROM teachers
-		WHERE id = $1
+		WHERE ` + where + ` AND deleted_at IS NULL
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	var t Teacher
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(
 		&t.ID,
 		&t.FirstName,
 		&t.LastName,
 		&t.Email,
 		&t.Subject,
+		&t.SubjectI18n,
 		&t.PhoneNumber,
 		&t.HireDate,
+		&t.NationalID,
+		&t.StaffCode,
 		&t.CreatedAt,
 		&t.UpdatedAt,
 	)
@@ -132,50 +190,98 @@ func (s *TeacherStore) GetByID(ctx context.Context, id int64) (*Teacher, error)
 	return &t, nil
 }
 
-func (s *TeacherStore) GetByEmail(ctx context.Context, email string) (*Teacher, error) {
+// Lookup returns teachers whose email matches email (case-insensitively) or
+// whose phone number is phone. Empty arguments are ignored.
+func (s *TeacherStore) Lookup(ctx context.Context, email, phone string) ([]*Teacher, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password, subject, phone_number, hire_date, created_at, updated_at
+		SELECT id, first_name, last_name, email, subject, subject_i18n, phone_number, hire_date, national_id, staff_code, created_at, updated_at
 		FROM teachers
-		WHERE email = $1
+		WHERE deleted_at IS NULL
+		  AND (($1 <> '' AND LOWER(email) = LOWER($1))
+		    OR ($2 <> '' AND phone_number = $2))
+		ORDER BY id
+		LIMIT 50
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	var t Teacher
-	err := s.db.QueryRowContext(ctx, query, email).Scan(
-		&t.ID,
-		&t.FirstName,
-		&t.LastName,
-		&t.Email,
-		&t.Password.hash,
-		&t.Subject,
-		&t.PhoneNumber,
-		&t.HireDate,
-		&t.CreatedAt,
-		&t.UpdatedAt,
-	)
-
+	rows, err := s.db.QueryContext(ctx, query, email, phone)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
+		return nil, err
+	}
+	defer rows.Close()
+
+	teachers := []*Teacher{}
+	for rows.Next() {
+		var t Teacher
+		if err := rows.Scan(
+			&t.ID,
+			&t.FirstName,
+			&t.LastName,
+			&t.Email,
+			&t.Subject,
+			&t.SubjectI18n,
+			&t.PhoneNumber,
+			&t.HireDate,
+			&t.NationalID,
+			&t.StaffCode,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		); err != nil {
+			return nil, err
 		}
+		teachers = append(teachers, &t)
+	}
+
+	return teachers, rows.Err()
+}
+
+// GetTeachingToday returns the IDs of teachers expected to teach today. There
+// is no timetable, so a teacher counts when attendance was taken in one of
+// their classrooms on today's weekday within the last four weeks.
+func (s *TeacherStore) GetTeachingToday(ctx context.Context) ([]int64, error) {
+	query := `
+		SELECT DISTINCT c.teacher_id
+		FROM attendance_records a
+		JOIN classrooms c ON c.id = a.classroom_id AND c.deleted_at IS NULL
+		JOIN teachers t ON t.id = c.teacher_id AND t.deleted_at IS NULL
+		WHERE a.date >= CURRENT_DATE - 28
+		  AND EXTRACT(ISODOW FROM a.date) = EXTRACT(ISODOW FROM CURRENT_DATE)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &t, nil
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
 }
 
-func (s *StudentStore) GetByTeacherID(ctx context.Context, teacherID int64) ([]*Student, error) {
+// GetByTeacherID returns the roster of a teacher's live students.
+func (s *StudentStore) GetByTeacherID(ctx context.Context, teacherID int64) ([]*StudentSummary, error) {
 	query := `
-		SELECT 
-			id, first_name, last_name, email, password, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, created_at, updated_at
+		SELECT
+			id, first_name, last_name, email, phone_number, classroom_id, birth_date, address, parent_name, parent_phone_number, teacher_id, student_code, created_at, updated_at
 		FROM students
-		WHERE teacher_id = $1
+		WHERE teacher_id = $1 AND deleted_at IS NULL
 		ORDER BY id ASC
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	rows, err := s.db.QueryContext(ctx, query, teacherID)
@@ -184,15 +290,14 @@ func (s *StudentStore) GetByTeacherID(ctx context.Context, teacherID int64) ([]*
 	}
 	defer rows.Close()
 
-	students := []*Student{}
+	students := []*StudentSummary{}
 	for rows.Next() {
-		var s Student
+		var s StudentSummary
 		if err := rows.Scan(
 			&s.ID,
 			&s.FirstName,
 			&s.LastName,
 			&s.Email,
-			&s.Password.hash,
 			&s.PhoneNumber,
 			&s.ClassRoomID,
 			&s.BirthDate,
@@ -200,6 +305,7 @@ func (s *StudentStore) GetByTeacherID(ctx context.Context, teacherID int64) ([]*
 			&s.ParentName,
 			&s.ParentPhoneNumber,
 			&s.TeacherID,
+			&s.StudentCode,
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		); err != nil {
@@ -224,12 +330,14 @@ func (s *TeacherStore) Update(ctx context.Context, teacher *Teacher) error {
 		    subject = $4,
 		    phone_number = $5,
 		    hire_date = $6,
+		    subject_i18n = $8,
+		    national_id = $9,
 		    updated_at = NOW()
-		WHERE id = $7
+		WHERE id = $7 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
 	err := s.db.QueryRowContext(ctx, query,
@@ -240,24 +348,33 @@ func (s *TeacherStore) Update(ctx context.Context, teacher *Teacher) error {
 		teacher.PhoneNumber,
 		teacher.HireDate,
 		teacher.ID,
+		teacher.SubjectI18n,
+		teacher.NationalID,
 	).Scan(&teacher.UpdatedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrNotFound
 		}
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
 		return err
 	}
 	return nil
 }
 
-func (s *TeacherStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM teachers WHERE id = $1`
+func (s *TeacherStore) Delete(ctx context.Context, id int64, deletedBy int64) error {
+	query := `
+		UPDATE teachers
+		SET deleted_at = NOW(), deleted_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	ctx, cancel := withQueryTimeout(ctx)
 	defer cancel()
 
-	result, err := s.db.ExecContext(ctx, query, id)
+	result, err := s.db.ExecContext(ctx, query, id, deletedBy)
 	if err != nil {
 		return err
 	}
@@ -272,3 +389,87 @@ func (s *TeacherStore) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// TeacherDependents are the live records that point at a teacher.
+type TeacherDependents struct {
+	Classrooms []int64 `json:"classrooms"`
+	Students   []int64 `json:"students"`
+}
+
+// Empty reports whether nothing depends on the teacher.
+func (d *TeacherDependents) Empty() bool {
+	return len(d.Classrooms) == 0 && len(d.Students) == 0
+}
+
+// Dependents returns the IDs of live classrooms and students assigned to
+// a teacher.
+func (s *TeacherStore) Dependents(ctx context.Context, id int64) (*TeacherDependents, error) {
+	query := `
+		SELECT
+			ARRAY(SELECT id FROM classrooms WHERE teacher_id = $1 AND deleted_at IS NULL ORDER BY id),
+			ARRAY(SELECT id FROM students WHERE teacher_id = $1 AND deleted_at IS NULL ORDER BY id)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	d := &TeacherDependents{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(pq.Array(&d.Classrooms), pq.Array(&d.Students))
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeleteReassigning moves a teacher's classrooms and students to another
+// live teacher and deletes the teacher, in one transaction. A missing
+// teacher or target is ErrNotFound.
+func (s *TeacherStore) DeleteReassigning(ctx context.Context, id, reassignTo, deletedBy int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the target so it cannot be deleted while taking over
+	var target int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM teachers WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		reassignTo,
+	).Scan(&target)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE classrooms SET teacher_id = $2, updated_at = NOW() WHERE teacher_id = $1 AND deleted_at IS NULL`,
+		id, reassignTo,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE students SET teacher_id = $2, updated_at = NOW() WHERE teacher_id = $1 AND deleted_at IS NULL`,
+		id, reassignTo,
+	); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE teachers SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL`,
+		id, deletedBy,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectRowsAffected(res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TelegramLink is a parent's connection to a Telegram chat.
+type TelegramLink struct {
+	ParentID int64      `json:"parent_id"`
+	Linked   bool       `json:"linked"`
+	LinkedAt *time.Time `json:"linked_at,omitempty"`
+}
+
+type TelegramStore struct {
+	db *routedDB
+}
+
+// IssueCode stores the hash of a new link code for parentID, replacing any
+// earlier one. An existing link stays until the new code is used.
+func (s *TelegramStore) IssueCode(ctx context.Context, parentID int64, hash []byte, expiresAt time.Time) error {
+	query := `
+		INSERT INTO telegram_links (parent_id, code_hash, code_expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (parent_id) DO UPDATE SET code_hash = EXCLUDED.code_hash, code_expires_at = EXCLUDED.code_expires_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, parentID, hash, expiresAt)
+	return err
+}
+
+// Link connects chatID to the parent holding the unexpired code with hash
+// and returns that parent's ID. A chat belongs to one parent at a time, so
+// any earlier link of the chat is dropped. An unknown or expired code is
+// ErrNotFound.
+func (s *TelegramStore) Link(ctx context.Context, hash []byte, chatID int64) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE telegram_links SET chat_id = NULL, linked_at = NULL WHERE chat_id = $1`, chatID); err != nil {
+		return 0, err
+	}
+
+	var parentID int64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE telegram_links
+		SET chat_id = $2, linked_at = NOW(), code_hash = NULL, code_expires_at = NULL
+		WHERE code_hash = $1 AND code_expires_at > NOW()
+		RETURNING parent_id
+	`, hash, chatID).Scan(&parentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return parentID, tx.Commit()
+}
+
+// ParentForChat returns the parent linked to chatID.
+func (s *TelegramStore) ParentForChat(ctx context.Context, chatID int64) (*Parent, error) {
+	query := `
+		SELECT p.id, p.phone_number, p.created_at, p.last_login_at
+		FROM telegram_links t
+		JOIN parents p ON p.id = t.parent_id
+		WHERE t.chat_id = $1
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var p Parent
+	err := s.db.QueryRowContext(ctx, query, chatID).Scan(&p.ID, &p.PhoneNumber, &p.CreatedAt, &p.LastLoginAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Get reports whether parentID has a linked chat.
+func (s *TelegramStore) Get(ctx context.Context, parentID int64) (*TelegramLink, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	link := TelegramLink{ParentID: parentID}
+	err := s.db.QueryRowContext(ctx, `SELECT chat_id IS NOT NULL, linked_at FROM telegram_links WHERE parent_id = $1`, parentID).
+		Scan(&link.Linked, &link.LinkedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Unlink disconnects the parent's chat. It returns ErrNotFound when none
+// was linked.
+func (s *TelegramStore) Unlink(ctx context.Context, parentID int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE telegram_links SET chat_id = NULL, linked_at = NULL WHERE parent_id = $1 AND chat_id IS NOT NULL`, parentID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// UnlinkChat disconnects chatID, for when the parent leaves from Telegram.
+func (s *TelegramStore) UnlinkChat(ctx context.Context, chatID int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE telegram_links SET chat_id = NULL, linked_at = NULL WHERE chat_id = $1`, chatID)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
+
+// ChatsForPhones returns the linked chats of the parents with the given
+// phone numbers, keyed by phone number.
+func (s *TelegramStore) ChatsForPhones(ctx context.Context, phones []string) (map[string]int64, error) {
+	query := `
+		SELECT p.phone_number, t.chat_id
+		FROM telegram_links t
+		JOIN parents p ON p.id = t.parent_id
+		WHERE t.chat_id IS NOT NULL AND p.phone_number = ANY($1)
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(phones))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chats := map[string]int64{}
+	for rows.Next() {
+		var phone string
+		var chatID int64
+		if err := rows.Scan(&phone, &chatID); err != nil {
+			return nil, err
+		}
+		chats[phone] = chatID
+	}
+	return chats, rows.Err()
+}
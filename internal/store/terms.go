@@ -0,0 +1,413 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Term is an academic term. Closing it ends changes to its records and
+// snapshots them for transcripts; archiving moves its attendance and grades
+// out of the hot tables.
+type Term struct {
+	ID         int64      `json:"id" example:"42"`
+	Name       string     `json:"name"`
+	StartsOn   time.Time  `json:"starts_on" example:"2026-09-23T00:00:00Z"`
+	EndsOn     time.Time  `json:"ends_on" example:"2027-01-20T00:00:00Z"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" example:"2026-09-01T08:00:00Z"`
+}
+
+// TermArchive is what archiving a term moved.
+type TermArchive struct {
+	TermID     int64 `json:"term_id"`
+	Attendance int64 `json:"attendance"`
+	Grades     int64 `json:"grades"`
+}
+
+// ReportCard is a student's record for a term, read from the archive once
+// the term is archived and from the live tables before.
+type ReportCard struct {
+	Term       *Term          `json:"term"`
+	StudentID  int64          `json:"student_id"`
+	Archived   bool           `json:"archived"`
+	Attendance map[string]int `json:"attendance"`
+	Days       int            `json:"days"`
+	Grades     []*Grade       `json:"grades"`
+}
+
+// Transcript is a student's record over every closed term, oldest first,
+// read from the snapshots taken when each term closed. The name is the one
+// the student had when the latest of them closed.
+type Transcript struct {
+	StudentID int64             `json:"student_id"`
+	FirstName string            `json:"first_name"`
+	LastName  string            `json:"last_name"`
+	Terms     []*TranscriptTerm `json:"terms"`
+}
+
+type TranscriptTerm struct {
+	Term       *Term            `json:"term"`
+	Attendance map[string]int   `json:"attendance"`
+	Days       int              `json:"days"`
+	Grades     []*SnapshotGrade `json:"grades"`
+}
+
+// SnapshotGrade is a grade as it stood when its term closed.
+type SnapshotGrade struct {
+	Classroom  string   `json:"classroom"`
+	Source     string   `json:"source"`
+	Assignment string   `json:"assignment"`
+	Score      float64  `json:"score"`
+	MaxScore   *float64 `json:"max_score,omitempty"`
+}
+
+type TermStore struct {
+	db *routedDB
+}
+
+const termColumns = `id, name, starts_on, ends_on, closed_at, archived_at, created_at`
+
+func scanTerm(row interface{ Scan(...any) error }) (*Term, error) {
+	var t Term
+	err := row.Scan(&t.ID, &t.Name, &t.StartsOn, &t.EndsOn, &t.ClosedAt, &t.ArchivedAt, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create adds a term; ErrConflict when its name is taken or its dates
+// overlap another term's.
+func (s *TermStore) Create(ctx context.Context, t *Term) error {
+	query := `
+		INSERT INTO terms (name, starts_on, ends_on)
+		VALUES ($1, $2::date, $3::date)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, t.Name, t.StartsOn.Format(time.DateOnly), t.EndsOn.Format(time.DateOnly)).
+		Scan(&t.ID, &t.CreatedAt)
+	if isUniqueViolation(err) || isExclusionViolation(err) {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *TermStore) List(ctx context.Context) ([]*Term, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+termColumns+` FROM terms ORDER BY starts_on DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []*Term{}
+	for rows.Next() {
+		t, err := scanTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, rows.Err()
+}
+
+func (s *TermStore) GetByID(ctx context.Context, id int64) (*Term, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return scanTerm(s.db.QueryRowContext(ctx, `SELECT `+termColumns+` FROM terms WHERE id = $1`, id))
+}
+
+// Close marks a term closed and, in the same transaction, snapshots the
+// attendance and grades of every student with records in it. ErrConflict
+// when it already is closed.
+func (s *TermStore) Close(ctx context.Context, id int64) (*Term, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTerm(tx.QueryRowContext(ctx, `
+		UPDATE terms SET closed_at = NOW()
+		WHERE id = $1 AND closed_at IS NULL
+		RETURNING `+termColumns, id))
+	if errors.Is(err, ErrNotFound) {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := t.StartsOn.Format(time.DateOnly), t.EndsOn.Format(time.DateOnly)
+	if _, err := tx.ExecContext(ctx, `
+		WITH attendance AS (
+			SELECT student_id, status::text AS status FROM attendance_records WHERE date BETWEEN $2::date AND $3::date
+		), students_in AS (
+			SELECT student_id FROM attendance
+			UNION
+			SELECT student_id FROM grades WHERE imported_at >= $2::date AND imported_at < $3::date + 1
+		)
+		INSERT INTO term_student_snapshots (term_id, student_id, first_name, last_name, present, absent, late, excused)
+		SELECT $1, i.student_id, s.first_name, s.last_name,
+			COUNT(a.status) FILTER (WHERE a.status = 'present'),
+			COUNT(a.status) FILTER (WHERE a.status = 'absent'),
+			COUNT(a.status) FILTER (WHERE a.status = 'late'),
+			COUNT(a.status) FILTER (WHERE a.status = 'excused')
+		FROM students_in i
+		JOIN students s ON s.id = i.student_id
+		LEFT JOIN attendance a ON a.student_id = i.student_id
+		GROUP BY i.student_id, s.first_name, s.last_name
+	`, id, from, to); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO term_grade_snapshots (term_id, student_id, classroom_name, source, assignment, score, max_score)
+		SELECT $1, g.student_id, COALESCE(c.name, ''), g.source, g.assignment, g.score, g.max_score
+		FROM grades g
+		LEFT JOIN classrooms c ON c.id = g.classroom_id
+		WHERE g.imported_at >= $2::date AND g.imported_at < $3::date + 1
+	`, id, from, to); err != nil {
+		return nil, err
+	}
+
+	return t, tx.Commit()
+}
+
+// Archivable lists the terms closed before closedBefore that are not
+// archived yet, oldest first.
+func (s *TermStore) Archivable(ctx context.Context, closedBefore time.Time) ([]*Term, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+termColumns+` FROM terms
+		WHERE closed_at < $1 AND archived_at IS NULL
+		ORDER BY starts_on
+	`, closedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []*Term{}
+	for rows.Next() {
+		t, err := scanTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, rows.Err()
+}
+
+// Archive moves a closed term's attendance, and the grades imported during
+// it, to the archive schema in one transaction. ErrNotFound when the term
+// does not exist and ErrConflict when it is open or already archived.
+func (s *TermStore) Archive(ctx context.Context, id int64) (*TermArchive, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTerm(tx.QueryRowContext(ctx, `SELECT `+termColumns+` FROM terms WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, err
+	}
+	if t.ClosedAt == nil || t.ArchivedAt != nil {
+		return nil, ErrConflict
+	}
+
+	from, to := t.StartsOn.Format(time.DateOnly), t.EndsOn.Format(time.DateOnly)
+	a := &TermArchive{TermID: id}
+
+	res, err := tx.ExecContext(ctx, `
+		WITH moved AS (
+			DELETE FROM attendance_records WHERE date BETWEEN $2::date AND $3::date RETURNING *
+		)
+		INSERT INTO archive.attendance_records (id, term_id, student_id, teacher_id, classroom_id, date, status,
+			note, method, checked_in_at, created_at)
+		SELECT id, $1, student_id, teacher_id, classroom_id, date, status, note, method, checked_in_at, created_at
+		FROM moved
+	`, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if a.Attendance, err = res.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	res, err = tx.ExecContext(ctx, `
+		WITH moved AS (
+			DELETE FROM grades WHERE imported_at >= $2::date AND imported_at < $3::date + 1 RETURNING *
+		)
+		INSERT INTO archive.grades (id, term_id, student_id, classroom_id, source, assignment, score, max_score, imported_at)
+		SELECT id, $1, student_id, classroom_id, source, assignment, score, max_score, imported_at
+		FROM moved
+	`, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if a.Grades, err = res.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE terms SET archived_at = NOW() WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	return a, tx.Commit()
+}
+
+// ReportCard gathers a student's attendance and grades for a term.
+func (s *TermStore) ReportCard(ctx context.Context, studentID int64, t *Term) (*ReportCard, error) {
+	attendanceTable, gradesTable := "attendance_records", "grades"
+	if t.ArchivedAt != nil {
+		attendanceTable, gradesTable = "archive.attendance_records", "archive.grades"
+	}
+	from, to := t.StartsOn.Format(time.DateOnly), t.EndsOn.Format(time.DateOnly)
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rc := &ReportCard{Term: t, StudentID: studentID, Archived: t.ArchivedAt != nil, Attendance: map[string]int{}}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT status, COUNT(*) FROM `+attendanceTable+`
+		WHERE student_id = $1 AND date BETWEEN $2::date AND $3::date
+		GROUP BY status
+	`, studentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		rc.Attendance[status] = n
+		rc.Days += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT g.id, g.student_id, s.first_name, s.last_name, COALESCE(g.classroom_id, 0), g.source, g.assignment,
+			g.score, g.max_score, g.imported_at
+		FROM `+gradesTable+` g
+		JOIN students s ON s.id = g.student_id
+		WHERE g.student_id = $1 AND g.imported_at >= $2::date AND g.imported_at < $3::date + 1
+		ORDER BY g.source, g.assignment
+	`, studentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rc.Grades = []*Grade{}
+	for rows.Next() {
+		var g Grade
+		if err := rows.Scan(&g.ID, &g.StudentID, &g.FirstName, &g.LastName, &g.ClassroomID, &g.Source,
+			&g.Assignment, &g.Score, &g.MaxScore, &g.ImportedAt); err != nil {
+			return nil, err
+		}
+		rc.Grades = append(rc.Grades, &g)
+	}
+	return rc, rows.Err()
+}
+
+// Transcript reads a student's term snapshots. ErrNotFound when no term
+// with records of theirs has been closed.
+func (s *TermStore) Transcript(ctx context.Context, studentID int64) (*Transcript, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.id, t.name, t.starts_on, t.ends_on, t.closed_at, t.archived_at, t.created_at,
+			sn.first_name, sn.last_name, sn.present, sn.absent, sn.late, sn.excused
+		FROM term_student_snapshots sn
+		JOIN terms t ON t.id = sn.term_id
+		WHERE sn.student_id = $1
+		ORDER BY t.starts_on
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tr := &Transcript{StudentID: studentID, Terms: []*TranscriptTerm{}}
+	terms := map[int64]*TranscriptTerm{}
+	for rows.Next() {
+		var (
+			t                              Term
+			present, absent, late, excused int
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.StartsOn, &t.EndsOn, &t.ClosedAt, &t.ArchivedAt, &t.CreatedAt,
+			&tr.FirstName, &tr.LastName, &present, &absent, &late, &excused); err != nil {
+			return nil, err
+		}
+		tt := &TranscriptTerm{
+			Term:       &t,
+			Attendance: map[string]int{"present": present, "absent": absent, "late": late, "excused": excused},
+			Days:       present + absent + late + excused,
+			Grades:     []*SnapshotGrade{},
+		}
+		tr.Terms = append(tr.Terms, tt)
+		terms[t.ID] = tt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tr.Terms) == 0 {
+		return nil, ErrNotFound
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT term_id, classroom_name, source, assignment, score, max_score
+		FROM term_grade_snapshots
+		WHERE student_id = $1
+		ORDER BY classroom_name, source, assignment
+	`, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			termID int64
+			g      SnapshotGrade
+		)
+		if err := rows.Scan(&termID, &g.Classroom, &g.Source, &g.Assignment, &g.Score, &g.MaxScore); err != nil {
+			return nil, err
+		}
+		if tt := terms[termID]; tt != nil {
+			tt.Grades = append(tt.Grades, &g)
+		}
+	}
+	return tr, rows.Err()
+}
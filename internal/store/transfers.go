@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ErrClassroomFull is returned when a transfer would put a classroom over
+// its capacity.
+var ErrClassroomFull = errors.New("classroom is over capacity")
+
+// RosterClassroom is a classroom with the students currently in it.
+type RosterClassroom struct {
+	ID        int64            `json:"id"`
+	Name      string           `json:"name"`
+	Capacity  int64            `json:"capacity"`
+	TeacherID int64            `json:"teacher_id"`
+	Students  []*RosterStudent `json:"students"`
+}
+
+type RosterStudent struct {
+	ID                int64  `json:"id"`
+	FirstName         string `json:"first_name"`
+	LastName          string `json:"last_name"`
+	ParentPhoneNumber string `json:"parent_phone_number"`
+}
+
+// StudentTransfer moves one student to another classroom.
+type StudentTransfer struct {
+	StudentID       int64 `json:"student_id" validate:"required"`
+	FromClassroomID int64 `json:"from_classroom_id" validate:"required"`
+	ToClassroomID   int64 `json:"to_classroom_id" validate:"required"`
+}
+
+// GradeRoster returns the live classrooms of a grade with their students,
+// ordered by classroom ID.
+func (s *StudentStore) GradeRoster(ctx context.Context, grade int64) ([]*RosterClassroom, error) {
+	query := `
+		SELECT c.id, c.name, c.capacity, c.teacher_id,
+		       s.id, s.first_name, s.last_name, s.parent_phone_number
+		FROM classrooms c
+		LEFT JOIN students s ON s.classroom_id = c.id AND s.deleted_at IS NULL
+		WHERE c.grade = $1 AND c.deleted_at IS NULL
+		ORDER BY c.id, s.id
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, grade)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roster := []*RosterClassroom{}
+	var current *RosterClassroom
+	for rows.Next() {
+		var c RosterClassroom
+		var sid *int64
+		var first, last, parentPhone *string
+		if err := rows.Scan(&c.ID, &c.Name, &c.Capacity, &c.TeacherID, &sid, &first, &last, &parentPhone); err != nil {
+			return nil, err
+		}
+		if current == nil || current.ID != c.ID {
+			c.Students = []*RosterStudent{}
+			current = &c
+			roster = append(roster, current)
+		}
+		if sid != nil {
+			current.Students = append(current.Students, &RosterStudent{
+				ID:                *sid,
+				FirstName:         *first,
+				LastName:          *last,
+				ParentPhoneNumber: *parentPhone,
+			})
+		}
+	}
+
+	return roster, rows.Err()
+}
+
+// Transfer applies moves in one transaction. Each student must still be in
+// its from classroom, and no target classroom may end up over capacity;
+// otherwise nothing is changed. Students take on the target classroom's
+// teacher.
+func (s *StudentStore) Transfer(ctx context.Context, moves []*StudentTransfer) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the target classrooms so concurrent transfers into them queue up
+	// behind this one's capacity check.
+	targetIDs := make([]int64, 0, len(moves))
+	for _, m := range moves {
+		targetIDs = append(targetIDs, m.ToClassroomID)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`SELECT id FROM classrooms WHERE id = ANY($1) ORDER BY id FOR UPDATE`,
+		pq.Array(targetIDs),
+	); err != nil {
+		return err
+	}
+
+	targets := map[int64]struct{}{}
+	for _, m := range moves {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE students s
+			SET classroom_id = c.id, teacher_id = c.teacher_id, updated_at = NOW()
+			FROM classrooms c
+			WHERE s.id = $1 AND s.classroom_id = $2 AND s.deleted_at IS NULL
+			  AND c.id = $3 AND c.deleted_at IS NULL
+		`, m.StudentID, m.FromClassroomID, m.ToClassroomID)
+		if err != nil {
+			return err
+		}
+		if err := expectRowsAffected(res); err != nil {
+			return fmt.Errorf("student %d in classroom %d to classroom %d: %w",
+				m.StudentID, m.FromClassroomID, m.ToClassroomID, err)
+		}
+		targets[m.ToClassroomID] = struct{}{}
+	}
+
+	for id := range targets {
+		var over bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(s.id) > c.capacity
+			FROM classrooms c
+			LEFT JOIN students s ON s.classroom_id = c.id AND s.deleted_at IS NULL
+			WHERE c.id = $1
+			GROUP BY c.id
+		`, id).Scan(&over)
+		if err != nil {
+			return err
+		}
+		if over {
+			return fmt.Errorf("classroom %d: %w", id, ErrClassroomFull)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrUnknownEntity = errors.New("unknown entity")
+
+// TrashedRecord is a soft-deleted row as shown in the recycle bin.
+type TrashedRecord struct {
+	ID          int64     `json:"id"`
+	Entity      string    `json:"entity"`
+	DisplayName string    `json:"display_name"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	DeletedBy   *int64    `json:"deleted_by,omitempty"`
+}
+
+type trashEntity struct {
+	table       string
+	displayName string
+	searchCols  []string
+	// purgeGuard must hold for a row to be purged, so a hard delete never
+	// cascades into, is blocked by or clears references from dependents
+	// that are kept.
+	purgeGuard string
+}
+
+var trashEntities = map[string]trashEntity{
+	"students": {
+		table:       "students",
+		displayName: "first_name || ' ' || last_name",
+		searchCols:  []string{"first_name", "last_name", "email"},
+	},
+	"teachers": {
+		table:       "teachers",
+		displayName: "first_name || ' ' || last_name",
+		searchCols:  []string{"first_name", "last_name", "email"},
+		// trashed students are purged along with their teacher
+		purgeGuard: `NOT EXISTS (SELECT 1 FROM students WHERE students.teacher_id = teachers.id AND students.deleted_at IS NULL)
+			AND NOT EXISTS (SELECT 1 FROM classrooms WHERE classrooms.teacher_id = teachers.id)`,
+	},
+	"classrooms": {
+		table:       "classrooms",
+		displayName: "name",
+		searchCols:  []string{"name"},
+		// trashed students count too: purging would set their classroom_id
+		// to NULL, which a restored student can't be read with
+		purgeGuard: `NOT EXISTS (SELECT 1 FROM students WHERE students.classroom_id = classrooms.id)`,
+	},
+}
+
+// trashPurgeOrder purges dependents before the rows they reference.
+var trashPurgeOrder = []string{"students", "classrooms", "teachers"}
+
+type TrashStore struct {
+	db *routedDB
+}
+
+func lookupTrashEntity(entity string) (trashEntity, error) {
+	te, ok := trashEntities[entity]
+	if !ok {
+		return trashEntity{}, ErrUnknownEntity
+	}
+	return te, nil
+}
+
+// List returns soft-deleted rows of an entity, most recently deleted first.
+func (s *TrashStore) List(ctx context.Context, entity string, pq PaginatedQuery) ([]*TrashedRecord, error) {
+	te, err := lookupTrashEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{"id", te.displayName, "deleted_at", "deleted_by"}
+	pq.SortBy = "deleted_at"
+	query, args, err := BuildPaginatedQuery(te.table, columns, pq, te.searchCols, []string{"deleted_at"}, "deleted_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*TrashedRecord{}
+	for rows.Next() {
+		rec := TrashedRecord{Entity: entity}
+		var deletedBy sql.NullInt64
+		if err := rows.Scan(&rec.ID, &rec.DisplayName, &rec.DeletedAt, &deletedBy); err != nil {
+			return nil, err
+		}
+		if deletedBy.Valid {
+			v := deletedBy.Int64
+			rec.DeletedBy = &v
+		}
+		records = append(records, &rec)
+	}
+
+	return records, rows.Err()
+}
+
+// Restore clears the soft-delete marker of a trashed row.
+func (s *TrashStore) Restore(ctx context.Context, entity string, id int64) error {
+	te, err := lookupTrashEntity(entity)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`, te.table)
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	return expectRowsAffected(res)
+}
+
+// Purge permanently deletes a trashed row. It returns ErrConflict when
+// records that would be kept still depend on it.
+func (s *TrashStore) Purge(ctx context.Context, entity string, id int64) error {
+	te, err := lookupTrashEntity(entity)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var trashed, purgeable bool
+	guard := "TRUE"
+	if te.purgeGuard != "" {
+		guard = te.purgeGuard
+	}
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT deleted_at IS NOT NULL, %s
+		FROM %s
+		WHERE id = $1
+	`, guard, te.table), id).Scan(&trashed, &purgeable)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !trashed {
+		return ErrNotFound
+	}
+	if !purgeable {
+		return ErrConflict
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND deleted_at IS NOT NULL`, te.table), id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	return expectRowsAffected(res)
+}
+
+// PurgeOlderThan permanently deletes every trashed row deleted before cutoff.
+// Rows that still have live dependents are kept.
+func (s *TrashStore) PurgeOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var purged int64
+	for _, entity := range trashPurgeOrder {
+		te := trashEntities[entity]
+
+		query := fmt.Sprintf(`DELETE FROM %s WHERE deleted_at < $1`, te.table)
+		if te.purgeGuard != "" {
+			query += " AND " + te.purgeGuard
+		}
+
+		res, err := s.db.ExecContext(ctx, query, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	return purged, nil
+}
+
+func expectRowsAffected(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isExclusionViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23P01"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func isUndefinedTable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42P01"
+}
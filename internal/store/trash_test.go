@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// trashDB stands in for a database holding one trashed classroom that
+// students still belong to. Its guard check only reports the classroom as
+// purgeable when the guard doesn't look at the classroom's students, and
+// it records every statement it runs.
+type trashDB struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (d *trashDB) Open(string) (driver.Conn, error) { return &trashConn{d}, nil }
+
+func (d *trashDB) ran(prefix string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, q := range d.queries {
+		if strings.HasPrefix(strings.TrimSpace(q), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type trashConn struct{ db *trashDB }
+
+func (c *trashConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *trashConn) Close() error                        { return nil }
+func (c *trashConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *trashConn) record(query string) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.queries = append(c.db.queries, query)
+}
+
+func (c *trashConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.record(query)
+	hasStudents := strings.Contains(query, "students.classroom_id = classrooms.id")
+	return &trashRows{row: []driver.Value{true, !hasStudents}}, nil
+}
+
+func (c *trashConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.record(query)
+	if strings.Contains(query, "students.classroom_id = classrooms.id") {
+		return driver.RowsAffected(0), nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type trashRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *trashRows) Columns() []string { return []string{"trashed", "purgeable"} }
+func (r *trashRows) Close() error      { return nil }
+
+func (r *trashRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func newTrashStore(t *testing.T) (*TrashStore, *trashDB) {
+	t.Helper()
+	fake := &trashDB{}
+	db := sql.OpenDB(fakeConnector{fake})
+	t.Cleanup(func() { db.Close() })
+	return &TrashStore{newRoutedDB(Pools{API: db})}, fake
+}
+
+type fakeConnector struct{ d *trashDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c fakeConnector) Driver() driver.Driver                        { return c.d }
+
+func TestPurgeClassroomWithStudents(t *testing.T) {
+	s, db := newTrashStore(t)
+
+	err := s.Purge(context.Background(), "classrooms", 3)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Purge = %v, want ErrConflict", err)
+	}
+	if db.ran("DELETE") {
+		t.Fatal("classroom deleted while students still belong to it")
+	}
+}
+
+func TestPurgeOlderThanKeepsClassroomsWithStudents(t *testing.T) {
+	s, db := newTrashStore(t)
+
+	if _, err := s.PurgeOlderThan(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !db.ran("DELETE FROM classrooms WHERE deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM students WHERE students.classroom_id = classrooms.id)") {
+		t.Fatalf("classrooms purged without a guard on their students; ran %q", db.queries)
+	}
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WidgetToken lets a school website embed read-only public data: the
+// token reads only its Scopes, and only from pages on its Origins.
+type WidgetToken struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Origins   []string   `json:"origins"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type WidgetTokenStore struct {
+	db *routedDB
+}
+
+const widgetTokenColumns = `id, name, scopes, origins, created_by, created_at, revoked_at`
+
+func scanWidgetToken(row interface{ Scan(...any) error }) (*WidgetToken, error) {
+	var t WidgetToken
+	err := row.Scan(&t.ID, &t.Name, pq.Array(&t.Scopes), pq.Array(&t.Origins), &t.CreatedBy, &t.CreatedAt, &t.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *WidgetTokenStore) Create(ctx context.Context, t *WidgetToken) error {
+	query := `
+		INSERT INTO widget_tokens (name, scopes, origins, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.QueryRowContext(ctx, query, t.Name, pq.Array(t.Scopes), pq.Array(t.Origins), t.CreatedBy).
+		Scan(&t.ID, &t.CreatedAt)
+}
+
+// List returns every token, revoked ones included, newest first.
+func (s *WidgetTokenStore) List(ctx context.Context) ([]*WidgetToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+widgetTokenColumns+` FROM widget_tokens ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*WidgetToken{}
+	for rows.Next() {
+		t, err := scanWidgetToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// GetLive returns the token with id; ErrNotFound for an unknown or revoked
+// one.
+func (s *WidgetTokenStore) GetLive(ctx context.Context, id int64) (*WidgetToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return scanWidgetToken(s.db.QueryRowContext(ctx,
+		`SELECT `+widgetTokenColumns+` FROM widget_tokens WHERE id = $1 AND revoked_at IS NULL`, id))
+}
+
+// Revoke stops a token from working. ErrNotFound when there is no such
+// live token.
+func (s *WidgetTokenStore) Revoke(ctx context.Context, id int64) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `UPDATE widget_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	return expectRowsAffected(res)
+}
@@ -0,0 +1,36 @@
+package utils
+
+// ValidNationalID reports whether id is a well-formed Iranian national ID
+// (code-e melli): ten digits whose last digit matches the weighted checksum
+// of the first nine. IDs made of a single repeated digit pass the checksum
+// but are never issued, so they are rejected.
+func ValidNationalID(id string) bool {
+	if len(id) != 10 {
+		return false
+	}
+
+	repeated := true
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := id[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		if c != id[0] {
+			repeated = false
+		}
+		if i < 9 {
+			sum += int(c-'0') * (10 - i)
+		}
+	}
+	if repeated {
+		return false
+	}
+
+	check := int(id[9] - '0')
+	if r := sum % 11; r < 2 {
+		return check == r
+	} else {
+		return check == 11-r
+	}
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "reflect"
+import (
+	"reflect"
+	"strings"
+)
 
 // ApplyPatch copies non-nil pointer fields from src to dst struct.
 // skipFields can be used to exclude certain fields like "Version".
@@ -46,3 +49,31 @@ func ApplyPatch(dst, src any, skipFields ...string) {
 		}
 	}
 }
+
+// PatchFields returns the non-nil pointer fields of src keyed by their JSON
+// name, for stores that update only the columns a PATCH provided.
+func PatchFields(src any) map[string]any {
+	fields := map[string]any{}
+
+	srcVal := reflect.ValueOf(src)
+	if srcVal.Kind() == reflect.Pointer {
+		srcVal = srcVal.Elem()
+	}
+	if srcVal.Kind() != reflect.Struct {
+		return fields
+	}
+
+	srcType := srcVal.Type()
+	for i := 0; i < srcVal.NumField(); i++ {
+		field := srcVal.Field(i)
+		if field.Kind() != reflect.Pointer || field.IsNil() {
+			continue
+		}
+		name, _, _ := strings.Cut(srcType.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field.Elem().Interface()
+	}
+	return fields
+}